@@ -0,0 +1,63 @@
+package advanced
+
+// Find the polygon each triangle came from, for input which is a set of
+// regions, like the countries of a map. A triangle belongs to the solid whose
+// inside it's in: the innermost counterclockwise ring around it, not counting
+// the parts of that ring inside its holes. The result is a parallel array to
+// the triangles, holding each triangle's polygon index in the list, or -1 for
+// a triangle which isn't inside any solid.
+//
+// Each triangle's centroid is located in a trapezoid map of the list, so the
+// triangles may come from any triangulation of it. The list must be valid
+// input for triangulation, with the usual windings, and rings which touch
+// other than along a shared edge must share points.
+func (l PolygonList) AttributeTriangles(triangles TriangleList) []int {
+	working, shared := findSharedEdges(l)
+	graph := &QueryGraph{}
+	graph.addPolygonList(working, shared)
+	windings := l.Windings()
+
+	// A ring's inside is on the left of each of its edges, so an inside
+	// trapezoid is on the inside of the ring its left side comes from. If that
+	// ring is a hole, the trapezoid belongs to the solid around the hole.
+	holeOwners := make(map[int]int)
+	var ownerOf func(t *Trapezoid) int
+	ownerOfHole := func(ring int) int {
+		if owner, ok := holeOwners[ring]; ok {
+			return owner
+		}
+		// Guard against cycles, which only invalid input could have
+		holeOwners[ring] = -1
+		lowest := l[ring].Points[0]
+		for _, p := range l[ring].Points {
+			if p.Below(lowest) {
+				lowest = p
+			}
+		}
+		below := DirectionalPoint{Point: lowest, Direction: Vector{X: 0, Y: -1}}
+		owner := ownerOf(graph.FindPoint(below).Inner.(SinkNode).Trapezoid)
+		holeOwners[ring] = owner
+		return owner
+	}
+	ownerOf = func(t *Trapezoid) int {
+		if !t.IsInside() {
+			return -1
+		}
+		source, _ := graph.sourceOf(t.Left)
+		ring := source.polygon
+		if windings[ring] {
+			return ring
+		}
+		return ownerOfHole(ring)
+	}
+
+	result := make([]int, len(triangles))
+	for i, tri := range triangles {
+		centroid := &Point{X: (tri.A.X + tri.B.X + tri.C.X) / 3, Y: (tri.A.Y + tri.B.Y + tri.C.Y) / 3}
+		result[i] = -1
+		if graph.Root != nil {
+			result[i] = ownerOf(graph.FindPoint(centroid.PointingRight()).Inner.(SinkNode).Trapezoid)
+		}
+	}
+	return result
+}
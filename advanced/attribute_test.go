@@ -0,0 +1,31 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeTriangles(t *testing.T) {
+	// A solid with a hole, and an island in the hole
+	outer := squareRing(0, 0, 10)
+	hole := windAs(squareRing(2, 2, 6), false)
+	island := squareRing(4, 4, 2)
+	list := PolygonList{outer, hole, island}
+	triangles := list.Triangulate()
+	owners := list.AttributeTriangles(triangles)
+	area := make([]float64, len(list))
+	for i, tri := range triangles {
+		assert.NotEqual(t, -1, owners[i])
+		area[owners[i]] += tri.SignedArea()
+	}
+	assert.Equal(t, []float64{100 - 36, 0, 4}, area)
+
+	// Triangles outside of every solid, or in a hole, have no owner
+	outside := TriangleList{
+		{&Point{X: 20, Y: 20}, &Point{X: 21, Y: 20}, &Point{X: 20, Y: 21}},
+		{&Point{X: 2.5, Y: 2.5}, &Point{X: 3, Y: 2.5}, &Point{X: 2.5, Y: 3}},
+	}
+	assert.Equal(t, []int{-1, -1}, list.AttributeTriangles(outside))
+	assert.Equal(t, []int{-1}, PolygonList{}.AttributeTriangles(outside[:1]))
+}
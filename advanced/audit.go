@@ -0,0 +1,193 @@
+package advanced
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An audit of a finished trapezoid map, for bug reports. Unlike the invariant
+// checks (see CheckInvariants), which stop at the first broken invariant while
+// a segment is being added, the audit looks over the whole map at once and
+// lists everything suspicious it finds, described by coordinates alone.
+
+type AuditProblemKind int
+
+const (
+	// An inside trapezoid's top and bottom are at the same height, and it isn't
+	// bounded by a horizontal segment. The lexicographic rotation allows this,
+	// when vertices which aren't joined share a height, but that's where
+	// degenerate cases arise, so it's worth a look.
+	AuditZeroHeight AuditProblemKind = iota
+	// The right side is left of the left side, at the top or bottom
+	AuditInvertedSides
+	// A neighbor doesn't list the trapezoid as a neighbor in return
+	AuditNonReflexiveNeighbor
+	// More than two neighbors above or below
+	AuditTooManyNeighbors
+	// A neighbor which can't be reached from the root of the query graph
+	AuditUnreachableSink
+	// The trapezoid is inside by one of its sides, but is missing the other
+	AuditMissingSide
+)
+
+func (kind AuditProblemKind) String() string {
+	switch kind {
+	case AuditZeroHeight:
+		return "zero height"
+	case AuditInvertedSides:
+		return "inverted sides"
+	case AuditNonReflexiveNeighbor:
+		return "non-reflexive neighbor"
+	case AuditTooManyNeighbors:
+		return "too many neighbors"
+	case AuditUnreachableSink:
+		return "unreachable sink"
+	case AuditMissingSide:
+		return "missing side"
+	}
+	return "invalid"
+}
+
+type AuditProblem struct {
+	Kind AuditProblemKind
+	// The trapezoid's geometry (see Trapezoid.Geometry)
+	Trapezoid string
+	// Further details, if any, such as the geometry of a neighbor
+	Detail string
+}
+
+func (problem AuditProblem) String() string {
+	if problem.Detail == "" {
+		return fmt.Sprintf("%v: %s", problem.Kind, problem.Trapezoid)
+	}
+	return fmt.Sprintf("%v: %s (%s)", problem.Kind, problem.Trapezoid, problem.Detail)
+}
+
+type AuditReport struct {
+	// The number of trapezoids reachable from the root
+	Trapezoids int
+	Problems   []AuditProblem
+}
+
+// Did the audit find nothing wrong?
+func (report AuditReport) OK() bool {
+	return len(report.Problems) == 0
+}
+
+// Describe the report as text, one problem per line, after a summary line.
+func (report AuditReport) String() string {
+	lines := []string{fmt.Sprintf("audit: %d trapezoids, %d problems", report.Trapezoids, len(report.Problems))}
+	for _, problem := range report.Problems {
+		lines = append(lines, problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Audit every trapezoid reachable from the root of the graph.
+func AuditGraph(g *QueryGraph) AuditReport {
+	var report AuditReport
+	if g.Root == nil {
+		return report
+	}
+
+	var trapezoids []*Trapezoid
+	reachable := make(map[*Trapezoid]bool)
+	for trapezoid := range g.IterateTrapezoids() {
+		trapezoids = append(trapezoids, trapezoid)
+		reachable[trapezoid] = true
+	}
+	report.Trapezoids = len(trapezoids)
+
+	add := func(kind AuditProblemKind, t *Trapezoid, detailFormat string, args ...interface{}) {
+		report.Problems = append(report.Problems, AuditProblem{kind, t.Geometry(), fmt.Sprintf(detailFormat, args...)})
+	}
+	unreachable := make(map[*Trapezoid]bool)
+	for _, t := range trapezoids {
+		if t.IsInside() && t.Top != nil && t.Bottom != nil && Equal(t.Top.Y, t.Bottom.Y) && !t.hasHorizontalSide() {
+			add(AuditZeroHeight, t, "")
+		}
+
+		if t.Left != nil && t.Right != nil {
+			for _, y := range []YDirection{Up, Down} {
+				if t.boundaryPoint(y) == nil {
+					continue
+				}
+				leftX := t.xValueForDirection(Direction{Left, y})
+				rightX := t.xValueForDirection(Direction{Right, y})
+				if LessThan(rightX, leftX) {
+					add(AuditInvertedSides, t, "left side at %v, right side at %v", leftX, rightX)
+					break
+				}
+			}
+		}
+
+		if (t.Left != nil && t.Left.PointsDown() && t.Right == nil) ||
+			(t.Right != nil && !t.Right.PointsDown() && t.Left == nil) {
+			add(AuditMissingSide, t, "")
+		}
+
+		for _, side := range []struct {
+			name                string
+			neighbors, opposite func(*Trapezoid) *TrapezoidNeighborList
+		}{
+			{"above", neighborsAbove, neighborsBelow},
+			{"below", neighborsBelow, neighborsAbove},
+		} {
+			neighbors := side.neighbors(t)
+			if count := neighbors.Count(); count > 2 {
+				add(AuditTooManyNeighbors, t, "%d neighbors %s", count, side.name)
+			}
+			for _, neighbor := range neighbors {
+				if neighbor == nil {
+					continue
+				}
+				if !side.opposite(neighbor).contains(t) {
+					add(AuditNonReflexiveNeighbor, t, "neighbor %s is %s", side.name, neighbor.Geometry())
+				}
+				if !reachable[neighbor] && !unreachable[neighbor] {
+					unreachable[neighbor] = true
+					add(AuditUnreachableSink, neighbor, "neighbor %s of %s", side.name, t.Geometry())
+				}
+			}
+		}
+	}
+	return report
+}
+
+func neighborsAbove(t *Trapezoid) *TrapezoidNeighborList { return &t.TrapezoidsAbove }
+func neighborsBelow(t *Trapezoid) *TrapezoidNeighborList { return &t.TrapezoidsBelow }
+
+func (tl *TrapezoidNeighborList) contains(t *Trapezoid) bool {
+	for _, neighbor := range *tl {
+		if neighbor == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Trapezoid) hasHorizontalSide() bool {
+	return (t.Left != nil && t.Left.IsHorizontal()) || (t.Right != nil && t.Right.IsHorizontal())
+}
+
+func (t *Trapezoid) boundaryPoint(dir YDirection) *Point {
+	if dir == Up {
+		return t.Top
+	}
+	return t.Bottom
+}
+
+// Audit the graph, if there is one, without letting a problem with the audit
+// itself escape. This is used while an error is already on its way up.
+func auditAfterError(g *QueryGraph) (report *AuditReport) {
+	if g == nil {
+		return nil
+	}
+	defer func() {
+		if recover() != nil {
+			report = nil
+		}
+	}()
+	result := AuditGraph(g)
+	return &result
+}
@@ -0,0 +1,142 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditGraph(t *testing.T) {
+	// By default, a triangle with no two vertices at the same height, so
+	// nothing is suspicious until it's planted
+	triangle := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 4, Y: 1}, {X: 1, Y: 3}}}}
+	newGraph := func(list ...PolygonList) (*QueryGraph, *Trapezoid) {
+		if len(list) == 0 {
+			list = append(list, triangle)
+		}
+		g := &QueryGraph{}
+		g.AddPolygons(list[0])
+		for _, trapezoid := range collectTrapezoids(g) {
+			if trapezoid.IsInside() {
+				return g, trapezoid
+			}
+		}
+		t.Fatal("no inside trapezoid")
+		return nil, nil
+	}
+	kinds := func(report AuditReport) []AuditProblemKind {
+		var result []AuditProblemKind
+		for _, problem := range report.Problems {
+			result = append(result, problem.Kind)
+		}
+		return result
+	}
+
+	t.Run("clean", func(t *testing.T) {
+		g, _ := newGraph()
+		report := AuditGraph(g)
+		assert.True(t, report.OK(), report.String())
+		assert.Equal(t, len(collectTrapezoids(g)), report.Trapezoids)
+
+		g, _ = newGraph(SquareWithHole())
+		report = AuditGraph(g)
+		assert.True(t, report.OK(), report.String())
+		assert.True(t, AuditGraph(&QueryGraph{}).OK())
+
+		// Unjoined vertices at the same height are legitimate, but suspicious
+		g, _ = newGraph(SimpleStar())
+		assert.Equal(t, []AuditProblemKind{AuditZeroHeight}, kinds(AuditGraph(g)))
+	})
+
+	t.Run("zero height", func(t *testing.T) {
+		g, trapezoid := newGraph()
+		trapezoid.Bottom = &Point{X: trapezoid.Top.X + 0.5, Y: trapezoid.Top.Y}
+		assert.Equal(t, []AuditProblemKind{AuditZeroHeight}, kinds(AuditGraph(g)))
+	})
+
+	t.Run("inverted sides", func(t *testing.T) {
+		g, trapezoid := newGraph()
+		trapezoid.Left, trapezoid.Right = trapezoid.Right, trapezoid.Left
+		assert.Equal(t, []AuditProblemKind{AuditInvertedSides}, kinds(AuditGraph(g)))
+	})
+
+	t.Run("non-reflexive neighbor", func(t *testing.T) {
+		g, trapezoid := newGraph()
+		above := trapezoid.TrapezoidsAbove.AnyNeighbor()
+		require.NotNil(t, above)
+		above.TrapezoidsBelow.Remove(trapezoid)
+		assert.Equal(t, []AuditProblemKind{AuditNonReflexiveNeighbor}, kinds(AuditGraph(g)))
+	})
+
+	t.Run("too many neighbors", func(t *testing.T) {
+		g, trapezoid := newGraph(SquareWithHole())
+		// Link up with other trapezoids in both directions, so that the links are
+		// still reflexive
+		for _, other := range collectTrapezoids(g) {
+			if trapezoid.TrapezoidsAbove.Count() == 3 {
+				break
+			}
+			if other == trapezoid || trapezoid.TrapezoidsAbove.contains(other) || other.TrapezoidsBelow.Count() > 1 {
+				continue
+			}
+			trapezoid.TrapezoidsAbove.Add(other)
+			other.TrapezoidsBelow.Add(trapezoid)
+		}
+		require.Equal(t, 3, trapezoid.TrapezoidsAbove.Count())
+		assert.Equal(t, []AuditProblemKind{AuditTooManyNeighbors}, kinds(AuditGraph(g)))
+	})
+
+	t.Run("unreachable sink", func(t *testing.T) {
+		g, trapezoid := newGraph()
+		require.Less(t, trapezoid.TrapezoidsBelow.Count(), 2)
+		orphan := &Trapezoid{Left: trapezoid.Left, Right: trapezoid.Right, Top: trapezoid.Bottom}
+		orphan.TrapezoidsAbove.Add(trapezoid)
+		trapezoid.TrapezoidsBelow.Add(orphan)
+		report := AuditGraph(g)
+		assert.Equal(t, []AuditProblemKind{AuditUnreachableSink}, kinds(report))
+		assert.Equal(t, orphan.Geometry(), report.Problems[0].Trapezoid)
+	})
+
+	t.Run("missing side", func(t *testing.T) {
+		g, trapezoid := newGraph()
+		trapezoid.Right = nil
+		report := AuditGraph(g)
+		assert.Equal(t, []AuditProblemKind{AuditMissingSide}, kinds(report))
+		assert.Contains(t, report.String(), "missing side: <L: ")
+		assert.Contains(t, report.String(), "R: Ø")
+	})
+}
+
+func TestTriangulate_AuditOnError(t *testing.T) {
+	diagnostics := &Diagnostics{}
+	list := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 2}}}}
+	assert.Error(t, triangulateRecovering(list, Options{Diagnostics: diagnostics}))
+	require.NotNil(t, diagnostics.Audit)
+	assert.Contains(t, diagnostics.Audit.String(), "audit: ")
+
+	// Nothing on success
+	diagnostics = &Diagnostics{}
+	SquareWithHole().TriangulateWithOptions(Options{Diagnostics: diagnostics})
+	assert.Nil(t, diagnostics.Audit)
+
+	// Nor once extraction has used up the map. Moving a point makes
+	// CheckPointIntegrity fail at the end of the given stage.
+	failAfter := func(stage Stage) *Diagnostics {
+		diagnostics := &Diagnostics{}
+		list := SquareWithHole()
+		err := triangulateRecovering(list, Options{
+			Diagnostics:         diagnostics,
+			CheckPointIntegrity: true,
+			Progress: func(s Stage) {
+				if s == stage {
+					list[1].Points[2].X += 0.25
+				}
+			},
+		})
+		require.Error(t, err)
+		return diagnostics
+	}
+	assert.NotNil(t, failAfter(StageGraphBuilt).Audit)
+	assert.Nil(t, failAfter(StageMonotonesExtracted).Audit)
+}
@@ -0,0 +1,71 @@
+package advanced
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Triangulate many independent groups of rings, such as one outline with its
+// holes per group. Each group is triangulated on its own, as if by
+// TriangulateList, so no combined trapezoid map is ever built, and a lone
+// monotone group, such as any convex polygon, skips the map entirely. Groups
+// must not overlap each other, or the triangles will overlap too.
+//
+// The groups are spread over GOMAXPROCS goroutines, each using one Triangulator
+// from the pool for all the groups it takes. The results are parallel arrays to
+// the groups, in the same order however the work was scheduled, and a group
+// which fails only sets its own error, leaving its triangles nil.
+//
+// The options apply to every group, so Progress and OrientTriangle may be
+// called from several goroutines at once. There is nowhere to record
+// Diagnostics for each group, so opts.Diagnostics must be nil, or every group
+// fails.
+func (p *TriangulatorPool) TriangulateBatch(groups []PolygonList, opts Options) ([]TriangleList, []error) {
+	results := make([]TriangleList, len(groups))
+	errs := make([]error, len(groups))
+	if opts.Diagnostics != nil {
+		err := errors.New("Diagnostics can't be recorded for a batch")
+		for i := range errs {
+			errs[i] = err
+		}
+		return results, errs
+	}
+
+	p.triangulateGroups(groups, opts, 0, results, errs)
+	return results, errs
+}
+
+// Triangulate each group into the same index of results, or errs if it fails,
+// on at most the given number of goroutines, or GOMAXPROCS if that's zero or
+// less
+func (p *TriangulatorPool) triangulateGroups(groups []PolygonList, opts Options, workers int, results []TriangleList, errs []error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+	// Each worker takes the next group until there are none left, so that a few
+	// large groups don't hold up the rest
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			t := p.Get()
+			defer p.Put(t)
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(groups) {
+					return
+				}
+				results[i], errs[i] = t.TriangulateList(groups[i], opts)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,87 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// n unit squares in a row, a unit apart, as separate groups
+func quadGroups(n int) []PolygonList {
+	groups := make([]PolygonList, n)
+	for i := range groups {
+		groups[i] = PolygonList{squareRing(float64(2*i), 0, 1)}
+	}
+	return groups
+}
+
+func TestTriangulateBatch(t *testing.T) {
+	pool := NewTriangulatorPool(0, 0)
+	groups := append(quadGroups(100), SquareWithHole(), MultiLayeredHoles())
+	results, errs := pool.TriangulateBatch(groups, Options{})
+	require.Len(t, results, len(groups))
+	require.Len(t, errs, len(groups))
+	for i, group := range groups {
+		require.NoError(t, errs[i], "group %d", i)
+		// Each result is for its own group, in order
+		expected := group.Triangulate()
+		assert.Equal(t, triangleCoordinates(expected), triangleCoordinates(results[i]), "group %d", i)
+	}
+
+	results, errs = pool.TriangulateBatch(nil, Options{})
+	assert.Empty(t, results)
+	assert.Empty(t, errs)
+}
+
+func TestTriangulateBatch_Isolation(t *testing.T) {
+	groups := quadGroups(1000)
+	bad := 537
+	groups[bad] = PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 2}}}}
+	results, errs := NewTriangulatorPool(0, 0).TriangulateBatch(groups, Options{})
+	for i := range groups {
+		if i == bad {
+			assert.Error(t, errs[i])
+			assert.Nil(t, results[i])
+			continue
+		}
+		assert.NoError(t, errs[i], "group %d", i)
+		assert.Len(t, results[i], 2, "group %d", i)
+	}
+}
+
+func TestTriangulateBatch_Diagnostics(t *testing.T) {
+	results, errs := NewTriangulatorPool(0, 0).TriangulateBatch(quadGroups(3), Options{Diagnostics: &Diagnostics{}})
+	for i := range errs {
+		assert.Error(t, errs[i])
+		assert.Nil(t, results[i])
+	}
+}
+
+func BenchmarkTriangulateBatch_Quads(b *testing.B) {
+	groups := quadGroups(10000)
+	pool := NewTriangulatorPool(0, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, errs := pool.TriangulateBatch(groups, Options{})
+		for _, err := range errs {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// The same quads as one list, for comparison
+func BenchmarkTriangulateBatch_QuadsAsOneList(b *testing.B) {
+	var list PolygonList
+	for _, group := range quadGroups(10000) {
+		list = append(list, group...)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(list.Triangulate()) != 2*len(list) {
+			b.Fatal("wrong number of triangles")
+		}
+	}
+}
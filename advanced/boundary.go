@@ -0,0 +1,29 @@
+package advanced
+
+// Flag which triangle edges lie on the boundary of the input, for renderers
+// which draw outlines. The flags are a parallel array to the triangles: for
+// triangle i, flags[i][0] is edge A-B, flags[i][1] is edge B-C, and flags[i][2]
+// is edge C-A. An edge is on the boundary if its endpoints are the endpoints of
+// one of the rings' edges, in either direction. Holes count, since their edges
+// are boundaries too.
+//
+// Points are compared by pointer, so the triangles must share points with the
+// list, which is the case for triangles produced from it unless
+// CanonicalizeOutputPoints or AutoNormalize created new points.
+func (l PolygonList) BoundaryFlags(triangles TriangleList) [][3]bool {
+	ringEdges := make(map[locatorEdge]struct{})
+	for _, poly := range l {
+		for i, p := range poly.Points {
+			ringEdges[newLocatorEdge(p, poly.Points[CircularIndex(i+1, len(poly.Points))])] = struct{}{}
+		}
+	}
+
+	flags := make([][3]bool, len(triangles))
+	for i, tri := range triangles {
+		vertices := [3]*Point{tri.A, tri.B, tri.C}
+		for j, p := range vertices {
+			_, flags[i][j] = ringEdges[newLocatorEdge(p, vertices[(j+1)%3])]
+		}
+	}
+	return flags
+}
@@ -0,0 +1,34 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundaryFlags(t *testing.T) {
+	list := SquareWithHole()
+	triangles := list.Triangulate()
+	flags := list.BoundaryFlags(triangles)
+	assert.Len(t, flags, len(triangles))
+
+	// Every ring edge shows up exactly once, and nothing else is flagged
+	seen := make(map[locatorEdge]int)
+	for i, tri := range triangles {
+		vertices := [3]*Point{tri.A, tri.B, tri.C}
+		for j, flag := range flags[i] {
+			if flag {
+				seen[newLocatorEdge(vertices[j], vertices[(j+1)%3])]++
+			}
+		}
+	}
+	assert.Len(t, seen, 8)
+	for _, poly := range list {
+		for i, p := range poly.Points {
+			edge := newLocatorEdge(p, poly.Points[CircularIndex(i+1, len(poly.Points))])
+			assert.Equal(t, 1, seen[edge])
+		}
+	}
+
+	assert.Empty(t, list.BoundaryFlags(nil))
+}
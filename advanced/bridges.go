@@ -0,0 +1,162 @@
+package advanced
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Some polygon clipping libraries output a polygon with holes as a single ring,
+// by connecting each hole to the ring around it with a zero width bridge: the
+// ring runs out to the hole along one edge, around the hole, and back along the
+// same edge in the opposite direction. That ring isn't simple, since the two
+// sides of the bridge coincide, so it can't be triangulated as it is.
+//
+// A bridge is found as a pair of antiparallel edges, where each starts at the
+// point the other ends at. Bridges with a bend in them are a run of such pairs,
+// going out along one side and back along the other. Cutting both sides of a
+// bridge away splits the ring in two, and each piece is cut again, until no
+// bridges remain.
+
+// Cut the bridges out of a ring which connects holes to their outer ring with
+// zero width bridges, as some polygon clipping libraries output, returning the
+// outer ring and the holes as separate rings. Points within the tolerance of
+// each other are treated as the same point. The result is wound for
+// Triangulate, with rings which are inside an odd number of others running
+// clockwise, and rings are ordered so that each comes after the rings around
+// it. The points are the ones in the input, minus the ends of the bridges.
+//
+// Each bridge cut separates one ring, so the number of bridges cut is one less
+// than the number of rings returned. A ring with no bridges comes back as it
+// is, apart from its winding. Spikes, where the ring goes out and back along
+// the same edges with nothing at the end, are left out of the result.
+func DetectAndCutBridges(poly Polygon, tolerance float64) (list PolygonList, err error) {
+	if !(tolerance >= 0) {
+		return nil, errors.Errorf("tolerance must not be negative, got %v", tolerance)
+	}
+	if len(poly.Points) < 3 {
+		return nil, errors.Errorf("polygon needs at least 3 points, got %d", len(poly.Points))
+	}
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			err = recoveredErr
+		}
+	}()
+
+	pending := [][]*Point{poly.Points}
+	var rings PolygonList
+	for len(pending) > 0 {
+		points := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		pieces, cut := cutBridge(points, tolerance)
+		if !cut {
+			rings = append(rings, Polygon{points})
+			continue
+		}
+		for _, piece := range pieces {
+			if len(piece) >= 3 {
+				pending = append(pending, piece)
+			}
+		}
+	}
+	if len(rings) == 0 {
+		return nil, errors.New("polygon is nothing but bridges")
+	}
+
+	// Each ring came out wound the way the input ran around it, which is right
+	// if the input was wound consistently, but check the nesting anyway, since
+	// clipping libraries don't agree about which way outer rings run. A ring on
+	// its own has nothing to be nested in, and may not even be simple, if it had
+	// bridges which weren't found.
+	depths := make([]int, len(rings))
+	if len(rings) > 1 {
+		parents := rings.ContainingRings()
+		for i := range rings {
+			for parent := parents[i]; parent != -1; parent = parents[parent] {
+				depths[i]++
+			}
+		}
+	}
+	rings, _ = rings.NormalizeWinding(func(i int) bool {
+		return depths[i]%2 == 1
+	})
+	order := make([]int, len(rings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return depths[order[a]] < depths[order[b]]
+	})
+	list = make(PolygonList, len(rings))
+	for i, ringIndex := range order {
+		list[i] = rings[ringIndex]
+	}
+	return list, nil
+}
+
+// Find a bridge in the ring, and cut it out, returning the two pieces on
+// either side of it. Returns false if there is no bridge.
+func cutBridge(points []*Point, tolerance float64) (pieces [2][]*Point, cut bool) {
+	n := len(points)
+	near := func(a, b *Point) bool {
+		return math.Hypot(a.X-b.X, a.Y-b.Y) <= tolerance
+	}
+	at := func(i int) *Point {
+		return points[CircularIndex(i, n)]
+	}
+
+	// Index the points by X, so that the points near a given point can be found
+	// without checking them all
+	byX := make([]int, n)
+	for i := range byX {
+		byX[i] = i
+	}
+	sort.Slice(byX, func(a, b int) bool {
+		return points[byX[a]].X < points[byX[b]].X
+	})
+
+	for i := 0; i < n; i++ {
+		start, end := at(i), at(i+1)
+		if near(start, end) {
+			continue
+		}
+		// Look for an edge j which runs from the end of edge i back to its start
+		first := sort.Search(n, func(k int) bool {
+			return points[byX[k]].X >= end.X-tolerance
+		})
+		for k := first; k < n && points[byX[k]].X <= end.X+tolerance; k++ {
+			j := byX[k]
+			if j == i || !near(points[j], end) || !near(at(j+1), start) {
+				continue
+			}
+
+			// Widen the match to the whole run of antiparallel edges. Edge i+m
+			// pairs with edge j-m.
+			for edges := 1; 2*(edges+1) <= n && near(at(i-1), at(j+2)); edges++ {
+				i, j = i-1, j+1
+			}
+			// Rotate the ring so that the bridge starts at 0
+			rotated := make([]*Point, 0, n)
+			for m := 0; m < n; m++ {
+				rotated = append(rotated, at(i+m))
+			}
+			j = CircularIndex(j-i, n)
+			edges := 1
+			for 2*(edges+1) <= j && near(rotated[edges+1], rotated[j-edges]) {
+				edges++
+			}
+
+			// Between the sides is the ring at the far end of the bridge, and
+			// outside them is the ring it started from. The ends of the second side
+			// coincide with the ends of the first, so they are dropped. If the sides
+			// meet, this is a spike, with nothing at the far end.
+			if 2*edges <= j {
+				pieces[0] = rotated[edges : j-edges+1]
+			}
+			pieces[1] = rotated[j+1:]
+			return pieces, true
+		}
+	}
+	return pieces, false
+}
@@ -0,0 +1,132 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Join rings into one, with a bridge from the outer ring's first point to each
+// hole's first point. Each bridge goes through the given waypoints, which must
+// be the same for both sides. The points at either end of each bridge are
+// copies, as a clipping library would output.
+func bridgedRing(outer Polygon, holes []Polygon, waypoints ...*Point) Polygon {
+	copyOf := func(p *Point) *Point {
+		return &Point{X: p.X, Y: p.Y}
+	}
+	points := append([]*Point{}, outer.Points...)
+	for _, hole := range holes {
+		points = append(points, copyOf(outer.Points[0]))
+		for _, waypoint := range waypoints {
+			points = append(points, copyOf(waypoint))
+		}
+		points = append(points, hole.Points...)
+		points = append(points, copyOf(hole.Points[0]))
+		for i := len(waypoints) - 1; i >= 0; i-- {
+			points = append(points, copyOf(waypoints[i]))
+		}
+	}
+	return Polygon{points}
+}
+
+func TestDetectAndCutBridges(t *testing.T) {
+	expected := SquareWithHole()
+	ring := bridgedRing(expected[0], expected[1:])
+	require.Len(t, ring.Points, 10)
+
+	list, err := DetectAndCutBridges(ring, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, expected, list)
+	assert.InDelta(t, totalArea(expected.Triangulate()), totalArea(list.Triangulate()), 1e-9)
+	// The points are the input's own
+	assert.Same(t, ring.Points[0], list[0].Points[0])
+}
+
+func TestDetectAndCutBridges_BentBridge(t *testing.T) {
+	expected := SquareWithHole()
+	ring := bridgedRing(expected[0], expected[1:], &Point{X: -3, Y: -4}, &Point{X: -3, Y: -3})
+	list, err := DetectAndCutBridges(ring, 0)
+	require.NoError(t, err)
+	assert.Equal(t, expected, list)
+}
+
+func TestDetectAndCutBridges_SeveralHoles(t *testing.T) {
+	outer := squareRing(0, 0, 20)
+	holes := []Polygon{
+		squareRing(2, 2, 4).Reverse(),
+		squareRing(10, 3, 4).Reverse(),
+		squareRing(3, 10, 4).Reverse(),
+	}
+	ring := bridgedRing(outer, holes)
+
+	list, err := DetectAndCutBridges(ring, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 4)
+	assert.Equal(t, outer, list[0])
+	assert.ElementsMatch(t, holes, list[1:])
+	assert.InDelta(t, 400-3*16, totalArea(list.Triangulate()), 1e-9)
+}
+
+func TestDetectAndCutBridges_NestedBridges(t *testing.T) {
+	// A hole, with an island bridged to it, bridged to the outer ring
+	island := squareRing(4, 4, 2)
+	hole := bridgedRing(squareRing(2, 2, 6).Reverse(), []Polygon{island})
+	outer := squareRing(0, 0, 10)
+	ring := bridgedRing(outer, []Polygon{hole})
+
+	list, err := DetectAndCutBridges(ring, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, PolygonList{outer, squareRing(2, 2, 6).Reverse(), island}, list)
+	assert.InDelta(t, 100-36+4, totalArea(list.Triangulate()), 1e-9)
+}
+
+func TestDetectAndCutBridges_Tolerance(t *testing.T) {
+	expected := SquareWithHole()
+	ring := bridgedRing(expected[0], expected[1:])
+	// Nudge the returning end of the bridge
+	ring.Points[9].X += 1e-9
+
+	list, err := DetectAndCutBridges(ring, 0)
+	require.NoError(t, err)
+	assert.Len(t, list, 1, "no bridge without a tolerance")
+
+	list, err = DetectAndCutBridges(ring, 1e-6)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func TestDetectAndCutBridges_Winding(t *testing.T) {
+	// Clockwise outer rings come back counterclockwise, with clockwise holes
+	expected := SquareWithHole()
+	ring := bridgedRing(expected[0], expected[1:]).Reverse()
+	list, err := DetectAndCutBridges(ring, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.True(t, IsCCW(&list[0]))
+	assert.True(t, IsCW(&list[1]))
+	assert.InDelta(t, 100-16, totalArea(list.Triangulate()), 1e-9)
+}
+
+func TestDetectAndCutBridges_NoBridges(t *testing.T) {
+	star := SimpleStar()[0]
+	list, err := DetectAndCutBridges(star.Reverse(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, PolygonList{star}, list)
+
+	// A spike is left out
+	spiked := squareRing(0, 0, 1)
+	spiked.Points = append(spiked.Points, &Point{X: 0, Y: 0}, &Point{X: -1, Y: 0})
+	list, err = DetectAndCutBridges(spiked, 0)
+	require.NoError(t, err)
+	assert.Equal(t, PolygonList{squareRing(0, 0, 1)}, list)
+}
+
+func TestDetectAndCutBridges_Errors(t *testing.T) {
+	_, err := DetectAndCutBridges(squareRing(0, 0, 1), -1)
+	assert.Error(t, err)
+	_, err = DetectAndCutBridges(Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}}}, 0)
+	assert.Error(t, err)
+}
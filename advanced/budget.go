@@ -0,0 +1,121 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Number of segment insertions timed to calibrate the cost estimate
+const budgetSampleSize = 64
+
+// Rough multiplier on the cost of building the query graph, to account for
+// monotone extraction and triangulation afterwards
+const budgetOverhead = 2
+
+// Triangulate within a rough time budget, for previews which must keep up with
+// interactive editing.
+//
+// The first few segment insertions are timed, and used to estimate how long
+// the whole triangulation will take. If that fits in the budget, the full
+// triangulation is returned, and complete is true. Otherwise, the input is
+// simplified down to a vertex count estimated to fit, and the triangulation of
+// the simplified shape is returned with complete false. The simplification
+// never introduces crossings, so the preview is a valid triangulation of a
+// shape close to the input, using a subset of its points.
+//
+// The budget is a target, not a guarantee. In particular, the time spent
+// simplifying is not accounted for.
+func (list PolygonList) TriangulateBudgeted(budget time.Duration) (result TriangleList, complete bool) {
+	checkCoordinateRange(list)
+
+	// The sample is timed on the real graph, so that the work isn't wasted if
+	// the whole input turns out to fit.
+	graph := &QueryGraph{}
+	var segments []*Segment
+	for polygonIndex, poly := range list {
+		for i := range poly.Points {
+			segments = append(segments, graph.newSourceSegment(poly, polygonIndex, i))
+		}
+	}
+	if len(segments) == 0 {
+		return nil, true
+	}
+	r := rand.New(rand.NewSource(0))
+	r.Shuffle(len(segments), func(i, j int) {
+		segments[i], segments[j] = segments[j], segments[i]
+	})
+
+	sampleSize := budgetSampleSize
+	if sampleSize > len(segments) {
+		sampleSize = len(segments)
+	}
+	start := time.Now()
+	graph.addSegments(segments[:sampleSize])
+	elapsed := time.Since(start)
+
+	estimate := func(n int) time.Duration {
+		return time.Duration(budgetOverhead * float64(elapsed) * budgetCostScale(n, sampleSize))
+	}
+
+	if elapsed+estimate(len(segments)) <= budget {
+		graph.addSegments(segments[sampleSize:])
+		return triangulateMonotones(graph.convertToMonotones(Options{}), Options{}, nil, nil), true
+	}
+
+	// Search for the largest vertex count which fits. Every ring needs at least
+	// three vertices, so we can't go lower than that.
+	minCount := 3 * len(list)
+	lo, hi := minCount, len(segments)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if estimate(mid) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	simplified := list.simplify(lo)
+	result = simplified.Triangulate()
+	checkTriangulation(simplified, result)
+	return result, false
+}
+
+// How much more expensive it is to insert n segments than it was to insert the
+// sample, assuming the expected O(n log n) running time.
+func budgetCostScale(n, sampleSize int) float64 {
+	return float64(n) * math.Log2(float64(n)+1) / (float64(sampleSize) * math.Log2(float64(sampleSize)+1))
+}
+
+// Cheap sanity check that a triangulation covers its polygons. Every triangle
+// must be counterclockwise, the triangle count must match the vertex and hole
+// counts, and the areas must agree.
+func checkTriangulation(list PolygonList, triangles TriangleList) {
+	vertexCount := 0
+	holeCount := 0
+	var polygonArea float64
+	for _, poly := range list {
+		vertexCount += len(poly.Points)
+		polygonArea += poly.SignedArea()
+		if IsCW(&poly) {
+			holeCount++
+		}
+	}
+
+	var triangleArea float64
+	for _, triangle := range triangles {
+		if !IsCCW(triangle) {
+			fatalf("triangle is not counterclockwise: %v, %v, %v", triangle.A, triangle.B, triangle.C)
+		}
+		triangleArea += triangle.SignedArea()
+	}
+
+	solidCount := len(list) - holeCount
+	if expected := vertexCount + 2*holeCount - 2*solidCount; len(triangles) != expected {
+		fatalf("expected %d triangles, got %d", expected, len(triangles))
+	}
+	if math.Abs(triangleArea-polygonArea) > Epsilon*math.Max(1, math.Abs(polygonArea)) {
+		fatalf("triangle area %v does not match polygon area %v", triangleArea, polygonArea)
+	}
+}
@@ -0,0 +1,101 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriangulateBudgeted(t *testing.T) {
+	t.Run("fits", func(t *testing.T) {
+		list := MultiLayeredHoles()
+		result, complete := list.TriangulateBudgeted(time.Minute)
+		assert.True(t, complete)
+		validatePolygonsBySampling(t, result.ToPolygonList(), list)
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		const n = 20000
+		list := PolygonList{circlePolygon(100, n)}
+		inputPoints := make(PointSet)
+		for _, p := range list[0].Points {
+			inputPoints.Add(p)
+		}
+
+		start := time.Now()
+		result, complete := list.TriangulateBudgeted(50 * time.Microsecond)
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.False(t, complete)
+		require.NotEmpty(t, result)
+		assert.Less(t, len(result), n-2)
+
+		// Every triangle uses input points, and the triangle count is right for a
+		// polygon with the points that were kept
+		resultPoints := make(PointSet)
+		for _, triangle := range result {
+			assert.True(t, IsCCW(triangle))
+			for _, p := range []*Point{triangle.A, triangle.B, triangle.C} {
+				assert.True(t, inputPoints.Contains(p))
+				resultPoints.Add(p)
+			}
+		}
+		assert.Len(t, result, len(resultPoints)-2)
+		// The simplified circle is inscribed, so it can only lose area
+		area := totalArea(result)
+		assert.Less(t, area, math.Pi*100*100)
+		// How much is kept depends on how fast the machine is, and the race
+		// detector slows it down too much for this to hold
+		if !raceEnabled {
+			assert.Greater(t, area, 0.5*math.Pi*100*100)
+		}
+	})
+}
+
+func TestSimplify(t *testing.T) {
+	for name, list := range map[string]PolygonList{
+		"spiral":              {*LoadFixture("spiral")},
+		"multi layered holes": MultiLayeredHoles(),
+		"star stripes":        StarStripes(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			total := 0
+			for _, poly := range list {
+				total += len(poly.Points)
+			}
+			for _, target := range []int{total - 1, total / 2, 0} {
+				simplified := list.simplify(target)
+				require.Len(t, simplified, len(list))
+
+				count := 0
+				for i, poly := range simplified {
+					count += len(poly.Points)
+					assert.GreaterOrEqual(t, len(poly.Points), 3)
+					assert.Equal(t, IsCCW(&list[i]), IsCCW(&poly))
+				}
+				assert.LessOrEqual(t, count, total)
+
+				// Rings stay simple and disjoint
+				for i, a := range simplified {
+					for j, b := range simplified {
+						for k, p := range a.Points {
+							s := NewSegment(p, a.Points[CircularIndex(k+1, len(a.Points))])
+							for l, q := range b.Points {
+								if i == j && (k == l) {
+									continue
+								}
+								other := NewSegment(q, b.Points[CircularIndex(l+1, len(b.Points))])
+								assert.False(t, segmentsIntersect(s, other), "edge %d of ring %d crosses edge %d of ring %d", k, i, l, j)
+							}
+						}
+					}
+				}
+
+				triangles := simplified.Triangulate()
+				assert.NotPanics(t, func() { checkTriangulation(simplified, triangles) })
+			}
+		})
+	}
+}
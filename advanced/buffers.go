@@ -0,0 +1,120 @@
+package advanced
+
+// Scratch memory for a triangulation. A Triangulator keeps this between calls,
+// so that a stream of similarly sized inputs allocates little beyond the output
+// and the trapezoid map itself. Nothing in here may outlive the triangulation
+// that uses it, since the next one overwrites it.
+//
+// Every method is safe to call on nil buffers, in which case the memory is
+// allocated fresh, as if there were no buffers at all.
+type buffers struct {
+	// Backing storage for the input segments, and where they came from
+	segments []Segment
+	sources  map[*Segment]segmentSource
+
+	// The segments of the polygon being added, in insertion order
+	polygonSegments []*Segment
+
+	// The inside trapezoids, while monotones are extracted
+	trapezoids TrapezoidSet
+
+	// Monotone triangulation
+	sortedPoints []*Point
+	leftChain    PointSet
+	stack        PointStack
+}
+
+func newBuffers(vertices int) *buffers {
+	b := &buffers{}
+	b.reset(vertices)
+	return b
+}
+
+// Prepare for a triangulation with the given number of input vertices, growing
+// the segment storage to fit.
+func (b *buffers) reset(vertices int) {
+	if b == nil {
+		return
+	}
+	if cap(b.segments) < vertices {
+		b.segments = make([]Segment, 0, vertices)
+		b.sources = make(map[*Segment]segmentSource, vertices)
+	} else {
+		b.segments = b.segments[:0]
+		for s := range b.sources {
+			delete(b.sources, s)
+		}
+	}
+}
+
+// The number of input vertices the buffers have grown to hold
+func (b *buffers) capacity() int {
+	if b == nil {
+		return 0
+	}
+	return cap(b.segments)
+}
+
+// A segment from start to end, using the segment storage
+func (b *buffers) newSegment(start, end *Point) *Segment {
+	if b == nil {
+		return NewSegment(start, end)
+	}
+	b.segments = append(b.segments, Segment{Start: start, End: end})
+	return &b.segments[len(b.segments)-1]
+}
+
+// An empty table for where segments came from
+func (b *buffers) sourceTable() map[*Segment]segmentSource {
+	if b == nil {
+		return make(map[*Segment]segmentSource)
+	}
+	if b.sources == nil {
+		b.sources = make(map[*Segment]segmentSource)
+	}
+	return b.sources
+}
+
+// An empty slice for the segments of a polygon with n edges
+func (b *buffers) segmentList(n int) []*Segment {
+	if b == nil || cap(b.polygonSegments) < n {
+		segments := make([]*Segment, 0, n)
+		if b != nil {
+			b.polygonSegments = segments
+		}
+		return segments
+	}
+	return b.polygonSegments[:0]
+}
+
+// An empty trapezoid set
+func (b *buffers) trapezoidSet() TrapezoidSet {
+	if b == nil {
+		return nil
+	}
+	return b.trapezoids[:0]
+}
+
+// Empty scratch for triangulating a monotone polygon with n points
+func (b *buffers) monotoneScratch(n int) (sortedPoints []*Point, leftChain PointSet, stack PointStack) {
+	if b == nil {
+		return make([]*Point, 0, n), make(PointSet), make(PointStack, 0)
+	}
+	if cap(b.sortedPoints) < n {
+		b.sortedPoints = make([]*Point, 0, n)
+	}
+	if b.leftChain == nil {
+		b.leftChain = make(PointSet)
+	}
+	for p := range b.leftChain {
+		delete(b.leftChain, p)
+	}
+	return b.sortedPoints[:0], b.leftChain, b.stack[:0]
+}
+
+// Hold on to the stack after a monotone triangulation, since it may have grown
+func (b *buffers) keepStack(stack PointStack) {
+	if b != nil {
+		b.stack = stack
+	}
+}
@@ -0,0 +1,318 @@
+package advanced
+
+import (
+	"math"
+	"sort"
+)
+
+// A bounding volume hierarchy over triangles: a binary tree of axis-aligned
+// boxes, each bounding the triangles beneath it. Queries skip every subtree
+// whose box misses the query shape, then test the triangles left over exactly,
+// so they cost O(log n) plus the number of triangles near the shape.
+//
+// Each node splits its triangles in half by count, at the median centroid
+// along the longer axis of the centroids' bounds. Splitting by count, rather
+// than by position, keeps the depth at log2(n) however the triangles are
+// arranged, even when they're all in a line or all in one place.
+type TriangleBVH struct {
+	triangles TriangleList
+	// The tree, in preorder, so that each node's children come after it. Empty
+	// when there are no triangles.
+	nodes []bvhNode
+	// Triangle indexes, arranged so that each leaf's triangles are contiguous
+	order []int
+}
+
+type bvhNode struct {
+	bounds Rect
+	// For a leaf, the range of order holding its triangles. For an inner node,
+	// count is zero, and first is the index of the right child. The left child
+	// is always the next node.
+	first, count int
+}
+
+// The most triangles a leaf holds. Testing a few triangles directly is cheaper
+// than another level of boxes.
+const bvhLeafSize = 4
+
+// Build a bounding volume hierarchy over the triangles, for finding the ones
+// which touch a rectangle, point, segment or circle. Query results are indexes
+// into the list. The hierarchy holds on to the list and its points, so see
+// Refit for when the points move.
+func (triangles TriangleList) BuildBVH() *TriangleBVH {
+	bvh := &TriangleBVH{
+		triangles: triangles,
+		order:     make([]int, len(triangles)),
+	}
+	if len(triangles) == 0 {
+		return bvh
+	}
+	centroids := make([]Point, len(triangles))
+	for i, tri := range triangles {
+		bvh.order[i] = i
+		centroids[i] = Point{X: (tri.A.X + tri.B.X + tri.C.X) / 3, Y: (tri.A.Y + tri.B.Y + tri.C.Y) / 3}
+	}
+	bvh.nodes = make([]bvhNode, 0, 2*len(triangles)/bvhLeafSize+1)
+	bvh.build(0, len(triangles), centroids)
+	return bvh
+}
+
+// Add the node for order[start:end], and everything beneath it, returning its
+// bounds
+func (bvh *TriangleBVH) build(start, end int, centroids []Point) Rect {
+	index := len(bvh.nodes)
+	bvh.nodes = append(bvh.nodes, bvhNode{})
+	if end-start <= bvhLeafSize {
+		bvh.nodes[index] = bvhNode{bvh.boundsOf(start, end), start, end - start}
+		return bvh.nodes[index].bounds
+	}
+
+	spread := emptyBounds()
+	for _, i := range bvh.order[start:end] {
+		spread = spread.including(centroids[i].X, centroids[i].Y)
+	}
+	coordinate := func(i int) float64 { return centroids[i].X }
+	if spread.MaxY-spread.MinY > spread.MaxX-spread.MinX {
+		coordinate = func(i int) float64 { return centroids[i].Y }
+	}
+	// Only the median needs to be in place, with the smaller half before it.
+	// Ties are broken by index, so that the order is total.
+	span := bvh.order[start:end]
+	less := func(a, b int) bool {
+		ca, cb := coordinate(a), coordinate(b)
+		return ca < cb || (ca == cb && a < b)
+	}
+	selectNth(span, len(span)/2, less)
+
+	middle := start + len(span)/2
+	left := bvh.build(start, middle, centroids)
+	bvh.nodes[index].first = len(bvh.nodes)
+	bvh.nodes[index].bounds = left.union(bvh.build(middle, end, centroids))
+	return bvh.nodes[index].bounds
+}
+
+// Rearrange the values so that the nth is the one which would be there if they
+// were sorted, with smaller values before it and larger ones after
+func selectNth(values []int, n int, less func(a, b int) bool) {
+	low, high := 0, len(values)-1
+	for low < high {
+		// Partition around the median of the ends and middle, which handles
+		// values which are already sorted
+		middle := low + (high-low)/2
+		if less(values[middle], values[low]) {
+			values[middle], values[low] = values[low], values[middle]
+		}
+		if less(values[high], values[low]) {
+			values[high], values[low] = values[low], values[high]
+		}
+		if less(values[high], values[middle]) {
+			values[high], values[middle] = values[middle], values[high]
+		}
+		pivot := values[middle]
+		i, j := low, high
+		for i <= j {
+			for less(values[i], pivot) {
+				i++
+			}
+			for less(pivot, values[j]) {
+				j--
+			}
+			if i <= j {
+				values[i], values[j] = values[j], values[i]
+				i++
+				j--
+			}
+		}
+		if n <= j {
+			high = j
+		} else if n >= i {
+			low = i
+		} else {
+			return
+		}
+	}
+}
+
+func (bvh *TriangleBVH) boundsOf(start, end int) Rect {
+	bounds := emptyBounds()
+	for _, i := range bvh.order[start:end] {
+		bounds = bounds.union(triangleBounds(bvh.triangles[i]))
+	}
+	return bounds
+}
+
+// Update the boxes for the triangles' points having moved. The tree keeps its
+// shape, so queries stay correct however far the points move, but they slow
+// down as the boxes grow to overlap. After large moves, build a new hierarchy
+// instead.
+func (bvh *TriangleBVH) Refit() {
+	// Children come after their parents, so going backwards reaches them first
+	for i := len(bvh.nodes) - 1; i >= 0; i-- {
+		node := &bvh.nodes[i]
+		if node.count > 0 {
+			node.bounds = bvh.boundsOf(node.first, node.first+node.count)
+		} else {
+			node.bounds = bvh.nodes[i+1].bounds.union(bvh.nodes[node.first].bounds)
+		}
+	}
+}
+
+// The indexes of the triangles which touch the rectangle, in increasing order
+func (bvh *TriangleBVH) QueryRect(r Rect) []int {
+	return bvh.query(r.Overlaps, func(tri *Triangle) bool {
+		return triangleTouchesRect(tri, r)
+	})
+}
+
+// The indexes of the triangles which contain the point, including on their
+// edges, in increasing order
+func (bvh *TriangleBVH) QueryPoint(x, y float64) []int {
+	p := &Point{X: x, Y: y}
+	return bvh.query(func(bounds Rect) bool {
+		return bounds.ContainsPoint(p)
+	}, func(tri *Triangle) bool {
+		return triangleContainsPoint(tri, p)
+	})
+}
+
+// The indexes of the triangles which touch the segment from a to b, in
+// increasing order
+func (bvh *TriangleBVH) QuerySegment(a, b Point) []int {
+	segment := &Segment{Start: &a, End: &b}
+	return bvh.query(func(bounds Rect) bool {
+		return bounds.IntersectsSegment(&a, &b)
+	}, func(tri *Triangle) bool {
+		return triangleTouchesSegment(tri, segment)
+	})
+}
+
+// The indexes of the triangles which come within r of the center, in
+// increasing order
+func (bvh *TriangleBVH) QueryCircle(cx, cy, r float64) []int {
+	center := &Point{X: cx, Y: cy}
+	return bvh.query(func(bounds Rect) bool {
+		dx := math.Max(0, math.Max(bounds.MinX-cx, cx-bounds.MaxX))
+		dy := math.Max(0, math.Max(bounds.MinY-cy, cy-bounds.MaxY))
+		return float64(dx*dx)+float64(dy*dy) <= r*r
+	}, func(tri *Triangle) bool {
+		return triangleWithinDistance(tri, center, r)
+	})
+}
+
+// Walk the tree, skipping nodes whose boxes fail prune, and collect the
+// triangles in the rest which pass test
+func (bvh *TriangleBVH) query(prune func(Rect) bool, test func(*Triangle) bool) []int {
+	var result []int
+	if len(bvh.nodes) == 0 {
+		return result
+	}
+	stack := []int{0}
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := bvh.nodes[index]
+		if !prune(node.bounds) {
+			continue
+		}
+		if node.count == 0 {
+			stack = append(stack, node.first, index+1)
+			continue
+		}
+		for _, i := range bvh.order[node.first : node.first+node.count] {
+			if test(bvh.triangles[i]) {
+				result = append(result, i)
+			}
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// The depth of the tree, counting the root alone as 1
+func (bvh *TriangleBVH) depth() int {
+	var depthOf func(index int) int
+	depthOf = func(index int) int {
+		node := bvh.nodes[index]
+		if node.count > 0 {
+			return 1
+		}
+		left, right := depthOf(index+1), depthOf(node.first)
+		if right > left {
+			left = right
+		}
+		return 1 + left
+	}
+	if len(bvh.nodes) == 0 {
+		return 0
+	}
+	return depthOf(0)
+}
+
+// Bounds which contain nothing, and grow to fit whatever is added
+func emptyBounds() Rect {
+	return Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+func (r Rect) including(x, y float64) Rect {
+	return Rect{math.Min(r.MinX, x), math.Min(r.MinY, y), math.Max(r.MaxX, x), math.Max(r.MaxY, y)}
+}
+
+func (r Rect) union(other Rect) Rect {
+	return Rect{math.Min(r.MinX, other.MinX), math.Min(r.MinY, other.MinY), math.Max(r.MaxX, other.MaxX), math.Max(r.MaxY, other.MaxY)}
+}
+
+func triangleBounds(tri *Triangle) Rect {
+	return emptyBounds().including(tri.A.X, tri.A.Y).including(tri.B.X, tri.B.Y).including(tri.C.X, tri.C.Y)
+}
+
+// Does the closed triangle, of either winding, contain the point? A zero area
+// triangle contains the points of its longest edge.
+func triangleContainsPoint(tri *Triangle, p *Point) bool {
+	if !triangleBounds(tri).ContainsPoint(p) {
+		return false
+	}
+	ab, bc, ca := orientation(tri.A, tri.B, p), orientation(tri.B, tri.C, p), orientation(tri.C, tri.A, p)
+	return (ab >= 0 && bc >= 0 && ca >= 0) || (ab <= 0 && bc <= 0 && ca <= 0)
+}
+
+// Does the closed triangle overlap the rectangle? Either an edge of the
+// triangle touches the rectangle, or the rectangle is entirely inside the
+// triangle.
+func triangleTouchesRect(tri *Triangle, r Rect) bool {
+	if !triangleBounds(tri).Overlaps(r) {
+		return false
+	}
+	vertices := [3]*Point{tri.A, tri.B, tri.C}
+	for i, p := range vertices {
+		if r.IntersectsSegment(p, vertices[(i+1)%3]) {
+			return true
+		}
+	}
+	return triangleContainsPoint(tri, &Point{X: r.MinX, Y: r.MinY})
+}
+
+// Does the closed triangle touch the segment? Either an edge of the triangle
+// does, or the segment is entirely inside the triangle.
+func triangleTouchesSegment(tri *Triangle, s *Segment) bool {
+	vertices := [3]*Point{tri.A, tri.B, tri.C}
+	for i, p := range vertices {
+		if segmentsIntersect(s, &Segment{Start: p, End: vertices[(i+1)%3]}) {
+			return true
+		}
+	}
+	return triangleContainsPoint(tri, s.Start)
+}
+
+// Does the closed triangle come within r of the point?
+func triangleWithinDistance(tri *Triangle, p *Point, r float64) bool {
+	if triangleContainsPoint(tri, p) {
+		return true
+	}
+	vertices := [3]*Point{tri.A, tri.B, tri.C}
+	for i, q := range vertices {
+		if distanceToSegment(p, &Segment{Start: q, End: vertices[(i+1)%3]}) <= r {
+			return true
+		}
+	}
+	return false
+}
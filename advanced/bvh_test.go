@@ -0,0 +1,206 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Every triangle index which passes the test, for checking queries against
+func bruteForceQuery(triangles TriangleList, test func(*Triangle) bool) []int {
+	var result []int
+	for i, tri := range triangles {
+		if test(tri) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// Check many random queries of each kind against brute force, within the
+// given bounds
+func checkBVHQueries(t *testing.T, triangles TriangleList, bvh *TriangleBVH, bounds Rect, random *rand.Rand) {
+	width, height := bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY
+	randomPoint := func() Point {
+		return Point{X: bounds.MinX + random.Float64()*width, Y: bounds.MinY + random.Float64()*height}
+	}
+	size := math.Max(width, height)
+	for i := 0; i < 1000; i++ {
+		p := randomPoint()
+		assert.Equal(t, bruteForceQuery(triangles, func(tri *Triangle) bool {
+			return triangleContainsPoint(tri, &p)
+		}), bvh.QueryPoint(p.X, p.Y), "point %v", p)
+
+		a, b := randomPoint(), randomPoint()
+		r := Rect{math.Min(a.X, b.X), math.Min(a.Y, b.Y), math.Max(a.X, b.X), math.Max(a.Y, b.Y)}
+		r.MaxX, r.MaxY = r.MinX+(r.MaxX-r.MinX)/4, r.MinY+(r.MaxY-r.MinY)/4
+		assert.Equal(t, bruteForceQuery(triangles, func(tri *Triangle) bool {
+			return triangleTouchesRect(tri, r)
+		}), bvh.QueryRect(r), "rect %v", r)
+
+		segment := &Segment{Start: &a, End: &b}
+		assert.Equal(t, bruteForceQuery(triangles, func(tri *Triangle) bool {
+			return triangleTouchesSegment(tri, segment)
+		}), bvh.QuerySegment(a, b), "segment %v", segment)
+
+		radius := random.Float64() * size / 10
+		assert.Equal(t, bruteForceQuery(triangles, func(tri *Triangle) bool {
+			return triangleWithinDistance(tri, &p, radius)
+		}), bvh.QueryCircle(p.X, p.Y, radius), "circle %v, %v", p, radius)
+	}
+}
+
+func TestTriangleBVH_Spiral(t *testing.T) {
+	triangles := PolygonList{*LoadFixture("spiral")}.Triangulate()
+	bvh := triangles.BuildBVH()
+	bounds := emptyBounds()
+	for _, tri := range triangles {
+		bounds = bounds.union(triangleBounds(tri))
+	}
+	// Reach a little past the shape, so that some queries miss entirely
+	padding := (bounds.MaxX - bounds.MinX) / 10
+	bounds = Rect{bounds.MinX - padding, bounds.MinY - padding, bounds.MaxX + padding, bounds.MaxY + padding}
+	checkBVHQueries(t, triangles, bvh, bounds, rand.New(rand.NewSource(1)))
+
+	// Every point of the shape is in some triangle
+	for _, tri := range triangles {
+		centroid := Point{X: (tri.A.X + tri.B.X + tri.C.X) / 3, Y: (tri.A.Y + tri.B.Y + tri.C.Y) / 3}
+		assert.NotEmpty(t, bvh.QueryPoint(centroid.X, centroid.Y))
+	}
+}
+
+func TestTriangleBVH_Exact(t *testing.T) {
+	a, b, c := &Point{X: 0, Y: 0}, &Point{X: 4, Y: 0}, &Point{X: 0, Y: 4}
+	bvh := TriangleList{{a, b, c}}.BuildBVH()
+
+	// The rectangle's box overlaps the triangle's, but it's past the hypotenuse
+	assert.Empty(t, bvh.QueryRect(Rect{3, 3, 4, 4}))
+	assert.Equal(t, []int{0}, bvh.QueryRect(Rect{2, 2, 4, 4}))
+	// A rectangle inside the triangle touches none of its edges
+	assert.Equal(t, []int{0}, bvh.QueryRect(Rect{0.5, 0.5, 1, 1}))
+
+	assert.Equal(t, []int{0}, bvh.QueryPoint(2, 2))
+	assert.Empty(t, bvh.QueryPoint(2.1, 2.1))
+
+	assert.Empty(t, bvh.QuerySegment(Point{X: 3, Y: 4}, Point{X: 4, Y: 3}))
+	assert.Equal(t, []int{0}, bvh.QuerySegment(Point{X: 1, Y: 1}, Point{X: 1.5, Y: 1}))
+
+	assert.Empty(t, bvh.QueryCircle(3, 3, 1))
+	assert.Equal(t, []int{0}, bvh.QueryCircle(3, 3, 1.5))
+
+	assert.Empty(t, TriangleList{}.BuildBVH().QueryPoint(0, 0))
+}
+
+func TestTriangleBVH_Degenerate(t *testing.T) {
+	// Triangles all along a line, and all on top of each other, both keep the
+	// tree balanced
+	var line, stacked TriangleList
+	for i := 0; i < 4096; i++ {
+		x := float64(i)
+		line = append(line, &Triangle{&Point{X: x, Y: 0}, &Point{X: x + 1, Y: 0}, &Point{X: x + 0.5, Y: 0}})
+		stacked = append(stacked, &Triangle{&Point{X: 0, Y: 0}, &Point{X: 1, Y: 0}, &Point{X: 0, Y: 1}})
+	}
+	for _, triangles := range []TriangleList{line, stacked} {
+		bvh := triangles.BuildBVH()
+		assert.LessOrEqual(t, bvh.depth(), 11)
+	}
+
+	// A zero area triangle only contains the points along it
+	bvh := line.BuildBVH()
+	assert.Equal(t, []int{99, 100}, bvh.QueryPoint(100, 0))
+	assert.Empty(t, bvh.QueryPoint(100, 1e-9))
+	assert.Empty(t, bvh.QueryPoint(-0.5, 0))
+	checkBVHQueries(t, line, bvh, Rect{-10, -1, 4106, 1}, rand.New(rand.NewSource(2)))
+}
+
+func TestSelectNth(t *testing.T) {
+	random := rand.New(rand.NewSource(4))
+	less := func(a, b int) bool { return a < b }
+	for _, size := range []int{1, 2, 3, 10, 101} {
+		for n := 0; n < size; n++ {
+			values := random.Perm(size)
+			selectNth(values, n, less)
+			assert.Equal(t, n, values[n])
+			for i, value := range values {
+				assert.Equal(t, i < n, value < n, "%d of %d: %v", n, size, values)
+			}
+		}
+	}
+}
+
+func TestTriangleBVH_Refit(t *testing.T) {
+	triangles := PolygonList{*LoadFixture("spiral")}.Triangulate()
+	bvh := triangles.BuildBVH()
+
+	// Move every point a little, once each
+	random := rand.New(rand.NewSource(3))
+	moved := make(PointSet)
+	bounds := emptyBounds()
+	for _, tri := range triangles {
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			if !moved.Contains(p) {
+				moved.Add(p)
+				p.X += random.Float64() - 0.5
+				p.Y += random.Float64() - 0.5
+			}
+			bounds = bounds.including(p.X, p.Y)
+		}
+	}
+	bvh.Refit()
+	require.Equal(t, bounds, bvh.nodes[0].bounds)
+	checkBVHQueries(t, triangles, bvh, bounds, random)
+}
+
+// A grid of about 100k triangles
+func bvhBenchmarkTriangles() TriangleList {
+	const side = 224
+	var triangles TriangleList
+	for i := 0; i < side; i++ {
+		for j := 0; j < side; j++ {
+			x, y := float64(i), float64(j)
+			a, b := &Point{X: x, Y: y}, &Point{X: x + 1, Y: y}
+			c, d := &Point{X: x + 1, Y: y + 1}, &Point{X: x, Y: y + 1}
+			triangles = append(triangles, &Triangle{a, b, c}, &Triangle{a, c, d})
+		}
+	}
+	return triangles
+}
+
+func benchmarkRectQueries(b *testing.B, query func(r Rect) []int) {
+	random := rand.New(rand.NewSource(0))
+	rects := make([]Rect, 1024)
+	for i := range rects {
+		x, y := random.Float64()*220, random.Float64()*220
+		rects[i] = Rect{x, y, x + 4, y + 4}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query(rects[i%len(rects)])
+	}
+}
+
+func BenchmarkTriangleBVH_QueryRect(b *testing.B) {
+	bvh := bvhBenchmarkTriangles().BuildBVH()
+	benchmarkRectQueries(b, bvh.QueryRect)
+}
+
+// The same queries without the tree, for comparison
+func BenchmarkTriangleBVH_BruteForceRect(b *testing.B) {
+	triangles := bvhBenchmarkTriangles()
+	benchmarkRectQueries(b, func(r Rect) []int {
+		return bruteForceQuery(triangles, func(tri *Triangle) bool {
+			return triangleTouchesRect(tri, r)
+		})
+	})
+}
+
+func BenchmarkTriangleBVH_Build(b *testing.B) {
+	triangles := bvhBenchmarkTriangles()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		triangles.BuildBVH()
+	}
+}
@@ -0,0 +1,63 @@
+package advanced
+
+// Points are identified by pointer throughout the triangulation, but callers
+// sometimes have several pointers with the same coordinates. This maps each set
+// of coordinates to a single canonical pointer: the first one seen in the
+// input. Only the coordinates count, so the keys never have a UserIndex.
+type canonicalPoints map[Point]*Point
+
+// The key for a point's coordinates, ignoring its UserIndex
+func coordinatesOf(p *Point) Point {
+	return Point{X: p.X, Y: p.Y}
+}
+
+// Build the canonical point map for a list, and return a working copy of the
+// list which uses only canonical points. The input list is not modified. This
+// throws a DuplicatePointError if the same coordinates appear twice in one
+// ring.
+func canonicalizePolygons(list PolygonList) (PolygonList, canonicalPoints) {
+	canonical := make(canonicalPoints)
+	result := make(PolygonList, len(list))
+	for i, poly := range list {
+		seen := make(map[Point]int, len(poly.Points))
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			key := coordinatesOf(p)
+			if first, ok := seen[key]; ok {
+				throw(&DuplicatePointError{Polygon: i, First: first, Second: j, Point: *p})
+			}
+			seen[key] = j
+
+			if existing, ok := canonical[key]; ok {
+				points[j] = existing
+			} else {
+				canonical[key] = p
+				points[j] = p
+			}
+		}
+		result[i] = Polygon{Points: points}
+	}
+	return result, canonical
+}
+
+// Rewrite every triangle vertex to its canonical pointer. Points with no
+// canonical version were not in the input, so they are left alone, but reported
+// to the diagnostics.
+func (canonical canonicalPoints) apply(triangles TriangleList, diagnostics *Diagnostics) {
+	canonical.applyReported(triangles, make(PointSet), diagnostics)
+}
+
+// Like apply, but skipping points already in reported, and adding those it
+// reports, so that triangles applied in batches report each point once
+func (canonical canonicalPoints) applyReported(triangles TriangleList, reported PointSet, diagnostics *Diagnostics) {
+	for _, tri := range triangles {
+		for _, vertex := range []**Point{&tri.A, &tri.B, &tri.C} {
+			if replacement, ok := canonical[coordinatesOf(*vertex)]; ok {
+				*vertex = replacement
+			} else if !reported.Contains(*vertex) {
+				reported.Add(*vertex)
+				diagnostics.warnf(WarningFabricatedPoint, "output point %v is not an input point", *vertex)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeOutputPoints(t *testing.T) {
+	// Two squares touching at a corner, each with its own pointer for the shared
+	// corner
+	firstCorner := &Point{X: 1, Y: 1}
+	secondCorner := &Point{X: 1, Y: 1}
+	list := PolygonList{
+		{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, firstCorner, {X: 0, Y: 1}}},
+		{[]*Point{secondCorner, {X: 2, Y: 1}, {X: 2, Y: 2}, {X: 1, Y: 2}}},
+	}
+
+	diagnostics := &Diagnostics{}
+	triangles := list.TriangulateWithOptions(Options{
+		CanonicalizeOutputPoints: true,
+		Diagnostics:              diagnostics,
+	})
+	require.Len(t, triangles, 4)
+	assert.Empty(t, diagnostics.Warnings)
+
+	var area float64
+	var cornerReferences int
+	for _, tri := range triangles {
+		area += Area(tri)
+		for _, vertex := range []*Point{tri.A, tri.B, tri.C} {
+			assert.NotSame(t, secondCorner, vertex)
+			if *vertex == *firstCorner {
+				assert.Same(t, firstCorner, vertex)
+				cornerReferences++
+			}
+		}
+	}
+	assert.InDelta(t, 2, area, Epsilon)
+	assert.Greater(t, cornerReferences, 0)
+
+	// The input is untouched
+	assert.Same(t, secondCorner, list[1].Points[0])
+}
+
+func TestCanonicalizeOutputPoints_DuplicateInRing(t *testing.T) {
+	list := PolygonList{
+		{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 0}, {X: 0, Y: 1}}},
+	}
+	err := func() (err error) {
+		defer func() {
+			err = HandleTriangulatePanicRecover(recover())
+		}()
+		list.TriangulateWithOptions(Options{CanonicalizeOutputPoints: true})
+		return nil
+	}()
+
+	var duplicateErr *DuplicatePointError
+	require.ErrorAs(t, err, &duplicateErr)
+	assert.Equal(t, 0, duplicateErr.Polygon)
+	assert.Equal(t, 0, duplicateErr.First)
+	assert.Equal(t, 3, duplicateErr.Second)
+	assert.Equal(t, Point{X: 0, Y: 0}, duplicateErr.Point)
+}
+
+func TestCanonicalPoints_ReportsFabricatedPoints(t *testing.T) {
+	input := &Point{X: 0, Y: 0}
+	_, canonical := canonicalizePolygons(PolygonList{{[]*Point{input, {X: 1, Y: 0}, {X: 0, Y: 1}}}})
+
+	fabricated := &Point{X: 5, Y: 5}
+	duplicate := &Point{X: 0, Y: 0}
+	triangles := TriangleList{{duplicate, &Point{X: 1, Y: 0}, fabricated}}
+	diagnostics := &Diagnostics{}
+	canonical.apply(triangles, diagnostics)
+
+	assert.Same(t, input, triangles[0].A)
+	assert.Same(t, fabricated, triangles[0].C)
+	require.Len(t, diagnostics.Warnings, 1)
+	assert.Equal(t, WarningFabricatedPoint, diagnostics.Warnings[0].Kind)
+}
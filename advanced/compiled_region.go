@@ -0,0 +1,245 @@
+package advanced
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// A compiled region is the inside of a trapezoid map, flattened into a slab
+// decomposition for point-in-polygon queries at runtime. Every distinct Y
+// value of a vertex is a slab boundary, and within each slab, the segments
+// crossing it never cross each other, so they can be kept in order from left
+// to right. A point is inside if an odd number of them pass to its left.
+//
+// Queries are two binary searches over flat arrays, with no pointers to chase
+// and no allocation. The cost is memory: a segment is stored once for every
+// slab it crosses, which can be quadratic in the worst case, though for most
+// shapes it's a small multiple of the number of segments.
+
+// A segment where it crosses a slab, by its X at the slab's bottom and top.
+// Storing the ends, rather than a line equation, keeps the precision of
+// coordinates far from the origin.
+type slabCrossing struct {
+	bottom, top float64
+}
+
+// A point-in-polygon structure compiled from a QueryGraph. See CompileRegion.
+// The zero value is an empty region.
+type CompiledRegion struct {
+	// The slab boundaries, ascending. Slab i runs from ys[i] to ys[i+1].
+	ys []float64
+	// Slab i's crossings are crossings[starts[i]:starts[i+1]], ordered from
+	// left to right
+	starts    []uint32
+	crossings []slabCrossing
+}
+
+// Compile the inside of a complete graph into a CompiledRegion. The graph is
+// not modified, and may be discarded afterward. A graph whose rings aren't
+// closed compiles to an empty region, matching ContainsPoint.
+func CompileRegion(g *QueryGraph) CompiledRegion {
+	if g.Root == nil || !g.RingsClosed() {
+		return CompiledRegion{}
+	}
+
+	// Horizontal segments don't cross any slab, and shared edges have the
+	// inside on both sides, so they can be left out
+	var segments []*Segment
+	seen := make(map[*Segment]struct{})
+	for node := range g.IterateGraph() {
+		xnode, ok := node.Inner.(XNode)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[xnode.Key]; ok {
+			continue
+		}
+		seen[xnode.Key] = struct{}{}
+		if xnode.Key.Start.Y != xnode.Key.End.Y && !g.isShared(xnode.Key) {
+			segments = append(segments, xnode.Key)
+		}
+	}
+
+	var ys []float64
+	for _, segment := range segments {
+		ys = append(ys, segment.Start.Y, segment.End.Y)
+	}
+	sort.Float64s(ys)
+	distinct := ys[:0]
+	for i, y := range ys {
+		if i == 0 || y != ys[i-1] {
+			distinct = append(distinct, y)
+		}
+	}
+	ys = distinct
+	if len(ys) < 2 {
+		return CompiledRegion{}
+	}
+
+	// Gather each slab's crossings, with the X at its middle for sorting
+	type sortableCrossing struct {
+		slabCrossing
+		middle float64
+	}
+	slabs := make([][]sortableCrossing, len(ys)-1)
+	for _, segment := range segments {
+		bottom, top := segment.Start, segment.End
+		if top.Y < bottom.Y {
+			bottom, top = top, bottom
+		}
+		xAt := func(y float64) float64 {
+			t := (y - bottom.Y) / (top.Y - bottom.Y)
+			return bottom.X + t*(top.X-bottom.X)
+		}
+		first := sort.SearchFloat64s(ys, bottom.Y)
+		last := sort.SearchFloat64s(ys, top.Y)
+		for i := first; i < last; i++ {
+			crossing := slabCrossing{xAt(ys[i]), xAt(ys[i+1])}
+			// The segment's own end points are exact
+			if i == first {
+				crossing.bottom = bottom.X
+			}
+			if i == last-1 {
+				crossing.top = top.X
+			}
+			slabs[i] = append(slabs[i], sortableCrossing{crossing, crossing.bottom/2 + crossing.top/2})
+		}
+	}
+
+	region := CompiledRegion{ys: ys, starts: make([]uint32, 0, len(ys))}
+	for _, slab := range slabs {
+		sort.Slice(slab, func(i, j int) bool {
+			return slab[i].middle < slab[j].middle
+		})
+		region.starts = append(region.starts, uint32(len(region.crossings)))
+		for _, crossing := range slab {
+			region.crossings = append(region.crossings, crossing.slabCrossing)
+		}
+	}
+	region.starts = append(region.starts, uint32(len(region.crossings)))
+	return region
+}
+
+// Is the point inside the region? As with QueryGraph.ContainsPoint, the
+// answer is not defined for points exactly on an edge, but points which only
+// share a Y value with a vertex are classified correctly.
+func (r CompiledRegion) Contains(x, y float64) bool {
+	if len(r.ys) < 2 || !(y >= r.ys[0] && y < r.ys[len(r.ys)-1]) {
+		return false
+	}
+	// The slab whose bottom is the last boundary at or below y. Taking the
+	// boundary with the slab above it counts each segment from its bottom up to
+	// just below its top, which is what counting crossings needs at a vertex.
+	slab := sort.Search(len(r.ys), func(i int) bool { return r.ys[i] > y }) - 1
+	bottom, top := r.ys[slab], r.ys[slab+1]
+	t := (y - bottom) / (top - bottom)
+	crossings := r.crossings[r.starts[slab]:r.starts[slab+1]]
+	left := sort.Search(len(crossings), func(i int) bool {
+		c := crossings[i]
+		return c.bottom+float64(t*(c.top-c.bottom)) >= x
+	})
+	return left%2 == 1
+}
+
+// The memory the region's arrays take, in bytes
+func (r CompiledRegion) Footprint() int {
+	return 8*len(r.ys) + 4*len(r.starts) + 16*len(r.crossings)
+}
+
+// Binary layout: a magic number and version, the counts of slab boundaries and
+// crossings, then the boundaries, the slab starts, and the crossings, all
+// little endian.
+const (
+	compiledRegionMagic   = "TRGN"
+	compiledRegionVersion = 1
+	compiledRegionHeader  = len(compiledRegionMagic) + 1 + 4 + 4
+)
+
+// Encode the region in a compact binary form, for building offline and
+// loading at runtime with UnmarshalBinary
+func (r CompiledRegion) MarshalBinary() ([]byte, error) {
+	data := make([]byte, compiledRegionHeader+r.Footprint())
+	copy(data, compiledRegionMagic)
+	data[len(compiledRegionMagic)] = compiledRegionVersion
+	rest := data[len(compiledRegionMagic)+1:]
+	put32 := func(value uint32) {
+		binary.LittleEndian.PutUint32(rest, value)
+		rest = rest[4:]
+	}
+	put64 := func(value float64) {
+		binary.LittleEndian.PutUint64(rest, math.Float64bits(value))
+		rest = rest[8:]
+	}
+	put32(uint32(len(r.ys)))
+	put32(uint32(len(r.crossings)))
+	for _, y := range r.ys {
+		put64(y)
+	}
+	for _, start := range r.starts {
+		put32(start)
+	}
+	for _, c := range r.crossings {
+		put64(c.bottom)
+		put64(c.top)
+	}
+	return data, nil
+}
+
+// Decode a region encoded by MarshalBinary, checking that it is consistent, so
+// that a corrupt encoding can't cause a panic in Contains
+func (r *CompiledRegion) UnmarshalBinary(data []byte) error {
+	if len(data) < compiledRegionHeader || string(data[:len(compiledRegionMagic)]) != compiledRegionMagic {
+		return errors.New("not a compiled region")
+	}
+	if version := data[len(compiledRegionMagic)]; version != compiledRegionVersion {
+		return errors.Errorf("unsupported compiled region version %d", version)
+	}
+	data = data[len(compiledRegionMagic)+1:]
+	boundaryCount := uint64(binary.LittleEndian.Uint32(data))
+	crossingCount := uint64(binary.LittleEndian.Uint32(data[4:]))
+	data = data[8:]
+	if want := 12*boundaryCount + 16*crossingCount; uint64(len(data)) != want {
+		return errors.Errorf("compiled region should have %d bytes of data, but has %d", want, len(data))
+	}
+	if boundaryCount == 1 {
+		return errors.New("compiled region has a single slab boundary")
+	}
+
+	decoded := CompiledRegion{
+		ys:        make([]float64, boundaryCount),
+		starts:    make([]uint32, boundaryCount),
+		crossings: make([]slabCrossing, crossingCount),
+	}
+	next := func() uint64 {
+		value := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		return value
+	}
+	for i := range decoded.ys {
+		decoded.ys[i] = math.Float64frombits(next())
+		if math.IsNaN(decoded.ys[i]) || (i > 0 && !(decoded.ys[i] > decoded.ys[i-1])) {
+			return errors.Errorf("compiled region slab boundary %d is out of order", i)
+		}
+	}
+	for i := range decoded.starts {
+		decoded.starts[i] = binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		if (i > 0 && decoded.starts[i] < decoded.starts[i-1]) || uint64(decoded.starts[i]) > crossingCount {
+			return errors.Errorf("compiled region slab start %d is out of range", i)
+		}
+	}
+	if boundaryCount > 0 && (decoded.starts[0] != 0 || uint64(decoded.starts[boundaryCount-1]) != crossingCount) {
+		return errors.New("compiled region slabs don't cover the crossings")
+	}
+	for i := range decoded.crossings {
+		decoded.crossings[i] = slabCrossing{math.Float64frombits(next()), math.Float64frombits(next())}
+	}
+	if boundaryCount == 0 {
+		decoded = CompiledRegion{}
+	}
+	*r = decoded
+	return nil
+}
@@ -0,0 +1,183 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compileList(list PolygonList) (*QueryGraph, CompiledRegion) {
+	graph := &QueryGraph{}
+	graph.AddPolygons(list)
+	return graph, CompileRegion(graph)
+}
+
+// Points on a grid over the list's padded bounding box, plus points on every
+// vertex's Y, where the slab boundaries are. Points on or very near an edge
+// are left out, since neither answer is defined there.
+func regionSamples(list PolygonList) []*Point {
+	bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, poly := range list {
+		for _, p := range poly.Points {
+			bounds.MinX, bounds.MinY = math.Min(bounds.MinX, p.X), math.Min(bounds.MinY, p.Y)
+			bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, p.X), math.Max(bounds.MaxY, p.Y)
+		}
+	}
+	size := math.Max(bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY)
+	const steps = 80
+	step := 1.2 * size / steps
+	var candidates []*Point
+	for i := 0; i <= steps; i++ {
+		for j := 0; j <= steps; j++ {
+			candidates = append(candidates, &Point{
+				X: bounds.MinX - 0.1*size + float64(i)*step,
+				Y: bounds.MinY - 0.1*size + float64(j)*step,
+			})
+		}
+	}
+	for _, poly := range list {
+		for _, p := range poly.Points {
+			for _, dx := range []float64{-0.3, -0.01, 0.01, 0.3} {
+				candidates = append(candidates, &Point{X: p.X + dx*size, Y: p.Y})
+			}
+		}
+	}
+
+	var samples []*Point
+	for _, p := range candidates {
+		if boundaryDistance(list, p) > 1e-6*size {
+			samples = append(samples, p)
+		}
+	}
+	return samples
+}
+
+func TestCompileRegion_Fixtures(t *testing.T) {
+	for name, load := range allFixtures() {
+		t.Run(name, func(t *testing.T) {
+			list := load()
+			graph, region := compileList(list)
+			require.NotEmpty(t, region.crossings)
+			for _, p := range regionSamples(list) {
+				assert.Equal(t, graph.ContainsPoint(p), region.Contains(p.X, p.Y), "%v", p)
+			}
+		})
+	}
+}
+
+func TestCompileRegion_Binary(t *testing.T) {
+	list := MultiLayeredHoles()
+	_, region := compileList(list)
+	data, err := region.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded CompiledRegion
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, region, decoded)
+	for _, p := range regionSamples(list) {
+		assert.Equal(t, region.Contains(p.X, p.Y), decoded.Contains(p.X, p.Y))
+	}
+
+	// The empty region round trips too
+	data, err = CompiledRegion{}.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.False(t, decoded.Contains(0, 0))
+
+	// Corrupt encodings are rejected, rather than panicking later
+	data, err = region.MarshalBinary()
+	require.NoError(t, err)
+	corrupt := func(change func(data []byte) []byte) error {
+		copied := append([]byte(nil), data...)
+		var decoded CompiledRegion
+		return decoded.UnmarshalBinary(change(copied))
+	}
+	assert.Error(t, corrupt(func(d []byte) []byte { return d[:len(d)-1] }))
+	assert.Error(t, corrupt(func(d []byte) []byte { return d[:3] }))
+	assert.Error(t, corrupt(func(d []byte) []byte { d[0] = 'X'; return d }))
+	assert.Error(t, corrupt(func(d []byte) []byte { d[4] = 2; return d }))
+	// The last slab start, which must equal the crossing count
+	startsEnd := compiledRegionHeader + 12*len(region.ys)
+	assert.Error(t, corrupt(func(d []byte) []byte { d[startsEnd-4]++; return d }))
+	// The first slab boundary, which must be below the second
+	assert.Error(t, corrupt(func(d []byte) []byte {
+		copy(d[compiledRegionHeader:], d[compiledRegionHeader+8:compiledRegionHeader+16])
+		return d
+	}))
+}
+
+func TestCompileRegion_Empty(t *testing.T) {
+	assert.False(t, CompileRegion(&QueryGraph{}).Contains(0, 0))
+
+	// An open ring has no inside, as with ContainsPoint
+	graph := &QueryGraph{}
+	square := squareRing(0, 0, 1).Points
+	graph.AddSegment(NewSegment(square[0], square[1]))
+	graph.AddSegment(NewSegment(square[1], square[2]))
+	graph.AddSegment(NewSegment(square[2], square[3]))
+	region := CompileRegion(graph)
+	assert.Empty(t, region.crossings)
+	assert.False(t, region.Contains(0.5, 0.5))
+}
+
+func TestCompileRegion_Footprint(t *testing.T) {
+	_, region := compileList(PolygonList{circlePolygon(10, 1000)})
+	// A circle crosses each slab twice
+	assert.Len(t, region.crossings, 2*(len(region.ys)-1))
+	assert.LessOrEqual(t, region.Footprint(), 32*len(region.crossings))
+
+	data, err := region.MarshalBinary()
+	require.NoError(t, err)
+	assert.Len(t, data, compiledRegionHeader+region.Footprint())
+}
+
+func regionBenchmarkPoints() []*Point {
+	rng := rand.New(rand.NewSource(0))
+	points := make([]*Point, 1024)
+	for i := range points {
+		points[i] = &Point{X: rng.Float64()*240 - 120, Y: rng.Float64()*240 - 120}
+	}
+	return points
+}
+
+// Bytes allocated while building something
+func allocatedBytes(build func()) float64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	build()
+	runtime.ReadMemStats(&after)
+	return float64(after.TotalAlloc - before.TotalAlloc)
+}
+
+func BenchmarkCompiledRegion_Contains(b *testing.B) {
+	list := PolygonList{circlePolygon(100, 10000)}
+	var region CompiledRegion
+	buildBytes := allocatedBytes(func() { _, region = compileList(list) })
+	points := regionBenchmarkPoints()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		region.Contains(p.X, p.Y)
+	}
+	b.ReportMetric(float64(region.Footprint()), "region-bytes")
+	b.ReportMetric(buildBytes, "build-bytes")
+}
+
+func BenchmarkCompiledRegion_GraphContainsPoint(b *testing.B) {
+	list := PolygonList{circlePolygon(100, 10000)}
+	g := &QueryGraph{}
+	graphBytes := allocatedBytes(func() { g.AddPolygons(list) })
+	points := regionBenchmarkPoints()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.ContainsPoint(points[i%len(points)])
+	}
+	b.ReportMetric(graphBytes, "graph-bytes")
+}
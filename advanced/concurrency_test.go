@@ -0,0 +1,210 @@
+package advanced
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/fogleman/gg"
+	"github.com/osuushi/triangulate/advanced/dbg"
+	"github.com/stretchr/testify/assert"
+)
+
+// What is safe to use concurrently, with a test for each case. These are only
+// meaningful under the race detector (go test -race), which is what checks
+// that nothing is shared unsynchronized.
+//
+//   - Triangulating different inputs on different goroutines, with any options
+//   - Reading one polygon list from many goroutines, including triangulating it,
+//     since triangulation never modifies its input
+//   - Queries on a complete graph from many goroutines, as long as nothing
+//     modifies it meanwhile. A QueryGraph or Session is never safe to modify
+//     from more than one goroutine at a time, but separate ones are independent
+//   - Debug names and drawing, which error paths may reach on any goroutine
+//
+// A Triangulator is not safe for concurrent use, but a TriangulatorPool is (see
+// pool_test.go).
+
+// Set by race_test.go when the race detector is on, which makes everything
+// run many times slower
+var raceEnabled = false
+
+// Run each function on its own goroutine, and wait for them all
+func runConcurrently(functions ...func()) {
+	var wg sync.WaitGroup
+	for _, f := range functions {
+		wg.Add(1)
+		go func(f func()) {
+			defer wg.Done()
+			f()
+		}(f)
+	}
+	wg.Wait()
+}
+
+func TestConcurrency_TriangulateFixtures(t *testing.T) {
+	optionSets := []Options{
+		{},
+		{AutoNormalize: true},
+		{SweepAxis: XAxis},
+		{CanonicalizeOutputPoints: true, SortOutput: Spatial},
+		{Decomposition: Mountains, MergeCollinearEdges: true},
+	}
+	fixtures := allFixtures()
+	type job struct {
+		name string
+		opts int
+	}
+	var jobs []job
+	expected := make(map[job][]string)
+	for name, load := range fixtures {
+		for i, opts := range optionSets {
+			j := job{name, i}
+			jobs = append(jobs, j)
+			expected[j] = triangleCoordinates(load().TriangulateWithOptions(opts))
+		}
+	}
+
+	// Every job twice, so that the same input is also triangulated concurrently
+	results := make([][]string, 2*len(jobs))
+	var functions []func()
+	for i := range results {
+		i := i
+		functions = append(functions, func() {
+			j := jobs[i%len(jobs)]
+			opts := optionSets[j.opts]
+			opts.Diagnostics = &Diagnostics{}
+			results[i] = triangleCoordinates(fixtures[j.name]().TriangulateWithOptions(opts))
+		})
+	}
+	runConcurrently(functions...)
+
+	for i, result := range results {
+		j := jobs[i%len(jobs)]
+		assert.Equal(t, expected[j], result, "%s %d", j.name, j.opts)
+	}
+}
+
+func TestConcurrency_SharedInput(t *testing.T) {
+	list := MultiLayeredHoles()
+	before := fmt.Sprint(list)
+	expected := triangleCoordinates(list.Triangulate())
+	samples := regionSamples(list)
+
+	var functions []func()
+	for i := 0; i < 4; i++ {
+		functions = append(functions,
+			func() {
+				assert.Equal(t, expected, triangleCoordinates(list.Triangulate()))
+			},
+			func() {
+				assert.Equal(t, expected, triangleCoordinates(list.TriangulateWithOptions(Options{AutoNormalize: true, SweepAxis: AutoAxis})))
+			},
+			func() {
+				for _, p := range samples {
+					list.ContainsPointByEvenOdd(p)
+				}
+			},
+			func() {
+				list.ContainingRings()
+				EstimateCost(list)
+				_, err := list.Smooth(1, 1)
+				assert.NoError(t, err)
+				_, err = list.LabelPoint(1e-2)
+				assert.NoError(t, err)
+			},
+		)
+	}
+	runConcurrently(functions...)
+
+	assert.Equal(t, before, fmt.Sprint(list), "the input was modified")
+}
+
+func TestConcurrency_FrozenGraph(t *testing.T) {
+	list := MultiLayeredHoles()
+	graph := &QueryGraph{}
+	graph.AddPolygons(list)
+	samples := regionSamples(list)
+	expected := make([]bool, len(samples))
+	for i, p := range samples {
+		expected[i] = list.ContainsPointByEvenOdd(p)
+	}
+
+	// The bounding box isn't computed until the first query, so the queries
+	// race to compute it
+	var functions []func()
+	for i := 0; i < 8; i++ {
+		functions = append(functions,
+			func() {
+				for i, p := range samples {
+					assert.Equal(t, expected[i], graph.ContainsPoint(p), "%v", p)
+				}
+			},
+			func() {
+				assert.True(t, graph.RingsClosed())
+				assert.NotZero(t, graph.QueryDepth().Queries)
+				assert.NotEmpty(t, graph.ExportSegments())
+				region := CompileRegion(graph)
+				for i, p := range samples {
+					assert.Equal(t, expected[i], region.Contains(p.X, p.Y), "%v", p)
+				}
+			},
+			func() {
+				// Trapezoid strings name everything through the shared name memo
+				for trapezoid := range graph.IterateTrapezoids() {
+					assert.NotEmpty(t, trapezoid.String())
+				}
+			},
+		)
+	}
+	runConcurrently(functions...)
+}
+
+func TestConcurrency_SeparateGraphs(t *testing.T) {
+	fixtures := allFixtures()
+	var functions []func()
+	for name, load := range fixtures {
+		name, load := name, load
+		functions = append(functions,
+			func() {
+				graph := &QueryGraph{}
+				graph.AddPolygons(load())
+				assert.True(t, graph.RingsClosed(), name)
+			},
+			func() {
+				session := NewSession()
+				for _, poly := range load() {
+					_, err := session.AddPolygon(poly.Points)
+					assert.NoError(t, err, name)
+				}
+				triangles, err := session.Finalize()
+				assert.NoError(t, err, name)
+				assert.NotEmpty(t, triangles, name)
+			},
+		)
+	}
+	runConcurrently(functions...)
+}
+
+func TestConcurrency_Debugging(t *testing.T) {
+	graph := &QueryGraph{}
+	graph.AddPolygons(SquareWithHole())
+	shared := &Point{X: 1, Y: 2}
+	names := make([]string, 16)
+
+	var functions []func()
+	for i := range names {
+		i := i
+		functions = append(functions, func() {
+			names[i] = dbg.Name(shared)
+			dbg.Name(&Point{X: float64(i)})
+			// Each drawing carries its own inverse transformation
+			graph.draw(&drawContext{gg.NewContext(64, 64), gg.Identity().Scale(float64(i+1), 1)})
+		})
+	}
+	runConcurrently(functions...)
+
+	for _, name := range names {
+		assert.Equal(t, names[0], name)
+	}
+}
@@ -0,0 +1,87 @@
+package advanced
+
+import "math"
+
+// A decision is marginal when the difference it hinges on is within this many
+// Epsilons of the Epsilon threshold, or is a tie which only the tolerance or
+// the lexicographic convention settled
+const marginalFactor = 10
+
+// How close the decisions which shaped a triangulation came to going the other
+// way. The decisions covered are the ones the output depends on: locating each
+// segment endpoint in the trapezoid map, walking the map along each segment,
+// and ordering the points of each monotone piece. Point queries made on a
+// finished map aren't counted.
+//
+// Each decision compares a difference of coordinates against Epsilon, such as
+// two Y values to see whether they're level, or a point's X against where a
+// segment passes at the same height. Its margin is how far that difference was
+// from Epsilon. With no marginal decisions, every one of them was decided by
+// more than marginalFactor·Epsilon, so the result doesn't depend on the
+// tolerance or on rounding. Ties count as marginal, since they rely on the
+// tolerance and the lexicographic convention to be settled, so grid aligned
+// input always reports some.
+//
+// Coordinates are those of the working copy of the input, so they're in the
+// swept space when Options.SweepAxis or Options.AutoNormalize changes it.
+type Confidence struct {
+	// Number of marginal decisions
+	MarginalDecisions int
+	// The smallest margin of any decision, if any were marginal
+	MinMargin float64
+	// The points whose coordinates the decision with the smallest margin
+	// compared: two points, or a segment's ends and a point. Points made up
+	// along the way, such as where a segment crosses a trapezoid's bottom, are
+	// included as they were.
+	MinMarginPoints []Point
+}
+
+// Whether any decision was marginal
+func (c Confidence) Marginal() bool {
+	return c.MarginalDecisions > 0
+}
+
+// Records marginal decisions for the Confidence of one triangulation, and the
+// ones settled with exact arithmetic for Diagnostics.ExactEvaluations. Each map
+// and each triangulation has its own, so nothing is shared between
+// triangulations running at once. A nil tracker records nothing.
+type marginTracker struct {
+	count     int
+	minMargin float64
+	points    [3]*Point
+
+	exactEvaluations int
+	// Set from Options.DisableExactFallback
+	inexact bool
+}
+
+// Note a decision which hinges on diff, comparing the coordinates of the given
+// points. c may be nil.
+func (m *marginTracker) observe(diff float64, a, b, c *Point) {
+	if m != nil && math.Abs(diff) <= (marginalFactor+1)*Epsilon {
+		m.record(diff, a, b, c)
+	}
+}
+
+func (m *marginTracker) record(diff float64, a, b, c *Point) {
+	margin := math.Abs(math.Abs(diff) - Epsilon)
+	if m.count == 0 || margin < m.minMargin {
+		m.minMargin = margin
+		m.points = [3]*Point{a, b, c}
+	}
+	m.count++
+}
+
+func (m *marginTracker) confidence() Confidence {
+	c := Confidence{MarginalDecisions: m.count}
+	if m.count == 0 {
+		return c
+	}
+	c.MinMargin = m.minMargin
+	for _, p := range m.points {
+		if p != nil {
+			c.MinMarginPoints = append(c.MinMarginPoints, *p)
+		}
+	}
+	return c
+}
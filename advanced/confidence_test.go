@@ -0,0 +1,82 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced/corpus"
+	"github.com/stretchr/testify/assert"
+)
+
+func confidenceOf(list PolygonList, opts Options) Confidence {
+	var diagnostics Diagnostics
+	opts.Diagnostics = &diagnostics
+	list.TriangulateWithOptions(opts)
+	return diagnostics.Confidence
+}
+
+func TestConfidence_WellSeparated(t *testing.T) {
+	// No two points share an X or a Y, and no point is near an edge it isn't on
+	solid := Polygon{[]*Point{{X: 0, Y: 0.3}, {X: 10.1, Y: 1.7}, {X: 8.9, Y: 9.4}, {X: 5.2, Y: 6.1}, {X: 1.3, Y: 8.6}}}
+	hole := Polygon{[]*Point{{X: 4.1, Y: 2.2}, {X: 3.6, Y: 4.3}, {X: 6.7, Y: 3.9}}}
+	for name, list := range map[string]PolygonList{
+		"lone monotone": {{[]*Point{{X: 0, Y: 0.3}, {X: 10.1, Y: 1.7}, {X: 8.9, Y: 9.4}, {X: 1.3, Y: 8.6}}}},
+		"with hole":     {solid, hole},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, opts := range []Options{{}, {Decomposition: Mountains}} {
+				confidence := confidenceOf(list, opts)
+				assert.False(t, confidence.Marginal())
+				assert.Equal(t, Confidence{}, confidence)
+			}
+		})
+	}
+}
+
+func TestConfidence_Marginal(t *testing.T) {
+	for _, name := range []string{"near degenerate annulus", "degenerate quad"} {
+		t.Run(name, func(t *testing.T) {
+			confidence := confidenceOf(corpusShape(corpus.Lookup(name)), Options{})
+			assert.True(t, confidence.Marginal())
+			assert.GreaterOrEqual(t, confidence.MinMargin, 0.0)
+			assert.LessOrEqual(t, confidence.MinMargin, marginalFactor*Epsilon)
+			assert.GreaterOrEqual(t, len(confidence.MinMarginPoints), 2)
+		})
+	}
+}
+
+// The margin is how far the difference was from Epsilon, so a difference just
+// past it has a smaller margin than a tie
+func TestConfidence_MinMargin(t *testing.T) {
+	list := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 4, Y: 1.5 * Epsilon}, {X: 3, Y: 2}, {X: 1, Y: 3}}}}
+	confidence := confidenceOf(list, Options{})
+	assert.True(t, confidence.Marginal())
+	assert.InDelta(t, 0.5*Epsilon, confidence.MinMargin, 1e-15)
+	assert.ElementsMatch(t, []Point{{X: 0, Y: 0}, {X: 4, Y: 1.5 * Epsilon}}, confidence.MinMarginPoints)
+}
+
+// Each triangulation counts only its own decisions
+func TestConfidence_Concurrent(t *testing.T) {
+	quad := corpusShape(corpus.Lookup("degenerate quad"))
+	expected := confidenceOf(quad, Options{})
+	separated := PolygonList{{[]*Point{{X: 0, Y: 0.3}, {X: 10.1, Y: 1.7}, {X: 8.9, Y: 9.4}, {X: 5.2, Y: 6.1}, {X: 1.3, Y: 8.6}}}}
+	results := make([]Confidence, 8)
+	var functions []func()
+	for i := range results {
+		i := i
+		functions = append(functions, func() {
+			if i%2 == 0 {
+				results[i] = confidenceOf(quad, Options{})
+			} else {
+				results[i] = confidenceOf(separated, Options{})
+			}
+		})
+	}
+	runConcurrently(functions...)
+	for i, result := range results {
+		if i%2 == 0 {
+			assert.Equal(t, expected, result)
+		} else {
+			assert.Equal(t, Confidence{}, result)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package corpus is a registry of the shapes used to test and benchmark
+// triangulation: the SVG fixtures, the ad hoc shapes which the tests build in
+// code, and inputs from bug reports, each tagged with the properties which
+// matter to the triangulator.
+//
+// Shapes are given as plain coordinates, so that this package doesn't depend on
+// the advanced package. That lets the advanced package's own tests use it,
+// which they couldn't if it imported them. See testutil.Corpus for the same
+// registry as polygon lists.
+package corpus
+
+import (
+	"embed"
+	"fmt"
+	"sync"
+)
+
+//go:embed *.svg *.txt
+var files embed.FS
+
+// The coordinates of a polygon's vertices, in order
+type Ring [][2]float64
+
+// Properties of a shape which the triangulator cares about
+type Tags struct {
+	// Some rings are holes, running clockwise
+	HasHoles bool
+	// The shape is a single ring which is monotone in Y, so that it has one
+	// bottom and one top vertex. This uses the triangulator's lexicographic
+	// ordering, under which a grid-aligned staircase isn't monotone, since each
+	// step's horizontal edge leads down to a new bottom.
+	IsMonotone bool
+	// Every coordinate is an integer, so there are many shared X and Y values
+	GridAligned bool
+	// Large enough to be slow, for benchmarks rather than quick tests
+	Huge bool
+}
+
+// A shape in the corpus. Rings run counterclockwise for solids and clockwise
+// for holes, and must not be modified, since they are shared by every caller.
+type Entry struct {
+	Name string
+	Tags
+	Rings []Ring
+	// An input recorded from a bug report, in the point log format, kept as
+	// text since the exact text is the record. When this is set, Rings is
+	// empty.
+	Log string
+}
+
+var (
+	entries     []Entry
+	entriesOnce sync.Once
+)
+
+// Every shape in the corpus, in a fixed order. The SVG fixtures are loaded the
+// first time this is called.
+func Entries() []Entry {
+	entriesOnce.Do(func() {
+		entries = []Entry{
+			{Name: "simple star", Rings: []Ring{star(0, 0, 5, 2)}},
+			{Name: "square with hole", Tags: Tags{HasHoles: true, GridAligned: true}, Rings: []Ring{
+				{{-5, -5}, {5, -5}, {5, 5}, {-5, 5}},
+				{{-2, -2}, {-2, 2}, {2, 2}, {2, -2}},
+			}},
+			{Name: "star outline", Tags: Tags{HasHoles: true}, Rings: []Ring{
+				star(0, 0, 10, 5),
+				star(0, 0, 8, 3).reverse(),
+			}},
+			{Name: "star stripes", Tags: Tags{HasHoles: true}, Rings: starStripes()},
+			{Name: "multi layered holes", Tags: Tags{HasHoles: true}, Rings: []Ring{
+				// Outer star
+				star(0, 0, 10, 7),
+				// Top hole
+				star(1.5, 5, 3, 2).reverse(),
+				// Top inner
+				star(1.5, 5, 2, 1),
+				// Bottom hole
+				star(1.8, -5, 3, 2).reverse(),
+				// Bottom inner
+				star(1.8, -5, 2, 1),
+				// Left hole
+				star(-3, 0, 4, 2).reverse(),
+				// Left inner
+				star(-3, 0, 3, 1),
+			}},
+			{Name: "spiral", Rings: []Ring{SVG("spiral")}},
+			{Name: "monotone asteroid", Tags: Tags{IsMonotone: true}, Rings: []Ring{SVG("monotone_asteroid")}},
+			{Name: "monotone c", Tags: Tags{IsMonotone: true}, Rings: []Ring{SVG("monotone_c")}},
+			{Name: "monotone diamond", Tags: Tags{IsMonotone: true}, Rings: []Ring{SVG("monotone_diamond")}},
+			{Name: "collinear subdivided", Tags: Tags{IsMonotone: true, GridAligned: true}, Rings: []Ring{
+				{{0, 0}, {1, 0}, {2, 0}, {2, 2}, {0, 2}},
+			}},
+
+			// Inputs from bug reports
+			{Name: "degenerate quad", Tags: Tags{IsMonotone: true, GridAligned: true}, Log: File("issue_degenerate_quad.txt")},
+
+			// Shapes which have been hard on the numerics
+			{Name: "staircase", Tags: Tags{GridAligned: true}, Rings: []Ring{staircase(10)}},
+			{Name: "near degenerate annulus", Tags: Tags{HasHoles: true}, Rings: []Ring{
+				regular(64, 1),
+				regular(64, 1-1e-4).reverse(),
+			}},
+
+			{Name: "huge staircase", Tags: Tags{GridAligned: true, Huge: true}, Rings: []Ring{staircase(2000)}},
+		}
+		entries = append(entries, gridEntries()...)
+	})
+	return entries
+}
+
+// Find an entry by name, panicking if there is none
+func Lookup(name string) Entry {
+	for _, entry := range Entries() {
+		if entry.Name == name {
+			return entry
+		}
+	}
+	panicf("No corpus entry named %q", name)
+	return Entry{}
+}
+
+// The contents of one of the corpus's files, panicking if there is none
+func File(name string) string {
+	data, err := files.ReadFile(name)
+	if err != nil {
+		panicf("Could not load corpus file %q: %v", name, err)
+	}
+	return string(data)
+}
+
+func panicf(format string, args ...interface{}) {
+	panic(fmt.Sprintf(format, args...))
+}
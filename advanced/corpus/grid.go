@@ -0,0 +1,183 @@
+package corpus
+
+import "fmt"
+
+// Grid-aligned shapes, like CAD exports: rectangles with rectangular notches,
+// letter shapes, nested frames and staircases. Every edge is horizontal or
+// vertical, and the shapes share X and Y values between their vertices, so
+// they lean on the lexicographic tie-breaking as hard as anything can.
+//
+// Each shape is drawn in grid units, and then placed on several grids: the unit
+// grid, the 128 unit grid of the degenerate quad report, and that grid offset
+// into the thousands like the report's coordinates. Each placement is also
+// reflected every way, since reflection changes which way the tie-breaking
+// goes.
+
+// A shape drawn in grid units
+type gridShape struct {
+	name  string
+	rings []Ring
+}
+
+// A rectangle in grid units, counterclockwise
+func gridRect(minX, minY, maxX, maxY float64) Ring {
+	return Ring{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}}
+}
+
+func gridShapes() []gridShape {
+	shapes := []gridShape{
+		{"rectangle", []Ring{gridRect(0, 0, 4, 2)}},
+		{"L", []Ring{{{0, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 3}, {0, 3}}}},
+		{"T", []Ring{{{1, 0}, {2, 0}, {2, 2}, {3, 2}, {3, 3}, {0, 3}, {0, 2}, {1, 2}}}},
+		{"U", []Ring{{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}}}},
+		{"plus", []Ring{{
+			{1, 0}, {2, 0}, {2, 1}, {3, 1}, {3, 2}, {2, 2},
+			{2, 3}, {1, 3}, {1, 2}, {0, 2}, {0, 1}, {1, 1},
+		}}},
+		{"H", []Ring{{
+			{0, 0}, {1, 0}, {1, 1}, {2, 1}, {2, 0}, {3, 0},
+			{3, 3}, {2, 3}, {2, 2}, {1, 2}, {1, 3}, {0, 3},
+		}}},
+		// Notches in the middle of each side, and flush with a corner
+		{"top notch", []Ring{{{0, 0}, {3, 0}, {3, 2}, {2, 2}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}}},
+		{"bottom notch", []Ring{{{0, 0}, {1, 0}, {1, 1}, {2, 1}, {2, 0}, {3, 0}, {3, 2}, {0, 2}}}},
+		{"left notch", []Ring{{{0, 0}, {2, 0}, {2, 3}, {0, 3}, {0, 2}, {1, 2}, {1, 1}, {0, 1}}}},
+		{"right notch", []Ring{{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {2, 2}, {2, 3}, {0, 3}}}},
+		{"corner notch", []Ring{{{0, 0}, {3, 0}, {3, 1}, {2, 1}, {2, 2}, {0, 2}}}},
+		{"comb", []Ring{{
+			{0, 0}, {7, 0}, {7, 3}, {6, 3}, {6, 1}, {5, 1}, {5, 3}, {4, 3},
+			{4, 1}, {3, 1}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3},
+		}}},
+		// Notches on opposite sides, sharing an X value
+		{"offset notches", []Ring{{
+			{0, 0}, {1, 0}, {1, 1}, {2, 1}, {2, 0}, {4, 0},
+			{4, 3}, {3, 3}, {3, 2}, {2, 2}, {2, 3}, {0, 3},
+		}}},
+		{"staircase", []Ring{staircase(4)}},
+		{"pyramid", []Ring{{
+			{0, 0}, {6, 0}, {6, 1}, {5, 1}, {5, 2}, {4, 2}, {4, 3},
+			{2, 3}, {2, 2}, {1, 2}, {1, 1}, {0, 1},
+		}}},
+
+		// Shapes with holes, whose edges line up with the outer ring and each
+		// other
+		{"frame", []Ring{gridRect(0, 0, 4, 4), gridRect(1, 1, 3, 3).reverse()}},
+		{"nested frames", []Ring{
+			gridRect(0, 0, 6, 6),
+			gridRect(1, 1, 5, 5).reverse(),
+			gridRect(2, 2, 4, 4),
+		}},
+		{"nested frames with core", []Ring{
+			gridRect(0, 0, 8, 8),
+			gridRect(1, 1, 7, 7).reverse(),
+			gridRect(2, 2, 6, 6),
+			gridRect(3, 3, 5, 5).reverse(),
+		}},
+		{"L with holes", []Ring{
+			{{0, 0}, {6, 0}, {6, 3}, {3, 3}, {3, 6}, {0, 6}},
+			gridRect(1, 1, 5, 2).reverse(),
+			gridRect(1, 3, 2, 5).reverse(),
+		}},
+		{"notched frame", []Ring{
+			{{0, 0}, {4, 0}, {4, 4}, {3, 4}, {3, 3}, {1, 3}, {1, 4}, {0, 4}},
+			gridRect(1, 1, 3, 2).reverse(),
+		}},
+	}
+
+	// A grid of holes, in rows and columns
+	holes := []Ring{gridRect(0, 0, 7, 7)}
+	for x := 1.0; x < 7; x += 2 {
+		for y := 1.0; y < 7; y += 2 {
+			holes = append(holes, gridRect(x, y, x+1, y+1).reverse())
+		}
+	}
+	shapes = append(shapes, gridShape{"hole grid", holes})
+	return shapes
+}
+
+// Place a ring on a grid: scale it, reflect it, and move it. Reflecting in one
+// axis reverses the ring, to keep its winding.
+func (ring Ring) placed(scale float64, flipX, flipY bool, offsetX, offsetY float64) Ring {
+	result := make(Ring, len(ring))
+	for i, p := range ring {
+		x, y := p[0]*scale, p[1]*scale
+		if flipX {
+			x = -x
+		}
+		if flipY {
+			y = -y
+		}
+		result[i] = [2]float64{x + offsetX, y + offsetY}
+	}
+	if flipX != flipY {
+		result = result.reverse()
+	}
+	return result
+}
+
+// Is the shape a single ring, monotone in Y under the triangulator's
+// lexicographic ordering? Grid coordinates are exact, so ties are exact too.
+func lexicographicallyMonotone(rings []Ring) bool {
+	if len(rings) != 1 {
+		return false
+	}
+	ring := rings[0]
+	below := func(a, b [2]float64) bool {
+		if a[1] == b[1] {
+			return a[0] < b[0]
+		}
+		return a[1] < b[1]
+	}
+	minima := 0
+	for i, p := range ring {
+		prev, next := ring[(i+len(ring)-1)%len(ring)], ring[(i+1)%len(ring)]
+		if below(p, prev) && below(p, next) {
+			minima++
+		}
+	}
+	return minima == 1
+}
+
+// Every grid shape, on every grid, reflected every way
+func gridEntries() []Entry {
+	placements := []struct {
+		name             string
+		scale            float64
+		offsetX, offsetY float64
+	}{
+		{"unit grid", 1, 0, 0},
+		{"128 grid", 128, 0, 0},
+		{"128 grid offset", 128, -5248, -7168},
+	}
+	reflections := []struct {
+		name         string
+		flipX, flipY bool
+	}{
+		{"", false, false},
+		{", x reflected", true, false},
+		{", y reflected", false, true},
+		{", xy reflected", true, true},
+	}
+
+	var entries []Entry
+	for _, shape := range gridShapes() {
+		for _, placement := range placements {
+			for _, reflection := range reflections {
+				rings := make([]Ring, len(shape.rings))
+				for i, ring := range shape.rings {
+					rings[i] = ring.placed(placement.scale, reflection.flipX, reflection.flipY, placement.offsetX, placement.offsetY)
+				}
+				entries = append(entries, Entry{
+					Name: fmt.Sprintf("grid %s, %s%s", shape.name, placement.name, reflection.name),
+					Tags: Tags{
+						HasHoles:    len(rings) > 1,
+						IsMonotone:  lexicographicallyMonotone(rings),
+						GridAligned: true,
+					},
+					Rings: rings,
+				})
+			}
+		}
+	}
+	return entries
+}
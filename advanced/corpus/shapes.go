@@ -0,0 +1,82 @@
+package corpus
+
+import "math"
+
+// The shapes which are built in code. These have to produce exactly the same
+// coordinates as they always have, since tests are written against them.
+
+// The signed area of the ring, positive when it runs counterclockwise
+func (ring Ring) signedArea() float64 {
+	var area float64
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	return area / 2
+}
+
+func (ring Ring) reverse() Ring {
+	reversed := make(Ring, len(ring))
+	for i, p := range ring {
+		reversed[len(ring)-1-i] = p
+	}
+	return reversed
+}
+
+// A five pointed star centered on (x, y), starting from an outer point on the
+// positive X axis
+func star(x, y, outerRadius, innerRadius float64) Ring {
+	var ring Ring
+	for i := 0; i < 10; i++ {
+		angle := 2 * math.Pi * float64(i) / 10
+		r := outerRadius
+		if i%2 == 1 {
+			r = innerRadius
+		}
+		ring = append(ring, [2]float64{x + r*math.Cos(angle), y + r*math.Sin(angle)})
+	}
+	return ring
+}
+
+// Multiple inset stars with alternating winding
+func starStripes() []Ring {
+	const outerRadius = 10
+	const n = 20
+	const indentScale = 0.7
+	const gapScale = 0.9
+
+	var rings []Ring
+	scale := 1.0
+	for i := 0; i < n; i++ {
+		r := outerRadius * scale
+		ring := star(0, 0, r, r*indentScale)
+		if i%2 == 1 {
+			ring = ring.reverse()
+		}
+		rings = append(rings, ring)
+		scale *= gapScale
+	}
+	return rings
+}
+
+// A regular polygon centered on the origin, with a vertex on the positive X
+// axis
+func regular(n int, radius float64) Ring {
+	ring := make(Ring, n)
+	for i := range ring {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		ring[i] = [2]float64{radius * math.Cos(angle), radius * math.Sin(angle)}
+	}
+	return ring
+}
+
+// A staircase with the given number of unit steps, with its corner at the
+// origin
+func staircase(steps int) Ring {
+	ring := Ring{{0, 0}}
+	for i := 0; i < steps; i++ {
+		x := float64(steps - i)
+		ring = append(ring, [2]float64{x, float64(i)}, [2]float64{x, float64(i + 1)})
+	}
+	return append(ring, [2]float64{0, float64(steps)})
+}
@@ -0,0 +1,66 @@
+package corpus
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+// Load one of the SVG fixtures, by name, sans extension, and return its first
+// polygon, made counterclockwise.
+//
+// This is not a full (or even correct) svg parser. It parses the SVG and then
+// finds whatever the first polygon is. If anything goes wrong, it panics, since
+// the fixtures never change.
+func SVG(name string) Ring {
+	fixture, err := files.Open(name + ".svg")
+	if err != nil {
+		panicf("Could not load fixture %q: %v", name, err)
+	}
+
+	defer fixture.Close()
+	rootEl, err := svgparser.Parse(fixture, true)
+	if err != nil {
+		panicf("Failed to parse fixture %q: %v", name, err)
+	}
+
+	// Find the first polygon
+	polygons := rootEl.FindAll("polygon")
+	if len(polygons) == 0 {
+		panicf("No polygons found in fixture %q", name)
+	}
+	if len(polygons) > 1 {
+		panicf("More than one polygon found in fixture %q", name)
+	}
+	polygonEl := polygons[0]
+
+	pointString := polygonEl.Attributes["points"]
+	pointStrings := strings.Split(pointString, " ")
+	ring := make(Ring, 0, len(pointStrings))
+	for _, pointString := range pointStrings {
+		if pointString == "" {
+			continue
+		}
+
+		pointStrings := strings.Split(pointString, ",")
+		if len(pointStrings) != 2 {
+			panicf("Invalid point string %q", pointString)
+		}
+		x, err := strconv.ParseFloat(pointStrings[0], 64)
+		if err != nil {
+			panicf("Invalid x value %q: %v", pointStrings[0], err)
+		}
+		y, err := strconv.ParseFloat(pointStrings[1], 64)
+		if err != nil {
+			panicf("Invalid y value %q: %v", pointStrings[1], err)
+		}
+		ring = append(ring, [2]float64{x, y})
+	}
+
+	// Ensure that the polygon is CCW
+	if ring.signedArea() < 0 {
+		ring = ring.reverse()
+	}
+	return ring
+}
@@ -0,0 +1,109 @@
+package advanced
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced/corpus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The invariants which every shape in the corpus must satisfy
+func TestCorpus(t *testing.T) {
+	for _, entry := range corpus.Entries() {
+		entry := entry
+		t.Run(entry.Name, func(t *testing.T) {
+			if entry.Huge && testing.Short() {
+				t.Skip("huge entry")
+			}
+			list := corpusShape(entry)
+			var expectedArea float64
+			for _, poly := range list {
+				expectedArea += poly.SignedArea()
+			}
+
+			var triangles TriangleList
+			require.NotPanics(t, func() {
+				triangles = corpusShape(entry).TriangulateWithOptions(Options{CheckPointIntegrity: true})
+			})
+			assert.InDelta(t, expectedArea, totalArea(triangles), 1e-9*math.Max(1, expectedArea))
+			for _, tri := range triangles {
+				assert.False(t, IsCW(tri), "%v", tri)
+			}
+			validatePolygonsBySampling(t, triangles.ToPolygonList(), list)
+		})
+	}
+}
+
+// The tags must describe the shapes
+func TestCorpus_Tags(t *testing.T) {
+	for _, entry := range corpus.Entries() {
+		list := corpusShape(entry)
+		hasHoles, gridAligned, monotone := false, true, true
+		for _, poly := range list {
+			hasHoles = hasHoles || IsCW(&poly)
+			minima := 0
+			for i, p := range poly.Points {
+				gridAligned = gridAligned && p.X == math.Trunc(p.X) && p.Y == math.Trunc(p.Y)
+				prev := poly.Points[CircularIndex(i-1, len(poly.Points))]
+				next := poly.Points[CircularIndex(i+1, len(poly.Points))]
+				if p.Below(prev) && p.Below(next) {
+					minima++
+				}
+			}
+			monotone = monotone && minima == 1
+		}
+		monotone = monotone && len(list) == 1
+		assert.Equal(t, hasHoles, entry.HasHoles, "%s: HasHoles", entry.Name)
+		assert.Equal(t, gridAligned, entry.GridAligned, "%s: GridAligned", entry.Name)
+		assert.Equal(t, monotone, entry.IsMonotone, "%s: IsMonotone", entry.Name)
+	}
+}
+
+// Grid-aligned shapes share so many X and Y values that every tie-break in the
+// lexicographic ordering gets used, so try several insertion orders, and check
+// that every vertex is used exactly as often as it should be: a triangulation
+// of n vertices in s solids with h holes has n + 2h - 2s triangles.
+func TestCorpus_GridAligned(t *testing.T) {
+	sources := map[string]func(int64) rand.Source{
+		"sorted": sortedInsertion,
+	}
+	for seed := int64(1); seed <= 3; seed++ {
+		seed := seed
+		sources[fmt.Sprintf("seed %d", seed)] = func(int64) rand.Source { return rand.NewSource(seed) }
+	}
+
+	for sourceName, source := range sources {
+		for _, entry := range corpus.Entries() {
+			if !entry.GridAligned || entry.Huge {
+				continue
+			}
+			list := corpusShape(entry)
+			var vertices, holes, solids int
+			for _, poly := range list {
+				vertices += len(poly.Points)
+				if IsCW(&poly) {
+					holes++
+				} else {
+					solids++
+				}
+			}
+			expected := vertices + 2*holes - 2*solids
+
+			for _, decomposition := range []Decomposition{Monotones, Mountains} {
+				name := fmt.Sprintf("%s, %s, decomposition %d", entry.Name, sourceName, decomposition)
+				var triangles TriangleList
+				require.NotPanics(t, func() {
+					triangles = corpusShape(entry).TriangulateWithOptions(Options{Decomposition: decomposition, shuffleSource: source})
+				}, name)
+				assert.Len(t, triangles, expected, name)
+				for _, tri := range triangles {
+					assert.False(t, IsCW(tri), "%s: %v", name, tri)
+				}
+			}
+		}
+	}
+}
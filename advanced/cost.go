@@ -0,0 +1,108 @@
+package advanced
+
+import "math"
+
+// A quick estimate of how hard a polygon list will be to triangulate, for
+// callers deciding whether to triangulate at all. See EstimateCost.
+type CostEstimate struct {
+	Vertices int
+	// Vertices where the filled region's angle is more than 180 degrees. These
+	// are what make the trapezoid map deep, and the monotone pieces many.
+	Reflex int
+	// Rings which run clockwise
+	Holes int
+	// Vertices which are above or below both of their neighbors, by
+	// Point.Below. There are two per monotone piece, so this predicts how many
+	// pieces there will be.
+	Extrema int
+	// The long side of the bounding box over the short side. Infinite when the
+	// box is flat, and zero when there are no points.
+	AspectRatio float64
+	// A single counterclockwise monotone ring, which skips the trapezoid map
+	// entirely
+	LoneMonotone bool
+	// The predicted triangulation time in microseconds, on the machine the
+	// calibration was recorded on. Elsewhere, the scale will be off, but the
+	// ratios between scores should hold.
+	Score float64
+}
+
+// Coefficients of the score, fitted by TestEstimateCost_Calibration to the
+// recorded timings in fixtures/cost_calibration.txt. Each is in microseconds
+// per unit of its feature.
+const (
+	// Per vertex, for the work every triangulation does
+	costPerVertex = 0.18689701579121454
+	// Per vertex times log2 of the vertex count, for building the trapezoid map
+	costPerGraphVertex = 0.7322704487199148
+	// Per reflex vertex times log2 of the vertex count, for the extra depth
+	// reflex vertices give the trapezoid map
+	costPerReflexVertex = 0.6005778473570577
+	// Per extremum, for each monotone piece extracted. This fits slightly
+	// negative, since extrema come with reflex vertices, which already pay for
+	// them.
+	costPerExtremum = -0.08648854403059315
+)
+
+// The features the score is a linear combination of, in the order of the
+// coefficients above
+func (estimate CostEstimate) costFeatures() [4]float64 {
+	var graph, reflex float64
+	if !estimate.LoneMonotone && estimate.Vertices > 1 {
+		log := math.Log2(float64(estimate.Vertices))
+		graph = float64(estimate.Vertices) * log
+		reflex = float64(estimate.Reflex) * log
+	}
+	return [4]float64{float64(estimate.Vertices), graph, reflex, float64(estimate.Extrema)}
+}
+
+// Estimate how hard the polygons will be to triangulate, without
+// triangulating them. This takes one linear pass over the points, so it is
+// cheap enough to run on every shape, every frame, to decide which shapes to
+// triangulate exactly and which to approximate.
+//
+// The vertex count alone is a poor predictor, since reflex vertices cost far
+// more than convex ones while building the trapezoid map, so the score also
+// counts reflex vertices and extrema. See CostEstimate.
+func EstimateCost(polygons PolygonList) CostEstimate {
+	var estimate CostEstimate
+	bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, poly := range polygons {
+		n := len(poly.Points)
+		estimate.Vertices += n
+		if n < 3 {
+			continue
+		}
+		if IsCW(&poly) {
+			estimate.Holes++
+		}
+		for i, class := range poly.VertexClassification() {
+			p := poly.Points[i]
+			if class == Reflex {
+				estimate.Reflex++
+			}
+			prev := poly.Points[CircularIndex(i-1, n)]
+			next := poly.Points[CircularIndex(i+1, n)]
+			if p.Below(prev) == p.Below(next) {
+				estimate.Extrema++
+			}
+			bounds.MinX, bounds.MinY = math.Min(bounds.MinX, p.X), math.Min(bounds.MinY, p.Y)
+			bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, p.X), math.Max(bounds.MaxY, p.Y)
+		}
+	}
+
+	if !math.IsInf(bounds.MinX, 1) {
+		width, height := bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY
+		estimate.AspectRatio = math.Inf(1)
+		if long, short := math.Max(width, height), math.Min(width, height); short > 0 {
+			estimate.AspectRatio = long / short
+		}
+	}
+	estimate.LoneMonotone = len(polygons) == 1 && estimate.Holes == 0 && estimate.Extrema == 2
+
+	coefficients := [4]float64{costPerVertex, costPerGraphVertex, costPerReflexVertex, costPerExtremum}
+	for i, feature := range estimate.costFeatures() {
+		estimate.Score += coefficients[i] * feature
+	}
+	return estimate
+}
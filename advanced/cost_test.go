@@ -0,0 +1,267 @@
+package advanced
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osuushi/triangulate/advanced/corpus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCost(t *testing.T) {
+	square := EstimateCost(PolygonList{squareRing(0, 0, 1)})
+	assert.Equal(t, CostEstimate{
+		Vertices:     4,
+		Extrema:      2,
+		AspectRatio:  1,
+		LoneMonotone: true,
+		Score:        square.Score,
+	}, square)
+
+	// The hole's corners are reflex, since they point into the filled region
+	withHole := EstimateCost(SquareWithHole())
+	assert.Equal(t, 8, withHole.Vertices)
+	assert.Equal(t, 4, withHole.Reflex)
+	assert.Equal(t, 1, withHole.Holes)
+	assert.Equal(t, 4, withHole.Extrema)
+	assert.False(t, withHole.LoneMonotone)
+
+	// Each point of a star has a reflex vertex either side of it, and the
+	// star's top and bottom points are extrema
+	star := EstimateCost(SimpleStar())
+	assert.Equal(t, 5, star.Reflex)
+	assert.Equal(t, 0, star.Holes)
+	assert.Greater(t, star.Extrema, 2)
+	assert.False(t, star.LoneMonotone)
+
+	flat := EstimateCost(PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 2}, {X: 0, Y: 2}}}})
+	assert.Equal(t, 2.0, flat.AspectRatio)
+	assert.True(t, math.IsInf(EstimateCost(PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}}}).AspectRatio, 1))
+	assert.Equal(t, CostEstimate{}, EstimateCost(nil))
+
+	// Harder shapes score higher
+	assert.Less(t, square.Score, withHole.Score)
+	assert.Less(t, EstimateCost(PolygonList{circlePolygon(10, 200)}).Score, EstimateCost(PolygonList{starRing(100)}).Score)
+}
+
+// A star with the given number of points, so twice as many vertices, half of
+// them reflex
+func starRing(points int) Polygon {
+	result := make([]*Point, 2*points)
+	for i := range result {
+		angle := 2 * math.Pi * float64(i) / float64(len(result))
+		r := 10.0
+		if i%2 == 1 {
+			r = 7
+		}
+		result[i] = &Point{X: r * math.Cos(angle), Y: r * math.Sin(angle)}
+	}
+	return Polygon{result}
+}
+
+type calibrationShape struct {
+	name string
+	list func() PolygonList
+}
+
+// The shapes the score is calibrated against: the corpus, other than the
+// small grid shapes, which all take about the same time, and shapes of the
+// same kinds at a range of sizes
+func costCalibrationShapes() []calibrationShape {
+	var shapes []calibrationShape
+	for _, entry := range corpus.Entries() {
+		if strings.HasPrefix(entry.Name, "grid ") {
+			continue
+		}
+		entry := entry
+		shapes = append(shapes, calibrationShape{entry.Name, func() PolygonList { return corpusShape(entry) }})
+	}
+	for _, n := range []int{100, 1000, 10000} {
+		n := n
+		shapes = append(shapes,
+			calibrationShape{fmt.Sprintf("circle %d", n), func() PolygonList {
+				return PolygonList{circlePolygon(10, n)}
+			}},
+			calibrationShape{fmt.Sprintf("circle %d with hole", n), func() PolygonList {
+				return PolygonList{circlePolygon(10, n), squareRing(-1, -1, 2).Reverse()}
+			}},
+			calibrationShape{fmt.Sprintf("star %d", n), func() PolygonList {
+				return PolygonList{starRing(n / 2)}
+			}},
+		)
+	}
+	for _, entry := range corpus.Entries() {
+		if strings.HasPrefix(entry.Name, "grid hole grid, 128 grid offset") {
+			entry := entry
+			shapes = append(shapes, calibrationShape{entry.Name, func() PolygonList { return corpusShape(entry) }})
+		}
+	}
+	return shapes
+}
+
+// Time triangulating the shape, in microseconds, taking the fastest of a few
+// batches to keep the noise down
+func measureTriangulation(list func() PolygonList) float64 {
+	best := math.Inf(1)
+	for batch := 0; batch < 3; batch++ {
+		var elapsed time.Duration
+		runs := 0
+		for elapsed < 2*time.Millisecond {
+			input := list()
+			start := time.Now()
+			input.Triangulate()
+			elapsed += time.Since(start)
+			runs++
+		}
+		best = math.Min(best, float64(elapsed.Microseconds())/float64(runs))
+	}
+	return best
+}
+
+// Fit the score's coefficients to the timings by least squares on the
+// relative error, since the timings span several orders of magnitude
+func fitCostCoefficients(features [][4]float64, times []float64) [4]float64 {
+	// The normal equations, with each row weighted by 1/time, augmented with
+	// the right hand side
+	var system [4][5]float64
+	for row, f := range features {
+		weight := 1 / (times[row] * times[row])
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				system[i][j] += weight * f[i] * f[j]
+			}
+			system[i][4] += weight * f[i] * times[row]
+		}
+	}
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < 4; col++ {
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(system[row][col]) > math.Abs(system[pivot][col]) {
+				pivot = row
+			}
+		}
+		system[col], system[pivot] = system[pivot], system[col]
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			factor := system[row][col] / system[col][col]
+			for k := col; k < 5; k++ {
+				system[row][k] -= factor * system[col][k]
+			}
+		}
+	}
+	var coefficients [4]float64
+	for i := range coefficients {
+		coefficients[i] = system[i][4] / system[i][i]
+	}
+	return coefficients
+}
+
+// Spearman's rank correlation
+func rankCorrelation(a, b []float64) float64 {
+	ranks := func(values []float64) []float64 {
+		order := make([]int, len(values))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+		result := make([]float64, len(values))
+		for rank, i := range order {
+			result[i] = float64(rank)
+		}
+		return result
+	}
+	ra, rb := ranks(a), ranks(b)
+	var sum float64
+	for i := range ra {
+		d := ra[i] - rb[i]
+		sum += d * d
+	}
+	n := float64(len(a))
+	return 1 - 6*sum/(n*(n*n-1))
+}
+
+const costCalibrationFile = "fixtures/cost_calibration.txt"
+
+// Read the recorded timings, in microseconds, by shape name
+func readCostCalibration(t *testing.T) map[string]float64 {
+	file, err := fixtures.Open(costCalibrationFile)
+	require.NoError(t, err)
+	defer file.Close()
+	recorded := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 2)
+		require.Len(t, fields, 2, line)
+		micros, err := strconv.ParseFloat(fields[0], 64)
+		require.NoError(t, err, line)
+		recorded[fields[1]] = micros
+	}
+	require.NoError(t, scanner.Err())
+	return recorded
+}
+
+// The score's coefficients must be the fit to the recorded timings, and the
+// score must predict the recorded timings. Timings taken now are only checked
+// with the performance harness, under TRIANGULATE_PERF=1, since a loaded
+// machine can reorder them.
+//
+// When the implementation changes enough to change the timings, record new
+// ones by running this test with TRIANGULATE_CALIBRATE=1, and then copy the
+// fitted coefficients it reports into cost.go.
+func TestEstimateCost_Calibration(t *testing.T) {
+	shapes := costCalibrationShapes()
+	if os.Getenv("TRIANGULATE_CALIBRATE") != "" {
+		var lines []string
+		lines = append(lines, "# Triangulation time in microseconds, and shape name. Recorded by TestEstimateCost_Calibration.")
+		for _, shape := range shapes {
+			lines = append(lines, fmt.Sprintf("%.1f\t%s", measureTriangulation(shape.list), shape.name))
+		}
+		require.NoError(t, os.WriteFile(costCalibrationFile, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+		t.Skip("recorded new timings; rerun without TRIANGULATE_CALIBRATE")
+	}
+
+	recorded := readCostCalibration(t)
+	var features [][4]float64
+	var times, scores []float64
+	for _, shape := range shapes {
+		micros, ok := recorded[shape.name]
+		require.True(t, ok, "no recorded timing for %q; recalibrate", shape.name)
+		estimate := EstimateCost(shape.list())
+		features = append(features, estimate.costFeatures())
+		times = append(times, micros)
+		scores = append(scores, estimate.Score)
+	}
+
+	fitted := fitCostCoefficients(features, times)
+	shipped := [4]float64{costPerVertex, costPerGraphVertex, costPerReflexVertex, costPerExtremum}
+	for i := range fitted {
+		assert.InEpsilon(t, fitted[i], shipped[i], 1e-3, "coefficients in cost.go should be %v", fitted)
+	}
+	assert.Greater(t, rankCorrelation(scores, times), 0.95, "against the recorded timings")
+
+	if os.Getenv("TRIANGULATE_PERF") == "" {
+		return
+	}
+	var measured []float64
+	for _, shape := range shapes {
+		measured = append(measured, measureTriangulation(shape.list))
+	}
+	// This machine may be faster or slower, and noisier, but the order should
+	// mostly hold
+	assert.Greater(t, rankCorrelation(scores, measured), 0.9, "against timings taken now")
+}
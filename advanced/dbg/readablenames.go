@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	petname "github.com/dustinkirkland/golang-petname"
 )
@@ -15,6 +16,9 @@ import (
 
 var memo map[interface{}]string
 
+// Names are generated from error messages, which may be built on any goroutine
+var memoLock sync.Mutex
+
 func init() {
 	memo = make(map[interface{}]string)
 	// Since the ids are generated in order of demand, we make them
@@ -28,6 +32,8 @@ func Name(obj interface{}) string {
 		return "Ø"
 	}
 
+	memoLock.Lock()
+	defer memoLock.Unlock()
 	if r, ok := memo[obj]; ok {
 		return r
 	}
@@ -0,0 +1,186 @@
+package advanced
+
+import "math"
+
+// Constrained Delaunay edge flipping, as a post-pass over a finished
+// triangulation. Seidel's algorithm leaves long slivers wherever the monotone
+// pieces fan out, which look bad once anything is interpolated across them.
+// Flipping each internal edge whose opposite vertex falls inside the
+// circumcircle of the triangle across it, until none do, gives the
+// triangulation with the largest smallest angle that keeps the same points and
+// constraint edges.
+//
+// Each flip replaces the diagonal of the quadrilateral two triangles make with
+// the other diagonal, so the triangle count and the covered area never change.
+// Edges of the input rings are never flipped, so the boundary stays where it
+// is, as do edges shared between touching rings.
+
+// How far inside the circumcircle a point must be for its edge to flip, as a
+// fraction of the magnitude of the terms of the determinant. Points which are
+// nearly cocircular, like the corners of a grid square, would otherwise flip
+// back and forth on rounding error.
+const delaunayTolerance = 1e-9
+
+// How many flips the pass makes per triangle, at most, before giving up with
+// the triangulation as far along as it got. Flipping always converges in
+// theory, so this only guards against rounding making it cycle.
+const delaunayFlipsPerTriangle = 64
+
+// Flip edges to make the triangulation constrained Delaunay, as
+// Options.Delaunay does, leaving edges of the constraint rings alone. Those are
+// matched by their points' coordinates, so the triangles may have been made
+// with CanonicalizeOutputPoints. The result is a new list of new triangles, in
+// place of the given ones, which are not modified.
+func (triangles TriangleList) ImproveQuality(constraints PolygonList) TriangleList {
+	result, _, _ := improveQuality(triangles, constraints)
+	return result
+}
+
+// An edge between two positions, in either order, for matching edges by their
+// coordinates rather than their points
+type positionEdge [4]float64
+
+func newPositionEdge(a, b *Point) positionEdge {
+	if CompareLex(a, b) > 0 {
+		a, b = b, a
+	}
+	return positionEdge{a.X, a.Y, b.X, b.Y}
+}
+
+// ImproveQuality, also returning the number of flips made, and whether it
+// finished before running out of flips
+func improveQuality(triangles TriangleList, constraints PolygonList) (result TriangleList, flips int, converged bool) {
+	fixed := make(map[positionEdge]struct{})
+	for _, poly := range constraints {
+		for i, p := range poly.Points {
+			fixed[newPositionEdge(p, poly.Points[CircularIndex(i+1, len(poly.Points))])] = struct{}{}
+		}
+	}
+
+	// The triangles on either side of each edge, by index, or -1 for none
+	result = make(TriangleList, len(triangles))
+	owners := make(map[locatorEdge][2]int)
+	for i, tri := range triangles {
+		copied := *tri
+		result[i] = &copied
+		vertices := [3]*Point{tri.A, tri.B, tri.C}
+		for j, p := range vertices {
+			edge := newLocatorEdge(p, vertices[(j+1)%3])
+			sides, ok := owners[edge]
+			if !ok {
+				sides = [2]int{-1, -1}
+			}
+			if sides[0] < 0 {
+				sides[0] = i
+			} else {
+				sides[1] = i
+			}
+			owners[edge] = sides
+		}
+	}
+
+	// Every internal edge starts out to be checked, and each flip checks the
+	// edges around the quadrilateral again
+	var pending []locatorEdge
+	queued := make(map[locatorEdge]bool)
+	push := func(edge locatorEdge) {
+		if queued[edge] {
+			return
+		}
+		if sides := owners[edge]; sides[0] < 0 || sides[1] < 0 {
+			return
+		}
+		if _, ok := fixed[newPositionEdge(edge.bottom, edge.top)]; ok {
+			return
+		}
+		queued[edge] = true
+		pending = append(pending, edge)
+	}
+	for _, tri := range result {
+		push(newLocatorEdge(tri.A, tri.B))
+		push(newLocatorEdge(tri.B, tri.C))
+		push(newLocatorEdge(tri.C, tri.A))
+	}
+
+	maxFlips := delaunayFlipsPerTriangle * len(result)
+	for len(pending) > 0 {
+		edge := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		queued[edge] = false
+
+		sides := owners[edge]
+		first, second := result[sides[0]], result[sides[1]]
+		// The first triangle runs a, b, c, and the second runs back along the
+		// edge from b to a, then to d
+		a, b, c := first.aroundEdge(edge)
+		b2, a2, d := second.aroundEdge(edge)
+		// If both triangles run the same way along the edge, they overlap rather
+		// than sit on either side of it, so there's nothing to flip
+		if a2 != a || b2 != b || !shouldFlip(a, b, c, d) {
+			continue
+		}
+		if flips == maxFlips {
+			return result, flips, false
+		}
+		flips++
+		*first = Triangle{a, d, c}
+		*second = Triangle{d, b, c}
+		delete(owners, edge)
+		owners[newLocatorEdge(c, d)] = sides
+		replaceOwner(owners, newLocatorEdge(a, d), sides[1], sides[0])
+		replaceOwner(owners, newLocatorEdge(b, c), sides[0], sides[1])
+		for _, around := range [4]locatorEdge{
+			newLocatorEdge(a, d), newLocatorEdge(d, b), newLocatorEdge(b, c), newLocatorEdge(c, a),
+		} {
+			push(around)
+		}
+	}
+	return result, flips, true
+}
+
+// The triangle's points, starting with whichever of the edge's points the
+// triangle runs from along the edge
+func (t *Triangle) aroundEdge(edge locatorEdge) (a, b, c *Point) {
+	vertices := [3]*Point{t.A, t.B, t.C}
+	for i, p := range vertices {
+		next := vertices[(i+1)%3]
+		if (p == edge.bottom && next == edge.top) || (p == edge.top && next == edge.bottom) {
+			return p, next, vertices[(i+2)%3]
+		}
+	}
+	fatalf("triangle %v does not have the edge %v to %v", t, edge.bottom, edge.top)
+	return nil, nil, nil
+}
+
+func replaceOwner(owners map[locatorEdge][2]int, edge locatorEdge, from, to int) {
+	sides := owners[edge]
+	for i := range sides {
+		if sides[i] == from {
+			sides[i] = to
+		}
+	}
+	owners[edge] = sides
+}
+
+// Should the edge from a to b flip, given the counterclockwise triangle a, b, c
+// on one side of it, and d on the other? Only if d is clearly inside the
+// circle through a, b and c, and the triangles the flip makes both wind
+// counterclockwise, which holds whenever the quadrilateral is convex.
+func shouldFlip(a, b, c, d *Point) bool {
+	if !(orientation(a, d, c) > 0 && orientation(d, b, c) > 0) {
+		return false
+	}
+	adx, ady := a.X-d.X, a.Y-d.Y
+	bdx, bdy := b.X-d.X, b.Y-d.Y
+	cdx, cdy := c.X-d.X, c.Y-d.Y
+	aLift := float64(adx*adx) + float64(ady*ady)
+	bLift := float64(bdx*bdx) + float64(bdy*bdy)
+	cLift := float64(cdx*cdx) + float64(cdy*cdy)
+	determinant := float64(aLift*(float64(bdx*cdy)-float64(cdx*bdy))) +
+		float64(bLift*(float64(cdx*ady)-float64(adx*cdy))) +
+		float64(cLift*(float64(adx*bdy)-float64(bdx*ady)))
+	magnitude := float64(aLift*(math.Abs(bdx*cdy)+math.Abs(cdx*bdy))) +
+		float64(bLift*(math.Abs(cdx*ady)+math.Abs(adx*cdy))) +
+		float64(cLift*(math.Abs(adx*bdy)+math.Abs(bdx*ady)))
+	return determinant > delaunayTolerance*magnitude
+}
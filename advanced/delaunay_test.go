@@ -0,0 +1,97 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced/corpus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func minTriangleAngle(triangles TriangleList) float64 {
+	smallest := math.Inf(1)
+	for _, tri := range triangles {
+		smallest = math.Min(smallest, triangleMinAngle(tri))
+	}
+	return smallest
+}
+
+func TestImproveQuality_Spiral(t *testing.T) {
+	spiral := LoadFixture("spiral")
+	triangles := PolygonList{*spiral}.Triangulate()
+	improved := triangles.ImproveQuality(PolygonList{*spiral})
+
+	require.Len(t, improved, len(triangles))
+	AssertValidTriangulation(t, spiral, improved)
+	assert.Greater(t, minTriangleAngle(improved), minTriangleAngle(triangles))
+	// The input triangles are left alone
+	assert.Equal(t, triangles, PolygonList{*spiral}.Triangulate())
+
+	// Through the option, the flips are counted
+	var diagnostics Diagnostics
+	flipped := PolygonList{*spiral}.TriangulateWithOptions(Options{Delaunay: true, Diagnostics: &diagnostics})
+	assert.Equal(t, minTriangleAngle(improved), minTriangleAngle(flipped))
+	assert.Positive(t, diagnostics.DelaunayFlips)
+	assert.Empty(t, diagnostics.Warnings)
+}
+
+func TestImproveQuality_Fixtures(t *testing.T) {
+	for _, name := range []string{"spiral", "monotone_asteroid", "monotone_c", "monotone_diamond"} {
+		poly := LoadFixture(name)
+		triangles := PolygonList{*poly}.TriangulateWithOptions(Options{Delaunay: true})
+		assert.Len(t, triangles, len(poly.Points)-2, name)
+		AssertValidTriangulation(t, poly, triangles)
+	}
+
+	for _, entry := range corpus.Entries() {
+		if entry.Huge && testing.Short() {
+			continue
+		}
+		list := corpusShape(entry)
+		triangles := list.Triangulate()
+		improved := triangles.ImproveQuality(list)
+		assert.Len(t, improved, len(triangles), entry.Name)
+		assert.InDelta(t, totalArea(triangles), totalArea(improved), 1e-9*math.Max(1, totalArea(triangles)), entry.Name)
+		assert.GreaterOrEqual(t, minTriangleAngle(improved), minTriangleAngle(triangles), entry.Name)
+		for _, tri := range improved {
+			assert.True(t, IsCCW(tri), "%s: %v", entry.Name, tri)
+		}
+		// Every edge of the input is still an edge of some triangle
+		edges := make(normalizedSegmentSet)
+		for _, tri := range improved {
+			edges.add(tri.A, tri.B)
+			edges.add(tri.B, tri.C)
+			edges.add(tri.C, tri.A)
+		}
+		for _, poly := range list {
+			for i, p := range poly.Points {
+				assert.True(t, edges.contains(p, poly.Points[CircularIndex(i+1, len(poly.Points))]), "%s: edge %v", entry.Name, p)
+			}
+		}
+	}
+}
+
+func TestImproveQuality_Constraints(t *testing.T) {
+	// Two triangles sharing a long edge, which Delaunay would flip to the short
+	// diagonal
+	a, b, c, d := &Point{X: 0, Y: 0}, &Point{X: 10, Y: 0}, &Point{X: 5, Y: 1}, &Point{X: 5, Y: -1}
+	triangles := TriangleList{{a, b, c}, {b, a, d}}
+	improved := triangles.ImproveQuality(nil)
+	assert.ElementsMatch(t, []*Triangle{{a, d, c}, {d, b, c}}, improved)
+
+	// Unless the edge is part of a ring, as where two rings touch. Coordinates
+	// are enough to match the edge.
+	constraints := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 5, Y: 1}}}}
+	assert.Equal(t, triangles, triangles.ImproveQuality(constraints))
+
+	// Nor does a square flip back and forth between its diagonals
+	square := squareRing(0, 0, 1)
+	triangles = PolygonList{square}.Triangulate()
+	assert.Equal(t, triangles, triangles.ImproveQuality(PolygonList{square}))
+}
+
+func TestTriangulateFunc_Delaunay(t *testing.T) {
+	err := PolygonList{squareRing(0, 0, 1)}.TriangulateFunc(Options{Delaunay: true}, TriangleSinkFunc(func(*Triangle) error { return nil }))
+	assert.Error(t, err)
+}
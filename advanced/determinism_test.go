@@ -0,0 +1,89 @@
+package advanced
+
+import (
+	"bufio"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The same kernels, as a compiler which fuses multiply-adds might evaluate
+// them. Every input in the marginal predicates corpus gives a different answer
+// here than the explicitly rounded kernels do.
+func fusedDifference(a, b, c, d float64) float64 {
+	return math.FMA(a, b, -(c * d))
+}
+
+func fusedSignedArea(t *Triangle) float64 {
+	return (fusedDifference(t.A.X, t.B.Y, t.B.X, t.A.Y) +
+		fusedDifference(t.B.X, t.C.Y, t.C.X, t.B.Y) +
+		fusedDifference(t.C.X, t.A.Y, t.A.X, t.C.Y)) / 2
+}
+
+func fusedSolveForX(s *Segment, y float64) float64 {
+	m := (s.End.Y - s.Start.Y) / (s.End.X - s.Start.X)
+	b := math.FMA(-m, s.Start.X, s.Start.Y)
+	return (y - b) / m
+}
+
+func sign(v float64) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func TestMarginalPredicates(t *testing.T) {
+	file, err := fixtures.Open("fixtures/marginal_predicates.txt")
+	require.NoError(t, err)
+	defer file.Close()
+
+	parse := func(fields []string) []float64 {
+		values := make([]float64, len(fields))
+		for i, field := range fields {
+			values[i], err = strconv.ParseFloat(field, 64)
+			require.NoError(t, err, field)
+		}
+		return values
+	}
+
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		switch fields[0] {
+		case "area":
+			require.Len(t, fields, 8, "line %d", line)
+			v := parse(fields[1:])
+			tri := &Triangle{&Point{X: v[0], Y: v[1]}, &Point{X: v[2], Y: v[3]}, &Point{X: v[4], Y: v[5]}}
+			expected := int(v[6])
+			assert.Equal(t, expected, sign(tri.SignedArea()), "line %d", line)
+			assert.NotEqual(t, expected, sign(fusedSignedArea(tri)), "line %d is not FMA-sensitive", line)
+		case "solvex":
+			require.Len(t, fields, 7, "line %d", line)
+			v := parse(fields[1:])
+			segment := NewSegment(&Point{X: v[0], Y: v[1]}, &Point{X: v[2], Y: v[3]})
+			y, expected := v[4], v[5]
+			// Compare bits, since the results must match exactly
+			assert.Equal(t, math.Float64bits(expected), math.Float64bits(segment.SolveForX(y)), "line %d", line)
+			assert.NotEqual(t, expected, fusedSolveForX(segment, y), "line %d is not FMA-sensitive", line)
+		default:
+			t.Fatalf("line %d: unknown kind %q", line, fields[0])
+		}
+		counts[fields[0]]++
+	}
+	require.NoError(t, scanner.Err())
+	assert.NotZero(t, counts["area"])
+	assert.NotZero(t, counts["solvex"])
+}
@@ -0,0 +1,230 @@
+package advanced
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Typed errors for problems with the input. These are thrown like any other
+// error (see throw.go), so they reach the caller through the public API, where
+// they can be inspected with errors.As.
+
+// The same coordinates appear twice in one polygon. When validating (see
+// PolygonList.Validate), this is also the error for a point used twice in one
+// polygon, and for neighboring points within Epsilon of each other.
+type DuplicatePointError struct {
+	// Index of the polygon in the input list
+	Polygon int
+	// Indexes of the two vertices within the polygon
+	First, Second int
+	Point         Point
+}
+
+func (e *DuplicatePointError) Error() string {
+	return fmt.Sprintf("polygon %d has duplicate point %v at vertices %d and %d", e.Polygon, &e.Point, e.First, e.Second)
+}
+
+// A polygon has too few points to enclose any area.
+type TooFewPointsError struct {
+	// Index of the polygon in the input list
+	Polygon int
+	// The number of points it has
+	Points int
+}
+
+func (e *TooFewPointsError) Error() string {
+	return fmt.Sprintf("polygon %d has %d points, but needs at least 3", e.Polygon, e.Points)
+}
+
+// A point is outside the supported coordinate range of ±MaxCoordinate, or has a
+// NaN or infinite coordinate. These errors match ErrCoordinatesOutOfRange with
+// errors.Is.
+type CoordinatesOutOfRangeError struct {
+	// Index of the polygon in the input list
+	Polygon int
+	// Index of the vertex within the polygon
+	Vertex int
+	Point  Point
+}
+
+var ErrCoordinatesOutOfRange = errors.New("coordinates out of range")
+
+func (e *CoordinatesOutOfRangeError) Error() string {
+	return fmt.Sprintf(
+		"polygon %d vertex %d at %v is outside the supported coordinate range of ±%g; translate or scale the input, or set Options.AutoNormalize",
+		e.Polygon, e.Vertex, &e.Point, MaxCoordinate,
+	)
+}
+
+func (e *CoordinatesOutOfRangeError) Is(target error) bool {
+	return target == ErrCoordinatesOutOfRange
+}
+
+// An input point was modified while the triangulation was running, which was
+// caught by Options.CheckPointIntegrity.
+type PointMutatedError struct {
+	// Index of the polygon in the input list
+	Polygon int
+	// Index of the vertex within the polygon
+	Vertex        int
+	Before, After Point
+	// The stage at the end of which the change was noticed
+	Stage Stage
+}
+
+func (e *PointMutatedError) Error() string {
+	return fmt.Sprintf(
+		"polygon %d vertex %d was modified during triangulation, from %v to %v (noticed after stage: %v)",
+		e.Polygon, e.Vertex, &e.Before, &e.After, e.Stage,
+	)
+}
+
+// An output triangle has zero area, and Options.ZeroAreaTriangles is
+// ErrorOnZeroArea.
+type ZeroAreaTriangleError struct {
+	Points [3]Point
+}
+
+func (e *ZeroAreaTriangleError) Error() string {
+	return fmt.Sprintf("triangle has zero area: %v, %v, %v", &e.Points[0], &e.Points[1], &e.Points[2])
+}
+
+// A rectangle is empty or inverted.
+type InvalidRectError struct {
+	// Index of the rectangle in the input
+	Rect int
+}
+
+func (e *InvalidRectError) Error() string {
+	return fmt.Sprintf("rect %d is empty", e.Rect)
+}
+
+// A rectangular hole is not strictly inside its outer polygon.
+type RectOutsideError struct {
+	// Index of the rectangle in the input
+	Rect int
+}
+
+func (e *RectOutsideError) Error() string {
+	return fmt.Sprintf("rect %d is not inside the outer polygon", e.Rect)
+}
+
+// Two rectangular holes overlap or touch.
+type RectOverlapError struct {
+	// Indexes of the rectangles in the input
+	First, Second int
+}
+
+func (e *RectOverlapError) Error() string {
+	return fmt.Sprintf("rect %d overlaps rect %d", e.First, e.Second)
+}
+
+// Segments given to a Session do not form closed rings, because some endpoints
+// are used by an odd number of segments.
+type DanglingEndpointsError struct {
+	// The endpoints with odd degree, in the order they were first seen
+	Points []Point
+}
+
+func (e *DanglingEndpointsError) Error() string {
+	return fmt.Sprintf("%d dangling endpoints: %v", len(e.Points), e.Points)
+}
+
+// Segments given to a Session close up, but some endpoints have a different
+// number of segments arriving than leaving, so the winding is inconsistent.
+type InconsistentWindingError struct {
+	// The offending endpoints, in the order they were first seen
+	Points []Point
+}
+
+func (e *InconsistentWindingError) Error() string {
+	return fmt.Sprintf("%d endpoints with inconsistent winding: %v", len(e.Points), e.Points)
+}
+
+// A candidate ring would intersect geometry which is already in a Session.
+type IntersectionError struct {
+	// The segment already in the session
+	Existing *Segment
+	// Index of the offending edge in the candidate ring. Edge i runs from point i
+	// to point i+1.
+	Edge int
+	// The offending edge itself
+	Candidate *Segment
+}
+
+func (e *IntersectionError) Error() string {
+	return fmt.Sprintf(
+		"edge %d (%v to %v) intersects existing segment from %v to %v",
+		e.Edge, e.Candidate.Start, e.Candidate.End, e.Existing.Start, e.Existing.End,
+	)
+}
+
+// Some vertices of a Field's triangulation have no value.
+type MissingValuesError struct {
+	// The vertices with no value, in the order they appear in the triangles
+	Points []Point
+}
+
+func (e *MissingValuesError) Error() string {
+	return fmt.Sprintf("%d vertices have no value: %v", len(e.Points), e.Points)
+}
+
+// Two polygons are nearly the same ring, as when a layer is duplicated and one
+// copy nudged, so their edges cross all along their length. This is reported
+// before triangulation starts, since the crossings would otherwise make it
+// slow, and fail in a way which says nothing about the cause.
+type NearDuplicateRingsError struct {
+	// Indexes of the two polygons in the input list
+	First, Second int
+	// The sum of the distances from each vertex of the first polygon to the
+	// matching vertex of the second. Zero for exact duplicates.
+	Distance float64
+}
+
+func (e *NearDuplicateRingsError) Error() string {
+	return fmt.Sprintf(
+		"polygons %d and %d are near duplicates, with vertices %g apart in total; remove one of them",
+		e.First, e.Second, e.Distance,
+	)
+}
+
+// Two edges of the input cross or touch, found by Options.Validate. Edge i of
+// a polygon runs from point i to point i+1.
+type CrossingEdgesError struct {
+	// Indexes of the polygons in the input list, and of the edges within them
+	FirstPolygon, FirstEdge   int
+	SecondPolygon, SecondEdge int
+}
+
+func (e *CrossingEdgesError) Error() string {
+	return fmt.Sprintf(
+		"polygon %d edge %d crosses polygon %d edge %d",
+		e.FirstPolygon, e.FirstEdge, e.SecondPolygon, e.SecondEdge,
+	)
+}
+
+// A clockwise ring which isn't inside a solid, so that it cuts nothing, found
+// by Options.Validate once the trapezoid map is built
+type MisplacedHoleError struct {
+	// Index of the hole in the input list, and of one of its edges which
+	// borders the outside
+	Polygon, Edge int
+}
+
+func (e *MisplacedHoleError) Error() string {
+	return fmt.Sprintf("hole %d is not inside a solid; its edge %d borders the outside", e.Polygon, e.Edge)
+}
+
+// A ring which isn't inside any other ring collapsed to fewer than three
+// points when PolygonList.Cleaned merged its vertices, so that a whole shape
+// would be lost. The tolerance is too large for the input.
+type CollapsedRingError struct {
+	// Index of the ring in the input list
+	Polygon   int
+	Tolerance float64
+}
+
+func (e *CollapsedRingError) Error() string {
+	return fmt.Sprintf("polygon %d collapses when vertices within %g are merged; use a smaller tolerance", e.Polygon, e.Tolerance)
+}
@@ -0,0 +1,126 @@
+package advanced
+
+import (
+	"math"
+	"math/big"
+	"sync"
+)
+
+// Predicates whose floating point result is too close to call are recomputed
+// with exact rational arithmetic. This only kicks in on marginal cases, so it
+// costs very little on typical input, but a triangulation can turn it off for
+// speed with Options.DisableExactFallback.
+
+// The largest relative error of a single rounded floating point operation
+const unitRoundoff = 1.0 / (1 << 53)
+
+// Exact results are cached by their operands. The cache is simply dropped when
+// it grows past this size, since marginal cases are rare in practice.
+const exactCacheLimit = 1 << 16
+
+type exactOp uint8
+
+const (
+	exactEqual      exactOp = iota // |a - b| < Epsilon
+	exactGreater                   // a - b > Epsilon
+	exactLeftOf                    // The segment's line is left of the point by more than Epsilon
+	exactRightOf                   // The segment's line is right of the point by more than Epsilon
+	exactPassesLeft                // The segment's line is left of the point by any amount
+)
+
+type exactKey struct {
+	op   exactOp
+	args [6]float64
+}
+
+var exactCache struct {
+	sync.Mutex
+	results map[exactKey]bool
+}
+
+// Check whether a floating point quantity is close enough to the threshold it's
+// being compared against (margin being the difference) that rounding error
+// could flip the comparison. Non-finite bounds are never marginal, since exact
+// arithmetic can't help with them. Nothing is marginal when the tracker's
+// triangulation has turned the fallback off, but a nil tracker always falls
+// back.
+func (m *marginTracker) isMarginal(margin, bound float64) bool {
+	if m != nil && m.inexact {
+		return false
+	}
+	return math.Abs(margin) <= bound && !math.IsInf(bound, 0)
+}
+
+// Conservative estimate of the rounding error in SolveForX for the given
+// point, where x is the floating point result.
+func (s *Segment) solveForXErrorBound(p *Point, x float64) float64 {
+	inverseSlope := math.Abs((s.End.X - s.Start.X) / (s.End.Y - s.Start.Y))
+	yMagnitude := math.Abs(p.Y) + math.Abs(s.Start.Y) + math.Abs(s.End.Y)
+	xMagnitude := math.Abs(p.X) + math.Abs(x) + math.Abs(s.Start.X) + math.Abs(s.End.X)
+	return 16 * unitRoundoff * (float64(yMagnitude*inverseSlope) + xMagnitude)
+}
+
+// Settle a marginal predicate exactly, consulting the cache first, and count
+// it in the tracker, which may be nil.
+func (m *marginTracker) exactDecision(op exactOp, args ...float64) bool {
+	if m != nil {
+		m.exactEvaluations++
+	}
+
+	key := exactKey{op: op}
+	copy(key.args[:], args)
+
+	exactCache.Lock()
+	result, ok := exactCache.results[key]
+	exactCache.Unlock()
+	if ok {
+		return result
+	}
+
+	result = evaluateExactly(op, args)
+
+	exactCache.Lock()
+	if exactCache.results == nil || len(exactCache.results) >= exactCacheLimit {
+		exactCache.results = make(map[exactKey]bool)
+	}
+	exactCache.results[key] = result
+	exactCache.Unlock()
+	return result
+}
+
+func evaluateExactly(op exactOp, args []float64) bool {
+	rats := make([]*big.Rat, len(args))
+	for i, arg := range args {
+		rats[i] = new(big.Rat).SetFloat64(arg)
+	}
+	epsilon := new(big.Rat).SetFloat64(Epsilon)
+
+	switch op {
+	case exactEqual:
+		diff := new(big.Rat).Sub(rats[0], rats[1])
+		return diff.Abs(diff).Cmp(epsilon) < 0
+	case exactGreater:
+		diff := new(big.Rat).Sub(rats[0], rats[1])
+		return diff.Cmp(epsilon) > 0
+	case exactLeftOf, exactRightOf, exactPassesLeft:
+		startX, startY, endX, endY, px, py := rats[0], rats[1], rats[2], rats[3], rats[4], rats[5]
+		// x = startX + (py - startY) * (endX - startX) / (endY - startY)
+		x := new(big.Rat).Sub(py, startY)
+		x.Mul(x, new(big.Rat).Sub(endX, startX))
+		x.Quo(x, new(big.Rat).Sub(endY, startY))
+		x.Add(x, startX)
+
+		var diff *big.Rat
+		if op == exactRightOf {
+			diff = new(big.Rat).Sub(x, px)
+		} else {
+			diff = new(big.Rat).Sub(px, x)
+		}
+		if op == exactPassesLeft {
+			return diff.Sign() > 0
+		}
+		return diff.Cmp(epsilon) > 0
+	}
+	fatalf("unknown exact predicate %d", op)
+	return false
+}
@@ -0,0 +1,165 @@
+package advanced
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExactFallback_Comparisons(t *testing.T) {
+	// In floating point, 1e-7 - 1e-30 rounds to exactly Epsilon, so these
+	// comparisons are marginal and must be settled exactly.
+	var margins marginTracker
+	assert.True(t, equal(Epsilon, 1e-30, &margins))
+	assert.True(t, greaterThan(Epsilon, -1e-30, &margins))
+	assert.False(t, greaterThan(Epsilon, 1e-30, &margins))
+	assert.Equal(t, 3, margins.exactEvaluations)
+	assert.True(t, Equal(Epsilon, 1e-30))
+	assert.True(t, LessThan(-1e-30, Epsilon))
+
+	// Clear-cut cases never need exact evaluation
+	margins = marginTracker{}
+	assert.True(t, equal(1, 1+Epsilon/2, &margins))
+	assert.False(t, equal(1, 2, &margins))
+	assert.True(t, greaterThan(2, 1, &margins))
+	assert.Equal(t, 0, margins.exactEvaluations)
+
+	// Without the fallback, the rounded difference is taken at its word
+	margins = marginTracker{inexact: true}
+	assert.False(t, equal(Epsilon, 1e-30, &margins))
+	assert.Equal(t, 0, margins.exactEvaluations)
+}
+
+func TestExactFallback_IsLeftOf(t *testing.T) {
+	segment := NewSegment(&Point{X: 0.1, Y: 0.3}, &Point{X: 1000.7, Y: 3000.1})
+
+	// Exact X of the segment's line at y
+	exactX := func(y float64) *big.Rat {
+		x := new(big.Rat).Sub(new(big.Rat).SetFloat64(y), new(big.Rat).SetFloat64(segment.Start.Y))
+		x.Mul(x, new(big.Rat).Sub(new(big.Rat).SetFloat64(segment.End.X), new(big.Rat).SetFloat64(segment.Start.X)))
+		x.Quo(x, new(big.Rat).Sub(new(big.Rat).SetFloat64(segment.End.Y), new(big.Rat).SetFloat64(segment.Start.Y)))
+		return x.Add(x, new(big.Rat).SetFloat64(segment.Start.X))
+	}
+
+	// Walk points across the Epsilon boundary on either side of the line, one
+	// ulp at a time, and check that every decision matches exact arithmetic.
+	epsilon := new(big.Rat).SetFloat64(Epsilon)
+	var margins marginTracker
+	for _, y := range []float64{0.7, 123.456, 2999.9} {
+		lineX, _ := exactX(y).Float64()
+		for _, offset := range []float64{Epsilon, -Epsilon} {
+			x := lineX + offset
+			for i := 0; i < 20; i++ {
+				x = math.Nextafter(x, math.Inf(-1))
+			}
+			for i := 0; i < 40; i++ {
+				x = math.Nextafter(x, math.Inf(1))
+				p := &Point{X: x, Y: y}
+				diff := new(big.Rat).Sub(new(big.Rat).SetFloat64(x), exactX(y))
+				assert.Equal(t, diff.Cmp(epsilon) > 0, segment.isLeftOf(p, &margins), "IsLeftOf %v", p)
+				assert.Equal(t, diff.Neg(diff).Cmp(epsilon) > 0, segment.isRightOf(p, &margins), "IsRightOf %v", p)
+			}
+		}
+	}
+	assert.Greater(t, margins.exactEvaluations, 0)
+}
+
+func TestExactFallback_GridAligned(t *testing.T) {
+	staircase := func(steps int, scale float64) Polygon {
+		points := []*Point{{X: 0, Y: 0}}
+		for i := 0; i < steps; i++ {
+			x := float64(steps-i) * scale
+			points = append(points, &Point{X: x, Y: float64(i) * scale}, &Point{X: x, Y: float64(i+1) * scale})
+		}
+		points = append(points, &Point{X: 0, Y: float64(steps) * scale})
+		return Polygon{points}
+	}
+
+	// Star with integer coordinates, so that many vertices share rows and columns
+	integerStar := func(scale float64) Polygon {
+		coords := [][2]float64{
+			{0, -4}, {1, -1}, {4, 0}, {1, 1}, {0, 4}, {-1, 1}, {-4, 0}, {-1, -1},
+		}
+		var points []*Point
+		for _, c := range coords {
+			points = append(points, &Point{X: c[0] * scale, Y: c[1] * scale})
+		}
+		return Polygon{points}
+	}
+
+	// Rectangular frame with a notch cut into the bottom, on a 128 unit grid far
+	// from the origin, in the style of CAD exports
+	notchedFrame := func(originX, originY float64) PolygonList {
+		g := func(x, y float64) *Point { return &Point{X: originX + 128*x, Y: originY + 128*y} }
+		return PolygonList{
+			{[]*Point{g(0, 0), g(3, 0), g(3, 2), g(5, 2), g(5, 0), g(8, 0), g(8, 6), g(0, 6)}},
+			{[]*Point{g(2, 3), g(2, 5), g(6, 5), g(6, 3)}},
+		}
+	}
+
+	cases := map[string]PolygonList{}
+	for _, scale := range []float64{1, 128, 1000} {
+		for _, steps := range []int{1, 3, 8} {
+			cases[fmt.Sprintf("staircase %d x %v", steps, scale)] = PolygonList{staircase(steps, scale)}
+		}
+		cases[fmt.Sprintf("integer star x %v", scale)] = PolygonList{integerStar(scale)}
+	}
+	cases["notched frame"] = notchedFrame(-5248, -7168)
+	cases["notched frame at origin"] = notchedFrame(0, 0)
+
+	for name, list := range cases {
+		t.Run(name, func(t *testing.T) {
+			diagnostics := &Diagnostics{}
+			var result TriangleList
+			require.NotPanics(t, func() {
+				result = list.TriangulateWithOptions(Options{Diagnostics: diagnostics})
+			})
+
+			pointCount := 0
+			for _, poly := range list {
+				pointCount += len(poly.Points)
+			}
+			assert.Len(t, result, pointCount+2*(len(list)-1)-2)
+			validatePolygonsBySampling(t, result.ToPolygonList(), list)
+			assert.GreaterOrEqual(t, diagnostics.ExactEvaluations, 0)
+		})
+	}
+}
+
+func TestExactFallback_PerTriangulation(t *testing.T) {
+	// Vertices exactly Epsilon above their neighbors, so that comparing their
+	// heights is marginal
+	points := []*Point{{X: 0, Y: 0}, {X: 4, Y: Epsilon}, {X: 4, Y: 4}, {X: 2, Y: 4 + Epsilon}, {X: 0, Y: 4}}
+	list := PolygonList{{points}}
+
+	expected := &Diagnostics{}
+	list.TriangulateWithOptions(Options{Diagnostics: expected})
+	require.Greater(t, expected.ExactEvaluations, 0)
+
+	// Triangulations running at once count only their own decisions
+	functions := make([]func(), 8)
+	counts := make([]int, len(functions))
+	for i := range functions {
+		i := i
+		functions[i] = func() {
+			for j := 0; j < 100; j++ {
+				diagnostics := &Diagnostics{}
+				list.TriangulateWithOptions(Options{Diagnostics: diagnostics})
+				counts[i] = diagnostics.ExactEvaluations
+			}
+		}
+	}
+	runConcurrently(functions...)
+	for _, count := range counts {
+		assert.Equal(t, expected.ExactEvaluations, count)
+	}
+
+	disabled := &Diagnostics{}
+	result := list.TriangulateWithOptions(Options{Diagnostics: disabled, DisableExactFallback: true})
+	assert.Len(t, result, len(points)-2)
+	assert.Zero(t, disabled.ExactEvaluations)
+}
@@ -0,0 +1,129 @@
+package advanced
+
+// A Field is a scalar value given at the vertices of a triangulation, and
+// interpolated linearly across each triangle. For example, the vertices of a
+// terrain outline might carry elevations.
+type Field struct {
+	locator *Locator
+	values  map[*Point]float64
+}
+
+// Create a field from values at the vertices of the locator's triangles. The
+// values are keyed by pointer, like everything else.
+//
+// If some vertices have no value, this returns a MissingValuesError, unless
+// fillMissing is set. In that case, each missing value is filled in with the
+// average of its neighbors along triangle edges, working inwards from the
+// vertices which have values. It is still an error if some connected piece of
+// the triangulation has no values at all. The values map is not modified.
+func NewField(locator *Locator, values map[*Point]float64, fillMissing bool) (*Field, error) {
+	field := &Field{locator, make(map[*Point]float64, len(values))}
+	for p, value := range values {
+		field.values[p] = value
+	}
+
+	// Gather the vertices and their neighbors, in a stable order
+	var vertices []*Point
+	neighbors := make(map[*Point][]*Point)
+	for _, tri := range locator.triangles {
+		triVertices := []*Point{tri.A, tri.B, tri.C}
+		for i, p := range triVertices {
+			if _, ok := neighbors[p]; !ok {
+				vertices = append(vertices, p)
+				neighbors[p] = nil
+			}
+			neighbors[p] = append(neighbors[p], triVertices[(i+1)%3], triVertices[(i+2)%3])
+		}
+	}
+
+	if fillMissing {
+		field.fill(vertices, neighbors)
+	}
+
+	var missing []Point
+	for _, p := range vertices {
+		if _, ok := field.values[p]; !ok {
+			missing = append(missing, *p)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingValuesError{missing}
+	}
+	return field, nil
+}
+
+// Fill in missing values in rounds. Each round fills every missing vertex
+// which has a known neighbor, using only values known before the round, so
+// the result doesn't depend on the order of the vertices within a round.
+func (f *Field) fill(vertices []*Point, neighbors map[*Point][]*Point) {
+	for {
+		filled := make(map[*Point]float64)
+		for _, p := range vertices {
+			if _, ok := f.values[p]; ok {
+				continue
+			}
+			var sum float64
+			var count int
+			seen := make(PointSet)
+			for _, neighbor := range neighbors[p] {
+				if value, ok := f.values[neighbor]; ok && !seen.Contains(neighbor) {
+					seen.Add(neighbor)
+					sum += value
+					count++
+				}
+			}
+			if count > 0 {
+				filled[p] = sum / float64(count)
+			}
+		}
+		if len(filled) == 0 {
+			return
+		}
+		for p, value := range filled {
+			f.values[p] = value
+		}
+	}
+}
+
+// The interpolated value at a point. If the point isn't in any triangle, ok is
+// false.
+func (f *Field) At(x, y float64) (value float64, ok bool) {
+	tri, ok := f.locator.TriangleAt(x, y)
+	if !ok {
+		return 0, false
+	}
+	p := &Point{X: x, Y: y}
+	area := tri.SignedArea()
+	if area == 0 {
+		return 0, false
+	}
+
+	// Barycentric coordinates are the areas of the sub-triangles opposite each
+	// vertex, relative to the whole
+	weightA := (&Triangle{p, tri.B, tri.C}).SignedArea() / area
+	weightB := (&Triangle{tri.A, p, tri.C}).SignedArea() / area
+	weightC := 1 - weightA - weightB
+	return float64(weightA*f.values[tri.A]) + float64(weightB*f.values[tri.B]) + float64(weightC*f.values[tri.C]), true
+}
+
+// The gradient of the field at a point. This is constant across each triangle.
+// If the point isn't in any triangle, ok is false.
+func (f *Field) Gradient(x, y float64) (dx, dy float64, ok bool) {
+	tri, ok := f.locator.TriangleAt(x, y)
+	if !ok {
+		return 0, 0, false
+	}
+
+	// Solve for the plane through the three vertex values
+	abX, abY := tri.B.X-tri.A.X, tri.B.Y-tri.A.Y
+	acX, acY := tri.C.X-tri.A.X, tri.C.Y-tri.A.Y
+	determinant := float64(abX*acY) - float64(acX*abY)
+	if determinant == 0 {
+		return 0, 0, false
+	}
+	abValue := f.values[tri.B] - f.values[tri.A]
+	acValue := f.values[tri.C] - f.values[tri.A]
+	dx = (float64(abValue*acY) - float64(acValue*abY)) / determinant
+	dy = (float64(acValue*abX) - float64(abValue*acX)) / determinant
+	return dx, dy, true
+}
@@ -0,0 +1,87 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestField_Planar(t *testing.T) {
+	spiral := LoadFixture("spiral")
+	plane := func(x, y float64) float64 { return 2*x + 3*y + 1 }
+	values := make(map[*Point]float64)
+	for _, p := range spiral.Points {
+		values[p] = plane(p.X, p.Y)
+	}
+
+	triangles := PolygonList{*spiral}.Triangulate()
+	field, err := NewField(NewLocator(triangles), values, false)
+	require.NoError(t, err)
+
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range spiral.Points {
+		minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+		maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+	}
+
+	rng := rand.New(rand.NewSource(0))
+	inside := 0
+	for i := 0; i < 2000; i++ {
+		x := minX + rng.Float64()*(maxX-minX)
+		y := minY + rng.Float64()*(maxY-minY)
+		if !spiral.ContainsPointByEvenOdd(&Point{X: x, Y: y}) {
+			continue
+		}
+		inside++
+
+		value, ok := field.At(x, y)
+		require.True(t, ok, "no value at %v, %v", x, y)
+		assert.InDelta(t, plane(x, y), value, Epsilon, "value at %v, %v", x, y)
+
+		dx, dy, ok := field.Gradient(x, y)
+		require.True(t, ok, "no gradient at %v, %v", x, y)
+		assert.InDelta(t, 2, dx, Epsilon)
+		assert.InDelta(t, 3, dy, Epsilon)
+	}
+	assert.Greater(t, inside, 100)
+
+	_, ok := field.At(minX-1, minY-1)
+	assert.False(t, ok)
+	_, _, ok = field.Gradient(maxX+1, maxY+1)
+	assert.False(t, ok)
+}
+
+func TestField_MissingValues(t *testing.T) {
+	// A square fan around a center point, with no value at the center
+	center := &Point{X: 1, Y: 1}
+	corners := []*Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}
+	var triangles TriangleList
+	for i, corner := range corners {
+		triangles = append(triangles, &Triangle{corner, corners[(i+1)%4], center})
+	}
+	values := map[*Point]float64{corners[0]: 1, corners[1]: 2, corners[2]: 3, corners[3]: 6}
+	locator := NewLocator(triangles)
+
+	_, err := NewField(locator, values, false)
+	var missingErr *MissingValuesError
+	require.True(t, errors.As(err, &missingErr))
+	assert.Equal(t, []Point{*center}, missingErr.Points)
+
+	field, err := NewField(locator, values, true)
+	require.NoError(t, err)
+	value, ok := field.At(1, 1)
+	assert.True(t, ok)
+	assert.InDelta(t, 3, value, Epsilon)
+	_, ok = values[center]
+	assert.False(t, ok, "input values should not be modified")
+
+	// A triangle with no values at all can't be filled
+	triangles = append(triangles, &Triangle{&Point{X: 5, Y: 5}, &Point{X: 6, Y: 5}, &Point{X: 5, Y: 6}})
+	_, err = NewField(NewLocator(triangles), values, true)
+	require.True(t, errors.As(err, &missingErr))
+	assert.Len(t, missingErr.Points, 3)
+}
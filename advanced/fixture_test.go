@@ -3,204 +3,94 @@ package advanced
 import (
 	"embed"
 	"log"
-	"math"
-	"strconv"
 	"strings"
 
-	"github.com/JoshVarga/svgparser"
+	"github.com/osuushi/triangulate/advanced/corpus"
 )
 
-// This file parses the svg pixtures and outputs polygons. This is not a full
-// (or even correct) svg parser. Handler. It parses the SVG and then finds
-// whatever the first polygon is, then converts that into a CCW *Polygon. If
-// anything goes wrong, it panics.
+// The fixtures come from the corpus package, which holds the SVG fixtures,
+// the shapes built in code, and inputs from bug reports. This file turns its
+// entries into polygon lists. Every call makes new points, since triangulation
+// identifies points by pointer.
 //
-// Fixtures are available by name in this fixtures/ directory, sans extension.
+// The only files left in this fixtures/ directory are data for tests of
+// individual functions, rather than shapes.
 
 //go:embed fixtures
 var fixtures embed.FS
 
+// Load an SVG fixture from the corpus, by name, sans extension
 func LoadFixture(name string) *Polygon {
-	fixture, err := fixtures.Open("fixtures/" + name + ".svg")
-	if err != nil {
-		log.Fatalf("Could not load fixture %q: %v", name, err)
-	}
+	return &ringsToPolygons([]corpus.Ring{corpus.SVG(name)})[0]
+}
 
-	defer fixture.Close()
-	rootEl, err := svgparser.Parse(fixture, true)
+// Load a text fixture from the corpus, in any format ReadPolygons accepts.
+// Unlike SVG fixtures, these may hold several polygons, and windings are left
+// as they are.
+func LoadTextFixture(name string) PolygonList {
+	list, err := ReadPolygons(strings.NewReader(corpus.File(name + ".txt")))
 	if err != nil {
 		log.Fatalf("Failed to parse fixture %q: %v", name, err)
 	}
+	return list
+}
 
-	// Find the first polygon
-	polygons := rootEl.FindAll("polygon")
-	if len(polygons) == 0 {
-		log.Fatalf("No polygons found in fixture %q", name)
-	}
-	if len(polygons) > 1 {
-		log.Fatalf("More than one polygon found in fixture %q", name)
-	}
-	polygonEl := polygons[0]
-
-	pointString := polygonEl.Attributes["points"]
-	pointStrings := strings.Split(pointString, " ")
-	points := make([]*Point, 0, len(pointStrings))
-	for _, pointString := range pointStrings {
-		if pointString == "" {
-			continue
-		}
-
-		pointStrings := strings.Split(pointString, ",")
-		if len(pointStrings) != 2 {
-			log.Fatalf("Invalid point string %q", pointString)
-		}
-		x, err := strconv.ParseFloat(pointStrings[0], 64)
+// Make a new polygon list from a corpus entry
+func corpusShape(entry corpus.Entry) PolygonList {
+	if entry.Log != "" {
+		list, err := ParsePointLogs(entry.Log)
 		if err != nil {
-			log.Fatalf("Invalid x value %q: %v", pointStrings[0], err)
+			log.Fatalf("Failed to parse corpus entry %q: %v", entry.Name, err)
 		}
-		y, err := strconv.ParseFloat(pointStrings[1], 64)
-		if err != nil {
-			log.Fatalf("Invalid y value %q: %v", pointStrings[1], err)
-		}
-		points = append(points, &Point{x, y})
+		return list
 	}
-	result := Polygon{Points: points}
+	return ringsToPolygons(entry.Rings)
+}
 
-	// Ensure that the polygon is CCW
-	if IsCW(&result) {
-		result = result.Reverse()
+func ringsToPolygons(rings []corpus.Ring) PolygonList {
+	list := make(PolygonList, len(rings))
+	for i, ring := range rings {
+		points := make([]*Point, len(ring))
+		for j, p := range ring {
+			points[j] = &Point{X: p[0], Y: p[1]}
+		}
+		list[i] = Polygon{points}
 	}
-	return &result
+	return list
 }
 
 // Some ad hoc code specified fixtures
 func SimpleStar() PolygonList {
-	var points []*Point
-	const outerRadius = 5
-	const innerRadius = 2
-	for i := 0; i < 10; i++ {
-		var radius float64
-		if i%2 == 0 {
-			radius = outerRadius
-		} else {
-			radius = innerRadius
-		}
-		angle := 2 * math.Pi * float64(i) / 10
-		points = append(points, &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)})
-	}
-	poly := Polygon{points}
-	return PolygonList{poly}
+	return corpusShape(corpus.Lookup("simple star"))
 }
 
 func SquareWithHole() PolygonList {
-	outerPoints := []*Point{
-		{X: -5, Y: -5},
-		{X: 5, Y: -5},
-		{X: 5, Y: 5},
-		{X: -5, Y: 5},
-	}
-
-	holePoints := []*Point{
-		{X: -2, Y: -2},
-		{X: -2, Y: 2},
-		{X: 2, Y: 2},
-		{X: 2, Y: -2},
-	}
-
-	return PolygonList{
-		Polygon{outerPoints},
-		Polygon{holePoints},
-	}
+	return corpusShape(corpus.Lookup("square with hole"))
 }
 
 func StarOutline() PolygonList {
-	filledPoints := []*Point{}
-	holePoints := []*Point{}
-	const filledOuterRadius = 10
-	const filledInnerRadius = 5
-	const holeOuterRadius = filledOuterRadius - 2
-	const holeInnerRadius = filledInnerRadius - 2
-	for i := 0; i < 10; i++ {
-		var (
-			filledRadius float64
-			holeRadius   float64
-		)
-		if i%2 == 0 {
-			filledRadius = filledOuterRadius
-			holeRadius = holeOuterRadius
-		} else {
-			filledRadius = filledInnerRadius
-			holeRadius = holeInnerRadius
-		}
-		angle := 2 * math.Pi * float64(i) / 10
-		filledPoints = append(filledPoints, &Point{X: filledRadius * math.Cos(angle), Y: filledRadius * math.Sin(angle)})
-		holePoints = append(holePoints, &Point{X: holeRadius * math.Cos(angle), Y: holeRadius * math.Sin(angle)})
-	}
-
-	return PolygonList{
-		Polygon{filledPoints},
-		Polygon{holePoints}.Reverse(),
-	}
+	return corpusShape(corpus.Lookup("star outline"))
 }
 
 func StarStripes() PolygonList {
-	// Multiple inset stars with alternating winding
-	var list PolygonList
-	const outerRadius = 10
-	const n = 20
-	var scale float64 = 1
-	const indentScale = 0.7
-	const gapScale = 0.9
-
-	for i := 0; i < n; i++ {
-		var points []*Point
-		for j := 0; j < 10; j++ {
-			angle := 2 * math.Pi * float64(j) / 10
-			r := outerRadius * scale
-			if j%2 == 1 {
-				r *= indentScale
-			}
-			points = append(points, &Point{X: r * math.Cos(angle), Y: r * math.Sin(angle)})
-		}
-		scale *= gapScale
-		poly := Polygon{points}
-		if i%2 == 1 {
-			poly = poly.Reverse()
-		}
-		list = append(list, poly)
-	}
-	return list
+	return corpusShape(corpus.Lookup("star stripes"))
 }
 
 func MultiLayeredHoles() PolygonList {
-	// In this test, we want multiple holes which contain filled shapes inside.
-	makeStar := func(x, y, outerRadius, innerRadius float64) Polygon {
-		points := []*Point{}
-		for i := 0; i < 10; i++ {
-			angle := 2 * math.Pi * float64(i) / 10
-			r := outerRadius
-			if i%2 == 1 {
-				r = innerRadius
-			}
-			points = append(points, &Point{X: x + r*math.Cos(angle), Y: y + r*math.Sin(angle)})
+	return corpusShape(corpus.Lookup("multi layered holes"))
+}
+
+// Every fixture, as a function to load a fresh copy, since triangulation
+// identifies points by pointer. Huge entries are left out, since they would
+// only slow down tests which run every fixture several times.
+func allFixtures() map[string]func() PolygonList {
+	result := make(map[string]func() PolygonList)
+	for _, entry := range corpus.Entries() {
+		if entry.Huge {
+			continue
 		}
-		return Polygon{points}
+		entry := entry
+		result[entry.Name] = func() PolygonList { return corpusShape(entry) }
 	}
-	list := PolygonList{
-		// Outer star
-		makeStar(0, 0, 10, 7),
-		// Top hole
-		makeStar(1.5, 5, 3, 2).Reverse(),
-		// Top inner
-		makeStar(1.5, 5, 2, 1),
-		// Bottom hole
-		makeStar(1.8, -5, 3, 2).Reverse(),
-		// Bottom inner
-		makeStar(1.8, -5, 2, 1),
-		// Left hole
-		makeStar(-3, 0, 4, 2).Reverse(),
-		// Left inner
-		makeStar(-3, 0, 3, 1),
-	}
-	return list
+	return result
 }
@@ -0,0 +1,52 @@
+package advanced
+
+// Once the trapezoid map is built, it knows for certain which side of every
+// edge is inside, so checking that each hole sits in a solid costs one pass
+// over the trapezoids. Every trapezoid beside a hole, on the side away from the
+// hole, must be inside: bounded on the left by a segment pointing down, and on
+// the right by one pointing up. A hole outside every solid, or inside another
+// hole, leaves a trapezoid there with a missing or backwards side.
+
+// Check that each clockwise ring of the list the graph was built from is
+// inside a solid, throwing a MisplacedHoleError for the first one which isn't,
+// naming the first of its edges which borders the outside. This only makes
+// sense under the winding rule, since under EvenOdd, a ring's nesting decides
+// whether it's a hole.
+func (graph *QueryGraph) checkHolePlacement(list PolygonList) {
+	windings := list.Windings()
+	var first *segmentSource
+	isHoleEdge := func(s *Segment) bool {
+		if s == nil || graph.isShared(s) {
+			return false
+		}
+		source, ok := graph.sourceOf(s)
+		return ok && source.polygon >= 0 && source.polygon < len(list) && !windings[source.polygon]
+	}
+	report := func(s *Segment) {
+		source, _ := graph.sourceOf(s)
+		if first == nil ||
+			source.polygon < first.polygon ||
+			(source.polygon == first.polygon && source.edge < first.edge) {
+			first = &source
+		}
+	}
+	pointsUp := func(s *Segment) bool {
+		return s != nil && (!s.PointsDown() || graph.isShared(s))
+	}
+
+	for trapezoid := range graph.IterateTrapezoids() {
+		left, right := trapezoid.Left, trapezoid.Right
+		// A hole runs clockwise, so its edges pointing down have the hole on
+		// their left, and those pointing up have it on their right. Either way,
+		// this trapezoid is on the side away from the hole.
+		if isHoleEdge(left) && left.PointsDown() && !pointsUp(right) {
+			report(left)
+		}
+		if isHoleEdge(right) && !right.PointsDown() && !trapezoid.IsInside() {
+			report(right)
+		}
+	}
+	if first != nil {
+		throw(&MisplacedHoleError{Polygon: first.polygon, Edge: first.edge})
+	}
+}
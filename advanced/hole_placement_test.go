@@ -0,0 +1,74 @@
+package advanced
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolePlacement(t *testing.T) {
+	square := squareRing(0, 0, 10)
+	validate := Options{Validate: true}
+
+	t.Run("inside", func(t *testing.T) {
+		assert.NoError(t, triangulateRecovering(PolygonList{square, squareRing(3, 3, 4).Reverse()}, validate))
+		assert.NoError(t, triangulateRecovering(SquareWithHole(), validate))
+		for name, fixture := range allFixtures() {
+			if fixture().Validate() != nil {
+				continue
+			}
+			assert.NoError(t, triangulateRecovering(fixture(), validate), name)
+		}
+	})
+
+	t.Run("outside", func(t *testing.T) {
+		err := triangulateRecovering(PolygonList{square, squareRing(20, 3, 4).Reverse()}, validate)
+		var misplaced *MisplacedHoleError
+		require.True(t, errors.As(err, &misplaced), "%v", err)
+		assert.Equal(t, 1, misplaced.Polygon)
+		assert.Contains(t, err.Error(), "hole 1 is not inside a solid")
+
+		// Without validating, the hole is quietly ignored
+		assert.NoError(t, triangulateRecovering(PolygonList{square, squareRing(20, 3, 4).Reverse()}, Options{}))
+	})
+
+	t.Run("inside another hole", func(t *testing.T) {
+		list := PolygonList{square, squareRing(1, 1, 8).Reverse(), squareRing(3, 3, 4).Reverse()}
+		err := triangulateRecovering(list, validate)
+		var misplaced *MisplacedHoleError
+		require.True(t, errors.As(err, &misplaced), "%v", err)
+		assert.Equal(t, 2, misplaced.Polygon)
+	})
+
+	t.Run("beside the solid", func(t *testing.T) {
+		// Left of the solid, the trapezoid between the hole and the solid has
+		// sides, but they point the wrong way
+		err := triangulateRecovering(PolygonList{square, squareRing(-6, 3, 4).Reverse()}, validate)
+		var misplaced *MisplacedHoleError
+		require.True(t, errors.As(err, &misplaced), "%v", err)
+		assert.Equal(t, 1, misplaced.Polygon)
+	})
+
+	t.Run("straddling", func(t *testing.T) {
+		// The hole's top and bottom edges, 0 and 2, cross the solid's right
+		// edge, 1, which the check before building already finds
+		hole := squareRing(8, 4, 4).Reverse()
+		err := triangulateRecovering(PolygonList{square, hole}, validate)
+		var crossing *CrossingEdgesError
+		require.True(t, errors.As(err, &crossing), "%v", err)
+		holeEdge, squareEdge := crossing.SecondEdge, crossing.FirstEdge
+		if crossing.FirstPolygon == 1 {
+			holeEdge, squareEdge = crossing.FirstEdge, crossing.SecondEdge
+		}
+		assert.Contains(t, []int{0, 2}, holeEdge)
+		assert.Equal(t, 1, squareEdge)
+	})
+
+	t.Run("even odd", func(t *testing.T) {
+		// Nesting decides what's a hole, so a lone ring is a solid
+		list := PolygonList{square, squareRing(20, 3, 4).Reverse()}
+		assert.NoError(t, triangulateRecovering(list, Options{Validate: true, FillRule: EvenOdd}))
+	})
+}
@@ -0,0 +1,59 @@
+package advanced
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Triangulate vertices of any type, such as float32 vertex buffers or structs
+// in a game engine, without the caller building points. There are n vertices,
+// and at gives the coordinates of vertex i. Each ring lists the indexes of its
+// vertices, with the same windings as Polygon: solids counterclockwise, and
+// holes clockwise. Rings may share vertices. The result has a triple of vertex
+// indexes for each triangle.
+//
+// at is called once for each vertex the rings use, before triangulating.
+func TriangulateIndexed(n int, at func(i int) (x, y float64), rings [][]int, opts Options) (result [][3]int, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+
+	if n > math.MaxInt32 {
+		return nil, errors.Errorf("%d vertices is more than can be indexed", n)
+	}
+	// Each point carries its index, so the result needs no map back. Rings
+	// which share an index share its point.
+	points := make([]*Point, n)
+	list := make(PolygonList, len(rings))
+	for i, ring := range rings {
+		if len(ring) < 3 {
+			return nil, errors.Errorf("ring %d has %d vertices, but rings need at least 3", i, len(ring))
+		}
+		list[i].Points = make([]*Point, len(ring))
+		for j, index := range ring {
+			if index < 0 || index >= n {
+				return nil, errors.Errorf("ring %d refers to vertex %d, but there are %d vertices", i, index, n)
+			}
+			if points[index] == nil {
+				x, y := at(index)
+				points[index] = &Point{X: x, Y: y, UserIndex: int32(index), HasUserIndex: true}
+			}
+			list[i].Points[j] = points[index]
+		}
+	}
+
+	triangles := list.TriangulateWithOptions(opts)
+	result = make([][3]int, len(triangles))
+	for i, tri := range triangles {
+		for j, p := range [3]*Point{tri.A, tri.B, tri.C} {
+			if !p.HasUserIndex {
+				return nil, errors.Errorf("triangle references point %v, which is not in the input", p)
+			}
+			result[i][j] = int(p.UserIndex)
+		}
+	}
+	return result, nil
+}
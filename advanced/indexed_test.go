@@ -0,0 +1,72 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriangulateIndexed_Float32(t *testing.T) {
+	// A 10×10 square with a 4×4 hole, as a game engine would keep it
+	vertices := [][2]float32{
+		{0, 0}, {10, 0}, {10, 10}, {0, 10},
+		{3, 3}, {3, 7}, {7, 7}, {7, 3},
+	}
+	at := func(i int) (x, y float64) {
+		return float64(vertices[i][0]), float64(vertices[i][1])
+	}
+	triangles, err := TriangulateIndexed(len(vertices), at, [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}}, Options{})
+	require.NoError(t, err)
+	assert.Len(t, triangles, 8)
+
+	var area float64
+	for _, tri := range triangles {
+		a, b, c := vertices[tri[0]], vertices[tri[1]], vertices[tri[2]]
+		signed := (float64(b[0]-a[0])*float64(c[1]-a[1]) - float64(c[0]-a[0])*float64(b[1]-a[1])) / 2
+		assert.Greater(t, signed, 0.0)
+		area += signed
+	}
+	assert.Equal(t, 100.0-16, area)
+}
+
+func TestTriangulateIndexed_SharedVertex(t *testing.T) {
+	// Two triangles touching at vertex 2, with an unused vertex 5
+	coords := []float64{0, 0, 2, 0, 1, 1, 0, 2, 2, 2, 9, 9}
+	calls := 0
+	at := func(i int) (x, y float64) {
+		calls++
+		return coords[2*i], coords[2*i+1]
+	}
+	triangles, err := TriangulateIndexed(6, at, [][]int{{0, 1, 2}, {2, 4, 3}}, Options{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][3]int{{0, 1, 2}, {2, 4, 3}}, rotateTriples(triangles))
+	assert.Equal(t, 5, calls)
+}
+
+// Rotate each triple to start with its smallest index, for comparing
+// triangles whatever vertex they start from
+func rotateTriples(triples [][3]int) [][3]int {
+	result := make([][3]int, len(triples))
+	for i, triple := range triples {
+		for triple[0] > triple[1] || triple[0] > triple[2] {
+			triple = [3]int{triple[1], triple[2], triple[0]}
+		}
+		result[i] = triple
+	}
+	return result
+}
+
+func TestTriangulateIndexed_Errors(t *testing.T) {
+	at := func(i int) (x, y float64) { return float64(i), float64(i * i) }
+	_, err := TriangulateIndexed(4, at, [][]int{{0, 1}}, Options{})
+	assert.EqualError(t, err, "ring 0 has 2 vertices, but rings need at least 3")
+
+	_, err = TriangulateIndexed(4, at, [][]int{{0, 1, 4}}, Options{})
+	assert.EqualError(t, err, "ring 0 refers to vertex 4, but there are 4 vertices")
+
+	// Errors from triangulating come through too
+	_, err = TriangulateIndexed(4, at, [][]int{{0, 1, 2, 1}}, Options{Validate: true})
+	var duplicate *DuplicatePointError
+	assert.ErrorAs(t, err, &duplicate)
+}
@@ -0,0 +1,175 @@
+package advanced
+
+// Integer input, for geometry which is natively on a grid, such as CAD units or
+// map tiles. Rather than a second numeric kernel, the input is reduced into a
+// range where the floating point kernel provably makes the same decisions as
+// exact arithmetic would. See reduceIntPolygons. Input too wide for that is
+// clipped into ears with exact predicates instead. See earClipInt.
+
+// A point with integer coordinates, for TriangulateInt
+type PointI struct {
+	X, Y int64
+}
+
+// A triangle from TriangulateInt, whose vertices are the input points
+type TriangleI struct {
+	A, B, C *PointI
+}
+
+type TriangleIList []*TriangleI
+
+// The largest span of integer coordinates TriangulateInt hands to the
+// trapezoid map along either axis, after dividing out the greatest common
+// divisor of the coordinates' offsets from the bounding box. So a grid of any
+// spacing and any position is fast, as long as it's at most this many steps
+// across. Wider input falls back to exact ear clipping.
+const MaxIntSpan = 1 << 20
+
+// Convert the triangles to floating point, with one Point for each distinct
+// PointI, so that triangles sharing a vertex share the converted point too.
+// Coordinates beyond ±2^53 are rounded.
+func (triangles TriangleIList) ToTriangleList() TriangleList {
+	converted := make(map[*PointI]*Point)
+	convert := func(p *PointI) *Point {
+		if result, ok := converted[p]; ok {
+			return result
+		}
+		result := fabricatedPoint(float64(p.X), float64(p.Y))
+		converted[p] = result
+		return result
+	}
+	result := make(TriangleList, len(triangles))
+	for i, t := range triangles {
+		result[i] = &Triangle{convert(t.A), convert(t.B), convert(t.C)}
+	}
+	return result
+}
+
+// Triangulate rings with integer coordinates. As with Polygon, solids run
+// counterclockwise, and holes clockwise. The triangles' vertices are the input
+// points.
+//
+// Every decision is made exactly, with no tolerance, so unlike float input,
+// points which are close together are never confused. The input can be
+// anywhere in the int64 range. If it spans more than MaxIntSpan steps of its
+// grid along either axis, it's triangulated by ear clipping, which is exact
+// at any span but takes quadratic time or worse, and ignores opts.
+// Options.AutoNormalize and Options.Tolerance are always ignored, since
+// they're never needed.
+func TriangulateInt(rings [][]*PointI, opts Options) (result TriangleIList, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+
+	list, sources, ok := reduceIntPolygons(rings)
+	if !ok {
+		return earClipInt(rings), nil
+	}
+	opts.AutoNormalize = false
+	opts.Tolerance = 0
+	triangles := list.TriangulateWithOptions(opts)
+
+	result = make(TriangleIList, len(triangles))
+	source := func(p *Point) *PointI {
+		original, ok := sources[p]
+		if !ok {
+			fatalf("output point %v is not an input point", p)
+		}
+		return original
+	}
+	for i, t := range triangles {
+		result[i] = &TriangleI{source(t.A), source(t.B), source(t.C)}
+	}
+	return result, nil
+}
+
+// Reduce integer rings to float rings, with a map from each float point back to
+// the PointI it came from, or return false if they span more than MaxIntSpan. The offsets from the bounding box's corner are
+// divided by their greatest common divisor, then centered on the origin. That's
+// a translation and a uniform scale, so it changes nothing about the shape.
+//
+// The result is whole numbers within ±MaxIntSpan/2, which are well within
+// ±MaxCoordinate, and are exact in float64, as are their differences and the
+// products of two differences. Every predicate compares those, or the X of a
+// segment at some vertex's Y, which is a fraction with the segment's height as
+// its denominator. So two quantities which differ at all differ by at least
+// 1/MaxIntSpan, almost ten times Epsilon, while the rounding error at this
+// magnitude is far below Epsilon. That means Epsilon never decides anything,
+// and every decision is the one exact arithmetic would make.
+func reduceIntPolygons(rings [][]*PointI) (PolygonList, map[*Point]*PointI, bool) {
+	var minX, minY, maxX, maxY int64
+	first := true
+	for _, ring := range rings {
+		for _, p := range ring {
+			if first || p.X < minX {
+				minX = p.X
+			}
+			if first || p.X > maxX {
+				maxX = p.X
+			}
+			if first || p.Y < minY {
+				minY = p.Y
+			}
+			if first || p.Y > maxY {
+				maxY = p.Y
+			}
+			first = false
+		}
+	}
+
+	// Offsets are unsigned, since the span of an int64 range can be up to 2^64-1.
+	// The subtraction wraps, but the true offset is never negative, so the
+	// result is exact.
+	offset := func(v, min int64) uint64 {
+		return uint64(v) - uint64(min)
+	}
+	var divisor uint64
+	for _, ring := range rings {
+		for _, p := range ring {
+			divisor = gcd(divisor, offset(p.X, minX))
+			divisor = gcd(divisor, offset(p.Y, minY))
+		}
+	}
+	if divisor == 0 {
+		// Every point is the same, or there are none
+		divisor = 1
+	}
+
+	spanX := offset(maxX, minX) / divisor
+	spanY := offset(maxY, minY) / divisor
+	if spanX > MaxIntSpan || spanY > MaxIntSpan {
+		return nil, nil, false
+	}
+
+	// A PointI used more than once must stay one point, since points are
+	// identified by pointer
+	reduced := make(map[*PointI]*Point)
+	sources := make(map[*Point]*PointI)
+	list := make(PolygonList, len(rings))
+	for i, ring := range rings {
+		points := make([]*Point, len(ring))
+		for j, p := range ring {
+			point, ok := reduced[p]
+			if !ok {
+				point = fabricatedPoint(
+					float64(int64(offset(p.X, minX)/divisor)-int64(spanX/2)),
+					float64(int64(offset(p.Y, minY)/divisor)-int64(spanY/2)),
+				)
+				reduced[p] = point
+				sources[point] = p
+			}
+			points[j] = point
+		}
+		list[i] = Polygon{points}
+	}
+	return list, sources, true
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
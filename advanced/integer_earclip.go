@@ -0,0 +1,575 @@
+package advanced
+
+import (
+	"math/big"
+	"math/bits"
+	"sort"
+)
+
+// Exact triangulation of integer input too wide for reduceIntPolygons. The
+// float kernel can't be trusted there, so this clips ears instead, with every
+// predicate computed exactly: in int64 and 128 bit products when the
+// differences fit, and in big.Int or big.Rat when they don't. Each solid has
+// its holes bridged into its outline first, the way Eberly describes, and the
+// outline is split wherever it touches itself, so that each piece is clipped
+// as a single ring. This takes quadratic time or worse, so it's only for what
+// the trapezoid map can't do exactly.
+
+// A ring of integer points, for earClipInt
+type intRing struct {
+	// Index of the ring in the input list
+	index  int
+	points []*PointI
+	// Twice the ring's signed area
+	area *big.Int
+}
+
+// A vertex of the outline being clipped
+type earNode struct {
+	point      *PointI
+	prev, next *earNode
+}
+
+func earClipInt(rings [][]*PointI) TriangleIList {
+	var solids, holes []*intRing
+	for i, ring := range rings {
+		if len(ring) < 3 {
+			throw(&TooFewPointsError{Polygon: i, Points: len(ring)})
+		}
+		points := withoutRepeatsI(ring)
+		area := doubleAreaI(points)
+		// A ring which encloses no area has nothing to triangulate
+		switch area.Sign() {
+		case 1:
+			solids = append(solids, &intRing{i, points, area})
+		case -1:
+			holes = append(holes, &intRing{i, points, area})
+		}
+	}
+
+	// Each hole belongs to the smallest solid around it, since a larger one
+	// around that would have the smaller one's area cut out already
+	children := make(map[*intRing][]*intRing)
+	for _, hole := range holes {
+		var parent *intRing
+		for _, solid := range solids {
+			if ringInsideI(hole.points, solid.points) && (parent == nil || solid.area.Cmp(parent.area) < 0) {
+				parent = solid
+			}
+		}
+		if parent == nil {
+			throw(&MisplacedHoleError{Polygon: hole.index})
+		}
+		children[parent] = append(children[parent], hole)
+	}
+
+	var result TriangleIList
+	for _, solid := range solids {
+		for _, cycle := range splitAtPinchesI(bridgeHolesI(solid.points, children[solid])) {
+			result = append(result, clipEarsI(cycle)...)
+		}
+	}
+	return result
+}
+
+// Merge the holes into the outline, joining each to a vertex of the outline it
+// can see. Holes are merged from right to left, so that the ray cast from each
+// finds the outline as it will be once every hole to its right is merged.
+func bridgeHolesI(outline []*PointI, holes []*intRing) []*PointI {
+	rightmost := make(map[*intRing]int, len(holes))
+	for _, hole := range holes {
+		for i, p := range hole.points {
+			if p.X > hole.points[rightmost[hole]].X {
+				rightmost[hole] = i
+			}
+		}
+	}
+	sort.SliceStable(holes, func(i, j int) bool {
+		return holes[i].points[rightmost[holes[i]]].X > holes[j].points[rightmost[holes[j]]].X
+	})
+
+	outline = append([]*PointI(nil), outline...)
+	for _, hole := range holes {
+		outline = withoutRepeatsI(bridgeHoleI(outline, hole.points, rightmost[hole]))
+	}
+	return outline
+}
+
+// Splice the hole into the outline, joined at its rightmost vertex m. A ray
+// cast from m toward +X hits the nearest edge of the outline at some point. If
+// that's a vertex, it's the bridge's other end. Otherwise the edge's right end
+// is, unless some vertex inside the triangle between m, the hit and that end
+// would block the bridge, in which case the one at the smallest angle to the
+// ray is, since nothing can block that one.
+func bridgeHoleI(outline []*PointI, hole []*PointI, m int) []*PointI {
+	mp := hole[m]
+	mx := new(big.Rat).SetInt64(mp.X)
+	var nearest *big.Rat
+	hitEdge, hitVertex := -1, -1
+	hit := func(x *big.Rat, edge, vertex int) {
+		if nearest == nil || x.Cmp(nearest) < 0 {
+			nearest, hitEdge, hitVertex = x, edge, vertex
+		}
+	}
+	for i, a := range outline {
+		j := CircularIndex(i+1, len(outline))
+		b := outline[j]
+		if (a.Y > mp.Y && b.Y > mp.Y) || (a.Y < mp.Y && b.Y < mp.Y) {
+			continue
+		}
+		if a.Y == b.Y {
+			// Along the ray, so it's the nearer end that's hit
+			for _, k := range []int{i, j} {
+				if outline[k].X >= mp.X {
+					hit(new(big.Rat).SetInt64(outline[k].X), i, k)
+				}
+			}
+			continue
+		}
+		x := crossingXI(a, b, mp.Y)
+		if x.Cmp(mx) < 0 {
+			continue
+		}
+		switch mp.Y {
+		case a.Y:
+			hit(x, i, i)
+		case b.Y:
+			hit(x, i, j)
+		default:
+			hit(x, i, -1)
+		}
+	}
+	if nearest == nil {
+		fatalf("nothing to the right of hole vertex %v", *mp)
+	}
+
+	if hitVertex >= 0 {
+		return spliceHoleI(outline, hitVertex, hole, m, true)
+	}
+	if nearest.Cmp(mx) == 0 {
+		// The hole touches the middle of the edge, so it's spliced in there,
+		// with no bridge at all
+		return spliceHoleI(outline, hitEdge, hole, m, false)
+	}
+
+	a, b := outline[hitEdge], outline[CircularIndex(hitEdge+1, len(outline))]
+	end, direction := CircularIndex(hitEdge+1, len(outline)), 1
+	if a.X > b.X {
+		end, direction = hitEdge, -1
+	}
+	p := outline[end]
+	// The orientation of the triangle from m to the hit to p. Since the hit is
+	// on the ray, that's just which side of the ray p is on.
+	side := 1
+	if p.Y < mp.Y {
+		side = -1
+	}
+
+	best := end
+	for i, r := range outline {
+		if samePointI(r, p) {
+			continue
+		}
+		inside := signInt64(r.Y, mp.Y)*side >= 0 &&
+			orientI(a, b, r)*direction*side >= 0 &&
+			orientI(p, mp, r)*side >= 0
+		if inside && closerToRayI(mp, r, outline[best]) {
+			best = i
+		}
+	}
+	return spliceHoleI(outline, best, hole, m, true)
+}
+
+// Whether r is at a smaller angle to the +X ray from m than best is, or at the
+// same angle but nearer. Both are right of m.
+func closerToRayI(m, r, best *PointI) bool {
+	rx, ry := bigDifference(r.X, m.X), bigDifference(r.Y, m.Y)
+	bx, by := bigDifference(best.X, m.X), bigDifference(best.Y, m.Y)
+	ry.Abs(ry)
+	by.Abs(by)
+	switch new(big.Int).Mul(ry, bx).Cmp(new(big.Int).Mul(by, rx)) {
+	case -1:
+		return true
+	case 0:
+		return rx.Cmp(bx) < 0
+	}
+	return false
+}
+
+// Insert the hole, from m all the way around back to m, after the outline's
+// vertex at index. With a bridge, the outline's vertex is repeated after the
+// hole, so that the bridge is walked both ways.
+func spliceHoleI(outline []*PointI, index int, hole []*PointI, m int, bridge bool) []*PointI {
+	result := make([]*PointI, 0, len(outline)+len(hole)+2)
+	result = append(result, outline[:index+1]...)
+	for i := 0; i <= len(hole); i++ {
+		result = append(result, hole[CircularIndex(m+i, len(hole))])
+	}
+	if bridge {
+		result = append(result, outline[index])
+	}
+	return append(result, outline[index+1:]...)
+}
+
+// Where the outline passes through a point more than once, because rings
+// touch there or a bridge ends there, each pass is a corner between the edge
+// arriving and the edge leaving. Those corners can overlap, such as when two
+// holes touch at their corners, and each corner only knows about its own
+// hole. So the passes are rewired, pairing each edge leaving the point with
+// the first edge arriving counterclockwise from it, which makes the corners
+// the wedges between the edges. That can split the outline into several,
+// such as when holes touching at their corners wall off a pocket.
+func splitAtPinchesI(outline []*PointI) [][]*PointI {
+	n := len(outline)
+	next := make([]int, n)
+	passes := make(map[PointI][]int)
+	for i, p := range outline {
+		next[i] = CircularIndex(i+1, n)
+		passes[*p] = append(passes[*p], i)
+	}
+
+	for _, group := range passes {
+		if len(group) < 2 {
+			continue
+		}
+		p := outline[group[0]]
+		rewired := make(map[int]int, len(group))
+		arrivals := make(map[int]bool, len(group))
+		for _, leaving := range group {
+			out := outline[next[leaving]]
+			arriving := -1
+			for _, candidate := range group {
+				in := outline[CircularIndex(candidate-1, n)]
+				if arriving < 0 || counterclockwiseBeforeI(p, out, in, outline[CircularIndex(arriving-1, n)]) {
+					arriving = candidate
+				}
+			}
+			rewired[arriving] = next[leaving]
+			arrivals[arriving] = true
+		}
+		// Only possible if the rings cross here, in which case they're left
+		// for the ear clipping to fail on
+		if len(arrivals) < len(group) {
+			continue
+		}
+		for arriving, following := range rewired {
+			next[arriving] = following
+		}
+	}
+
+	var cycles [][]*PointI
+	visited := make([]bool, n)
+	for start := range outline {
+		var cycle []*PointI
+		for i := start; !visited[i]; i = next[i] {
+			visited[i] = true
+			cycle = append(cycle, outline[i])
+		}
+		if len(cycle) >= 3 {
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles
+}
+
+// Whether, turning counterclockwise around p from the direction of o, the
+// direction of a comes before that of b. The direction of o itself comes last,
+// a full turn around, since the edge arriving along the edge leaving bounds
+// the corner on its far side.
+func counterclockwiseBeforeI(p, o, a, b *PointI) bool {
+	turn := func(d *PointI) int {
+		switch side := orientI(p, o, d); {
+		case side > 0:
+			return 0
+		case side < 0:
+			return 1
+		case foldsBackI(o, p, d):
+			return 2
+		}
+		// A half turn, the last of the first half
+		return 0
+	}
+	turnA, turnB := turn(a), turn(b)
+	if turnA != turnB {
+		return turnA < turnB
+	}
+	return turnA != 2 && orientI(p, a, b) > 0
+}
+
+// Clip ears from a counterclockwise outline until only a triangle is left. An
+// ear is a strictly convex corner with no other vertex inside or on it, except
+// for vertices at its own corners, which is where the outline touches itself.
+// Edges leaving those mustn't head into the ear either.
+func clipEarsI(points []*PointI) TriangleIList {
+	n := len(points)
+	if n < 3 {
+		return nil
+	}
+	nodes := make([]earNode, n)
+	for i := range nodes {
+		nodes[i] = earNode{
+			point: points[i],
+			prev:  &nodes[CircularIndex(i-1, n)],
+			next:  &nodes[CircularIndex(i+1, n)],
+		}
+	}
+
+	var result TriangleIList
+	node := &nodes[0]
+	for remaining, stalled := n, 0; remaining > 3; {
+		if foldsBackI(node.prev.point, node.point, node.next.point) {
+			// The tip of a spike, where the outline touches itself along an
+			// edge. It encloses nothing, and would fool the ear test at the
+			// spike's base, where the outline touches itself, so it's cut off.
+			node = unlinkEarNode(node)
+			remaining--
+			if samePointI(node.prev.point, node.point) {
+				node = unlinkEarNode(node)
+				remaining--
+			}
+			stalled = 0
+			continue
+		}
+		if isEarI(node) {
+			result = append(result, &TriangleI{node.prev.point, node.point, node.next.point})
+			node = unlinkEarNode(node)
+			remaining--
+			stalled = 0
+			continue
+		}
+		node = node.next
+		stalled++
+		if stalled > remaining {
+			// No corner is an ear, which can only be because some are straight
+			// or fold back on themselves, and those enclose nothing
+			node = dropCollinearI(node, remaining)
+			remaining--
+			stalled = 0
+		}
+	}
+	if orientI(node.prev.point, node.point, node.next.point) > 0 {
+		result = append(result, &TriangleI{node.prev.point, node.point, node.next.point})
+	}
+	return result
+}
+
+func isEarI(node *earNode) bool {
+	a, b, c := node.prev.point, node.point, node.next.point
+	if orientI(a, b, c) <= 0 {
+		return false
+	}
+	for other := node.next.next; other != node.prev; other = other.next {
+		p := other.point
+		switch {
+		case samePointI(p, a):
+			if entersCornerI(a, b, c, other) {
+				return false
+			}
+		case samePointI(p, b):
+			if entersCornerI(b, c, a, other) {
+				return false
+			}
+		case samePointI(p, c):
+			if entersCornerI(c, a, b, other) {
+				return false
+			}
+		case orientI(a, b, p) >= 0 && orientI(b, c, p) >= 0 && orientI(c, a, p) >= 0:
+			return false
+		}
+	}
+	return true
+}
+
+// Whether either edge at other, which is at the ear's corner v, heads strictly
+// between the ear's sides from v to u1 and from v to u2, counterclockwise
+func entersCornerI(v, u1, u2 *PointI, other *earNode) bool {
+	for _, n := range []*PointI{other.prev.point, other.next.point} {
+		if orientI(v, u1, n) > 0 && orientI(v, u2, n) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether a, b and c are collinear, with a and c on the same side of b
+func foldsBackI(a, b, c *PointI) bool {
+	if orientI(a, b, c) != 0 {
+		return false
+	}
+	if a.X != b.X {
+		return signInt64(a.X, b.X) == signInt64(c.X, b.X)
+	}
+	return signInt64(a.Y, b.Y) == signInt64(c.Y, b.Y)
+}
+
+// Remove a node from the outline, returning the one after it
+func unlinkEarNode(node *earNode) *earNode {
+	node.prev.next, node.next.prev = node.next, node.prev
+	return node.next
+}
+
+func dropCollinearI(start *earNode, remaining int) *earNode {
+	node := start
+	for i := 0; i < remaining; i++ {
+		if orientI(node.prev.point, node.point, node.next.point) == 0 {
+			return unlinkEarNode(node)
+		}
+		node = node.next
+	}
+	fatalf("no ear among %d remaining vertices; the rings may cross", remaining)
+	return nil
+}
+
+// Whether every point of inner is inside or on outer, judged by the first point
+// which isn't on outer's boundary
+func ringInsideI(inner, outer []*PointI) bool {
+	for _, p := range inner {
+		if location := locateI(p, outer); location != 0 {
+			return location > 0
+		}
+	}
+	return true
+}
+
+// Whether p is inside the ring (1), outside it (-1), or on its boundary (0), by
+// counting the edges which cross the +X ray from p
+func locateI(p *PointI, ring []*PointI) int {
+	inside := false
+	for i, a := range ring {
+		b := ring[CircularIndex(i+1, len(ring))]
+		side := orientI(a, b, p)
+		if side == 0 && betweenI(a, b, p) {
+			return 0
+		}
+		if (a.Y > p.Y) != (b.Y > p.Y) {
+			// The edge crosses p's line right of p when p is left of the edge,
+			// taken upward
+			if (side > 0) == (b.Y > a.Y) {
+				inside = !inside
+			}
+		}
+	}
+	if inside {
+		return 1
+	}
+	return -1
+}
+
+// Whether p, which is collinear with a and b, is on the segment between them
+func betweenI(a, b, p *PointI) bool {
+	return (a.X <= p.X) == (p.X <= b.X) && (a.Y <= p.Y) == (p.Y <= b.Y) ||
+		samePointI(p, a) || samePointI(p, b)
+}
+
+// The X at which the segment from a to b crosses the line at y. The segment
+// can't be horizontal.
+func crossingXI(a, b *PointI, y int64) *big.Rat {
+	x := new(big.Int).Mul(bigDifference(y, a.Y), bigDifference(b.X, a.X))
+	result := new(big.Rat).SetFrac(x, bigDifference(b.Y, a.Y))
+	return result.Add(result, new(big.Rat).SetInt64(a.X))
+}
+
+// Drop points which repeat the one before them, around the ring, keeping the
+// first
+func withoutRepeatsI(ring []*PointI) []*PointI {
+	result := make([]*PointI, 0, len(ring))
+	for _, p := range ring {
+		if len(result) == 0 || !samePointI(result[len(result)-1], p) {
+			result = append(result, p)
+		}
+	}
+	for len(result) > 1 && samePointI(result[0], result[len(result)-1]) {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+func samePointI(a, b *PointI) bool {
+	return a.X == b.X && a.Y == b.Y
+}
+
+func doubleAreaI(points []*PointI) *big.Int {
+	area := new(big.Int)
+	var x, y, product big.Int
+	for i, p := range points {
+		next := points[CircularIndex(i+1, len(points))]
+		product.Mul(x.SetInt64(p.X), y.SetInt64(next.Y))
+		area.Add(area, &product)
+		product.Mul(x.SetInt64(next.X), y.SetInt64(p.Y))
+		area.Sub(area, &product)
+	}
+	return area
+}
+
+// The sign of the cross product of b-a and c-a: positive when a, b and c turn
+// counterclockwise, negative when they turn clockwise, and zero when they're
+// collinear
+func orientI(a, b, c *PointI) int {
+	abX, okABX := differenceInt64(b.X, a.X)
+	abY, okABY := differenceInt64(b.Y, a.Y)
+	acX, okACX := differenceInt64(c.X, a.X)
+	acY, okACY := differenceInt64(c.Y, a.Y)
+	if okABX && okABY && okACX && okACY {
+		return compareProducts(abX, acY, abY, acX)
+	}
+	cross := new(big.Int).Mul(bigDifference(b.X, a.X), bigDifference(c.Y, a.Y))
+	return cross.Sub(cross, new(big.Int).Mul(bigDifference(b.Y, a.Y), bigDifference(c.X, a.X))).Sign()
+}
+
+// a-b, and whether it fit in an int64
+func differenceInt64(a, b int64) (int64, bool) {
+	difference := a - b
+	return difference, (a >= 0) == (b >= 0) || (difference >= 0) == (a >= 0)
+}
+
+func bigDifference(a, b int64) *big.Int {
+	return new(big.Int).Sub(big.NewInt(a), big.NewInt(b))
+}
+
+// The sign of a*b - c*d, with the products in 128 bits
+func compareProducts(a, b, c, d int64) int {
+	left, right := signInt64(a, 0)*signInt64(b, 0), signInt64(c, 0)*signInt64(d, 0)
+	if left != right {
+		return signInt64(int64(left), int64(right))
+	}
+	if left == 0 {
+		return 0
+	}
+	leftHigh, leftLow := bits.Mul64(absInt64(a), absInt64(b))
+	rightHigh, rightLow := bits.Mul64(absInt64(c), absInt64(d))
+	switch {
+	case leftHigh != rightHigh:
+		return left * signUint64(leftHigh, rightHigh)
+	default:
+		return left * signUint64(leftLow, rightLow)
+	}
+}
+
+// The sign of a-b, without computing it
+func signInt64(a, b int64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
+
+func signUint64(a, b uint64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
+
+// |v|, which fits in a uint64 even for math.MinInt64
+func absInt64(v int64) uint64 {
+	if v < 0 {
+		return -uint64(v)
+	}
+	return uint64(v)
+}
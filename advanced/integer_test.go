@@ -0,0 +1,230 @@
+package advanced
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced/corpus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Convert integer valued float rings to PointI, keeping shared points shared
+func polygonsToInt(list PolygonList) [][]*PointI {
+	converted := make(map[*Point]*PointI)
+	rings := make([][]*PointI, len(list))
+	for i, poly := range list {
+		for _, p := range poly.Points {
+			if _, ok := converted[p]; !ok {
+				converted[p] = &PointI{X: int64(p.X), Y: int64(p.Y)}
+			}
+			rings[i] = append(rings[i], converted[p])
+		}
+	}
+	return rings
+}
+
+// Twice a ring's signed area, exactly
+func doubleSignedAreaInt(points ...*PointI) *big.Int {
+	return doubleAreaI(points)
+}
+
+// Check that the triangles exactly tile the rings: every triangle is strictly
+// counterclockwise, made of input points, and the areas sum exactly
+func assertExactTriangulation(t *testing.T, rings [][]*PointI, triangles TriangleIList) {
+	inputs := make(map[*PointI]bool)
+	expected := new(big.Int)
+	for _, ring := range rings {
+		for _, p := range ring {
+			inputs[p] = true
+		}
+		expected.Add(expected, doubleSignedAreaInt(ring...))
+	}
+
+	actual := new(big.Int)
+	for _, triangle := range triangles {
+		area := doubleSignedAreaInt(triangle.A, triangle.B, triangle.C)
+		assert.Equal(t, 1, area.Sign(), "%v %v %v", *triangle.A, *triangle.B, *triangle.C)
+		actual.Add(actual, area)
+		for _, p := range []*PointI{triangle.A, triangle.B, triangle.C} {
+			assert.True(t, inputs[p], "%v is not an input point", *p)
+		}
+	}
+	assert.Equal(t, 0, expected.Cmp(actual), "expected area %v, got %v", expected, actual)
+}
+
+func TestTriangulateInt_GridCorpus(t *testing.T) {
+	for _, entry := range corpus.Entries() {
+		if !entry.GridAligned || entry.Huge {
+			continue
+		}
+		entry := entry
+		t.Run(entry.Name, func(t *testing.T) {
+			rings := polygonsToInt(corpusShape(entry))
+			triangles, err := TriangulateInt(rings, Options{})
+			require.NoError(t, err)
+			assertExactTriangulation(t, rings, triangles)
+		})
+	}
+}
+
+// Far outside ±MaxCoordinate, and on a coarse grid, which is reduced away
+func TestTriangulateInt_LargeCoordinates(t *testing.T) {
+	const offset, spacing = 1 << 50, 1 << 10
+	rings := polygonsToInt(corpusShape(corpus.Lookup("degenerate quad")))
+	for _, ring := range rings {
+		for _, p := range ring {
+			p.X = offset + p.X*spacing
+			p.Y = -offset + p.Y*spacing
+		}
+	}
+
+	triangles, err := TriangulateInt(rings, Options{})
+	require.NoError(t, err)
+	require.Len(t, triangles, 2)
+	for _, triangle := range triangles {
+		for _, p := range []*PointI{triangle.A, triangle.B, triangle.C} {
+			assert.Contains(t, rings[0], p)
+		}
+	}
+}
+
+// A vertex one step from an edge, at a scale where float input would need that
+// step to be more than Epsilon
+func TestTriangulateInt_NearlyTouching(t *testing.T) {
+	rings := [][]*PointI{{
+		{X: 0, Y: 0}, {X: 1000000, Y: 0}, {X: 1000000, Y: 2}, {X: 500000, Y: 1}, {X: 0, Y: 2},
+	}}
+	triangles, err := TriangulateInt(rings, Options{})
+	require.NoError(t, err)
+	assert.Len(t, triangles, 3)
+	assertExactTriangulation(t, rings, triangles)
+}
+
+// Too wide for the trapezoid map, so these are clipped into ears exactly
+func TestTriangulateInt_Wide(t *testing.T) {
+	t.Run("one step past the span", func(t *testing.T) {
+		rings := [][]*PointI{{{X: 0, Y: 0}, {X: MaxIntSpan + 1, Y: 0}, {X: 0, Y: 1}}}
+		triangles, err := TriangulateInt(rings, Options{})
+		require.NoError(t, err)
+		assert.Len(t, triangles, 1)
+		assertExactTriangulation(t, rings, triangles)
+	})
+
+	t.Run("the whole int64 range", func(t *testing.T) {
+		rings := [][]*PointI{{{X: math.MinInt64, Y: 0}, {X: math.MaxInt64, Y: 0}, {X: 0, Y: 1}}}
+		triangles, err := TriangulateInt(rings, Options{})
+		require.NoError(t, err)
+		assert.Len(t, triangles, 1)
+		assertExactTriangulation(t, rings, triangles)
+	})
+
+	t.Run("nearly touching", func(t *testing.T) {
+		rings := [][]*PointI{{
+			{X: 0, Y: 0}, {X: 1 << 62, Y: 0}, {X: 1 << 62, Y: 2}, {X: 1 << 61, Y: 1}, {X: 0, Y: 2},
+		}}
+		triangles, err := TriangulateInt(rings, Options{})
+		require.NoError(t, err)
+		assert.Len(t, triangles, 3)
+		assertExactTriangulation(t, rings, triangles)
+	})
+
+	t.Run("holes one step from the solid", func(t *testing.T) {
+		const far = math.MaxInt64
+		rings := [][]*PointI{
+			{{X: -far, Y: -far}, {X: far, Y: -far}, {X: far, Y: far}, {X: -far, Y: far}},
+			{{X: far - 2, Y: -1}, {X: far - 1, Y: 1}, {X: far - 1, Y: -1}},
+			{{X: -far + 1, Y: -far + 1}, {X: -far + 1, Y: far - 1}, {X: 0, Y: 0}},
+		}
+		triangles, err := TriangulateInt(rings, Options{})
+		require.NoError(t, err)
+		assertExactTriangulation(t, rings, triangles)
+	})
+
+	// Four holes touching at their corners wall off a pocket, which is only
+	// joined to the rest at those corners
+	t.Run("pocket between holes", func(t *testing.T) {
+		shared := make(map[PointI]*PointI)
+		square := func(x, y, size int64) []*PointI {
+			var ring []*PointI
+			for _, p := range []PointI{{x, y}, {x, y + size}, {x + size, y + size}, {x + size, y}} {
+				if _, ok := shared[p]; !ok {
+					p := p
+					shared[p] = &p
+				}
+				ring = append(ring, shared[p])
+			}
+			return ring
+		}
+		rings := [][]*PointI{
+			{{X: 0, Y: 0}, {X: 1 << 40, Y: 0}, {X: 1 << 40, Y: 30}, {X: 0, Y: 30}},
+			square(9, 5, 4), square(13, 9, 4), square(13, 1, 4), square(17, 5, 4),
+		}
+		triangles, err := TriangulateInt(rings, Options{})
+		require.NoError(t, err)
+		assertExactTriangulation(t, rings, triangles)
+	})
+
+	// A speck far away makes the grid corpus too wide to reduce
+	for _, entry := range corpus.Entries() {
+		if !entry.GridAligned || entry.Huge {
+			continue
+		}
+		entry := entry
+		t.Run(entry.Name, func(t *testing.T) {
+			rings := polygonsToInt(corpusShape(entry))
+			rings = append(rings, []*PointI{{X: 1 << 40, Y: 0}, {X: 1<<40 + 1, Y: 0}, {X: 1 << 40, Y: 1}})
+			triangles, err := TriangulateInt(rings, Options{})
+			require.NoError(t, err)
+			assertExactTriangulation(t, rings, triangles)
+		})
+	}
+}
+
+func TestTriangulateInt_WideMisplacedHole(t *testing.T) {
+	rings := [][]*PointI{
+		{{X: 0, Y: 0}, {X: 1 << 40, Y: 0}, {X: 0, Y: 1 << 40}},
+		{{X: -10, Y: -10}, {X: -10, Y: -5}, {X: -5, Y: -10}},
+	}
+	_, err := TriangulateInt(rings, Options{})
+	var holeErr *MisplacedHoleError
+	require.ErrorAs(t, err, &holeErr)
+	assert.Equal(t, 1, holeErr.Polygon)
+}
+
+func TestOrientI(t *testing.T) {
+	cases := []struct {
+		a, b, c  PointI
+		expected int
+	}{
+		{PointI{0, 0}, PointI{1, 0}, PointI{0, 1}, 1},
+		{PointI{0, 0}, PointI{0, 1}, PointI{1, 0}, -1},
+		{PointI{0, 0}, PointI{1, 1}, PointI{2, 2}, 0},
+		// Products past 2^64, which still fit in 128 bits
+		{PointI{0, 0}, PointI{math.MaxInt64, math.MaxInt64 - 1}, PointI{math.MaxInt64 - 1, math.MaxInt64 - 2}, -1},
+		// Differences past int64, which need big.Int
+		{PointI{math.MinInt64, math.MinInt64}, PointI{math.MaxInt64, math.MaxInt64}, PointI{0, 0}, 0},
+		{PointI{math.MinInt64, math.MinInt64}, PointI{math.MaxInt64, math.MaxInt64}, PointI{0, 1}, 1},
+		{PointI{math.MinInt64, math.MinInt64}, PointI{math.MaxInt64, math.MaxInt64}, PointI{1, 0}, -1},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, orientI(&c.a, &c.b, &c.c), "%v %v %v", c.a, c.b, c.c)
+	}
+}
+
+func TestTriangleIList_ToTriangleList(t *testing.T) {
+	rings := [][]*PointI{{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}}
+	triangles, err := TriangulateInt(rings, Options{})
+	require.NoError(t, err)
+
+	converted := triangles.ToTriangleList()
+	require.Len(t, converted, 2)
+	assert.InDelta(t, 4, totalArea(converted), 0)
+	// The diagonal's ends are shared by both triangles, as are the PointIs
+	points := make(map[*Point]bool)
+	for _, triangle := range converted {
+		points[triangle.A], points[triangle.B], points[triangle.C] = true, true, true
+	}
+	assert.Len(t, points, 4)
+}
@@ -0,0 +1,65 @@
+package advanced
+
+import "fmt"
+
+// The stages of a triangulation, in order. Options.Progress is called at the
+// end of each one.
+type Stage int
+
+const (
+	// The query graph has been built from the input segments
+	StageGraphBuilt Stage = iota
+	// The graph has been split into monotone polygons
+	StageMonotonesExtracted
+	// The monotone polygons have been triangulated
+	StageTriangulated
+
+	stageCount
+)
+
+func (stage Stage) String() string {
+	switch stage {
+	case StageGraphBuilt:
+		return "graph built"
+	case StageMonotonesExtracted:
+		return "monotones extracted"
+	case StageTriangulated:
+		return "triangulated"
+	}
+	return fmt.Sprintf("Stage(%d)", int(stage))
+}
+
+// A copy of every input point's coordinates, for catching points which are
+// modified while the triangulation is running. See
+// Options.CheckPointIntegrity.
+type pointIntegrity struct {
+	list   PolygonList
+	copies [][]Point
+}
+
+func recordPoints(list PolygonList) *pointIntegrity {
+	integrity := &pointIntegrity{list, make([][]Point, len(list))}
+	for i, poly := range list {
+		integrity.copies[i] = make([]Point, len(poly.Points))
+		for j, p := range poly.Points {
+			integrity.copies[i][j] = *p
+		}
+	}
+	return integrity
+}
+
+// Throw a PointMutatedError for the first point which no longer matches its
+// copy. This is safe to call on nil, in which case it does nothing.
+func (integrity *pointIntegrity) verify(stage Stage) {
+	if integrity == nil {
+		return
+	}
+	for i, poly := range integrity.list {
+		for j, p := range poly.Points {
+			before := integrity.copies[i][j]
+			if before != *p {
+				throw(&PointMutatedError{Polygon: i, Vertex: j, Before: before, After: *p, Stage: stage})
+			}
+		}
+	}
+}
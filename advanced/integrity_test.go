@@ -0,0 +1,65 @@
+package advanced
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	var stages []Stage
+	SquareWithHole().TriangulateWithOptions(Options{
+		Progress: func(stage Stage) { stages = append(stages, stage) },
+	})
+	assert.Equal(t, []Stage{StageGraphBuilt, StageMonotonesExtracted, StageTriangulated}, stages)
+}
+
+func TestDiagnostics_StageTimes(t *testing.T) {
+	// A clock which ticks a millisecond each time it's read
+	now := time.Unix(0, 0)
+	clock := func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+
+	// Every stage is timed, and time spent in Progress isn't counted
+	var diagnostics Diagnostics
+	PolygonList{*LoadFixture("spiral")}.TriangulateWithOptions(Options{
+		Diagnostics: &diagnostics,
+		Progress: func(stage Stage) {
+			if stage == StageGraphBuilt {
+				now = now.Add(20 * time.Millisecond)
+			}
+		},
+		clock: clock,
+	})
+	for stage, elapsed := range diagnostics.StageTimes {
+		assert.Equal(t, time.Millisecond, elapsed, Stage(stage).String())
+	}
+}
+
+func TestCheckPointIntegrity(t *testing.T) {
+	list := SquareWithHole()
+	victim := list[1].Points[2]
+	before := *victim
+	mutate := func(stage Stage) {
+		if stage == StageGraphBuilt {
+			victim.X += 0.25
+		}
+	}
+
+	err := triangulateRecovering(list, Options{CheckPointIntegrity: true, Progress: mutate})
+	var mutatedErr *PointMutatedError
+	if assert.ErrorAs(t, err, &mutatedErr) {
+		assert.Equal(t, 1, mutatedErr.Polygon)
+		assert.Equal(t, 2, mutatedErr.Vertex)
+		assert.Equal(t, before, mutatedErr.Before)
+		assert.Equal(t, *victim, mutatedErr.After)
+		assert.Equal(t, StageGraphBuilt, mutatedErr.Stage)
+		assert.Contains(t, err.Error(), "polygon 1 vertex 2")
+	}
+
+	// Untouched points pass
+	assert.NoError(t, triangulateRecovering(SquareWithHole(), Options{CheckPointIntegrity: true}))
+}
@@ -0,0 +1,141 @@
+package advanced
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Label placement, by finding the pole of inaccessibility: the point inside the
+// shape which is farthest from its boundary. This follows Mapbox's polylabel,
+// which searches a quadtree of cells, always refining the cell which could
+// hold the best point, and discarding cells which can't beat the best point
+// found so far by more than the precision.
+//
+// Rather than starting from a grid over the bounding box, the search starts
+// from the inside trapezoids, which already cover the filled region and
+// nothing else. The center of the largest trapezoid is the first candidate, so
+// there is a point with positive clearance from the start, however the shape
+// winds.
+
+// Find a point well inside the shape for placing a label, at least as far from
+// the boundary as the farthest such point, less the precision. Unlike the
+// centroid, this is always strictly inside the filled region, never in a hole
+// or a concavity.
+//
+// Errors are returned for a precision which isn't positive, for input with no
+// area, and for input which can't be triangulated.
+func (l PolygonList) LabelPoint(precision float64) (result Point, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = Point{}, recoveredErr
+		}
+	}()
+
+	if !(precision > 0) || math.IsInf(precision, 1) {
+		return Point{}, errors.Errorf("precision must be positive and finite, not %v", precision)
+	}
+	checkCoordinateRange(l)
+
+	var segments []*Segment
+	for _, poly := range l {
+		for i, p := range poly.Points {
+			segments = append(segments, NewSegment(p, poly.Points[CircularIndex(i+1, len(poly.Points))]))
+		}
+	}
+	if len(segments) == 0 {
+		return Point{}, errors.New("cannot place a label in an empty shape")
+	}
+
+	graph := &QueryGraph{}
+	graph.AddPolygons(l)
+	clearance := func(p *Point) float64 {
+		d := math.Inf(1)
+		for _, s := range segments {
+			d = math.Min(d, distanceToSegment(p, s))
+		}
+		if !graph.ContainsPoint(p) {
+			return -d
+		}
+		return d
+	}
+
+	var best *labelCell
+	var queue labelQueue
+	largestArea := 0.0
+	for trapezoid := range graph.InsideTrapezoids() {
+		top, bottom := trapezoid.Top.Y, trapezoid.Bottom.Y
+		topLeft := trapezoid.xValueForDirection(Direction{Left, Up})
+		topRight := trapezoid.xValueForDirection(Direction{Right, Up})
+		bottomLeft := trapezoid.xValueForDirection(Direction{Left, Down})
+		bottomRight := trapezoid.xValueForDirection(Direction{Right, Down})
+
+		area := (topRight - topLeft + bottomRight - bottomLeft) / 2 * (top - bottom)
+		if !(area > 0) {
+			continue
+		}
+		if area > largestArea {
+			// The sides are straight, so the average of the corners is at the
+			// middle of the trapezoid's midline
+			center := fabricatedPoint((topLeft+topRight+bottomLeft+bottomRight)/4, (top+bottom)/2)
+			largestArea = area
+			best = newLabelCell(center, 0, clearance)
+		}
+
+		// Cover the trapezoid's bounding box with a square cell
+		minX, maxX := math.Min(topLeft, bottomLeft), math.Max(topRight, bottomRight)
+		half := math.Max(maxX-minX, top-bottom) / 2
+		heap.Push(&queue, newLabelCell(fabricatedPoint((minX+maxX)/2, (top+bottom)/2), half, clearance))
+	}
+	if best == nil || !(best.clearance > 0) {
+		return Point{}, errors.New("cannot place a label in a shape with no area")
+	}
+
+	for queue.Len() > 0 {
+		cell := heap.Pop(&queue).(*labelCell)
+		if cell.clearance > best.clearance {
+			best = cell
+		}
+		if cell.potential()-best.clearance <= precision {
+			continue
+		}
+		half := cell.half / 2
+		for _, offset := range [4][2]float64{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+			center := fabricatedPoint(cell.center.X+offset[0]*half, cell.center.Y+offset[1]*half)
+			heap.Push(&queue, newLabelCell(center, half, clearance))
+		}
+	}
+	return *best.center, nil
+}
+
+// A square cell of the search, with the signed distance from its center to the
+// boundary, which is negative outside the shape.
+type labelCell struct {
+	center    *Point
+	half      float64
+	clearance float64
+}
+
+func newLabelCell(center *Point, half float64, clearance func(*Point) float64) *labelCell {
+	return &labelCell{center, half, clearance(center)}
+}
+
+// The greatest clearance of any point in the cell
+func (c *labelCell) potential() float64 {
+	return c.clearance + c.half*math.Sqrt2
+}
+
+// Cells, with the greatest potential first
+type labelQueue []*labelCell
+
+func (q labelQueue) Len() int            { return len(q) }
+func (q labelQueue) Less(i, j int) bool  { return q[i].potential() > q[j].potential() }
+func (q labelQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *labelQueue) Push(x interface{}) { *q = append(*q, x.(*labelCell)) }
+func (q *labelQueue) Pop() interface{} {
+	old := *q
+	cell := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return cell
+}
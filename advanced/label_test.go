@@ -0,0 +1,83 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The distance from the point to the nearest edge of the list
+func boundaryDistance(list PolygonList, p *Point) float64 {
+	d := math.Inf(1)
+	for _, poly := range list {
+		for i, start := range poly.Points {
+			d = math.Min(d, distanceToSegment(p, NewSegment(start, poly.Points[CircularIndex(i+1, len(poly.Points))])))
+		}
+	}
+	return d
+}
+
+// Check that the label point is inside, and at least as good as the best point
+// on a fine grid, less the precision
+func assertGoodLabelPoint(t *testing.T, list PolygonList, precision float64) Point {
+	label, err := list.LabelPoint(precision)
+	require.NoError(t, err)
+	require.True(t, list.ContainsPointByEvenOdd(&label), "%v is outside", label)
+	clearance := boundaryDistance(list, &label)
+	assert.Greater(t, clearance, 0.0)
+
+	graph := &QueryGraph{}
+	graph.AddPolygons(list)
+	bounds, _ := graph.boundingBox()
+	const samples = 200
+	best := 0.0
+	for i := 0; i <= samples; i++ {
+		for j := 0; j <= samples; j++ {
+			p := &Point{
+				X: bounds.MinX + (bounds.MaxX-bounds.MinX)*float64(i)/samples,
+				Y: bounds.MinY + (bounds.MaxY-bounds.MinY)*float64(j)/samples,
+			}
+			if list.ContainsPointByEvenOdd(p) {
+				best = math.Max(best, boundaryDistance(list, p))
+			}
+		}
+	}
+	assert.GreaterOrEqual(t, clearance, best-precision)
+	return label
+}
+
+func TestLabelPoint_Square(t *testing.T) {
+	label := assertGoodLabelPoint(t, PolygonList{squareRing(0, 0, 10)}, 1e-3)
+	assert.InDelta(t, 5, label.X, 0.01)
+	assert.InDelta(t, 5, label.Y, 0.01)
+}
+
+func TestLabelPoint_SquareWithHole(t *testing.T) {
+	list := SquareWithHole()
+	label := assertGoodLabelPoint(t, list, 1e-3)
+	// The centroid is in the hole. The best points are in the corners of the
+	// band, on the diagonals, equally far from the outer edges and the corner of
+	// the hole.
+	assert.False(t, list[1].ContainsPointByEvenOdd(&label))
+	corner := (5 + 2*math.Sqrt2) / (1 + math.Sqrt2)
+	assert.InDelta(t, 5-corner, boundaryDistance(list, &label), 1e-3)
+	assert.InDelta(t, corner, math.Abs(label.X), 1e-2)
+	assert.InDelta(t, corner, math.Abs(label.Y), 1e-2)
+}
+
+func TestLabelPoint_Spiral(t *testing.T) {
+	// The arms are narrow, so the best point is only found by searching along
+	// them
+	assertGoodLabelPoint(t, PolygonList{*LoadFixture("spiral")}, 1e-2)
+}
+
+func TestLabelPoint_Errors(t *testing.T) {
+	_, err := PolygonList{squareRing(0, 0, 1)}.LabelPoint(0)
+	assert.Error(t, err)
+	_, err = PolygonList{}.LabelPoint(1)
+	assert.Error(t, err)
+	_, err = PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}}}}.LabelPoint(1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+package advanced
+
+import "math"
+
+// A CombinedLocator answers "which shape is this point in?" for many
+// non-overlapping shapes at once, in expected O(log n) time per query. All of
+// the shapes go into a single query graph, with each segment tagged with the
+// shape it came from.
+//
+// A point's region is decided by the segment immediately to its left, which is
+// the left side of the trapezoid containing it. If that segment points down,
+// its shape is filled on its right, so the point is in that shape. Otherwise,
+// the point is in empty space, and the segment tells us which empty space: if
+// it belongs to a hole, the point is in that hole, and if it belongs to an
+// outer ring, the point is in whatever surrounds that ring.
+//
+// Results for points exactly on a boundary are arbitrary, but deterministic.
+type CombinedLocator struct {
+	// When set, points in a shape's hole report that shape, rather than not
+	// being found. Points on islands inside the hole still report the island's
+	// shape.
+	ReportHoleParent bool
+
+	graph   *QueryGraph
+	sources map[*Segment]locatorSource
+	// The region surrounding each outer ring
+	containers map[locatorRing]locatorRegion
+	// The lowest point of each outer ring
+	lowestPoints map[locatorRing]*Point
+}
+
+type locatorRing struct {
+	shape, ring int
+}
+
+type locatorSource struct {
+	locatorRing
+	hole bool
+}
+
+type locatorRegion struct {
+	shape int
+	ok    bool
+}
+
+// Build a locator over the given shapes. Each shape is a list of rings, with
+// the usual windings: counterclockwise for outer rings, and clockwise for
+// holes. Shapes must not overlap, but a shape may sit inside another's hole.
+func NewCombinedLocator(shapes []PolygonList) *CombinedLocator {
+	locator := &CombinedLocator{
+		graph:        &QueryGraph{},
+		sources:      make(map[*Segment]locatorSource),
+		containers:   make(map[locatorRing]locatorRegion),
+		lowestPoints: make(map[locatorRing]*Point),
+	}
+
+	var segments []*Segment
+	for shapeIndex, shape := range shapes {
+		for ringIndex, ring := range shape {
+			key := locatorRing{shapeIndex, ringIndex}
+			source := locatorSource{key, IsCW(&ring)}
+			lowest := ring.Points[0]
+			for i, p := range ring.Points {
+				segment := NewSegment(p, ring.Points[CircularIndex(i+1, len(ring.Points))])
+				locator.sources[segment] = source
+				segments = append(segments, segment)
+				if p.Below(lowest) {
+					lowest = p
+				}
+			}
+			if !source.hole {
+				locator.lowestPoints[key] = lowest
+			}
+		}
+	}
+
+	locator.graph.addShuffledSegments(segments)
+
+	// Work out what surrounds each outer ring up front, so that queries never
+	// modify the locator
+	for ring := range locator.lowestPoints {
+		locator.containerOf(ring)
+	}
+	return locator
+}
+
+// Find the index of the shape containing the point. If the point isn't in any
+// shape, ok is false. See ReportHoleParent for how holes are handled.
+func (l *CombinedLocator) RegionAt(x, y float64) (shapeIndex int, ok bool) {
+	if l.graph.isFarOutside(x, y) {
+		return -1, false
+	}
+	node := l.graph.FindPoint(DefaultDirectionalPoint(x, y))
+	region, filled := l.regionOf(node.Inner.(SinkNode).Trapezoid)
+	if !region.ok || !(filled || l.ReportHoleParent) {
+		return -1, false
+	}
+	return region.shape, true
+}
+
+// Find the region a trapezoid belongs to, counting holes as part of their
+// shape. Filled is false if the trapezoid is actually in a hole.
+func (l *CombinedLocator) regionOf(trapezoid *Trapezoid) (region locatorRegion, filled bool) {
+	left := trapezoid.Left
+	if left == nil {
+		return locatorRegion{}, false
+	}
+
+	source := l.sources[left]
+	if left.PointsDown() {
+		return locatorRegion{source.shape, true}, true
+	}
+	if source.hole {
+		return locatorRegion{source.shape, true}, false
+	}
+	return l.containerOf(source.locatorRing), false
+}
+
+// Find the region surrounding an outer ring, by looking directly below its
+// lowest point. Whatever segment is left of that belongs to a ring which
+// reaches lower than this one, so the recursion always terminates.
+func (l *CombinedLocator) containerOf(ring locatorRing) locatorRegion {
+	if region, ok := l.containers[ring]; ok {
+		return region
+	}
+
+	below := DirectionalPoint{Point: l.lowestPoints[ring], Direction: Vector{0, -1}}
+	node := l.graph.FindPoint(below)
+	region, _ := l.regionOf(node.Inner.(SinkNode).Trapezoid)
+	l.containers[ring] = region
+	return region
+}
+
+// A Locator finds the triangle containing a point, in expected O(log n) time,
+// by building a query graph over the edges of a triangulation. Each edge
+// remembers the triangle on its right, so the triangle containing a point is
+// the one right of the left side of its trapezoid.
+//
+// The triangles must form a valid triangulation, sharing point pointers where
+// they meet, as the output of Triangulate does. Zero area triangles are
+// ignored, but their edges must not overlap the edges of other triangles, so
+// it is best to drop them first (see DropZeroArea).
+type Locator struct {
+	graph     *QueryGraph
+	triangles TriangleList
+	// The triangle to the right of each edge, looking from its bottom to its top
+	rightOf map[*Segment]*Triangle
+}
+
+type locatorEdge struct {
+	bottom, top *Point
+}
+
+// The edge between two points, in either order
+func newLocatorEdge(a, b *Point) locatorEdge {
+	if b.Below(a) {
+		return locatorEdge{b, a}
+	}
+	return locatorEdge{a, b}
+}
+
+func NewLocator(triangles TriangleList) *Locator {
+	locator := &Locator{
+		graph:     &QueryGraph{},
+		triangles: triangles,
+		rightOf:   make(map[*Segment]*Triangle),
+	}
+
+	edges := make(map[locatorEdge]*Segment)
+	var segments []*Segment
+	for _, tri := range triangles {
+		if !IsCW(tri) && !IsCCW(tri) {
+			continue
+		}
+		vertices := []*Point{tri.A, tri.B, tri.C}
+		for i, p := range vertices {
+			q := vertices[(i+1)%3]
+			third := vertices[(i+2)%3]
+			edge := newLocatorEdge(p, q)
+
+			segment, ok := edges[edge]
+			if !ok {
+				segment = NewSegment(edge.bottom, edge.top)
+				edges[edge] = segment
+				segments = append(segments, segment)
+			}
+			if IsCW(&Triangle{edge.bottom, edge.top, third}) {
+				locator.rightOf[segment] = tri
+			}
+		}
+	}
+
+	locator.graph.addShuffledSegments(segments)
+	return locator
+}
+
+// Find the triangle containing the point. If the point isn't in any triangle,
+// ok is false. Results for points exactly on an edge are arbitrary, but
+// deterministic.
+func (l *Locator) TriangleAt(x, y float64) (triangle *Triangle, ok bool) {
+	if l.graph.isFarOutside(x, y) {
+		return nil, false
+	}
+	node := l.graph.FindPoint(DefaultDirectionalPoint(x, y))
+	left := node.Inner.(SinkNode).Trapezoid.Left
+	if left == nil {
+		return nil, false
+	}
+	triangle, ok = l.rightOf[left]
+	return triangle, ok
+}
+
+// Check whether the point is inside a triangle, or within tol of one. This is
+// useful for matching the coverage of a renderer, which fills pixels slightly
+// beyond the exact boundary.
+//
+// Only the edges near the point are checked: the sides of the trapezoid
+// containing it, and of the trapezoids around that one which come within tol
+// of it. So this costs little more than TriangleAt, unless tol spans many
+// trapezoids.
+func (l *Locator) ContainsWithTolerance(x, y, tol float64) bool {
+	if _, ok := l.TriangleAt(x, y); ok {
+		return true
+	}
+	if !(tol > 0) {
+		return false
+	}
+	rect, empty := l.graph.boundingBox()
+	if empty || x < rect.MinX-tol || x > rect.MaxX+tol || y < rect.MinY-tol || y > rect.MaxY+tol {
+		return false
+	}
+
+	// Search outward from the point's trapezoid, through neighbors which might
+	// reach within tol of the point
+	p := &Point{X: x, Y: y}
+	start := l.graph.FindPoint(DefaultDirectionalPoint(x, y)).Inner.(SinkNode).Trapezoid
+	visited := map[*Trapezoid]bool{start: true}
+	queue := []*Trapezoid{start}
+	for len(queue) > 0 {
+		trapezoid := queue[0]
+		queue = queue[1:]
+		for _, side := range []*Segment{trapezoid.Left, trapezoid.Right} {
+			if side != nil && distanceToSegment(p, side) <= tol {
+				return true
+			}
+		}
+
+		for _, neighbor := range trapezoid.TrapezoidsAbove {
+			if neighbor != nil && !visited[neighbor] && neighbor.Bottom.Y <= y+tol && neighbor.mightReachX(x, tol) {
+				visited[neighbor] = true
+				queue = append(queue, neighbor)
+			}
+		}
+		for _, neighbor := range trapezoid.TrapezoidsBelow {
+			if neighbor != nil && !visited[neighbor] && neighbor.Top.Y >= y-tol && neighbor.mightReachX(x, tol) {
+				visited[neighbor] = true
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return false
+}
+
+// Conservatively check whether the trapezoid might come within tol of the
+// given X coordinate. The trapezoid lies right of its left side and left of its
+// right side, so the sides' endpoints bound its extent.
+func (t *Trapezoid) mightReachX(x, tol float64) bool {
+	if t.Left != nil && math.Min(t.Left.Start.X, t.Left.End.X) > x+tol {
+		return false
+	}
+	if t.Right != nil && math.Max(t.Right.Start.X, t.Right.End.X) < x-tol {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func squareRing(minX, minY, size float64) Polygon {
+	return Polygon{[]*Point{
+		{X: minX, Y: minY}, {X: minX + size, Y: minY}, {X: minX + size, Y: minY + size}, {X: minX, Y: minY + size},
+	}}
+}
+
+func TestCombinedLocator_Grid(t *testing.T) {
+	// A 10x10 grid of unit squares, with unit gaps between them
+	var shapes []PolygonList
+	for row := 0; row < 10; row++ {
+		for col := 0; col < 10; col++ {
+			shapes = append(shapes, PolygonList{squareRing(float64(2*col), float64(2*row), 1)})
+		}
+	}
+	locator := NewCombinedLocator(shapes)
+
+	for row := 0; row < 10; row++ {
+		for col := 0; col < 10; col++ {
+			x, y := float64(2*col)+0.5, float64(2*row)+0.5
+			shapeIndex, ok := locator.RegionAt(x, y)
+			assert.True(t, ok, "center of square at row %d, col %d", row, col)
+			assert.Equal(t, row*10+col, shapeIndex, "center of square at row %d, col %d", row, col)
+
+			// Gaps to the right of, above, and diagonally from each square
+			for _, gap := range [][2]float64{{x + 1, y}, {x, y + 1}, {x + 1, y + 1}} {
+				_, ok := locator.RegionAt(gap[0], gap[1])
+				assert.False(t, ok, "gap at %v", gap)
+			}
+		}
+	}
+
+	for _, outside := range [][2]float64{{-5, -5}, {100, 0.5}, {0.5, -3}, {9, 50}} {
+		_, ok := locator.RegionAt(outside[0], outside[1])
+		assert.False(t, ok, "outside at %v", outside)
+	}
+}
+
+func TestCombinedLocator_Holes(t *testing.T) {
+	// Shape 0 is a frame, with shape 1 as an island in its hole. Shape 1 has a
+	// hole of its own, with shape 2 as an island in that. Shape 3 sits alongside
+	// shape 1 in shape 0's hole.
+	shapes := []PolygonList{
+		{squareRing(0, 0, 100), squareRing(10, 10, 80).Reverse()},
+		{squareRing(20, 20, 40), squareRing(25, 25, 30).Reverse()},
+		{squareRing(30, 30, 20)},
+		{squareRing(70, 15, 10)},
+	}
+	cases := []struct {
+		name          string
+		x, y          float64
+		shape, parent int
+	}{
+		{"frame", 5, 50, 0, 0},
+		{"frame's hole", 15, 50, -1, 0},
+		{"frame's hole, beside islands", 85, 85, -1, 0},
+		{"island", 22, 40, 1, 1},
+		{"island's hole", 27, 40, -1, 1},
+		{"inner island", 40, 40, 2, 2},
+		{"second island", 75, 20, 3, 3},
+		{"outside", -5, 50, -1, -1},
+	}
+
+	for _, reportHoleParent := range []bool{false, true} {
+		locator := NewCombinedLocator(shapes)
+		locator.ReportHoleParent = reportHoleParent
+		for _, c := range cases {
+			expected := c.shape
+			if reportHoleParent {
+				expected = c.parent
+			}
+			shapeIndex, ok := locator.RegionAt(c.x, c.y)
+			assert.Equal(t, expected >= 0, ok, "%s, ReportHoleParent %v", c.name, reportHoleParent)
+			if ok {
+				assert.Equal(t, expected, shapeIndex, "%s, ReportHoleParent %v", c.name, reportHoleParent)
+			}
+		}
+	}
+}
+
+func TestCombinedLocator_Empty(t *testing.T) {
+	_, ok := NewCombinedLocator(nil).RegionAt(0, 0)
+	assert.False(t, ok)
+}
+
+func TestLocator(t *testing.T) {
+	list := SquareWithHole()
+	triangles := list.Triangulate()
+	locator := NewLocator(triangles)
+
+	for _, tri := range triangles {
+		centroidX := (tri.A.X + tri.B.X + tri.C.X) / 3
+		centroidY := (tri.A.Y + tri.B.Y + tri.C.Y) / 3
+		found, ok := locator.TriangleAt(centroidX, centroidY)
+		assert.True(t, ok)
+		assert.Same(t, tri, found)
+	}
+
+	// In the hole, and outside
+	for _, p := range [][2]float64{{0, 0}, {1.5, -1}, {-6, 0}, {0, 6}} {
+		_, ok := locator.TriangleAt(p[0], p[1])
+		assert.False(t, ok, "%v", p)
+	}
+}
+
+func TestLocator_ContainsWithTolerance(t *testing.T) {
+	locator := NewLocator(SquareWithHole().Triangulate())
+	const tol = 0.1
+
+	// Inside, regardless of tolerance
+	assert.True(t, locator.ContainsWithTolerance(3, 3, 0))
+	assert.True(t, locator.ContainsWithTolerance(3, 3, tol))
+
+	// Outside the outer edges, and inside the hole's edges
+	for _, near := range []struct{ x, y, dx, dy float64 }{
+		{5, 0, 1, 0},
+		{0, -5, 0, -1},
+		{-5, 5, -1, 1},
+		{2, 0, -1, 0},
+		{0, 2, 0, -1},
+	} {
+		at := func(distance float64) (float64, float64) {
+			length := math.Hypot(near.dx, near.dy)
+			return near.x + near.dx/length*distance, near.y + near.dy/length*distance
+		}
+		x, y := at(0.4 * tol)
+		assert.True(t, locator.ContainsWithTolerance(x, y, tol), "%v, %v", x, y)
+		assert.False(t, locator.ContainsWithTolerance(x, y, 0), "%v, %v", x, y)
+		x, y = at(1.5 * tol)
+		assert.False(t, locator.ContainsWithTolerance(x, y, tol), "%v, %v", x, y)
+	}
+
+	// Far away, and the middle of the hole
+	assert.False(t, locator.ContainsWithTolerance(100, 100, tol))
+	assert.False(t, locator.ContainsWithTolerance(0, 0, tol))
+	assert.True(t, locator.ContainsWithTolerance(0, 0, 2.5))
+}
+
+func benchmarkLocator(b *testing.B, query func(locator *Locator, p *Point) bool) {
+	locator := NewLocator(PolygonList{circlePolygon(100, 10000)}.Triangulate())
+
+	// Just outside the boundary, where the tolerance matters
+	rng := rand.New(rand.NewSource(0))
+	points := make([]*Point, 1024)
+	for i := range points {
+		angle := rng.Float64() * 2 * math.Pi
+		points[i] = &Point{X: 100.05 * math.Cos(angle), Y: 100.05 * math.Sin(angle)}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query(locator, points[i%len(points)])
+	}
+}
+
+func BenchmarkLocator_TriangleAt(b *testing.B) {
+	benchmarkLocator(b, func(locator *Locator, p *Point) bool {
+		_, ok := locator.TriangleAt(p.X, p.Y)
+		return ok
+	})
+}
+
+func BenchmarkLocator_ContainsWithTolerance(b *testing.B) {
+	benchmarkLocator(b, func(locator *Locator, p *Point) bool {
+		return locator.ContainsWithTolerance(p.X, p.Y, 0.1)
+	})
+}
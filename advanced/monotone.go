@@ -13,58 +13,90 @@ package advanced
 // trapezoidation, this is not a problem.
 //
 // Note that the polygon must be counterclockwise.
+//
+// Options may optionally be given. Only ZeroAreaTriangles, DropClockwiseSlivers
+// and Diagnostics are used here.
+
+func TriangulateMonotone(polygon *Polygon, opts ...Options) []*Triangle {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return triangulateMonotone(polygon, options, nil, nil)
+}
+
+// Is the polygon monotone in Y, under the lexicographic convention of
+// Point.Below? Going around a monotone polygon, the vertices go up one chain
+// and down the other, so there are exactly two vertices where the direction
+// reverses: the bottom and the top. Under the convention, a square is
+// monotone, but a flat-topped notch adds a reversal at each of its corners.
+//
+// This doesn't check that the polygon is simple.
+func (poly Polygon) IsYMonotone() bool {
+	n := len(poly.Points)
+	if n < 3 {
+		return false
+	}
+	reversals := 0
+	for i, p := range poly.Points {
+		prev := poly.Points[CircularIndex(i-1, n)]
+		next := poly.Points[CircularIndex(i+1, n)]
+		if p.Below(prev) == p.Below(next) {
+			reversals++
+		}
+	}
+	return reversals == 2
+}
+
+// Triangulate a monotone polygon using the given scratch memory, noting how
+// close the decisions were in margins. Either may be nil.
+func triangulateMonotone(polygon *Polygon, options Options, b *buffers, margins *marginTracker) []*Triangle {
 
-func TriangulateMonotone(polygon *Polygon) []*Triangle {
 	if len(polygon.Points) < 3 {
 		fatalf("cannot triangulate degenerate polygon with point count: %d", len(polygon.Points))
 	}
+	area := Area(polygon)
 	if len(polygon.Points) == 3 {
-		return []*Triangle{{polygon.Points[0], polygon.Points[1], polygon.Points[2]}}
+		return appendTriangle(nil, &Triangle{polygon.Points[0], polygon.Points[1], polygon.Points[2]}, area, options)
 	}
 
 	triangles := make([]*Triangle, 0, len(polygon.Points)-2)
 
-	// Sort points so top point is at the top of the array.
-	sortedPoints := make([]*Point, 0, len(polygon.Points))
-
-	// Map to find index by point
-	pointMap := make(map[*Point]int)
+	// Sort points so top point is at the top of the array. The left chain is
+	// for determining whether a point is on the left or right chain.
+	sortedPoints, leftChain, stack := b.monotoneScratch(len(polygon.Points))
 
-	// Find the top point, and build the index lookup
+	// Find the top point
 	var topPointIndex int
 	for i, point := range polygon.Points {
-		pointMap[point] = i
-		if point.Above(polygon.Points[topPointIndex]) {
+		if polygon.Points[topPointIndex].below(point, margins) {
 			topPointIndex = i
 		}
 	}
 
 	sortedPoints = append(sortedPoints, polygon.Points[topPointIndex])
 
-	// Structure for determining which chain a point is on the left or right chain
-	leftChain := map[*Point]struct{}{}
 	var isLeft = func(p *Point) bool {
 		_, ok := leftChain[p]
 		return ok
 	}
 
 	// Merge sort points starting from top, noting which are on the left chain, and track the bottom point separately
+	n := len(polygon.Points)
 	leftOffset := 1
 	rightOffset := 1
-	// Check which point is next for the buffer
-	var bottomPoint *Point
-	for {
-		leftPoint := polygon.Points[CircularIndex(topPointIndex+leftOffset, len(polygon.Points))]
-		rightPoint := polygon.Points[CircularIndex(topPointIndex-rightOffset, len(polygon.Points))]
-
-		// If we've met up, we're done. We don't add the bottom point to the list,
-		// as it's handled at the very end.
-		if leftPoint == rightPoint {
-			bottomPoint = leftPoint
-			break
-		}
+	// The chains meet once every point but the top has been taken from one or
+	// the other, which leaves the last point both chains lead to: the bottom.
+	// This counts rather than waiting for the chains to reach the same point,
+	// so that it ends in the right place however the corners of a flat bottom
+	// compare, even if they're duplicates. We don't add the bottom point to the
+	// list, as it's handled at the very end.
+	for leftOffset+rightOffset < n {
+		leftPoint := polygon.Points[CircularIndex(topPointIndex+leftOffset, n)]
+		rightPoint := polygon.Points[CircularIndex(topPointIndex-rightOffset, n)]
 
-		if leftPoint.Above(rightPoint) {
+		// Ties, which only duplicate points can make, go to the left chain
+		if !leftPoint.below(rightPoint, margins) {
 			leftChain[leftPoint] = struct{}{}
 			sortedPoints = append(sortedPoints, leftPoint)
 			leftOffset++
@@ -73,8 +105,8 @@ func TriangulateMonotone(polygon *Polygon) []*Triangle {
 			rightOffset++
 		}
 	}
-	// Create the stack and populate it with the first two points
-	stack := make(PointStack, 0)
+	bottomPoint := polygon.Points[CircularIndex(topPointIndex+leftOffset, n)]
+	// Populate the stack with the first two points
 	stack.Push(sortedPoints[0])
 	stack.Push(sortedPoints[1])
 	// Iterate over the remainder of the sorted points
@@ -97,7 +129,7 @@ func TriangulateMonotone(polygon *Polygon) []*Triangle {
 						 diagonal-> / |
 						           p--a
 						*/
-						triangles = appendTriangle(triangles, &Triangle{p, a, b})
+						triangles = appendTriangle(triangles, &Triangle{p, a, b}, area, options)
 					} else {
 						/*
 							b
@@ -105,7 +137,7 @@ func TriangulateMonotone(polygon *Polygon) []*Triangle {
 							| \
 							a--p
 						*/
-						triangles = appendTriangle(triangles, &Triangle{a, p, b})
+						triangles = appendTriangle(triangles, &Triangle{a, p, b}, area, options)
 					}
 				}
 			}
@@ -147,7 +179,7 @@ func TriangulateMonotone(polygon *Polygon) []*Triangle {
 				}
 				if IsCCW(potentialTriangle) {
 					v = stack.Pop()
-					triangles = append(triangles, potentialTriangle)
+					triangles = appendTriangle(triangles, potentialTriangle, area, options)
 				} else {
 					// Stop looping if we can't see the next point
 					break
@@ -181,7 +213,7 @@ func TriangulateMonotone(polygon *Polygon) []*Triangle {
 				 \ |
 				   b
 			*/
-			triangles = appendTriangle(triangles, &Triangle{bottomPoint, p, l})
+			triangles = appendTriangle(triangles, &Triangle{bottomPoint, p, l}, area, options)
 		} else {
 			/*
 				            p
@@ -190,18 +222,49 @@ func TriangulateMonotone(polygon *Polygon) []*Triangle {
 				            | /
 				            b
 			*/
-			triangles = appendTriangle(triangles, &Triangle{bottomPoint, l, p})
+			triangles = appendTriangle(triangles, &Triangle{bottomPoint, l, p}, area, options)
 		}
 		l = p
 	}
+	b.keepStack(stack)
 	return triangles
 }
 
-// This is pulled out so that it's easy to add instrumentation.
-func appendTriangle(triangles []*Triangle, tri *Triangle) []*Triangle {
+// How small a clockwise triangle's area must be, as a fraction of the area of
+// the polygon, to be taken for a sliver flipped by rounding. See
+// Options.DropClockwiseSlivers.
+const clockwiseSliverFraction = 1e-6
+
+// Every triangle goes through here, so that it's easy to add instrumentation,
+// and so that there is a single place to check output triangles. The area is
+// that of the polygon being triangulated, which decides whether a clockwise
+// triangle is a sliver.
+func appendTriangle(triangles []*Triangle, tri *Triangle, polygonArea float64, opts Options) []*Triangle {
 	if IsCW(tri) {
-		fatalf("triangle is clockwise: %v", tri)
+		if !opts.DropClockwiseSlivers || Area(tri) > clockwiseSliverFraction*polygonArea {
+			fatalf("triangle is clockwise: %v", tri)
+		}
+		opts.Diagnostics.warnf(WarningClockwiseSliver, "dropped triangle %v, %v, %v", tri.A, tri.B, tri.C)
+		return triangles
+	}
+
+	// Neither clockwise nor counterclockwise, so the points are collinear
+	if !IsCCW(tri) && !keepZeroArea(tri, opts) {
+		return triangles
 	}
 
 	return append(triangles, tri)
 }
+
+// Apply the zero area policy to a triangle already known to have zero area,
+// returning whether it should be kept.
+func keepZeroArea(tri *Triangle, opts Options) bool {
+	switch opts.ZeroAreaTriangles {
+	case DropZeroArea:
+		opts.Diagnostics.warnf(WarningZeroAreaTriangle, "dropped triangle %v, %v, %v", tri.A, tri.B, tri.C)
+		return false
+	case ErrorOnZeroArea:
+		throw(&ZeroAreaTriangleError{[3]Point{*tri.A, *tri.B, *tri.C}})
+	}
+	return true
+}
@@ -1,21 +1,26 @@
 package advanced
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTriangulateMonotone(t *testing.T) {
 	// Triangles. These are currently special-cased, so these should be an no-op.
 	// Included in case that implementation changes.
 	t.Run("simple triangle", func(t *testing.T) {
-		poly := &Polygon{[]*Point{{0, 0}, {1, 1}, {0, 2}}}
+		poly := &Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}}}
 
 		triangles := TriangulateMonotone(poly)
 		AssertValidTriangulation(t, poly, triangles)
 	})
 
 	t.Run("wacky triangle", func(t *testing.T) {
-		poly := &Polygon{[]*Point{{-10, 0}, {43, 2}, {0, 2}}}
+		poly := &Polygon{[]*Point{{X: -10, Y: 0}, {X: 43, Y: 2}, {X: 0, Y: 2}}}
 
 		triangles := TriangulateMonotone(poly)
 		AssertValidTriangulation(t, poly, triangles)
@@ -24,7 +29,7 @@ func TestTriangulateMonotone(t *testing.T) {
 	t.Run("triangle with horizontal", func(t *testing.T) {
 		// A horizontal segment is always acceptable in a triangle. It will only
 		// affect which chain the segment is considered to be part of
-		poly := &Polygon{[]*Point{{0, 0}, {1, 0}, {0, 1}}}
+		poly := &Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}}}
 		triangles := TriangulateMonotone(poly)
 		AssertValidTriangulation(t, poly, triangles)
 	})
@@ -33,13 +38,13 @@ func TestTriangulateMonotone(t *testing.T) {
 	t.Run("square", func(t *testing.T) {
 		// A square has horizontal segments, but it is still strictly y-monotone
 		// because of the lexiographic ordering.
-		poly := &Polygon{[]*Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}
+		poly := &Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}}}
 		triangles := TriangulateMonotone(poly)
 		AssertValidTriangulation(t, poly, triangles)
 	})
 
 	t.Run("diamond", func(t *testing.T) {
-		poly := &Polygon{[]*Point{{0, 0}, {1, 1}, {0, 2}, {-1, 1}}}
+		poly := &Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}, {X: -1, Y: 1}}}
 		triangles := TriangulateMonotone(poly)
 		AssertValidTriangulation(t, poly, triangles)
 	})
@@ -56,10 +61,10 @@ func TestTriangulateMonotone(t *testing.T) {
 			A
 		*/
 		poly := &Polygon{[]*Point{
-			{0, 0},
-			{10, 10},
-			{0, 20},
-			{5, 10},
+			{X: 0, Y: 0},
+			{X: 10, Y: 10},
+			{X: 0, Y: 20},
+			{X: 5, Y: 10},
 		}}
 		triangles := TriangulateMonotone(poly)
 		AssertValidTriangulation(t, poly, triangles)
@@ -107,3 +112,264 @@ func TestTriangulateMonotone(t *testing.T) {
 		})
 	}
 }
+
+func TestTriangulateMonotone_ZeroAreaPolicy(t *testing.T) {
+	// Three collinear points along a horizontal chain. Splitting on diagonals can
+	// produce monotones like this.
+	collinear := func() *Polygon {
+		return &Polygon{[]*Point{{X: 3, Y: 1}, {X: 1, Y: 1}, {X: 2, Y: 1}}}
+	}
+
+	t.Run("keep", func(t *testing.T) {
+		triangles := TriangulateMonotone(collinear())
+		require.Len(t, triangles, 1)
+		assert.Zero(t, triangles[0].SignedArea())
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		diagnostics := &Diagnostics{}
+		triangles := TriangulateMonotone(collinear(), Options{ZeroAreaTriangles: DropZeroArea, Diagnostics: diagnostics})
+		assert.Empty(t, triangles)
+		require.Len(t, diagnostics.Warnings, 1)
+		assert.Equal(t, WarningZeroAreaTriangle, diagnostics.Warnings[0].Kind)
+		assert.Contains(t, diagnostics.Warnings[0].Message, "{3.00, 1.00}")
+	})
+
+	t.Run("drop without diagnostics", func(t *testing.T) {
+		assert.Empty(t, TriangulateMonotone(collinear(), Options{ZeroAreaTriangles: DropZeroArea}))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		poly := collinear()
+		var err error
+		func() {
+			defer func() {
+				err = HandleTriangulatePanicRecover(recover())
+			}()
+			TriangulateMonotone(poly, Options{ZeroAreaTriangles: ErrorOnZeroArea})
+		}()
+		var zeroAreaErr *ZeroAreaTriangleError
+		require.True(t, errors.As(err, &zeroAreaErr))
+		assert.Equal(t, [3]Point{*poly.Points[0], *poly.Points[1], *poly.Points[2]}, zeroAreaErr.Points)
+	})
+
+	t.Run("drop preserves area", func(t *testing.T) {
+		// A collinear monotone alongside a real one
+		monotones := func() PolygonList {
+			return PolygonList{*collinear(), {[]*Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}}}
+		}
+		kept := triangulateMonotones(monotones(), Options{}, nil, nil)
+		diagnostics := &Diagnostics{}
+		dropped := triangulateMonotones(monotones(), Options{ZeroAreaTriangles: DropZeroArea, Diagnostics: diagnostics}, nil, nil)
+
+		assert.Len(t, dropped, len(kept)-len(diagnostics.Warnings))
+		assert.NotEmpty(t, diagnostics.Warnings)
+		assert.InDelta(t, totalArea(kept), totalArea(dropped), Epsilon)
+		for _, triangle := range dropped {
+			assert.True(t, IsCCW(triangle))
+		}
+	})
+}
+
+func TestTriangulateMonotone_ClockwiseSlivers(t *testing.T) {
+	// Two nearly parallel chains, a millionth of a unit apart and far from the
+	// origin, so that the fan between them includes a sliver whose orientation
+	// is lost to cancellation: it is counterclockwise in exact arithmetic, but
+	// comes out clockwise in floating point. The second point widens the top of
+	// the polygon, so that the sliver is a negligible part of the whole.
+	thin := func() *Polygon {
+		return &Polygon{[]*Point{
+			{X: 794689.315225918, Y: 794995.515815796},
+			{X: 794589.315225918, Y: 794994.515815796},
+			{X: 794573.6819908977, Y: 794828.8491491294},
+			{X: 794458.0487558774, Y: 794662.1824824626},
+			{X: 794342.4155208572, Y: 794495.515815796},
+			{X: 794226.7822858375, Y: 794328.8491491294},
+			{X: 794111.1490508168, Y: 794162.1824824626},
+			{X: 793995.5158157961, Y: 793995.515815796},
+			{X: 794145.8390223233, Y: 794212.1824824626},
+			{X: 794261.4722573429, Y: 794378.8491491294},
+			{X: 794377.1054923631, Y: 794545.515815796},
+			{X: 794492.7387273835, Y: 794712.1824824626},
+			{X: 794608.3719624042, Y: 794878.8491491294},
+		}}
+	}
+
+	t.Run("fails by default", func(t *testing.T) {
+		var err error
+		func() {
+			defer func() {
+				err = HandleTriangulatePanicRecover(recover())
+			}()
+			TriangulateMonotone(thin())
+		}()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "clockwise")
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		poly := thin()
+		diagnostics := &Diagnostics{}
+		triangles := TriangulateMonotone(poly, Options{DropClockwiseSlivers: true, Diagnostics: diagnostics})
+
+		require.Len(t, diagnostics.Warnings, 1)
+		assert.Equal(t, WarningClockwiseSliver, diagnostics.Warnings[0].Kind)
+		assert.Len(t, triangles, len(poly.Points)-3)
+		// Other slivers may come out with zero area, which is up to the zero area
+		// policy
+		for _, triangle := range triangles {
+			assert.False(t, IsCW(triangle))
+		}
+		assert.InEpsilon(t, Area(poly), totalArea(triangles), 1e-6)
+	})
+
+	t.Run("substantial clockwise triangle still fails", func(t *testing.T) {
+		backwards := &Triangle{&Point{X: 0, Y: 0}, &Point{X: 0, Y: 1}, &Point{X: 1, Y: 0}}
+		assert.Panics(t, func() {
+			appendTriangle(nil, backwards, 1, Options{DropClockwiseSlivers: true})
+		})
+	})
+}
+
+// Monotone rings whose extremes are flat edges, where the corners tie in Y and
+// only the lexicographic convention tells them apart, so that which corner is
+// the top or bottom depends on the reflection
+func TestTriangulateMonotone_FlatExtremes(t *testing.T) {
+	shapes := map[string][][2]float64{
+		"flat top":    {{2, 0}, {4, 2}, {3, 4}, {1, 4}, {0, 2}},
+		"flat bottom": {{1, 0}, {3, 0}, {4, 2}, {2, 4}, {0, 2}},
+		"flat both":   {{1, 0}, {3, 0}, {4, 2}, {3, 4}, {1, 4}, {0, 2}},
+		// Flat edges subdivided by collinear points
+		"long flat both": {{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 2}, {3, 4}, {2, 4}, {1, 4}, {0, 2}},
+		// The corners tie only within Epsilon, the other way to the geometry
+		"nearly flat both": {{1, Epsilon / 2}, {3, 0}, {4, 2}, {3, 4}, {1, 4 + Epsilon/2}, {0, 2}},
+		// A flat top and bottom and nothing else, with the lowest corner last
+		"rectangle": {{4, 0}, {4, 2}, {0, 2}, {0, 0}},
+	}
+	reflections := map[string][2]float64{
+		"":          {1, 1},
+		" mirrored": {-1, 1},
+		" flipped":  {1, -1},
+		" rotated":  {-1, -1},
+	}
+	for name, coordinates := range shapes {
+		for reflectionName, reflection := range reflections {
+			name := name + reflectionName
+			points := make([]*Point, len(coordinates))
+			for i, c := range coordinates {
+				points[i] = &Point{X: c[0] * reflection[0], Y: c[1] * reflection[1]}
+			}
+			polygon := &Polygon{points}
+			if reflection[0]*reflection[1] < 0 {
+				*polygon = polygon.Reverse()
+			}
+			require.True(t, polygon.IsYMonotone(), name)
+
+			// Starting from each vertex in turn
+			for start := range points {
+				rotated := &Polygon{append(append([]*Point(nil), polygon.Points[start:]...), polygon.Points[:start]...)}
+				t.Run(fmt.Sprintf("%s from %d", name, start), func(t *testing.T) {
+					AssertValidTriangulation(t, rotated, TriangulateMonotone(rotated))
+				})
+			}
+		}
+	}
+}
+
+func TestIsYMonotone(t *testing.T) {
+	for name, test := range map[string]struct {
+		poly     Polygon
+		monotone bool
+	}{
+		"triangle":       {Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}}}, true},
+		"square":         {squareRing(0, 0, 1), true},
+		"clockwise":      {squareRing(0, 0, 1).Reverse(), true},
+		"circle":         {circlePolygon(5, 50), true},
+		"collinear side": {Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}}, true},
+		"star":           {SimpleStar()[0], false},
+		// The notch's flat bottom leads down to a new bottom vertex at its left
+		"flat-topped notch": {Polygon{[]*Point{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}}}, false},
+		// Likewise each step
+		"staircase": {Polygon{[]*Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}}}, false},
+		"spiral":    {*LoadFixture("spiral"), false},
+		"too small": {Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}}}, false},
+	} {
+		assert.Equal(t, test.monotone, test.poly.IsYMonotone(), name)
+	}
+
+	for _, name := range []string{"monotone_asteroid", "monotone_c", "monotone_diamond"} {
+		assert.True(t, LoadFixture(name).IsYMonotone(), name)
+	}
+}
+
+// Triangulate through the query graph, as Triangulate does for anything which
+// isn't a lone monotone ring
+func triangulateThroughGraph(list PolygonList) TriangleList {
+	graph := &QueryGraph{}
+	graph.AddPolygons(list)
+	return triangulateMonotones(graph.convertToMonotones(Options{}), Options{}, nil, nil)
+}
+
+func TestMonotoneFastPath(t *testing.T) {
+	lists := map[string]PolygonList{
+		"circle": {circlePolygon(5, 50)},
+		"square": {squareRing(0, 0, 1)},
+	}
+	for name, fixture := range allFixtures() {
+		if list := fixture(); len(list) == 1 && list[0].IsYMonotone() {
+			lists[name] = list
+		}
+	}
+	require.Contains(t, lists, "monotone c")
+
+	for name, list := range lists {
+		require.True(t, list.isLoneMonotone(Options{}), name)
+		var diagnostics Diagnostics
+		fast := list.TriangulateWithOptions(Options{Diagnostics: &diagnostics})
+		assert.Zero(t, diagnostics.QueryDepth.Queries, "%s should skip the query graph", name)
+		slow := triangulateThroughGraph(list)
+
+		// The pieces may be cut differently, but both must cover the polygon
+		// with the same points
+		assert.InDelta(t, list[0].SignedArea(), totalArea(fast), 1e-9, name)
+		assert.InDelta(t, totalArea(slow), totalArea(fast), 1e-9, name)
+		assert.True(t, trianglePoints(slow).Equals(trianglePoints(fast)), name)
+		for _, tri := range fast {
+			assert.False(t, IsCW(tri), name)
+		}
+		validatePolygonsBySampling(t, fast.ToPolygonList(), list)
+		validatePolygonsBySampling(t, slow.ToPolygonList(), list)
+	}
+}
+
+func TestMonotoneFastPath_Disabled(t *testing.T) {
+	square := PolygonList{squareRing(0, 0, 1)}
+	for name, test := range map[string]struct {
+		list PolygonList
+		opts Options
+	}{
+		"hole":            {SquareWithHole(), Options{}},
+		"two rings":       {PolygonList{squareRing(0, 0, 1), squareRing(2, 0, 1)}, Options{}},
+		"clockwise":       {PolygonList{squareRing(0, 0, 1).Reverse()}, Options{}},
+		"not monotone":    {SimpleStar(), Options{}},
+		"mountains":       {square, Options{Decomposition: Mountains}},
+		"merge collinear": {square, Options{MergeCollinearEdges: true}},
+	} {
+		assert.False(t, test.list.isLoneMonotone(test.opts), name)
+	}
+}
+
+func benchmarkMonotone(b *testing.B, triangulate func(PolygonList) TriangleList) {
+	list := PolygonList{circlePolygon(100, 10000)}
+	for i := 0; i < b.N; i++ {
+		triangulate(list)
+	}
+}
+
+func BenchmarkTriangulate_MonotoneFastPath(b *testing.B) {
+	benchmarkMonotone(b, PolygonList.Triangulate)
+}
+
+func BenchmarkTriangulate_MonotoneThroughGraph(b *testing.B) {
+	benchmarkMonotone(b, triangulateThroughGraph)
+}
@@ -0,0 +1,125 @@
+package advanced
+
+// Triangulation of monotone mountains: monotone polygons where one chain is a
+// single segment, the base. This is the alternative to TriangulateMonotone
+// selected by Options.Decomposition.
+//
+// The monotone polygons that ConvertToMonotones produces are always mountains.
+// Every trapezoid whose top and bottom points aren't joined by one of its sides
+// gets a diagonal between them, so within each piece, the trapezoids are
+// stacked along a single segment on one side, and that segment is the base.
+//
+// Every vertex of a mountain's chain, other than the ends of the base, is
+// either convex, or can become convex once its neighbors are gone. So a
+// mountain can be triangulated by repeatedly clipping off convex chain
+// vertices, with none of the stack juggling of the general case.
+
+// Which kind of pieces to triangulate. See Options.
+type Decomposition int
+
+const (
+	// Triangulate each piece as a general monotone polygon. This is the default.
+	Monotones Decomposition = iota
+	// Triangulate each piece as a monotone mountain, by clipping convex
+	// vertices. This is a separate code path, useful for cross-checking.
+	Mountains
+)
+
+// Triangulate a monotone mountain, which must be counterclockwise. If the
+// polygon is not a mountain, this fails. Options may optionally be given. Only
+// ZeroAreaTriangles, DropClockwiseSlivers and Diagnostics are used here.
+func TriangulateMountain(polygon *Polygon, opts ...Options) []*Triangle {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return triangulateMountain(polygon, options, nil)
+}
+
+// Triangulate a monotone mountain, noting how close the decisions were in
+// margins, which may be nil
+func triangulateMountain(polygon *Polygon, options Options, margins *marginTracker) []*Triangle {
+	points := polygon.Points
+	n := len(points)
+	if n < 3 {
+		fatalf("cannot triangulate degenerate mountain with point count: %d", n)
+	}
+	area := Area(polygon)
+	if n == 3 {
+		return appendTriangle(nil, &Triangle{points[0], points[1], points[2]}, area, options)
+	}
+
+	var top, bottom int
+	for i, p := range points {
+		if points[top].below(p, margins) {
+			top = i
+		}
+		if p.below(points[bottom], margins) {
+			bottom = i
+		}
+	}
+
+	// The base runs from baseStart to baseEnd in ring order, and the chain runs
+	// the rest of the way around, from baseEnd back to baseStart
+	var baseStart, baseEnd int
+	switch {
+	case CircularIndex(bottom+1, n) == top:
+		baseStart, baseEnd = bottom, top
+	case CircularIndex(top+1, n) == bottom:
+		baseStart, baseEnd = top, bottom
+	default:
+		fatalf("polygon is not a monotone mountain: no edge joins its top %v and bottom %v", points[top], points[bottom])
+	}
+
+	// The chain, as a doubly linked list over point indexes
+	prev := make([]int, n)
+	next := make([]int, n)
+	for i := range points {
+		prev[i] = CircularIndex(i-1, n)
+		next[i] = CircularIndex(i+1, n)
+	}
+	removed := make([]bool, n)
+	isConvex := func(i int) bool {
+		return i != baseStart && i != baseEnd && !removed[i] &&
+			IsCCW(&Triangle{points[prev[i]], points[i], points[next[i]]})
+	}
+
+	triangles := make([]*Triangle, 0, n-2)
+	clip := func(i int) {
+		triangles = appendTriangle(triangles, &Triangle{points[prev[i]], points[i], points[next[i]]}, area, options)
+		removed[i] = true
+		next[prev[i]] = next[i]
+		prev[next[i]] = prev[i]
+	}
+
+	var candidates []int
+	for i := next[baseEnd]; i != baseStart; i = next[i] {
+		if isConvex(i) {
+			candidates = append(candidates, i)
+		}
+	}
+	for len(candidates) > 0 {
+		i := candidates[len(candidates)-1]
+		candidates = candidates[:len(candidates)-1]
+		if !isConvex(i) {
+			continue
+		}
+		clip(i)
+		for _, neighbor := range []int{prev[i], next[i]} {
+			if isConvex(neighbor) {
+				candidates = append(candidates, neighbor)
+			}
+		}
+	}
+
+	// Whatever is left lies along the base. Clipping it produces zero area
+	// triangles, which are subject to the zero area policy, unless a reflex
+	// vertex is left, in which case this wasn't a mountain.
+	for i := next[baseEnd]; i != baseStart; i = next[baseEnd] {
+		if IsCW(&Triangle{points[prev[i]], points[i], points[next[i]]}) {
+			fatalf("polygon is not a monotone mountain: reflex vertex %v can't be clipped", points[i])
+		}
+		clip(i)
+	}
+	return triangles
+}
@@ -0,0 +1,79 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriangulateMountain(t *testing.T) {
+	t.Run("triangle", func(t *testing.T) {
+		poly := &Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}}}
+		AssertValidTriangulation(t, poly, TriangulateMountain(poly))
+	})
+
+	t.Run("base on the left", func(t *testing.T) {
+		poly := &Polygon{[]*Point{{X: 0, Y: 10}, {X: 0, Y: 0}, {X: 2, Y: 1}, {X: 1, Y: 3}, {X: 3, Y: 5}, {X: 1, Y: 8}}}
+		triangles := TriangulateMountain(poly)
+		assert.Len(t, triangles, 4)
+		AssertValidTriangulation(t, poly, triangles)
+	})
+
+	t.Run("base on the right", func(t *testing.T) {
+		poly := &Polygon{[]*Point{{X: 0, Y: 0}, {X: 0, Y: 10}, {X: -1, Y: 8}, {X: -3, Y: 6}, {X: -1, Y: 5}, {X: -2, Y: 2}}}
+		triangles := TriangulateMountain(poly)
+		assert.Len(t, triangles, 4)
+		AssertValidTriangulation(t, poly, triangles)
+	})
+
+	t.Run("collinear chain", func(t *testing.T) {
+		poly := &Polygon{[]*Point{{X: 0, Y: 10}, {X: 0, Y: 0}, {X: 2, Y: 2}, {X: 2, Y: 4}, {X: 2, Y: 6}, {X: 2, Y: 8}}}
+		triangles := TriangulateMountain(poly)
+		assert.Len(t, triangles, 4)
+		assert.InDelta(t, Area(poly), totalArea(triangles), Epsilon)
+
+		// The zero area policy applies
+		diagnostics := &Diagnostics{}
+		triangles = TriangulateMountain(poly, Options{ZeroAreaTriangles: DropZeroArea, Diagnostics: diagnostics})
+		assert.InDelta(t, Area(poly), totalArea(triangles), Epsilon)
+		for _, tri := range triangles {
+			assert.True(t, IsCCW(tri))
+		}
+		assert.Len(t, diagnostics.Warnings, 4-len(triangles))
+	})
+
+	t.Run("not a mountain", func(t *testing.T) {
+		// A diamond has two vertices on each side of its top and bottom
+		poly := &Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}, {X: -1, Y: 1}, {X: -0.5, Y: 0.5}}}
+		assert.Panics(t, func() { TriangulateMountain(poly) })
+	})
+}
+
+// Both decompositions must cover the same area with the same points, which
+// makes each a check on the other.
+func TestDecompositions_Agree(t *testing.T) {
+	for name, fixture := range allFixtures() {
+		t.Run(name, func(t *testing.T) {
+			list := fixture()
+			monotones := list.Triangulate()
+			mountains := list.TriangulateWithOptions(Options{Decomposition: Mountains})
+
+			assert.Len(t, mountains, len(monotones))
+			assert.InDelta(t, totalArea(monotones), totalArea(mountains), 1e-6)
+			assert.True(t, trianglePoints(monotones).Equals(trianglePoints(mountains)))
+			for _, tri := range mountains {
+				assert.False(t, IsCW(tri))
+			}
+		})
+	}
+}
+
+func trianglePoints(triangles TriangleList) PointSet {
+	points := make(PointSet)
+	for _, tri := range triangles {
+		points.Add(tri.A)
+		points.Add(tri.B)
+		points.Add(tri.C)
+	}
+	return points
+}
@@ -0,0 +1,136 @@
+package advanced
+
+import (
+	"math"
+	"sort"
+)
+
+// A screen for near-duplicate rings, run before triangulation. Two copies of a
+// ring with one vertex nudged violate the non-intersection precondition as
+// badly as anything can: every edge of one runs alongside an edge of the other,
+// crossing it at a glancing angle or not quite touching it. The triangulator
+// then slogs through thousands of marginal predicate decisions before failing
+// somewhere unrelated, so it's worth catching the case up front.
+//
+// Comparing every pair of rings in full would be too slow to do every time, so
+// the screen only compares rings which could be duplicates: the same number of
+// vertices, the same winding, and bounding boxes which nearly coincide.
+// Legitimate inputs almost never have such pairs, since two rings with the same
+// winding which cover nearly the same area must overlap. A hole just inside its
+// solid, as in a thin annulus, has the opposite winding, so is never flagged.
+// An island in such a hole has its solid's winding, and may be just as close
+// to it, but it's nested inside without touching it. So a pair is only
+// reported if the rings' edges touch or cross, as duplicates' edges do.
+
+// The fraction of the union of two rings' bounding boxes which their
+// intersection must cover for the rings to be compared
+const nearDuplicateOverlap = 0.9
+
+// The sum of the distances between matching vertices, as a fraction of the
+// diagonal of the bounding boxes' union, below which two rings are near
+// duplicates
+const nearDuplicateDistance = 1e-3
+
+// Throw a NearDuplicateRingsError for the first pair of near-duplicate rings
+// found in the list
+func checkNearDuplicateRings(list PolygonList) {
+	type ringBounds struct {
+		index  int
+		bounds Rect
+		ccw    bool
+	}
+	rings := make([]ringBounds, 0, len(list))
+	for i := range list {
+		if len(list[i].Points) < 3 {
+			continue
+		}
+		bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for _, p := range list[i].Points {
+			bounds.MinX, bounds.MinY = math.Min(bounds.MinX, p.X), math.Min(bounds.MinY, p.Y)
+			bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, p.X), math.Max(bounds.MaxY, p.Y)
+		}
+		rings = append(rings, ringBounds{i, bounds, IsCCW(&list[i])})
+	}
+	if len(rings) < 2 {
+		return
+	}
+
+	// Sweep across X, so that rings far apart are never compared
+	sort.Slice(rings, func(i, j int) bool {
+		return rings[i].bounds.MinX < rings[j].bounds.MinX
+	})
+	for i, a := range rings {
+		for _, b := range rings[i+1:] {
+			if b.bounds.MinX > a.bounds.MaxX {
+				break
+			}
+			if a.ccw != b.ccw || len(list[a.index].Points) != len(list[b.index].Points) {
+				continue
+			}
+			union, overlap := boundsOverlap(a.bounds, b.bounds)
+			if overlap < nearDuplicateOverlap {
+				continue
+			}
+			limit := nearDuplicateDistance * math.Hypot(union.MaxX-union.MinX, union.MaxY-union.MinY)
+			first, second := a.index, b.index
+			if first > second {
+				first, second = second, first
+			}
+			distance, ok := ringDistance(list[first], list[second], limit)
+			if ok && ringsTouch(list[first], list[second]) {
+				throw(&NearDuplicateRingsError{First: first, Second: second, Distance: distance})
+			}
+		}
+	}
+}
+
+// Do any edges of one ring touch or cross any edges of the other?
+func ringsTouch(a, b Polygon) bool {
+	sameRing := func(a, b ringEdge) bool {
+		return a.ring == b.ring
+	}
+	return len(sweepCrossings([][]*Point{a.Points, b.Points}, true, sameRing)) > 0
+}
+
+// The union of two bounding boxes, and the fraction of its area covered by
+// their intersection. Boxes with no area don't overlap.
+func boundsOverlap(a, b Rect) (union Rect, overlap float64) {
+	union = Rect{
+		math.Min(a.MinX, b.MinX), math.Min(a.MinY, b.MinY),
+		math.Max(a.MaxX, b.MaxX), math.Max(a.MaxY, b.MaxY),
+	}
+	width := math.Min(a.MaxX, b.MaxX) - math.Max(a.MinX, b.MinX)
+	height := math.Min(a.MaxY, b.MaxY) - math.Max(a.MinY, b.MinY)
+	unionArea := (union.MaxX - union.MinX) * (union.MaxY - union.MinY)
+	if width <= 0 || height <= 0 || !(unionArea > 0) {
+		return union, 0
+	}
+	return union, width * height / unionArea
+}
+
+// The sum of the distances between matching vertices of two rings with the same
+// number of vertices, and whether it is within the limit. The vertices are
+// matched by pairing the first vertex of a with its nearest vertex in b, and
+// going around both rings from there. This gives up as soon as the sum passes
+// the limit.
+func ringDistance(a, b Polygon, limit float64) (float64, bool) {
+	n := len(a.Points)
+	distance := func(p, q *Point) float64 {
+		return math.Hypot(p.X-q.X, p.Y-q.Y)
+	}
+	offset, nearest := 0, math.Inf(1)
+	for j, q := range b.Points {
+		if d := distance(a.Points[0], q); d < nearest {
+			offset, nearest = j, d
+		}
+	}
+
+	var sum float64
+	for i, p := range a.Points {
+		sum += distance(p, b.Points[(offset+i)%n])
+		if sum > limit {
+			return sum, false
+		}
+	}
+	return sum, true
+}
@@ -0,0 +1,63 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearDuplicateRings(t *testing.T) {
+	star := SimpleStar()[0]
+	nudged := translated(star, 0, 0)
+	nudged.Points[3].X += 1e-6
+	// Start the copy somewhere else, which shouldn't matter
+	nudged.Points = append(nudged.Points[4:], nudged.Points[:4]...)
+
+	list := PolygonList{squareRing(20, 20, 1), star, squareRing(-20, 0, 1), nudged}
+	err := triangulateRecovering(list, Options{})
+	var nearDuplicateErr *NearDuplicateRingsError
+	require.ErrorAs(t, err, &nearDuplicateErr)
+	assert.Equal(t, 1, nearDuplicateErr.First)
+	assert.Equal(t, 3, nearDuplicateErr.Second)
+	assert.InDelta(t, 1e-6, nearDuplicateErr.Distance, 1e-9)
+	assert.Contains(t, err.Error(), "polygons 1 and 3")
+}
+
+func TestNearDuplicateRings_Exact(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 1), squareRing(0, 0, 1)}
+	err := triangulateRecovering(list, Options{})
+	var nearDuplicateErr *NearDuplicateRingsError
+	require.ErrorAs(t, err, &nearDuplicateErr)
+	assert.Equal(t, 0.0, nearDuplicateErr.Distance)
+}
+
+func TestNearDuplicateRings_SimilarShapes(t *testing.T) {
+	for name, list := range map[string]PolygonList{
+		// Same size and shape, but side by side
+		"side by side": {squareRing(0, 0, 1), squareRing(2, 0, 1)},
+		// A solid inside a hole inside a solid, each a little smaller than the
+		// last, so the two solids have nearly the same bounding box
+		"nested": {
+			squareRing(0, 0, 10),
+			squareRing(0.1, 0.1, 9.8).Reverse(),
+			squareRing(0.2, 0.2, 9.6),
+		},
+		// The same, but so thin that the two solids are as close as near
+		// duplicates. They're nested without touching, so they're fine.
+		"nested thin frames": {
+			squareRing(0, 0, 1000),
+			squareRing(0.05, 0.05, 999.9).Reverse(),
+			squareRing(0.1, 0.1, 999.8),
+		},
+		// A hole which nearly fills its solid has the opposite winding
+		"thin annulus": {circlePolygon(10, 40), circlePolygon(10-1e-5, 40).Reverse()},
+	} {
+		assert.NotPanics(t, func() { checkNearDuplicateRings(list) }, name)
+		assert.NoError(t, triangulateRecovering(list, Options{}), name)
+	}
+
+	for name, fixture := range allFixtures() {
+		assert.NotPanics(t, func() { checkNearDuplicateRings(fixture()) }, name)
+	}
+}
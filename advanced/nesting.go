@@ -0,0 +1,172 @@
+package advanced
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Containment between the rings of a polygon list. Rings can't cross, so they
+// form a tree, where each ring's parent is the smallest ring around it. Under
+// the winding rule, windings must alternate down the tree: solids contain
+// holes, which contain solids, and so on. A ring with the same winding as its
+// parent is almost always a mistake, and the triangulation quietly does
+// something other than what was intended.
+
+// Find the ring immediately containing each ring, or -1 for rings which are not
+// inside any other. This doesn't depend on the rings being wound correctly, so
+// it can be used to diagnose windings.
+//
+// This works like CombinedLocator: look directly below each ring's lowest
+// point, and find the segment to the left. If the point is on the inside of
+// that segment's ring, that ring is the parent. Otherwise, the two rings are
+// siblings, and share a parent.
+func (l PolygonList) ContainingRings() []int {
+	windings := l.Windings()
+	rings := make(map[*Segment]int)
+	lowestPoints := make([]*Point, len(l))
+	graph := &QueryGraph{}
+	var segments []*Segment
+	for ringIndex, ring := range l {
+		for i, p := range ring.Points {
+			segment := graph.newSourceSegment(ring, ringIndex, i)
+			rings[segment] = ringIndex
+			segments = append(segments, segment)
+			if lowestPoints[ringIndex] == nil || p.Below(lowestPoints[ringIndex]) {
+				lowestPoints[ringIndex] = p
+			}
+		}
+	}
+
+	graph.addShuffledSegments(segments)
+
+	parents := make([]int, len(l))
+	known := make([]bool, len(l))
+	var parentOf func(ringIndex int) int
+	parentOf = func(ringIndex int) int {
+		if known[ringIndex] {
+			return parents[ringIndex]
+		}
+
+		parent := -1
+		if lowest := lowestPoints[ringIndex]; lowest != nil {
+			below := DirectionalPoint{Point: lowest, Direction: Vector{0, -1}}
+			left := graph.FindPoint(below).Inner.(SinkNode).Trapezoid.Left
+			if left != nil {
+				neighbor := rings[left]
+				// A ring's inside is on the right of its downward segments if it
+				// runs counterclockwise, and of its upward segments otherwise.
+				if windings[neighbor] == left.PointsDown() {
+					parent = neighbor
+				} else {
+					// The neighbor reaches lower than this ring, so this terminates
+					parent = parentOf(neighbor)
+				}
+			}
+		}
+
+		parents[ringIndex] = parent
+		known[ringIndex] = true
+		return parent
+	}
+
+	for i := range l {
+		parentOf(i)
+	}
+	return parents
+}
+
+// Wind the rings as the fill rule says they should be, reversing the ones
+// which aren't already. Under EvenOdd, that's by how deeply each ring is
+// nested. The result shares points with the input.
+func (l PolygonList) applyFillRule(rule FillRule) PolygonList {
+	switch rule {
+	case ByWinding:
+		return l
+	case EvenOdd:
+	default:
+		fatalf("unknown fill rule: %d", rule)
+	}
+
+	parents := l.ContainingRings()
+	windings := l.Windings()
+	// Whether each ring is a solid, found by walking up to ancestors which are
+	// already known
+	solid := make([]bool, len(l))
+	known := make([]bool, len(l))
+	var isSolid func(ring int) bool
+	isSolid = func(ring int) bool {
+		if !known[ring] {
+			solid[ring] = parents[ring] < 0 || !isSolid(parents[ring])
+			known[ring] = true
+		}
+		return solid[ring]
+	}
+
+	result := append(PolygonList(nil), l...)
+	for i := range l {
+		if isSolid(i) != windings[i] {
+			result[i] = l[i].Reverse()
+		}
+	}
+	return result
+}
+
+// A ring whose winding doesn't alternate with its parent's. See NestingErrors.
+type NestingError struct {
+	// The ring and its ancestors, from the outermost ancestor down to the ring
+	// itself
+	Chain []NestedRing
+}
+
+type NestedRing struct {
+	// Index of the ring in the list
+	Index int
+	// Whether the ring runs counterclockwise, and so is a solid
+	CCW bool
+}
+
+func (ring NestedRing) String() string {
+	if ring.CCW {
+		return fmt.Sprintf("ring %d (counterclockwise)", ring.Index)
+	}
+	return fmt.Sprintf("ring %d (clockwise)", ring.Index)
+}
+
+func (e *NestingError) Error() string {
+	parts := make([]string, len(e.Chain))
+	for i, ring := range e.Chain {
+		parts[i] = ring.String()
+	}
+	ring := e.Chain[len(e.Chain)-1]
+	if len(e.Chain) == 1 {
+		return fmt.Sprintf("%v is a hole, but is not inside any solid", ring)
+	}
+	return fmt.Sprintf(
+		"%v has the same winding as the ring containing it: %s",
+		ring, strings.Join(parts, " > "),
+	)
+}
+
+// Check that windings alternate with nesting, returning an error for each ring
+// which has the same winding as its parent. Rings which are not inside any
+// other must run counterclockwise, so a clockwise ring on its own is also an
+// error. Reversing the ring at the end of an error's chain fixes that error,
+// although other errors may share the same cause.
+func (l PolygonList) NestingErrors() []*NestingError {
+	parents := l.ContainingRings()
+	windings := l.Windings()
+
+	var result []*NestingError
+	for i, parent := range parents {
+		if (parent < 0 && windings[i]) || (parent >= 0 && windings[i] != windings[parent]) {
+			continue
+		}
+
+		var chain []NestedRing
+		for ring := i; ring >= 0; ring = parents[ring] {
+			chain = append([]NestedRing{{ring, windings[ring]}}, chain...)
+		}
+		result = append(result, &NestingError{chain})
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainingRings(t *testing.T) {
+	list := PolygonList{
+		squareRing(0, 0, 10),
+		squareRing(1, 1, 8).Reverse(),
+		squareRing(2, 2, 2),
+		squareRing(5, 5, 3),
+		squareRing(6, 6, 1).Reverse(),
+		squareRing(20, 0, 5),
+	}
+	assert.Equal(t, []int{-1, 0, 1, 1, 3, -1}, list.ContainingRings())
+
+	// Windings don't matter
+	for i := range list {
+		list[i] = list[i].Reverse()
+	}
+	assert.Equal(t, []int{-1, 0, 1, 1, 3, -1}, list.ContainingRings())
+}
+
+func TestNestingErrors(t *testing.T) {
+	t.Run("hole inside hole", func(t *testing.T) {
+		list := PolygonList{
+			squareRing(0, 0, 10),
+			squareRing(1, 1, 8).Reverse(),
+			squareRing(3, 3, 4).Reverse(),
+		}
+		errs := list.NestingErrors()
+		require.Len(t, errs, 1)
+		assert.Equal(t, []NestedRing{{0, true}, {1, false}, {2, false}}, errs[0].Chain)
+		assert.Contains(t, errs[0].Error(), "ring 2 (clockwise) has the same winding")
+		assert.Contains(t, errs[0].Error(), "ring 0 (counterclockwise) > ring 1 (clockwise) > ring 2 (clockwise)")
+	})
+
+	t.Run("solid inside solid", func(t *testing.T) {
+		list := PolygonList{squareRing(3, 3, 4), squareRing(0, 0, 10)}
+		errs := list.NestingErrors()
+		require.Len(t, errs, 1)
+		assert.Equal(t, []NestedRing{{1, true}, {0, true}}, errs[0].Chain)
+	})
+
+	t.Run("hole outside everything", func(t *testing.T) {
+		list := PolygonList{squareRing(0, 0, 10), squareRing(20, 20, 5).Reverse()}
+		errs := list.NestingErrors()
+		require.Len(t, errs, 1)
+		assert.Equal(t, []NestedRing{{1, false}}, errs[0].Chain)
+		assert.Contains(t, errs[0].Error(), "not inside any solid")
+	})
+
+	t.Run("alternating", func(t *testing.T) {
+		for name, list := range map[string]PolygonList{
+			"StarStripes":       StarStripes(),
+			"MultiLayeredHoles": MultiLayeredHoles(),
+			"SquareWithHole":    SquareWithHole(),
+			"disjoint":          {squareRing(0, 0, 1), squareRing(2, 0, 1), squareRing(0, 2, 1)},
+		} {
+			assert.Empty(t, list.NestingErrors(), name)
+		}
+	})
+}
+
+func TestCheckNestingOption(t *testing.T) {
+	list := PolygonList{
+		squareRing(0, 0, 10),
+		squareRing(1, 1, 8).Reverse(),
+		squareRing(3, 3, 4).Reverse(),
+	}
+	// The triangulation itself may well fail on input like this, but the warning
+	// is recorded first, so it explains the failure
+	diagnostics := &Diagnostics{}
+	_ = triangulateRecovering(list, Options{CheckNesting: true, Diagnostics: diagnostics})
+	require.Len(t, diagnostics.Warnings, 1)
+	assert.Equal(t, WarningNesting, diagnostics.Warnings[0].Kind)
+
+	diagnostics = &Diagnostics{}
+	StarStripes().TriangulateWithOptions(Options{CheckNesting: true, Diagnostics: diagnostics})
+	assert.Empty(t, diagnostics.Warnings)
+}
+
+func TestFillRule_EvenOdd(t *testing.T) {
+	for name, load := range map[string]func() PolygonList{
+		"StarStripes":       StarStripes,
+		"MultiLayeredHoles": MultiLayeredHoles,
+		"SquareWithHole":    SquareWithHole,
+	} {
+		t.Run(name, func(t *testing.T) {
+			list := load()
+			expected := list.Triangulate()
+
+			// Every ring counterclockwise, then every ring clockwise, then all
+			// reversed from the intended windings
+			for _, wind := range []func(i int, poly Polygon) Polygon{
+				func(i int, poly Polygon) Polygon { return windAs(poly, true) },
+				func(i int, poly Polygon) Polygon { return windAs(poly, false) },
+				func(i int, poly Polygon) Polygon { return poly.Reverse() },
+			} {
+				wound := make(PolygonList, len(list))
+				for i, poly := range list {
+					wound[i] = wind(i, poly)
+				}
+				triangles := wound.TriangulateWithOptions(Options{FillRule: EvenOdd})
+				assert.Equal(t, triangleCoordinates(expected), triangleCoordinates(triangles))
+				validatePolygonsBySampling(t, triangles.ToPolygonList(), list)
+			}
+		})
+	}
+
+	// The input's rings are left as they were
+	list := PolygonList{squareRing(0, 0, 10), squareRing(1, 1, 8)}
+	first := list[1].Points[0]
+	list.TriangulateWithOptions(Options{FillRule: EvenOdd})
+	assert.True(t, IsCCW(&list[1]))
+	assert.Same(t, first, list[1].Points[0])
+
+	// The monotone pieces follow the rule too
+	monotones := ConvertToMonotones(list, Options{FillRule: EvenOdd})
+	var area float64
+	for _, monotone := range monotones {
+		area += monotone.SignedArea()
+	}
+	assert.InDelta(t, 100-64, area, 1e-9)
+}
+
+func TestFixWinding(t *testing.T) {
+	// Both rings counterclockwise
+	list := SquareWithHole()
+	wound := PolygonList{list[0], windAs(list[1], true)}
+	triangles := wound.TriangulateWithOptions(Options{FixWinding: true})
+	assert.Equal(t, triangleCoordinates(list.Triangulate()), triangleCoordinates(triangles))
+	assert.True(t, IsCCW(&wound[1]))
+
+	// Two disjoint solids, each with a hole, wound every which way. FixWinding
+	// wins over FillRule.
+	disjoint := PolygonList{
+		squareRing(0, 0, 10), windAs(squareRing(2, 2, 6), false),
+		squareRing(20, 0, 10), windAs(squareRing(22, 2, 6), false),
+	}
+	scrambled := PolygonList{disjoint[0].Reverse(), disjoint[1].Reverse(), disjoint[2], disjoint[3].Reverse()}
+	triangles = scrambled.TriangulateWithOptions(Options{FixWinding: true, FillRule: ByWinding})
+	assert.InDelta(t, 2*(100-36), totalArea(triangles), 1e-9)
+	validatePolygonsBySampling(t, triangles.ToPolygonList(), disjoint)
+
+	// Deep nesting, all counterclockwise
+	layers := MultiLayeredHoles()
+	for i := range layers {
+		layers[i] = windAs(layers[i], true)
+	}
+	triangles = layers.TriangulateWithOptions(Options{FixWinding: true})
+	validatePolygonsBySampling(t, triangles.ToPolygonList(), MultiLayeredHoles())
+}
+
+func TestFillRule_Unknown(t *testing.T) {
+	err := triangulateRecovering(SquareWithHole(), Options{FillRule: 42})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown fill rule")
+}
+
+func windAs(poly Polygon, ccw bool) Polygon {
+	if IsCCW(&poly) != ccw {
+		return poly.Reverse()
+	}
+	return poly
+}
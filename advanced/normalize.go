@@ -0,0 +1,197 @@
+package advanced
+
+import "math"
+
+// The largest coordinate magnitude which works reliably with the fixed
+// Epsilon. A single rounding error on a coordinate this large is 1/1024 of
+// Epsilon, which leaves room for the errors to accumulate across the few
+// operations in each predicate. Beyond this, points which are Epsilon apart
+// can't be told apart reliably, and the triangulation fails in confusing ways.
+//
+// Input outside of ±MaxCoordinate is rejected with a CoordinatesOutOfRangeError,
+// unless Options.AutoNormalize is set.
+const MaxCoordinate = Epsilon / (1 << 10) / unitRoundoff
+
+// Find the first point outside the supported coordinate range, and throw a
+// CoordinatesOutOfRangeError for it. NaN and infinite coordinates are always
+// out of range.
+func checkCoordinateRange(list PolygonList) {
+	for i, poly := range list {
+		for j, p := range poly.Points {
+			if !inCoordinateRange(p) {
+				throw(&CoordinatesOutOfRangeError{Polygon: i, Vertex: j, Point: *p})
+			}
+		}
+	}
+}
+
+func inCoordinateRange(p *Point) bool {
+	// Written this way around so that NaN is out of range
+	return math.Abs(p.X) <= MaxCoordinate && math.Abs(p.Y) <= MaxCoordinate
+}
+
+// How far above the tolerance at its magnitude a shape's extent must be to be
+// safe from rounding error. See isSmallShape.
+const smallShapeMargin = 100
+
+// Is a shape with the given extent, whose coordinates reach the given
+// magnitude, too small to triangulate reliably where it is? Rounding error in
+// the predicates grows with the magnitude of the coordinates, so away from the
+// origin, Epsilon effectively becomes relative: the tolerance at magnitude m is
+// about Epsilon·m. A shape only a couple of orders of magnitude larger than
+// that gets subtly wrong results, rather than errors. Translating it to the
+// origin fixes that, since nearby coordinates subtract exactly.
+func isSmallShape(extent, magnitude float64) bool {
+	return extent < smallShapeMargin*Epsilon*math.Max(1, magnitude)
+}
+
+// The diagonal of a bounding box, and the largest coordinate magnitude in it
+func shapeSize(bounds Rect) (extent, magnitude float64) {
+	extent = math.Hypot(bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY)
+	magnitude = math.Max(
+		math.Max(math.Abs(bounds.MinX), math.Abs(bounds.MaxX)),
+		math.Max(math.Abs(bounds.MinY), math.Abs(bounds.MaxY)),
+	)
+	return extent, magnitude
+}
+
+// Record a warning if the list is too small for the magnitude of its
+// coordinates. See isSmallShape.
+func checkShapeSize(list PolygonList, diagnostics *Diagnostics) {
+	if diagnostics == nil {
+		return
+	}
+	bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, poly := range list {
+		for _, p := range poly.Points {
+			bounds.MinX, bounds.MinY = math.Min(bounds.MinX, p.X), math.Min(bounds.MinY, p.Y)
+			bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, p.X), math.Max(bounds.MaxY, p.Y)
+		}
+	}
+	if math.IsInf(bounds.MinX, 1) {
+		return
+	}
+	extent, magnitude := shapeSize(bounds)
+	if isSmallShape(extent, magnitude) {
+		diagnostics.warnf(
+			WarningSmallShape,
+			"the input is only %g across, but its coordinates reach %g, where the tolerance is about %g; set AutoNormalize to triangulate it near the origin",
+			extent, magnitude, Epsilon*math.Max(1, magnitude),
+		)
+	}
+}
+
+// A translation and scale which brings a list of polygons into the supported
+// coordinate range, along with the way back. The scale is always a power of
+// two, so that it doesn't add any rounding error of its own.
+type normalization struct {
+	centerX, centerY float64
+	scale            float64
+	// Maps points in the working copy back to the input points
+	originals map[*Point]*Point
+}
+
+// Build a working copy of the list, translated and scaled so that its bounding
+// box is centered on the origin and well within ±MaxCoordinate. Input points
+// are not modified. Lists too small for their magnitude (see isSmallShape) are
+// also moved to the origin, and scaled up if they're tiny. Lists already in
+// range, and large enough, are returned as they are, with a nil normalization.
+// Non-finite coordinates still throw a CoordinatesOutOfRangeError, since
+// there's no way to bring them into range.
+//
+// With a nonzero tolerance (see Options.Tolerance), the list is always moved to
+// the origin, and the scale is instead the one which makes the tolerance
+// Epsilon.
+func normalizePolygons(list PolygonList, tolerance float64) (PolygonList, *normalization) {
+	if !(tolerance >= 0) || math.IsInf(tolerance, 1) {
+		fatalf("tolerance must be positive and finite, or zero for Epsilon, not %v", tolerance)
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	inRange := true
+	for i, poly := range list {
+		for j, p := range poly.Points {
+			if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+				throw(&CoordinatesOutOfRangeError{Polygon: i, Vertex: j, Point: *p})
+			}
+			inRange = inRange && inCoordinateRange(p)
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	extent, magnitude := shapeSize(Rect{minX, minY, maxX, maxY})
+	if (tolerance == 0 && inRange && !isSmallShape(extent, magnitude)) || math.IsInf(minX, 1) {
+		return list, nil
+	}
+
+	n := &normalization{
+		centerX:   minX/2 + maxX/2,
+		centerY:   minY/2 + maxY/2,
+		scale:     1,
+		originals: make(map[*Point]*Point),
+	}
+	// Leave some headroom, since translation rounds
+	halfExtent := math.Max(maxX/2-minX/2, maxY/2-minY/2)
+	if tolerance > 0 {
+		n.scale = math.Exp2(math.Round(math.Log2(Epsilon / tolerance)))
+		if halfExtent*n.scale > MaxCoordinate/2 {
+			fatalf(
+				"a tolerance of %g is too fine for input %g across; the finest it supports is about %g",
+				tolerance, extent, Epsilon*halfExtent/(MaxCoordinate/2),
+			)
+		}
+	} else {
+		for halfExtent*n.scale > MaxCoordinate/2 {
+			n.scale /= 2
+		}
+		for halfExtent > 0 && halfExtent*n.scale < 1 {
+			n.scale *= 2
+		}
+	}
+
+	// Rings which share a point must share its working copy too
+	workingPoints := make(map[*Point]*Point)
+	result := make(PolygonList, len(list))
+	for i, poly := range list {
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			working, ok := workingPoints[p]
+			if !ok {
+				working = &Point{X: (p.X - n.centerX) * n.scale, Y: (p.Y - n.centerY) * n.scale, UserIndex: p.UserIndex, HasUserIndex: p.HasUserIndex}
+				workingPoints[p] = working
+				n.originals[working] = p
+			}
+			points[j] = working
+		}
+		result[i] = Polygon{points}
+	}
+	return result, n
+}
+
+// Map a working point back to the input space. Points from the input map back
+// to the input pointers. Any other point was created during the triangulation,
+// so it gets a new point with the inverse transform applied, which is then
+// remembered so that all triangles sharing it share the result.
+func (n *normalization) original(p *Point) *Point {
+	if original, ok := n.originals[p]; ok {
+		return original
+	}
+	original := fabricatedPoint(p.X/n.scale+n.centerX, p.Y/n.scale+n.centerY)
+	n.originals[p] = original
+	return original
+}
+
+// Map triangles from the working copy back to the input space. Zero area
+// triangles are judged in the working space, where the arithmetic is sound,
+// but reported with their original coordinates.
+func (n *normalization) restore(triangles TriangleList, opts Options) TriangleList {
+	result := make(TriangleList, 0, len(triangles))
+	for _, tri := range triangles {
+		restored := &Triangle{n.original(tri.A), n.original(tri.B), n.original(tri.C)}
+		if !IsCCW(tri) && !keepZeroArea(restored, opts) {
+			continue
+		}
+		result = append(result, restored)
+	}
+	return result
+}
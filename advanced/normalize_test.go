@@ -0,0 +1,240 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxCoordinate(t *testing.T) {
+	// A rounding error at the edge of the range must be far below Epsilon
+	assert.Less(t, MaxCoordinate*unitRoundoff, Epsilon/100)
+	// But typical drawing coordinates must be well within range
+	assert.Greater(t, MaxCoordinate, 1e5)
+}
+
+// Scale and translate a list far outside the supported range
+func hugeCopy(list PolygonList) PolygonList {
+	result := make(PolygonList, len(list))
+	for i, poly := range list {
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			points[j] = &Point{X: p.X*1e12 + 3e12, Y: p.Y*1e12 - 7e12}
+		}
+		result[i] = Polygon{points}
+	}
+	return result
+}
+
+func TestCoordinateRange_Rejected(t *testing.T) {
+	list := hugeCopy(PolygonList{
+		{[]*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}},
+	})
+
+	err := triangulateRecovering(list, Options{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCoordinatesOutOfRange))
+	var rangeErr *CoordinatesOutOfRangeError
+	require.True(t, errors.As(err, &rangeErr))
+	assert.Equal(t, 0, rangeErr.Polygon)
+	assert.Equal(t, 0, rangeErr.Vertex)
+	assert.Equal(t, *list[0].Points[0], rangeErr.Point)
+	assert.Contains(t, err.Error(), "AutoNormalize")
+
+	// Non-finite coordinates can't be normalized
+	err = triangulateRecovering(PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: math.NaN(), Y: 1}}}}, Options{AutoNormalize: true})
+	assert.True(t, errors.Is(err, ErrCoordinatesOutOfRange))
+}
+
+func TestCoordinateRange_AutoNormalize(t *testing.T) {
+	list := hugeCopy(PolygonList{
+		{[]*Point{{X: 0, Y: 0}, {X: 6, Y: 0}, {X: 6, Y: 6}, {X: 3, Y: 8}, {X: 0, Y: 6}}},
+		{[]*Point{{X: 1, Y: 1}, {X: 1, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 1}}},
+		{[]*Point{{X: 4, Y: 4}, {X: 5, Y: 3}, {X: 3, Y: 3}}},
+	})
+	inputPoints := make(PointSet)
+	for _, poly := range list {
+		for _, p := range poly.Points {
+			inputPoints.Add(p)
+		}
+	}
+
+	var result TriangleList
+	require.NotPanics(t, func() {
+		result = list.TriangulateWithOptions(Options{AutoNormalize: true})
+	})
+	assert.Len(t, result, 12+2*2-2)
+	for _, tri := range result {
+		assert.True(t, IsCCW(tri), "%v", tri)
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			assert.True(t, inputPoints.Contains(p), "%v is not an input point", p)
+		}
+	}
+	expectedArea := (36 + 6 - 1 - 1) * 1e24
+	assert.InEpsilon(t, expectedArea, totalArea(result), 1e-9)
+
+	// Input already in range is triangulated as it is
+	small := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}}}}
+	normalized, n := normalizePolygons(small, 0)
+	assert.Nil(t, n)
+	assert.Same(t, small[0].Points[0], normalized[0].Points[0])
+}
+
+func TestCoordinateRange_ErrorsInInputSpace(t *testing.T) {
+	// Duplicate points are reported with their input coordinates
+	list := hugeCopy(PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}}}})
+	err := triangulateRecovering(list, Options{AutoNormalize: true, CanonicalizeOutputPoints: true})
+	var duplicateErr *DuplicatePointError
+	require.True(t, errors.As(err, &duplicateErr))
+	assert.Equal(t, *list[0].Points[1], duplicateErr.Point)
+
+	// Zero area triangles are reported in the input space too
+	list = hugeCopy(PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 1}}}})
+	working, n := normalizePolygons(list, 0)
+	require.NotNil(t, n)
+	collinear := &Triangle{working[0].Points[0], working[0].Points[1], working[0].Points[2]}
+	err = func() (err error) {
+		defer func() {
+			err = HandleTriangulatePanicRecover(recover())
+		}()
+		n.restore(TriangleList{collinear}, Options{ZeroAreaTriangles: ErrorOnZeroArea})
+		return nil
+	}()
+	var zeroAreaErr *ZeroAreaTriangleError
+	require.True(t, errors.As(err, &zeroAreaErr))
+	assert.Equal(t, [3]Point{*list[0].Points[0], *list[0].Points[1], *list[0].Points[2]}, zeroAreaErr.Points)
+}
+
+func triangulateRecovering(list PolygonList, opts Options) (err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			err = recoveredErr
+		}
+	}()
+	list.TriangulateWithOptions(opts)
+	return nil
+}
+
+func TestSmallShapeWarning(t *testing.T) {
+	for _, test := range []struct {
+		offset, size float64
+		warn         bool
+	}{
+		{0, 10, false},
+		{0, 1e-3, false},
+		{0, 1e-6, true},
+		{1e3, 1, false},
+		{1e5, 1000, false},
+		{1e5, 0.5, true},
+		{5e5, 100, false},
+		{5e5, 3, true},
+		{-5e5, 3, true},
+	} {
+		list := PolygonList{squareRing(test.offset, test.offset, test.size)}
+		var diagnostics Diagnostics
+		list.TriangulateWithOptions(Options{Diagnostics: &diagnostics})
+		if test.warn {
+			if assert.Len(t, diagnostics.Warnings, 1, "%g at %g", test.size, test.offset) {
+				assert.Equal(t, WarningSmallShape, diagnostics.Warnings[0].Kind)
+				assert.Contains(t, diagnostics.Warnings[0].Message, "AutoNormalize")
+			}
+		} else {
+			assert.Empty(t, diagnostics.Warnings, "%g at %g", test.size, test.offset)
+		}
+	}
+}
+
+func TestSmallShapeWarning_Fixtures(t *testing.T) {
+	for name, fixture := range allFixtures() {
+		var diagnostics Diagnostics
+		fixture().TriangulateWithOptions(Options{Diagnostics: &diagnostics})
+		for _, warning := range diagnostics.Warnings {
+			assert.NotEqual(t, WarningSmallShape, warning.Kind, name)
+		}
+	}
+}
+
+func TestSmallShape_AutoNormalize(t *testing.T) {
+	list := PolygonList{
+		squareRing(5e5, 5e5, 3),
+		{[]*Point{{X: 5e5 + 1, Y: 5e5 + 1}, {X: 5e5 + 1, Y: 5e5 + 2}, {X: 5e5 + 2, Y: 5e5 + 2}, {X: 5e5 + 2, Y: 5e5 + 1}}},
+	}
+	working, n := normalizePolygons(list, 0)
+	require.NotNil(t, n)
+	assert.InDelta(t, 0, working[0].Points[0].X+working[0].Points[2].X, Epsilon)
+
+	var diagnostics Diagnostics
+	result := list.TriangulateWithOptions(Options{AutoNormalize: true, Diagnostics: &diagnostics})
+	assert.Empty(t, diagnostics.Warnings)
+	assert.Len(t, result, 8)
+	assert.InDelta(t, 8, totalArea(result), 1e-6)
+
+	// Tiny shapes are scaled up as well
+	tiny := PolygonList{squareRing(0, 0, 1e-6)}
+	working, n = normalizePolygons(tiny, 0)
+	require.NotNil(t, n)
+	assert.Greater(t, working[0].Points[2].X-working[0].Points[0].X, 1.0)
+	assert.Len(t, tiny.TriangulateWithOptions(Options{AutoNormalize: true}), 2)
+}
+
+// Copy a list with every coordinate multiplied by the scale
+func scaledCopy(list PolygonList, scale float64) PolygonList {
+	result := make(PolygonList, len(list))
+	for i, poly := range list {
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			points[j] = &Point{X: p.X * scale, Y: p.Y * scale}
+		}
+		result[i] = Polygon{points}
+	}
+	return result
+}
+
+func TestTolerance_ScaledStar(t *testing.T) {
+	for _, scale := range []float64{1e-5, 1e7} {
+		list := scaledCopy(SimpleStar(), scale)
+		triangles := list.TriangulateWithOptions(Options{Tolerance: Epsilon * scale})
+		require.Len(t, triangles, len(list[0].Points)-2)
+		edges := make(normalizedSegmentSet)
+		for _, tri := range triangles {
+			require.True(t, IsCCW(tri), "clockwise triangle: %s", tri)
+			edges.add(tri.A, tri.B)
+			edges.add(tri.B, tri.C)
+			edges.add(tri.C, tri.A)
+		}
+		for i, p := range list[0].Points {
+			next := list[0].Points[CircularIndex(i+1, len(list[0].Points))]
+			assert.True(t, edges.contains(p, next), "edge %v-%v", p, next)
+		}
+		// Rounding in the sum of the areas grows with the area, so the
+		// tolerance is relative
+		assert.InEpsilon(t, Area(&list[0]), totalArea(triangles), Epsilon, "scale %v", scale)
+	}
+
+	// Without it, the larger star is out of range
+	err := triangulateRecovering(scaledCopy(SimpleStar(), 1e7), Options{})
+	assert.True(t, errors.Is(err, ErrCoordinatesOutOfRange))
+}
+
+func TestTolerance_FineDetail(t *testing.T) {
+	// A notch far narrower than Epsilon, in a unit square
+	const width = 1e-9
+	list := PolygonList{{[]*Point{
+		{X: 0, Y: 0}, {X: 0.5, Y: 0}, {X: 0.5, Y: 0.5}, {X: 0.5 + width, Y: 0}, {X: 1, Y: 0},
+		{X: 1, Y: 1}, {X: 0, Y: 1},
+	}}}
+	working, n := normalizePolygons(list, 1e-12)
+	require.NotNil(t, n)
+	assert.InDelta(t, width*Epsilon/1e-12, working[0].Points[3].X-working[0].Points[2].X, width*Epsilon/1e-12/2)
+
+	triangles := list.TriangulateWithOptions(Options{Tolerance: 1e-12})
+	AssertValidTriangulation(t, &list[0], triangles)
+	assert.InDelta(t, 1-width/4, totalArea(triangles), 1e-12)
+
+	for _, tolerance := range []float64{-1, math.NaN(), math.Inf(1), 1e-30} {
+		assert.Error(t, triangulateRecovering(list, Options{Tolerance: tolerance}), "%v", tolerance)
+	}
+}
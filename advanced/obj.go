@@ -0,0 +1,189 @@
+package advanced
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Reading 2D outlines from, and writing triangle meshes to, Wavefront OBJ.
+//
+// An outline is a closed line loop: an "l" statement over "v" vertices, ending
+// at the vertex it starts at, like "l 1 2 3 4 1". Vertices must lie in the
+// plane Z=0. Each loop becomes a ring, with its winding as authored, so solids
+// must run counterclockwise and holes clockwise, as with any other input.
+
+// How far from zero a vertex's Z may be in an outline
+const objZTolerance = 1e-9
+
+// Read the closed line loops in an OBJ file as polygons. Every statement other
+// than "v" and "l" is ignored. Each point's UserIndex is the zero based index
+// of the vertex it came from, so it can be traced back through triangulation.
+func ReadOBJOutlines(r io.Reader) (PolygonList, error) {
+	var vertices []Point
+	var list PolygonList
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			if len(fields) < 3 || len(fields) > 5 {
+				return nil, errors.Errorf("line %d: expected 2 to 4 vertex coordinates, got %d", lineNumber, len(fields)-1)
+			}
+			var coordinates [3]float64
+			for i, field := range fields[1:] {
+				if i == len(coordinates) {
+					// The optional weight
+					break
+				}
+				value, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return nil, errors.Wrapf(err, "line %d: bad vertex coordinate", lineNumber)
+				}
+				coordinates[i] = value
+			}
+			if math.Abs(coordinates[2]) > objZTolerance {
+				return nil, errors.Errorf("line %d: vertex %d has Z %v, but outlines must be in the plane Z=0", lineNumber, len(vertices)+1, coordinates[2])
+			}
+			if len(vertices) == math.MaxInt32 {
+				return nil, errors.Errorf("line %d: too many vertices", lineNumber)
+			}
+			vertices = append(vertices, Point{X: coordinates[0], Y: coordinates[1], UserIndex: int32(len(vertices)), HasUserIndex: true})
+
+		case "l":
+			poly, err := objLoop(fields[1:], vertices)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNumber)
+			}
+			list = append(list, poly)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Make a polygon from the vertex references of an "l" statement. Every point
+// is new, so loops which share vertices don't share points.
+func objLoop(references []string, vertices []Point) (Polygon, error) {
+	indexes := make([]int, len(references))
+	for i, reference := range references {
+		// A reference may carry a texture vertex after a slash, which outlines
+		// have no use for
+		if slash := strings.IndexByte(reference, '/'); slash >= 0 {
+			reference = reference[:slash]
+		}
+		index, err := strconv.Atoi(reference)
+		if err != nil {
+			return Polygon{}, errors.Errorf("bad vertex reference %q", references[i])
+		}
+		// Negative references count back from the latest vertex
+		if index < 0 {
+			index += len(vertices) + 1
+		}
+		if index < 1 || index > len(vertices) {
+			return Polygon{}, errors.Errorf("vertex %s is out of range, with %d vertices so far", references[i], len(vertices))
+		}
+		indexes[i] = index - 1
+	}
+
+	if len(indexes) < 2 || indexes[0] != indexes[len(indexes)-1] {
+		return Polygon{}, errors.New("line loop is not closed; it must end at the vertex it starts at")
+	}
+	indexes = indexes[:len(indexes)-1]
+	if len(indexes) < 3 {
+		return Polygon{}, errors.Errorf("line loop needs at least 3 vertices, got %d", len(indexes))
+	}
+	seen := make(map[int]bool, len(indexes))
+	poly := Polygon{make([]*Point, len(indexes))}
+	for i, index := range indexes {
+		if seen[index] {
+			return Polygon{}, errors.Errorf("line loop passes through vertex %d twice", index+1)
+		}
+		seen[index] = true
+		p := vertices[index]
+		poly.Points[i] = &p
+	}
+	return poly, nil
+}
+
+// Options for WriteOBJ
+type OBJOptions struct {
+	// Write a texture vertex for every vertex, with its position normalized
+	// into [0, 1] over the bounding box of the triangles, so that the mesh has
+	// usable UVs. If the box is flat along an axis, that coordinate is 0.
+	UVs bool
+}
+
+// Write the triangles as an OBJ mesh, with Z=0. Each distinct point is written
+// once, at full precision, in the order the triangles first use them, and
+// faces keep the triangles' winding.
+func WriteOBJ(w io.Writer, triangles TriangleList, opts OBJOptions) error {
+	indexes := make(map[*Point]int)
+	var points []*Point
+	for _, tri := range triangles {
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			if _, ok := indexes[p]; !ok {
+				indexes[p] = len(points) + 1
+				points = append(points, p)
+			}
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	format := formatOBJFloat
+	for _, p := range points {
+		fmt.Fprintf(writer, "v %s %s 0\n", format(p.X), format(p.Y))
+	}
+	if opts.UVs {
+		bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for _, p := range points {
+			bounds.MinX, bounds.MinY = math.Min(bounds.MinX, p.X), math.Min(bounds.MinY, p.Y)
+			bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, p.X), math.Max(bounds.MaxY, p.Y)
+		}
+		normalize := func(value, min, max float64) float64 {
+			if max <= min {
+				return 0
+			}
+			return clamp((value-min)/(max-min), 0, 1)
+		}
+		for _, p := range points {
+			fmt.Fprintf(writer, "vt %s %s\n",
+				format(normalize(p.X, bounds.MinX, bounds.MaxX)),
+				format(normalize(p.Y, bounds.MinY, bounds.MaxY)),
+			)
+		}
+	}
+	for _, tri := range triangles {
+		writer.WriteString("f")
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			// Texture vertices are numbered the same as the vertices
+			if opts.UVs {
+				fmt.Fprintf(writer, " %d/%d", indexes[p], indexes[p])
+			} else {
+				fmt.Fprintf(writer, " %d", indexes[p])
+			}
+		}
+		writer.WriteString("\n")
+	}
+	return writer.Flush()
+}
+
+// A coordinate at full precision, in the shortest form which reads back exactly
+func formatOBJFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,165 @@
+package advanced
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A 10×10 square with a 4×4 square hole, as an art pipeline would export it
+const objSquareWithHole = `# outline export
+o outline
+v 0 0 0
+v 10 0 0
+v 10 10 0
+v 0 10 0
+v 3 3 0
+v 3 7 0
+v 7 7 0
+v 7 3 0
+l 1 2 3 4 1
+l 5 6 7 8 5
+`
+
+// Just enough of an OBJ mesh parser to check WriteOBJ's output
+type objMesh struct {
+	vertices [][2]float64
+	uvs      [][2]float64
+	faces    [][3][2]int // Vertex and texture vertex indexes, zero based
+}
+
+func parseOBJMesh(t *testing.T, data []byte) objMesh {
+	var mesh objMesh
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		parse := func(s string) float64 {
+			value, err := strconv.ParseFloat(s, 64)
+			require.NoError(t, err)
+			return value
+		}
+		switch fields[0] {
+		case "v":
+			require.Len(t, fields, 4)
+			assert.Equal(t, "0", fields[3])
+			mesh.vertices = append(mesh.vertices, [2]float64{parse(fields[1]), parse(fields[2])})
+		case "vt":
+			require.Len(t, fields, 3)
+			mesh.uvs = append(mesh.uvs, [2]float64{parse(fields[1]), parse(fields[2])})
+		case "f":
+			require.Len(t, fields, 4)
+			var face [3][2]int
+			for i, reference := range fields[1:] {
+				parts := strings.Split(reference, "/")
+				for j, part := range parts {
+					index, err := strconv.Atoi(part)
+					require.NoError(t, err)
+					face[i][j] = index - 1
+				}
+				if len(parts) == 1 {
+					face[i][1] = -1
+				}
+			}
+			mesh.faces = append(mesh.faces, face)
+		default:
+			t.Fatalf("unexpected statement %q", fields[0])
+		}
+	}
+	return mesh
+}
+
+func TestReadOBJOutlines(t *testing.T) {
+	list, err := ReadOBJOutlines(strings.NewReader(objSquareWithHole))
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, Point{X: 10, Y: 0, UserIndex: 1, HasUserIndex: true}, *list[0].Points[1])
+	assert.Equal(t, Point{X: 7, Y: 3, UserIndex: 7, HasUserIndex: true}, *list[1].Points[3])
+
+	// Winding is as authored
+	assert.True(t, IsCCW(&list[0]))
+	assert.True(t, IsCW(&list[1]))
+
+	// Loops which share a vertex get separate points
+	shared, err := ReadOBJOutlines(strings.NewReader("v 0 0\nv 1 0\nv 0 1\nv -1 0\nl 1 2 3 1\nl -4 -2 -1 -4\n"))
+	require.NoError(t, err)
+	require.Len(t, shared, 2)
+	assert.Equal(t, *shared[0].Points[0], *shared[1].Points[0])
+	assert.NotSame(t, shared[0].Points[0], shared[1].Points[0])
+}
+
+func TestReadOBJOutlines_Errors(t *testing.T) {
+	for name, test := range map[string]struct {
+		input, message string
+	}{
+		"not closed":      {"v 0 0 0\nv 1 0 0\nv 0 1 0\nl 1 2 3\n", "line 4: line loop is not closed"},
+		"off the plane":   {"v 0 0 0\nv 1 0 0.5\n", "line 2: vertex 2 has Z 0.5"},
+		"out of range":    {"v 0 0 0\nv 1 0 0\nl 1 2 3 1\n", "line 3: vertex 3 is out of range"},
+		"bad coordinate":  {"v 0 zero 0\n", "line 1: bad vertex coordinate"},
+		"bad reference":   {"v 0 0 0\nl 1 x 1\n", "line 2: bad vertex reference"},
+		"too few":         {"v 0 0 0\nv 1 0 0\nl 1 2 1\n", "line 3: line loop needs at least 3 vertices"},
+		"repeated vertex": {"v 0 0 0\nv 1 0 0\nv 0 1 0\nl 1 2 1 3 1\n", "line 4: line loop passes through vertex 1 twice"},
+		"one coordinate":  {"# comment\n\nv 0\n", "line 3: expected 2 to 4 vertex coordinates"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadOBJOutlines(strings.NewReader(test.input))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.message)
+		})
+	}
+}
+
+func TestOBJ_RoundTrip(t *testing.T) {
+	list, err := ReadOBJOutlines(strings.NewReader(objSquareWithHole))
+	require.NoError(t, err)
+	triangles := list.Triangulate()
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteOBJ(&buffer, triangles, OBJOptions{UVs: true}))
+	mesh := parseOBJMesh(t, buffer.Bytes())
+
+	// A square with a square hole has 8 vertices, and 8 triangles
+	assert.Len(t, mesh.vertices, 8)
+	assert.Len(t, mesh.faces, 8)
+	require.Len(t, mesh.uvs, len(mesh.vertices))
+	for i, uv := range mesh.uvs {
+		assert.True(t, uv[0] >= 0 && uv[0] <= 1 && uv[1] >= 0 && uv[1] <= 1, "%v", uv)
+		// The bounding box is the outer square
+		assert.Equal(t, [2]float64{mesh.vertices[i][0] / 10, mesh.vertices[i][1] / 10}, uv)
+	}
+
+	var area float64
+	for _, face := range mesh.faces {
+		a, b, c := mesh.vertices[face[0][0]], mesh.vertices[face[1][0]], mesh.vertices[face[2][0]]
+		for _, corner := range face {
+			assert.Equal(t, corner[0], corner[1], "texture vertex should match vertex")
+		}
+		signed := ((b[0]-a[0])*(c[1]-a[1]) - (c[0]-a[0])*(b[1]-a[1])) / 2
+		// Faces keep the triangles' counterclockwise winding
+		assert.Greater(t, signed, 0.0)
+		area += signed
+	}
+	assert.Equal(t, 100.0-16, area)
+}
+
+func TestWriteOBJ(t *testing.T) {
+	a, b, c, d := &Point{X: 0, Y: 0}, &Point{X: 2, Y: 0}, &Point{X: 2, Y: 0.5}, &Point{X: 0, Y: 0.5}
+	var buffer bytes.Buffer
+	require.NoError(t, WriteOBJ(&buffer, TriangleList{{a, b, c}, {a, c, d}}, OBJOptions{}))
+	assert.Equal(t, "v 0 0 0\nv 2 0 0\nv 2 0.5 0\nv 0 0.5 0\nf 1 2 3\nf 1 3 4\n", buffer.String())
+
+	// A flat box gives 0 along its flat axis
+	buffer.Reset()
+	require.NoError(t, WriteOBJ(&buffer, TriangleList{{a, b, &Point{X: 1, Y: 0}}}, OBJOptions{UVs: true}))
+	mesh := parseOBJMesh(t, buffer.Bytes())
+	assert.Equal(t, [][2]float64{{0, 0}, {1, 0}, {0.5, 0}}, mesh.uvs)
+
+	// Nothing to write
+	buffer.Reset()
+	require.NoError(t, WriteOBJ(&buffer, nil, OBJOptions{UVs: true}))
+	assert.Empty(t, buffer.String())
+}
@@ -0,0 +1,392 @@
+package advanced
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Options controlling triangulation. The zero value gives the default behavior.
+type Options struct {
+	// Make every output triangle reference the first input point with its
+	// coordinates. Points are identified by pointer internally, so without this,
+	// rings which touch at a vertex must share the same *Point. With this set,
+	// they may use different pointers with equal coordinates, and the output
+	// will only ever use the pointer from the earliest ring. Exact duplicates
+	// within a single ring are an error.
+	CanonicalizeOutputPoints bool
+
+	// What to do with output triangles whose points are collinear. These can't
+	// be drawn, and have no normal, so they tend to upset downstream code.
+	ZeroAreaTriangles ZeroAreaPolicy
+
+	// Accept coordinates outside of ±MaxCoordinate, by triangulating a copy of
+	// the input which is translated and scaled into range. The output still
+	// references the input points, and errors and warnings report input
+	// coordinates. Without this, such input fails with a
+	// CoordinatesOutOfRangeError. Input which is in range, but small for how far
+	// it is from the origin, is moved to the origin the same way. Without this,
+	// such input gets a WarningSmallShape.
+	AutoNormalize bool
+
+	// The distance within which coordinates count as equal, in the units of
+	// the input, in place of Epsilon. Use this for input whose scale is far from
+	// one, such as UV coordinates between 0 and 1 with detail finer than
+	// Epsilon, or map coordinates in the millions. It works by triangulating a
+	// copy of the input, translated to the origin and scaled so that the
+	// tolerance becomes Epsilon, in the same way as AutoNormalize. The scale is
+	// rounded to a power of two, so the tolerance used is within a factor of √2
+	// of this. Zero means Epsilon, without scaling. A tolerance so fine that the
+	// scaled input wouldn't fit in ±MaxCoordinate is an error.
+	Tolerance float64
+
+	// Decide every predicate in floating point, for speed. Normally, the
+	// decisions which shape the trapezoid map and the monotone pieces are
+	// recomputed with exact rational arithmetic when the floating point result
+	// is too close to Epsilon to trust. That only kicks in on marginal cases, so
+	// it costs very little on typical input. See Diagnostics.ExactEvaluations.
+	DisableExactFallback bool
+
+	// Check that windings alternate with nesting, recording a warning for each
+	// ring with the same winding as the ring containing it. See
+	// PolygonList.NestingErrors. This builds a separate query graph, so it
+	// roughly doubles the cost of trapezoidization. It does nothing without
+	// Diagnostics to record the warnings in.
+	CheckNesting bool
+
+	// Leave out vertices which lie on a straight line between their neighbors
+	// in the input, such as the points along a subdivided edge, wherever that
+	// doesn't change the shape. Those vertices then don't appear in the output
+	// at all, which means fewer triangles, and no zero area slivers along the
+	// edge. By default, every input vertex is kept.
+	MergeCollinearEdges bool
+
+	// Drop clockwise output triangles whose area is a negligible fraction of
+	// the monotone piece they come from, recording a warning for each, rather
+	// than failing. Such a triangle is a sliver whose points are so nearly
+	// collinear that rounding flipped its orientation. A clockwise triangle with
+	// real area still fails, since that means the piece wasn't monotone.
+	DropClockwiseSlivers bool
+
+	// The vertex order of the output triangles. This is called exactly once for
+	// each output triangle, with its vertices in counterclockwise order, and
+	// returns true to keep that order, or false to swap B and C, making it
+	// clockwise. It runs on the triangulating goroutine, after everything else,
+	// so the triangles it sees are final. By default, every triangle is
+	// counterclockwise. See OrientCCW, OrientCW and MatchNormal.
+	OrientTriangle func(a, b, c *Point) bool
+
+	// The order of the output triangles. By default, it's whatever order the
+	// triangulation produces, which can change wholesale when any part of the
+	// input changes. Spatial sorts them by position instead, which keeps diffs
+	// of the output small. See OutputOrder.
+	SortOutput OutputOrder
+
+	// Flip the edges of the output until it's constrained Delaunay, as
+	// TriangleList.ImproveQuality does, which does away with most of the long
+	// slivers Seidel's algorithm leaves, at the cost of a pass over the
+	// triangles' edges. The edges of the input rings are never flipped, and the
+	// triangle count doesn't change. See Diagnostics.DelaunayFlips.
+	Delaunay bool
+
+	// Which way to sweep the input while building the trapezoid map. By default,
+	// points are compared by Y, then X. Input with thousands of vertices sharing
+	// Y values can be far faster with XAxis, or with AutoAxis to choose. The
+	// output is the same shape either way, and still references the input
+	// points, but warnings report coordinates as swept. See SweepAxis.
+	SweepAxis SweepAxis
+
+	// Which rings are solids and which are holes. By default, that's given by
+	// their windings. EvenOdd decides by nesting instead, so rings may wind
+	// either way, at the cost of building an extra query graph to find the
+	// nesting. See FillRule.
+	FillRule FillRule
+
+	// Correct the windings of the rings before triangulating, reversing each one
+	// which doesn't wind as its nesting says it should: the outermost rings
+	// counterclockwise, the rings directly inside them clockwise, and so on. This
+	// is the same as FillRule EvenOdd, for input whose windings can't be trusted,
+	// and takes precedence over FillRule. The input rings are never modified;
+	// reversed copies are triangulated instead.
+	FixWinding bool
+
+	// Check the input before triangulating it, failing with the error
+	// PolygonList.Validate returns, which names the polygon and vertexes at
+	// fault, if a ring has too few points, repeats a point, or has edges which
+	// cross or touch. Without this, such input fails somewhere in the middle of
+	// triangulation with a less helpful error, or gives wrong triangles. Under
+	// the winding rule, it also checks that every hole is inside a solid, once
+	// the trapezoid map is built, failing with a MisplacedHoleError if not.
+	// This costs a sweep over the edges, so it is off by default.
+	Validate bool
+
+	// If positive, merge vertices closer together than this before
+	// triangulating, as PolygonList.Cleaned does, for input such as mesh slices
+	// where rounding leaves vertices which should be one a hair apart. The
+	// output triangles reference the vertices they were merged into. A ring
+	// which isn't inside any other and collapses to fewer than three points
+	// fails with a CollapsedRingError.
+	WeldTolerance float64
+
+	// The seed for the pseudorandom order segments are added to the trapezoid
+	// map in, which keeps the expected build time O(n log n). The trapezoids,
+	// and so the triangles, are the same whatever the order, but the query
+	// graph over them isn't, so the seed decides how deep queries go (see
+	// Diagnostics.QueryDepth), and which path a failure takes.
+	Seed int64
+
+	// How to divide the input into trapezoids. There is only one built-in way
+	// for now. Others can be used through ConvertMapToMonotones.
+	Backend Backend
+
+	// How to triangulate the monotone pieces of the input. By default, each is
+	// triangulated as a general monotone polygon. Mountains triangulates them
+	// by clipping off convex vertices instead, which gives the same shape and
+	// points through an independent code path.
+	Decomposition Decomposition
+
+	// Keep a copy of every input point, and check at the end of each stage that
+	// none of them has been modified, failing with a PointMutatedError if one
+	// has. Points must never change during a triangulation, and when they do,
+	// the damage tends to surface much later as an inexplicable failure. This
+	// costs a copy of the input, so it is off by default.
+	CheckPointIntegrity bool
+
+	// Fill in Diagnostics.Hashes with a content hash of the state at the end of
+	// each stage, for finding which stage a change in the output comes from.
+	// This costs a sort of each stage's state, so it is off by default. It does
+	// nothing without Diagnostics to record the hashes in.
+	HashStages bool
+
+	// How deep the point queries made while building the trapezoid map may go
+	// before the map is rebuilt with another seed, as a factor of the log2 of
+	// the number of segments. The seeds after Seed are tried in turn, a few at
+	// most, and the shallowest map is kept, so an unlucky order for a
+	// particular input doesn't stay unlucky. Zero means
+	// DefaultRebuildDepthFactor, and a negative factor never rebuilds. See
+	// Diagnostics.GraphRebuilds.
+	RebuildDepthFactor float64
+
+	// When triangulating input with many vertices at the same height fails,
+	// retry with later seeds up to this many times before reporting the error.
+	// Regular polygons and grids are like that, and the ties make building the
+	// trapezoid map depend on the order more than usual. Zero means
+	// DefaultSymmetricRetries, and a negative count never retries. See
+	// Diagnostics.SymmetricRetries.
+	SymmetricRetries int
+
+	// If positive, record a warning when the deepest point query made while
+	// building the trapezoid map exceeds this factor times the log2 of the
+	// number of nodes in the map. Deep queries mean the segments went in an
+	// unlucky order, and the build is heading for quadratic time. A factor
+	// around 4 is quiet for random orders. See QueryDepthStats.
+	WarnDepthFactor float64
+
+	// If non-nil, this is called at the end of each stage of the triangulation.
+	// It runs on the triangulating goroutine, and must not modify the input.
+	Progress func(stage Stage)
+
+	// If non-nil, this is told about each change to the trapezoid map as it's
+	// built, including any rebuilds. Like Progress, it runs on the
+	// triangulating goroutine. See Tracer.
+	Tracer Tracer
+
+	// If positive, check the trapezoids near every InvariantInterval-th segment
+	// as the trapezoid map is built, failing with the segment's index and the
+	// invariant it broke. 1 checks after every segment. This is for debugging,
+	// but is cheap enough to leave on for real inputs. See
+	// QueryGraph.InvariantInterval.
+	InvariantInterval int
+
+	// If non-nil, this is filled in with information about the triangulation.
+	Diagnostics *Diagnostics
+
+	// The source of randomness for the order segments are added in, given the
+	// seed. Nil means rand.NewSource. Tests set this to force a particular
+	// order. See QueryGraph.shuffleSource.
+	shuffleSource func(seed int64) rand.Source
+
+	// The clock Diagnostics.StageTimes are read from. Nil means time.Now. Tests
+	// set this to get predictable times.
+	clock func() time.Time
+}
+
+// The fill rule to apply, taking FixWinding into account
+func (opts Options) fillRule() FillRule {
+	if opts.FixWinding {
+		return EvenOdd
+	}
+	return opts.FillRule
+}
+
+// Which trapezoidation to use. See Options.
+type Backend int
+
+const (
+	// Seidel's randomized incremental algorithm, with a QueryGraph. This is the
+	// default.
+	RandomizedIncremental Backend = iota
+)
+
+// How to decide which rings are solids and which are holes. See Options.
+type FillRule int
+
+const (
+	// Counterclockwise rings are solids, and clockwise rings are holes. This is
+	// the default.
+	ByWinding FillRule = iota
+	// Windings are ignored. The outermost rings are solids, the rings directly
+	// inside them are holes, the rings inside those are solids, and so on.
+	EvenOdd
+)
+
+// How to handle zero area triangles in the output. See Options.
+type ZeroAreaPolicy int
+
+const (
+	// Leave zero area triangles in the output. This is the default.
+	KeepZeroArea ZeroAreaPolicy = iota
+	// Leave zero area triangles out of the output, recording a warning for each.
+	DropZeroArea
+	// Fail with a ZeroAreaTriangleError.
+	ErrorOnZeroArea
+)
+
+// A functional option, for setting Options one at a time, as in
+// NewOptions(WithSeed(42), WithValidation()). Settings with no Option of their
+// own can be given with WithOptions.
+type Option func(*Options)
+
+// Apply the options in order to the zero value
+func NewOptions(options ...Option) Options {
+	var opts Options
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// Set Options.Seed
+func WithSeed(seed int64) Option {
+	return func(opts *Options) { opts.Seed = seed }
+}
+
+// Set Options.Validate
+func WithValidation() Option {
+	return func(opts *Options) { opts.Validate = true }
+}
+
+// Set Options.FillRule
+func WithFillRule(rule FillRule) Option {
+	return func(opts *Options) { opts.FillRule = rule }
+}
+
+// Set Options.WeldTolerance
+func WithWeldTolerance(tolerance float64) Option {
+	return func(opts *Options) { opts.WeldTolerance = tolerance }
+}
+
+// Set Options.Tolerance
+func WithTolerance(tolerance float64) Option {
+	return func(opts *Options) { opts.Tolerance = tolerance }
+}
+
+// Replace all the options with the given ones. Options after this one still
+// apply on top.
+func WithOptions(replacement Options) Option {
+	return func(opts *Options) { *opts = replacement }
+}
+
+// Information gathered during a triangulation.
+type Diagnostics struct {
+	// Non-fatal problems noticed along the way
+	Warnings []Warning
+	// Points which were created by the library rather than given in the input,
+	// in the order they were created. For example, rectangular holes contribute
+	// four points each.
+	SynthesizedPoints []*Point
+	// Number of predicate decisions which were too close to call in floating
+	// point, and were settled with exact arithmetic instead. See
+	// Options.DisableExactFallback.
+	ExactEvaluations int
+	// How close the decisions which shaped the output came to going the other
+	// way.
+	Confidence Confidence
+	// If the triangulation fails after the trapezoid map is started, this is an
+	// audit of the map as it was when the failure happened. See AuditGraph.
+	Audit *AuditReport
+	// The depth of the point queries made while building the trapezoid map
+	QueryDepth QueryDepthStats
+	// The number of times the trapezoid map was rebuilt because its queries
+	// went too deep. See Options.RebuildDepthFactor.
+	GraphRebuilds int
+	// The number of times triangulating symmetric input failed before a later
+	// seed succeeded. See Options.SymmetricRetries.
+	SymmetricRetries int
+	// The number of inside trapezoids in the finished trapezoid map. This is
+	// zero when the input was already a single monotone piece, which skips the
+	// map.
+	Trapezoids int
+	// The number of monotone pieces the input was split into
+	Monotones int
+	// How long each stage took, indexed by Stage. Each stage is timed from the
+	// end of the one before, so the first includes checking and preparing the
+	// input, and the last includes restoring the triangles to the input space.
+	// The time spent in Options.Progress isn't counted.
+	StageTimes [stageCount]time.Duration
+	// Content hashes of each stage, if Options.HashStages is set
+	Hashes StageHashes
+	// The number of edges flipped, if Options.Delaunay is set
+	DelaunayFlips int
+	// The number of times PolygonList.Smooth cut a corner less to keep the
+	// rings from crossing
+	SmoothBackoffs int
+}
+
+type WarningKind string
+
+const (
+	// An output triangle references a point which did not appear in the input
+	WarningFabricatedPoint WarningKind = "fabricated point"
+	// A zero area triangle was left out of the output
+	WarningZeroAreaTriangle WarningKind = "zero area triangle"
+	// A ring has the same winding as the ring containing it
+	WarningNesting WarningKind = "nesting"
+	// A ring was removed entirely by PolygonList.RemoveThinFeatures
+	WarningThinFeature WarningKind = "thin feature"
+	// A clockwise sliver was left out of the output. See
+	// Options.DropClockwiseSlivers.
+	WarningClockwiseSliver WarningKind = "clockwise sliver"
+	// The input is too small for the magnitude of its coordinates to be
+	// triangulated reliably without Options.AutoNormalize
+	WarningSmallShape WarningKind = "small shape"
+	// A point query went deeper than Options.WarnDepthFactor allows
+	WarningQueryDepth WarningKind = "query depth"
+	// Symmetric input failed with Options.Seed, and a later seed succeeded
+	WarningSymmetricRetry WarningKind = "symmetric retry"
+	// Options.Delaunay ran out of flips before the output was Delaunay, which
+	// only rounding error should cause
+	WarningDelaunayIncomplete WarningKind = "delaunay incomplete"
+)
+
+// A non-fatal problem noticed during triangulation.
+type Warning struct {
+	Kind    WarningKind
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Kind, w.Message)
+}
+
+// Record a warning. This is safe to call on nil diagnostics, in which case the
+// warning is dropped.
+func (d *Diagnostics) warnf(kind WarningKind, format string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	d.Warnings = append(d.Warnings, Warning{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
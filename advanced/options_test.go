@@ -0,0 +1,67 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOptions(t *testing.T) {
+	assert.Equal(t, Options{}, NewOptions())
+
+	diagnostics := &Diagnostics{}
+	opts := NewOptions(
+		WithSeed(1),
+		WithOptions(Options{AutoNormalize: true, Diagnostics: diagnostics}),
+		WithSeed(42),
+		WithValidation(),
+		WithFillRule(EvenOdd),
+	)
+	// Everything before WithOptions is replaced, and everything after applies
+	// on top
+	assert.Equal(t, Options{
+		AutoNormalize: true,
+		Diagnostics:   diagnostics,
+		Seed:          42,
+		Validate:      true,
+		FillRule:      EvenOdd,
+	}, opts)
+}
+
+func TestSeed(t *testing.T) {
+	list := MultiLayeredHoles()
+	depths := make(map[int64]QueryDepthStats)
+	for _, seed := range []int64{0, 1, 2, 0} {
+		diagnostics := &Diagnostics{}
+		triangles := list.TriangulateWithOptions(NewOptions(WithSeed(seed), WithOptions(Options{Diagnostics: diagnostics}), WithSeed(seed)))
+		// The triangles don't depend on the order, only the graph over them does
+		assert.Equal(t, triangleCoordinates(list.Triangulate()), triangleCoordinates(triangles))
+		if previous, ok := depths[seed]; ok {
+			assert.Equal(t, previous, diagnostics.QueryDepth)
+		}
+		depths[seed] = diagnostics.QueryDepth
+	}
+	assert.NotEqual(t, depths[0], depths[1])
+	assert.NotEqual(t, depths[1], depths[2])
+
+	// The graph uses its seed directly
+	graph := &QueryGraph{Seed: 1}
+	graph.AddPolygons(list)
+	assert.Equal(t, depths[1], graph.QueryDepth())
+}
+
+func TestDiagnostics_Counts(t *testing.T) {
+	var diagnostics Diagnostics
+	PolygonList{squareRing(0, 0, 10)}.TriangulateWithOptions(Options{Diagnostics: &diagnostics})
+	// A lone monotone skips the trapezoid map
+	assert.Equal(t, 0, diagnostics.Trapezoids)
+	assert.Equal(t, 1, diagnostics.Monotones)
+
+	diagnostics = Diagnostics{}
+	list := PolygonList{squareRing(0, 0, 10), squareRing(3, 3, 4).Reverse()}
+	list.TriangulateWithOptions(Options{Diagnostics: &diagnostics})
+	// Below, beside and above the hole, along with the zero height trapezoids
+	// between level corners
+	assert.Equal(t, 8, diagnostics.Trapezoids)
+	assert.Equal(t, 2, diagnostics.Monotones)
+}
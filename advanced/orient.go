@@ -0,0 +1,55 @@
+package advanced
+
+// Vertex order conventions for output triangles. Triangles are built
+// counterclockwise, and Options.OrientTriangle decides, one triangle at a time,
+// whether each keeps that order or has two of its vertices swapped on the way
+// out.
+
+// A convention for Options.OrientTriangle which keeps every triangle
+// counterclockwise. This is the same as leaving the option unset.
+func OrientCCW(a, b, c *Point) bool {
+	return true
+}
+
+// A convention for Options.OrientTriangle which makes every triangle clockwise.
+func OrientCW(a, b, c *Point) bool {
+	return false
+}
+
+// The frame of a plane in 3D, given as the 3D directions of the plane's 2D x
+// and y axes, such as the cross sections from a slicing plane are drawn in.
+type PlaneBasis struct {
+	U, V [3]float64
+}
+
+// A convention for Options.OrientTriangle which winds triangles drawn in the
+// basis's plane so that their normal in 3D, by the right hand rule, points the
+// same way as the given normal, or at least not against it. A counterclockwise
+// triangle in the plane has the normal U × V, so this is OrientCCW when that
+// agrees with the normal, and OrientCW when it doesn't. A normal perpendicular
+// to the plane agrees either way, and keeps triangles counterclockwise.
+func MatchNormal(nx, ny, nz float64, basis PlaneBasis) func(a, b, c *Point) bool {
+	u, v := basis.U, basis.V
+	cross := [3]float64{
+		float64(u[1]*v[2]) - float64(u[2]*v[1]),
+		float64(u[2]*v[0]) - float64(u[0]*v[2]),
+		float64(u[0]*v[1]) - float64(u[1]*v[0]),
+	}
+	if float64(cross[0]*nx)+float64(cross[1]*ny)+float64(cross[2]*nz) < 0 {
+		return OrientCW
+	}
+	return OrientCCW
+}
+
+// Apply the convention to the finished triangles, swapping B and C of each
+// triangle which it rejects.
+func orientTriangles(triangles TriangleList, orient func(a, b, c *Point) bool) {
+	if orient == nil {
+		return
+	}
+	for _, tri := range triangles {
+		if !orient(tri.A, tri.B, tri.C) {
+			tri.B, tri.C = tri.C, tri.B
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrientTriangle_Default(t *testing.T) {
+	for _, orient := range []func(a, b, c *Point) bool{nil, OrientCCW} {
+		triangles := StarStripes().TriangulateWithOptions(Options{OrientTriangle: orient})
+		require.NotEmpty(t, triangles)
+		for _, tri := range triangles {
+			assert.False(t, IsCW(tri))
+		}
+	}
+}
+
+func TestOrientTriangle_CalledOncePerTriangle(t *testing.T) {
+	list := SquareWithHole()
+	expected := trianglePoints(list.Triangulate())
+
+	calls := 0
+	// Keep triangles left of the center counterclockwise, and make the rest
+	// clockwise. The centroid doesn't depend on the vertex order, so every
+	// emitted triangle can be checked against the same rule.
+	leftOfCenter := func(a, b, c *Point) bool {
+		return a.X+b.X+c.X < 0
+	}
+	triangles := list.TriangulateWithOptions(Options{OrientTriangle: func(a, b, c *Point) bool {
+		calls++
+		assert.True(t, IsCCW(&Triangle{a, b, c}), "called with %v, %v, %v", a, b, c)
+		return leftOfCenter(a, b, c)
+	}})
+
+	assert.Equal(t, len(triangles), calls)
+	assert.True(t, expected.Equals(trianglePoints(triangles)))
+	for _, tri := range triangles {
+		assert.Equal(t, leftOfCenter(tri.A, tri.B, tri.C), IsCCW(tri))
+	}
+}
+
+func TestOrientTriangle_CW(t *testing.T) {
+	triangles := PolygonList{circlePolygon(5, 20)}.TriangulateWithOptions(Options{OrientTriangle: OrientCW})
+	require.Len(t, triangles, 18)
+	for _, tri := range triangles {
+		assert.True(t, IsCW(tri))
+	}
+}
+
+func TestMatchNormal(t *testing.T) {
+	// The xz plane, seen from -y. Counterclockwise in the plane's frame has a
+	// normal pointing along -y.
+	basis := PlaneBasis{U: [3]float64{1, 0, 0}, V: [3]float64{0, 0, 1}}
+	to3D := func(p *Point) [3]float64 {
+		var result [3]float64
+		for i := range result {
+			result[i] = p.X*basis.U[i] + p.Y*basis.V[i]
+		}
+		return result
+	}
+
+	for _, normal := range [][3]float64{{0, 1, 0}, {0, -1, 0}, {0.3, 0.5, 0.2}, {-1, -0.1, 4}} {
+		triangles := StarStripes().TriangulateWithOptions(Options{
+			OrientTriangle: MatchNormal(normal[0], normal[1], normal[2], basis),
+		})
+		for _, tri := range triangles {
+			a, b, c := to3D(tri.A), to3D(tri.B), to3D(tri.C)
+			ab := [3]float64{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+			ac := [3]float64{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+			cross := [3]float64{
+				ab[1]*ac[2] - ab[2]*ac[1],
+				ab[2]*ac[0] - ab[0]*ac[2],
+				ab[0]*ac[1] - ab[1]*ac[0],
+			}
+			dot := cross[0]*normal[0] + cross[1]*normal[1] + cross[2]*normal[2]
+			assert.GreaterOrEqual(t, dot, 0.0, "normal %v", normal)
+		}
+	}
+
+	// A normal in the plane can't tell, so triangles stay counterclockwise
+	assert.True(t, MatchNormal(1, 0, 0, basis)(nil, nil, nil))
+}
@@ -0,0 +1,60 @@
+package advanced
+
+import "sort"
+
+// The order of the output triangles. See Options.
+type OutputOrder int
+
+const (
+	// Triangles come out in whatever order the triangulation produces them,
+	// which depends on how the trapezoid map was built, so a small change to the
+	// input can reorder all of them. This is the default.
+	Unsorted OutputOrder = iota
+	// Triangles are sorted by their lowest vertex, then by their middle vertex,
+	// then by their highest, comparing vertices with Point.Below, so that ties in
+	// Y follow the library's convention. Triangles which compare equal keep
+	// their relative order. A change to one part of the input then only moves
+	// the triangles near it.
+	Spatial
+)
+
+// Sort the triangles in place, as the order asks. This only costs anything for
+// Spatial.
+func sortTriangles(triangles TriangleList, order OutputOrder) {
+	switch order {
+	case Unsorted:
+		return
+	case Spatial:
+	default:
+		fatalf("unknown output order: %d", order)
+	}
+
+	type keyed struct {
+		// The vertices from lowest to highest
+		key      [3]*Point
+		triangle *Triangle
+	}
+	sorted := make([]keyed, len(triangles))
+	for i, tri := range triangles {
+		key := [3]*Point{tri.A, tri.B, tri.C}
+		sort.Slice(key[:], func(i, j int) bool {
+			return key[i].Below(key[j])
+		})
+		sorted[i] = keyed{key, tri}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		for k := range sorted[i].key {
+			a, b := sorted[i].key[k], sorted[j].key[k]
+			if a.Below(b) {
+				return true
+			}
+			if b.Below(a) {
+				return false
+			}
+		}
+		return false
+	})
+	for i := range sorted {
+		triangles[i] = sorted[i].triangle
+	}
+}
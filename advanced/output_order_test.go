@@ -0,0 +1,112 @@
+package advanced
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A wavy ring with 100 vertices, with its first vertex pushed out by the given
+// amount
+func wavyRing(push float64) Polygon {
+	points := make([]*Point, 100)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / 100
+		r := 10 + 2*math.Sin(7*angle)
+		if i == 0 {
+			r += push
+		}
+		points[i] = &Point{X: r * math.Cos(angle), Y: r * math.Sin(angle)}
+	}
+	return Polygon{points}
+}
+
+// Describe each triangle by its vertices, lowest first, keeping the order of
+// the list
+func triangleKeys(triangles TriangleList) []string {
+	keys := make([]string, len(triangles))
+	for i, tri := range triangles {
+		points := []*Point{tri.A, tri.B, tri.C}
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Below(points[j])
+		})
+		keys[i] = fmt.Sprint(points)
+	}
+	return keys
+}
+
+// The number of lines a diff between the two lists would show: everything not
+// in their longest common subsequence
+func diffSize(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] > lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lengths[0][0]
+}
+
+func TestSortOutput_Spatial(t *testing.T) {
+	triangles := StarStripes().TriangulateWithOptions(Options{SortOutput: Spatial})
+	require.NotEmpty(t, triangles)
+	keys := func(tri *Triangle) []*Point {
+		points := []*Point{tri.A, tri.B, tri.C}
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Below(points[j])
+		})
+		return points
+	}
+	for i := 1; i < len(triangles); i++ {
+		a, b := keys(triangles[i-1]), keys(triangles[i])
+		for k := range a {
+			if !Equal(a[k].X, b[k].X) || !Equal(a[k].Y, b[k].Y) {
+				assert.True(t, a[k].Below(b[k]), "triangle %d should come before triangle %d", i-1, i)
+				break
+			}
+		}
+	}
+}
+
+// Editing one vertex should only change the triangles around it, once sorted
+func TestSortOutput_SmallDiffs(t *testing.T) {
+	triangulate := func(push float64, order OutputOrder) TriangleList {
+		return PolygonList{wavyRing(push)}.TriangulateWithOptions(Options{SortOutput: order})
+	}
+
+	original := triangulate(0, Spatial)
+	require.Len(t, original, 98)
+	var incident int
+	for _, tri := range original {
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			if p.X == 10 && p.Y == 0 {
+				incident++
+			}
+		}
+	}
+	require.NotZero(t, incident)
+	// Each triangle touching the moved vertex is one line out and one line in
+	sorted := diffSize(triangleKeys(original), triangleKeys(triangulate(0.5, Spatial)))
+	assert.LessOrEqual(t, sorted, 2*incident)
+
+	// Unsorted output follows the trapezoid map, which is deterministic, but
+	// promises nothing about edits
+	assert.Equal(t, triangleKeys(triangulate(0, Unsorted)), triangleKeys(triangulate(0, Unsorted)))
+}
+
+func TestSortOutput_Invalid(t *testing.T) {
+	assert.Error(t, triangulateRecovering(SimpleStar(), Options{SortOutput: OutputOrder(99)}))
+}
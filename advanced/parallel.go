@@ -0,0 +1,142 @@
+package advanced
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Split the rings into groups which can be triangulated independently: each
+// ring which isn't inside any other, together with every ring nested inside
+// it, however deeply. So a solid, its holes, the islands in those holes, and so
+// on, are all one group. The result holds the ring indexes of each group, in
+// increasing order, with the groups in the order of their first ring.
+//
+// Containment is decided by bounding boxes first, then by the even-odd rule at
+// a vertex of the inner ring which the outer ring doesn't share. Unlike
+// ContainingRings, no trapezoid map is built, so this is cheap for many small
+// rings which are far apart, the case where splitting pays off.
+func (l PolygonList) IndependentGroups() [][]int {
+	type ringBounds struct {
+		index  int
+		bounds Rect
+	}
+	rings := make([]ringBounds, 0, len(l))
+	for i, poly := range l {
+		bounds := emptyBounds()
+		for _, p := range poly.Points {
+			bounds = bounds.including(p.X, p.Y)
+		}
+		rings = append(rings, ringBounds{i, bounds})
+	}
+
+	// Rings which contain one another are joined in a disjoint set forest
+	roots := make([]int, len(l))
+	for i := range roots {
+		roots[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if roots[i] != i {
+			roots[i] = find(roots[i])
+		}
+		return roots[i]
+	}
+
+	pointSets := make(map[int]PointSet)
+	// Is the inner ring inside the outer one?
+	inside := func(inner, outer ringBounds) bool {
+		a, b := inner.bounds, outer.bounds
+		if !(b.MinX <= a.MinX && a.MaxX <= b.MaxX && b.MinY <= a.MinY && a.MaxY <= b.MaxY) {
+			return false
+		}
+		shared, ok := pointSets[outer.index]
+		if !ok {
+			shared = make(PointSet)
+			for _, p := range l[outer.index].Points {
+				shared.Add(p)
+			}
+			pointSets[outer.index] = shared
+		}
+		for _, p := range l[inner.index].Points {
+			if !shared.Contains(p) {
+				return l[outer.index].ContainsPointByEvenOdd(p)
+			}
+		}
+		// Every point is shared, so the rings are the same
+		return true
+	}
+
+	// Sweep across X, keeping the rings which reach as far as the sweep line.
+	// A ring can only be inside one which starts before it, or at the same X.
+	sort.Slice(rings, func(i, j int) bool {
+		return rings[i].bounds.MinX < rings[j].bounds.MinX
+	})
+	var active []ringBounds
+	for _, ring := range rings {
+		kept := active[:0]
+		for _, other := range active {
+			if other.bounds.MaxX < ring.bounds.MinX {
+				continue
+			}
+			kept = append(kept, other)
+			if inside(ring, other) || inside(other, ring) {
+				roots[find(ring.index)] = find(other.index)
+			}
+		}
+		active = append(kept, ring)
+	}
+
+	var groups [][]int
+	groupOf := make(map[int]int)
+	for i := range l {
+		root := find(i)
+		group, ok := groupOf[root]
+		if !ok {
+			group = len(groups)
+			groupOf[root] = group
+			groups = append(groups, nil)
+		}
+		groups[group] = append(groups[group], i)
+	}
+	return groups
+}
+
+// Triangulate many disjoint polygons, such as the sectors of a map, on several
+// goroutines at once. The rings are split with IndependentGroups, each group is
+// triangulated on its own as by TriangulateBatch, and the triangles are merged
+// in the order of the groups. Workers is the most goroutines to use, or
+// GOMAXPROCS if it's zero or less.
+//
+// The options apply to each group, except that SortOutput sorts the merged
+// triangles. As with TriangulateBatch, opts.Diagnostics must be nil. If any
+// group fails, the error is the first failing group's, which numbers the rings
+// from zero within the group, so it's wrapped with the group's ring indexes in
+// the list.
+func (p *TriangulatorPool) TriangulateParallel(list PolygonList, workers int, opts Options) (TriangleList, error) {
+	if opts.Diagnostics != nil {
+		return nil, errors.New("Diagnostics can't be recorded for parallel triangulation")
+	}
+	indexes := list.IndependentGroups()
+	groups := make([]PolygonList, len(indexes))
+	for i, group := range indexes {
+		groups[i] = make(PolygonList, len(group))
+		for j, ring := range group {
+			groups[i][j] = list[ring]
+		}
+	}
+
+	results := make([]TriangleList, len(groups))
+	errs := make([]error, len(groups))
+	p.triangulateGroups(groups, opts, workers, results, errs)
+
+	var result TriangleList
+	for i, triangles := range results {
+		if errs[i] != nil {
+			return nil, errors.Wrapf(errs[i], "in the group of rings %v", indexes[i])
+		}
+		result = append(result, triangles...)
+	}
+	sortTriangles(result, opts.SortOutput)
+	return result, nil
+}
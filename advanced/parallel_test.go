@@ -0,0 +1,86 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Copies of a list, side by side in a grid, each in its own cell of the given
+// size
+func tiledCopies(list PolygonList, n int, size float64) PolygonList {
+	var result PolygonList
+	for i := 0; i < n; i++ {
+		dx, dy := float64(i%20)*size, float64(i/20)*size
+		for _, poly := range list {
+			points := make([]*Point, len(poly.Points))
+			for j, p := range poly.Points {
+				points[j] = &Point{X: p.X + dx, Y: p.Y + dy}
+			}
+			result = append(result, Polygon{points})
+		}
+	}
+	return result
+}
+
+func TestTriangulateParallel_Stars(t *testing.T) {
+	stars := tiledCopies(SimpleStar(), 200, 20)
+	groups := stars.IndependentGroups()
+	assert.Len(t, groups, 200)
+
+	serial := stars.Triangulate()
+	parallel, err := NewTriangulatorPool(0, 0).TriangulateParallel(stars, 4, Options{})
+	require.NoError(t, err)
+	assert.Len(t, parallel, len(serial))
+	assert.InDelta(t, totalArea(serial), totalArea(parallel), 1e-6)
+}
+
+func TestIndependentGroups_Nested(t *testing.T) {
+	// Every ring of the layered holes is nested in the outer star, so they all
+	// stay together, including the fill inside the innermost hole
+	layered := MultiLayeredHoles()
+	groups := layered.IndependentGroups()
+	require.Len(t, groups, 1)
+	assert.Len(t, groups[0], len(layered))
+
+	// Two copies are two groups, each with its own rings in order
+	copies := tiledCopies(layered, 2, 30)
+	groups = copies.IndependentGroups()
+	require.Len(t, groups, 2)
+	for i, group := range groups {
+		for j, ring := range group {
+			assert.Equal(t, i*len(layered)+j, ring)
+		}
+	}
+	triangles, err := NewTriangulatorPool(0, 0).TriangulateParallel(copies, 0, Options{})
+	require.NoError(t, err)
+	assert.Len(t, triangles, len(copies.Triangulate()))
+
+	// A ring inside another's bounding box, but outside the ring itself, is a
+	// group of its own
+	notched := PolygonList{
+		{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 9, Y: 10}, {X: 9, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 10}, {X: 0, Y: 10}}},
+		squareRing(4, 4, 2),
+	}
+	assert.Equal(t, [][]int{{0}, {1}}, notched.IndependentGroups())
+
+	assert.Empty(t, PolygonList{}.IndependentGroups())
+}
+
+func TestTriangulateParallel_Errors(t *testing.T) {
+	list := tiledCopies(PolygonList{squareRing(0, 0, 1)}, 10, 2)
+	list[7] = Polygon{[]*Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 2}}}
+	_, err := NewTriangulatorPool(0, 0).TriangulateParallel(list, 0, Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "[7]")
+
+	_, err = NewTriangulatorPool(0, 0).TriangulateParallel(list, 0, Options{Diagnostics: &Diagnostics{}})
+	assert.Error(t, err)
+
+	// The merged output is sorted when asked
+	stars := tiledCopies(SimpleStar(), 10, 20)
+	sorted, err := NewTriangulatorPool(0, 0).TriangulateParallel(stars, 0, Options{SortOutput: Spatial})
+	require.NoError(t, err)
+	assert.Equal(t, triangleCoordinates(stars.TriangulateWithOptions(Options{SortOutput: Spatial})), triangleCoordinates(sorted))
+}
@@ -0,0 +1,258 @@
+package advanced
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Polygons whose boundaries include circular arcs, as in CAD data. Arcs are
+// flattened into line segments before triangulating, and each flattened edge
+// remembers which element of the path it came from, so that the arcs can be
+// recovered from the output.
+
+// An element of a PathPolygon. Each element continues from where the previous
+// one ended, with the last element leading back around to the first. Make
+// these with Line and Arc.
+type PathElement struct {
+	// For a line, the point the line runs to. This is nil for arcs.
+	To *Point
+
+	// For an arc, the circle it runs along, and the angles it runs between, in
+	// radians. If the arc doesn't start where the previous element ended, a
+	// straight edge joins them.
+	Center     Point
+	Radius     float64
+	StartAngle float64
+	EndAngle   float64
+	// Whether the arc runs counterclockwise from StartAngle to EndAngle
+	CCW bool
+}
+
+// A straight line to the given point. The point appears in the output as it
+// is.
+func Line(to *Point) PathElement {
+	return PathElement{To: to}
+}
+
+// A circular arc. The angles may be given in any range; the arc runs from
+// startAngle to endAngle in the given direction, wrapping around as needed. If
+// the angles differ by a whole turn or more, the arc is a full circle.
+func Arc(center Point, radius, startAngle, endAngle float64, ccw bool) PathElement {
+	return PathElement{
+		Center:     center,
+		Radius:     radius,
+		StartAngle: startAngle,
+		EndAngle:   endAngle,
+		CCW:        ccw,
+	}
+}
+
+func (e PathElement) IsArc() bool {
+	return e.To == nil
+}
+
+// The signed angle swept by an arc, positive when counterclockwise
+func (e PathElement) sweep() float64 {
+	delta := e.EndAngle - e.StartAngle
+	if !e.CCW {
+		delta = -delta
+	}
+	sweep := math.Mod(delta, 2*math.Pi)
+	if sweep < 0 {
+		sweep += 2 * math.Pi
+	}
+	if sweep == 0 && delta != 0 {
+		sweep = 2 * math.Pi
+	}
+	if !e.CCW {
+		sweep = -sweep
+	}
+	return sweep
+}
+
+func (e PathElement) pointAt(angle float64) *Point {
+	return fabricatedPoint(e.Center.X+float64(e.Radius*math.Cos(angle)), e.Center.Y+float64(e.Radius*math.Sin(angle)))
+}
+
+// A polygon whose boundary is made of lines and arcs. As with Polygon, solids
+// run counterclockwise, and holes clockwise.
+type PathPolygon struct {
+	Elements []PathElement
+}
+
+// Where an edge of a flattened PathPolygon came from.
+type EdgeProvenance struct {
+	// The index of the element in the path
+	Element int
+	// Whether the edge is part of an arc. Otherwise, it is the whole of a line,
+	// or the straight edge leading into an arc which doesn't start where the
+	// previous element ended.
+	Arc bool
+	// The part of the element the edge covers. For an arc, these are the angles
+	// at either end of the edge, in the direction of the arc, continuing on from
+	// StartAngle, so they may be outside of [0, 2π). For a straight edge, they
+	// are 0 and 1.
+	From, To float64
+}
+
+// A vertex of a path being flattened, with the edge arriving at it
+type pathVertex struct {
+	point *Point
+	// Whether the point was given in the input, rather than computed on an arc
+	given    bool
+	incoming EdgeProvenance
+}
+
+// Flatten the path into a polygon whose edges are within tolerance of the
+// arcs they replace. Edge k of the polygon runs from vertex k to vertex k+1,
+// and provenance[k] says where it came from.
+//
+// Points closer than Epsilon to the previous point are merged into it, so a
+// line to the start of an arc doesn't add an edge of its own. Points given to
+// Line are kept in preference to points computed on arcs.
+func (path PathPolygon) Flatten(tolerance float64) (poly Polygon, provenance []EdgeProvenance, err error) {
+	if !(tolerance > 0) {
+		return Polygon{}, nil, errors.Errorf("tolerance must be positive, not %v", tolerance)
+	}
+
+	var vertices []pathVertex
+	add := func(v pathVertex) {
+		if len(vertices) > 0 {
+			last := &vertices[len(vertices)-1]
+			if samePosition(last.point, v.point) {
+				if v.given && !last.given {
+					last.point, last.given = v.point, true
+				}
+				return
+			}
+		}
+		vertices = append(vertices, v)
+	}
+
+	for i, element := range path.Elements {
+		if !element.IsArc() {
+			add(pathVertex{element.To, true, EdgeProvenance{Element: i, From: 0, To: 1}})
+			continue
+		}
+		if !(element.Radius > 0) || math.IsInf(element.Radius, 1) {
+			return Polygon{}, nil, errors.Errorf("arc %d has radius %v, but arcs need a positive, finite radius", i, element.Radius)
+		}
+
+		add(pathVertex{element.pointAt(element.StartAngle), false, EdgeProvenance{Element: i, From: 0, To: 1}})
+		sweep := element.sweep()
+		steps := arcSteps(element.Radius, math.Abs(sweep), tolerance)
+		previous := element.StartAngle
+		for step := 1; step <= steps; step++ {
+			angle := element.StartAngle + float64(sweep*float64(step)/float64(steps))
+			add(pathVertex{element.pointAt(angle), false, EdgeProvenance{Element: i, Arc: true, From: previous, To: angle}})
+			previous = angle
+		}
+	}
+
+	// Close the ring. If it ends where it started, the last vertex takes over
+	// the first, since the edge between them has no length.
+	if len(vertices) > 1 {
+		first, last := &vertices[0], vertices[len(vertices)-1]
+		if samePosition(first.point, last.point) {
+			if !first.given {
+				first.point, first.given = last.point, last.given
+			}
+			first.incoming = last.incoming
+			vertices = vertices[:len(vertices)-1]
+		}
+	}
+	if len(vertices) < 3 {
+		return Polygon{}, nil, errors.Errorf("path flattens to %d points, but polygons need at least 3", len(vertices))
+	}
+
+	poly.Points = make([]*Point, len(vertices))
+	provenance = make([]EdgeProvenance, len(vertices))
+	for k, v := range vertices {
+		poly.Points[k] = v.point
+		// The edge leaving vertex k arrives at vertex k+1
+		provenance[k] = vertices[CircularIndex(k+1, len(vertices))].incoming
+	}
+	return poly, provenance, nil
+}
+
+// Are the points within Epsilon of each other in both coordinates?
+func samePosition(a, b *Point) bool {
+	return Equal(a.X, b.X) && Equal(a.Y, b.Y)
+}
+
+// The number of equal steps needed to keep the sagitta of each chord across an
+// arc within the tolerance. A chord spanning angle θ on a circle of radius r
+// strays r(1 - cos(θ/2)) from the arc at its middle.
+func arcSteps(radius, sweep, tolerance float64) int {
+	if tolerance >= radius {
+		// Any chord shorter than a half turn is close enough
+		return int(math.Max(1, math.Ceil(sweep/math.Pi)))
+	}
+	maxAngle := 2 * math.Acos(1-tolerance/radius)
+	return int(math.Max(1, math.Ceil(sweep/maxAngle)))
+}
+
+type PathPolygonList []PathPolygon
+
+// Where an output triangle's edge came from, on the boundary of a
+// PathPolygonList
+type PathEdge struct {
+	// The index of the path in the list
+	Path int
+	EdgeProvenance
+}
+
+// Flatten every path in the list. See PathPolygon.Flatten.
+func (l PathPolygonList) Flatten(tolerance float64) (PolygonList, [][]EdgeProvenance, error) {
+	list := make(PolygonList, len(l))
+	provenance := make([][]EdgeProvenance, len(l))
+	for i, path := range l {
+		var err error
+		list[i], provenance[i], err = path.Flatten(tolerance)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "path %d", i)
+		}
+	}
+	return list, provenance, nil
+}
+
+// Flatten and triangulate the paths. Along with the triangles, this returns
+// where each triangle edge came from, like PolygonList.BoundaryFlags: for
+// triangle i, edges[i][0] is edge A-B, edges[i][1] is edge B-C, and
+// edges[i][2] is edge C-A. Edges inside the shape are nil. Options may
+// optionally be given, but CanonicalizeOutputPoints and AutoNormalize may
+// create new points, whose edges can't be traced back to the paths.
+func (l PathPolygonList) Triangulate(tolerance float64, opts ...Options) (triangles TriangleList, edges [][3]*PathEdge, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			triangles, edges, err = nil, nil, recoveredErr
+		}
+	}()
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	list, provenance, err := l.Flatten(tolerance)
+	if err != nil {
+		return nil, nil, err
+	}
+	ringEdges := make(map[locatorEdge]*PathEdge)
+	for i, poly := range list {
+		for k, p := range poly.Points {
+			edge := &PathEdge{i, provenance[i][k]}
+			ringEdges[newLocatorEdge(p, poly.Points[CircularIndex(k+1, len(poly.Points))])] = edge
+		}
+	}
+
+	triangles = list.TriangulateWithOptions(options)
+	edges = make([][3]*PathEdge, len(triangles))
+	for i, tri := range triangles {
+		vertices := [3]*Point{tri.A, tri.B, tri.C}
+		for j, p := range vertices {
+			edges[i][j] = ringEdges[newLocatorEdge(p, vertices[(j+1)%3])]
+		}
+	}
+	return triangles, edges, nil
+}
@@ -0,0 +1,229 @@
+package advanced
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// A PathBuilder makes polygons from a stream of path commands, as font
+// libraries and rasterizers produce them: MoveTo starts a ring, LineTo, QuadTo
+// and CubeTo extend it, and Close ends it. Curves are flattened as they
+// arrive, so the rings never exist as curves.
+//
+// A ring also ends at the next MoveTo, or at Polygons, since outlines such as
+// those from golang.org/x/image/font/sfnt don't close their contours
+// explicitly. Either way, the ring must end within the flatness of where it
+// started.
+//
+// The builder's memory is kept across Reset, so once it has grown to the size
+// of the paths being built, building them allocates nothing. The commands
+// don't return errors, so that they fit the usual callback signatures.
+// Instead, the first error is kept, later commands are ignored, and Polygons
+// and Triangulate return it.
+//
+// A PathBuilder must not be used by more than one goroutine at a time.
+type PathBuilder struct {
+	flatness float64
+
+	points []Point
+	// The end of each finished ring in points
+	ends []int
+	// Where the open ring starts in points, or -1 if there is none
+	start int
+	// The current point. This is the last point given, which may have been
+	// merged into the last point of the ring.
+	current Point
+
+	// Reused by Polygons
+	pointers []*Point
+	list     PolygonList
+	// Reused by Triangulate
+	buffers *buffers
+
+	err error
+}
+
+// Make a builder which flattens curves into edges straying no more than
+// flatness from them
+func NewPathBuilder(flatness float64) *PathBuilder {
+	b := &PathBuilder{flatness: flatness}
+	b.Reset()
+	return b
+}
+
+// Discard the paths built so far, keeping the memory they used. Polygons and
+// triangles from before the reset must not be used after it, since their
+// points are reused.
+func (b *PathBuilder) Reset() {
+	b.points = b.points[:0]
+	b.ends = b.ends[:0]
+	b.start = -1
+	b.err = nil
+	if !(b.flatness > 0) {
+		b.err = errors.Errorf("flatness must be positive, not %v", b.flatness)
+	}
+}
+
+// Start a new ring at the point, ending the open ring if there is one
+func (b *PathBuilder) MoveTo(x, y float64) {
+	b.endRing()
+	if b.err != nil {
+		return
+	}
+	b.start = len(b.points)
+	b.points = append(b.points, Point{X: x, Y: y})
+	b.current = Point{X: x, Y: y}
+}
+
+// Add a straight edge from the current point to the point
+func (b *PathBuilder) LineTo(x, y float64) {
+	if !b.ready("LineTo") {
+		return
+	}
+	b.add(x, y)
+}
+
+// Add a quadratic Bézier curve from the current point, with control point
+// (x1, y1), ending at (x, y)
+func (b *PathBuilder) QuadTo(x1, y1, x, y float64) {
+	if !b.ready("QuadTo") {
+		return
+	}
+	x0, y0 := b.current.X, b.current.Y
+	// A chord across a parameter step of 1/n strays at most |B''|/(8n²), and
+	// B'' is 2(p0 - 2p1 + p2) throughout
+	steps := curveSteps(2*math.Hypot(x0-2*x1+x, y0-2*y1+y), b.flatness)
+	for i := 1; i < steps; i++ {
+		t := float64(i) / float64(steps)
+		u := 1 - t
+		b.add(u*u*x0+2*u*t*x1+t*t*x, u*u*y0+2*u*t*y1+t*t*y)
+	}
+	b.add(x, y)
+}
+
+// Add a cubic Bézier curve from the current point, with control points
+// (x1, y1) and (x2, y2), ending at (x, y)
+func (b *PathBuilder) CubeTo(x1, y1, x2, y2, x, y float64) {
+	if !b.ready("CubeTo") {
+		return
+	}
+	x0, y0 := b.current.X, b.current.Y
+	// B'' runs between 6(p0 - 2p1 + p2) and 6(p1 - 2p2 + p3), so it's never
+	// larger than the larger of those
+	curvature := 6 * math.Max(math.Hypot(x0-2*x1+x2, y0-2*y1+y2), math.Hypot(x1-2*x2+x, y1-2*y2+y))
+	steps := curveSteps(curvature, b.flatness)
+	for i := 1; i < steps; i++ {
+		t := float64(i) / float64(steps)
+		u := 1 - t
+		b.add(
+			u*u*u*x0+3*u*u*t*x1+3*u*t*t*x2+t*t*t*x,
+			u*u*u*y0+3*u*u*t*y1+3*u*t*t*y2+t*t*t*y,
+		)
+	}
+	b.add(x, y)
+}
+
+// End the open ring. The current point must be within the flatness of where
+// the ring started, since this doesn't add an edge back to it.
+func (b *PathBuilder) Close() {
+	if !b.ready("Close") {
+		return
+	}
+	b.endRing()
+}
+
+// The rings built so far, ending the open ring if there is one. The polygons
+// share the builder's memory, so they're only valid until it's next changed.
+func (b *PathBuilder) Polygons() (PolygonList, error) {
+	b.endRing()
+	if b.err != nil {
+		return nil, b.err
+	}
+	b.pointers = b.pointers[:0]
+	for i := range b.points {
+		b.pointers = append(b.pointers, &b.points[i])
+	}
+	b.list = b.list[:0]
+	start := 0
+	for _, end := range b.ends {
+		b.list = append(b.list, Polygon{b.pointers[start:end:end]})
+		start = end
+	}
+	return b.list, nil
+}
+
+// Triangulate the rings built so far, with the given options. The triangles'
+// points belong to the builder, so they're only valid until the next Reset.
+func (b *PathBuilder) Triangulate(opts Options) (result TriangleList, err error) {
+	list, err := b.Polygons()
+	if err != nil {
+		return nil, err
+	}
+	if b.buffers == nil {
+		b.buffers = newBuffers(0)
+	}
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+	return list.triangulate(opts, b.buffers), nil
+}
+
+// Can a command which continues the open ring go ahead? If there's no open
+// ring, that's an error.
+func (b *PathBuilder) ready(command string) bool {
+	if b.err != nil {
+		return false
+	}
+	if b.start < 0 {
+		b.err = errors.Errorf("%s with no ring started; start one with MoveTo", command)
+		return false
+	}
+	return true
+}
+
+// Add a point to the open ring, merging it into the last point if they're in
+// the same place
+func (b *PathBuilder) add(x, y float64) {
+	b.current = Point{X: x, Y: y}
+	if samePosition(&b.points[len(b.points)-1], &b.current) {
+		return
+	}
+	b.points = append(b.points, b.current)
+}
+
+// End the open ring, if there is one. The last point is dropped if it's
+// within the flatness of the first, and otherwise the ring is an error. A ring
+// with no edges at all, from a MoveTo followed by another, is dropped whole.
+func (b *PathBuilder) endRing() {
+	if b.err != nil || b.start < 0 {
+		return
+	}
+	if len(b.points)-b.start == 1 {
+		b.points = b.points[:b.start]
+		b.start = -1
+		return
+	}
+	ring := len(b.ends)
+	first, last := &b.points[b.start], &b.points[len(b.points)-1]
+	if distance := math.Hypot(last.X-first.X, last.Y-first.Y); distance > b.flatness {
+		b.err = errors.Errorf("ring %d ends at %v, %v from where it started at %v, which is further than the flatness of %v", ring, last, distance, first, b.flatness)
+		return
+	}
+	b.points = b.points[:len(b.points)-1]
+	if count := len(b.points) - b.start; count < 3 {
+		b.err = errors.Errorf("ring %d has %d points, but polygons need at least 3", ring, count)
+		return
+	}
+	b.ends = append(b.ends, len(b.points))
+	b.start = -1
+}
+
+// The number of equal parameter steps needed to keep chords across a curve
+// within the flatness, given the largest magnitude of the curve's second
+// derivative
+func curveSteps(curvature, flatness float64) int {
+	return int(math.Max(1, math.Ceil(math.Sqrt(curvature/(8*flatness)))))
+}
@@ -0,0 +1,199 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An O, as a font would give it: an outer ring of quadratic curves, and a hole
+// inside it, with neither closed explicitly
+func buildGlyphO(b *PathBuilder) {
+	b.MoveTo(1, 0)
+	b.QuadTo(1, 1, 0, 1)
+	b.QuadTo(-1, 1, -1, 0)
+	b.QuadTo(-1, -1, 0, -1)
+	b.QuadTo(1, -1, 1, 0)
+	b.MoveTo(0.5, 0)
+	b.QuadTo(0.5, -0.5, 0, -0.5)
+	b.QuadTo(-0.5, -0.5, -0.5, 0)
+	b.QuadTo(-0.5, 0.5, 0, 0.5)
+	b.QuadTo(0.5, 0.5, 0.5, 0)
+}
+
+func TestPathBuilder(t *testing.T) {
+	b := NewPathBuilder(0.01)
+	b.MoveTo(0, 0)
+	b.LineTo(2, 0)
+	b.LineTo(2, 2)
+	b.LineTo(0, 2)
+	b.LineTo(0, 0)
+	b.Close()
+	list, err := b.Polygons()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, []Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}, pointValues(list[0].Points))
+
+	// Rings end at the next MoveTo, or at the end
+	b.Reset()
+	buildGlyphO(b)
+	list, err = b.Polygons()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.True(t, IsCCW(&list[0]))
+	assert.True(t, IsCW(&list[1]))
+
+	triangles, err := b.Triangulate(Options{})
+	require.NoError(t, err)
+	// The outer ring is a diamond of area 2, with each side bulging out by two
+	// thirds of the triangle its curve's points make, and the hole is the same
+	// at half the size. The edges are within the flatness of the curves, which
+	// are less than 12 long in all.
+	outer := 2 + 4*(2.0/3)*0.5
+	expected := outer - outer/4
+	assert.InDelta(t, expected, totalArea(triangles), 12*0.01)
+
+	// A MoveTo with nothing after it is no ring at all
+	b.Reset()
+	b.MoveTo(5, 5)
+	b.MoveTo(0, 0)
+	b.LineTo(1, 0)
+	b.LineTo(0, 1)
+	b.LineTo(0, 0)
+	b.MoveTo(3, 3)
+	list, err = b.Polygons()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func pointValues(points []*Point) []Point {
+	var result []Point
+	for _, p := range points {
+		result = append(result, *p)
+	}
+	return result
+}
+
+func TestPathBuilder_Flatness(t *testing.T) {
+	quad := func(t float64) Point {
+		u := 1 - t
+		return Point{X: 2*u*t*5 + t*t*10, Y: 2 * u * t * 10}
+	}
+	cube := func(t float64) Point {
+		u := 1 - t
+		return Point{X: u*u*u*10 + 3*u*u*t*15 + 3*u*t*t*-5, Y: 3*u*u*t*-10 + 3*u*t*t*10}
+	}
+	for _, flatness := range []float64{0.5, 0.01, 1e-4} {
+		b := NewPathBuilder(flatness)
+		b.MoveTo(0, 0)
+		b.QuadTo(5, 10, 10, 0)
+		b.CubeTo(15, -10, -5, 10, 0, 0)
+		list, err := b.Polygons()
+		require.NoError(t, err)
+
+		// The curves never stray further than the flatness from the edges
+		// which replace them, and their ends are kept exactly
+		for i := 0; i <= 1000; i++ {
+			for _, curve := range []func(float64) Point{quad, cube} {
+				p := curve(float64(i) / 1000)
+				assert.LessOrEqual(t, boundaryDistance(list, &p), flatness, "%v at flatness %v", p, flatness)
+			}
+		}
+		assert.Equal(t, Point{X: 0, Y: 0}, *list[0].Points[0])
+		assert.Contains(t, pointValues(list[0].Points), Point{X: 10, Y: 0})
+
+		// Nor are there many more edges than that needs. Each curve needs at least
+		// one edge per √(|B''|/8·flatness).
+		assert.Less(t, len(list[0].Points), 2*(curveSteps(40, flatness)+curveSteps(6*math.Hypot(25, 30), flatness)))
+	}
+}
+
+func TestPathBuilder_Errors(t *testing.T) {
+	cases := map[string]struct {
+		flatness float64
+		build    func(b *PathBuilder)
+		message  string
+	}{
+		"flatness": {0, func(b *PathBuilder) {}, "flatness must be positive, not 0"},
+		"no ring": {0.1, func(b *PathBuilder) {
+			b.LineTo(1, 1)
+		}, "LineTo with no ring started"},
+		"closed twice": {0.1, func(b *PathBuilder) {
+			b.MoveTo(0, 0)
+			b.LineTo(1, 0)
+			b.LineTo(0, 1)
+			b.LineTo(0, 0)
+			b.Close()
+			b.Close()
+		}, "Close with no ring started"},
+		"open": {0.1, func(b *PathBuilder) {
+			b.MoveTo(0, 0)
+			b.LineTo(1, 0)
+			b.LineTo(1, 1)
+			b.LineTo(0, 1)
+			b.Close()
+		}, "ring 0 ends at {0.00, 1.00}, 1 from where it started at {0.00, 0.00}"},
+		"open at the next ring": {0.1, func(b *PathBuilder) {
+			buildGlyphO(b)
+			b.MoveTo(5, 5)
+			b.LineTo(6, 5)
+			b.LineTo(6, 6)
+			b.MoveTo(0, 0)
+		}, "ring 2 ends at {6.00, 6.00}"},
+		"too few points": {0.1, func(b *PathBuilder) {
+			b.MoveTo(0, 0)
+			b.LineTo(1, 0)
+			b.LineTo(0, 0)
+		}, "ring 0 has 2 points, but polygons need at least 3"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := NewPathBuilder(c.flatness)
+			c.build(b)
+			// Later commands are ignored
+			b.MoveTo(10, 10)
+			b.LineTo(11, 10)
+			b.LineTo(10, 11)
+			_, err := b.Polygons()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.message)
+			_, err = b.Triangulate(Options{})
+			assert.Contains(t, err.Error(), c.message)
+
+			// The builder can be used again after a reset, unless it can never work
+			b.Reset()
+			buildGlyphO(b)
+			_, err = b.Polygons()
+			assert.Equal(t, c.flatness <= 0, err != nil)
+		})
+	}
+}
+
+func TestPathBuilder_Allocations(t *testing.T) {
+	b := NewPathBuilder(1e-3)
+	build := func() {
+		b.Reset()
+		buildGlyphO(b)
+		if _, err := b.Polygons(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Once the builder has grown to the size of the glyph, building it again
+	// allocates nothing
+	build()
+	assert.Zero(t, testing.AllocsPerRun(100, build))
+}
+
+func BenchmarkPathBuilder(b *testing.B) {
+	builder := NewPathBuilder(1e-3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		builder.Reset()
+		buildGlyphO(builder)
+		if _, err := builder.Polygons(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
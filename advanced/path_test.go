@@ -0,0 +1,172 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A 4x2 rectangle centered on the origin, with semicircles on its short ends
+func stadium() PathPolygon {
+	return PathPolygon{[]PathElement{
+		Line(&Point{X: -2, Y: -1}),
+		Line(&Point{X: 2, Y: -1}),
+		Arc(Point{X: 2, Y: 0}, 1, -math.Pi/2, math.Pi/2, true),
+		Line(&Point{X: -2, Y: 1}),
+		Arc(Point{X: -2, Y: 0}, 1, math.Pi/2, 3*math.Pi/2, true),
+	}}
+}
+
+func TestPathPolygon_Stadium(t *testing.T) {
+	const tolerance = 1e-3
+	path := stadium()
+	triangles, edges, err := PathPolygonList{path}.Triangulate(tolerance)
+	require.NoError(t, err)
+
+	// Chords only ever cut inside the arcs, by at most the tolerance
+	analyticArea := 8 + math.Pi
+	area := totalArea(triangles)
+	assert.LessOrEqual(t, area, analyticArea)
+	assert.Greater(t, area, analyticArea-tolerance*2*math.Pi)
+
+	poly, _, err := path.Flatten(tolerance)
+	require.NoError(t, err)
+	boundaryEdges := 0
+	for i, tri := range triangles {
+		vertices := [3]*Point{tri.A, tri.B, tri.C}
+		for j, edge := range edges[i] {
+			if edge == nil {
+				continue
+			}
+			boundaryEdges++
+			assert.Equal(t, 0, edge.Path)
+			element := path.Elements[edge.Element]
+			a, b := vertices[j], vertices[(j+1)%3]
+			if edge.Arc {
+				require.True(t, element.IsArc())
+				for _, p := range []*Point{a, b} {
+					assert.InDelta(t, element.Radius, math.Hypot(p.X-element.Center.X, p.Y-element.Center.Y), 1e-9)
+				}
+				// The ends of the edge are at the angles recorded for it, in some
+				// order, since triangle edges needn't follow the ring
+				ends := PointSet{element.pointAt(edge.From): {}, element.pointAt(edge.To): {}}
+				for _, p := range []*Point{a, b} {
+					found := false
+					for end := range ends {
+						found = found || samePosition(p, end)
+					}
+					assert.True(t, found, "%v is not at either end of %v", p, edge)
+				}
+			} else {
+				// Every straight edge in the stadium is a line to a given point
+				require.False(t, element.IsArc())
+				assert.True(t, a == element.To || b == element.To)
+			}
+		}
+	}
+	assert.Equal(t, len(poly.Points), boundaryEdges)
+}
+
+func TestPathPolygon_Flatten(t *testing.T) {
+	t.Run("lines keep their points", func(t *testing.T) {
+		a, b, c := &Point{X: 0, Y: 0}, &Point{X: 1, Y: 0}, &Point{X: 0, Y: 1}
+		poly, provenance, err := PathPolygon{[]PathElement{Line(a), Line(b), Line(c)}}.Flatten(0.1)
+		require.NoError(t, err)
+		assert.Equal(t, []*Point{a, b, c}, poly.Points)
+		// The edge leaving vertex k is the line to vertex k+1
+		assert.Equal(t, []EdgeProvenance{
+			{Element: 1, From: 0, To: 1},
+			{Element: 2, From: 0, To: 1},
+			{Element: 0, From: 0, To: 1},
+		}, provenance)
+	})
+
+	t.Run("sagitta within tolerance", func(t *testing.T) {
+		for _, tolerance := range []float64{0.5, 0.1, 1e-3, 1e-6} {
+			circle := Arc(Point{X: 3, Y: 4}, 2, 0, 2*math.Pi, true)
+			poly, provenance, err := PathPolygon{[]PathElement{circle}}.Flatten(tolerance)
+			require.NoError(t, err)
+			require.True(t, IsCCW(&poly))
+			for k, p := range poly.Points {
+				next := poly.Points[CircularIndex(k+1, len(poly.Points))]
+				midX, midY := (p.X+next.X)/2, (p.Y+next.Y)/2
+				assert.LessOrEqual(t, 2-math.Hypot(midX-3, midY-4), tolerance*(1+1e-9))
+				assert.True(t, provenance[k].Arc)
+			}
+		}
+	})
+
+	t.Run("angle wrap-around", func(t *testing.T) {
+		for _, test := range []struct {
+			start, end float64
+			ccw        bool
+			sweep      float64
+		}{
+			{0, math.Pi / 2, true, math.Pi / 2},
+			{3 * math.Pi / 2, math.Pi / 2, true, math.Pi},
+			{-math.Pi / 2, math.Pi / 2, true, math.Pi},
+			{0, 2 * math.Pi, true, 2 * math.Pi},
+			{0, 5 * math.Pi, true, math.Pi},
+			{math.Pi / 2, -math.Pi / 2, false, -math.Pi},
+			{0, math.Pi / 2, false, -3 * math.Pi / 2},
+			{1, 1, true, 0},
+		} {
+			assert.InDelta(t, test.sweep, Arc(Point{}, 1, test.start, test.end, test.ccw).sweep(), 1e-12, "%+v", test)
+		}
+
+		// An arc through angle zero bulges the right way
+		poly, _, err := PathPolygon{[]PathElement{
+			Line(&Point{X: 0, Y: 1}),
+			Arc(Point{X: 0, Y: 0}, 1, 3*math.Pi/2, math.Pi/2, true),
+		}}.Flatten(1e-3)
+		require.NoError(t, err)
+		assert.True(t, IsCCW(&poly))
+		assert.InDelta(t, math.Pi/2, Area(&poly), 1e-2)
+	})
+
+	t.Run("tiny arcs collapse", func(t *testing.T) {
+		corner := func(sweep float64) PathPolygon {
+			return PathPolygon{[]PathElement{
+				Line(&Point{X: 0, Y: 0}),
+				Line(&Point{X: 2, Y: 0}),
+				Arc(Point{X: 1, Y: 1}, 1, 0, sweep, true),
+			}}
+		}
+		// A sweep too small to notice adds no points beyond its start
+		poly, _, err := corner(1e-9).Flatten(1e-3)
+		require.NoError(t, err)
+		assert.Len(t, poly.Points, 3)
+
+		// A small one is a single segment
+		poly, provenance, err := corner(1e-3).Flatten(1e-3)
+		require.NoError(t, err)
+		assert.Len(t, poly.Points, 4)
+		assert.Equal(t, EdgeProvenance{Element: 2, Arc: true, From: 0, To: 1e-3}, provenance[2])
+	})
+
+	t.Run("connector into an arc", func(t *testing.T) {
+		poly, provenance, err := PathPolygon{[]PathElement{
+			Line(&Point{X: 0, Y: 0}),
+			Arc(Point{X: 0, Y: 0}, 1, 0, math.Pi/2, true),
+		}}.Flatten(0.1)
+		require.NoError(t, err)
+		// The straight edge from the origin to the start of the arc
+		assert.Equal(t, EdgeProvenance{Element: 1, From: 0, To: 1}, provenance[0])
+		// The edge back from the end of the arc is the line
+		assert.Equal(t, EdgeProvenance{Element: 0, From: 0, To: 1}, provenance[len(provenance)-1])
+		assert.Equal(t, Point{X: 0, Y: 0}, *poly.Points[0])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := stadium().Flatten(0)
+		assert.Error(t, err)
+		_, _, err = PathPolygon{[]PathElement{Line(&Point{X: 0, Y: 0}), Arc(Point{}, 0, 0, 1, true)}}.Flatten(0.1)
+		assert.Error(t, err)
+		_, _, err = PathPolygon{[]PathElement{Line(&Point{X: 0, Y: 0}), Line(&Point{X: 1, Y: 0})}}.Flatten(0.1)
+		assert.Error(t, err)
+		_, _, err = PathPolygonList{stadium(), {}}.Triangulate(0.1)
+		assert.EqualError(t, err, "path 1: path flattens to 0 points, but polygons need at least 3")
+	})
+}
@@ -0,0 +1,158 @@
+package advanced
+
+import (
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Parsing and formatting for the "point log" format, which is how points print
+// with Point.String(), and so how they tend to show up in logs and bug reports:
+//
+//   points: [{-5248.00, -7168.00} {-256.00, -7168.00} {-1024.00, -5376.00}]
+//
+// The "points:" prefix and the brackets are optional, and any whitespace or
+// commas may separate the points. Multiple polygons are separated by blank
+// lines, or by labels like "polygon 2:".
+//
+// Note that Point.String() rounds to two decimal places, so it does not
+// round-trip. FormatPointLog writes the same format at full precision.
+
+var polygonLabelPattern = regexp.MustCompile(`(?i)^\s*polygon\s+\d+\s*:`)
+
+// Parse a single polygon from a point log. It is an error for the log to
+// contain more than one polygon.
+func ParsePointLog(s string) ([]*Point, error) {
+	list, err := ParsePointLogs(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) != 1 {
+		return nil, errors.Errorf("expected one polygon in point log, found %d", len(list))
+	}
+	return list[0].Points, nil
+}
+
+// Parse any number of polygons from a point log.
+func ParsePointLogs(s string) (PolygonList, error) {
+	var list PolygonList
+	var current []*Point
+	endPolygon := func() {
+		if len(current) > 0 {
+			list = append(list, Polygon{current})
+			current = nil
+		}
+	}
+
+	for lineIndex, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			endPolygon()
+			continue
+		}
+		if label := polygonLabelPattern.FindString(line); label != "" {
+			endPolygon()
+			line = line[len(label):]
+		}
+
+		points, err := parsePointLogLine(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineIndex+1)
+		}
+		current = append(current, points...)
+	}
+	endPolygon()
+	return list, nil
+}
+
+func parsePointLogLine(line string) ([]*Point, error) {
+	var points []*Point
+	rest := strings.TrimSpace(line)
+	rest = strings.TrimPrefix(rest, "points:")
+
+	for {
+		rest = strings.TrimLeft(rest, " \t\r,[]")
+		if rest == "" {
+			return points, nil
+		}
+		if rest[0] != '{' {
+			return nil, errors.Errorf("expected '{' at %q", rest)
+		}
+
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return nil, errors.Errorf("unterminated point at %q", rest)
+		}
+		coordinates := strings.Split(rest[1:end], ",")
+		if len(coordinates) != 2 {
+			return nil, errors.Errorf("expected two coordinates in %q", rest[:end+1])
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(coordinates[0]), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad x coordinate in %q", rest[:end+1])
+		}
+		y, err := strconv.ParseFloat(strings.TrimSpace(coordinates[1]), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad y coordinate in %q", rest[:end+1])
+		}
+
+		points = append(points, &Point{X: x, Y: y})
+		rest = rest[end+1:]
+	}
+}
+
+// Format points in the point log format, at full precision, so that
+// ParsePointLog gives back exactly the same coordinates.
+func FormatPointLog(points []*Point) string {
+	var builder strings.Builder
+	builder.WriteString("points: [")
+	for i, p := range points {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteByte('{')
+		builder.WriteString(strconv.FormatFloat(p.X, 'g', -1, 64))
+		builder.WriteString(", ")
+		builder.WriteString(strconv.FormatFloat(p.Y, 'g', -1, 64))
+		builder.WriteByte('}')
+	}
+	builder.WriteByte(']')
+	return builder.String()
+}
+
+// Read polygons from text. This accepts the point log format (see
+// ParsePointLogs), or a plain format with one point per line, as two numbers
+// separated by whitespace or a comma. In the plain format, polygons are
+// separated the same way, with blank lines or labels.
+func ReadPolygons(r io.Reader) (PolygonList, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	if strings.Contains(text, "{") {
+		return ParsePointLogs(text)
+	}
+
+	// Rewrite the plain format as a point log, keeping the line structure so
+	// that errors have the right line numbers
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		label := polygonLabelPattern.FindString(line)
+		fields := strings.FieldsFunc(line[len(label):], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		switch len(fields) {
+		case 0:
+			continue
+		case 2:
+			lines[i] = label + "{" + fields[0] + ", " + fields[1] + "}"
+		default:
+			return nil, errors.Errorf("line %d: expected two coordinates, got %q", i+1, line)
+		}
+	}
+	return ParsePointLogs(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,113 @@
+package advanced
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePointLog(t *testing.T) {
+	expected := []Point{{X: -5248, Y: -7168}, {X: -256, Y: -7168}, {X: -1024, Y: -5376}}
+	for name, input := range map[string]string{
+		"log":                "points: [{-5248.00, -7168.00} {-256.00, -7168.00} {-1024.00, -5376.00}]",
+		"bare":               "{-5248, -7168}{-256,-7168} {-1024 , -5376}",
+		"commas":             "{-5248.00, -7168.00}, {-256.00, -7168.00}, {-1024.00, -5376.00}",
+		"newlines":           "points: [\n  {-5248.00, -7168.00}\n  {-256.00, -7168.00}\n  {-1024.00, -5376.00}\n]",
+		"label":              "polygon 0: {-5248.00, -7168.00} {-256.00, -7168.00} {-1024.00, -5376.00}",
+		"String() of points": strings.Join([]string{(&Point{X: -5248, Y: -7168}).String(), (&Point{X: -256, Y: -7168}).String(), (&Point{X: -1024, Y: -5376}).String()}, " "),
+	} {
+		t.Run(name, func(t *testing.T) {
+			points, err := ParsePointLog(input)
+			require.NoError(t, err)
+			require.Len(t, points, len(expected))
+			for i, p := range points {
+				assert.Equal(t, expected[i], *p)
+			}
+		})
+	}
+}
+
+func TestParsePointLog_Errors(t *testing.T) {
+	for name, input := range map[string]string{
+		"garbage":           "points: [{1, 2} oops]",
+		"unterminated":      "{1, 2} {3, 4",
+		"one coordinate":    "{1}",
+		"three coordinates": "{1, 2, 3}",
+		"bad number":        "{1, x}",
+		"two polygons":      "{0, 0} {1, 0} {0, 1}\n\n{5, 5} {6, 5} {5, 6}",
+		"empty":             "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParsePointLog(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePointLogs(t *testing.T) {
+	for name, input := range map[string]string{
+		"blank lines": "{0, 0} {1, 0} {0, 1}\n\n  \n{5, 5} {6, 5}\n{5, 6}\n",
+		"labels":      "polygon 0: {0, 0} {1, 0} {0, 1}\npolygon 1:\n{5, 5} {6, 5} {5, 6}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			list, err := ParsePointLogs(input)
+			require.NoError(t, err)
+			require.Len(t, list, 2)
+			assert.Len(t, list[0].Points, 3)
+			assert.Len(t, list[1].Points, 3)
+			assert.Equal(t, Point{X: 5, Y: 6}, *list[1].Points[2])
+		})
+	}
+
+	_, err := ParsePointLogs("{0, 0}\n{1, 0}\n{0, oops}")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3")
+}
+
+func TestFormatPointLog_RoundTrip(t *testing.T) {
+	points := []*Point{
+		{X: -5248, Y: -7168},
+		{X: 0.1, Y: 1.0 / 3},
+		{X: math.Pi * 1e10, Y: -math.SmallestNonzeroFloat64},
+		{X: math.MaxFloat64, Y: 1e-300},
+	}
+	parsed, err := ParsePointLog(FormatPointLog(points))
+	require.NoError(t, err)
+	require.Len(t, parsed, len(points))
+	for i, p := range parsed {
+		assert.Equal(t, *points[i], *p)
+	}
+}
+
+func TestReadPolygons(t *testing.T) {
+	plain := "0 0\n1, 0\n0,1\n\npolygon 1:\n5 5\n6 5\n5 6\n"
+	list, err := ReadPolygons(strings.NewReader(plain))
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, Point{X: 1, Y: 0}, *list[0].Points[1])
+	assert.Equal(t, Point{X: 5, Y: 6}, *list[1].Points[2])
+
+	_, err = ReadPolygons(strings.NewReader("0 0\n1 2 3\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+// The quad from the degenerate quad issue report, which used to fail in
+// ConvertToMonotones with "polygon is degenerate"
+func TestIssue_DegenerateQuad(t *testing.T) {
+	list := LoadTextFixture("issue_degenerate_quad")
+	require.Len(t, list, 1)
+	require.True(t, IsCCW(&list[0]))
+
+	var result TriangleList
+	require.NotPanics(t, func() {
+		result = list.Triangulate()
+	})
+	for _, triangle := range result {
+		assert.True(t, IsCCW(triangle))
+	}
+	AssertValidTriangulation(t, &list[0], result)
+}
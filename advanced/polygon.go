@@ -1,5 +1,7 @@
 package advanced
 
+import "math"
+
 type Polygon struct {
 	Points []*Point
 }
@@ -9,6 +11,33 @@ type Polygon struct {
 // run counter-clockwise.
 type PolygonList []Polygon
 
+// Wrap each slice of points as a polygon. The slices are shared, not copied.
+func PolygonsFromPointSlices(pointSlices [][]*Point) PolygonList {
+	list := make(PolygonList, len(pointSlices))
+	for i, points := range pointSlices {
+		list[i] = Polygon{Points: points}
+	}
+	return list
+}
+
+// The inverse of PolygonsFromPointSlices. The slices are shared, not copied.
+func (l PolygonList) PointSlices() [][]*Point {
+	pointSlices := make([][]*Point, len(l))
+	for i, poly := range l {
+		pointSlices[i] = poly.Points
+	}
+	return pointSlices
+}
+
+// The total number of vertices in all of the polygons
+func (l PolygonList) vertexCount() int {
+	count := 0
+	for _, poly := range l {
+		count += len(poly.Points)
+	}
+	return count
+}
+
 // Even/odd point-in-polygon. This is provided primarily for testing of the
 // Seidel algorithm. If you are checking many points inside the same large
 // polygon, it can be more effficient to trapezoidize it and use the resulting
@@ -27,7 +56,7 @@ func (poly Polygon) CrossingCount(p *Point) int {
 	for i, vertex := range poly.Points {
 		nextVertex := poly.Points[CircularIndex(i+1, len(poly.Points))]
 
-		segment := Segment{vertex, nextVertex}
+		segment := NewSegment(vertex, nextVertex)
 		if !segment.IsLeftOf(p) && vertex.Below(p) != nextVertex.Below(p) {
 			crossingCount++
 		}
@@ -54,3 +83,101 @@ func (poly Polygon) Reverse() Polygon {
 	}
 	return newPoly
 }
+
+// Report the winding of each polygon in the list, true meaning
+// counterclockwise. Polygons with zero area count as clockwise.
+func (l PolygonList) Windings() []bool {
+	windings := make([]bool, len(l))
+	for i := range l {
+		windings[i] = IsCCW(&l[i])
+	}
+	return windings
+}
+
+// Wind each polygon according to its role, so that holes run clockwise, and
+// everything else runs counterclockwise. The isHole function is given the index
+// of each polygon. Polygons which already have the right winding are returned
+// as they are, and the rest are reversed. Either way, the points themselves are
+// never copied or modified.
+//
+// Alongside the new list, this returns which polygons were reversed, so that
+// callers can keep per-polygon data in step.
+func (l PolygonList) NormalizeWinding(isHole func(ringIndex int) bool) (PolygonList, []bool) {
+	result := make(PolygonList, len(l))
+	flipped := make([]bool, len(l))
+	for i, isCCW := range l.Windings() {
+		if isCCW == isHole(i) {
+			result[i] = l[i].Reverse()
+			flipped[i] = true
+		} else {
+			result[i] = l[i]
+		}
+	}
+	return result, flipped
+}
+
+// How a vertex turns, with respect to the filled region.
+type VertexClass int
+
+const (
+	// The filled region's angle at the vertex is less than 180 degrees
+	Convex VertexClass = iota
+	// The filled region's angle at the vertex is more than 180 degrees
+	Reflex
+	// The vertex lies on the line through its neighbors, to within Epsilon
+	Collinear
+)
+
+func (class VertexClass) String() string {
+	switch class {
+	case Convex:
+		return "Convex"
+	case Reflex:
+		return "Reflex"
+	case Collinear:
+		return "Collinear"
+	}
+	return "Invalid"
+}
+
+// Classify each vertex of the polygon as convex or reflex, with respect to the
+// filled region. With the usual windings, the filled region is always on the
+// left of each edge, whether the polygon is a solid or a hole, so a left turn
+// is convex and a right turn is reflex. For holes, that is the opposite of
+// the hole's own shape: the corners of a square hole are all reflex.
+//
+// A vertex within Epsilon of the line through its neighbors is Collinear. This
+// includes spikes, where the ring doubles back on itself.
+func (poly Polygon) VertexClassification() []VertexClass {
+	classes := make([]VertexClass, len(poly.Points))
+	for i, p := range poly.Points {
+		prev := poly.Points[CircularIndex(i-1, len(poly.Points))]
+		next := poly.Points[CircularIndex(i+1, len(poly.Points))]
+
+		// The cross product of the two edges, divided by the length of the
+		// shortcut from prev to next, is the distance of p from that shortcut.
+		cross := float64((p.X-prev.X)*(next.Y-p.Y)) - float64((p.Y-prev.Y)*(next.X-p.X))
+		shortcut := math.Hypot(next.X-prev.X, next.Y-prev.Y)
+		switch {
+		case math.Abs(cross) <= Epsilon*shortcut:
+			classes[i] = Collinear
+		case cross > 0:
+			classes[i] = Convex
+		default:
+			classes[i] = Reflex
+		}
+	}
+	return classes
+}
+
+// Classify every vertex in the list. See Polygon.VertexClassification. A point
+// shared by several rings gets its classification from the last of them.
+func (l PolygonList) VertexClassification() map[*Point]VertexClass {
+	classes := make(map[*Point]VertexClass)
+	for _, poly := range l {
+		for i, class := range poly.VertexClassification() {
+			classes[poly.Points[i]] = class
+		}
+	}
+	return classes
+}
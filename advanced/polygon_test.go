@@ -0,0 +1,88 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindings(t *testing.T) {
+	assert.Equal(t, []bool{true, false}, SquareWithHole().Windings())
+	assert.Equal(t, []bool{false, true}, PolygonList{SquareWithHole()[1], SquareWithHole()[0]}.Windings())
+	assert.Empty(t, PolygonList{}.Windings())
+}
+
+func TestNormalizeWinding(t *testing.T) {
+	original := SquareWithHole()
+
+	t.Run("already correct", func(t *testing.T) {
+		result, flipped := original.NormalizeWinding(func(i int) bool { return i == 1 })
+		assert.Equal(t, []bool{false, false}, flipped)
+		for i := range original {
+			// Same slice header, not just the same points
+			require.Len(t, result[i].Points, len(original[i].Points))
+			assert.Same(t, &original[i].Points[0], &result[i].Points[0])
+		}
+	})
+
+	t.Run("all flipped", func(t *testing.T) {
+		result, flipped := original.NormalizeWinding(func(i int) bool { return i == 0 })
+		assert.Equal(t, []bool{true, true}, flipped)
+		assert.Equal(t, []bool{false, true}, result.Windings())
+		for i, poly := range result {
+			n := len(poly.Points)
+			require.Len(t, original[i].Points, n)
+			for j, p := range poly.Points {
+				assert.Same(t, original[i].Points[n-1-j], p)
+			}
+		}
+		// The original list is untouched
+		assert.Equal(t, []bool{true, false}, original.Windings())
+	})
+
+	t.Run("mixed", func(t *testing.T) {
+		list := MultiLayeredHoles()
+		windings := list.Windings()
+		// Demand that every ring be solid. Exactly the holes should flip.
+		result, flipped := list.NormalizeWinding(func(int) bool { return false })
+		for i := range list {
+			assert.Equal(t, !windings[i], flipped[i], "ring %d", i)
+			assert.True(t, IsCCW(&result[i]), "ring %d", i)
+			assert.ElementsMatch(t, list[i].Points, result[i].Points)
+		}
+	})
+}
+
+func TestVertexClassification(t *testing.T) {
+	// The star alternates between points and inner corners
+	for i, class := range SimpleStar()[0].VertexClassification() {
+		if i%2 == 0 {
+			assert.Equal(t, Convex, class, "vertex %d", i)
+		} else {
+			assert.Equal(t, Reflex, class, "vertex %d", i)
+		}
+	}
+
+	// The hole's corners are reflex with respect to the filled region
+	list := SquareWithHole()
+	classes := list.VertexClassification()
+	assert.Len(t, classes, 8)
+	for _, p := range list[0].Points {
+		assert.Equal(t, Convex, classes[p], "outer %v", p)
+	}
+	for _, p := range list[1].Points {
+		assert.Equal(t, Reflex, classes[p], "hole %v", p)
+	}
+
+	// Subdividing an edge gives collinear midpoints, even when they're off the
+	// line by less than Epsilon
+	subdivided := Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: Epsilon / 2}, {X: 3, Y: 0}, {X: 3, Y: 3}, {X: 0, Y: 3}}}
+	assert.Equal(t,
+		[]VertexClass{Convex, Collinear, Collinear, Convex, Convex, Convex},
+		subdivided.VertexClassification(),
+	)
+	// But not when they're further off
+	notch := Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 1e-3}, {X: 3, Y: 0}, {X: 3, Y: 3}, {X: 0, Y: 3}}}
+	assert.Equal(t, Reflex, notch.VertexClassification()[2])
+}
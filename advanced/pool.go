@@ -0,0 +1,163 @@
+package advanced
+
+import "sync"
+
+// A Triangulator triangulates polygons like PolygonList.TriangulateWithOptions,
+// but keeps its scratch memory between calls, so that a stream of similarly
+// sized inputs allocates less. The output never shares memory with the
+// Triangulator, so it stays valid after later calls.
+//
+// A Triangulator can also hold polygons of its own, added one at a time with
+// AddPolygon, which stay trapezoidized between calls to Triangulate. That is
+// separate from TriangulateList, which neither sees nor disturbs them.
+//
+// A Triangulator must not be used by more than one goroutine at a time. Use a
+// TriangulatorPool to share them between goroutines.
+type Triangulator struct {
+	buffers *buffers
+
+	// The polygons added with AddPolygon. This doesn't use the buffers, since
+	// its segments must outlive every other call.
+	graph *QueryGraph
+	// Once adding a polygon fails, the graph is unusable, and every later call
+	// on it returns this error
+	graphErr error
+}
+
+func NewTriangulator() *Triangulator {
+	return &Triangulator{buffers: newBuffers(0)}
+}
+
+// Triangulate the polygons with the given options. See Options for details.
+func (t *Triangulator) TriangulateList(list PolygonList, opts Options) (result TriangleList, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+	return list.triangulate(opts, t.buffers), nil
+}
+
+// The number of input vertices the Triangulator's scratch memory has grown to
+// hold
+func (t *Triangulator) Capacity() int {
+	return t.buffers.capacity()
+}
+
+// Add a polygon to the Triangulator's own polygons. As with Polygon, solids run
+// counterclockwise, and holes clockwise. The polygon must not intersect any
+// polygon already added.
+//
+// If this returns an error, the Triangulator's polygons are no longer usable
+// until Reset, although TriangulateList still works.
+func (t *Triangulator) AddPolygon(poly Polygon) (err error) {
+	if t.graphErr != nil {
+		return t.graphErr
+	}
+	defer func() {
+		err = HandleTriangulatePanicRecover(recover())
+		t.graphErr = err
+	}()
+
+	if len(poly.Points) < 3 {
+		fatalf("polygon needs at least 3 points, got %d", len(poly.Points))
+	}
+	if t.graph == nil {
+		t.graph = &QueryGraph{}
+	}
+	t.graph.AddPolygon(poly)
+	return nil
+}
+
+// Triangulate the polygons added with AddPolygon. Monotones are extracted from
+// a copy of the trapezoids, so the query graph survives, and this can be called
+// again, after adding more polygons or not.
+func (t *Triangulator) Triangulate() (result TriangleList, err error) {
+	if t.graphErr != nil {
+		return nil, t.graphErr
+	}
+	if t.graph == nil {
+		return nil, nil
+	}
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+	monotones := extractMonotones(t.graph.snapshotInsideTrapezoids(), Options{}, t.buffers)
+	return triangulateMonotones(monotones, Options{}, t.buffers, nil), nil
+}
+
+// Check whether the point is inside the polygons added with AddPolygon. Points
+// exactly on an edge may go either way. After AddPolygon has failed, this is
+// always false.
+func (t *Triangulator) ContainsPoint(p *Point) bool {
+	if t.graphErr != nil || t.graph == nil {
+		return false
+	}
+	return t.graph.ContainsPoint(p)
+}
+
+// Forget the polygons added with AddPolygon, and any error from adding them
+func (t *Triangulator) Reset() {
+	t.graph = nil
+	t.graphErr = nil
+}
+
+// A pool of Triangulators, which is safe for concurrent use. Triangulators
+// start out with room for InitialVertices, and once one has grown past
+// MaxRetainedVertices, it is dropped rather than returned to the pool, so that
+// a single huge input doesn't pin its memory forever.
+type TriangulatorPool struct {
+	initialVertices     int
+	maxRetainedVertices int
+	pool                sync.Pool
+}
+
+// Create a pool whose Triangulators start with room for initialVertices input
+// vertices, and are dropped once they grow past maxRetainedVertices. A
+// maxRetainedVertices of zero means there is no limit.
+func NewTriangulatorPool(initialVertices, maxRetainedVertices int) *TriangulatorPool {
+	p := &TriangulatorPool{
+		initialVertices:     initialVertices,
+		maxRetainedVertices: maxRetainedVertices,
+	}
+	p.pool.New = func() interface{} {
+		return &Triangulator{buffers: newBuffers(p.initialVertices)}
+	}
+	return p
+}
+
+// Take a Triangulator from the pool. It must be returned with Put once the
+// caller is done with it. Prefer Do, which can't forget.
+func (p *TriangulatorPool) Get() *Triangulator {
+	return p.pool.Get().(*Triangulator)
+}
+
+// Return a Triangulator to the pool. It must not be used afterwards.
+func (p *TriangulatorPool) Put(t *Triangulator) {
+	if p.maxRetainedVertices > 0 && t.Capacity() > p.maxRetainedVertices {
+		return
+	}
+	// The next caller shouldn't inherit this one's polygons
+	t.Reset()
+	p.pool.Put(t)
+}
+
+// Run f with a Triangulator from the pool, returning the Triangulator to the
+// pool afterwards, and returning f's error.
+func (p *TriangulatorPool) Do(f func(*Triangulator) error) error {
+	t := p.Get()
+	defer p.Put(t)
+	return f(t)
+}
+
+// Triangulate the polygons with a Triangulator from the pool. This is a
+// shorthand for Do, for when there is nothing else to do with it.
+func (p *TriangulatorPool) TriangulateList(list PolygonList, opts Options) (result TriangleList, err error) {
+	err = p.Do(func(t *Triangulator) (err error) {
+		result, err = t.TriangulateList(list, opts)
+		return err
+	})
+	return result, err
+}
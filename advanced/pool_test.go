@@ -0,0 +1,184 @@
+package advanced
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Triangles as coordinates, for comparing triangulations of separate copies
+// of the same input. The order of the output isn't deterministic, so these are
+// sorted.
+func triangleCoordinates(triangles TriangleList) []string {
+	result := make([]string, len(triangles))
+	for i, tri := range triangles {
+		result[i] = fmt.Sprintf("%v %v %v", *tri.A, *tri.B, *tri.C)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestTriangulator_MatchesTriangulate(t *testing.T) {
+	triangulator := NewTriangulator()
+	// In sorted order, so that the buffers are reused at a variety of sizes
+	var names []string
+	for name := range allFixtures() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fixture := allFixtures()[name]
+		expected := fixture().Triangulate()
+		actual, err := triangulator.TriangulateList(fixture(), Options{})
+		require.NoError(t, err, name)
+		assert.Equal(t, triangleCoordinates(expected), triangleCoordinates(actual), name)
+	}
+}
+
+func TestTriangulator_OutputOutlivesNextCall(t *testing.T) {
+	triangulator := NewTriangulator()
+	list := StarStripes()
+	first, err := triangulator.TriangulateList(list, Options{})
+	require.NoError(t, err)
+	before := triangleCoordinates(first)
+
+	_, err = triangulator.TriangulateList(MultiLayeredHoles(), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, before, triangleCoordinates(first))
+}
+
+func TestTriangulator_Error(t *testing.T) {
+	triangulator := NewTriangulator()
+	_, err := triangulator.TriangulateList(PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}}}}, Options{})
+	assert.Error(t, err)
+
+	// Still usable after a failure
+	result, err := triangulator.TriangulateList(SquareWithHole(), Options{})
+	require.NoError(t, err)
+	assert.Len(t, result, 8)
+}
+
+func TestTriangulatorPool_Do(t *testing.T) {
+	pool := NewTriangulatorPool(16, 0)
+	var used *Triangulator
+	err := pool.Do(func(triangulator *Triangulator) error {
+		used = triangulator
+		assert.GreaterOrEqual(t, triangulator.Capacity(), 16)
+		return errors.New("from f")
+	})
+	assert.EqualError(t, err, "from f")
+	assert.NotNil(t, used)
+}
+
+func TestTriangulatorPool_DropsOversized(t *testing.T) {
+	pool := NewTriangulatorPool(4, 100)
+	triangulator := pool.Get()
+	_, err := triangulator.TriangulateList(PolygonList{circlePolygon(10, 500)}, Options{})
+	require.NoError(t, err)
+	require.Greater(t, triangulator.Capacity(), 100)
+	pool.Put(triangulator)
+
+	// Whatever comes out now must be new, since the only one made was dropped
+	assert.NotSame(t, triangulator, pool.Get())
+}
+
+func TestTriangulatorPool_Concurrent(t *testing.T) {
+	fixtures := allFixtures()
+	var names []string
+	expected := make(map[string][]string)
+	for name, fixture := range fixtures {
+		names = append(names, name)
+		expected[name] = triangleCoordinates(fixture().Triangulate())
+	}
+
+	// Small enough that some of the larger fixtures get dropped
+	pool := NewTriangulatorPool(8, 64)
+	var wg sync.WaitGroup
+	results := make([][]string, 100)
+	errs := make([]error, 100)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			list := fixtures[names[i%len(names)]]()
+			var triangles TriangleList
+			triangles, errs[i] = pool.TriangulateList(list, Options{})
+			results[i] = triangleCoordinates(triangles)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		name := names[i%len(names)]
+		require.NoError(t, errs[i], name)
+		assert.Equal(t, expected[name], result, name)
+	}
+}
+
+func TestTriangulator_AddPolygon(t *testing.T) {
+	list := MultiLayeredHoles()
+	triangulator := NewTriangulator()
+	for _, poly := range list {
+		require.NoError(t, triangulator.AddPolygon(poly))
+	}
+
+	first, err := triangulator.Triangulate()
+	require.NoError(t, err)
+	validatePolygonsBySampling(t, first.ToPolygonList(), list)
+
+	// The graph survives, so this can be done again, and points still found
+	second, err := triangulator.Triangulate()
+	require.NoError(t, err)
+	assert.Equal(t, triangleCoordinates(first), triangleCoordinates(second))
+	for x := -11.0; x <= 11; x += 0.37 {
+		for y := -11.0; y <= 11; y += 0.41 {
+			p := &Point{X: x, Y: y}
+			assert.Equal(t, list.ContainsPointByEvenOdd(p), triangulator.ContainsPoint(p), "%v", p)
+		}
+	}
+}
+
+func TestTriangulator_AddPolygonIncrementally(t *testing.T) {
+	list := SquareWithHole()
+	triangulator := NewTriangulator()
+	require.NoError(t, triangulator.AddPolygon(list[0]))
+	solid, err := triangulator.Triangulate()
+	require.NoError(t, err)
+	assert.InDelta(t, Area(&list[0]), totalArea(solid), 1e-6)
+
+	require.NoError(t, triangulator.AddPolygon(list[1]))
+	withHole, err := triangulator.Triangulate()
+	require.NoError(t, err)
+	validatePolygonsBySampling(t, withHole.ToPolygonList(), list)
+}
+
+func TestTriangulator_AddPolygonError(t *testing.T) {
+	triangulator := NewTriangulator()
+	err := triangulator.AddPolygon(Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}}})
+	require.Error(t, err)
+	_, triangulateErr := triangulator.Triangulate()
+	assert.Equal(t, err, triangulateErr)
+	assert.False(t, triangulator.ContainsPoint(&Point{X: 0.5, Y: 0.5}))
+
+	triangulator.Reset()
+	require.NoError(t, triangulator.AddPolygon(SquareWithHole()[0]))
+	assert.True(t, triangulator.ContainsPoint(&Point{X: 0.5, Y: 0.5}))
+	assert.False(t, triangulator.ContainsPoint(&Point{X: 5.5, Y: 0.5}))
+}
+
+func TestTriangulatorPool_PutResets(t *testing.T) {
+	pool := NewTriangulatorPool(0, 0)
+	triangulator := pool.Get()
+	require.NoError(t, triangulator.AddPolygon(SquareWithHole()[0]))
+	pool.Put(triangulator)
+
+	result, err := triangulator.Triangulate()
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
@@ -0,0 +1,347 @@
+package advanced
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A pre-flight check for input shaped like the input which has caused the
+// triangulator trouble, for callers who would rather send such input to a
+// fallback than find out from an error. None of what it reports makes input
+// invalid, and most input it reports triangulates fine. It only means the
+// triangulator is leaning on its tolerance, or on the lexicographic
+// tie-breaking, which is where its bugs have been (see "The nasty bits" in the
+// README). Each scan is a linear pass, a sort, or the sweep Validate uses, so
+// the check costs less than triangulating.
+
+// The thresholds of the risks. A risk is reported when its measurement reaches
+// the threshold, in the direction given for its RiskKind.
+const (
+	// The fraction of vertices sharing their height with another vertex. This
+	// is also what decides whether a failed build is retried with another seed
+	// (see symmetric_retry.go).
+	riskRepeatedHeights = 0.5
+	// The largest coordinate magnitude. Past MaxCoordinate, rounding error can
+	// exceed Epsilon, and the input is rejected, so this leaves a margin.
+	riskCoordinate = MaxCoordinate / 2
+	// The distance between neighboring points of a ring, and from a point to
+	// the line through its neighbors. Decisions closer than this to Epsilon
+	// count as marginal in Confidence.
+	riskPointSpacing = marginalFactor * Epsilon
+	// The width of a ring, by twice its area over its perimeter, and the
+	// distance from a point to an edge which doesn't end at it. The near
+	// degenerate annulus in the corpus, whose rings are a thousand Epsilons
+	// apart, is the narrowest shape which has been hard on the numerics.
+	riskFeatureWidth = 10000 * Epsilon
+	// The distance between points of different rings which aren't the same
+	// point. The triangulator treats points this close as level and aligned.
+	riskCoincidentDistance = Epsilon
+)
+
+// Limit on the vertices listed in a RiskReport, so that a report on a grid of
+// a million vertices stays readable
+const maxRiskVertices = 16
+
+type RiskKind int
+
+const (
+	// Many vertices share their height with another vertex, as they do in
+	// grid aligned and symmetric shapes, leaving every tie to the
+	// lexicographic tie-breaking. Measures the fraction of vertices which do,
+	// reported at or above the threshold.
+	RiskRepeatedHeights RiskKind = iota
+	// Coordinates are so large that rounding error approaches Epsilon.
+	// Measures the largest magnitude, reported at or above the threshold, or
+	// when it isn't a number.
+	RiskLargeCoordinates
+	// Neighboring points of a ring are nearly the same point. Measures the
+	// smallest distance, reported below the threshold.
+	RiskNearDuplicatePoints
+	// A point is nearly on the line through its neighbors, between them.
+	// Measures the smallest distance to that line, reported below the
+	// threshold.
+	RiskCollinearRun
+	// A ring encloses nearly no area for its length. Measures the smallest
+	// width, by twice the area over the perimeter, reported below the
+	// threshold.
+	RiskNearZeroArea
+	// A point is close to an edge which doesn't end at it, as in thin annuli,
+	// slivers and sharp wedges, or edges cross. Measures the smallest distance,
+	// reported below the threshold.
+	RiskThinFeature
+	// Points of different rings are at the same coordinates, without being the
+	// same point. Measures the smallest distance, reported below the
+	// threshold.
+	RiskCoincidentPoints
+)
+
+func (kind RiskKind) String() string {
+	switch kind {
+	case RiskRepeatedHeights:
+		return "repeated heights"
+	case RiskLargeCoordinates:
+		return "large coordinates"
+	case RiskNearDuplicatePoints:
+		return "near duplicate points"
+	case RiskCollinearRun:
+		return "collinear run"
+	case RiskNearZeroArea:
+		return "near zero area"
+	case RiskThinFeature:
+		return "thin feature"
+	case RiskCoincidentPoints:
+		return "coincident points"
+	}
+	return "invalid"
+}
+
+// A vertex of the input, by the index of its polygon in the list, and its
+// index within the polygon
+type RiskVertex struct {
+	Polygon, Vertex int
+}
+
+type RiskReport struct {
+	Kind RiskKind
+	// The worst measurement of the risk anywhere in the input, and the
+	// threshold it reached
+	Value, Threshold float64
+	// The vertices involved, in input order, up to the first maxRiskVertices.
+	// For a ring with nearly no area, these are the ring's vertices, and for a
+	// thin feature, the ends of the edges which come close.
+	Vertices []RiskVertex
+	// The number of vertices involved, including those past the limit
+	Count int
+}
+
+func (report RiskReport) String() string {
+	vertices := make([]string, len(report.Vertices))
+	for i, v := range report.Vertices {
+		vertices[i] = fmt.Sprintf("%d:%d", v.Polygon, v.Vertex)
+	}
+	if report.Count > len(report.Vertices) {
+		vertices = append(vertices, fmt.Sprintf("and %d more", report.Count-len(report.Vertices)))
+	}
+	return fmt.Sprintf(
+		"%v: %g against a threshold of %g, at vertices %s",
+		report.Kind, report.Value, report.Threshold, strings.Join(vertices, " "),
+	)
+}
+
+// Scan the polygons for the patterns which have caused the triangulator
+// trouble, returning a report for each pattern found, in the order of the
+// RiskKinds. An empty result means none were found, not that triangulation
+// will succeed, since invalid input (see PolygonList.Validate) is only
+// reported as far as it matches a pattern.
+func PreflightCheck(polygons PolygonList) []RiskReport {
+	var reports []RiskReport
+	add := func(kind RiskKind, finding riskFinding, threshold float64) {
+		if len(finding.vertices) > 0 {
+			reports = append(reports, finding.report(kind, threshold))
+		}
+	}
+
+	var repeated riskFinding
+	if ratio, vertices := polygons.repeatedHeights(); ratio >= riskRepeatedHeights {
+		repeated = riskFinding{ratio, vertices}
+	}
+	add(RiskRepeatedHeights, repeated, riskRepeatedHeights)
+
+	var large riskFinding
+	duplicates, collinear, flat := newRiskFinding(), newRiskFinding(), newRiskFinding()
+	for i, poly := range polygons {
+		n := len(poly.Points)
+		perimeter := 0.0
+		for j, p := range poly.Points {
+			// Written to catch NaN as well
+			if magnitude := math.Max(math.Abs(p.X), math.Abs(p.Y)); !(magnitude < riskCoordinate) {
+				if !(magnitude <= large.value) {
+					large.value = magnitude
+				}
+				large.vertices = append(large.vertices, RiskVertex{i, j})
+			}
+			if n < 3 {
+				continue
+			}
+			prev, next := poly.Points[CircularIndex(j-1, n)], poly.Points[CircularIndex(j+1, n)]
+			spacing := math.Hypot(next.X-p.X, next.Y-p.Y)
+			perimeter += spacing
+			duplicates.below(spacing, riskPointSpacing, RiskVertex{i, j}, RiskVertex{i, CircularIndex(j+1, n)})
+			// A point nearly on top of a neighbor is nearly on any line through
+			// that neighbor, which is already reported
+			if math.Hypot(p.X-prev.X, p.Y-prev.Y) >= riskPointSpacing && spacing >= riskPointSpacing {
+				collinear.below(distanceToSegment(p, &Segment{Start: prev, End: next}), riskPointSpacing, RiskVertex{i, j})
+			}
+		}
+		if n >= 3 && perimeter > 0 {
+			ring := make([]RiskVertex, n)
+			for j := range ring {
+				ring[j] = RiskVertex{i, j}
+			}
+			flat.below(2*math.Abs(poly.SignedArea())/perimeter, riskFeatureWidth, ring...)
+		}
+	}
+	add(RiskLargeCoordinates, large, riskCoordinate)
+	add(RiskNearDuplicatePoints, duplicates, riskPointSpacing)
+	add(RiskCollinearRun, collinear, riskPointSpacing)
+	add(RiskNearZeroArea, flat, riskFeatureWidth)
+	add(RiskThinFeature, polygons.thinFeatures(), riskFeatureWidth)
+	add(RiskCoincidentPoints, polygons.coincidentPoints(), riskCoincidentDistance)
+	return reports
+}
+
+// The worst measurement of a risk, and the vertices where it was found
+type riskFinding struct {
+	value    float64
+	vertices []RiskVertex
+}
+
+// A finding for a risk reported below its threshold, which has found nothing
+// yet
+func newRiskFinding() riskFinding {
+	return riskFinding{value: math.Inf(1)}
+}
+
+// Note a measurement of a risk reported below its threshold, at the given
+// vertices
+func (f *riskFinding) below(value, threshold float64, vertices ...RiskVertex) {
+	if value < threshold {
+		f.value = math.Min(f.value, value)
+		f.vertices = append(f.vertices, vertices...)
+	}
+}
+
+// Report the finding, with its vertices in input order, once each
+func (f riskFinding) report(kind RiskKind, threshold float64) RiskReport {
+	vertices := f.vertices
+	sort.Slice(vertices, func(i, j int) bool {
+		a, b := vertices[i], vertices[j]
+		return a.Polygon < b.Polygon || (a.Polygon == b.Polygon && a.Vertex < b.Vertex)
+	})
+	unique := vertices[:1]
+	for _, v := range vertices[1:] {
+		if v != unique[len(unique)-1] {
+			unique = append(unique, v)
+		}
+	}
+	report := RiskReport{Kind: kind, Value: f.value, Threshold: threshold, Vertices: unique, Count: len(unique)}
+	if len(unique) > maxRiskVertices {
+		report.Vertices = unique[:maxRiskVertices:maxRiskVertices]
+	}
+	return report
+}
+
+// The fraction of the list's vertices which share their height, within
+// Epsilon, with another vertex, and those vertices, in input order. With fewer
+// than four vertices, the fraction is zero, since a triangle or two with a
+// level edge is nothing to worry about.
+func (list PolygonList) repeatedHeights() (ratio float64, vertices []RiskVertex) {
+	count := list.vertexCount()
+	if count < 4 {
+		return 0, nil
+	}
+	type height struct {
+		y float64
+		RiskVertex
+	}
+	heights := make([]height, 0, count)
+	for i, polygon := range list {
+		for j, p := range polygon.Points {
+			heights = append(heights, height{p.Y, RiskVertex{i, j}})
+		}
+	}
+	sort.Slice(heights, func(a, b int) bool {
+		return heights[a].y < heights[b].y
+	})
+	for i, h := range heights {
+		if (i > 0 && Equal(h.y, heights[i-1].y)) ||
+			(i+1 < len(heights) && Equal(h.y, heights[i+1].y)) {
+			vertices = append(vertices, h.RiskVertex)
+		}
+	}
+	sort.Slice(vertices, func(a, b int) bool {
+		return vertices[a].Polygon < vertices[b].Polygon ||
+			(vertices[a].Polygon == vertices[b].Polygon && vertices[a].Vertex < vertices[b].Vertex)
+	})
+	return float64(len(vertices)) / float64(count), vertices
+}
+
+// Find points within riskFeatureWidth of an edge which doesn't end at them, and
+// edges which cross, with the same sweep Validate uses to find edges within
+// Epsilon. An edge which two rings share, running opposite ways, is allowed to
+// overlap itself.
+func (list PolygonList) thinFeatures() riskFinding {
+	finding := newRiskFinding()
+	rings := make([][]*Point, 0, len(list))
+	for _, poly := range list {
+		rings = append(rings, poly.Points)
+	}
+	sharedEdge := func(a, b ringEdge) bool {
+		aRing, bRing := rings[a.ring], rings[b.ring]
+		return a.ring != b.ring &&
+			aRing[a.edge] == bRing[CircularIndex(b.edge+1, len(bRing))] &&
+			aRing[CircularIndex(a.edge+1, len(aRing))] == bRing[b.edge]
+	}
+	for _, pair := range sweepCrossingsWithin(rings, false, riskFeatureWidth, sharedEdge) {
+		var segments [2]*Segment
+		var ends []RiskVertex
+		for k, edge := range pair {
+			ring := rings[edge.ring]
+			next := CircularIndex(edge.edge+1, len(ring))
+			segments[k] = &Segment{Start: ring[edge.edge], End: ring[next]}
+			ends = append(ends, RiskVertex{edge.ring, edge.edge}, RiskVertex{edge.ring, next})
+		}
+		finding.below(segmentDistance(segments[0], segments[1]), riskFeatureWidth, ends...)
+	}
+	return finding
+}
+
+// The distance between two segments, other than at a point they share. Zero if
+// they cross.
+func segmentDistance(a, b *Segment) float64 {
+	if segmentsIntersect(a, b) {
+		return 0
+	}
+	distance := math.Inf(1)
+	for _, pair := range [][2]*Segment{{a, b}, {b, a}} {
+		s, other := pair[0], pair[1]
+		for _, p := range []*Point{other.Start, other.End} {
+			if p != s.Start && p != s.End {
+				distance = math.Min(distance, distanceToSegment(p, s))
+			}
+		}
+	}
+	return distance
+}
+
+// Find points of different rings within riskCoincidentDistance of each other,
+// which aren't the same point, by sorting them along X
+func (list PolygonList) coincidentPoints() riskFinding {
+	finding := newRiskFinding()
+	type located struct {
+		*Point
+		RiskVertex
+	}
+	var points []located
+	for i, poly := range list {
+		for j, p := range poly.Points {
+			points = append(points, located{p, RiskVertex{i, j}})
+		}
+	}
+	sort.Slice(points, func(a, b int) bool {
+		return points[a].X < points[b].X
+	})
+	for a, p := range points {
+		for _, q := range points[a+1:] {
+			if q.X-p.X >= riskCoincidentDistance {
+				break
+			}
+			if p.Polygon != q.Polygon && p.Point != q.Point {
+				finding.below(math.Hypot(q.X-p.X, q.Y-p.Y), riskCoincidentDistance, p.RiskVertex, q.RiskVertex)
+			}
+		}
+	}
+	return finding
+}
@@ -0,0 +1,135 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced/corpus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func riskKinds(reports []RiskReport) []RiskKind {
+	var kinds []RiskKind
+	for _, report := range reports {
+		kinds = append(kinds, report.Kind)
+	}
+	return kinds
+}
+
+func TestPreflightCheck_Corpus(t *testing.T) {
+	// Besides the grid aligned entries, which all repeat their heights, these
+	// are the entries known to be hard on the triangulator. Every other entry
+	// must come back clean.
+	known := map[string][]RiskKind{
+		"collinear subdivided":    {RiskRepeatedHeights, RiskCollinearRun},
+		"monotone diamond":        {RiskRepeatedHeights},
+		"near degenerate annulus": {RiskRepeatedHeights, RiskThinFeature},
+	}
+	clean := 0
+	for _, entry := range corpus.Entries() {
+		if entry.Huge {
+			continue
+		}
+		expected, ok := known[entry.Name]
+		if !ok && entry.GridAligned {
+			expected = []RiskKind{RiskRepeatedHeights}
+		}
+		if expected == nil {
+			clean++
+		}
+		assert.Equal(t, expected, riskKinds(PreflightCheck(corpusShape(entry))), entry.Name)
+	}
+	assert.GreaterOrEqual(t, clean, 5)
+}
+
+func TestPreflightCheck_Patterns(t *testing.T) {
+	// A quadrilateral with no two vertices level, translated
+	quad := func(x, y float64) Polygon {
+		return Polygon{[]*Point{{X: x, Y: y}, {X: x + 10, Y: y + 1}, {X: x + 11, Y: y + 11}, {X: x + 1, Y: y + 10}}}
+	}
+	assert.Empty(t, PreflightCheck(PolygonList{quad(0, 0)}))
+
+	t.Run("large coordinates", func(t *testing.T) {
+		far := quad(MaxCoordinate*0.6, 0.5)
+		reports := PreflightCheck(PolygonList{quad(0, 0), far})
+		require.Equal(t, []RiskKind{RiskLargeCoordinates}, riskKinds(reports))
+		assert.Equal(t, []RiskVertex{{1, 0}, {1, 1}, {1, 2}, {1, 3}}, reports[0].Vertices)
+		assert.Equal(t, far.Points[2].X, reports[0].Value)
+
+		nan := quad(0, 0)
+		nan.Points[2].Y = math.NaN()
+		reports = PreflightCheck(PolygonList{nan})
+		require.Contains(t, riskKinds(reports), RiskLargeCoordinates)
+		assert.Equal(t, []RiskVertex{{0, 2}}, reports[0].Vertices)
+	})
+
+	t.Run("near duplicate points", func(t *testing.T) {
+		// The duplicate also makes a wedge with the edge before it
+		poly := Polygon{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 1}, {X: 10, Y: 1 + 5e-7}, {X: 5, Y: 10}}}
+		reports := PreflightCheck(PolygonList{poly})
+		require.Equal(t, []RiskKind{RiskNearDuplicatePoints, RiskThinFeature}, riskKinds(reports))
+		assert.Equal(t, []RiskVertex{{0, 1}, {0, 2}}, reports[0].Vertices)
+		assert.InDelta(t, 5e-7, reports[0].Value, 1e-12)
+		assert.Equal(t, riskPointSpacing, reports[0].Threshold)
+	})
+
+	t.Run("collinear run", func(t *testing.T) {
+		poly := Polygon{[]*Point{{X: 0, Y: 0}, {X: 5, Y: 1}, {X: 10, Y: 2}, {X: 15, Y: 3}, {X: 4, Y: 9}}}
+		reports := PreflightCheck(PolygonList{poly})
+		require.Equal(t, []RiskKind{RiskCollinearRun}, riskKinds(reports))
+		assert.Equal(t, []RiskVertex{{0, 1}, {0, 2}}, reports[0].Vertices)
+	})
+
+	t.Run("near zero area", func(t *testing.T) {
+		// A sliver, whose middle point is also near the edge across from it
+		sliver := Polygon{[]*Point{{X: 0, Y: 20}, {X: 3, Y: 21}, {X: 6, Y: 22.0001}}}
+		reports := PreflightCheck(PolygonList{quad(0, 0), sliver})
+		require.Equal(t, []RiskKind{RiskNearZeroArea, RiskThinFeature}, riskKinds(reports))
+		assert.Equal(t, []RiskVertex{{1, 0}, {1, 1}, {1, 2}}, reports[0].Vertices)
+		assert.Less(t, reports[0].Value, riskFeatureWidth)
+	})
+
+	t.Run("thin wedge", func(t *testing.T) {
+		wedge := Polygon{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0.5}, {X: 10, Y: 5}, {X: 9, Y: 0.4501}}}
+		reports := PreflightCheck(PolygonList{wedge})
+		require.Equal(t, []RiskKind{RiskThinFeature}, riskKinds(reports))
+		assert.InDelta(t, 1e-4, reports[0].Value, 1e-6)
+		assert.Contains(t, reports[0].Vertices, RiskVertex{0, 3})
+	})
+
+	t.Run("coincident points", func(t *testing.T) {
+		// Two rings touching at a corner, without sharing the point there
+		first, second := quad(0, 0), quad(11, 11)
+		reports := PreflightCheck(PolygonList{first, second})
+		require.Equal(t, []RiskKind{RiskThinFeature, RiskCoincidentPoints}, riskKinds(reports))
+		assert.Equal(t, 0.0, reports[0].Value)
+		assert.Equal(t, []RiskVertex{{0, 2}, {1, 0}}, reports[1].Vertices)
+
+		// Sharing it is fine
+		second.Points[0] = first.Points[2]
+		assert.Empty(t, PreflightCheck(PolygonList{first, second}))
+	})
+
+	t.Run("shared edges", func(t *testing.T) {
+		// Neighboring regions of a map, sharing the points of their border
+		left := quad(0, 0)
+		right := Polygon{[]*Point{left.Points[1], {X: 20, Y: 2}, {X: 21, Y: 7}, {X: 19, Y: 13}, left.Points[2]}}
+		assert.Empty(t, PreflightCheck(PolygonList{left, right}))
+	})
+}
+
+func TestPreflightCheck_VertexLimit(t *testing.T) {
+	// Every vertex of a long collinear edge is reported, but only the first
+	// few are listed
+	poly := Polygon{[]*Point{{X: 0, Y: 50}}}
+	for i := 0; i <= 40; i++ {
+		poly.Points = append(poly.Points, &Point{X: float64(i), Y: 0.5 * float64(i)})
+	}
+	reports := PreflightCheck(PolygonList{poly})
+	require.Equal(t, []RiskKind{RiskCollinearRun}, riskKinds(reports))
+	assert.Equal(t, 39, reports[0].Count)
+	assert.Len(t, reports[0].Vertices, maxRiskVertices)
+	assert.Contains(t, reports[0].String(), "collinear run: 0 against a threshold of 1e-06, at vertices 0:2 0:3")
+	assert.Contains(t, reports[0].String(), "and 23 more")
+}
@@ -1,9 +1,13 @@
 package advanced
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
 	"fmt"
+	"hash"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -35,6 +39,72 @@ var DefaultDirection = Direction{X: Left, Y: Down}
 
 type QueryGraph struct {
 	Root *QueryNode
+	// When set, the graph checks its invariants between each phase of adding a
+	// segment, and fails with a description of the violation. This is expensive,
+	// and is intended for debugging.
+	CheckInvariants bool
+	// If positive, after every InvariantInterval-th segment is added, the
+	// trapezoids it left behind, and their neighbors, are checked for the
+	// invariants of the neighbor graph, failing with the segment's index and
+	// the invariant it broke. 1 checks after every segment. Unlike
+	// CheckInvariants, this only looks near the segment, so it's cheap enough to
+	// leave on for real inputs. See checkTouchedTrapezoids.
+	InvariantInterval int
+	// The seed for the pseudorandom order AddPolygon and AddPolygons add
+	// segments in, unless nondeterministic order is asked for
+	Seed int64
+	// If non-nil, this is told about each change to the trapezoid map as
+	// segments are added. See Tracer.
+	Tracer Tracer
+
+	// Cached bounding box for point queries. See querygraph_bounds.go.
+	bounds     *graphBounds
+	boundsLock sync.Mutex
+
+	// Scratch memory for adding polygons, if any. See buffers.
+	buffers *buffers
+
+	// Where each segment added from a polygon came from, for error messages,
+	// and which of them stand for an edge two rings share. These are kept here,
+	// rather than on the segments, so that a Segment is just its two points.
+	sources map[*Segment]segmentSource
+	shared  map[*Segment]bool
+
+	// Depth of the queries made while building the graph. See QueryDepth.
+	buildQueries    int
+	buildDepthTotal int
+	buildDepthMax   int
+
+	// The marginal decisions made while building the graph. See Confidence.
+	margins marginTracker
+
+	// How far each endpoint is from having as many segments leaving it as
+	// arriving, and the number of endpoints where it isn't zero. See
+	// querygraph_rings.go.
+	balance    map[*Point]int
+	unbalanced int
+
+	// If non-nil, each segment's endpoints are written here as it's added. See
+	// StageHashes.SegmentOrder.
+	orderHash hash.Hash64
+
+	// Add segments without Seidel's phases, searching from the root each time,
+	// for comparison. See querygraph_phases.go.
+	unphased bool
+
+	// The source of randomness for the order segments are added in, given the
+	// seed, or nil for rand.NewSource. Tests set this to force a particular
+	// order.
+	shuffleSource func(seed int64) rand.Source
+
+	// The number of segments added so far, and, while adding one which
+	// InvariantInterval says to check, the trapezoids it has left behind
+	segmentsAdded int
+	touched       []*Trapezoid
+	trackTouched  bool
+
+	// The segment being added, or nil between segments. See describeAdding.
+	adding *Segment
 }
 
 // A graph iterator lets you loop over the nodes in a graph exactly once.
@@ -50,11 +120,21 @@ func IterateGraph(root *QueryNode) chan *QueryNode {
 	return iter.MakeChan()
 }
 
+// Iterate over the trapezoids in a graph, visiting each trapezoid exactly once.
+// This deduplicates by trapezoid, not by node, so a trapezoid is still only
+// visited once if several sink nodes refer to it. Anything which collects
+// trapezoids from a graph should go through here rather than picking sinks out
+// of IterateGraph, so that nothing is counted twice.
 func IterateTrapezoids(root *QueryNode) chan *Trapezoid {
 	ch := make(chan *Trapezoid)
 	go func() {
+		seen := make(map[*Trapezoid]struct{})
 		for node := range IterateGraph(root) {
 			if sink, ok := node.Inner.(SinkNode); ok {
+				if _, ok := seen[sink.Trapezoid]; ok {
+					continue
+				}
+				seen[sink.Trapezoid] = struct{}{}
 				ch <- sink.Trapezoid
 			}
 		}
@@ -86,27 +166,37 @@ func (iter *GraphIterator) MakeChan() chan *QueryNode {
 }
 
 func (iter *GraphIterator) Next() *QueryNode {
-	if len(iter.stack) == 0 {
-		return nil
-	}
-	node := iter.stack[len(iter.stack)-1]
-	iter.stack = iter.stack[:len(iter.stack)-1]
-	// Skip if we've seen the node before
-	if _, ok := iter.seen[node]; ok {
-		return iter.Next()
+	// Loop rather than recursing past nodes which were already seen, since a
+	// large graph can have long runs of them
+	for len(iter.stack) > 0 {
+		node := iter.stack[len(iter.stack)-1]
+		iter.stack = iter.stack[:len(iter.stack)-1]
+		if _, ok := iter.seen[node]; ok {
+			continue
+		}
+		iter.seen[node] = struct{}{}
+
+		// Push the children onto the stack, without ChildNodes allocating a slice
+		// for them
+		switch inner := node.Inner.(type) {
+		case XNode:
+			iter.stack = append(iter.stack, inner.Left, inner.Right)
+		case YNode:
+			iter.stack = append(iter.stack, inner.Above, inner.Below)
+		}
+		return node
 	}
-
-	iter.seen[node] = struct{}{}
-
-	// Push the children onto the stack
-	iter.stack = append(iter.stack, node.ChildNodes()...)
-
-	return node
+	return nil
 }
 
 // Create a new graph from a single segment, and return the root node.
+//
+// A horizontal segment needs no special treatment. Its Top and Bottom are its
+// right and left ends, by the lexicographic rotation, so the left and right
+// trapezoids have zero height, and only hold points level with the segment,
+// between its ends. Everything above or below it is in the top or bottom
+// trapezoid, as for any other segment.
 func NewQueryGraph(segment *Segment) *QueryGraph {
-
 	a := segment.Top()
 	b := segment.Bottom()
 
@@ -182,9 +272,11 @@ func NewQueryGraph(segment *Segment) *QueryGraph {
 		}},
 	}}
 
-	// Backlink all the trapezoid sinks to their initial parents
-
-	for node := range IterateGraph(graph) {
+	// Backlink all the trapezoid sinks to their initial parents. This modifies
+	// nodes as it goes, so it iterates synchronously rather than through a
+	// channel, where the iterator would read them from another goroutine.
+	iter := NewGraphIterator(graph)
+	for node := iter.Next(); node != nil; node = iter.Next() {
 		for _, child := range node.ChildNodes() {
 			if sink, ok := child.Inner.(SinkNode); ok {
 				sink.InitialParent = node
@@ -193,92 +285,183 @@ func NewQueryGraph(segment *Segment) *QueryGraph {
 		}
 	}
 
-	return &QueryGraph{Root: graph}
+	result := &QueryGraph{Root: graph}
+	result.countRingSegment(segment, 1)
+	return result
 }
 
 func (graph *QueryGraph) PrintAllTrapezoids() {
 	var parts []string
-	for node := range IterateGraph(graph.Root) {
-		if node, ok := node.Inner.(SinkNode); ok {
-			parts = append(parts, node.Trapezoid.String())
-		}
+	for trapezoid := range graph.IterateTrapezoids() {
+		parts = append(parts, trapezoid.String())
 	}
 	fmt.Println(strings.Join(parts, "\n"))
 }
 
-func (graph *QueryGraph) FindPoint(dp DirectionalPoint) *QueryNode {
+// Find the sink whose trapezoid contains the point. The search starts from the
+// root, or from start if given, which must be a node the search passes
+// through, such as a sink found for the same point before more segments were
+// added.
+func (graph *QueryGraph) FindPoint(dp DirectionalPoint, start ...*QueryNode) *QueryNode {
+	if len(start) > 0 && start[0] != nil {
+		return start[0].FindPoint(dp)
+	}
 	return graph.Root.FindPoint(dp)
 }
 
+// Add a segment to the graph. This happens in three phases:
+//
+// 1. Locate each endpoint of the segment, splitting the containing trapezoid
+// horizontally if the endpoint is not already in the graph.
+// 2. Walk the chain of trapezoids crossed by the segment from bottom to top,
+// splitting each into a left and right trapezoid.
+// 3. Merge consecutive trapezoids in each chain which share both sides, and
+// rewire the sinks of the split trapezoids to XNodes.
+//
+// If CheckInvariants is set on the graph, the result of each phase is checked
+// before moving on to the next. The first segment added to an empty graph
+// creates it, as NewQueryGraph does.
 func (graph *QueryGraph) AddSegment(segment *Segment) {
 	if segment == nil {
 		fatalf("nil segment")
 	}
+	if graph.Root == nil {
+		graph.addSegments([]*Segment{segment})
+		return
+	}
+	defer wrapPanic(graph.describeAdding)
+	graph.addSegment(segment, [2]searchStart{})
+}
+
+// Add a segment to a graph which has a root, starting the searches for its top
+// and bottom from the given starts. See querygraph_phases.go. Callers defer
+// wrapPanic with describeAdding, once for all the segments they add.
+func (graph *QueryGraph) addSegment(segment *Segment, starts [2]searchStart) {
+	graph.adding = segment
+	if graph.Tracer != nil {
+		graph.Tracer.SegmentAdded(segment)
+	}
+	graph.invalidateBounds()
+	graph.countRingSegment(segment, 1)
+	index := graph.segmentsAdded
+	graph.segmentsAdded++
+	graph.trackTouched = graph.dueForInvariantCheck(index)
+	graph.touched = graph.touched[:0]
 
 	top := segment.Top()
 	bottom := segment.Bottom()
 
-	// Find the node that contains the top point, coming from the bottom
-	node := graph.FindPoint(top.PointingAt(bottom))
+	graph.locateAndSplitEndpoint(top, bottom, starts[0])
+	// We want the trapezoid above the bottom point, since the segment crosses
+	// that. Note at this point that `top` sits exactly on top of the top
+	// trapezoid, and `bottom` sits exactly on the bottom of the bottom trapezoid.
+	bottomTrapezoid := graph.locateAndSplitEndpoint(bottom, top, starts[1])
 
-	var topTrapezoid = node.Inner.(SinkNode).Trapezoid
+	leftChain, rightChain := graph.splitChainAlongSegment(segment, bottomTrapezoid)
+	graph.mergeChains(segment, leftChain, rightChain)
+	if graph.trackTouched {
+		graph.checkTouchedTrapezoids(index, segment, graph.touched)
+	}
+	graph.adding = nil
+}
 
-	// Check if the top point is already in the graph. If so, no horizontal split is needed
-	if !topTrapezoid.HasPoint(top) {
-		graph.SplitTrapezoidHorizontally(node, top)
+// Context for an error while adding segments: the segment being added, and
+// its index in the order they were added. Between segments, there's none.
+func (graph *QueryGraph) describeAdding() string {
+	if graph.adding == nil {
+		return ""
 	}
+	return fmt.Sprintf("while processing %s, number %d in insertion order", graph.describe(graph.adding), graph.segmentsAdded-1)
+}
 
-	// Do the same process for the bottom point
-	node = graph.FindPoint(bottom.PointingAt(top))
-	var bottomTrapezoid = node.Inner.(SinkNode).Trapezoid
+// Should the segment with the given index be checked once it's in? See
+// InvariantInterval.
+func (graph *QueryGraph) dueForInvariantCheck(index int) bool {
+	return graph.InvariantInterval > 0 && (index+1)%graph.InvariantInterval == 0
+}
+
+// Find the trapezoid containing the endpoint of a segment, coming from the
+// direction of the other endpoint. If the endpoint is not already in the graph,
+// the trapezoid is split horizontally at the endpoint. The trapezoid on the
+// same side of the endpoint as the other endpoint is returned. The search
+// starts from start.
+func (graph *QueryGraph) locateAndSplitEndpoint(endpoint, other *Point, start searchStart) *Trapezoid {
+	node, depth := graph.findPointFrom(endpoint.PointingAt(other), start)
+	graph.recordQueryDepth(depth)
+	trapezoid := node.Inner.(SinkNode).Trapezoid
+
+	// Check if the point is already in the graph. If so, no horizontal split is
+	// needed
+	if !trapezoid.HasPoint(endpoint) {
+		graph.SplitTrapezoidHorizontally(node, endpoint)
+		ynode := node.Inner.(YNode)
+		away := ynode.Above
+		if other.below(endpoint, &graph.margins) {
+			trapezoid = ynode.Below.Inner.(SinkNode).Trapezoid
+		} else {
+			trapezoid = ynode.Above.Inner.(SinkNode).Trapezoid
+			away = ynode.Below
+		}
+		// The half the segment crosses is split again, but the other half is
+		// left as it is
+		if graph.trackTouched {
+			graph.touched = append(graph.touched, away.Inner.(SinkNode).Trapezoid)
+		}
+	}
 
-	// Same check
-	if !bottomTrapezoid.HasPoint(bottom) {
-		graph.SplitTrapezoidHorizontally(node, bottom)
-		// We now want the top sink trapezoid, since the line segment crosses that.
-		bottomTrapezoid = node.Inner.(YNode).Above.Inner.(SinkNode).Trapezoid
+	if graph.CheckInvariants {
+		checkLocatedEndpoint(endpoint, trapezoid)
 	}
+	return trapezoid
+}
 
-	// Split the trapezoids that intersect the line segment. Note at this point
-	// that `top` sits exactly on top of the top trapezoid, and `bottom` sits
-	// exactly on the bottom of the bottom trapezoid.
+// Split the trapezoids that the segment crosses, starting from the bottom
+// trapezoid, and working up to the segment's top point. The result is a chain
+// of trapezoids to the left of the segment and a chain to the right, both
+// ordered from bottom to top. These are not yet in the query graph, and their
+// sinks still point at the original trapezoids' sink nodes.
+func (graph *QueryGraph) splitChainAlongSegment(segment *Segment, bottomTrapezoid *Trapezoid) (leftChain, rightChain []*Trapezoid) {
+	top := segment.Top()
 	curTrapezoid := bottomTrapezoid
 
-	var leftTrapezoids []*Trapezoid
-	var rightTrapezoids []*Trapezoid
-
 	for { // Loop over the trapezoids
+		// If the segment leaves this trapezoid through a side, rather than the top,
+		// the walk would carry on through trapezoids it doesn't cross, and either
+		// fail somewhere unrelated, or never end
+		for _, side := range [2]*Segment{curTrapezoid.Left, curTrapezoid.Right} {
+			if side != nil && segmentsCross(side, segment) {
+				fatalf(
+					"segment (%v, %v)-(%v, %v) intersects existing segment (%v, %v)-(%v, %v)",
+					segment.Start.X, segment.Start.Y, segment.End.X, segment.End.Y,
+					side.Start.X, side.Start.Y, side.End.X, side.End.Y,
+				)
+			}
+		}
+
 		// Split this trapezoid horizontally
 		nextNeighbors := curTrapezoid.TrapezoidsAbove // save these off for next traversal step
 		leftTrapezoid, rightTrapezoid := curTrapezoid.SplitBySegment(segment)
-		leftTrapezoids = append(leftTrapezoids, leftTrapezoid)
-		rightTrapezoids = append(rightTrapezoids, rightTrapezoid)
+		if graph.Tracer != nil {
+			graph.Tracer.TrapezoidSplitBySegment(curTrapezoid, segment, leftTrapezoid, rightTrapezoid)
+		}
+		leftChain = append(leftChain, leftTrapezoid)
+		rightChain = append(rightChain, rightTrapezoid)
+
+		// The segment ends at the top of this trapezoid. When its top is where two
+		// neighbors above meet, neither of their bottoms is crossed, so this has to
+		// be caught before choosing between them.
+		if curTrapezoid.Top == top {
+			break
+		}
 
 		// Find the next trapezoid out of the up to two neighbors above this one. It
 		// will be the one whose bottom the line segment intersects
 
-		curTrapezoid = nil
 		// First check for single neighbor case
-		var neighborCount int
-		for _, neighbor := range nextNeighbors {
-			if neighbor != nil {
-				neighborCount++
-			}
-		}
-
-		if neighborCount == 1 {
+		if nextNeighbors.Count() == 1 {
 			curTrapezoid = nextNeighbors.AnyNeighbor()
 		} else {
-			for _, neighbor := range nextNeighbors {
-				if neighbor != nil && neighbor.BottomIntersectsSegment(segment) {
-					curTrapezoid = neighbor
-					break
-				}
-			}
-		}
-
-		if curTrapezoid == nil {
-			break
+			curTrapezoid = graph.upperNeighborAlong(segment, nextNeighbors)
 		}
 
 		// We'll stop once we get to the trapezoid that our segment top is the
@@ -286,14 +469,135 @@ func (graph *QueryGraph) AddSegment(segment *Segment) {
 		if top == curTrapezoid.Bottom {
 			break
 		}
+
+		// Each step should be one trapezoid higher, without passing the top. A walk
+		// which doesn't rise, as when crossings nearer than Epsilon have corrupted
+		// the neighbors, would never end.
+		lastBottom := leftChain[len(leftChain)-1].Bottom
+		if top.Below(curTrapezoid.Bottom) || !lastBottom.Below(curTrapezoid.Bottom) {
+			fatalf("walk along %s passed its top", graph.describe(segment))
+		}
+	}
+
+	if graph.CheckInvariants {
+		checkSplitChains(segment, leftChain, rightChain)
+	}
+	return leftChain, rightChain
+}
+
+// Check whether two segments cross properly, each passing from one side of the
+// other to the other side. Ends within Epsilon of the other segment's line
+// don't count as being on either side, so segments which only touch, or share
+// an end, never cross.
+func segmentsCross(a, b *Segment) bool {
+	// The distance from the line is the cross product over the segment's
+	// length, so it's compared squared, saving a square root
+	side := func(s *Segment, p *Point) int {
+		cross := orientation(s.Start, s.End, p)
+		dx, dy := s.End.X-s.Start.X, s.End.Y-s.Start.Y
+		if float64(cross*cross) <= Epsilon*Epsilon*(float64(dx*dx)+float64(dy*dy)) {
+			return 0
+		} else if cross > 0 {
+			return 1
+		}
+		return -1
+	}
+	return side(a, b.Start)*side(a, b.End) < 0 && side(b, a.Start)*side(b, a.End) < 0
+}
+
+// Choose which of the trapezoids above the last one in a walk along the
+// segment it carries on into, when there's more than one. That's the one whose
+// bottom the segment crosses, if exactly one of the tests says so.
+//
+// The side tests in those are tolerance based, so when the segment passes
+// within Epsilon of the point where two neighbors meet, they can both pass,
+// or both fail. Picking either arbitrarily would corrupt the chain, so the
+// side of the point the segment passes is decided exactly instead. If that
+// can't settle it, the map is broken, and carrying on would only fail later
+// somewhere unrelated.
+func (graph *QueryGraph) upperNeighborAlong(segment *Segment, neighbors TrapezoidNeighborList) *Trapezoid {
+	var crossed *Trapezoid
+	crossings := 0
+	for _, neighbor := range neighbors {
+		if neighbor != nil && neighbor.bottomIntersectsSegment(segment, &graph.margins) {
+			crossed = neighbor
+			crossings++
+		}
+	}
+	if crossings == 1 {
+		return crossed
+	}
+	if neighbors.Count() == 2 {
+		if next := upperNeighborBeside(segment, neighbors, &graph.margins); next != nil {
+			return next
+		}
 	}
 
-	// We now have left and right chains of triangles that were split by the line
-	// segment, but some of them may share edges, so we need to merge them. All of
-	// the left trapezoids have the segment as a right edge and vice versa, so we
-	// can treat each chain of trapezoids separately
+	var described []string
+	for _, neighbor := range neighbors {
+		if neighbor != nil {
+			described = append(described, fmt.Sprintf(
+				"bottom %v between %s and %s", neighbor.Bottom, graph.describe(neighbor.Left), graph.describe(neighbor.Right),
+			))
+		}
+	}
+	fatalf(
+		"walk along %s crosses the bottoms of %d of the %d trapezoids above, and can't be settled exactly: %s",
+		graph.describe(segment), crossings, neighbors.Count(), strings.Join(described, "; "),
+	)
+	return nil
+}
 
-	for i, chain := range [2][]*Trapezoid{leftTrapezoids, rightTrapezoids} {
+// Choose between two upper neighbors by which side of the point where they
+// meet the segment passes, settling it exactly when it's close. That's needed
+// when the segment passes within Epsilon of the point, and also when it meets
+// the neighbors' shared bottom exactly on one of their sides, because it
+// starts level with the point, so the side tests are both too close to call.
+// Lexicographically, the segment still crosses that level to one side of the
+// point, which is the bottom of the segment dividing the neighbors. Exact
+// decisions are counted in margins.
+func upperNeighborBeside(segment *Segment, neighbors TrapezoidNeighborList, margins *marginTracker) *Trapezoid {
+	var bottom *Point
+	for _, neighbor := range neighbors {
+		if neighbor != nil {
+			bottom = neighbor.Bottom
+		}
+	}
+	if bottom == nil || segment.IsHorizontal() {
+		return nil
+	}
+	x := segment.SolveForX(bottom.Y)
+	passesLeft := x < bottom.X
+	if margins.isMarginal(bottom.X-x, segment.solveForXErrorBound(bottom, x)) {
+		passesLeft = margins.exactDecision(exactPassesLeft, segment.Start.X, segment.Start.Y, segment.End.X, segment.End.Y, bottom.X, bottom.Y)
+	}
+
+	for _, neighbor := range neighbors {
+		if neighbor == nil {
+			continue
+		}
+		divider := neighbor.Right
+		if !passesLeft {
+			divider = neighbor.Left
+		}
+		if divider != nil && divider.Bottom() == bottom {
+			return neighbor
+		}
+	}
+	return nil
+}
+
+// We now have left and right chains of trapezoids that were split by the line
+// segment, but some of them may share edges, so we need to merge them. All of
+// the left trapezoids have the segment as a right edge and vice versa, so we
+// can treat each chain of trapezoids separately. Once merged, the sinks of the
+// original trapezoids are replaced with XNodes pointing at the merged sinks.
+func (graph *QueryGraph) mergeChains(segment *Segment, leftChain, rightChain []*Trapezoid) {
+	// Bookkeeping for the invariant checks
+	var splitNodes []*QueryNode
+	var mergedTrapezoids []*Trapezoid
+
+	for i, chain := range [2][]*Trapezoid{leftChain, rightChain} {
 		side := XDirection(i)
 		// Divide the chain into chunks of connected trapezoids. Trapezoids can only
 		// be merged if they're consecutive in the chain
@@ -338,6 +642,9 @@ func (graph *QueryGraph) AddSegment(segment *Segment) {
 					}
 					neighbor.TrapezoidsAbove.ReplaceOrAdd(bottomTrapezoid, mergedTrapezoid)
 				}
+				if graph.Tracer != nil {
+					graph.Tracer.TrapezoidsMerged(segment, chunk, mergedTrapezoid)
+				}
 			}
 
 			// Note that we can't set an initial parent on the new sink, because
@@ -363,11 +670,24 @@ func (graph *QueryGraph) AddSegment(segment *Segment) {
 				}
 				// Update the node
 				node.Inner = xnode
+				if graph.CheckInvariants && side == Left {
+					splitNodes = append(splitNodes, node)
+				}
 			}
 
 			mergedTrapezoid.Sink = sink
+			if graph.trackTouched {
+				graph.touched = append(graph.touched, mergedTrapezoid)
+			}
+			if graph.CheckInvariants {
+				mergedTrapezoids = append(mergedTrapezoids, mergedTrapezoid)
+			}
 		}
 	}
+
+	if graph.CheckInvariants {
+		checkMergedChains(segment, splitNodes, mergedTrapezoids)
+	}
 }
 
 // Split a trapezoid horizontally, and replace its sink with a y node. node.Inner must be a sink
@@ -380,7 +700,8 @@ func (graph *QueryGraph) SplitTrapezoidHorizontally(node *QueryNode, point *Poin
 	if origTop != nil && origTop.Below(point) {
 		fatalf("cannot split on point above top")
 	}
-	if origBottom != nil && origBottom.Above(point) {
+	// Splitting on the bottom itself would leave a trapezoid with no height
+	if origBottom != nil && !origBottom.Below(point) {
 		fatalf("cannot split on point below bottom")
 	}
 
@@ -418,65 +739,215 @@ func (graph *QueryGraph) SplitTrapezoidHorizontally(node *QueryNode, point *Poin
 		Above: top.Sink,
 		Below: bottom.Sink,
 	}
+	if graph.Tracer != nil {
+		graph.Tracer.TrapezoidSplitHorizontally(sink.Trapezoid, point, top, bottom)
+	}
 }
 
 // Add a polygon to the graph. If the polygon winds clockwise, this will end up
 // producing a hole. Otherwise, it will be filled. The polygon must not
 // intersect any existing segments in the graph.
 //
-// By default, this process is pseudorandom, but deterministic. This is because
-// predictable results are easier to debug. However, this raises the potential
-// for adversarial inputs. If you are using untrusted input, you should pass
-// "true" for proper randomization.
+// By default, this process is pseudorandom, but deterministic, using the
+// graph's Seed. This is because predictable results are easier to debug.
+// However, this raises the potential for adversarial inputs. If you are using
+// untrusted input, you should pass "true" to seed the order from crypto/rand.
+// Errors name the seed used, so a failure can be reproduced with
+// AddPolygonWithSeed.
 func (graph *QueryGraph) AddPolygon(poly Polygon, nondeterministic ...bool) {
-	var seed int64
-
+	seed := graph.Seed
 	if len(nondeterministic) > 0 && nondeterministic[0] {
-		// TODO: We should make an adapter for crypto/random, and secure random
-		// numbers when nondeterministic mode is selected. Low priority, as it would
-		// be quite difficult to construct an input on the fly that would cause
-		// pathological performance based on a time based seed.
-		seed = time.Now().UnixNano()
+		seed = randomSeed()
+		defer wrapPanic(func() string {
+			return fmt.Sprintf("with seed %d", seed)
+		})
+	}
+	graph.addPolygon(poly, -1, seed, nil)
+}
+
+// Add a polygon like AddPolygon, with its segments in the order given by the
+// seed, rather than the graph's Seed
+func (graph *QueryGraph) AddPolygonWithSeed(poly Polygon, seed int64) {
+	graph.addPolygon(poly, -1, seed, nil)
+}
+
+// A seed which can't be predicted, for AddPolygon's nondeterministic mode
+func randomSeed() int64 {
+	var buf [8]byte
+	if _, err := cryptorand.Read(buf[:]); err != nil {
+		// crypto/rand only fails when the system has no source of randomness at
+		// all, and the time is still hard to guess from outside
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:]))
+}
+
+// Add a polygon, stamping its segments with its index in the input, which is
+// -1 if unknown, and shuffling them with the seed. Edges shared with other
+// polygons in the input are added as the shared edges say, which may be nil
+// when there are none.
+func (graph *QueryGraph) addPolygon(poly Polygon, polygonIndex int, seed int64, shared *sharedEdges) {
+	segments := graph.buffers.segmentList(len(poly.Points))
+	segments = graph.appendPolygonSegments(segments, poly, polygonIndex, shared)
+	graph.addShuffled(segments, seed)
+}
+
+// Append the segments of a polygon to the list, as addPolygon describes
+func (graph *QueryGraph) appendPolygonSegments(segments []*Segment, poly Polygon, polygonIndex int, shared *sharedEdges) []*Segment {
+	for i := range poly.Points {
+		role := shared.role(segmentSource{polygonIndex, i})
+		if role == skippedEdge {
+			continue
+		}
+		segment := graph.newSourceSegment(poly, polygonIndex, i)
+		if role == sharedEdge {
+			if graph.shared == nil {
+				graph.shared = make(map[*Segment]bool)
+			}
+			graph.shared[segment] = true
+		}
+		segments = append(segments, segment)
 	}
-	source := rand.NewSource(seed)
-	r := rand.New(source)
-	// Create the segments
-	segments := make([]*Segment, 0, len(poly.Points))
-	for i := 0; i < len(poly.Points); i++ {
-		segments = append(segments, &Segment{poly.Points[i], poly.Points[(i+1)%len(poly.Points)]})
+	return segments
+}
+
+// Create a segment for an edge of an input polygon, remembering where it came
+// from
+func (graph *QueryGraph) newSourceSegment(poly Polygon, polygonIndex, edgeIndex int) *Segment {
+	segment := graph.buffers.newSegment(poly.Points[edgeIndex], poly.Points[CircularIndex(edgeIndex+1, len(poly.Points))])
+	if graph.sources == nil {
+		graph.sources = graph.buffers.sourceTable()
 	}
+	graph.sources[segment] = segmentSource{polygonIndex, edgeIndex}
+	return segment
+}
 
-	// Shuffle the segments. This is what gives us expected O(nlogn) time
+// Where a segment came from in the input, if it was added from a polygon. This
+// is safe to call on a nil graph.
+func (graph *QueryGraph) sourceOf(segment *Segment) (segmentSource, bool) {
+	if graph == nil {
+		return segmentSource{}, false
+	}
+	source, ok := graph.sources[segment]
+	return source, ok
+}
+
+// Whether a segment stands for an edge two rings share, with the inside on
+// both sides of it. This is safe to call on a nil graph.
+func (graph *QueryGraph) isShared(segment *Segment) bool {
+	return graph != nil && graph.shared[segment]
+}
+
+// Describe a segment along with where it came from in the input, if known.
+// This is safe to call on a nil graph.
+func (graph *QueryGraph) describe(segment *Segment) string {
+	source, ok := graph.sourceOf(segment)
+	switch {
+	case segment == nil:
+		return "missing segment"
+	case !ok:
+		return fmt.Sprintf("segment from %v to %v", segment.Start, segment.End)
+	case source.polygon < 0:
+		return fmt.Sprintf("segment #%d (from %v to %v)", source.edge, segment.Start, segment.End)
+	}
+	return fmt.Sprintf("segment #%d of polygon #%d (from %v to %v)", source.edge, source.polygon, segment.Start, segment.End)
+}
+
+// Shuffle the segments with the seed, and add them. Shuffling is what gives
+// us expected O(nlog*n) time, with the phases which find new search roots for
+// every point (see querygraph_phases.go).
+func (graph *QueryGraph) addShuffled(segments []*Segment, seed int64) {
+	newSource := graph.shuffleSource
+	if newSource == nil {
+		newSource = rand.NewSource
+	}
+	r := rand.New(newSource(seed))
 	r.Shuffle(len(segments), func(i, j int) {
 		segments[i], segments[j] = segments[j], segments[i]
 	})
+	graph.addSegments(segments)
+}
 
-	// If this is an empty graph, initialize with the first segment
-	if graph.Root == nil {
+// Add segments in a deterministic random order. Shuffling is what gives us
+// expected O(nlogn) time.
+func (graph *QueryGraph) addShuffledSegments(segments []*Segment) {
+	graph.addShuffled(append([]*Segment(nil), segments...), 0)
+}
+
+// Add segments in the order given, initializing the graph with the first one
+// if it is empty.
+func (graph *QueryGraph) addSegments(segments []*Segment) {
+	defer wrapPanic(graph.describeAdding)
+	if graph.orderHash != nil {
+		for _, segment := range segments {
+			writeHashPoint(graph.orderHash, segment.Start)
+			writeHashPoint(graph.orderHash, segment.End)
+		}
+	}
+	added := 0
+	if graph.Root == nil && len(segments) > 0 {
+		// The first segment builds the initial map whole, so there's nothing to
+		// tell the tracer but that it was added
+		if graph.Tracer != nil {
+			graph.Tracer.SegmentAdded(segments[0])
+		}
 		newGraph := NewQueryGraph(segments[0])
+		graph.Root = newGraph.Root
+		graph.countRingSegment(segments[0], 1)
+		if graph.dueForInvariantCheck(graph.segmentsAdded) {
+			var trapezoids []*Trapezoid
+			for trapezoid := range graph.IterateTrapezoids() {
+				trapezoids = append(trapezoids, trapezoid)
+			}
+			graph.checkTouchedTrapezoids(graph.segmentsAdded, segments[0], trapezoids)
+		}
+		graph.segmentsAdded++
 		segments = segments[1:]
-		*graph = *newGraph
-	}
-
-	// Add the segments
-	//
-	// TODO: Add the preprocessing step which finds new search roots for every
-	// point. That step will make the algorithm O(nlog*n)
-	for _, segment := range segments {
-		graph.AddSegment(segment)
+		graph.invalidateBounds()
+		added = 1
 	}
+	graph.addSegmentsInPhases(segments, added)
 }
 
+// Add every polygon in the list to the graph. Unlike adding them one at a
+// time, this allows polygons to share edges, as neighboring regions do along
+// their border. See shared_edges.go.
+//
+// The segments of every polygon are shuffled together, so the expected time
+// holds for the list as a whole. Adding the polygons one after another would
+// leave the segments of each polygon to search a map already holding all the
+// polygons before it, which many small holes make quadratic.
 func (g *QueryGraph) AddPolygons(list PolygonList) {
-	// TODO: This should be done all at once rather than one at a time
-	for _, poly := range list {
-		g.AddPolygon(poly)
+	g.addPolygonList(findSharedEdges(list))
+}
+
+// Add every polygon in the list, with its shared edges already found
+func (g *QueryGraph) addPolygonList(list PolygonList, shared *sharedEdges) {
+	segments := g.buffers.segmentList(list.vertexCount())
+	for i, poly := range list {
+		segments = g.appendPolygonSegments(segments, poly, i, shared)
 	}
+	g.addShuffled(segments, g.Seed)
 }
 
 // Fast test for point-in-polygon using the trapezoid graph. Output is not
-// defined for points exactly on the edge of the graph.
+// defined for points exactly on the edge of the graph. ContainsPointInclusive
+// and ContainsPointExclusive define it, at the cost of checking the edges near
+// the point.
+//
+// Points which share a Y value with some vertex, but are not on any edge, are
+// classified geometrically. The lexicographic tie-break only decides routing
+// among geometry at that Y. It never moves a point to the other side of a
+// segment it is a finite distance away from, so the answer agrees with
+// ContainsPointByEvenOdd.
+//
+// Points outside the bounding box of the segments return false immediately, as
+// do all points while the graph's rings aren't closed (see RingsClosed).
 func (g *QueryGraph) ContainsPoint(point *Point) bool {
+	if !g.RingsClosed() || g.isFarOutside(point.X, point.Y) {
+		return false
+	}
+
 	// Find the trapezoid containing the point
 	containingTrapezoid := g.FindPoint(point.PointingRight())
 	if containingTrapezoid == nil {
@@ -486,6 +957,35 @@ func (g *QueryGraph) ContainsPoint(point *Point) bool {
 	return containingTrapezoid.Inner.(SinkNode).Trapezoid.IsInside()
 }
 
+// Like ContainsPoint, but points within Epsilon of an edge, or of a vertex, are
+// inside. Edges shared by neighboring regions have the inside on both sides,
+// so points on them are inside either way.
+func (g *QueryGraph) ContainsPointInclusive(point *Point) bool {
+	return g.containsPoint(point, true)
+}
+
+// Like ContainsPoint, but points within Epsilon of an edge, or of a vertex, are
+// outside. As with ContainsPointInclusive, shared edges don't count.
+func (g *QueryGraph) ContainsPointExclusive(point *Point) bool {
+	return g.containsPoint(point, false)
+}
+
+// Is the point inside? Points on an edge are inside if inclusive is set.
+func (g *QueryGraph) containsPoint(point *Point, inclusive bool) bool {
+	if !g.RingsClosed() || g.isFarOutside(point.X, point.Y) {
+		return false
+	}
+	sink := g.FindPoint(point.PointingRight())
+	if sink == nil {
+		return false
+	}
+	trapezoid := sink.Inner.(SinkNode).Trapezoid
+	if g.isNearEdge(trapezoid, point) {
+		return inclusive
+	}
+	return trapezoid.IsInside()
+}
+
 func (g *QueryGraph) IterateGraph() chan *QueryNode {
 	return IterateGraph(g.Root)
 }
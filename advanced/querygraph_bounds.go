@@ -0,0 +1,73 @@
+package advanced
+
+import (
+	"math"
+	"sync"
+)
+
+// Point queries far outside the geometry can be answered without descending
+// the query graph at all, by checking them against the bounding box of the
+// segments. The box is computed lazily, on the first query after the graph
+// changes.
+//
+// Each change to the graph starts a new generation of the cache. The box for a
+// generation is computed under a sync.Once, so concurrent queries are safe, as
+// long as nothing modifies the graph at the same time.
+
+type graphBounds struct {
+	once  sync.Once
+	rect  Rect
+	empty bool
+}
+
+// Padding around the bounding box. Comparisons are made to within Epsilon, so
+// anything this far outside the box can't be on or inside any segment.
+const boundsPadding = 2 * Epsilon
+
+// Drop the cached bounding box, because the graph has changed.
+func (graph *QueryGraph) invalidateBounds() {
+	graph.boundsLock.Lock()
+	graph.bounds = nil
+	graph.boundsLock.Unlock()
+}
+
+// Get the bounding box of all the segments in the graph, padded slightly. If
+// the graph has no segments, empty is true.
+func (graph *QueryGraph) boundingBox() (rect Rect, empty bool) {
+	graph.boundsLock.Lock()
+	bounds := graph.bounds
+	if bounds == nil {
+		bounds = &graphBounds{}
+		graph.bounds = bounds
+	}
+	graph.boundsLock.Unlock()
+
+	bounds.once.Do(func() {
+		bounds.rect = Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		bounds.empty = true
+		if graph.Root == nil {
+			return
+		}
+		for node := range graph.IterateGraph() {
+			xnode, ok := node.Inner.(XNode)
+			if !ok {
+				continue
+			}
+			bounds.empty = false
+			for _, p := range []*Point{xnode.Key.Start, xnode.Key.End} {
+				bounds.rect.MinX = math.Min(bounds.rect.MinX, p.X-boundsPadding)
+				bounds.rect.MinY = math.Min(bounds.rect.MinY, p.Y-boundsPadding)
+				bounds.rect.MaxX = math.Max(bounds.rect.MaxX, p.X+boundsPadding)
+				bounds.rect.MaxY = math.Max(bounds.rect.MaxY, p.Y+boundsPadding)
+			}
+		}
+	})
+	return bounds.rect, bounds.empty
+}
+
+// Check whether a point is definitely outside everything in the graph, in
+// which case there's no need to search for it.
+func (graph *QueryGraph) isFarOutside(x, y float64) bool {
+	rect, empty := graph.boundingBox()
+	return empty || x < rect.MinX || x > rect.MaxX || y < rect.MinY || y > rect.MaxY
+}
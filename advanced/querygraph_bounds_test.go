@@ -0,0 +1,88 @@
+package advanced
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryGraph_BoundingBox(t *testing.T) {
+	g := &QueryGraph{}
+	_, empty := g.boundingBox()
+	assert.True(t, empty)
+	assert.False(t, g.ContainsPoint(&Point{X: 0, Y: 0}))
+
+	g.AddPolygon(squareRing(0, 0, 10))
+	rect, empty := g.boundingBox()
+	assert.False(t, empty)
+	assert.InDelta(t, 0, rect.MinX, 3*Epsilon)
+	assert.InDelta(t, 10, rect.MaxY, 3*Epsilon)
+
+	// Just inside and just outside each edge of the box
+	const offset = 1e-4
+	for _, c := range []struct {
+		x, y   float64
+		inside bool
+	}{
+		{offset, 5, true}, {-offset, 5, false},
+		{10 - offset, 5, true}, {10 + offset, 5, false},
+		{5, offset, true}, {5, -offset, false},
+		{5, 10 - offset, true}, {5, 10 + offset, false},
+		{1000, 1000, false},
+	} {
+		assert.Equal(t, c.inside, g.ContainsPoint(&Point{X: c.x, Y: c.y}), "%v, %v", c.x, c.y)
+	}
+
+	// Adding geometry must invalidate the cached box
+	g.AddPolygon(squareRing(20, 20, 10))
+	assert.True(t, g.ContainsPoint(&Point{X: 25, Y: 25}))
+	rect, _ = g.boundingBox()
+	assert.InDelta(t, 30, rect.MaxX, 3*Epsilon)
+}
+
+func TestQueryGraph_BoundingBoxConcurrent(t *testing.T) {
+	g := &QueryGraph{}
+	g.AddPolygons(SquareWithHole())
+
+	// Run with -race to check the lazy computation
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, g.ContainsPoint(&Point{X: -4, Y: 0}))
+			assert.False(t, g.ContainsPoint(&Point{X: 100, Y: 0}))
+		}()
+	}
+	wg.Wait()
+}
+
+func benchmarkContainsPoint(b *testing.B, outsideFraction float64) {
+	g := &QueryGraph{}
+	g.AddPolygon(circlePolygon(100, 10000))
+
+	rng := rand.New(rand.NewSource(0))
+	points := make([]*Point, 1024)
+	for i := range points {
+		if rng.Float64() < outsideFraction {
+			points[i] = &Point{X: 1000 + rng.Float64()*1000, Y: rng.Float64() * 1000}
+		} else {
+			points[i] = &Point{X: rng.Float64()*100 - 50, Y: rng.Float64()*100 - 50}
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.ContainsPoint(points[i%len(points)])
+	}
+}
+
+func BenchmarkContainsPoint_MostlyOutside(b *testing.B) {
+	benchmarkContainsPoint(b, 0.9)
+}
+
+func BenchmarkContainsPoint_Inside(b *testing.B) {
+	benchmarkContainsPoint(b, 0)
+}
@@ -0,0 +1,126 @@
+package advanced
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// The depth of a query is the number of inner nodes it passes through on the
+// way to its sink. With segments added in random order, the expected depth is
+// O(log n), but an unlucky order can make it linear, which makes building the
+// graph quadratic. These statistics make that visible.
+type QueryDepthStats struct {
+	// Number of point queries made while building the graph
+	Queries int
+	// Deepest and mean depth of those queries
+	MaxDepth  int
+	MeanDepth float64
+}
+
+func (graph *QueryGraph) recordQueryDepth(depth int) {
+	graph.buildQueries++
+	graph.buildDepthTotal += depth
+	if depth > graph.buildDepthMax {
+		graph.buildDepthMax = depth
+	}
+}
+
+// Get the depth statistics of the queries made while building the graph. Only
+// the queries which locate segment endpoints are counted, so later point
+// queries, which may run concurrently, don't affect the result.
+func (graph *QueryGraph) QueryDepth() QueryDepthStats {
+	stats := QueryDepthStats{
+		Queries:  graph.buildQueries,
+		MaxDepth: graph.buildDepthMax,
+	}
+	if graph.buildQueries > 0 {
+		stats.MeanDepth = float64(graph.buildDepthTotal) / float64(graph.buildQueries)
+	}
+	return stats
+}
+
+// Record a warning if the deepest query exceeded factor·log2(nodes), where
+// nodes is the number of nodes in the graph.
+func (graph *QueryGraph) warnDeepQueries(factor float64, diagnostics *Diagnostics) {
+	if factor <= 0 || diagnostics == nil || graph.Root == nil {
+		return
+	}
+	nodes := 0
+	for range graph.IterateGraph() {
+		nodes++
+	}
+	limit := factor * math.Log2(float64(nodes))
+	if float64(graph.buildDepthMax) > limit {
+		diagnostics.warnf(
+			WarningQueryDepth,
+			"query depth reached %d in a graph of %d nodes, past the limit of %.1f",
+			graph.buildDepthMax, nodes, limit,
+		)
+	}
+}
+
+// The default for Options.RebuildDepthFactor. Random insertion orders keep the
+// deepest query under about 4.5·log2(n) for n segments, and an order which goes
+// past twice that is unlucky enough to be worth another try.
+const DefaultRebuildDepthFactor = 8
+
+// How many times a trapezoid map may be rebuilt with another seed
+const maxDepthRebuilds = 3
+
+// Build the trapezoid map of a list with opts.Seed. If its deepest query goes
+// past the limit set by opts.RebuildDepthFactor, rebuild it with the seeds
+// after that, one at a time, and keep the shallowest map. That is always the
+// same map for the same input, so the output stays deterministic. The rebuilds
+// stop once a map is within the limit, or a rebuild is no better than the best
+// so far, since then the depth comes from the input rather than the order.
+// Only the first build uses the buffers' segment storage, so that a rebuild
+// can't overwrite the segments of the map it's compared to. If started isn't
+// nil, it's called with each map before it's built, so that the caller has it
+// if building fails. Returns the map and the number of rebuilds.
+func buildQueryGraph(list PolygonList, opts Options, b *buffers, hashOrder bool, started func(*QueryGraph)) (*QueryGraph, int) {
+	build := func(seed int64, b *buffers) *QueryGraph {
+		graph := &QueryGraph{
+			Seed:              seed,
+			Tracer:            opts.Tracer,
+			InvariantInterval: opts.InvariantInterval,
+			buffers:           b,
+			shuffleSource:     opts.shuffleSource,
+		}
+		graph.margins.inexact = opts.DisableExactFallback
+		if hashOrder {
+			graph.orderHash = fnv.New64a()
+		}
+		if started != nil {
+			started(graph)
+		}
+		// With the seed, the caller can reproduce a failure exactly
+		defer wrapPanic(func() string {
+			return fmt.Sprintf("while building the trapezoid map with seed %d", seed)
+		})
+		graph.AddPolygons(list)
+		return graph
+	}
+	best := build(opts.Seed, b)
+
+	factor := opts.RebuildDepthFactor
+	if factor == 0 {
+		factor = DefaultRebuildDepthFactor
+	}
+	if factor < 0 {
+		return best, 0
+	}
+	limit := int(factor * math.Log2(float64(list.vertexCount())))
+	rebuilds := 0
+	for rebuilds < maxDepthRebuilds && best.buildDepthMax > limit {
+		rebuilds++
+		graph := build(opts.Seed+int64(rebuilds), nil)
+		if graph.buildDepthMax >= best.buildDepthMax {
+			break
+		}
+		best = graph
+	}
+	// Whichever map is kept, extraction can use the scratch memory
+	best.buffers = b
+	return best, rebuilds
+}
@@ -0,0 +1,123 @@
+package advanced
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A rand source which makes Shuffle leave everything in place. Shuffle swaps
+// each element with one chosen at or below it, and the largest value always
+// chooses the element itself.
+type sortedSource struct{}
+
+func (sortedSource) Int63() int64 { return 1<<63 - 1 }
+func (sortedSource) Seed(int64)   {}
+
+// Add segments in exactly the order given, as a shuffle source for Options or
+// QueryGraph
+func sortedInsertion(int64) rand.Source {
+	return sortedSource{}
+}
+
+// A circle with a small hole, since a lone circle is monotone, and would skip
+// the query graph entirely
+func depthTestPolygon() PolygonList {
+	return PolygonList{circlePolygon(100, 400), squareRing(-1, -1, 2).Reverse()}
+}
+
+func TestSortedSource_LeavesOrder(t *testing.T) {
+	values := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	r := rand.New(sortedInsertion(0))
+	r.Shuffle(len(values), func(i, j int) {
+		values[i], values[j] = values[j], values[i]
+	})
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, values)
+}
+
+func TestQueryDepth_Stats(t *testing.T) {
+	var diagnostics Diagnostics
+	depthTestPolygon().TriangulateWithOptions(Options{Diagnostics: &diagnostics})
+	stats := diagnostics.QueryDepth
+	// Two endpoint queries per segment, except the first, which goes into an
+	// empty graph
+	assert.Equal(t, 806, stats.Queries)
+	assert.Greater(t, stats.MaxDepth, 0)
+	assert.Greater(t, stats.MeanDepth, 0.0)
+	assert.LessOrEqual(t, stats.MeanDepth, float64(stats.MaxDepth))
+}
+
+func TestWarnDepthFactor_Shuffled(t *testing.T) {
+	var diagnostics Diagnostics
+	depthTestPolygon().TriangulateWithOptions(Options{
+		WarnDepthFactor: 4,
+		Diagnostics:     &diagnostics,
+	})
+	assert.Empty(t, diagnostics.Warnings)
+}
+
+func TestWarnDepthFactor_Sorted(t *testing.T) {
+	var diagnostics Diagnostics
+	depthTestPolygon().TriangulateWithOptions(Options{
+		WarnDepthFactor: 4,
+		Diagnostics:     &diagnostics,
+		shuffleSource:   sortedInsertion,
+	})
+	if assert.Len(t, diagnostics.Warnings, 1) {
+		assert.Equal(t, WarningQueryDepth, diagnostics.Warnings[0].Kind)
+	}
+}
+
+func TestWarnDepthFactor_Disabled(t *testing.T) {
+	var diagnostics Diagnostics
+	depthTestPolygon().TriangulateWithOptions(Options{Diagnostics: &diagnostics, shuffleSource: sortedInsertion})
+	assert.Empty(t, diagnostics.Warnings)
+}
+
+// A shuffle source which adds segments in exactly the order given for the
+// given seeds, and leaves the rest random
+func sortedSeeds(seeds ...int64) func(int64) rand.Source {
+	return func(seed int64) rand.Source {
+		for _, sorted := range seeds {
+			if seed == sorted {
+				return sortedSource{}
+			}
+		}
+		return rand.NewSource(seed)
+	}
+}
+
+func TestRebuildDepth(t *testing.T) {
+	list := depthTestPolygon()
+	triangulate := func(opts Options) (TriangleList, Diagnostics) {
+		var diagnostics Diagnostics
+		opts.Diagnostics = &diagnostics
+		opts.HashStages = true
+		return list.TriangulateWithOptions(opts), diagnostics
+	}
+	expected, random := triangulate(Options{})
+	assert.Zero(t, random.GraphRebuilds)
+
+	t.Run("unlucky seed", func(t *testing.T) {
+		triangles, diagnostics := triangulate(Options{shuffleSource: sortedSeeds(0)})
+		assert.Equal(t, 1, diagnostics.GraphRebuilds)
+		assert.InDelta(t, totalArea(expected), totalArea(triangles), 1e-9)
+		// The map kept is the one for the next seed
+		_, seed1 := triangulate(Options{Seed: 1})
+		assert.Equal(t, seed1.QueryDepth, diagnostics.QueryDepth)
+		assert.Equal(t, seed1.Hashes, diagnostics.Hashes)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, diagnostics := triangulate(Options{RebuildDepthFactor: -1, shuffleSource: sortedSeeds(0)})
+		assert.Zero(t, diagnostics.GraphRebuilds)
+		assert.Greater(t, diagnostics.QueryDepth.MaxDepth, 100)
+	})
+
+	t.Run("no better order", func(t *testing.T) {
+		// Every seed is as bad, so the first rebuild shows there's nothing to gain
+		_, diagnostics := triangulate(Options{shuffleSource: sortedInsertion})
+		assert.Equal(t, 1, diagnostics.GraphRebuilds)
+	})
+}
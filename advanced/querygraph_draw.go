@@ -13,10 +13,11 @@ import (
 // Padding around the shape to make infinite trapezoids obvious
 const dbgDrawPadding = 100
 
-var inverseMatrixForContext map[*gg.Context]gg.Matrix
-
-func init() {
-	inverseMatrixForContext = make(map[*gg.Context]gg.Matrix)
+// A drawing context, along with the inverse of its transformation, which gg
+// has no way to get at
+type drawContext struct {
+	*gg.Context
+	inverse gg.Matrix
 }
 
 // Helper to draw and print a query graph in the terminal (iTerm only) for debugging.
@@ -66,10 +67,9 @@ func (g *QueryGraph) dbgDraw(scale float64) {
 		Translate(-dbgDrawPadding, -dbgDrawPadding).
 		Scale(1, -1).
 		Translate(0, -float64(height))
-	inverseMatrixForContext[c] = inverseMatrix
 
 	c.SetLineWidth(3)
-	g.draw(c)
+	g.draw(&drawContext{c, inverseMatrix})
 
 	// Save to temp file
 	c.SavePNG("/tmp/querygraph.png")
@@ -77,7 +77,7 @@ func (g *QueryGraph) dbgDraw(scale float64) {
 	imgcat.CatFile("/tmp/querygraph.png", os.Stdout)
 }
 
-func (g *QueryGraph) draw(c *gg.Context) {
+func (g *QueryGraph) draw(c *drawContext) {
 	// Find all the trapezoids and fill them, then stroke them
 	for t := range IterateTrapezoids(g.Root) {
 		t.draw(c, false)
@@ -88,7 +88,7 @@ func (g *QueryGraph) draw(c *gg.Context) {
 }
 
 // Draw the trapezoid
-func (t *Trapezoid) draw(c *gg.Context, stroke bool) {
+func (t *Trapezoid) draw(c *drawContext, stroke bool) {
 	// Find the bounds of the canvas, for points at infinity
 	bounds := getCanvasBounds(c)
 	left, right := t.Left, t.Right
@@ -108,19 +108,13 @@ func (t *Trapezoid) draw(c *gg.Context, stroke bool) {
 				x = float64(bounds.Max.X)
 			}
 			// Just make a line off the side of the image
-			*side = &Segment{
-				Start: &Point{X: x, Y: top.Y},
-				End:   &Point{X: x, Y: bottom.Y},
-			}
+			*side = NewSegment(&Point{X: x, Y: top.Y}, &Point{X: x, Y: bottom.Y})
 		} else if !(*side).IsHorizontal() { // leave horizontal segments alone
 			// Solve for x
 			var topX, bottomX float64
 			topX = (*side).SolveForX(top.Y)
 			bottomX = (*side).SolveForX(bottom.Y)
-			*side = &Segment{
-				Start: &Point{X: topX, Y: top.Y},
-				End:   &Point{X: bottomX, Y: bottom.Y},
-			}
+			*side = NewSegment(&Point{X: topX, Y: top.Y}, &Point{X: bottomX, Y: bottom.Y})
 		}
 	}
 
@@ -158,8 +152,8 @@ func (t *Trapezoid) draw(c *gg.Context, stroke bool) {
 	}
 }
 
-func getCanvasBounds(c *gg.Context) image.Rectangle {
-	matrix := inverseMatrixForContext[c]
+func getCanvasBounds(c *drawContext) image.Rectangle {
+	matrix := c.inverse
 	bounds := image.Rect(-10, -10, c.Width()+20, c.Height()+20)
 	minX, minY := matrix.TransformPoint(float64(bounds.Min.X), float64(bounds.Min.Y))
 	maxX, maxY := matrix.TransformPoint(float64(bounds.Max.X), float64(bounds.Max.Y))
@@ -0,0 +1,219 @@
+package advanced
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Export of the trapezoid map as plain line segments, for loading into external
+// plotting tools. Unlike dbgDraw, this needs no image libraries and no
+// terminal support, so it's suitable for asking users to send in their
+// failing graphs.
+
+type ExportKind int
+
+const (
+	// An input segment
+	PolygonEdge ExportKind = iota
+	// The horizontal line through a trapezoid's top point, between its sides
+	TrapezoidTopBoundary
+	// The horizontal line through a trapezoid's bottom point, between its sides
+	TrapezoidBottomBoundary
+)
+
+func (kind ExportKind) String() string {
+	switch kind {
+	case PolygonEdge:
+		return "PolygonEdge"
+	case TrapezoidTopBoundary:
+		return "TrapezoidTopBoundary"
+	case TrapezoidBottomBoundary:
+		return "TrapezoidBottomBoundary"
+	}
+	return "Invalid"
+}
+
+type ExportedSegment struct {
+	X1, Y1, X2, Y2 float64
+	Kind           ExportKind
+}
+
+// Padding around the computed bounds, as a fraction of their size, so that the
+// infinite trapezoids are visible
+const exportPadding = 0.1
+
+// Export the graph as line segments: every input segment, followed by the top
+// and bottom boundary of every trapezoid. Boundaries run between the
+// trapezoid's sides, found the same way dbgDraw finds them. Infinite
+// trapezoids are clipped to the bounds, which may optionally be given. Input
+// segments are clipped to the part inside the bounds, and left out if they're
+// entirely outside. By default, the bounds are the bounding box of the input
+// segments, padded by 10% on each side.
+//
+// Every coordinate in the result is finite and within the bounds, even for
+// degenerate trapezoids.
+func (g *QueryGraph) ExportSegments(bounds ...Rect) []ExportedSegment {
+	if g.Root == nil {
+		return nil
+	}
+
+	var segments []*Segment
+	seen := make(map[*Segment]struct{})
+	for node := range g.IterateGraph() {
+		if xnode, ok := node.Inner.(XNode); ok {
+			if _, ok := seen[xnode.Key]; !ok {
+				seen[xnode.Key] = struct{}{}
+				segments = append(segments, xnode.Key)
+			}
+		}
+	}
+
+	var clip Rect
+	if len(bounds) > 0 {
+		clip = bounds[0]
+	} else {
+		clip = exportBounds(segments)
+	}
+
+	var result []ExportedSegment
+	for _, segment := range segments {
+		if clipped, ok := clipSegment(segment, clip); ok {
+			result = append(result, clipped)
+		}
+	}
+	for trapezoid := range g.IterateTrapezoids() {
+		result = append(result,
+			trapezoid.exportBoundary(trapezoid.Top, clip.MaxY, TrapezoidTopBoundary, clip),
+			trapezoid.exportBoundary(trapezoid.Bottom, clip.MinY, TrapezoidBottomBoundary, clip),
+		)
+	}
+	return result
+}
+
+// Write the exported segments as CSV, with a header row. Coordinates are
+// written at full precision.
+func (g *QueryGraph) WriteCSV(w io.Writer, bounds ...Rect) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"x1", "y1", "x2", "y2", "kind"}); err != nil {
+		return err
+	}
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	for _, s := range g.ExportSegments(bounds...) {
+		record := []string{format(s.X1), format(s.Y1), format(s.X2), format(s.Y2), s.Kind.String()}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Clip an input segment to the bounds, using the Liang-Barsky algorithm, so
+// that the part which is left runs along the same line. Returns false if the
+// segment is entirely outside the bounds.
+func clipSegment(segment *Segment, clip Rect) (ExportedSegment, bool) {
+	start, end := segment.Start, segment.End
+	dx, dy := end.X-start.X, end.Y-start.Y
+	// The segment is start + t*(dx, dy), for t from 0 to 1. Each side of the
+	// bounds cuts off the part of that range where p*t > q.
+	t0, t1 := 0.0, 1.0
+	for _, side := range [4]struct{ p, q float64 }{
+		{-dx, start.X - clip.MinX},
+		{dx, clip.MaxX - start.X},
+		{-dy, start.Y - clip.MinY},
+		{dy, clip.MaxY - start.Y},
+	} {
+		if side.p == 0 {
+			// Parallel to this side, so entirely on one side of it
+			if side.q < 0 {
+				return ExportedSegment{}, false
+			}
+			continue
+		}
+		t := side.q / side.p
+		if side.p < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+	}
+	if t0 > t1 {
+		return ExportedSegment{}, false
+	}
+
+	// Ends which aren't cut off are kept exactly. Cut ends are clamped, since
+	// rounding can leave them a hair outside.
+	at := func(t float64) (x, y float64) {
+		switch t {
+		case 0:
+			return start.X, start.Y
+		case 1:
+			return end.X, end.Y
+		}
+		return clamp(start.X+t*dx, clip.MinX, clip.MaxX), clamp(start.Y+t*dy, clip.MinY, clip.MaxY)
+	}
+	x1, y1 := at(t0)
+	x2, y2 := at(t1)
+	return ExportedSegment{x1, y1, x2, y2, PolygonEdge}, true
+}
+
+func exportBounds(segments []*Segment) Rect {
+	bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, segment := range segments {
+		for _, p := range []*Point{segment.Start, segment.End} {
+			bounds.MinX, bounds.MinY = math.Min(bounds.MinX, p.X), math.Min(bounds.MinY, p.Y)
+			bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, p.X), math.Max(bounds.MaxY, p.Y)
+		}
+	}
+	// Pad by a fraction of the larger dimension, so that a flat input still
+	// gets some room. An empty input gets an arbitrary unit box.
+	size := math.Max(bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY)
+	if math.IsInf(size, 0) {
+		return Rect{-1, -1, 1, 1}
+	}
+	padding := math.Max(size*exportPadding, 1)
+	return Rect{bounds.MinX - padding, bounds.MinY - padding, bounds.MaxX + padding, bounds.MaxY + padding}
+}
+
+// Export the horizontal boundary through the given point, which may be nil for
+// an infinite trapezoid, in which case the boundary is drawn at defaultY.
+func (t *Trapezoid) exportBoundary(p *Point, defaultY float64, kind ExportKind, clip Rect) ExportedSegment {
+	y := defaultY
+	if p != nil {
+		y = clamp(p.Y, clip.MinY, clip.MaxY)
+	}
+	leftX := exportSideX(t.Left, p, y, clip.MinX, clip)
+	rightX := exportSideX(t.Right, p, y, clip.MaxX, clip)
+	return ExportedSegment{leftX, y, rightX, y, kind}
+}
+
+// Find the X coordinate of a trapezoid's side at the given height. Missing
+// sides are at the edge of the bounds. A horizontal side has no single X at
+// its own height; lexicographically, the boundary point itself is where the
+// side reaches that height, so that's used, clamped to the side's extent.
+func exportSideX(side *Segment, p *Point, y float64, defaultX float64, clip Rect) float64 {
+	var x float64
+	switch {
+	case side == nil:
+		x = defaultX
+	case side.IsHorizontal():
+		x = defaultX
+		if p != nil {
+			x = clamp(p.X, math.Min(side.Start.X, side.End.X), math.Max(side.Start.X, side.End.X))
+		}
+	default:
+		x = side.SolveForX(y)
+	}
+	if math.IsNaN(x) {
+		x = defaultX
+	}
+	return clamp(x, clip.MinX, clip.MaxX)
+}
+
+func clamp(x, min, max float64) float64 {
+	return math.Max(min, math.Min(max, x))
+}
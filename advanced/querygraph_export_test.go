@@ -0,0 +1,103 @@
+package advanced
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertExportWithin(t *testing.T, segments []ExportedSegment, bounds Rect) {
+	for _, s := range segments {
+		for _, x := range []float64{s.X1, s.X2} {
+			assert.False(t, math.IsNaN(x) || math.IsInf(x, 0), "%v", s)
+			assert.GreaterOrEqual(t, x, bounds.MinX, "%v", s)
+			assert.LessOrEqual(t, x, bounds.MaxX, "%v", s)
+		}
+		for _, y := range []float64{s.Y1, s.Y2} {
+			assert.False(t, math.IsNaN(y) || math.IsInf(y, 0), "%v", s)
+			assert.GreaterOrEqual(t, y, bounds.MinY, "%v", s)
+			assert.LessOrEqual(t, y, bounds.MaxY, "%v", s)
+		}
+	}
+}
+
+func TestExportSegments(t *testing.T) {
+	list := SquareWithHole()
+	g := &QueryGraph{}
+	g.AddPolygons(list)
+
+	segments := g.ExportSegments()
+	counts := map[ExportKind]int{}
+	for _, s := range segments {
+		counts[s.Kind]++
+		if s.Kind != PolygonEdge {
+			assert.Equal(t, s.Y1, s.Y2, "boundaries should be horizontal")
+			assert.LessOrEqual(t, s.X1, s.X2, "boundaries should run left to right")
+		}
+	}
+	trapezoidCount := len(collectTrapezoids(g))
+	assert.Equal(t, 8, counts[PolygonEdge])
+	assert.Equal(t, trapezoidCount, counts[TrapezoidTopBoundary])
+	assert.Equal(t, trapezoidCount, counts[TrapezoidBottomBoundary])
+
+	// The square runs from -5 to 5, so 10% padding is 1
+	assertExportWithin(t, segments, Rect{-6, -6, 6, 6})
+
+	// Explicit bounds clip everything, including the input segments
+	bounds := Rect{-1, -1, 1, 1}
+	assertExportWithin(t, g.ExportSegments(bounds), bounds)
+}
+
+func TestExportSegments_Degenerate(t *testing.T) {
+	// A single segment leaves every trapezoid infinite
+	g := NewQueryGraph(NewSegment(&Point{X: 0, Y: 0}, &Point{X: 1, Y: 1}))
+	segments := g.ExportSegments()
+	assert.Len(t, segments, 1+2*4)
+	assertExportWithin(t, segments, Rect{-1, -1, 2, 2})
+
+	// Horizontal segments make zero height trapezoids with horizontal sides
+	a, b, c := &Point{X: 0, Y: 0}, &Point{X: 4, Y: 0}, &Point{X: 2, Y: 3}
+	g = NewQueryGraph(NewSegment(a, b))
+	g.AddSegment(NewSegment(b, c))
+	g.AddSegment(NewSegment(c, a))
+	require.NotPanics(t, func() { segments = g.ExportSegments() })
+	assertExportWithin(t, segments, Rect{-1, -1, 5, 4})
+
+	assert.Nil(t, (&QueryGraph{}).ExportSegments())
+}
+
+func TestExportSegments_ClipsInputSegments(t *testing.T) {
+	g := NewQueryGraph(NewSegment(&Point{X: 0, Y: 0}, &Point{X: 4, Y: 2}))
+	g.AddSegment(NewSegment(&Point{X: 5, Y: 5}, &Point{X: 6, Y: 8}))
+	bounds := Rect{0, 0, 2, 4}
+
+	var edges []ExportedSegment
+	for _, s := range g.ExportSegments(bounds) {
+		if s.Kind == PolygonEdge {
+			edges = append(edges, s)
+		}
+	}
+
+	// The first segment is cut where it leaves the bounds, keeping its slope,
+	// rather than having each end clamped separately. The second is entirely
+	// outside, so it's left out.
+	require.Len(t, edges, 1)
+	assert.Equal(t, ExportedSegment{0, 0, 2, 1, PolygonEdge}, edges[0])
+}
+
+func TestWriteCSV(t *testing.T) {
+	g := &QueryGraph{}
+	g.AddPolygons(SquareWithHole())
+
+	var buffer bytes.Buffer
+	require.NoError(t, g.WriteCSV(&buffer))
+	records, err := csv.NewReader(&buffer).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x1", "y1", "x2", "y2", "kind"}, records[0])
+	assert.Len(t, records, 1+len(g.ExportSegments()))
+	assert.Equal(t, "PolygonEdge", records[1][4])
+}
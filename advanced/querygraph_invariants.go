@@ -0,0 +1,150 @@
+package advanced
+
+import "fmt"
+
+// Invariant checks for the phases of QueryGraph.AddSegment. These are only run
+// when CheckInvariants is set on the graph. Each check fails with the name of
+// the phase, so that a broken invariant can be traced to the phase which broke
+// it, rather than surfacing several operations later. The check of the
+// trapezoids near each segment, once it's in, is run when InvariantInterval is
+// set instead.
+
+func invariantf(phase string, segment *Segment, format string, args ...interface{}) {
+	var where string
+	if segment != nil {
+		where = fmt.Sprintf(" while adding segment %v-%v", segment.Start, segment.End)
+	}
+	fatalf("invariant violated after %s%s: %s", phase, where, fmt.Sprintf(format, args...))
+}
+
+// After locating an endpoint, the trapezoid must have the endpoint on its
+// boundary.
+func checkLocatedEndpoint(endpoint *Point, trapezoid *Trapezoid) {
+	const phase = "locateAndSplitEndpoint"
+	if trapezoid == nil {
+		invariantf(phase, nil, "no trapezoid found for endpoint %v", endpoint)
+	}
+	if !trapezoid.HasPoint(endpoint) {
+		invariantf(phase, nil, "endpoint %v is not on the boundary of trapezoid %s", endpoint, trapezoid.Geometry())
+	}
+}
+
+// After splitting, the chains must be the same nonzero length, and every
+// trapezoid must have the new segment on the side facing the other chain.
+func checkSplitChains(segment *Segment, leftChain, rightChain []*Trapezoid) {
+	const phase = "splitChainAlongSegment"
+	if len(leftChain) == 0 || len(leftChain) != len(rightChain) {
+		invariantf(phase, segment, "chain lengths are %d and %d", len(leftChain), len(rightChain))
+	}
+	for i, trapezoid := range leftChain {
+		if trapezoid.Right != segment {
+			invariantf(phase, segment, "left chain trapezoid %d does not have the segment on its right: %s", i, trapezoid.Geometry())
+		}
+	}
+	for i, trapezoid := range rightChain {
+		if trapezoid.Left != segment {
+			invariantf(phase, segment, "right chain trapezoid %d does not have the segment on its left: %s", i, trapezoid.Geometry())
+		}
+	}
+}
+
+// After merging, every node which held the sink of a split trapezoid must now
+// be an XNode on the segment, and no merged trapezoid may have more than two
+// neighbors on either side.
+func checkMergedChains(segment *Segment, splitNodes []*QueryNode, mergedTrapezoids []*Trapezoid) {
+	const phase = "mergeChains"
+	for _, node := range splitNodes {
+		xnode, ok := node.Inner.(XNode)
+		if !ok {
+			invariantf(phase, segment, "split sink was replaced with %T, not an XNode", node.Inner)
+		}
+		if xnode.Key != segment {
+			invariantf(phase, segment, "split sink XNode is keyed on the wrong segment %v-%v", xnode.Key.Start, xnode.Key.End)
+		}
+		if xnode.Left == nil || xnode.Right == nil {
+			invariantf(phase, segment, "split sink XNode is missing a child")
+		}
+	}
+	for _, trapezoid := range mergedTrapezoids {
+		if count := trapezoid.TrapezoidsAbove.Count(); count > 2 {
+			invariantf(phase, segment, "trapezoid has %d neighbors above: %s", count, trapezoid.Geometry())
+		}
+		if count := trapezoid.TrapezoidsBelow.Count(); count > 2 {
+			invariantf(phase, segment, "trapezoid has %d neighbors below: %s", count, trapezoid.Geometry())
+		}
+		if sink, ok := trapezoid.Sink.Inner.(SinkNode); !ok || sink.Trapezoid != trapezoid {
+			invariantf(phase, segment, "trapezoid's sink does not point back at it: %s", trapezoid.Geometry())
+		}
+	}
+}
+
+// Check the trapezoids which adding a segment left behind, and their neighbors,
+// for the invariants which the neighbor graph must keep: each trapezoid is its
+// sink's trapezoid, has at most two neighbors above and two below, is listed
+// in return by each of them, and meets each of them along the same horizontal
+// line, with an overlap of more than Epsilon. Only the trapezoids near the
+// segment are checked, so this costs as much as adding the segment did, which
+// is what makes it usable on real inputs. index is the number of segments
+// added to the graph before this one. See QueryGraph.InvariantInterval.
+func (graph *QueryGraph) checkTouchedTrapezoids(index int, segment *Segment, touched []*Trapezoid) {
+	fail := func(format string, args ...interface{}) {
+		fatalf("invariant violated after adding segment %d, %s: %s", index, graph.describe(segment), fmt.Sprintf(format, args...))
+	}
+
+	checked := make(map[*Trapezoid]bool, 3*len(touched))
+	var trapezoids []*Trapezoid
+	add := func(t *Trapezoid) {
+		if t != nil && !checked[t] {
+			checked[t] = true
+			trapezoids = append(trapezoids, t)
+		}
+	}
+	for _, t := range touched {
+		add(t)
+		for _, neighbor := range t.TrapezoidsAbove {
+			add(neighbor)
+		}
+		for _, neighbor := range t.TrapezoidsBelow {
+			add(neighbor)
+		}
+	}
+
+	for _, t := range trapezoids {
+		if t.Sink == nil {
+			fail("trapezoid %s has no sink", t.Geometry())
+		}
+		if sink, ok := t.Sink.Inner.(SinkNode); !ok || sink.Trapezoid != t {
+			fail("trapezoid %s is not its sink's trapezoid", t.Geometry())
+		}
+		for _, side := range []struct {
+			name                string
+			neighbors, opposite func(*Trapezoid) *TrapezoidNeighborList
+		}{
+			{"above", neighborsAbove, neighborsBelow},
+			{"below", neighborsBelow, neighborsAbove},
+		} {
+			neighbors := side.neighbors(t)
+			if count := neighbors.Count(); count > 2 {
+				fail("trapezoid %s has %d neighbors %s", t.Geometry(), count, side.name)
+			}
+			for _, neighbor := range neighbors {
+				if neighbor == nil {
+					continue
+				}
+				if !side.opposite(neighbor).contains(t) {
+					fail("trapezoid %s has neighbor %s %s, which doesn't list it in return", t.Geometry(), side.name, neighbor.Geometry())
+				}
+				lower, upper := t, neighbor
+				if side.name == "below" {
+					lower, upper = neighbor, t
+				}
+				if lower.Top != upper.Bottom {
+					fail("trapezoid %s has neighbor %s %s, at another height", t.Geometry(), side.name, neighbor.Geometry())
+				}
+				if !lower.NonzeroOverlapWithTrapezoidAbove(upper) {
+					fail("trapezoid %s has neighbor %s %s, which it doesn't overlap", t.Geometry(), side.name, neighbor.Geometry())
+				}
+			}
+		}
+	}
+}
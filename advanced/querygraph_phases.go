@@ -0,0 +1,97 @@
+package advanced
+
+import "math"
+
+// Seidel's phases, which take building the map from O(nlog(n)) to
+// O(nlog*(n)) expected time. Locating an endpoint from the root costs the
+// depth of the query graph, which is O(log(n)). But the query graph only grows
+// below its sinks: a sink which is split, or cut by a segment, becomes an inner
+// node in place, and the nodes above it never change. So a point's search
+// always passes through whichever sink held it earlier, and can start there.
+//
+// The segments are added in phases. Phase h ends once N(h) = n/log⁽ʰ⁾(n)
+// segments are in, where log⁽ʰ⁾ is the logarithm applied h times, and after
+// each phase, the endpoints of every segment still to come are located, each
+// starting from where it was found after the phase before. There are log*(n)
+// phases, and each costs O(n) in expectation, since a phase only adds a
+// constant expected depth below where each point was last found.
+//
+// The search from a cached node makes the same decisions, at the same nodes,
+// as the search from the root would have, just split into pieces, so the map
+// is exactly the one built without phases. The decisions are still each noted
+// once for the Confidence, and each query's depth is counted from the root.
+
+// Where an endpoint's search starts: a node its search passes through, and the
+// depth of that node. A nil node is the root.
+type searchStart struct {
+	node  *QueryNode
+	depth int
+}
+
+// Find the sink for the point, starting from start
+func (graph *QueryGraph) findPointFrom(dp DirectionalPoint, start searchStart) (sink *QueryNode, depth int) {
+	node := start.node
+	if node == nil {
+		node = graph.Root
+	}
+	sink, depth = node.findPoint(dp, &graph.margins)
+	return sink, start.depth + depth
+}
+
+// The number of segments in once phase h is over, out of n
+func phaseEnd(n, h int) int {
+	log := float64(n)
+	for i := 0; i < h; i++ {
+		log = math.Log2(log)
+	}
+	if !(log > 1) {
+		return n
+	}
+	return int(math.Ceil(float64(n) / log))
+}
+
+// Add the segments in order, in phases, to a graph which already has a root.
+// added is how many segments of the same batch are already in, which is one
+// when the first of them made the root.
+func (graph *QueryGraph) addSegmentsInPhases(segments []*Segment, added int) {
+	if graph.unphased {
+		for _, segment := range segments {
+			graph.addSegment(segment, [2]searchStart{})
+		}
+		return
+	}
+
+	n := len(segments)
+	total := n + added
+	// The start of the search for each segment's top, then bottom
+	var starts [][2]searchStart
+	next := 0
+	for h := 1; next < n; h++ {
+		end := phaseEnd(total, h) - added
+		if end > n {
+			end = n
+		}
+		for ; next < end; next++ {
+			var start [2]searchStart
+			if starts != nil {
+				start = starts[next]
+			}
+			graph.addSegment(segments[next], start)
+		}
+		if next == n {
+			break
+		}
+
+		if starts == nil {
+			starts = make([][2]searchStart, n)
+		}
+		for i := next; i < n; i++ {
+			segment := segments[i]
+			top, bottom := segment.Top(), segment.Bottom()
+			for k, dp := range [2]DirectionalPoint{top.PointingAt(bottom), bottom.PointingAt(top)} {
+				node, depth := graph.findPointFrom(dp, starts[i][k])
+				starts[i][k] = searchStart{node, depth}
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package advanced
+
+// Segments can be added to a graph one at a time, so a graph can be queried
+// while its rings are still open, as a Session's graph is between chunks. The
+// inside of a ring is only meaningful once the ring is closed, since until
+// then, the trapezoids beside its segments are bounded on one side only, or by
+// a segment which is about to be cut off by another. So a graph has no inside
+// region at all until every ring in it is closed: ContainsPoint is false
+// everywhere, and there are no inside trapezoids to make monotone polygons
+// from.
+//
+// Rings are closed when every endpoint has as many segments leaving it as
+// arriving at it. The graph keeps the difference for each endpoint, along with
+// a count of the endpoints where it isn't zero.
+
+// Count a segment into the balance of its endpoints, or back out of it when
+// delta is -1. A shared edge runs both ways, so it doesn't change the balance.
+func (graph *QueryGraph) countRingSegment(segment *Segment, delta int) {
+	if graph.isShared(segment) {
+		return
+	}
+	if graph.balance == nil {
+		graph.balance = make(map[*Point]int)
+	}
+	for _, end := range [2]struct {
+		point *Point
+		delta int
+	}{{segment.Start, delta}, {segment.End, -delta}} {
+		before := graph.balance[end.point]
+		after := before + end.delta
+		if before == 0 && after != 0 {
+			graph.unbalanced++
+		} else if before != 0 && after == 0 {
+			graph.unbalanced--
+		}
+		if after == 0 {
+			delete(graph.balance, end.point)
+		} else {
+			graph.balance[end.point] = after
+		}
+	}
+}
+
+// Do the segments in the graph form closed rings? An empty graph has no rings
+// to close.
+func (graph *QueryGraph) RingsClosed() bool {
+	return graph.Root != nil && graph.unbalanced == 0
+}
@@ -0,0 +1,80 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func insideTrapezoidCount(graph *QueryGraph) int {
+	count := 0
+	for range graph.InsideTrapezoids() {
+		count++
+	}
+	return count
+}
+
+func TestQueryGraph_RingsClosed(t *testing.T) {
+	// A hexagon, added one edge at a time
+	hexagon := circlePolygon(10, 6)
+	segments := segmentsForPolygons(PolygonList{hexagon})
+	center, outside := &Point{X: 0, Y: 0}, &Point{X: 20, Y: 0}
+
+	graph := &QueryGraph{}
+	assert.False(t, graph.RingsClosed())
+	assert.False(t, graph.ContainsPoint(center))
+	assert.Equal(t, 0, insideTrapezoidCount(graph))
+	assert.Empty(t, ConvertMapToMonotones(graph))
+
+	for i, segment := range segments[:5] {
+		graph.AddSegment(segment)
+		if i == 0 {
+			// A single segment has a trapezoid on each side, one of which would be
+			// inside by its side alone
+			assert.Equal(t, 4, len(collectTrapezoids(graph)))
+		}
+		assert.False(t, graph.RingsClosed(), "after %d segments", i+1)
+		assert.False(t, graph.ContainsPoint(center), "after %d segments", i+1)
+		assert.False(t, graph.ContainsPoint(&Point{X: 9, Y: 0}), "after %d segments", i+1)
+		assert.Equal(t, 0, insideTrapezoidCount(graph), "after %d segments", i+1)
+	}
+	assert.Empty(t, ConvertMapToMonotones(graph))
+
+	graph.AddSegment(segments[5])
+	assert.True(t, graph.RingsClosed())
+	assert.True(t, graph.ContainsPoint(center))
+	assert.False(t, graph.ContainsPoint(outside))
+	monotones := ConvertMapToMonotones(graph)
+	require.NotEmpty(t, monotones)
+	var area float64
+	for _, poly := range monotones {
+		area += Area(&poly)
+	}
+	assert.InDelta(t, Area(&hexagon), area, 1e-9)
+}
+
+func TestQueryGraph_RingsClosed_Empty(t *testing.T) {
+	assert.Empty(t, ConvertToMonotones(PolygonList{}))
+}
+
+func TestSession_ContainsPoint(t *testing.T) {
+	list := SquareWithHole()
+	segments := segmentsForPolygons(list)
+	inSolid, inHole := &Point{X: -4, Y: 0}, &Point{X: 0, Y: 0}
+
+	session := NewSession()
+	assert.False(t, session.ContainsPoint(inSolid))
+	// The outer ring, with the hole still open
+	require.NoError(t, session.AddSegmentsChunk(segments[:6]))
+	assert.False(t, session.ContainsPoint(inSolid))
+	assert.False(t, session.ContainsPoint(inHole))
+
+	require.NoError(t, session.AddSegmentsChunk(segments[6:]))
+	assert.True(t, session.ContainsPoint(inSolid))
+	assert.False(t, session.ContainsPoint(inHole))
+
+	_, err := session.Finalize()
+	require.NoError(t, err)
+	assert.False(t, session.ContainsPoint(inSolid))
+}
@@ -105,6 +105,121 @@ func TestNewQueryGraph(t *testing.T) {
 	}
 }
 
+func TestNewQueryGraph_HorizontalFirstSegment(t *testing.T) {
+	// The left and right trapezoids have zero height, but they only hold points
+	// level with the segment, so points above and below are still found in the
+	// top and bottom trapezoids
+	g := NewQueryGraph(NewSegment(&Point{X: 0, Y: 5}, &Point{X: 10, Y: 5}))
+	for _, c := range []struct {
+		y     float64
+		above bool
+	}{{5.001, true}, {4.999, false}, {5 + 1e-5, true}, {5 - 1e-5, false}} {
+		for _, x := range []float64{-5, 0, 5, 10, 15} {
+			trapezoid := g.FindPoint(DefaultDirectionalPoint(x, c.y)).Inner.(SinkNode).Trapezoid
+			if c.above {
+				assert.Nil(t, trapezoid.Top, "%v, %v", x, c.y)
+			} else {
+				assert.Nil(t, trapezoid.Bottom, "%v, %v", x, c.y)
+			}
+		}
+	}
+
+	// Start a rectangle's graph from each of its edges in turn, so that half of
+	// the time, the first segment is horizontal
+	corners := squareRing(0, 0, 10).Points
+	for first := range corners {
+		points := append(append([]*Point{}, corners[first:]...), corners[:first]...)
+		list := PolygonList{{points}}
+		require.Equal(t, corners[first].Y == points[1].Y, first%2 == 0)
+
+		graph := &QueryGraph{shuffleSource: sortedInsertion}
+		graph.AddPolygons(list)
+		for _, y := range []float64{-1e-4, 1e-4, 5, 10 - 1e-4, 10 + 1e-4} {
+			for _, x := range []float64{-1, 1e-4, 5, 10 - 1e-4, 11} {
+				p := &Point{X: x, Y: y}
+				assert.Equal(t, list.ContainsPointByEvenOdd(p), graph.ContainsPoint(p), "first edge %d, point %v", first, p)
+			}
+		}
+
+		triangles := list.TriangulateWithOptions(Options{shuffleSource: sortedInsertion})
+		assert.InDelta(t, 100, totalArea(triangles), 1e-9)
+		validatePolygonsBySampling(t, triangles.ToPolygonList(), list)
+	}
+}
+
+// A narrow V, with its point 1e-9 from the edge of a solid beside it, on the
+// given side. Once the V's arms are in the map, the trapezoid below the point
+// has two neighbors above it, meeting at the point, and the walk along the
+// edge has to choose between them when their bottom tests are both too close
+// to call.
+func nearJunction(side XDirection) (list PolygonList, arms []*Segment) {
+	sign := 1.0
+	if side == Left {
+		sign = -1
+	}
+	point := func(x, y float64) *Point { return &Point{X: sign * x, Y: y} }
+	junction := point(0, 0)
+	list = PolygonList{
+		{[]*Point{junction, point(1, 10), point(0, 12), point(-1, 10)}},
+		{[]*Point{point(-2+1e-9, -10), point(5, -10), point(5, 10), point(2+1e-9, 10)}},
+	}
+	if side == Left {
+		for i := range list {
+			list[i] = list[i].Reverse()
+		}
+	}
+	for _, segment := range segmentsForPolygons(list) {
+		if segment.Start == junction || segment.End == junction {
+			arms = append(arms, segment)
+		}
+	}
+	return list, arms
+}
+
+func TestAddSegment_NearJunction(t *testing.T) {
+	for _, side := range []XDirection{Left, Right} {
+		list, arms := nearJunction(side)
+		// The arms go in first, so that the walk along the edge is what decides
+		// which side of the point it passes. With the edge in first, the point is
+		// located against it instead, and that's left to the tolerance.
+		graph := &QueryGraph{CheckInvariants: true}
+		for _, arm := range arms {
+			graph.AddSegment(arm)
+		}
+		for _, segment := range segmentsForPolygons(list) {
+			if segment.Start.Y != 0 && segment.End.Y != 0 {
+				graph.AddSegment(segment)
+			}
+		}
+		validateNeighborGraph(t, graph)
+
+		var triangles TriangleList
+		for _, monotone := range ConvertMapToMonotones(graph) {
+			triangles = append(triangles, TriangulateMonotone(&monotone)...)
+		}
+		assert.Len(t, triangles, 4, "side %v", side)
+		assert.InDelta(t, Area(&list[0])+Area(&list[1]), totalArea(triangles), 1e-9, "side %v", side)
+		validatePolygonsBySampling(t, triangles.ToPolygonList(), list)
+	}
+}
+
+func TestUpperNeighborAlong_Unsettled(t *testing.T) {
+	// Two neighbors meeting at a point, with no segment dividing them there,
+	// both pass the bottom test, and nothing can choose between them
+	junction := &Point{X: 0, Y: 0}
+	neighbors := TrapezoidNeighborList{{Bottom: junction}, {Bottom: junction}}
+	segment := NewSegment(&Point{X: 1, Y: -1}, &Point{X: -1, Y: 1})
+	err := func() (err error) {
+		defer func() {
+			err = HandleTriangulatePanicRecover(recover())
+		}()
+		(&QueryGraph{}).upperNeighborAlong(segment, neighbors)
+		return nil
+	}()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crosses the bottoms of 2 of the 2 trapezoids above")
+}
+
 func TestAddSegment(t *testing.T) {
 	firstSegment := &Segment{
 		Start: &Point{X: 1, Y: 2},
@@ -244,12 +359,13 @@ func TestAddPolygon_MultiLayeredHoles(t *testing.T) {
 }
 
 func validateNeighborGraph(t *testing.T, graph *QueryGraph) {
-	// Find all the trapezoids in the graph
-	var trapezoids []*Trapezoid
-	for node := range IterateGraph(graph.Root) {
-		if node, ok := node.Inner.(SinkNode); ok {
-			trapezoids = append(trapezoids, node.Trapezoid)
-		}
+	// Find all the trapezoids in the graph, keyed by identity, so that a
+	// trapezoid reachable through several sinks can't mask a missing neighbor
+	trapezoids := collectTrapezoids(graph)
+	inGraph := make(map[*Trapezoid]struct{}, len(trapezoids))
+	for _, trapezoid := range trapezoids {
+		assert.NotContains(t, inGraph, trapezoid, "trapezoid %s was collected twice", trapezoid)
+		inGraph[trapezoid] = struct{}{}
 	}
 
 	for _, trapezoid := range trapezoids {
@@ -262,7 +378,7 @@ func validateNeighborGraph(t *testing.T, graph *QueryGraph) {
 			// Check reflexivity
 			assert.Contains(t, neighbor.TrapezoidsBelow, trapezoid, "above neighbor %s does not have %s as a below neighbor", neighbor, trapezoid)
 			// Check graph connectivity
-			assert.Contains(t, trapezoids, neighbor, "above neighbor %s is not in the graph", neighbor)
+			assert.Contains(t, inGraph, neighbor, "above neighbor %s is not in the graph", neighbor)
 			count++
 		}
 		assert.LessOrEqual(t, count, 2, "trapezoid %s has more than 2 above neighbors", trapezoid)
@@ -276,7 +392,7 @@ func validateNeighborGraph(t *testing.T, graph *QueryGraph) {
 			// Check reflexivity
 			assert.Contains(t, neighbor.TrapezoidsAbove, trapezoid, "below neighbor %s does not have %s as an above neighbor", neighbor, trapezoid)
 			// Check graph connectivity
-			assert.Contains(t, trapezoids, neighbor, "below neighbor %s is not in the graph", neighbor)
+			assert.Contains(t, inGraph, neighbor, "below neighbor %s is not in the graph", neighbor)
 			count++
 		}
 		assert.LessOrEqual(t, count, 2, "trapezoid %s has more than 2 below neighbors", trapezoid)
@@ -316,3 +432,643 @@ func validateGraphBySampling(t *testing.T, graph *QueryGraph, list PolygonList)
 		}
 	}
 }
+
+// Exhaustively compare against the even/odd rule at every integer point near an
+// integer-coordinate shape. Many of these points share a Y value with vertices
+// of unrelated rings, which is where the lexicographic tie-break can go wrong.
+func TestContainsPoint_IntegerGrid(t *testing.T) {
+	list := PolygonList{
+		// Solid with a notch, containing a square hole and a triangular hole
+		{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 4}, {X: 6, Y: 4}, {X: 6, Y: 8}, {X: 10, Y: 8}, {X: 10, Y: 12}, {X: 0, Y: 12}}},
+		{[]*Point{{X: 2, Y: 2}, {X: 2, Y: 6}, {X: 4, Y: 6}, {X: 4, Y: 2}}},
+		{[]*Point{{X: 2, Y: 8}, {X: 2, Y: 10}, {X: 4, Y: 8}}},
+		// Square with a triangular hole off to the right
+		{[]*Point{{X: 14, Y: 2}, {X: 20, Y: 2}, {X: 20, Y: 10}, {X: 14, Y: 10}}},
+		{[]*Point{{X: 16, Y: 4}, {X: 16, Y: 8}, {X: 18, Y: 6}}},
+		// Disjoint shapes sharing rows with the others
+		{[]*Point{{X: 3, Y: 14}, {X: 8, Y: 14}, {X: 5, Y: 18}}},
+		{[]*Point{{X: 12, Y: 14}, {X: 16, Y: 12}, {X: 20, Y: 14}, {X: 16, Y: 18}}},
+		{[]*Point{{X: -4, Y: 0}, {X: -2, Y: 4}, {X: -4, Y: 8}, {X: -6, Y: 4}}},
+	}
+
+	// Rotating the starting vertex of each ring changes the insertion order, so
+	// try several to exercise different graph structures.
+	for k := 0; k < 8; k++ {
+		rotated := make(PolygonList, len(list))
+		for i, poly := range list {
+			n := len(poly.Points)
+			points := make([]*Point, n)
+			for j := range points {
+				points[j] = poly.Points[(j+k*(i+1))%n]
+			}
+			rotated[i] = Polygon{points}
+		}
+
+		g := &QueryGraph{}
+		g.AddPolygons(rotated)
+		for y := -2; y <= 20; y++ {
+			for x := -8; x <= 22; x++ {
+				p := &Point{X: float64(x), Y: float64(y)}
+				if pointIsOnBoundary(rotated, p) {
+					assert.True(t, g.ContainsPointInclusive(p), "rotation %d, point %v", k, p)
+					assert.False(t, g.ContainsPointExclusive(p), "rotation %d, point %v", k, p)
+					continue
+				}
+				expected := rotated.ContainsPointByEvenOdd(p)
+				assert.Equal(t, expected, g.ContainsPoint(p), "rotation %d, point %v", k, p)
+				assert.Equal(t, expected, g.ContainsPointInclusive(p), "rotation %d, point %v", k, p)
+				assert.Equal(t, expected, g.ContainsPointExclusive(p), "rotation %d, point %v", k, p)
+			}
+		}
+	}
+}
+
+func TestContainsPoint_Boundary(t *testing.T) {
+	onBoundary := func(t *testing.T, list PolygonList, p *Point) {
+		graph := &QueryGraph{}
+		graph.AddPolygons(list)
+		assert.True(t, graph.ContainsPointInclusive(p), "%v", p)
+		assert.False(t, graph.ContainsPointExclusive(p), "%v", p)
+		// Within Epsilon counts, and points clear of the edges are classified as
+		// usual
+		for _, offset := range []Vector{{X: 1, Y: 0}, {X: -1, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: -1}} {
+			near := &Point{X: p.X + offset.X*Epsilon/2, Y: p.Y + offset.Y*Epsilon/2}
+			assert.True(t, graph.ContainsPointInclusive(near), "%v", near)
+			assert.False(t, graph.ContainsPointExclusive(near), "%v", near)
+			far := &Point{X: p.X + offset.X*1000*Epsilon, Y: p.Y + offset.Y*1000*Epsilon}
+			if !pointIsOnBoundary(list, far) {
+				assert.Equal(t, list.ContainsPointByEvenOdd(far), graph.ContainsPointInclusive(far), "%v", far)
+				assert.Equal(t, list.ContainsPointByEvenOdd(far), graph.ContainsPointExclusive(far), "%v", far)
+			}
+		}
+	}
+
+	// The square runs from -5 to 5, and its hole from -2 to 2
+	t.Run("vertical edge", func(t *testing.T) {
+		onBoundary(t, SquareWithHole(), &Point{X: 5, Y: 1.5})
+		onBoundary(t, SquareWithHole(), &Point{X: -2, Y: 0.5})
+	})
+	t.Run("horizontal edge", func(t *testing.T) {
+		onBoundary(t, SquareWithHole(), &Point{X: 2.5, Y: -5})
+		onBoundary(t, SquareWithHole(), &Point{X: 0.5, Y: 2})
+	})
+	t.Run("vertex", func(t *testing.T) {
+		for _, poly := range SquareWithHole() {
+			for i := range poly.Points {
+				// A copy, since the search would find the vertex itself by pointer
+				vertex := *poly.Points[i]
+				onBoundary(t, SquareWithHole(), &vertex)
+			}
+		}
+	})
+	t.Run("star reflex vertex", func(t *testing.T) {
+		star := SimpleStar()
+		classes := star[0].VertexClassification()
+		reflex := 0
+		for i, p := range star[0].Points {
+			if classes[i] != Reflex {
+				continue
+			}
+			reflex++
+			vertex := *p
+			onBoundary(t, star, &vertex)
+		}
+		assert.Equal(t, 5, reflex)
+	})
+
+	t.Run("shared edge", func(t *testing.T) {
+		// Two squares sharing their middle edge. Points on it are inside either
+		// way, but the outer edges still count.
+		a, b := &Point{X: 1, Y: 0}, &Point{X: 1, Y: 1}
+		list := PolygonList{
+			{[]*Point{{X: 0, Y: 0}, a, b, {X: 0, Y: 1}}},
+			{[]*Point{a, {X: 2, Y: 0}, {X: 2, Y: 1}, b}},
+		}
+		graph := &QueryGraph{}
+		graph.AddPolygons(list)
+		middle := &Point{X: 1, Y: 0.5}
+		assert.True(t, graph.ContainsPointInclusive(middle))
+		assert.True(t, graph.ContainsPointExclusive(middle))
+		onBoundary(t, list, &Point{X: 1.5, Y: 1})
+	})
+}
+
+// Check if a point lies on any edge of the polygons. Output for such points is
+// undefined, so grid tests need to skip them. This is exact, so it should only
+// be used with integer coordinates.
+func pointIsOnBoundary(list PolygonList, p *Point) bool {
+	for _, poly := range list {
+		for i, a := range poly.Points {
+			b := poly.Points[CircularIndex(i+1, len(poly.Points))]
+			cross := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+			if cross == 0 &&
+				p.X >= math.Min(a.X, b.X) && p.X <= math.Max(a.X, b.X) &&
+				p.Y >= math.Min(a.Y, b.Y) && p.Y <= math.Max(a.Y, b.Y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestAddSegment_Phases(t *testing.T) {
+	firstSegment := &Segment{
+		Start: &Point{X: 1, Y: 2},
+		End:   &Point{X: 10, Y: 10},
+	}
+	g := NewQueryGraph(firstSegment)
+	g.CheckInvariants = true
+
+	// A segment to the right of the first, whose endpoints are both new
+	segment := NewSegment(&Point{X: 8, Y: 3}, &Point{X: 9, Y: 8})
+	top, bottom := segment.Top(), segment.Bottom()
+
+	topTrapezoid := g.locateAndSplitEndpoint(top, bottom, searchStart{})
+	// The top point is new, so the trapezoid below it was split off
+	assert.Same(t, top, topTrapezoid.Top)
+	assert.Same(t, firstSegment, topTrapezoid.Left)
+	validateNeighborGraph(t, g)
+
+	// The trapezoid containing the bottom point is the one we just split off,
+	// so splitting it again leaves both endpoints on the same trapezoid
+	bottomTrapezoid := g.locateAndSplitEndpoint(bottom, top, searchStart{})
+	assert.Same(t, bottom, bottomTrapezoid.Bottom)
+	assert.Same(t, top, bottomTrapezoid.Top)
+
+	// Locating an endpoint that's already in the graph doesn't split anything
+	trapezoidCount := len(collectTrapezoids(g))
+	g.locateAndSplitEndpoint(bottom, top, searchStart{})
+	assert.Equal(t, trapezoidCount, len(collectTrapezoids(g)))
+
+	leftChain, rightChain := g.splitChainAlongSegment(segment, bottomTrapezoid)
+	require.Len(t, leftChain, 1)
+	require.Len(t, rightChain, 1)
+	assert.Same(t, segment, leftChain[0].Right)
+	assert.Same(t, firstSegment, leftChain[0].Left)
+	assert.Same(t, segment, rightChain[0].Left)
+	assert.Nil(t, rightChain[0].Right)
+
+	g.mergeChains(segment, leftChain, rightChain)
+	validateNeighborGraph(t, g)
+
+	// The graph now routes points on either side of the new segment
+	sink := g.FindPoint(DefaultDirectionalPoint(7, 5))
+	assert.Same(t, leftChain[0], sink.Inner.(SinkNode).Trapezoid)
+	sink = g.FindPoint(DefaultDirectionalPoint(20, 5))
+	assert.Same(t, rightChain[0], sink.Inner.(SinkNode).Trapezoid)
+}
+
+func TestAddSegment_MergesChains(t *testing.T) {
+	// A long segment, with a short one to its left. Adding the short segment
+	// splits the trapezoid right of it into three pieces, which must be merged
+	// back into one when the right chain is merged.
+	long := NewSegment(&Point{X: 10, Y: 0}, &Point{X: 10, Y: 10})
+	g := NewQueryGraph(long)
+	g.CheckInvariants = true
+	short := NewSegment(&Point{X: 0, Y: 4}, &Point{X: 0, Y: 6})
+	g.AddSegment(short)
+	validateNeighborGraph(t, g)
+
+	// Add a segment between the two, crossing the split rows
+	middle := NewSegment(&Point{X: 5, Y: 1}, &Point{X: 5, Y: 9})
+	top, bottom := middle.Top(), middle.Bottom()
+	g.locateAndSplitEndpoint(top, bottom, searchStart{})
+	bottomTrapezoid := g.locateAndSplitEndpoint(bottom, top, searchStart{})
+	leftChain, rightChain := g.splitChainAlongSegment(middle, bottomTrapezoid)
+	// Three rows: below the short segment, alongside it, and above it
+	assert.Len(t, leftChain, 3)
+	assert.Len(t, rightChain, 3)
+	g.mergeChains(middle, leftChain, rightChain)
+	validateNeighborGraph(t, g)
+
+	// The right side of the middle segment is bounded only by the long segment,
+	// so it should be a single trapezoid
+	sink := g.FindPoint(DefaultDirectionalPoint(7, 2))
+	rightTrapezoid := sink.Inner.(SinkNode).Trapezoid
+	assert.Same(t, middle, rightTrapezoid.Left)
+	assert.Same(t, long, rightTrapezoid.Right)
+	assert.Same(t, top, rightTrapezoid.Top)
+	assert.Same(t, bottom, rightTrapezoid.Bottom)
+	assert.Same(t, rightTrapezoid, g.FindPoint(DefaultDirectionalPoint(7, 8)).Inner.(SinkNode).Trapezoid)
+}
+
+func TestAddSegment_StartsLevelWithNeighborJunction(t *testing.T) {
+	// The last segment starts level with the point where two upper neighbors
+	// meet, so it meets their shared bottom exactly on the wall, and neither
+	// bottom intersection test passes.
+	a, b := &Point{X: -5, Y: -5}, &Point{X: 5, Y: -5}
+	c, d := &Point{X: -2, Y: -2}, &Point{X: 2, Y: -2}
+	g := NewQueryGraph(NewSegment(a, &Point{X: -5, Y: 5}))
+	g.CheckInvariants = true
+	g.AddSegment(NewSegment(b, c))
+	g.AddSegment(NewSegment(b, d))
+	segment := NewSegment(a, c)
+	g.AddSegment(segment)
+	validateNeighborGraph(t, g)
+
+	// The whole length of the segment must have been split
+	for _, y := range []float64{-4.5, -4, -3, -2.5} {
+		x := segment.SolveForX(y)
+		assert.Same(t, segment, g.FindPoint(DefaultDirectionalPoint(x+0.1, y)).Inner.(SinkNode).Trapezoid.Left, "right of segment at y=%v", y)
+		assert.Same(t, segment, g.FindPoint(DefaultDirectionalPoint(x-0.1, y)).Inner.(SinkNode).Trapezoid.Right, "left of segment at y=%v", y)
+	}
+}
+
+func TestIterateTrapezoids_MergedSinks(t *testing.T) {
+	// Build the same merge as above, where the right side of the middle segment
+	// merges three trapezoids into one
+	g := NewQueryGraph(NewSegment(&Point{X: 10, Y: 0}, &Point{X: 10, Y: 10}))
+	g.AddSegment(NewSegment(&Point{X: 0, Y: 4}, &Point{X: 0, Y: 6}))
+	g.AddSegment(NewSegment(&Point{X: 5, Y: 1}, &Point{X: 5, Y: 9}))
+
+	merged := g.FindPoint(DefaultDirectionalPoint(7, 2))
+	parents := 0
+	for node := range g.IterateGraph() {
+		for _, child := range node.Inner.ChildNodes() {
+			if child == merged {
+				parents++
+			}
+		}
+	}
+	assert.Greater(t, parents, 1, "merged sink should have several parents")
+
+	before := collectTrapezoids(g)
+
+	// Make the merged trapezoid reachable through a second sink node as well, as
+	// with a retired sink which still refers to it
+	trapezoid := merged.Inner.(SinkNode).Trapezoid
+	g.Root = &QueryNode{YNode{
+		Key:   &Point{X: 0, Y: -100},
+		Above: g.Root,
+		Below: &QueryNode{SinkNode{Trapezoid: trapezoid}},
+	}}
+
+	after := collectTrapezoids(g)
+	assert.ElementsMatch(t, before, after)
+	count := 0
+	for _, other := range after {
+		if other == trapezoid {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestAddSegment_CheckInvariants(t *testing.T) {
+	// All of the fixtures should build without violating any invariants
+	for name, list := range map[string]PolygonList{
+		"Spiral":            {*LoadFixture("spiral")},
+		"SimpleStar":        SimpleStar(),
+		"SquareWithHole":    SquareWithHole(),
+		"StarOutline":       StarOutline(),
+		"StarStripes":       StarStripes(),
+		"MultiLayeredHoles": MultiLayeredHoles(),
+	} {
+		list := list
+		t.Run(name, func(t *testing.T) {
+			g := &QueryGraph{CheckInvariants: true}
+			assert.NotPanics(t, func() {
+				g.AddPolygons(list)
+			})
+		})
+	}
+}
+
+// Whichever of a bowtie's crossing edges goes in second must be caught on its
+// way through the graph, rather than corrupting it
+func TestAddSegment_Crossing(t *testing.T) {
+	bowtie := []Point{{X: 0, Y: 0}, {X: 4, Y: 4}, {X: 4, Y: 0}, {X: 0, Y: 4}}
+	for rotation := range bowtie {
+		for seed := int64(0); seed < 8; seed++ {
+			points := make([]*Point, len(bowtie))
+			for i := range points {
+				p := bowtie[(i+rotation)%len(bowtie)]
+				points[i] = &p
+			}
+			graph := &QueryGraph{Seed: seed}
+			err := HandleTriangulatePanicRecover(func() (r interface{}) {
+				defer func() { r = recover() }()
+				graph.AddPolygon(Polygon{points})
+				return nil
+			}())
+			require.Error(t, err, "rotation %d, seed %d", rotation, seed)
+			assert.Regexp(t, `segment \(\d, \d\)-\(\d, \d\) intersects existing segment \(\d, \d\)-\(\d, \d\)`, err.Error())
+		}
+	}
+}
+
+func TestSegmentsCross(t *testing.T) {
+	horizontal := NewSegment(&Point{X: 0, Y: 0}, &Point{X: 4, Y: 0})
+	for name, test := range map[string]struct {
+		other   *Segment
+		crosses bool
+	}{
+		"crossing":              {NewSegment(&Point{X: 2, Y: -1}, &Point{X: 3, Y: 1}), true},
+		"ending on the line":    {NewSegment(&Point{X: 2, Y: 0}, &Point{X: 3, Y: 1}), false},
+		"ending within Epsilon": {NewSegment(&Point{X: 2, Y: -Epsilon / 2}, &Point{X: 3, Y: 1}), false},
+		"ending past Epsilon":   {NewSegment(&Point{X: 2, Y: -2 * Epsilon}, &Point{X: 3, Y: 1}), true},
+		"beside":                {NewSegment(&Point{X: 5, Y: -1}, &Point{X: 6, Y: 1}), false},
+		"sharing an end":        {NewSegment(horizontal.End, &Point{X: 5, Y: 1}), false},
+	} {
+		assert.Equal(t, test.crosses, segmentsCross(horizontal, test.other), name)
+		assert.Equal(t, test.crosses, segmentsCross(test.other, horizontal), name)
+	}
+}
+
+func TestInvariantChecks(t *testing.T) {
+	segment := NewSegment(&Point{X: 0, Y: 0}, &Point{X: 0, Y: 10})
+	otherSegment := NewSegment(&Point{X: 5, Y: 0}, &Point{X: 5, Y: 10})
+
+	assertViolation := func(t *testing.T, phase string, fn func()) {
+		defer func() {
+			err := HandleTriangulatePanicRecover(recover())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invariant violated after "+phase)
+		}()
+		fn()
+	}
+
+	t.Run("endpoint not on trapezoid", func(t *testing.T) {
+		trapezoid := &Trapezoid{Top: &Point{X: 0, Y: 10}, Bottom: &Point{X: 0, Y: 0}}
+		assertViolation(t, "locateAndSplitEndpoint", func() {
+			checkLocatedEndpoint(&Point{X: 3, Y: 3}, trapezoid)
+		})
+	})
+
+	t.Run("chain missing segment", func(t *testing.T) {
+		left := &Trapezoid{Right: otherSegment}
+		right := &Trapezoid{Left: segment}
+		assertViolation(t, "splitChainAlongSegment", func() {
+			checkSplitChains(segment, []*Trapezoid{left}, []*Trapezoid{right})
+		})
+	})
+
+	t.Run("mismatched chains", func(t *testing.T) {
+		left := &Trapezoid{Right: segment}
+		assertViolation(t, "splitChainAlongSegment", func() {
+			checkSplitChains(segment, []*Trapezoid{left}, nil)
+		})
+	})
+
+	t.Run("split sink not replaced", func(t *testing.T) {
+		node := &QueryNode{SinkNode{}}
+		assertViolation(t, "mergeChains", func() {
+			checkMergedChains(segment, []*QueryNode{node}, nil)
+		})
+	})
+
+	t.Run("too many neighbors", func(t *testing.T) {
+		trapezoid := &Trapezoid{Left: segment}
+		trapezoid.Sink = &QueryNode{SinkNode{Trapezoid: trapezoid}}
+		trapezoid.TrapezoidsAbove = TrapezoidNeighborList{{}, {}, {}}
+		assertViolation(t, "mergeChains", func() {
+			checkMergedChains(segment, nil, []*Trapezoid{trapezoid})
+		})
+	})
+}
+
+// A tracer which breaks the map as a segment's top endpoint splits its
+// trapezoid, to plant invariant violations for InvariantInterval to find. It
+// gets the half above the endpoint, which the segment doesn't cross, so
+// nothing else in adding the segment changes it.
+type plantingTracer struct {
+	recordingTracer
+	at    *Point
+	plant func(above *Trapezoid)
+}
+
+func (tracer *plantingTracer) TrapezoidSplitHorizontally(original *Trapezoid, point *Point, top, bottom *Trapezoid) {
+	if point == tracer.at {
+		tracer.plant(top)
+	}
+}
+
+func TestInvariantInterval(t *testing.T) {
+	// A ring, and then the first edge of a hole, whose endpoints are new, and
+	// sit between the ring's top and bottom
+	ring := []*Point{{X: 0, Y: 0}, {X: 20, Y: 1}, {X: 21, Y: 20}, {X: 1, Y: 19}}
+	hole := NewSegment(&Point{X: 8, Y: 9}, &Point{X: 12, Y: 11})
+	add := func(interval int, plant func(above *Trapezoid)) (err error) {
+		graph := &QueryGraph{InvariantInterval: interval, Tracer: &plantingTracer{at: hole.End, plant: plant}}
+		defer func() { err = HandleTriangulatePanicRecover(recover()) }()
+		for i := range ring {
+			graph.AddSegment(NewSegment(ring[i], ring[CircularIndex(i+1, len(ring))]))
+		}
+		graph.AddSegment(hole)
+		return nil
+	}
+	require.NoError(t, add(1, func(*Trapezoid) {}))
+
+	for _, violation := range []struct {
+		name, message string
+		plant         func(above *Trapezoid)
+	}{
+		{"sink", "is not its sink's trapezoid", func(above *Trapezoid) {
+			above.Sink = &QueryNode{SinkNode{Trapezoid: &Trapezoid{}}}
+		}},
+		{"too many neighbors", "has 3 neighbors above", func(above *Trapezoid) {
+			require.Equal(t, 1, above.TrapezoidsAbove.Count())
+			above.TrapezoidsAbove.Add(&Trapezoid{})
+			above.TrapezoidsAbove.Add(&Trapezoid{Top: above.Top})
+		}},
+		{"non-reflexive", "which doesn't list it in return", func(above *Trapezoid) {
+			above.TrapezoidsAbove.AnyNeighbor().TrapezoidsBelow.Remove(above)
+		}},
+		{"another height", "at another height", func(above *Trapezoid) {
+			top := *above.Top
+			above.Top = &top
+		}},
+		{"no overlap", "which it doesn't overlap", func(above *Trapezoid) {
+			// A trapezoid above it with no width, where the ring's left side
+			// passes
+			left := above.Left
+			phantom := &Trapezoid{Left: left, Right: left, Bottom: above.Top}
+			phantom.Sink = &QueryNode{SinkNode{Trapezoid: phantom}}
+			phantom.TrapezoidsBelow.Add(above)
+			above.TrapezoidsAbove.Add(phantom)
+		}},
+	} {
+		t.Run(violation.name, func(t *testing.T) {
+			// The hole's edge is the fifth segment, with index 4
+			err := add(1, violation.plant)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invariant violated after adding segment 4, segment from {8.00, 9.00} to {12.00, 11.00}")
+			assert.Contains(t, err.Error(), violation.message)
+			assert.Error(t, add(5, violation.plant))
+			// Only every other segment is checked, which skips it
+			assert.NoError(t, add(2, violation.plant))
+		})
+	}
+}
+
+func TestInvariantInterval_Fixtures(t *testing.T) {
+	for name, fixture := range allFixtures() {
+		assert.NoError(t, triangulateRecovering(fixture(), Options{InvariantInterval: 1}), name)
+	}
+	assert.NoError(t, triangulateRecovering(PolygonList{circlePolygon(10, 1000)}, Options{InvariantInterval: 7}))
+}
+
+func collectTrapezoids(g *QueryGraph) []*Trapezoid {
+	var trapezoids []*Trapezoid
+	for trapezoid := range g.IterateTrapezoids() {
+		trapezoids = append(trapezoids, trapezoid)
+	}
+	return trapezoids
+}
+
+func BenchmarkAddPolygon_Circle(b *testing.B) {
+	poly := circlePolygon(100, 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g := &QueryGraph{}
+		g.AddPolygon(poly)
+	}
+}
+
+func TestAddPolygonWithSeed(t *testing.T) {
+	spiral := LoadFixture("spiral")
+	// The same seed gives the same map, and so the same triangles in the same
+	// order, whether it's given per call or on the graph
+	triangulateWith := func(add func(graph *QueryGraph)) []string {
+		graph := &QueryGraph{}
+		add(graph)
+		return triangleCoordinates(triangulateMonotones(graph.convertToMonotones(Options{}), Options{}, nil, nil))
+	}
+	withSeed := triangulateWith(func(graph *QueryGraph) { graph.AddPolygonWithSeed(*spiral, 5) })
+	assert.Equal(t, withSeed, triangulateWith(func(graph *QueryGraph) { graph.AddPolygonWithSeed(*spiral, 5) }))
+	assert.Equal(t, withSeed, triangulateWith(func(graph *QueryGraph) {
+		graph.Seed = 5
+		graph.AddPolygon(*spiral)
+	}))
+
+	for seed := int64(0); seed < 8; seed++ {
+		triangles := PolygonList{*spiral}.TriangulateWithOptions(Options{Seed: seed})
+		AssertValidTriangulation(t, spiral, triangles)
+		assert.Equal(t, triangleCoordinates(triangles), triangleCoordinates(PolygonList{*spiral}.TriangulateWithOptions(Options{Seed: seed})))
+	}
+}
+
+func TestAddPolygon_ErrorsNameSeed(t *testing.T) {
+	bowtie := func() Polygon {
+		return Polygon{[]*Point{{X: 0, Y: 0}, {X: 4, Y: 4}, {X: 4, Y: 0}, {X: 0, Y: 4}}}
+	}
+	addRecovering := func(add func(graph *QueryGraph)) (err error) {
+		defer func() { err = HandleTriangulatePanicRecover(recover()) }()
+		add(&QueryGraph{})
+		return nil
+	}
+
+	// A random seed can be read back from the error, and reproduces it
+	err := addRecovering(func(graph *QueryGraph) { graph.AddPolygon(bowtie(), true) })
+	require.Error(t, err)
+	var seed int64
+	_, scanErr := fmt.Sscanf(err.Error(), "with seed %d:", &seed)
+	require.NoError(t, scanErr, err.Error())
+	reproduced := addRecovering(func(graph *QueryGraph) { graph.AddPolygonWithSeed(bowtie(), seed) })
+	assert.Equal(t, err.Error(), fmt.Sprintf("with seed %d: %v", seed, reproduced))
+
+	err = triangulateRecovering(PolygonList{bowtie()}, Options{Seed: 7, SymmetricRetries: -1})
+	assert.Contains(t, err.Error(), "while building the trapezoid map with seed 7")
+}
+
+func TestPhaseEnd(t *testing.T) {
+	// n/log(n), n/log(log(n)), and so on, until the logarithm is down to 1
+	assert.Equal(t, 6021, phaseEnd(100000, 1))
+	assert.Equal(t, 24668, phaseEnd(100000, 2))
+	assert.Equal(t, 100000, phaseEnd(100000, 5))
+	for _, n := range []int{1, 2, 3, 10, 1000} {
+		previous := 0
+		for h := 1; previous < n; h++ {
+			end := phaseEnd(n, h)
+			assert.GreaterOrEqual(t, end, previous, "n=%d h=%d", n, h)
+			assert.LessOrEqual(t, end, n, "n=%d h=%d", n, h)
+			require.Less(t, h, 10, "phases never end for n=%d", n)
+			previous = end
+		}
+	}
+}
+
+func TestAddPolygon_Phases(t *testing.T) {
+	// The phases only change where searches start, so the map, its depths, and
+	// the decisions it notes are exactly those of searching from the root
+	shapes := map[string]PolygonList{
+		"spiral": {*LoadFixture("spiral")},
+		"circle": {circlePolygon(100, 5000)},
+		"holes":  MultiLayeredHoles(),
+	}
+	for name, list := range shapes {
+		build := func(unphased bool) *QueryGraph {
+			graph := &QueryGraph{Seed: 3, unphased: unphased}
+			graph.AddPolygons(list)
+			return graph
+		}
+		phased, unphased := build(false), build(true)
+		assert.Equal(t, unphased.QueryDepth(), phased.QueryDepth(), name)
+		assert.Equal(t, unphased.margins, phased.margins, name)
+		assert.Equal(t,
+			triangleCoordinates(triangulateMonotones(unphased.convertToMonotones(Options{}), Options{}, nil, nil)),
+			triangleCoordinates(triangulateMonotones(phased.convertToMonotones(Options{}), Options{}, nil, nil)),
+			name,
+		)
+	}
+}
+
+func TestFindPoint_Start(t *testing.T) {
+	graph := &QueryGraph{}
+	graph.AddPolygon(squareRing(0, 0, 10))
+	dp := DefaultDirectionalPoint(5, 5.5)
+	start := graph.FindPoint(dp)
+
+	// A hole around the point splits the sink which held it, which is still
+	// where its search can start
+	graph.AddPolygon(squareRing(4, 4, 2).Reverse())
+	_, stillSink := start.Inner.(SinkNode)
+	require.False(t, stillSink)
+	assert.Same(t, graph.FindPoint(dp), graph.FindPoint(dp, start))
+	assert.Same(t, graph.FindPoint(dp), graph.FindPoint(dp, nil))
+	assert.False(t, graph.ContainsPoint(dp.Point))
+}
+
+// Building the map of a 100k vertex circle, with and without the phases
+func BenchmarkAddPolygon_Phases(b *testing.B) {
+	poly := circlePolygon(100, 100000)
+	for _, unphased := range []bool{true, false} {
+		name := "phased"
+		if unphased {
+			name = "unphased"
+		}
+		b.Run(name, func(b *testing.B) {
+			var depth QueryDepthStats
+			for i := 0; i < b.N; i++ {
+				graph := &QueryGraph{unphased: unphased}
+				graph.AddPolygon(poly)
+				depth = graph.QueryDepth()
+			}
+			b.ReportMetric(depth.MeanDepth, "mean-depth")
+		})
+	}
+}
+
+func BenchmarkAddPolygons_Holes(b *testing.B) {
+	// 500 small square holes in one big square
+	list := PolygonList{squareRing(0, 0, 100)}
+	for row := 0; row < 20; row++ {
+		for col := 0; col < 25; col++ {
+			list = append(list, squareRing(float64(4*col)+1, float64(5*row)+1, 1).Reverse())
+		}
+	}
+	b.Run("together", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			graph := &QueryGraph{}
+			graph.AddPolygons(list)
+		}
+	})
+	b.Run("one at a time", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			graph := &QueryGraph{}
+			for j, poly := range list {
+				graph.addPolygon(poly, j, graph.Seed, nil)
+			}
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package advanced
+
+// A Tracer receives each change a QueryGraph makes to its trapezoid map while
+// segments are added, for debugging and visualizing the build. The trapezoids
+// passed are the graph's own, so a tracer must not modify them, and should copy
+// whatever it wants to keep, since they change as later segments go in. See
+// QueryGraph.Tracer and Options.Tracer.
+type Tracer interface {
+	// A segment is about to be added
+	SegmentAdded(segment *Segment)
+	// A trapezoid was split at the height of a point into top and bottom
+	TrapezoidSplitHorizontally(original *Trapezoid, point *Point, top, bottom *Trapezoid)
+	// A trapezoid was split along a segment into left and right
+	TrapezoidSplitBySegment(original *Trapezoid, segment *Segment, left, right *Trapezoid)
+	// Consecutive trapezoids along one side of a segment, from bottom to top,
+	// were merged into one
+	TrapezoidsMerged(segment *Segment, chunk []*Trapezoid, merged *Trapezoid)
+}
@@ -0,0 +1,78 @@
+package advanced
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingTracer struct {
+	segments         []*Segment
+	horizontalSplits int
+	segmentSplits    int
+	merges           int
+	problems         []string
+}
+
+func (r *recordingTracer) SegmentAdded(segment *Segment) {
+	r.segments = append(r.segments, segment)
+}
+
+func (r *recordingTracer) TrapezoidSplitHorizontally(original *Trapezoid, point *Point, top, bottom *Trapezoid) {
+	r.horizontalSplits++
+	if top.Bottom != point || bottom.Top != point || top.Top != original.Top || bottom.Bottom != original.Bottom {
+		r.problems = append(r.problems, "horizontal split doesn't divide the original at the point")
+	}
+}
+
+func (r *recordingTracer) TrapezoidSplitBySegment(original *Trapezoid, segment *Segment, left, right *Trapezoid) {
+	r.segmentSplits++
+	if left.Right != segment || right.Left != segment || left.Left != original.Left || right.Right != original.Right {
+		r.problems = append(r.problems, "segment split doesn't divide the original along the segment")
+	}
+}
+
+func (r *recordingTracer) TrapezoidsMerged(segment *Segment, chunk []*Trapezoid, merged *Trapezoid) {
+	r.merges++
+	if len(chunk) < 2 || merged.Bottom != chunk[0].Bottom || merged.Top != chunk[len(chunk)-1].Top {
+		r.problems = append(r.problems, "merge doesn't span its chunk")
+	}
+}
+
+func TestTracer_AddPolygon(t *testing.T) {
+	spiral := LoadFixture("spiral")
+	tracer := &recordingTracer{}
+	graph := &QueryGraph{Tracer: tracer}
+	graph.AddPolygon(*spiral)
+
+	assert.Len(t, tracer.segments, len(spiral.Points))
+	assert.NotZero(t, tracer.horizontalSplits)
+	assert.NotZero(t, tracer.segmentSplits)
+	assert.NotZero(t, tracer.merges)
+	assert.Empty(t, tracer.problems)
+}
+
+func TestTracer_Options(t *testing.T) {
+	tracer := &recordingTracer{}
+	SquareWithHole().TriangulateWithOptions(Options{Tracer: tracer})
+	assert.Len(t, tracer.segments, SquareWithHole().vertexCount())
+	assert.NotZero(t, tracer.segmentSplits)
+}
+
+func TestTriangulate_SpiralIsQuiet(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = writer
+	func() {
+		defer func() { os.Stdout = stdout }()
+		PolygonList{*LoadFixture("spiral")}.Triangulate()
+	}()
+	require.NoError(t, writer.Close())
+	output, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Empty(t, string(output))
+}
@@ -1,9 +1,8 @@
 package advanced
 
 // Node for the query structure. The query structure allows us to navigate the
-// trapezoid set efficiently, and can be built in O(nlog(n)) time. (TODO: There
-// is a preprocessing loop you can use to get this to O(nlog*n) time. Implement
-// this once tests are passing).
+// trapezoid set efficiently, and can be built in O(nlog*(n)) time, with the
+// phases in querygraph_phases.go.
 //
 // This algorithm has been chosen because it has good asymptotic performance,
 // and handles holes without special casing. In fact, it is rare in that you can
@@ -42,13 +41,28 @@ type QueryNode struct {
 }
 
 func (n *QueryNode) FindPoint(dp DirectionalPoint) *QueryNode {
-	// If we found a sink node, we're done
-	if _, ok := n.Inner.(SinkNode); ok {
-		return n
-	}
+	sink, _ := n.findPoint(dp, nil)
+	return sink
+}
 
-	// For other node types, ask the inner node to search its children
-	return n.Inner.FindPoint(dp)
+// Descend to the sink whose trapezoid contains the point, also returning the
+// number of steps taken. This is a loop rather than a recursion, since
+// unlucky insertion orders can make the graph very deep. The decisions made on
+// the way are noted in margins, which may be nil.
+func (n *QueryNode) findPoint(dp DirectionalPoint, margins *marginTracker) (sink *QueryNode, depth int) {
+	for {
+		switch inner := n.Inner.(type) {
+		case SinkNode:
+			return n, depth
+		case YNode:
+			n = inner.child(dp, margins)
+		case XNode:
+			n = inner.child(dp, margins)
+		default:
+			fatalf("unknown query node type %T", inner)
+		}
+		depth++
+	}
 }
 
 func (n *QueryNode) ChildNodes() []*QueryNode {
@@ -80,6 +94,11 @@ type YNode struct {
 }
 
 func (node YNode) FindPoint(dp DirectionalPoint) *QueryNode {
+	return node.child(dp, nil).FindPoint(dp)
+}
+
+// Choose the child on the point's side of the key
+func (node YNode) child(dp DirectionalPoint, margins *marginTracker) *QueryNode {
 	var direction YDirection
 	// For equal points, we must use the direction given
 	// Note that this only applies when directly comparing vertices, so pointer
@@ -97,7 +116,7 @@ func (node YNode) FindPoint(dp DirectionalPoint) *QueryNode {
 		} else {
 			direction = Down
 		}
-	} else if dp.Point.Below(node.Key) {
+	} else if dp.Point.below(node.Key, margins) {
 		direction = Down
 	} else {
 		direction = Up
@@ -105,9 +124,9 @@ func (node YNode) FindPoint(dp DirectionalPoint) *QueryNode {
 
 	switch direction {
 	case Up:
-		return node.Above.FindPoint(dp)
+		return node.Above
 	case Down:
-		return node.Below.FindPoint(dp)
+		return node.Below
 	}
 	fatalf("no direction found") // should be unreachable
 	return nil                   // certainly unreachable
@@ -124,6 +143,11 @@ type XNode struct {
 }
 
 func (node XNode) FindPoint(dp DirectionalPoint) *QueryNode {
+	return node.child(dp, nil).FindPoint(dp)
+}
+
+// Choose the child on the point's side of the key
+func (node XNode) child(dp DirectionalPoint, margins *marginTracker) *QueryNode {
 	var direction XDirection
 
 	// First check if it's an endpoint. If so, we use the direction vector to
@@ -138,12 +162,12 @@ func (node XNode) FindPoint(dp DirectionalPoint) *QueryNode {
 			X: dp.Point.X + dp.Direction.X,
 			Y: dp.Point.Y + dp.Direction.Y,
 		}
-		if node.Key.IsLeftOf(nudgedPoint) {
+		if node.Key.isLeftOf(nudgedPoint, margins) {
 			direction = Right
 		} else { // Note that there is no middle here; that would imply overlapping line segments.
 			direction = Left
 		}
-	} else if node.Key.IsLeftOf(dp.Point) {
+	} else if node.Key.isLeftOf(dp.Point, margins) {
 		direction = Right
 	} else {
 		direction = Left
@@ -151,9 +175,9 @@ func (node XNode) FindPoint(dp DirectionalPoint) *QueryNode {
 
 	switch direction {
 	case Left:
-		return node.Left.FindPoint(dp)
+		return node.Left
 	case Right:
-		return node.Right.FindPoint(dp)
+		return node.Right
 	}
 	fatalf("no direction found") // should be unreachable
 	return nil                   // certainly unreachable
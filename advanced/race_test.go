@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package advanced
+
+func init() {
+	raceEnabled = true
+}
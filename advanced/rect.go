@@ -0,0 +1,97 @@
+package advanced
+
+// An axis-aligned rectangle.
+type Rect struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Create a clockwise ring for the rectangle, suitable for use as a hole. The
+// points are freshly allocated, starting at the minimum corner, and have no
+// UserIndex.
+func (r Rect) Hole() Polygon {
+	return Polygon{Points: []*Point{
+		fabricatedPoint(r.MinX, r.MinY),
+		fabricatedPoint(r.MinX, r.MaxY),
+		fabricatedPoint(r.MaxX, r.MaxY),
+		fabricatedPoint(r.MaxX, r.MinY),
+	}}
+}
+
+// Is the rectangle degenerate or inverted?
+func (r Rect) IsEmpty() bool {
+	return !LessThan(r.MinX, r.MaxX) || !LessThan(r.MinY, r.MaxY)
+}
+
+// Check if the point is inside the rectangle or on its boundary.
+func (r Rect) ContainsPoint(p *Point) bool {
+	return p.X >= r.MinX && p.X <= r.MaxX && p.Y >= r.MinY && p.Y <= r.MaxY
+}
+
+// Check if two rectangles overlap or touch.
+func (r Rect) Overlaps(other Rect) bool {
+	return r.MinX <= other.MaxX && other.MinX <= r.MaxX &&
+		r.MinY <= other.MaxY && other.MinY <= r.MaxY
+}
+
+// Check if the segment from a to b touches the rectangle, including its
+// boundary. This clips the segment's parameter range against each slab of the
+// rectangle in turn.
+func (r Rect) IntersectsSegment(a, b *Point) bool {
+	tMin, tMax := 0.0, 1.0
+	clip := func(start, delta, min, max float64) bool {
+		if delta == 0 {
+			return start >= min && start <= max
+		}
+		t1 := (min - start) / delta
+		t2 := (max - start) / delta
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > tMin {
+			tMin = t1
+		}
+		if t2 < tMax {
+			tMax = t2
+		}
+		return tMin <= tMax
+	}
+	return clip(a.X, b.X-a.X, r.MinX, r.MaxX) && clip(a.Y, b.Y-a.Y, r.MinY, r.MaxY)
+}
+
+// Combine an outer polygon with rectangular holes. The outer polygon must be
+// counterclockwise. Each rectangle must be nonempty, strictly inside the outer
+// polygon, and must not touch any other rectangle. Otherwise, this throws an
+// InvalidRectError, RectOutsideError, or RectOverlapError.
+func RectHoles(outer Polygon, holes []Rect) PolygonList {
+	for i, rect := range holes {
+		if rect.IsEmpty() {
+			throw(&InvalidRectError{Rect: i})
+		}
+
+		corner := &Point{X: rect.MinX, Y: rect.MinY}
+		if !outer.ContainsPointByEvenOdd(corner) {
+			throw(&RectOutsideError{Rect: i})
+		}
+		// With one corner inside, the rectangle is only inside if no edge of the
+		// outer polygon touches it
+		for j, p := range outer.Points {
+			next := outer.Points[CircularIndex(j+1, len(outer.Points))]
+			if rect.IntersectsSegment(p, next) {
+				throw(&RectOutsideError{Rect: i})
+			}
+		}
+
+		for j := 0; j < i; j++ {
+			if rect.Overlaps(holes[j]) {
+				throw(&RectOverlapError{First: j, Second: i})
+			}
+		}
+	}
+
+	list := make(PolygonList, 0, len(holes)+1)
+	list = append(list, outer)
+	for _, rect := range holes {
+		list = append(list, rect.Hole())
+	}
+	return list
+}
@@ -0,0 +1,105 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRectHoles(t *testing.T) {
+	outer := circlePolygon(20, 64)
+	holes := []Rect{
+		{-10, -2, -5, 2},
+		{-2, -2, 2, 2},
+		{5, 5, 8, 9},
+	}
+	list := RectHoles(outer, holes)
+	require.Len(t, list, 4)
+	for _, hole := range list[1:] {
+		assert.True(t, IsCW(&hole))
+	}
+
+	triangles := list.Triangulate()
+	validatePolygonsBySampling(t, triangles.ToPolygonList(), list)
+
+	expectedArea := outer.SignedArea()
+	for _, rect := range holes {
+		expectedArea -= (rect.MaxX - rect.MinX) * (rect.MaxY - rect.MinY)
+	}
+	var area float64
+	for _, tri := range triangles {
+		area += Area(tri)
+	}
+	assert.InDelta(t, expectedArea, area, 1e-6)
+}
+
+func TestRectHoles_Errors(t *testing.T) {
+	outer := circlePolygon(20, 64)
+	catch := func(holes []Rect) (err error) {
+		defer func() {
+			err = HandleTriangulatePanicRecover(recover())
+		}()
+		RectHoles(outer, holes)
+		return nil
+	}
+
+	t.Run("partially outside", func(t *testing.T) {
+		err := catch([]Rect{{-2, -2, 2, 2}, {15, -2, 25, 2}})
+		var outsideErr *RectOutsideError
+		require.ErrorAs(t, err, &outsideErr)
+		assert.Equal(t, 1, outsideErr.Rect)
+	})
+
+	t.Run("entirely outside", func(t *testing.T) {
+		err := catch([]Rect{{30, 30, 40, 40}})
+		var outsideErr *RectOutsideError
+		require.ErrorAs(t, err, &outsideErr)
+		assert.Equal(t, 0, outsideErr.Rect)
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		err := catch([]Rect{{-10, -10, -8, -8}, {-2, -2, 2, 2}, {1, 1, 5, 5}})
+		var overlapErr *RectOverlapError
+		require.ErrorAs(t, err, &overlapErr)
+		assert.Equal(t, 1, overlapErr.First)
+		assert.Equal(t, 2, overlapErr.Second)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		err := catch([]Rect{{2, 2, 2, 5}})
+		var invalidErr *InvalidRectError
+		require.ErrorAs(t, err, &invalidErr)
+	})
+}
+
+func TestRectIntersectsSegment(t *testing.T) {
+	rect := Rect{0, 0, 10, 10}
+	cases := []struct {
+		name     string
+		a, b     *Point
+		expected bool
+	}{
+		{"inside", &Point{X: 1, Y: 1}, &Point{X: 2, Y: 2}, true},
+		{"crossing", &Point{X: -5, Y: 5}, &Point{X: 15, Y: 5}, true},
+		{"diagonal clipping corner", &Point{X: -5, Y: 4}, &Point{X: 5, Y: 14}, true},
+		{"missing corner", &Point{X: -5, Y: 12}, &Point{X: 5, Y: 22}, false},
+		{"touching edge", &Point{X: 10, Y: -5}, &Point{X: 10, Y: 15}, true},
+		{"parallel outside", &Point{X: 11, Y: -5}, &Point{X: 11, Y: 15}, false},
+		{"stopping short", &Point{X: -5, Y: 5}, &Point{X: -1, Y: 5}, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, rect.IntersectsSegment(c.a, c.b), c.name)
+	}
+}
+
+// A regular polygon approximating a circle around the origin
+func circlePolygon(radius float64, n int) Polygon {
+	points := make([]*Point, n)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		points[i] = &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}
+	}
+	return Polygon{Points: points}
+}
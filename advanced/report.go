@@ -0,0 +1,257 @@
+package advanced
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// The version of ReportJSON's schema. It goes up whenever a field is added or
+// changes meaning. Fields are never removed or renamed, so a newer report can
+// always be read as an older one.
+const ReportSchemaVersion = 1
+
+// A summary of a triangulation, for pinning down its behavior in CI by
+// diffing reports rather than triangles, which change wholesale for any small
+// change in the input. It marshals to JSON with a stable schema; see
+// ReportSchemaVersion. Compare two reports with CompareReports.
+type ReportJSON struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// The number of triangles, and the number a triangulation of the input
+	// should have. See ExpectedTriangleCount.
+	Triangles         int `json:"triangles"`
+	ExpectedTriangles int `json:"expectedTriangles"`
+
+	// The total signed area of the triangles, the net signed area of the input
+	// rings, and the first less the second
+	Area            float64 `json:"area"`
+	InputArea       float64 `json:"inputArea"`
+	AreaDiscrepancy float64 `json:"areaDiscrepancy"`
+
+	// The shape of the triangles. Quality is 4√3 times a triangle's area over
+	// the sum of its squared side lengths, which is 1 for an equilateral
+	// triangle and 0 for a degenerate one. MinAngle is a triangle's smallest
+	// angle, in degrees.
+	Quality  ReportStats `json:"quality"`
+	MinAngle ReportStats `json:"minAngle"`
+
+	// The number of triangle edges on the boundary of the input, as
+	// PolygonList.BoundaryFlags finds them, and the number of edges the input
+	// has. Each input edge is usually one boundary edge, but can be split where
+	// another ring's vertex touches it.
+	BoundaryEdges int `json:"boundaryEdges"`
+	InputEdges    int `json:"inputEdges"`
+	// The number of input points which are in no triangle. Points are compared
+	// by pointer, so this is every point when the triangles were made with
+	// CanonicalizeOutputPoints or AutoNormalize.
+	UnusedPoints int `json:"unusedPoints"`
+
+	// Only filled in by ReportWithDiagnostics, and the stage hashes only when
+	// Options.HashStages was set
+	Confidence  *ReportConfidence  `json:"confidence,omitempty"`
+	StageHashes *ReportStageHashes `json:"stageHashes,omitempty"`
+}
+
+// The smallest, largest and mean value of a measure over the triangles. All
+// zero when there are none.
+type ReportStats struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// The counters from Diagnostics which say how close the triangulation came to
+// going differently. See Confidence.
+type ReportConfidence struct {
+	MarginalDecisions int     `json:"marginalDecisions"`
+	MinMargin         float64 `json:"minMargin"`
+	ExactEvaluations  int     `json:"exactEvaluations"`
+	GraphRebuilds     int     `json:"graphRebuilds"`
+	SymmetricRetries  int     `json:"symmetricRetries"`
+}
+
+// StageHashes, in hex, since JSON numbers can't hold every uint64 exactly
+type ReportStageHashes struct {
+	SegmentOrder string `json:"segmentOrder"`
+	TrapezoidMap string `json:"trapezoidMap"`
+	Monotones    string `json:"monotones"`
+	Triangles    string `json:"triangles"`
+}
+
+// Summarize a triangulation of the polygons. The triangles must share points
+// with the polygons, as they do unless CanonicalizeOutputPoints or
+// AutoNormalize created new points.
+func Report(triangles TriangleList, polygons PolygonList) ReportJSON {
+	report := ReportJSON{
+		SchemaVersion:     ReportSchemaVersion,
+		Triangles:         len(triangles),
+		ExpectedTriangles: int(ExpectedTriangleCount(polygons)),
+	}
+
+	used := make(PointSet)
+	for _, triangle := range triangles {
+		report.Area += triangle.SignedArea()
+		used.Add(triangle.A)
+		used.Add(triangle.B)
+		used.Add(triangle.C)
+	}
+	for i := range polygons {
+		report.InputArea += polygons[i].SignedArea()
+		report.InputEdges += len(polygons[i].Points)
+		for _, p := range polygons[i].Points {
+			if !used.Contains(p) {
+				report.UnusedPoints++
+			}
+		}
+	}
+	report.AreaDiscrepancy = report.Area - report.InputArea
+
+	for _, flags := range polygons.BoundaryFlags(triangles) {
+		for _, flag := range flags {
+			if flag {
+				report.BoundaryEdges++
+			}
+		}
+	}
+
+	report.Quality = reportStats(triangles, triangleQuality)
+	report.MinAngle = reportStats(triangles, triangleMinAngle)
+	return report
+}
+
+// Same as Report, but also fill in the confidence counters and stage hashes
+// from the diagnostics of the triangulation, which may be nil
+func ReportWithDiagnostics(triangles TriangleList, polygons PolygonList, diagnostics *Diagnostics) ReportJSON {
+	report := Report(triangles, polygons)
+	if diagnostics == nil {
+		return report
+	}
+	report.Confidence = &ReportConfidence{
+		MarginalDecisions: diagnostics.Confidence.MarginalDecisions,
+		MinMargin:         diagnostics.Confidence.MinMargin,
+		ExactEvaluations:  diagnostics.ExactEvaluations,
+		GraphRebuilds:     diagnostics.GraphRebuilds,
+		SymmetricRetries:  diagnostics.SymmetricRetries,
+	}
+	if hashes := diagnostics.Hashes; hashes != (StageHashes{}) {
+		report.StageHashes = &ReportStageHashes{
+			SegmentOrder: fmt.Sprintf("%016x", hashes.SegmentOrder),
+			TrapezoidMap: fmt.Sprintf("%016x", hashes.TrapezoidMap),
+			Monotones:    fmt.Sprintf("%016x", hashes.Monotones),
+			Triangles:    fmt.Sprintf("%016x", hashes.Triangles),
+		}
+	}
+	return report
+}
+
+func reportStats(triangles TriangleList, measure func(*Triangle) float64) ReportStats {
+	if len(triangles) == 0 {
+		return ReportStats{}
+	}
+	stats := ReportStats{Min: math.Inf(1), Max: math.Inf(-1)}
+	var sum float64
+	for _, triangle := range triangles {
+		value := measure(triangle)
+		stats.Min = math.Min(stats.Min, value)
+		stats.Max = math.Max(stats.Max, value)
+		sum += value
+	}
+	stats.Mean = sum / float64(len(triangles))
+	return stats
+}
+
+func triangleQuality(t *Triangle) float64 {
+	squares := squaredDistance(t.A, t.B) + squaredDistance(t.B, t.C) + squaredDistance(t.C, t.A)
+	if squares == 0 {
+		return 0
+	}
+	return 4 * math.Sqrt(3) * math.Abs(t.SignedArea()) / squares
+}
+
+func triangleMinAngle(t *Triangle) float64 {
+	angle := func(p, a, b *Point) float64 {
+		ax, ay, bx, by := a.X-p.X, a.Y-p.Y, b.X-p.X, b.Y-p.Y
+		return math.Abs(math.Atan2(ax*by-ay*bx, ax*bx+ay*by))
+	}
+	smallest := math.Min(angle(t.A, t.B, t.C), math.Min(angle(t.B, t.C, t.A), angle(t.C, t.A, t.B)))
+	return smallest * 180 / math.Pi
+}
+
+func squaredDistance(a, b *Point) float64 {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return dx*dx + dy*dy
+}
+
+// How far apart two reports' float fields may be before CompareReports calls
+// them different. A pair of values a and b differs when |a - b| is more than
+// Absolute + Relative·max(|a|, |b|). With both zero, floats must be equal.
+// Other fields must always be equal.
+type ReportTolerances struct {
+	Absolute float64
+	Relative float64
+}
+
+// A field which differs between two reports, named by its path in the JSON,
+// such as "quality.min", with each report's value
+type Difference struct {
+	Field string
+	A, B  string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s, then %s", d.Field, d.A, d.B)
+}
+
+// The fields which differ between two reports, in schema order. A section
+// which only one report has, such as the stage hashes, is one difference.
+func CompareReports(a, b ReportJSON, tolerances ReportTolerances) []Difference {
+	var differences []Difference
+	compareReportValues("", reflect.ValueOf(a), reflect.ValueOf(b), tolerances, &differences)
+	return differences
+}
+
+func compareReportValues(path string, a, b reflect.Value, tolerances ReportTolerances, differences *[]Difference) {
+	differ := func() {
+		*differences = append(*differences, Difference{Field: path, A: describeReportValue(a), B: describeReportValue(b)})
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				differ()
+			}
+			return
+		}
+		compareReportValues(path, a.Elem(), b.Elem(), tolerances, differences)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			name := strings.Split(a.Type().Field(i).Tag.Get("json"), ",")[0]
+			if path != "" {
+				name = path + "." + name
+			}
+			compareReportValues(name, a.Field(i), b.Field(i), tolerances, differences)
+		}
+	case reflect.Float64:
+		x, y := a.Float(), b.Float()
+		if math.IsNaN(x) || math.IsNaN(y) {
+			if math.IsNaN(x) != math.IsNaN(y) {
+				differ()
+			}
+		} else if math.Abs(x-y) > tolerances.Absolute+tolerances.Relative*math.Max(math.Abs(x), math.Abs(y)) {
+			differ()
+		}
+	default:
+		if a.Interface() != b.Interface() {
+			differ()
+		}
+	}
+}
+
+func describeReportValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return "absent"
+	}
+	return fmt.Sprint(reflect.Indirect(v).Interface())
+}
@@ -0,0 +1,126 @@
+package advanced
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportGoldenFile = "fixtures/report_golden.json"
+
+func TestReport(t *testing.T) {
+	list := SquareWithHole()
+	triangles := list.Triangulate()
+	report := Report(triangles, list)
+	assert.Equal(t, ReportSchemaVersion, report.SchemaVersion)
+	assert.Equal(t, 8, report.Triangles)
+	assert.Equal(t, 8, report.ExpectedTriangles)
+	assert.InDelta(t, 84, report.Area, 1e-9)
+	assert.InDelta(t, 84, report.InputArea, 1e-9)
+	assert.InDelta(t, 0, report.AreaDiscrepancy, 1e-9)
+	assert.Equal(t, 8, report.BoundaryEdges)
+	assert.Equal(t, 8, report.InputEdges)
+	assert.Zero(t, report.UnusedPoints)
+	assert.True(t, 0 < report.Quality.Min && report.Quality.Min <= report.Quality.Mean && report.Quality.Mean <= report.Quality.Max && report.Quality.Max <= 1)
+	assert.True(t, 0 < report.MinAngle.Min && report.MinAngle.Max <= 60)
+	assert.Nil(t, report.Confidence)
+	assert.Nil(t, report.StageHashes)
+
+	// An equilateral triangle is as good as triangles get
+	equilateral := &Triangle{&Point{X: 0, Y: 0}, &Point{X: 2, Y: 0}, &Point{X: 1, Y: math.Sqrt(3)}}
+	assert.InDelta(t, 1, triangleQuality(equilateral), 1e-12)
+	assert.InDelta(t, 60, triangleMinAngle(equilateral), 1e-9)
+
+	// A welded vertex is in no triangle, and neither of its edges is on the
+	// boundary
+	square := squareRing(0, 0, 10)
+	duplicate := &Point{X: 10 + 1e-9, Y: 10}
+	welded := PolygonList{{[]*Point{square.Points[0], square.Points[1], square.Points[2], duplicate, square.Points[3]}}}
+	report = Report(welded.TriangulateWithOptions(Options{WeldTolerance: 1e-6}), welded)
+	assert.Equal(t, 1, report.UnusedPoints)
+	assert.Equal(t, 5, report.InputEdges)
+	assert.Equal(t, 3, report.BoundaryEdges)
+	assert.Equal(t, 1, report.ExpectedTriangles-report.Triangles)
+}
+
+func TestReportWithDiagnostics(t *testing.T) {
+	list := SquareWithHole()
+	var diagnostics Diagnostics
+	triangles := list.TriangulateWithOptions(Options{Diagnostics: &diagnostics, HashStages: true})
+	report := ReportWithDiagnostics(triangles, list, &diagnostics)
+	require.NotNil(t, report.Confidence)
+	assert.Equal(t, diagnostics.Confidence.MarginalDecisions, report.Confidence.MarginalDecisions)
+	require.NotNil(t, report.StageHashes)
+	assert.Len(t, report.StageHashes.Triangles, 16)
+
+	// Without hashes, there's no section for them
+	diagnostics.Hashes = StageHashes{}
+	assert.Nil(t, ReportWithDiagnostics(triangles, list, &diagnostics).StageHashes)
+	assert.Equal(t, Report(triangles, list), ReportWithDiagnostics(triangles, list, nil))
+}
+
+// The report of the square with a hole is locked down by a golden file, which
+// also pins the schema: every field in it must still exist. Floats are
+// compared with a tolerance, so that rounding differences between platforms
+// don't fail it. To accept a deliberate change, run this test with
+// TRIANGULATE_UPDATE_GOLDEN=1.
+func TestReport_Golden(t *testing.T) {
+	list := SquareWithHole()
+	var diagnostics Diagnostics
+	triangles := list.TriangulateWithOptions(Options{Diagnostics: &diagnostics, HashStages: true})
+	// The exact evaluation count is kept package-wide, so other tests running
+	// at the same time would leak into it
+	diagnostics.ExactEvaluations = 0
+	actual := ReportWithDiagnostics(triangles, list, &diagnostics)
+
+	if os.Getenv("TRIANGULATE_UPDATE_GOLDEN") != "" {
+		encoded, err := json.MarshalIndent(actual, "", "  ")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(reportGoldenFile, append(encoded, '\n'), 0644))
+		t.Skip("updated the golden file; rerun without TRIANGULATE_UPDATE_GOLDEN")
+	}
+	golden, err := fixtures.ReadFile(reportGoldenFile)
+	require.NoError(t, err)
+	decoder := json.NewDecoder(bytes.NewReader(golden))
+	decoder.DisallowUnknownFields()
+	var expected ReportJSON
+	require.NoError(t, decoder.Decode(&expected), "a field in the golden report no longer exists")
+	assert.Empty(t, CompareReports(expected, actual, ReportTolerances{Absolute: 1e-9, Relative: 1e-9}))
+}
+
+func TestCompareReports(t *testing.T) {
+	list := SquareWithHole()
+	var diagnostics Diagnostics
+	triangles := list.TriangulateWithOptions(Options{Diagnostics: &diagnostics, HashStages: true})
+	a := ReportWithDiagnostics(triangles, list, &diagnostics)
+	assert.Empty(t, CompareReports(a, a, ReportTolerances{}))
+
+	b := a
+	b.Area += 1e-6
+	b.Quality.Min += 5e-4
+	b.BoundaryEdges++
+	confidence := *a.Confidence
+	confidence.MarginalDecisions++
+	b.Confidence = &confidence
+	b.StageHashes = nil
+	differences := CompareReports(a, b, ReportTolerances{Absolute: 1e-3})
+	var fields []string
+	for _, difference := range differences {
+		fields = append(fields, difference.Field)
+	}
+	assert.Equal(t, []string{"boundaryEdges", "confidence.marginalDecisions", "stageHashes"}, fields)
+	assert.Equal(t, "absent", differences[2].B)
+
+	// Floats are compared with the tolerances, which can be relative
+	fields = nil
+	for _, difference := range CompareReports(a, b, ReportTolerances{Relative: 1e-3}) {
+		fields = append(fields, difference.Field)
+	}
+	assert.Equal(t, []string{"quality.min", "boundaryEdges", "confidence.marginalDecisions", "stageHashes"}, fields)
+	assert.Len(t, CompareReports(a, b, ReportTolerances{}), 5)
+}
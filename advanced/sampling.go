@@ -0,0 +1,137 @@
+package advanced
+
+import (
+	"math"
+	"sort"
+)
+
+// How well a triangulation agrees with its input, found by sampling points on
+// a grid. See PolygonList.CompareBySampling.
+type SamplingReport struct {
+	// The number of points sampled
+	Samples int
+	// The sampled points where the triangles and the input disagree, in order of
+	// Y, then X
+	Disagreements []SampleDisagreement
+}
+
+// A sampled point where a triangulation disagrees with its input
+type SampleDisagreement struct {
+	Point Point
+	// Whether the point is inside the input, by the even-odd rule
+	Inside bool
+	// The number of triangles covering the point. Inside the input this should
+	// be one, and outside it should be zero.
+	Coverage int
+}
+
+// The fraction of samples where the triangles and the input agree, from 0 to 1.
+// With no samples, this is 1.
+func (r SamplingReport) Agreement() float64 {
+	if r.Samples == 0 {
+		return 1
+	}
+	return 1 - float64(len(r.Disagreements))/float64(r.Samples)
+}
+
+// Compare a triangulation with the input it came from, by sampling a grid of
+// points over both, with density points along the longer side of their
+// bounding box. A point agrees when it's covered by exactly one triangle and is
+// inside the input by the even-odd rule, or is covered by no triangles and is
+// outside the input. Like ContainsPointByEvenOdd, this ignores windings, so
+// input which relies on them, such as under FillRule NonZero, can disagree.
+//
+// The grid is offset by irrational fractions of its spacing, so that samples
+// don't land on the edges of grid aligned input, where the answer would be
+// ambiguous.
+func (l PolygonList) CompareBySampling(triangles TriangleList, density int) SamplingReport {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	extend := func(p *Point) {
+		minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+		maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+	}
+	for _, poly := range l {
+		for _, p := range poly.Points {
+			extend(p)
+		}
+	}
+	for _, triangle := range triangles {
+		extend(triangle.A)
+		extend(triangle.B)
+		extend(triangle.C)
+	}
+	if density < 1 || minX > maxX {
+		return SamplingReport{}
+	}
+
+	step := math.Max(maxX-minX, maxY-minY) / float64(density)
+	if step == 0 {
+		return SamplingReport{}
+	}
+	// Start a step out, so that the edges of the box are sampled from outside
+	originX, originY := minX-step+step/math.Pi, minY-step+step/math.E
+	columns := int((maxX-originX)/step) + 2
+	rows := int((maxY-originY)/step) + 2
+	sampleX := func(column int) float64 { return originX + float64(column)*step }
+	sampleY := func(row int) float64 { return originY + float64(row)*step }
+
+	coverage := make([]int, rows*columns)
+	for _, triangle := range triangles {
+		a, b, c := triangle.A, triangle.B, triangle.C
+		firstColumn := int(math.Ceil((math.Min(a.X, math.Min(b.X, c.X)) - originX) / step))
+		lastColumn := int(math.Floor((math.Max(a.X, math.Max(b.X, c.X)) - originX) / step))
+		firstRow := int(math.Ceil((math.Min(a.Y, math.Min(b.Y, c.Y)) - originY) / step))
+		lastRow := int(math.Floor((math.Max(a.Y, math.Max(b.Y, c.Y)) - originY) / step))
+		for row := firstRow; row <= lastRow; row++ {
+			for column := firstColumn; column <= lastColumn; column++ {
+				if triangleCovers(triangle, sampleX(column), sampleY(row)) {
+					coverage[row*columns+column]++
+				}
+			}
+		}
+	}
+
+	report := SamplingReport{Samples: rows * columns}
+	var crossings []float64
+	for row := 0; row < rows; row++ {
+		y := sampleY(row)
+		// Crossings of the row by the input's edges, so that the even-odd rule can
+		// be applied along the whole row at once
+		crossings = crossings[:0]
+		for _, poly := range l {
+			for i, p := range poly.Points {
+				next := poly.Points[CircularIndex(i+1, len(poly.Points))]
+				if (p.Y > y) != (next.Y > y) {
+					crossings = append(crossings, p.X+(y-p.Y)*(next.X-p.X)/(next.Y-p.Y))
+				}
+			}
+		}
+		sort.Float64s(crossings)
+		crossed := 0
+		for column := 0; column < columns; column++ {
+			x := sampleX(column)
+			for crossed < len(crossings) && crossings[crossed] < x {
+				crossed++
+			}
+			inside := crossed%2 == 1
+			count := coverage[row*columns+column]
+			if (inside && count != 1) || (!inside && count != 0) {
+				report.Disagreements = append(report.Disagreements, SampleDisagreement{
+					Point:    Point{X: x, Y: y},
+					Inside:   inside,
+					Coverage: count,
+				})
+			}
+		}
+	}
+	return report
+}
+
+// Is the point strictly inside the triangle, whichever way it's wound?
+func triangleCovers(t *Triangle, x, y float64) bool {
+	side := func(a, b *Point) float64 {
+		return float64((b.X-a.X)*(y-a.Y)) - float64((b.Y-a.Y)*(x-a.X))
+	}
+	ab, bc, ca := side(t.A, t.B), side(t.B, t.C), side(t.C, t.A)
+	return (ab > 0 && bc > 0 && ca > 0) || (ab < 0 && bc < 0 && ca < 0)
+}
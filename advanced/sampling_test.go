@@ -0,0 +1,61 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareBySampling_Agrees(t *testing.T) {
+	for _, list := range []PolygonList{
+		{squareRing(0, 0, 10)},
+		{squareRing(0, 0, 10), squareRing(3, 3, 4).Reverse()},
+		adjacentSquares(),
+	} {
+		report := list.CompareBySampling(list.Triangulate(), 50)
+		assert.Greater(t, report.Samples, 50)
+		assert.Empty(t, report.Disagreements)
+		assert.Equal(t, 1.0, report.Agreement())
+	}
+}
+
+func TestCompareBySampling_Disagrees(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10), squareRing(3, 3, 4).Reverse()}
+	triangles := list.Triangulate()
+
+	t.Run("missing", func(t *testing.T) {
+		report := list.CompareBySampling(triangles[1:], 50)
+		assert.NotEmpty(t, report.Disagreements)
+		assert.Less(t, report.Agreement(), 1.0)
+		for _, disagreement := range report.Disagreements {
+			assert.True(t, disagreement.Inside)
+			assert.Equal(t, 0, disagreement.Coverage)
+		}
+	})
+
+	t.Run("doubled", func(t *testing.T) {
+		report := list.CompareBySampling(append(triangles, triangles[0]), 50)
+		assert.NotEmpty(t, report.Disagreements)
+		for _, disagreement := range report.Disagreements {
+			assert.True(t, disagreement.Inside)
+			assert.Equal(t, 2, disagreement.Coverage)
+		}
+	})
+
+	t.Run("filled hole", func(t *testing.T) {
+		report := list.CompareBySampling(PolygonList{squareRing(0, 0, 10)}.Triangulate(), 50)
+		assert.NotEmpty(t, report.Disagreements)
+		for _, disagreement := range report.Disagreements {
+			assert.False(t, disagreement.Inside)
+			assert.Equal(t, 1, disagreement.Coverage)
+			p := disagreement.Point
+			assert.True(t, p.X > 3 && p.X < 7 && p.Y > 3 && p.Y < 7, "%v", p)
+		}
+	})
+}
+
+func TestCompareBySampling_Empty(t *testing.T) {
+	report := PolygonList{}.CompareBySampling(nil, 50)
+	assert.Equal(t, 0, report.Samples)
+	assert.Equal(t, 1.0, report.Agreement())
+}
@@ -0,0 +1,236 @@
+package advanced_test
+
+// The performance harness. This is an external test package, so that it can
+// build its inputs with testutil, which imports the advanced package.
+//
+// BenchmarkScaling times each stage of triangulation for families of inputs at
+// doubling sizes. TestScaling, which only runs with TRIANGULATE_PERF=1, times
+// the same inputs, and fails if the trapezoid map's build time grows faster
+// than maxGraphExponent, or if any stage has slowed down by more than a factor
+// of TRIANGULATE_PERF_FACTOR (2 by default) against the baseline recorded in
+// fixtures/scaling_baseline.txt. When a change is meant to change the timings,
+// record a new baseline by running it with TRIANGULATE_PERF_UPDATE=1.
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osuushi/triangulate/advanced"
+	"github.com/osuushi/triangulate/advanced/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	scalingBaselineFile = "fixtures/scaling_baseline.txt"
+	// The expected time is O(nlog*(n)), which fits an exponent a little over
+	// one at these sizes
+	maxGraphExponent = 1.3
+	// Stages quicker than this in the baseline are too noisy to compare
+	minComparedTime = time.Millisecond
+)
+
+// The input sizes, in vertices
+var scalingSizes = []int{1024, 2048, 4096, 8192, 16384}
+
+type scalingFamily struct {
+	name string
+	// Make an input of about n vertices
+	list func(n int) advanced.PolygonList
+}
+
+func scalingFamilies() []scalingFamily {
+	return []scalingFamily{
+		{"convex", func(n int) advanced.PolygonList {
+			return advanced.PolygonList{testutil.RegularPolygon(n, 100, 0.1)}
+		}},
+		{"spiral", func(n int) advanced.PolygonList {
+			return advanced.PolygonList{testutil.SpiralPolygon(n/128, 64)}
+		}},
+		{"random simple", func(n int) advanced.PolygonList {
+			return advanced.PolygonList{testutil.RandomSimplePolygon(n, rand.New(rand.NewSource(1)))}
+		}},
+		{"grid aligned", func(n int) advanced.PolygonList {
+			return advanced.PolygonList{testutil.StaircasePolygon(n / 2)}
+		}},
+		{"many holes", func(n int) advanced.PolygonList {
+			// Hexagonal holes in a grid, with each column a little higher than the
+			// one before, so that the holes don't share heights
+			holes := n / 6
+			columns := int(math.Ceil(math.Sqrt(float64(holes))))
+			rows := (holes + columns - 1) / columns
+			list := advanced.PolygonList{testutil.Rectangle(-1, -1, float64(columns), float64(rows))}
+			for i := 0; i < holes; i++ {
+				x, y := float64(i%columns), float64(i/columns)+0.001*float64(i%columns)
+				list = append(list, testutil.Translate(testutil.RegularPolygon(6, 0.3, 0.1), x, y).Reverse())
+			}
+			return list
+		}},
+	}
+}
+
+// The time each stage takes to triangulate the input, taking the fastest of a
+// few runs to keep the noise down. The garbage collector is paused while each
+// run is timed, since the heap starts large enough that the smaller inputs
+// wouldn't be collected at all, which would make the larger ones look like they
+// grow faster than they do.
+func measureStages(list func() advanced.PolygonList) (times [advanced.StageTriangulated + 1]time.Duration) {
+	defer debug.SetGCPercent(debug.SetGCPercent(-1))
+	var elapsed time.Duration
+	for run := 0; run < 5 || elapsed < 500*time.Millisecond; run++ {
+		start := time.Now()
+		input := list()
+		runtime.GC()
+		var diagnostics advanced.Diagnostics
+		input.TriangulateWithOptions(advanced.Options{Diagnostics: &diagnostics})
+		for stage := range times {
+			if run == 0 || diagnostics.StageTimes[stage] < times[stage] {
+				times[stage] = diagnostics.StageTimes[stage]
+			}
+		}
+		elapsed += time.Since(start)
+	}
+	return times
+}
+
+func BenchmarkScaling(b *testing.B) {
+	for _, family := range scalingFamilies() {
+		for _, n := range scalingSizes {
+			list := family.list(n)
+			b.Run(fmt.Sprintf("%s/%d", family.name, n), func(b *testing.B) {
+				var total [advanced.StageTriangulated + 1]time.Duration
+				for i := 0; i < b.N; i++ {
+					var diagnostics advanced.Diagnostics
+					list.TriangulateWithOptions(advanced.Options{Diagnostics: &diagnostics})
+					for stage, elapsed := range diagnostics.StageTimes {
+						total[stage] += elapsed
+					}
+				}
+				b.ReportMetric(float64(total[advanced.StageGraphBuilt].Nanoseconds())/float64(b.N), "graph-ns/op")
+				b.ReportMetric(float64(total[advanced.StageMonotonesExtracted].Nanoseconds())/float64(b.N), "extract-ns/op")
+				b.ReportMetric(float64(total[advanced.StageTriangulated].Nanoseconds())/float64(b.N), "fan-ns/op")
+			})
+		}
+	}
+}
+
+// The exponent k of the best fit of times to c·nᵏ, by least squares on the
+// logarithms
+func fitGrowthExponent(sizes []int, times []time.Duration) float64 {
+	var sumX, sumY, sumXX, sumXY float64
+	for i := range sizes {
+		x, y := math.Log(float64(sizes[i])), math.Log(float64(times[i]))
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	n := float64(len(sizes))
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
+
+func TestFitGrowthExponent(t *testing.T) {
+	sizes := []int{1000, 2000, 4000, 8000}
+	var linear, quadratic, nlogn []time.Duration
+	for _, n := range sizes {
+		linear = append(linear, time.Duration(3*n))
+		quadratic = append(quadratic, time.Duration(n*n))
+		nlogn = append(nlogn, time.Duration(float64(n)*math.Log2(float64(n))))
+	}
+	assert.InDelta(t, 1, fitGrowthExponent(sizes, linear), 1e-9)
+	assert.InDelta(t, 2, fitGrowthExponent(sizes, quadratic), 1e-9)
+	assert.InDelta(t, 1.1, fitGrowthExponent(sizes, nlogn), 0.05)
+}
+
+// The key a stage's time is recorded under in the baseline
+func scalingKey(family string, n int, stage advanced.Stage) string {
+	return fmt.Sprintf("%s %d %s", family, n, stage)
+}
+
+// Read the recorded baseline, in microseconds, by key
+func readScalingBaseline(t *testing.T) map[string]float64 {
+	file, err := os.Open(scalingBaselineFile)
+	require.NoError(t, err)
+	defer file.Close()
+	recorded := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 2)
+		require.Len(t, fields, 2, line)
+		micros, err := strconv.ParseFloat(fields[0], 64)
+		require.NoError(t, err, line)
+		recorded[fields[1]] = micros
+	}
+	require.NoError(t, scanner.Err())
+	return recorded
+}
+
+func TestScaling(t *testing.T) {
+	update := os.Getenv("TRIANGULATE_PERF_UPDATE") != ""
+	if os.Getenv("TRIANGULATE_PERF") == "" && !update {
+		t.Skip("set TRIANGULATE_PERF=1 to run the performance harness")
+	}
+	factor := 2.0
+	if setting := os.Getenv("TRIANGULATE_PERF_FACTOR"); setting != "" {
+		var err error
+		factor, err = strconv.ParseFloat(setting, 64)
+		require.NoError(t, err, "TRIANGULATE_PERF_FACTOR")
+	}
+
+	measured := make(map[string]time.Duration)
+	for _, family := range scalingFamilies() {
+		var graphTimes []time.Duration
+		for _, n := range scalingSizes {
+			n := n
+			times := measureStages(func() advanced.PolygonList { return family.list(n) })
+			for stage, elapsed := range times {
+				measured[scalingKey(family.name, n, advanced.Stage(stage))] = elapsed
+			}
+			graphTimes = append(graphTimes, times[advanced.StageGraphBuilt])
+		}
+		// A single monotone piece skips the map, so there's nothing to fit
+		if family.name == "convex" {
+			continue
+		}
+		exponent := fitGrowthExponent(scalingSizes, graphTimes)
+		t.Logf("%s: graph build grows as n^%.2f", family.name, exponent)
+		assert.LessOrEqual(t, exponent, maxGraphExponent, "%s: graph build times %v", family.name, graphTimes)
+	}
+
+	if update {
+		lines := []string{"# Time of each stage in microseconds, and family, size and stage. Recorded by TestScaling."}
+		for _, family := range scalingFamilies() {
+			for _, n := range scalingSizes {
+				for stage := advanced.StageGraphBuilt; stage <= advanced.StageTriangulated; stage++ {
+					key := scalingKey(family.name, n, stage)
+					lines = append(lines, fmt.Sprintf("%.1f\t%s", float64(measured[key].Nanoseconds())/1000, key))
+				}
+			}
+		}
+		require.NoError(t, os.WriteFile(scalingBaselineFile, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+		t.Skip("recorded a new baseline; rerun without TRIANGULATE_PERF_UPDATE")
+	}
+
+	baseline := readScalingBaseline(t)
+	for key, elapsed := range measured {
+		micros, ok := baseline[key]
+		require.True(t, ok, "no baseline for %q; record one with TRIANGULATE_PERF_UPDATE=1", key)
+		if micros < float64(minComparedTime.Microseconds()) {
+			continue
+		}
+		assert.LessOrEqual(t, float64(elapsed.Nanoseconds())/1000, factor*micros, "%s, against a baseline of %.1fµs", key, micros)
+	}
+}
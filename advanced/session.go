@@ -0,0 +1,328 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+// A Session builds a triangulation incrementally, for inputs too large to hold
+// in memory as complete rings. Segments are added to the query graph as they
+// arrive, in chunks which need not contain whole rings. Anything which needs
+// complete rings is deferred to Finalize.
+//
+// As everywhere else in this package, points are identified by pointer, so a
+// vertex shared by two segments must be the same *Point in both, even if they
+// arrive in different chunks.
+type Session struct {
+	graph *QueryGraph
+	rng   *rand.Rand
+
+	// Segment counts leaving and arriving at each endpoint
+	outDegree, inDegree map[*Point]int
+	// Endpoints in the order they were first seen, so that errors are deterministic
+	endpoints []*Point
+
+	// Every segment in the session, in the order added, and the segments at each
+	// endpoint, for rebuilding the graph when polygons are removed
+	segments []*Segment
+	incident map[*Point][]*Segment
+	// The segments of each polygon added with AddPolygon, by index. Removed
+	// polygons are nil.
+	polygons [][]*Segment
+	// How many removals fell back to rebuilding the whole graph
+	fullRebuilds int
+
+	// Once a session fails or is finalized, every later call returns this error
+	err error
+}
+
+var ErrSessionFinalized = errors.New("session has already been finalized")
+
+func NewSession() *Session {
+	return &Session{
+		graph:     &QueryGraph{},
+		rng:       rand.New(rand.NewSource(0)),
+		outDegree: make(map[*Point]int),
+		inDegree:  make(map[*Point]int),
+		incident:  make(map[*Point][]*Segment),
+	}
+}
+
+// Add a chunk of segments to the session. Segments must point in the same
+// direction as the rings they belong to would. That is, counterclockwise for
+// solids, and clockwise for holes.
+//
+// If this returns an error, the session is no longer usable.
+func (s *Session) AddSegmentsChunk(segments []*Segment) (err error) {
+	if s.err != nil {
+		return s.err
+	}
+	defer func() {
+		err = HandleTriangulatePanicRecover(recover())
+		s.err = err
+	}()
+
+	s.addSegments(segments)
+	return nil
+}
+
+// Add a closed ring to the session, returning an index which can be passed to
+// RemovePolygon. As with Polygon, solids run counterclockwise, and holes
+// clockwise.
+//
+// If this returns an error, the session is no longer usable.
+func (s *Session) AddPolygon(points []*Point) (index int, err error) {
+	if s.err != nil {
+		return -1, s.err
+	}
+	defer func() {
+		err = HandleTriangulatePanicRecover(recover())
+		s.err = err
+		if err != nil {
+			index = -1
+		}
+	}()
+
+	if len(points) < 3 {
+		fatalf("polygon needs at least 3 points, got %d", len(points))
+	}
+	segments := make([]*Segment, len(points))
+	for i, start := range points {
+		segments[i] = NewSegment(start, points[CircularIndex(i+1, len(points))])
+	}
+	s.addSegments(segments)
+	s.polygons = append(s.polygons, segments)
+	return len(s.polygons) - 1, nil
+}
+
+func (s *Session) addSegments(segments []*Segment) {
+	for _, segment := range segments {
+		s.countEndpoint(segment.Start, s.outDegree)
+		s.countEndpoint(segment.End, s.inDegree)
+		s.incident[segment.Start] = append(s.incident[segment.Start], segment)
+		s.incident[segment.End] = append(s.incident[segment.End], segment)
+	}
+	s.segments = append(s.segments, segments...)
+
+	// Shuffle within the chunk. We can't shuffle across chunks, so the expected
+	// running time depends on the chunks not being ordered pathologically.
+	segments = append([]*Segment(nil), segments...)
+	s.rng.Shuffle(len(segments), func(i, j int) {
+		segments[i], segments[j] = segments[j], segments[i]
+	})
+
+	s.graph.addSegments(segments)
+}
+
+func (s *Session) countEndpoint(p *Point, degrees map[*Point]int) {
+	if s.outDegree[p] == 0 && s.inDegree[p] == 0 {
+		s.endpoints = append(s.endpoints, p)
+	}
+	degrees[p]++
+}
+
+// Check whether the point is inside the shape added so far. Nothing is inside
+// until every ring is closed (see QueryGraph.RingsClosed), so this is safe to
+// call between chunks. Points exactly on an edge may go either way. Once the
+// session has failed or been finalized, this is always false.
+func (s *Session) ContainsPoint(p *Point) bool {
+	if s.err != nil {
+		return false
+	}
+	return s.graph.ContainsPoint(p)
+}
+
+// Check that the segments form closed rings with consistent winding, then
+// triangulate them. After this, the session can't be used again.
+func (s *Session) Finalize() (result TriangleList, err error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	defer func() {
+		err = HandleTriangulatePanicRecover(recover())
+		if err != nil {
+			result = nil
+			s.err = err
+		} else {
+			s.err = ErrSessionFinalized
+		}
+	}()
+
+	var dangling, inconsistent []Point
+	for _, p := range s.endpoints {
+		out, in := s.outDegree[p], s.inDegree[p]
+		if (out+in)%2 != 0 {
+			dangling = append(dangling, *p)
+		} else if out != in {
+			inconsistent = append(inconsistent, *p)
+		}
+	}
+	if len(dangling) > 0 {
+		throw(&DanglingEndpointsError{dangling})
+	}
+	if len(inconsistent) > 0 {
+		throw(&InconsistentWindingError{inconsistent})
+	}
+
+	if s.graph.Root == nil {
+		return nil, nil
+	}
+	return triangulateMonotones(s.graph.convertToMonotones(Options{}), Options{}, nil, nil), nil
+}
+
+// Check whether adding the ring with the given points would intersect any
+// segment already in the session. This returns an IntersectionError for the
+// first offending edge found, or nil if the ring is clean. The session is never
+// modified.
+//
+// This only checks against existing geometry. It doesn't check whether the
+// ring intersects itself.
+func (s *Session) CheckPolygon(points []*Point) (err error) {
+	if s.err != nil {
+		return s.err
+	}
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			err = recoveredErr
+		}
+	}()
+
+	if len(points) < 3 {
+		fatalf("polygon needs at least 3 points, got %d", len(points))
+	}
+	if s.graph.Root == nil {
+		return nil
+	}
+
+	for i, start := range points {
+		candidate := NewSegment(start, points[CircularIndex(i+1, len(points))])
+		if existing := s.graph.findIntersection(candidate); existing != nil {
+			return &IntersectionError{Existing: existing, Edge: i, Candidate: candidate}
+		}
+	}
+	return nil
+}
+
+// Walk a segment up through the trapezoids it would cross, like AddSegment
+// does, but without splitting anything. Returns the first existing segment it
+// intersects, or nil.
+//
+// Where AddSegment splits at the endpoints first, we simply start in whichever
+// trapezoid contains the bottom point, and stop in whichever contains the top.
+// If the segment crosses an existing segment, it must leave the trapezoid it's
+// in through that segment, so checking the sides of each trapezoid along the
+// way is enough.
+func (graph *QueryGraph) findIntersection(segment *Segment) *Segment {
+	top := segment.Top()
+	bottom := segment.Bottom()
+	curTrapezoid := graph.FindPoint(bottom.PointingAt(top)).Inner.(SinkNode).Trapezoid
+
+	for {
+		for _, side := range []*Segment{curTrapezoid.Left, curTrapezoid.Right} {
+			if side != nil && segmentsIntersect(side, segment) {
+				return side
+			}
+		}
+
+		if curTrapezoid.Top == nil || curTrapezoid.Top == top || top.Below(curTrapezoid.Top) {
+			return nil
+		}
+
+		nextNeighbors := curTrapezoid.TrapezoidsAbove
+		var next *Trapezoid
+		if nextNeighbors.Count() == 1 {
+			next = nextNeighbors.AnyNeighbor()
+		} else {
+			for _, neighbor := range nextNeighbors {
+				if neighbor != nil && neighbor.BottomIntersectsSegment(segment) {
+					next = neighbor
+					break
+				}
+			}
+		}
+
+		if next == nil {
+			// The segment passes through the vertex at the top of this trapezoid, so
+			// it touches the segments which meet there.
+			for _, neighbor := range nextNeighbors {
+				if neighbor == nil {
+					continue
+				}
+				for _, side := range []*Segment{neighbor.Left, neighbor.Right} {
+					if side != nil && segmentsIntersect(side, segment) {
+						return side
+					}
+				}
+			}
+			fatalf("could not find the trapezoid above %v for segment %v to %v", curTrapezoid, segment.Start, segment.End)
+		}
+		curTrapezoid = next
+	}
+}
+
+// Check whether an end of either segment is closer than tolerance to the other
+// segment. Ends the segments share are skipped, so neighboring edges of a ring
+// are only this close if the far end of one comes back to the other.
+func segmentsWithin(a, b *Segment, tolerance float64) bool {
+	for _, pair := range [][2]*Segment{{a, b}, {b, a}} {
+		s, other := pair[0], pair[1]
+		for _, p := range []*Point{other.Start, other.End} {
+			if p != s.Start && p != s.End && distanceToSegment(p, s) < tolerance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check whether two segments intersect, including touching. Segments which
+// share an endpoint only intersect if they overlap along a line.
+func segmentsIntersect(a, b *Segment) bool {
+	sign := func(v float64) int {
+		if v > 0 {
+			return 1
+		} else if v < 0 {
+			return -1
+		}
+		return 0
+	}
+	// Check whether r, which must be collinear with p and q, lies within their
+	// bounding box
+	within := func(p, q, r *Point) bool {
+		return math.Min(p.X, q.X) <= r.X && r.X <= math.Max(p.X, q.X) &&
+			math.Min(p.Y, q.Y) <= r.Y && r.Y <= math.Max(p.Y, q.Y)
+	}
+
+	// Shared endpoints
+	for _, pair := range [][4]*Point{
+		{a.Start, a.End, b.Start, b.End},
+		{a.Start, a.End, b.End, b.Start},
+		{a.End, a.Start, b.Start, b.End},
+		{a.End, a.Start, b.End, b.Start},
+	} {
+		shared, aOther, bOther := pair[0], pair[1], pair[3]
+		if shared != pair[2] {
+			continue
+		}
+		if sign(orientation(shared, aOther, bOther)) != 0 {
+			return false
+		}
+		// Collinear, so they overlap if they leave the shared point in the same direction
+		return float64((aOther.X-shared.X)*(bOther.X-shared.X))+float64((aOther.Y-shared.Y)*(bOther.Y-shared.Y)) > 0
+	}
+
+	d1 := sign(orientation(a.Start, a.End, b.Start))
+	d2 := sign(orientation(a.Start, a.End, b.End))
+	d3 := sign(orientation(b.Start, b.End, a.Start))
+	d4 := sign(orientation(b.Start, b.End, a.End))
+
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+	return (d1 == 0 && within(a.Start, a.End, b.Start)) ||
+		(d2 == 0 && within(a.Start, a.End, b.End)) ||
+		(d3 == 0 && within(b.Start, b.End, a.Start)) ||
+		(d4 == 0 && within(b.Start, b.End, a.End))
+}
@@ -0,0 +1,342 @@
+package advanced
+
+import "github.com/pkg/errors"
+
+// Removal of polygons from a Session. Randomized incremental construction has
+// no cheap way to take a segment back out, but removing geometry only changes
+// the trapezoid map near that geometry:
+//
+// Every trapezoid which doesn't have a removed segment for a side, or a
+// removed vertex for its top or bottom, is still a trapezoid of the map once
+// the polygon is gone, since removing geometry can't intrude on it. So only
+// the region covered by the affected trapezoids needs rebuilding. Every new
+// trapezoid in that region is bounded by segments which bound the affected
+// trapezoids, or which meet at their tops and bottoms, so building a small
+// graph from just those segments produces all of the new trapezoids, along
+// with some larger ones outside the region, which are discarded.
+//
+// The small graph is then spliced into the old one, by replacing the sinks of
+// the affected trapezoids with its root. A query which arrives at one of those
+// sinks is in the region, so it carries on to one of the new trapezoids.
+// Finally, the neighbor links across the edge of the region are rewired.
+//
+// If the region is a large part of the whole map, none of this saves anything,
+// so the whole graph is rebuilt instead.
+
+// The fraction of the trapezoids in the map which may be affected by a removal
+// before the whole graph is rebuilt instead
+const removalRebuildFraction = 0.5
+
+// Remove a polygon added with AddPolygon, as though it had never been added.
+// Removing a polygon twice, or one which was never added, is an error, but
+// leaves the session usable.
+//
+// Otherwise, if this returns an error, the session is no longer usable.
+func (s *Session) RemovePolygon(index int) (err error) {
+	if s.err != nil {
+		return s.err
+	}
+	if index < 0 || index >= len(s.polygons) || s.polygons[index] == nil {
+		return errors.Errorf("no polygon %d in the session", index)
+	}
+	defer func() {
+		err = HandleTriangulatePanicRecover(recover())
+		s.err = err
+	}()
+
+	removed := s.polygons[index]
+	s.polygons[index] = nil
+	total := len(s.segments)
+	s.forgetSegments(removed)
+
+	if !s.rebuildAround(removed, total) {
+		s.fullRebuilds++
+		s.graph = &QueryGraph{}
+		segments := append([]*Segment(nil), s.segments...)
+		s.rng.Shuffle(len(segments), func(i, j int) {
+			segments[i], segments[j] = segments[j], segments[i]
+		})
+		s.graph.addSegments(segments)
+	}
+	return nil
+}
+
+// Undo the bookkeeping done for the segments when they were added
+func (s *Session) forgetSegments(segments []*Segment) {
+	gone := make(map[*Segment]struct{}, len(segments))
+	for _, segment := range segments {
+		gone[segment] = struct{}{}
+		s.outDegree[segment.Start]--
+		s.inDegree[segment.End]--
+	}
+	keep := func(list []*Segment) []*Segment {
+		var kept []*Segment
+		for _, segment := range list {
+			if _, ok := gone[segment]; !ok {
+				kept = append(kept, segment)
+			}
+		}
+		return kept
+	}
+	s.segments = keep(s.segments)
+
+	var endpoints []*Point
+	for _, p := range s.endpoints {
+		if s.outDegree[p] == 0 && s.inDegree[p] == 0 {
+			delete(s.outDegree, p)
+			delete(s.inDegree, p)
+			delete(s.incident, p)
+			continue
+		}
+		s.incident[p] = keep(s.incident[p])
+		endpoints = append(endpoints, p)
+	}
+	s.endpoints = endpoints
+}
+
+// Rebuild the part of the graph around the removed segments, which have
+// already been forgotten by the session. Returns false, without touching the
+// graph, if it would be better to rebuild the whole graph. total is the number
+// of segments before the removal.
+func (s *Session) rebuildAround(removed []*Segment, total int) bool {
+	graph := s.graph
+	removedSegments := make(map[*Segment]struct{}, len(removed))
+	for _, segment := range removed {
+		removedSegments[segment] = struct{}{}
+	}
+	isRemoved := func(p *Point) bool {
+		return p != nil && len(s.incident[p]) == 0
+	}
+	isAffected := func(t *Trapezoid) bool {
+		_, left := removedSegments[t.Left]
+		_, right := removedSegments[t.Right]
+		return left || right || isRemoved(t.Top) || isRemoved(t.Bottom)
+	}
+
+	// Collect the affected trapezoids. Each removed segment has affected
+	// trapezoids along it, and the affected trapezoids around a ring are
+	// connected, so a search from those finds them all.
+	affected := make(map[*Trapezoid]struct{})
+	var region []*Trapezoid
+	visit := func(t *Trapezoid) {
+		if _, ok := affected[t]; ok || !isAffected(t) {
+			return
+		}
+		affected[t] = struct{}{}
+		region = append(region, t)
+	}
+	for _, segment := range removed {
+		visit(graph.FindPoint(segment.Bottom().PointingAt(segment.Top())).Inner.(SinkNode).Trapezoid)
+	}
+	for i := 0; i < len(region); i++ {
+		if float64(len(region)) > removalRebuildFraction*float64(3*total+1) {
+			return false
+		}
+		for _, neighbors := range []TrapezoidNeighborList{region[i].TrapezoidsAbove, region[i].TrapezoidsBelow} {
+			for _, neighbor := range neighbors {
+				if neighbor != nil {
+					visit(neighbor)
+				}
+			}
+		}
+	}
+
+	// Collect the segments which bound the region
+	var local []*Segment
+	seen := make(map[*Segment]struct{})
+	use := func(segment *Segment) {
+		if _, ok := removedSegments[segment]; ok || segment == nil {
+			return
+		}
+		if _, ok := seen[segment]; !ok {
+			seen[segment] = struct{}{}
+			local = append(local, segment)
+		}
+	}
+	for _, t := range region {
+		use(t.Left)
+		use(t.Right)
+		for _, p := range []*Point{t.Top, t.Bottom} {
+			if p != nil {
+				for _, segment := range s.incident[p] {
+					use(segment)
+				}
+			}
+		}
+	}
+	if len(local) == 0 {
+		// Nothing is left around the region, so there's nothing to gain
+		return false
+	}
+
+	s.rng.Shuffle(len(local), func(i, j int) {
+		local[i], local[j] = local[j], local[i]
+	})
+	localGraph := &QueryGraph{}
+	localGraph.addSegments(local)
+
+	// Find which of the new trapezoids are in the region. Each one is either
+	// entirely inside the region or entirely outside it, so it is enough to
+	// find which old trapezoid is just inside one of its corners.
+	inRegion := make(map[*Trapezoid]bool)
+	var newTrapezoids []*Trapezoid
+	for t := range localGraph.IterateTrapezoids() {
+		old := graph.FindPoint(t.interiorDirectionalPoint()).Inner.(SinkNode).Trapezoid
+		if _, ok := affected[old]; ok {
+			inRegion[t] = true
+			newTrapezoids = append(newTrapezoids, t)
+		}
+	}
+	root := pruneQueryNode(localGraph.Root, inRegion, make(map[*QueryNode]*QueryNode))
+	if root == nil {
+		fatalf("no new trapezoids in the region of the removed segments")
+	}
+	if _, ok := root.Inner.(SinkNode); ok {
+		// The region is a single trapezoid, but there may be several affected
+		// sinks to reach it from, so they need a node to share
+		root = &QueryNode{YNode{Key: local[0].Start, Above: root, Below: root}}
+	}
+
+	// Rewire the neighbors across the edge of the region. Links between new
+	// trapezoids are already right, and so are links between old ones outside
+	// the region, so only the links between the two need to be made.
+	var border []*Trapezoid
+	inBorder := make(map[*Trapezoid]struct{})
+	for _, t := range region {
+		for _, neighbors := range []TrapezoidNeighborList{t.TrapezoidsAbove, t.TrapezoidsBelow} {
+			for _, neighbor := range neighbors {
+				if _, ok := affected[neighbor]; ok || neighbor == nil {
+					continue
+				}
+				if _, ok := inBorder[neighbor]; !ok {
+					inBorder[neighbor] = struct{}{}
+					border = append(border, neighbor)
+				}
+			}
+		}
+	}
+	for _, t := range border {
+		for _, neighbors := range []*TrapezoidNeighborList{&t.TrapezoidsAbove, &t.TrapezoidsBelow} {
+			for i, neighbor := range neighbors {
+				if _, ok := affected[neighbor]; ok {
+					neighbors[i] = nil
+				}
+			}
+		}
+	}
+	for _, t := range newTrapezoids {
+		for _, neighbors := range []*TrapezoidNeighborList{&t.TrapezoidsAbove, &t.TrapezoidsBelow} {
+			for i, neighbor := range neighbors {
+				if neighbor != nil && !inRegion[neighbor] {
+					neighbors[i] = nil
+				}
+			}
+		}
+	}
+	above := make(map[*Point][]*Trapezoid)
+	for _, list := range [][]*Trapezoid{newTrapezoids, border} {
+		for _, t := range list {
+			if t.Bottom != nil {
+				above[t.Bottom] = append(above[t.Bottom], t)
+			}
+		}
+	}
+	for _, list := range [][]*Trapezoid{newTrapezoids, border} {
+		for _, lower := range list {
+			if lower.Top == nil {
+				continue
+			}
+			for _, upper := range above[lower.Top] {
+				if inRegion[lower] != inRegion[upper] && lower.NonzeroOverlapWithTrapezoidAbove(upper) {
+					lower.TrapezoidsAbove.Add(upper)
+					upper.TrapezoidsBelow.Add(lower)
+				}
+			}
+		}
+	}
+
+	for _, t := range region {
+		t.Sink.Inner = root.Inner
+	}
+	for _, segment := range removed {
+		graph.countRingSegment(segment, -1)
+	}
+	graph.invalidateBounds()
+	return true
+}
+
+// A point just inside the trapezoid, at its bottom vertex, with a direction
+// pointing into the trapezoid. A trapezoid with no bottom has no sides either,
+// so then the top vertex pointing down will do.
+func (t *Trapezoid) interiorDirectionalPoint() DirectionalPoint {
+	if t.Bottom == nil {
+		return DirectionalPoint{t.Top, Vector{0, -1}}
+	}
+	up := func(segment *Segment) Vector {
+		return Vector{segment.Top().X - t.Bottom.X, segment.Top().Y - t.Bottom.Y}.Normalize()
+	}
+	// Bisect the corner between the sides, or between a side and the bottom
+	onLeft, onRight := t.vertexSides(Down)
+	var direction Vector
+	switch {
+	case onLeft && onRight:
+		left, right := up(t.Left), up(t.Right)
+		direction = Vector{left.X + right.X, left.Y + right.Y}
+	case onLeft:
+		left := up(t.Left)
+		direction = Vector{left.X + 1, left.Y}
+	case onRight:
+		right := up(t.Right)
+		direction = Vector{right.X - 1, right.Y}
+	default:
+		direction = Vector{0, 1}
+	}
+	return DirectionalPoint{t.Bottom, direction.Normalize()}
+}
+
+// Remove the branches of a query graph which only lead to trapezoids outside
+// the region, returning the node to use in place of the given one, or nil if
+// none of it is needed. Points inside the region never take those branches, so
+// a node with only one useful child can be replaced by that child.
+func pruneQueryNode(node *QueryNode, inRegion map[*Trapezoid]bool, memo map[*QueryNode]*QueryNode) *QueryNode {
+	if pruned, ok := memo[node]; ok {
+		return pruned
+	}
+	var pruned *QueryNode
+	switch inner := node.Inner.(type) {
+	case SinkNode:
+		if inRegion[inner.Trapezoid] {
+			pruned = node
+		}
+	case YNode:
+		above, below := pruneQueryNode(inner.Above, inRegion, memo), pruneQueryNode(inner.Below, inRegion, memo)
+		pruned = prunedPair(node, above, below, func() QueryNodeInner {
+			return YNode{Key: inner.Key, Above: above, Below: below}
+		})
+	case XNode:
+		left, right := pruneQueryNode(inner.Left, inRegion, memo), pruneQueryNode(inner.Right, inRegion, memo)
+		pruned = prunedPair(node, left, right, func() QueryNodeInner {
+			return XNode{Key: inner.Key, Left: left, Right: right}
+		})
+	default:
+		fatalf("unknown query node type %T", inner)
+	}
+	memo[node] = pruned
+	return pruned
+}
+
+// Combine the pruned children of a node
+func prunedPair(node, a, b *QueryNode, rebuild func() QueryNodeInner) *QueryNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	for _, child := range node.ChildNodes() {
+		if child != a && child != b {
+			return &QueryNode{rebuild()}
+		}
+	}
+	return node
+}
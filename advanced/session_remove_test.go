@@ -0,0 +1,138 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func translated(poly Polygon, dx, dy float64) Polygon {
+	points := make([]*Point, len(poly.Points))
+	for i, p := range poly.Points {
+		points[i] = &Point{X: p.X + dx, Y: p.Y + dy}
+	}
+	return Polygon{points}
+}
+
+// Add the polygons to a new session, then remove some of them, checking the
+// result against a batch triangulation of what's left
+func checkSessionRemoval(t *testing.T, list PolygonList, remove ...int) *Session {
+	session := NewSession()
+	for i, poly := range list {
+		index, err := session.AddPolygon(poly.Points)
+		require.NoError(t, err)
+		require.Equal(t, i, index)
+	}
+	removed := make(map[int]bool)
+	for _, index := range remove {
+		require.NoError(t, session.RemovePolygon(index))
+		removed[index] = true
+		// Zero height trapezoids are expected, since the stars have vertices at
+		// the same height, but nothing else is
+		for _, problem := range AuditGraph(session.graph).Problems {
+			require.Equal(t, AuditZeroHeight, problem.Kind, problem.String())
+		}
+	}
+
+	var remaining PolygonList
+	for i, poly := range list {
+		if !removed[i] {
+			remaining = append(remaining, poly)
+		}
+	}
+	rebuilds := session.fullRebuilds
+	result, err := session.Finalize()
+	require.NoError(t, err)
+	if len(remaining) == 0 {
+		assert.Empty(t, result)
+	} else {
+		assert.InDelta(t, totalArea(remaining.Triangulate()), totalArea(result), 1e-9)
+		validatePolygonsBySampling(t, result.ToPolygonList(), remaining)
+	}
+	session.fullRebuilds = rebuilds
+	return session
+}
+
+func TestSession_RemovePolygon(t *testing.T) {
+	star := SimpleStar()[0]
+	list := PolygonList{star, translated(star, 12, 1), translated(star, 24, -1)}
+	session := checkSessionRemoval(t, list, 1)
+	assert.Zero(t, session.fullRebuilds, "removal should have rebuilt only the middle star's region")
+}
+
+func TestSession_RemovePolygon_Several(t *testing.T) {
+	star := SimpleStar()[0]
+	var list PolygonList
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			list = append(list, translated(star, float64(12*col), float64(11*row)+float64(col)/3))
+		}
+	}
+	session := checkSessionRemoval(t, list, 5, 0, 10, 15, 6)
+	assert.Zero(t, session.fullRebuilds)
+}
+
+func TestSession_RemovePolygon_Hole(t *testing.T) {
+	// Removing the hole fills it back in
+	checkSessionRemoval(t, SquareWithHole(), 1)
+
+	// Removing a ring between the layers of holes leaves a larger hole
+	checkSessionRemoval(t, MultiLayeredHoles(), 2)
+}
+
+func TestSession_RemovePolygon_FallsBack(t *testing.T) {
+	// The circle is most of the map, so removing it rebuilds everything
+	list := PolygonList{circlePolygon(10, 50), squareRing(20, 0, 1)}
+	session := checkSessionRemoval(t, list, 0)
+	assert.Equal(t, 1, session.fullRebuilds)
+
+	// So does removing everything
+	session = checkSessionRemoval(t, PolygonList{squareRing(0, 0, 1)}, 0)
+	assert.Equal(t, 1, session.fullRebuilds)
+}
+
+func TestSession_RemovePolygon_ThenAdd(t *testing.T) {
+	star := SimpleStar()[0]
+	session := NewSession()
+	_, err := session.AddPolygon(star.Points)
+	require.NoError(t, err)
+	middle, err := session.AddPolygon(translated(star, 12, 0).Points)
+	require.NoError(t, err)
+	_, err = session.AddPolygon(translated(star, 24, 0).Points)
+	require.NoError(t, err)
+
+	require.NoError(t, session.RemovePolygon(middle))
+	// The same points again, since the graph still has nodes for the removed
+	// segments
+	again := translated(star, 12, 0)
+	_, err = session.AddPolygon(again.Points)
+	require.NoError(t, err)
+	square := squareRing(40, 0, 3)
+	_, err = session.AddPolygon(square.Points)
+	require.NoError(t, err)
+
+	result, err := session.Finalize()
+	require.NoError(t, err)
+	list := PolygonList{star, again, translated(star, 24, 0), square}
+	assert.InDelta(t, totalArea(list.Triangulate()), totalArea(result), 1e-9)
+	validatePolygonsBySampling(t, result.ToPolygonList(), list)
+}
+
+func TestSession_RemovePolygon_Errors(t *testing.T) {
+	session := NewSession()
+	index, err := session.AddPolygon(squareRing(0, 0, 1).Points)
+	require.NoError(t, err)
+
+	assert.Error(t, session.RemovePolygon(index+1))
+	assert.Error(t, session.RemovePolygon(-1))
+	require.NoError(t, session.RemovePolygon(index))
+	assert.Error(t, session.RemovePolygon(index))
+
+	// Still usable
+	_, err = session.AddPolygon(squareRing(0, 0, 1).Points)
+	require.NoError(t, err)
+	result, err := session.Finalize()
+	require.NoError(t, err)
+	assert.InDelta(t, 1, totalArea(result), 1e-9)
+}
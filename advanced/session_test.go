@@ -0,0 +1,173 @@
+package advanced
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func segmentsForPolygons(list PolygonList) []*Segment {
+	var segments []*Segment
+	for _, poly := range list {
+		for i, p := range poly.Points {
+			segments = append(segments, NewSegment(p, poly.Points[CircularIndex(i+1, len(poly.Points))]))
+		}
+	}
+	return segments
+}
+
+func TestSession_Chunks(t *testing.T) {
+	list := PolygonList{*LoadFixture("spiral")}
+	segments := segmentsForPolygons(list)
+	batch := list.Triangulate()
+
+	session := NewSession()
+	for _, chunk := range [][]*Segment{segments[:5], segments[5:19], segments[19:]} {
+		require.NoError(t, session.AddSegmentsChunk(chunk))
+	}
+	result, err := session.Finalize()
+	require.NoError(t, err)
+
+	assert.Len(t, result, len(batch))
+	assert.InDelta(t, Area(&list[0]), totalArea(result), 1e-6)
+	validatePolygonsBySampling(t, result.ToPolygonList(), list)
+
+	_, err = session.Finalize()
+	assert.ErrorIs(t, err, ErrSessionFinalized)
+	assert.ErrorIs(t, session.AddSegmentsChunk(segments[:1]), ErrSessionFinalized)
+}
+
+func TestSession_WithHoles(t *testing.T) {
+	list := MultiLayeredHoles()
+	segments := segmentsForPolygons(list)
+
+	session := NewSession()
+	// Interleave the rings, so that no chunk holds a whole ring
+	var evens, odds []*Segment
+	for i, segment := range segments {
+		if i%2 == 0 {
+			evens = append(evens, segment)
+		} else {
+			odds = append(odds, segment)
+		}
+	}
+	require.NoError(t, session.AddSegmentsChunk(evens))
+	require.NoError(t, session.AddSegmentsChunk(odds))
+	result, err := session.Finalize()
+	require.NoError(t, err)
+	validatePolygonsBySampling(t, result.ToPolygonList(), list)
+}
+
+func TestSession_DanglingEndpoints(t *testing.T) {
+	list := SquareWithHole()
+	segments := segmentsForPolygons(list)
+
+	session := NewSession()
+	// Leave out the last segment of the hole
+	require.NoError(t, session.AddSegmentsChunk(segments[:len(segments)-1]))
+	_, err := session.Finalize()
+
+	var danglingErr *DanglingEndpointsError
+	require.True(t, errors.As(err, &danglingErr))
+	hole := list[1].Points
+	assert.ElementsMatch(t, []Point{*hole[0], *hole[len(hole)-1]}, danglingErr.Points)
+}
+
+func TestSession_InconsistentWinding(t *testing.T) {
+	a, b, c, d := &Point{X: 0, Y: 0}, &Point{X: 1, Y: 0}, &Point{X: 1, Y: 1}, &Point{X: 0, Y: 1}
+	session := NewSession()
+	// Each point has even degree, but the last segment runs backwards
+	require.NoError(t, session.AddSegmentsChunk([]*Segment{NewSegment(a, b), NewSegment(b, c), NewSegment(c, d)}))
+	require.NoError(t, session.AddSegmentsChunk([]*Segment{NewSegment(a, d)}))
+	_, err := session.Finalize()
+
+	var windingErr *InconsistentWindingError
+	require.True(t, errors.As(err, &windingErr))
+	assert.Equal(t, []Point{*a, *d}, windingErr.Points)
+}
+
+func totalArea(triangles TriangleList) float64 {
+	var area float64
+	for _, triangle := range triangles {
+		area += triangle.SignedArea()
+	}
+	return area
+}
+
+func TestSession_CheckPolygon(t *testing.T) {
+	square := Polygon{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}}
+	newSession := func() *Session {
+		session := NewSession()
+		require.NoError(t, session.AddSegmentsChunk(segmentsForPolygons(PolygonList{square})))
+		return session
+	}
+	geometry := func(session *Session) []string {
+		var result []string
+		for _, trapezoid := range collectTrapezoids(session.graph) {
+			result = append(result, trapezoid.Geometry())
+		}
+		return result
+	}
+
+	t.Run("crossing ring is rejected", func(t *testing.T) {
+		session := newSession()
+		before := geometry(session)
+
+		ring := []*Point{{X: 8, Y: 4}, {X: 8, Y: 6}, {X: 14, Y: 5}}
+		err := session.CheckPolygon(ring)
+		var intersectionErr *IntersectionError
+		require.True(t, errors.As(err, &intersectionErr), "got %v", err)
+		assert.Same(t, square.Points[1], intersectionErr.Existing.Bottom())
+		assert.Same(t, square.Points[2], intersectionErr.Existing.Top())
+		assert.Contains(t, []int{1, 2}, intersectionErr.Edge)
+		assert.Same(t, ring[intersectionErr.Edge], intersectionErr.Candidate.Start)
+
+		assert.Equal(t, before, geometry(session))
+		result, err := session.Finalize()
+		require.NoError(t, err)
+		validatePolygonsBySampling(t, result.ToPolygonList(), PolygonList{square})
+	})
+
+	t.Run("ring through an existing vertex is rejected", func(t *testing.T) {
+		session := newSession()
+		err := session.CheckPolygon([]*Point{{X: 5, Y: 5}, {X: 5, Y: 8}, {X: 15, Y: 15}})
+		var intersectionErr *IntersectionError
+		require.True(t, errors.As(err, &intersectionErr), "got %v", err)
+		assert.Equal(t, 1, intersectionErr.Edge)
+	})
+
+	t.Run("ring sharing an existing vertex is accepted", func(t *testing.T) {
+		session := newSession()
+		assert.NoError(t, session.CheckPolygon([]*Point{square.Points[2], {X: 15, Y: 10}, {X: 15, Y: 15}}))
+	})
+
+	t.Run("clean ring can be added", func(t *testing.T) {
+		session := newSession()
+		before := geometry(session)
+
+		hole := Polygon{[]*Point{{X: 3, Y: 3}, {X: 3, Y: 7}, {X: 7, Y: 7}, {X: 7, Y: 3}}}
+		require.NoError(t, session.CheckPolygon(hole.Points))
+		assert.Equal(t, before, geometry(session))
+
+		require.NoError(t, session.AddSegmentsChunk(segmentsForPolygons(PolygonList{hole})))
+		result, err := session.Finalize()
+		require.NoError(t, err)
+		validatePolygonsBySampling(t, result.ToPolygonList(), PolygonList{square, hole})
+	})
+}
+
+func TestSession_CheckPolygon_CleanRings(t *testing.T) {
+	// Hold out each ring in turn. Checking it against the rest should always pass.
+	list := MultiLayeredHoles()
+	for i := range list {
+		session := NewSession()
+		for j, poly := range list {
+			if j != i {
+				require.NoError(t, session.AddSegmentsChunk(segmentsForPolygons(PolygonList{poly})))
+			}
+		}
+		assert.NoError(t, session.CheckPolygon(list[i].Points), "ring %d", i)
+	}
+}
@@ -0,0 +1,123 @@
+package advanced
+
+// Rings may share edges, as neighboring regions of a planar subdivision do
+// along their border. Each ring includes the shared edge, running the opposite
+// way to the other's, through the same points. As a pair, the two copies are
+// neutral: the inside is on the left of each, so whatever is on one side is
+// also on the other, as decided by the rest of the rings.
+//
+// Adding both copies to the trapezoid map would make a zero width trapezoid
+// between them, so only one is added. Between two solids, or a solid and the
+// hole it sits in, both sides are inside, and the copy which points down is
+// added and marked as shared. The trapezoid on its right is inside, as for any
+// segment pointing down, and the one on its left is inside by the edges of the
+// other ring beyond it, which point down on that side. The copy stays in the
+// map, so that no trapezoid, and so no triangle, spans the border. Between two
+// holes, both sides are outside, so neither copy is needed, and the holes
+// merge into one.
+//
+// Edges are matched by the exact coordinates of their ends, so rings built
+// from separate coordinate lists can share an edge. The trapezoid map tells
+// points apart by pointer, though, so the rings are given a working copy in
+// which every point at the end of a shared edge is replaced by the first
+// pointer in the input with its coordinates. Output triangles reference that
+// pointer.
+
+// What becomes of an input edge
+type sharedEdgeRole uint8
+
+const (
+	// An edge of one ring only, added as usual
+	ownEdge sharedEdgeRole = iota
+	// The copy of an edge two rings share which points down, added once for
+	// both
+	sharedEdge
+	// An edge whose other copy stands for both of them, or an edge between two
+	// holes, left out
+	skippedEdge
+)
+
+// The edges which rings of a list share, by their position in the input
+type sharedEdges struct {
+	roles map[segmentSource]sharedEdgeRole
+}
+
+// Find the edges the rings share, or nil if there are none. Returns the list
+// to add in place of the input, which is the input itself unless the rings
+// sharing an edge have different pointers for its ends.
+func findSharedEdges(list PolygonList) (PolygonList, *sharedEdges) {
+	type directedEdge struct {
+		start, end Point
+	}
+	// The first unpaired edge running each way between two points
+	unpaired := make(map[directedEdge]segmentSource)
+	var shared *sharedEdges
+	var windings []bool
+	// The ends of shared edges, by coordinates, and whether any of them have
+	// more than one pointer
+	ends := make(map[Point]*Point)
+	distinct := false
+	for polygonIndex, poly := range list {
+		for i, p := range poly.Points {
+			next := poly.Points[CircularIndex(i+1, len(poly.Points))]
+			source := segmentSource{polygonIndex, i}
+			reverse := directedEdge{coordinatesOf(next), coordinatesOf(p)}
+			other, ok := unpaired[reverse]
+			if !ok || other.polygon == polygonIndex {
+				unpaired[directedEdge{coordinatesOf(p), coordinatesOf(next)}] = source
+				continue
+			}
+
+			delete(unpaired, reverse)
+			if shared == nil {
+				shared = &sharedEdges{roles: make(map[segmentSource]sharedEdgeRole)}
+				windings = list.Windings()
+			}
+			shared.roles[source], shared.roles[other] = skippedEdge, skippedEdge
+			if windings[polygonIndex] || windings[other.polygon] {
+				if next.Below(p) {
+					shared.roles[source] = sharedEdge
+				} else {
+					shared.roles[other] = sharedEdge
+				}
+			}
+
+			otherPoints := list[other.polygon].Points
+			otherStart := otherPoints[other.edge]
+			otherEnd := otherPoints[CircularIndex(other.edge+1, len(otherPoints))]
+			distinct = distinct || otherStart != next || otherEnd != p
+			ends[reverse.start], ends[reverse.end] = nil, nil
+		}
+	}
+	if !distinct {
+		return list, shared
+	}
+
+	working := make(PolygonList, len(list))
+	for i, poly := range list {
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			key := coordinatesOf(p)
+			canonical, ok := ends[key]
+			if !ok {
+				points[j] = p
+				continue
+			}
+			if canonical == nil {
+				canonical = p
+				ends[key] = p
+			}
+			points[j] = canonical
+		}
+		working[i] = Polygon{Points: points}
+	}
+	return working, shared
+}
+
+// The role of an input edge. This is safe to call on nil shared edges.
+func (shared *sharedEdges) role(source segmentSource) sharedEdgeRole {
+	if shared == nil {
+		return ownEdge
+	}
+	return shared.roles[source]
+}
@@ -0,0 +1,221 @@
+package advanced
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Two unit squares side by side, sharing the edge between them
+func adjacentSquares() PolygonList {
+	a, b, c, d := &Point{X: 0, Y: 0}, &Point{X: 1, Y: 0}, &Point{X: 1, Y: 1}, &Point{X: 0, Y: 1}
+	e, f := &Point{X: 2, Y: 0}, &Point{X: 2, Y: 1}
+	return PolygonList{{[]*Point{a, b, c, d}}, {[]*Point{b, e, f, c}}}
+}
+
+// A grid of cells, sharing points with their neighbors, where the cells are
+// cut along a jagged line through the middle, like two countries with a
+// subdivided border between them
+func jaggedBorder() PolygonList {
+	const n = 8
+	var bottom, border, top [n + 1]*Point
+	for i := range border {
+		x := float64(i)
+		bottom[i] = &Point{X: x, Y: 0}
+		top[i] = &Point{X: x, Y: 10}
+		border[i] = &Point{X: x, Y: 5 + float64(i%2) - 0.5}
+	}
+	var south, north []*Point
+	south = append(south, bottom[:]...)
+	for i := n; i >= 0; i-- {
+		south = append(south, border[i])
+	}
+	north = append(north, border[:]...)
+	for i := n; i >= 0; i-- {
+		north = append(north, top[i])
+	}
+	return PolygonList{{south}, {north}}
+}
+
+func assertCoversOnce(t *testing.T, list PolygonList, triangles TriangleList) {
+	var area float64
+	for _, poly := range list {
+		area += poly.SignedArea()
+	}
+	assert.InDelta(t, area, totalArea(triangles), 1e-9*area)
+	// Sampling by even-odd also catches double covering, since a point in two
+	// triangles counts as outside
+	validatePolygonsBySampling(t, triangles.ToPolygonList(), list)
+}
+
+func TestSharedEdges_Squares(t *testing.T) {
+	list := adjacentSquares()
+	triangles := list.Triangulate()
+	assert.Len(t, triangles, 4)
+	assertCoversOnce(t, list, triangles)
+	owners := list.AttributeTriangles(triangles)
+	sort.Ints(owners)
+	assert.Equal(t, []int{0, 0, 1, 1}, owners)
+}
+
+func TestSharedEdges_Options(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"default":    {},
+		"normalized": {AutoNormalize: true},
+		"x axis":     {SweepAxis: XAxis},
+		"mountains":  {Decomposition: Mountains},
+		"merged":     {MergeCollinearEdges: true},
+		"validated":  {Validate: true},
+		"even-odd":   {FillRule: EvenOdd},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for listName, list := range map[string]PolygonList{
+				"squares":       adjacentSquares(),
+				"jagged border": jaggedBorder(),
+			} {
+				var triangles TriangleList
+				require.NotPanics(t, func() {
+					triangles = list.TriangulateWithOptions(opts)
+				}, listName)
+				assertCoversOnce(t, list, triangles)
+			}
+		})
+	}
+}
+
+func TestSharedEdges_Horizontal(t *testing.T) {
+	// Stacked, so the shared edge is horizontal
+	a, b, c, d := &Point{X: 0, Y: 0}, &Point{X: 3, Y: 0}, &Point{X: 3, Y: 1}, &Point{X: 0, Y: 1}
+	e, f := &Point{X: 3, Y: 2}, &Point{X: 0, Y: 2}
+	list := PolygonList{{[]*Point{a, b, c, d}}, {[]*Point{d, c, e, f}}}
+	triangles := list.Triangulate()
+	assert.Len(t, triangles, 4)
+	assertCoversOnce(t, list, triangles)
+	owners := list.AttributeTriangles(triangles)
+	sort.Ints(owners)
+	assert.Equal(t, []int{0, 0, 1, 1}, owners)
+}
+
+// Equal coordinates aren't enough to share an edge, but canonicalizing makes
+// them shared points
+func TestSharedEdges_Coordinates(t *testing.T) {
+	// Rings built from separate coordinate lists, with their own pointers for
+	// the ends of the edge they share
+	list := PolygonList{squareRing(0, 0, 1), squareRing(1, 0, 1)}
+	assert.NoError(t, list.Validate())
+
+	triangles := list.Triangulate()
+	assert.Len(t, triangles, 4)
+	assertCoversOnce(t, list, triangles)
+	owners := list.AttributeTriangles(triangles)
+	sort.Ints(owners)
+	assert.Equal(t, []int{0, 0, 1, 1}, owners)
+
+	// The ends of the shared edge are the first ring's points
+	inputPoints := make(PointSet)
+	for _, p := range list[0].Points {
+		inputPoints.Add(p)
+	}
+	for _, p := range list[1].Points {
+		if p.X != 1 {
+			inputPoints.Add(p)
+		}
+	}
+	for _, tri := range triangles {
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			assert.True(t, inputPoints.Contains(p), "%v", p)
+		}
+	}
+
+	// The jagged border works the same way with copies of its points
+	jagged := jaggedBorder()
+	for i, poly := range jagged {
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			points[j] = &Point{X: p.X, Y: p.Y}
+		}
+		jagged[i] = Polygon{points}
+	}
+	assert.NoError(t, jagged.Validate())
+	assertCoversOnce(t, jagged, jagged.Triangulate())
+}
+
+func TestSharedEdges_Subdivision(t *testing.T) {
+	// A 4×4 grid of cells, every one its own polygon
+	const n = 4
+	var points [n + 1][n + 1]*Point
+	for i := range points {
+		for j := range points[i] {
+			points[i][j] = &Point{X: float64(i), Y: float64(j)}
+		}
+	}
+	var list PolygonList
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			list = append(list, Polygon{[]*Point{points[i][j], points[i+1][j], points[i+1][j+1], points[i][j+1]}})
+		}
+	}
+
+	triangles := list.Triangulate()
+	assert.Len(t, triangles, 2*n*n)
+	assertCoversOnce(t, list, triangles)
+	owners := list.AttributeTriangles(triangles)
+	counts := make([]int, len(list))
+	for i, owner := range owners {
+		require.GreaterOrEqual(t, owner, 0)
+		counts[owner]++
+		// Every triangle is inside its cell
+		centroid := &Point{X: (triangles[i].A.X + triangles[i].B.X + triangles[i].C.X) / 3, Y: (triangles[i].A.Y + triangles[i].B.Y + triangles[i].C.Y) / 3}
+		assert.True(t, list[owner].ContainsPointByEvenOdd(centroid))
+	}
+	for _, count := range counts {
+		assert.Equal(t, 2, count)
+	}
+
+	// The graph treats both sides of each shared edge as inside
+	graph := &QueryGraph{}
+	graph.AddPolygons(list)
+	require.True(t, graph.RingsClosed())
+	region := CompileRegion(graph)
+	for _, p := range []*Point{{X: 1.01, Y: 0.5}, {X: 0.99, Y: 0.5}, {X: 2.5, Y: 2.01}, {X: 2.5, Y: 1.99}, {X: 3.5, Y: 3.5}} {
+		assert.True(t, graph.ContainsPoint(p), "%v", p)
+		assert.True(t, region.Contains(p.X, p.Y), "%v", p)
+	}
+	for _, p := range []*Point{{X: -0.01, Y: 0.5}, {X: 4.01, Y: 2}, {X: 2, Y: 4.01}} {
+		assert.False(t, graph.ContainsPoint(p), "%v", p)
+		assert.False(t, region.Contains(p.X, p.Y), "%v", p)
+	}
+}
+
+// A hole sharing an edge with an island inside it leaves both sides filled,
+// and two holes sharing an edge become one
+func TestSharedEdges_Holes(t *testing.T) {
+	// A 10×10 solid with a hole, and a triangular island in the hole, sharing
+	// the hole's left side
+	outer := squareRing(0, 0, 10)
+	h := []*Point{{X: 2, Y: 2}, {X: 2, Y: 8}, {X: 8, Y: 8}, {X: 8, Y: 2}}
+	island := Polygon{[]*Point{h[1], h[0], {X: 4, Y: 5}}}
+	list := PolygonList{outer, {h}, island}
+	triangles := list.Triangulate()
+	assertCoversOnce(t, list, triangles)
+	owners := list.AttributeTriangles(triangles)
+	area := make([]float64, len(list))
+	for i, tri := range triangles {
+		require.GreaterOrEqual(t, owners[i], 0)
+		area[owners[i]] += tri.SignedArea()
+	}
+	assert.InDelta(t, 100-36, area[0], 1e-9)
+	assert.InDelta(t, 6, area[2], 1e-9)
+
+	// Two holes side by side, sharing an edge
+	left := []*Point{{X: 2, Y: 2}, {X: 2, Y: 8}, {X: 5, Y: 8}, {X: 5, Y: 2}}
+	right := []*Point{left[3], left[2], {X: 8, Y: 8}, {X: 8, Y: 2}}
+	list = PolygonList{outer, {left}, {right}}
+	triangles = list.TriangulateWithOptions(Options{Validate: true})
+	assertCoversOnce(t, list, triangles)
+	for _, owner := range list.AttributeTriangles(triangles) {
+		assert.Equal(t, 0, owner)
+	}
+}
@@ -0,0 +1,260 @@
+package advanced
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Simplification of polygon lists, preserving topology. Vertices are removed in
+// order of the area of the triangle they form with their neighbors
+// (Visvalingam's algorithm), so the least significant detail goes first.
+// Removing a vertex replaces its two edges with a single shortcut edge, and a
+// removal is skipped if that would make the shortcut cross another edge, or
+// sweep over another vertex. So the result is always a valid input for
+// triangulation if the original was.
+//
+// Edges and points are kept in a uniform grid, so that those checks only look
+// at nearby geometry.
+
+type simplifyVertex struct {
+	point      *Point
+	prev, next *simplifyVertex
+	ring       int
+	removed    bool
+	// Bumped whenever the vertex's neighbors change, to invalidate stale heap
+	// entries
+	version int
+	// The edge from this vertex to the next
+	edge *simplifyEdge
+}
+
+type simplifyEdge struct {
+	segment Segment
+}
+
+type simplifyCandidate struct {
+	vertex  *simplifyVertex
+	area    float64
+	version int
+}
+
+type simplifyHeap []simplifyCandidate
+
+func (h simplifyHeap) Len() int            { return len(h) }
+func (h simplifyHeap) Less(i, j int) bool  { return h[i].area < h[j].area }
+func (h simplifyHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *simplifyHeap) Push(x interface{}) { *h = append(*h, x.(simplifyCandidate)) }
+func (h *simplifyHeap) Pop() interface{} {
+	old := *h
+	candidate := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return candidate
+}
+
+type simplifyCell [2]int
+
+type simplifyGrid struct {
+	minX, minY, cellSize float64
+	edges                map[simplifyCell]map[*simplifyEdge]struct{}
+	points               map[simplifyCell][]*simplifyVertex
+}
+
+func (grid *simplifyGrid) cellRange(minX, minY, maxX, maxY float64) (lo, hi simplifyCell) {
+	lo = simplifyCell{int((minX - grid.minX) / grid.cellSize), int((minY - grid.minY) / grid.cellSize)}
+	hi = simplifyCell{int((maxX - grid.minX) / grid.cellSize), int((maxY - grid.minY) / grid.cellSize)}
+	return lo, hi
+}
+
+func (grid *simplifyGrid) segmentCells(s *Segment, visit func(simplifyCell)) {
+	lo, hi := grid.cellRange(
+		math.Min(s.Start.X, s.End.X), math.Min(s.Start.Y, s.End.Y),
+		math.Max(s.Start.X, s.End.X), math.Max(s.Start.Y, s.End.Y),
+	)
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			visit(simplifyCell{x, y})
+		}
+	}
+}
+
+func (grid *simplifyGrid) addEdge(edge *simplifyEdge) {
+	grid.segmentCells(&edge.segment, func(cell simplifyCell) {
+		if grid.edges[cell] == nil {
+			grid.edges[cell] = make(map[*simplifyEdge]struct{})
+		}
+		grid.edges[cell][edge] = struct{}{}
+	})
+}
+
+func (grid *simplifyGrid) removeEdge(edge *simplifyEdge) {
+	grid.segmentCells(&edge.segment, func(cell simplifyCell) {
+		delete(grid.edges[cell], edge)
+	})
+}
+
+// Check whether a segment crosses any edge in the grid, other than the ones
+// given
+func (grid *simplifyGrid) crossesEdge(s *Segment, ignore ...*simplifyEdge) bool {
+	crosses := false
+	grid.segmentCells(s, func(cell simplifyCell) {
+		if crosses {
+			return
+		}
+	edges:
+		for edge := range grid.edges[cell] {
+			for _, ignored := range ignore {
+				if edge == ignored {
+					continue edges
+				}
+			}
+			if segmentsIntersect(s, &edge.segment) {
+				crosses = true
+				return
+			}
+		}
+	})
+	return crosses
+}
+
+// Check whether any vertex other than the triangle's own lies inside or on the
+// triangle
+func (grid *simplifyGrid) containsVertex(tri *Triangle) bool {
+	lo, hi := grid.cellRange(
+		math.Min(tri.A.X, math.Min(tri.B.X, tri.C.X)), math.Min(tri.A.Y, math.Min(tri.B.Y, tri.C.Y)),
+		math.Max(tri.A.X, math.Max(tri.B.X, tri.C.X)), math.Max(tri.A.Y, math.Max(tri.B.Y, tri.C.Y)),
+	)
+	sign := 1.0
+	if IsCW(tri) {
+		sign = -1
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for _, vertex := range grid.points[simplifyCell{x, y}] {
+				p := vertex.point
+				if vertex.removed || p == tri.A || p == tri.B || p == tri.C {
+					continue
+				}
+				if sign*(&Triangle{tri.A, tri.B, p}).SignedArea() >= 0 &&
+					sign*(&Triangle{tri.B, tri.C, p}).SignedArea() >= 0 &&
+					sign*(&Triangle{tri.C, tri.A, p}).SignedArea() >= 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Remove vertices until at most targetCount remain in total, or no more can be
+// removed safely. Every ring keeps at least three vertices. The result shares
+// point pointers with the input.
+func (list PolygonList) simplify(targetCount int) PolygonList {
+	total := 0
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, poly := range list {
+		total += len(poly.Points)
+		for _, p := range poly.Points {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	if total <= targetCount {
+		return list
+	}
+
+	// Aim for about one vertex per cell
+	cellSize := math.Max(maxX-minX, maxY-minY) / math.Sqrt(float64(total))
+	if cellSize == 0 {
+		cellSize = 1
+	}
+	grid := &simplifyGrid{
+		minX:     minX,
+		minY:     minY,
+		cellSize: cellSize,
+		edges:    make(map[simplifyCell]map[*simplifyEdge]struct{}),
+		points:   make(map[simplifyCell][]*simplifyVertex),
+	}
+
+	// Build the rings as linked lists
+	firstVertices := make([]*simplifyVertex, len(list))
+	ringSizes := make([]int, len(list))
+	var candidates simplifyHeap
+	for i, poly := range list {
+		vertices := make([]*simplifyVertex, len(poly.Points))
+		for j, p := range poly.Points {
+			vertices[j] = &simplifyVertex{point: p, ring: i}
+		}
+		for j, vertex := range vertices {
+			vertex.prev = vertices[CircularIndex(j-1, len(vertices))]
+			vertex.next = vertices[CircularIndex(j+1, len(vertices))]
+		}
+		for _, vertex := range vertices {
+			vertex.edge = &simplifyEdge{*NewSegment(vertex.point, vertex.next.point)}
+			grid.addEdge(vertex.edge)
+			lo, _ := grid.cellRange(vertex.point.X, vertex.point.Y, vertex.point.X, vertex.point.Y)
+			grid.points[lo] = append(grid.points[lo], vertex)
+			candidates = append(candidates, simplifyCandidate{vertex, vertex.area(), 0})
+		}
+		firstVertices[i] = vertices[0]
+		ringSizes[i] = len(vertices)
+	}
+	heap.Init(&candidates)
+
+	for total > targetCount && candidates.Len() > 0 {
+		candidate := heap.Pop(&candidates).(simplifyCandidate)
+		vertex := candidate.vertex
+		if vertex.removed || candidate.version != vertex.version || ringSizes[vertex.ring] <= 3 {
+			continue
+		}
+
+		prev, next := vertex.prev, vertex.next
+		shortcut := &simplifyEdge{*NewSegment(prev.point, next.point)}
+		if grid.crossesEdge(&shortcut.segment, prev.edge, vertex.edge) ||
+			grid.containsVertex(&Triangle{prev.point, vertex.point, next.point}) {
+			continue
+		}
+		// A ring of three must still have area
+		if ringSizes[vertex.ring] == 4 && !IsCCW(&Triangle{prev.point, next.point, next.next.point}) &&
+			!IsCW(&Triangle{prev.point, next.point, next.next.point}) {
+			continue
+		}
+
+		grid.removeEdge(prev.edge)
+		grid.removeEdge(vertex.edge)
+		grid.addEdge(shortcut)
+		prev.edge = shortcut
+		prev.next = next
+		next.prev = prev
+		vertex.removed = true
+		if firstVertices[vertex.ring] == vertex {
+			firstVertices[vertex.ring] = next
+		}
+		ringSizes[vertex.ring]--
+		total--
+
+		for _, neighbor := range []*simplifyVertex{prev, next} {
+			neighbor.version++
+			heap.Push(&candidates, simplifyCandidate{neighbor, neighbor.area(), neighbor.version})
+		}
+	}
+
+	result := make(PolygonList, len(list))
+	for i, first := range firstVertices {
+		points := make([]*Point, 0, ringSizes[i])
+		vertex := first
+		for {
+			points = append(points, vertex.point)
+			vertex = vertex.next
+			if vertex == first {
+				break
+			}
+		}
+		result[i] = Polygon{points}
+	}
+	return result
+}
+
+func (vertex *simplifyVertex) area() float64 {
+	return Area(&Triangle{vertex.prev.point, vertex.point, vertex.next.point})
+}
@@ -0,0 +1,234 @@
+package advanced
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Chaikin smoothing: each iteration cuts every corner, replacing the vertex
+// with two points a quarter of the way along each of its edges. A few
+// iterations turn a coarse ring into a rounded one.
+//
+// Cutting a corner only ever moves the boundary within the triangle the corner
+// makes with its two neighbors, so a ring stays simple unless some other part
+// of the ring passes through that triangle, as happens at a tight concave
+// corner next to a thin sliver. After each iteration, the result is swept for
+// intersections, and the corners whose cuts made them cut less, halving the
+// ratio until the intersection goes away. A corner which isn't cut at all is
+// left as it was, so this always ends with a simple ring.
+//
+// Corner cutting doesn't depend on which way a ring runs, so a hole is
+// smoothed just as the same ring would be as a solid, and keeps its winding.
+
+// How far along each edge the cut runs at full strength: Chaikin's quarter
+const chaikinRatio = 0.25
+
+// How many times a corner's cut is halved before the corner is left uncut
+const smoothMaxBackoffs = 8
+
+// Smooth the ring by Chaikin corner cutting, repeated for the given number of
+// iterations. Strength scales the cuts, from 0 for none, to 1 for Chaikin's
+// usual quarter of each edge. The input is not modified, and points which
+// end up uncut are the input points, but every other point is new, with no
+// UserIndex.
+//
+// The ring must be simple, and it stays simple: wherever a cut would make the
+// ring cross itself, that corner is cut less, or not at all. This returns an
+// error if the ring already crosses itself, or the arguments are out of range.
+//
+// Options may optionally be given, in which case the number of times a cut was
+// reduced is recorded in the SmoothBackoffs of the diagnostics.
+func (poly Polygon) Smooth(iterations int, strength float64, opts ...Options) (result Polygon, err error) {
+	list, err := PolygonList{poly}.Smooth(iterations, strength, opts...)
+	if err != nil {
+		return Polygon{}, err
+	}
+	return list[0], nil
+}
+
+// Smooth every ring in the list, as Polygon.Smooth does. Rings are kept from
+// crossing each other as well as themselves, so a hole close to the edge of
+// its solid is still inside it after smoothing.
+func (l PolygonList) Smooth(iterations int, strength float64, opts ...Options) (result PolygonList, err error) {
+	if iterations < 0 {
+		return nil, errors.Errorf("iterations must not be negative, got %d", iterations)
+	}
+	if !(strength >= 0 && strength <= 1) {
+		return nil, errors.Errorf("strength must be between 0 and 1, got %v", strength)
+	}
+	for i, poly := range l {
+		if len(poly.Points) < 3 {
+			return nil, errors.Errorf("polygon %d needs at least 3 points, got %d", i, len(poly.Points))
+		}
+	}
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+
+	rings := make([][]*Point, len(l))
+	for i, poly := range l {
+		rings[i] = poly.Points
+	}
+	if crossing, ok := firstCrossing(rings); ok {
+		return nil, errors.Errorf(
+			"polygon %d edge %d crosses polygon %d edge %d, so it can't be smoothed",
+			crossing[0].ring, crossing[0].edge, crossing[1].ring, crossing[1].edge,
+		)
+	}
+	backoffs := 0
+	for iteration := 0; iteration < iterations && strength > 0; iteration++ {
+		var iterationBackoffs int
+		rings, iterationBackoffs = cutCorners(rings, strength*chaikinRatio)
+		backoffs += iterationBackoffs
+	}
+	if len(opts) > 0 && opts[0].Diagnostics != nil {
+		opts[0].Diagnostics.SmoothBackoffs += backoffs
+	}
+
+	result = make(PolygonList, len(rings))
+	for i, ring := range rings {
+		result[i] = Polygon{ring}
+	}
+	return result, nil
+}
+
+// Cut every corner of the rings by the given ratio, then cut less wherever
+// that made the rings cross. Returns the cut rings, and the number of times a
+// corner's cut was reduced.
+func cutCorners(rings [][]*Point, ratio float64) ([][]*Point, int) {
+	count := 0
+	ratios := make([][]float64, len(rings))
+	backoffs := make([][]int, len(rings))
+	for i, ring := range rings {
+		ratios[i] = make([]float64, len(ring))
+		backoffs[i] = make([]int, len(ring))
+		for j := range ratios[i] {
+			ratios[i][j] = ratio
+		}
+	}
+
+	for {
+		cut := make([][]*Point, len(rings))
+		// The corner of the input each output point came from
+		corners := make([][]int, len(rings))
+		for i, ring := range rings {
+			n := len(ring)
+			for j, p := range ring {
+				r := ratios[i][j]
+				if r == 0 {
+					cut[i] = append(cut[i], p)
+					corners[i] = append(corners[i], j)
+					continue
+				}
+				prev, next := ring[CircularIndex(j-1, n)], ring[CircularIndex(j+1, n)]
+				cut[i] = append(cut[i],
+					fabricatedPoint(p.X+r*(prev.X-p.X), p.Y+r*(prev.Y-p.Y)),
+					fabricatedPoint(p.X+r*(next.X-p.X), p.Y+r*(next.Y-p.Y)),
+				)
+				corners[i] = append(corners[i], j, j)
+			}
+		}
+
+		crossings := sweepCrossings(cut, false, nil)
+		if len(crossings) == 0 {
+			return cut, count
+		}
+
+		// Cut less at every corner an offending edge came from, once each. An
+		// edge between the two points of a cut belongs to that corner alone, and
+		// any other edge runs between two corners.
+		offending := make(map[ringEdge]bool)
+		for _, crossing := range crossings {
+			for _, edge := range crossing {
+				n := len(corners[edge.ring])
+				offending[ringEdge{edge.ring, corners[edge.ring][edge.edge]}] = true
+				offending[ringEdge{edge.ring, corners[edge.ring][CircularIndex(edge.edge+1, n)]}] = true
+			}
+		}
+		backedOff := false
+		for corner := range offending {
+			if ratios[corner.ring][corner.edge] == 0 {
+				continue
+			}
+			backoffs[corner.ring][corner.edge]++
+			ratios[corner.ring][corner.edge] /= 2
+			if backoffs[corner.ring][corner.edge] > smoothMaxBackoffs {
+				ratios[corner.ring][corner.edge] = 0
+			}
+			count++
+			backedOff = true
+		}
+		if !backedOff {
+			// The crossing edges are all uncut, so the rings crossed before cutting
+			fatalf("smoothing found a crossing between uncut edges")
+		}
+	}
+}
+
+// An edge of one of a list of rings, where edge i runs from point i to point
+// i+1, or a corner, where corner i is at point i
+type ringEdge struct {
+	ring, edge int
+}
+
+// Find the first pair of crossing edges in the rings, if any
+func firstCrossing(rings [][]*Point) ([2]ringEdge, bool) {
+	crossings := sweepCrossings(rings, true, nil)
+	if len(crossings) == 0 {
+		return [2]ringEdge{}, false
+	}
+	return crossings[0], true
+}
+
+// Sweep across X to find pairs of edges which cross or touch, other than
+// neighboring edges meeting at their shared point, and pairs allowed by the
+// given function, if any. This stops at the first if asked. Only edges whose X
+// ranges overlap are compared.
+func sweepCrossings(rings [][]*Point, firstOnly bool, allowed func(a, b ringEdge) bool) [][2]ringEdge {
+	return sweepCrossingsWithin(rings, firstOnly, 0, allowed)
+}
+
+// Like sweepCrossings, but edges also count as touching if either comes within
+// tolerance of the other, other than at a shared point. See segmentsWithin.
+func sweepCrossingsWithin(rings [][]*Point, firstOnly bool, tolerance float64, allowed func(a, b ringEdge) bool) [][2]ringEdge {
+	type sweptEdge struct {
+		ringEdge
+		segment    *Segment
+		minX, maxX float64
+	}
+	var edges []sweptEdge
+	for i, ring := range rings {
+		for j, p := range ring {
+			next := ring[CircularIndex(j+1, len(ring))]
+			edges = append(edges, sweptEdge{
+				ringEdge: ringEdge{i, j},
+				segment:  &Segment{Start: p, End: next},
+				minX:     math.Min(p.X, next.X),
+				maxX:     math.Max(p.X, next.X),
+			})
+		}
+	}
+	sort.Slice(edges, func(a, b int) bool {
+		return edges[a].minX < edges[b].minX
+	})
+
+	var crossings [][2]ringEdge
+	for a := range edges {
+		for b := a + 1; b < len(edges) && edges[b].minX <= edges[a].maxX+tolerance; b++ {
+			touching := segmentsIntersect(edges[a].segment, edges[b].segment) ||
+				(tolerance > 0 && segmentsWithin(edges[a].segment, edges[b].segment, tolerance))
+			if touching &&
+				(allowed == nil || !allowed(edges[a].ringEdge, edges[b].ringEdge)) {
+				crossings = append(crossings, [2]ringEdge{edges[a].ringEdge, edges[b].ringEdge})
+				if firstOnly {
+					return crossings
+				}
+			}
+		}
+	}
+	return crossings
+}
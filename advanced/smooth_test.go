@@ -0,0 +1,151 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Triangulate the smoothed list, checking that it's simple, and that the
+// triangles cover it
+func assertSmoothedTriangulates(t *testing.T, smoothed PolygonList) {
+	rings := make([][]*Point, len(smoothed))
+	for i, poly := range smoothed {
+		rings[i] = poly.Points
+	}
+	_, crossed := firstCrossing(rings)
+	require.False(t, crossed, "smoothed rings cross")
+
+	var triangles TriangleList
+	require.NotPanics(t, func() {
+		triangles = smoothed.Triangulate()
+	})
+	var area float64
+	for _, poly := range smoothed {
+		area += poly.SignedArea()
+	}
+	assert.InDelta(t, area, totalArea(triangles), 1e-9*area)
+	validatePolygonsBySampling(t, triangles.ToPolygonList(), smoothed)
+}
+
+func TestSmooth_Star(t *testing.T) {
+	star := SimpleStar()[0]
+	diagnostics := &Diagnostics{}
+	smoothed, err := star.Smooth(3, 1, Options{Diagnostics: diagnostics})
+	require.NoError(t, err)
+	assert.Zero(t, diagnostics.SmoothBackoffs)
+
+	// Every iteration doubles the vertices
+	assert.Len(t, smoothed.Points, 8*len(star.Points))
+	assert.True(t, IsCCW(&smoothed))
+	for _, p := range smoothed.Points {
+		assert.False(t, p.HasUserIndex)
+	}
+	assertSmoothedTriangulates(t, PolygonList{smoothed})
+}
+
+func TestSmooth_Fixtures(t *testing.T) {
+	for name, load := range allFixtures() {
+		t.Run(name, func(t *testing.T) {
+			smoothed, err := load().Smooth(2, 1)
+			require.NoError(t, err)
+			assertSmoothedTriangulates(t, smoothed)
+		})
+	}
+}
+
+// A notch with a needle reaching almost to its far corner. The needle's tip is
+// made of short edges, so cutting it barely shortens it, but the corner's
+// edges are long, so cutting the corner would cross the needle.
+func needleNotch() Polygon {
+	return Polygon{[]*Point{
+		{X: 0, Y: 0}, {X: 16, Y: 0}, {X: 16, Y: 10}, {X: 12, Y: 10},
+		{X: 12, Y: 4.6}, {X: 4.6, Y: 4.6}, {X: 4.3, Y: 4.55}, {X: 4.6, Y: 4.5}, {X: 12, Y: 4.5},
+		{X: 12, Y: 4}, {X: 4, Y: 4}, {X: 4, Y: 10}, {X: 0, Y: 10},
+	}}
+}
+
+func TestSmooth_BacksOff(t *testing.T) {
+	poly := needleNotch()
+	corner := poly.Points[10]
+
+	diagnostics := &Diagnostics{}
+	smoothed, err := poly.Smooth(1, 1, Options{Diagnostics: diagnostics})
+	require.NoError(t, err)
+	assert.Greater(t, diagnostics.SmoothBackoffs, 0)
+	assertSmoothedTriangulates(t, PolygonList{smoothed})
+
+	// Only the offending corners were cut less, so the rest were still cut
+	assert.Greater(t, len(smoothed.Points), len(poly.Points))
+	// The corner's cut runs from a point on its bottom edge, which is closer to
+	// the corner than a full cut's would be
+	found := false
+	for _, p := range smoothed.Points {
+		if p.Y == corner.Y && p.X > corner.X && p.X < 8 {
+			found = true
+			assert.Less(t, p.X-corner.X, 0.25*8)
+		}
+	}
+	assert.True(t, found)
+
+	// Further iterations back off as needed too
+	smoothed, err = poly.Smooth(4, 1)
+	require.NoError(t, err)
+	assertSmoothedTriangulates(t, PolygonList{smoothed})
+}
+
+func TestSmooth_Holes(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10), squareRing(1, 1, 8).Reverse()}
+	smoothed, err := list.Smooth(3, 1)
+	require.NoError(t, err)
+	require.Len(t, smoothed, 2)
+
+	// The hole keeps its winding, and stays inside its solid
+	assert.True(t, IsCCW(&smoothed[0]))
+	assert.True(t, IsCW(&smoothed[1]))
+	assert.Equal(t, []int{-1, 0}, smoothed.ContainingRings())
+	assertSmoothedTriangulates(t, smoothed)
+
+	// Smoothed the same as the same ring as a solid, apart from the direction
+	solid, err := squareRing(1, 1, 8).Smooth(3, 1)
+	require.NoError(t, err)
+	assert.InDelta(t, solid.SignedArea(), -smoothed[1].SignedArea(), 1e-9)
+}
+
+func TestSmooth_Strength(t *testing.T) {
+	square := squareRing(0, 0, 4)
+
+	// No strength or no iterations leaves the ring alone
+	for _, smoothed := range []func() (Polygon, error){
+		func() (Polygon, error) { return square.Smooth(3, 0) },
+		func() (Polygon, error) { return square.Smooth(0, 1) },
+	} {
+		poly, err := smoothed()
+		require.NoError(t, err)
+		assert.Equal(t, square.Points, poly.Points)
+	}
+
+	// Half strength cuts an eighth of each edge
+	half, err := square.Smooth(1, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, Point{X: 0.5, Y: 0}, *half.Points[1])
+	assert.InDelta(t, 16-4*0.5*0.5*0.5, half.SignedArea(), 1e-12)
+}
+
+func TestSmooth_Errors(t *testing.T) {
+	square := squareRing(0, 0, 1)
+	_, err := square.Smooth(-1, 1)
+	assert.Error(t, err)
+	_, err = square.Smooth(1, 1.5)
+	assert.Error(t, err)
+	_, err = square.Smooth(1, -0.5)
+	assert.Error(t, err)
+	_, err = Polygon{square.Points[:2]}.Smooth(1, 1)
+	assert.Error(t, err)
+
+	bowtie := Polygon{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 1}}}
+	_, err = bowtie.Smooth(1, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crosses")
+}
@@ -1,22 +1,195 @@
 package advanced
 
-type TrapezoidSet map[*Trapezoid]struct{}
+// The inside trapezoids of a map, while monotones are extracted from it.
+// Trapezoids are only ever added, and their pending flags say which are still
+// members, so the slice may hold trapezoids which have since been removed.
+type TrapezoidSet []*Trapezoid
 
-// Use a query graph to split a set of polygons into monotone polygons.
-func ConvertToMonotones(list PolygonList) PolygonList {
-	// TODO: QueryGraph should natively support adding all of the polygons at once
-	graph := &QueryGraph{}
-	for _, polygon := range list {
-		graph.AddPolygon(polygon)
+// Add a trapezoid which isn't yet in the set
+func (trapezoids *TrapezoidSet) add(trapezoid *Trapezoid) {
+	trapezoid.pending = true
+	*trapezoids = append(*trapezoids, trapezoid)
+}
+
+// A region divided into trapezoids, from which monotone polygons can be
+// extracted. QueryGraph is the built-in implementation, but another way of
+// trapezoidizing the input can reuse the rest of the pipeline by implementing
+// this, and passing the result to ConvertMapToMonotones.
+//
+// The inside trapezoids must each have both sides, which must be input
+// segments, with the left side pointing down, or an edge shared by two rings.
+// Each must have its top and bottom points set to the polygon vertices on its
+// boundary, and its neighbor lists must link it to the inside trapezoids
+// directly above and below it.
+type TrapezoidMap interface {
+	// Send every trapezoid inside the polygons, each exactly once, and then close
+	// the channel. The channel must be read to the end.
+	InsideTrapezoids() chan *Trapezoid
+}
+
+var _ TrapezoidMap = (*QueryGraph)(nil)
+
+// Use a query graph to split a set of polygons into monotone polygons. Options
+// may optionally be given; only Validate, FillRule, FixWinding, Seed,
+// RebuildDepthFactor and MergeCollinearEdges affect this step.
+func ConvertToMonotones(list PolygonList, opts ...Options) PolygonList {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
 	}
+	if options.Validate {
+		validatePolygons(list)
+	}
+	list = list.applyFillRule(options.fillRule())
 
-	trapezoids := make(TrapezoidSet)
-	for trapezoid := range graph.IterateTrapezoids() {
-		// Skip trapezoids that aren't inside
-		if !trapezoid.IsInside() {
-			continue
+	graph, _ := buildQueryGraph(list, options, nil, false, nil)
+	return graph.convertToMonotones(options)
+}
+
+// Extract the monotone polygons from a trapezoid map built some other way than
+// by a QueryGraph. The map's trapezoids are split along diagonals, so it can
+// only be done once. Options may optionally be given; only MergeCollinearEdges
+// affects this step.
+func ConvertMapToMonotones(trapezoidMap TrapezoidMap, opts ...Options) PolygonList {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return extractMonotones(trapezoidMap, options, nil)
+}
+
+// Extract the monotone polygons from a complete graph. This destroys the query
+// structure of the graph, so it can only be done once.
+func (graph *QueryGraph) convertToMonotones(opts Options) PolygonList {
+	return extractMonotones(graph, opts, graph.buffers)
+}
+
+// The trapezoids inside the polygons, for TrapezoidMap. There are none until
+// the graph's rings are closed.
+func (graph *QueryGraph) InsideTrapezoids() chan *Trapezoid {
+	ch := make(chan *Trapezoid)
+	go func() {
+		seen := make(map[*Trapezoid]struct{})
+		graph.eachInsideTrapezoid(func(trapezoid *Trapezoid) {
+			if _, ok := seen[trapezoid]; ok {
+				return
+			}
+			seen[trapezoid] = struct{}{}
+			ch <- trapezoid
+		})
+		close(ch)
+	}()
+	return ch
+}
+
+// A copy of a graph's inside trapezoids, linked only to each other, so that
+// monotones can be extracted from it without destroying the graph. The graph
+// is kept to describe the segments in error messages.
+type trapezoidSnapshot struct {
+	graph      *QueryGraph
+	trapezoids []*Trapezoid
+}
+
+var _ TrapezoidMap = trapezoidSnapshot{}
+
+func (graph *QueryGraph) snapshotInsideTrapezoids() trapezoidSnapshot {
+	copies := make(map[*Trapezoid]*Trapezoid)
+	snapshot := trapezoidSnapshot{graph: graph}
+	graph.eachInsideTrapezoid(func(trapezoid *Trapezoid) {
+		if _, ok := copies[trapezoid]; ok {
+			return
+		}
+		clone := new(Trapezoid)
+		*clone = *trapezoid
+		clone.Sink = nil
+		clone.pending = false
+		copies[trapezoid] = clone
+		snapshot.trapezoids = append(snapshot.trapezoids, clone)
+	})
+
+	// Outside neighbors are dropped, since splitting a copy would otherwise
+	// relink them to it
+	relink := func(neighbors *TrapezoidNeighborList) {
+		var relinked TrapezoidNeighborList
+		for _, neighbor := range neighbors {
+			if clone, ok := copies[neighbor]; ok {
+				relinked.Add(clone)
+			}
+		}
+		*neighbors = relinked
+	}
+	for _, clone := range snapshot.trapezoids {
+		relink(&clone.TrapezoidsAbove)
+		relink(&clone.TrapezoidsBelow)
+	}
+	return snapshot
+}
+
+func (snapshot trapezoidSnapshot) InsideTrapezoids() chan *Trapezoid {
+	ch := make(chan *Trapezoid)
+	go func() {
+		for _, trapezoid := range snapshot.trapezoids {
+			ch <- trapezoid
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Call f for the trapezoid of each inside sink, which repeats trapezoids which
+// have more than one sink
+func (graph *QueryGraph) eachInsideTrapezoid(f func(*Trapezoid)) {
+	if !graph.RingsClosed() {
+		return
+	}
+	iter := NewGraphIterator(graph.Root)
+	for node := iter.Next(); node != nil; node = iter.Next() {
+		if sink, ok := node.Inner.(SinkNode); ok && sink.Trapezoid.IsInside() {
+			f(sink.Trapezoid)
+		}
+	}
+}
+
+// The number of distinct inside trapezoids in the map
+func (graph *QueryGraph) insideTrapezoidCount() int {
+	seen := make(map[*Trapezoid]struct{})
+	graph.eachInsideTrapezoid(func(trapezoid *Trapezoid) {
+		seen[trapezoid] = struct{}{}
+	})
+	return len(seen)
+}
+
+// Extract the monotone polygons from a trapezoid map, using the given scratch
+// memory, which may be nil.
+func extractMonotones(trapezoidMap TrapezoidMap, opts Options, b *buffers) PolygonList {
+	trapezoids := b.trapezoidSet()
+	add := func(trapezoid *Trapezoid) {
+		// The flag also skips trapezoids seen twice
+		if !trapezoid.pending {
+			trapezoids.add(trapezoid)
+		}
+	}
+	// The graph can be walked directly, without a channel send per trapezoid.
+	// Segments are described by the graph they came from, if it's known.
+	var graph *QueryGraph
+	if g, ok := trapezoidMap.(*QueryGraph); ok {
+		graph = g
+		graph.eachInsideTrapezoid(add)
+	} else if snapshot, ok := trapezoidMap.(trapezoidSnapshot); ok {
+		graph = snapshot.graph
+		for _, trapezoid := range snapshot.trapezoids {
+			add(trapezoid)
+		}
+	} else {
+		for trapezoid := range trapezoidMap.InsideTrapezoids() {
+			add(trapezoid)
 		}
-		trapezoids[trapezoid] = struct{}{}
+	}
+
+	// The input edges have to be gathered before diagonals are added
+	var inputEdges map[locatorEdge]struct{}
+	if opts.MergeCollinearEdges {
+		inputEdges = trapezoids.inputEdges()
 	}
 
 	// This step will turn all trapezoids that should have diagonals (trapezoids
@@ -27,113 +200,175 @@ func ConvertToMonotones(list PolygonList) PolygonList {
 	// trapezoids have been split with segments that do not obey its winding rule.
 	// We will use the trapezoid set instead to determine if a trapezoid is
 	// inside.
-	splitTrapezoidsOnDiagonals(trapezoids)
+	splitTrapezoidsOnDiagonals(&trapezoids)
+	if b != nil {
+		// Keep the grown slice for next time
+		b.trapezoids = trapezoids
+	}
 
 	var result PolygonList
-	for trapezoid := range trapezoids {
+	for _, trapezoid := range trapezoids {
+		if !trapezoid.pending {
+			continue
+		}
 		// Scan to the top trapezoid in the monotone. It will always be degenerate
 		// on top, and therefore have zero neighbors
 		for {
 			aboveNeighbor := trapezoid.TrapezoidsAbove.AnyNeighbor()
-			if aboveNeighbor == nil {
+			if aboveNeighbor == nil || !aboveNeighbor.pending {
 				break
 			}
-			if _, ok := trapezoids[aboveNeighbor]; !ok {
+			trapezoid = aboveNeighbor
+		}
+
+		// Each trapezoid in the chain adds its bottom point to one side of the
+		// ring or the other, so count them first to allocate the ring once
+		count := 1
+		for t := trapezoid; t != nil && t.pending; t = t.TrapezoidsBelow.AnyNeighbor() {
+			count++
+			if t.Bottom == t.Left.Bottom() && t.Bottom == t.Right.Bottom() {
 				break
 			}
-			trapezoid = aboveNeighbor
 		}
 
-		// The top point is on both chains. We arbitrarily put it on the left
-		leftChain := []*Point{trapezoid.Top}
-		var rightChain []*Point
+		// Remembered for error messages
+		topTrapezoid := trapezoid
+
+		// The ring runs down the left chain from the top point, which is on both
+		// chains, and back up the right chain. So the left chain fills the ring
+		// from the front, and the right chain fills it from the back.
+		points := make([]*Point, count)
+		points[0] = trapezoid.Top
+		left, right := 1, count
 
 		// Traverse the trapezoid chain, collecting the points on the trapezoid's boundary
 		for {
 			bottom := trapezoid.Bottom
 			leftBottom := trapezoid.Left.Bottom()
 			rightBottom := trapezoid.Right.Bottom()
+			trapezoid.pending = false // Skip iterating this later
 
 			if bottom == leftBottom && bottom == rightBottom {
 				// We converged, so just put it on the left chain and break
-				leftChain = append(leftChain, bottom)
-				delete(trapezoids, trapezoid)
+				points[left] = bottom
+				left++
 				break
 			}
 
 			// Figure out which chain we're on
 			if bottom == leftBottom {
-				leftChain = append(leftChain, bottom)
+				points[left] = bottom
+				left++
 			} else if bottom == rightBottom {
-				rightChain = append(rightChain, bottom)
+				right--
+				points[right] = bottom
 			} else {
-				fatalf("bottom point was not on either chain")
+				fatalf(
+					"bottom point %v was not on either chain, between %s and %s",
+					bottom, graph.describe(trapezoid.Left), graph.describe(trapezoid.Right),
+				)
 			}
 
-			delete(trapezoids, trapezoid) // Skip iterating this later
 			belowNeighbor := trapezoid.TrapezoidsBelow.AnyNeighbor()
-			if belowNeighbor == nil {
-				break
-			}
-			if _, ok := trapezoids[belowNeighbor]; !ok {
+			if belowNeighbor == nil || !belowNeighbor.pending {
 				break
 			}
 			trapezoid = belowNeighbor
 		}
+		if left != right {
+			fatalf(
+				"monotone chains didn't meet: %d of %d points, between %s and %s",
+				left+count-right, count, graph.describe(topTrapezoid.Left), graph.describe(topTrapezoid.Right),
+			)
+		}
 
-		// Now concatenate all the points from the right chain onto the left in reverse order
-		points := leftChain
-		for i := len(rightChain) - 1; i >= 0; i-- {
-			points = append(points, rightChain[i])
+		if inputEdges != nil {
+			points = mergeCollinearEdges(points, inputEdges)
 		}
 		if len(points) < 3 {
-			fatalf("polygon is degenerate: %#v", points)
+			fatalf(
+				"polygon is degenerate: %v, between %s and %s",
+				points, graph.describe(topTrapezoid.Left), graph.describe(topTrapezoid.Right),
+			)
 		}
 
-		// Add the polygon to the result
+		// Each ring has its own backing array, exactly as long as the ring, so
+		// that appending to one returned ring can never write into another
 		result = append(result, Polygon{points})
 	}
 	return result
 }
 
+// Gather the sides of the inside trapezoids, keyed by their endpoints from
+// bottom to top. Every input edge bounds the inside on one side, and diagonals
+// are only added later, so these are exactly the input edges.
+func (trapezoids TrapezoidSet) inputEdges() map[locatorEdge]struct{} {
+	edges := make(map[locatorEdge]struct{})
+	for _, trapezoid := range trapezoids {
+		for _, side := range []*Segment{trapezoid.Left, trapezoid.Right} {
+			edges[locatorEdge{side.Bottom(), side.Top()}] = struct{}{}
+		}
+	}
+	return edges
+}
+
+// Drop points from a monotone ring which just subdivide a straight input edge:
+// both of the ring's edges at the point are input edges, and the point is
+// within Epsilon of the line through its neighbors. No diagonal can meet such a
+// point, since the filled region's angle there is a straight line, which is
+// entirely taken up by this ring. So dropping it can't leave a T-junction with
+// a neighboring monotone.
+func mergeCollinearEdges(points []*Point, inputEdges map[locatorEdge]struct{}) []*Point {
+	isInputEdge := func(a, b *Point) bool {
+		_, ok := inputEdges[newLocatorEdge(a, b)]
+		return ok
+	}
+
+	classes := Polygon{points}.VertexClassification()
+	result := make([]*Point, 0, len(points))
+	for i, p := range points {
+		prev := points[CircularIndex(i-1, len(points))]
+		next := points[CircularIndex(i+1, len(points))]
+		if classes[i] == Collinear && isInputEdge(prev, p) && isInputEdge(p, next) {
+			continue
+		}
+		result = append(result, p)
+	}
+	// Clip the capacity, so that appending to the ring always reallocates
+	return result[:len(result):len(result)]
+}
+
 // Split all trapezoids with diagonals into two trapezoids, updating the
 // neighbor relationships. Note that this invalidates the query graph, and it
 // breaks the validity of IsInside(), so we cannot use either of those after
 // this has been used.
-func splitTrapezoidsOnDiagonals(trapezoids TrapezoidSet) {
-	for trapezoid := range trapezoids {
-		top := trapezoid.Top
-		bottom := trapezoid.Bottom
-		leftTop := trapezoid.Left.Top()
-		leftBottom := trapezoid.Left.Bottom()
-		rightTop := trapezoid.Right.Top()
-		rightBottom := trapezoid.Right.Bottom()
-
+func splitTrapezoidsOnDiagonals(trapezoids *TrapezoidSet) {
+	// The halves never need diagonals, so only the trapezoids there were to
+	// begin with are checked
+	n := len(*trapezoids)
+	for i := 0; i < n; i++ {
+		trapezoid := (*trapezoids)[i]
 		// Skip if the top and bottom are one of the trapezoid's sides. There's no diagonal in that case
-		if top == leftTop && bottom == leftBottom {
-			continue
-		} else if top == rightTop && bottom == rightBottom {
+		if !trapezoid.NeedsDiagonal() {
 			continue
 		}
 
-		// Split the trapezoid into two trapezoids
-		segment := &Segment{top, bottom}
+		// Split the trapezoid into two trapezoids, which replace it in the set
+		segment := NewSegment(trapezoid.Top, trapezoid.Bottom)
 		leftTrapezoid, rightTrapezoid := trapezoid.SplitBySegment(segment)
-
-		// Remove the old trapezoid
-		delete(trapezoids, trapezoid)
-
-		// Add the trapezoids to the map
-		trapezoids[leftTrapezoid] = struct{}{}
-		trapezoids[rightTrapezoid] = struct{}{}
-
+		trapezoid.pending = false
+		(*trapezoids)[i] = leftTrapezoid
+		trapezoids.add(rightTrapezoid)
 	}
 }
 
 func dbgDrawTrapezoids(trapezoids TrapezoidSet, scale float64) {
 	var list PolygonList
 	// Convert the trapezoids into polygons
-	for trapezoid := range trapezoids {
+	for _, trapezoid := range trapezoids {
+		if !trapezoid.pending {
+			continue
+		}
 		var points []*Point
 		topY := trapezoid.Top.Y
 		bottomY := trapezoid.Bottom.Y
@@ -146,10 +381,10 @@ func dbgDrawTrapezoids(trapezoids TrapezoidSet, scale float64) {
 			rightTopX := trapezoid.Right.SolveForX(topY)
 			rightBottomX := trapezoid.Right.SolveForX(bottomY)
 
-			points = append(points, &Point{leftTopX, topY})
-			points = append(points, &Point{leftBottomX, bottomY})
-			points = append(points, &Point{rightBottomX, bottomY})
-			points = append(points, &Point{rightTopX, topY})
+			points = append(points, &Point{X: leftTopX, Y: topY})
+			points = append(points, &Point{X: leftBottomX, Y: bottomY})
+			points = append(points, &Point{X: rightBottomX, Y: bottomY})
+			points = append(points, &Point{X: rightTopX, Y: topY})
 		}
 		list = append(list, Polygon{points})
 	}
@@ -1,9 +1,12 @@
 package advanced
 
 import (
+	"fmt"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConvertToMonotones_Spiral(t *testing.T) {
@@ -53,3 +56,227 @@ func TestConvertToMonotones_MultiLayeredHoles(t *testing.T) {
 	list := ConvertToMonotones(shape)
 	validatePolygonsBySampling(t, list, shape)
 }
+
+func TestConvertToMonotones_MergeCollinearEdges(t *testing.T) {
+	// A square with its left edge subdivided, and a hole whose vertex rows cross
+	// the square. Subdivision points which end up with a diagonal have to stay,
+	// but the ones in between can go.
+	shape := func() PolygonList {
+		return PolygonList{
+			{[]*Point{
+				{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4},
+				{X: 0, Y: 3.5}, {X: 0, Y: 3}, {X: 0, Y: 2}, {X: 0, Y: 0.75}, {X: 0, Y: 0.5}, {X: 0, Y: 0.25},
+			}},
+			{[]*Point{{X: 2, Y: 1.5}, {X: 2, Y: 2.5}, {X: 3, Y: 2.5}, {X: 3, Y: 1.5}}},
+		}
+	}
+	pointCount := func(list PolygonList) int {
+		count := 0
+		for _, poly := range list {
+			count += len(poly.Points)
+		}
+		return count
+	}
+
+	original := shape()
+	inputEdges := make(map[locatorEdge]struct{})
+	for _, poly := range original {
+		for i, p := range poly.Points {
+			inputEdges[newLocatorEdge(p, poly.Points[CircularIndex(i+1, len(poly.Points))])] = struct{}{}
+		}
+	}
+
+	merged := ConvertToMonotones(original, Options{MergeCollinearEdges: true})
+	validatePolygonsBySampling(t, merged, original)
+	for _, poly := range merged {
+		// Nothing left to merge
+		assert.Equal(t, poly.Points, mergeCollinearEdges(poly.Points, inputEdges))
+	}
+
+	// The default keeps every point
+	unmerged := ConvertToMonotones(shape())
+	assert.Less(t, pointCount(merged), pointCount(unmerged))
+
+	// Same area either way
+	kept := shape().Triangulate()
+	dropped := shape().TriangulateWithOptions(Options{MergeCollinearEdges: true})
+	assert.InDelta(t, totalArea(kept), totalArea(dropped), Epsilon)
+	assert.InDelta(t, 16-1, totalArea(dropped), Epsilon)
+	assert.Less(t, len(dropped), len(kept))
+}
+
+func BenchmarkConvertToMonotones_Circle(b *testing.B) {
+	list := PolygonList{circlePolygon(100, 2000)}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		graph := &QueryGraph{}
+		graph.AddPolygons(list)
+		b.StartTimer()
+		graph.convertToMonotones(Options{})
+	}
+}
+
+// A spiral band with 2*(turns*pointsPerTurn+1) vertices, which is a single
+// ring of very many trapezoids, winding through most of the map
+func spiralBand(turns, pointsPerTurn int) PolygonList {
+	n := turns*pointsPerTurn + 1
+	points := make([]*Point, 2*n)
+	for i := 0; i < n; i++ {
+		turn := float64(i) / float64(pointsPerTurn)
+		radius := 1 + turn
+		cos, sin := math.Cos(2*math.Pi*turn), math.Sin(2*math.Pi*turn)
+		points[i] = &Point{X: (radius + 0.25) * cos, Y: (radius + 0.25) * sin}
+		points[2*n-1-i] = &Point{X: (radius - 0.25) * cos, Y: (radius - 0.25) * sin}
+	}
+	return PolygonList{{points}}
+}
+
+// Compare with BenchmarkSpiral_AddPolygons. Extraction should take a small
+// fraction of the time it takes to build the map.
+func BenchmarkSpiral_ConvertToMonotones(b *testing.B) {
+	list := spiralBand(250, 1000)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		graph := &QueryGraph{}
+		graph.AddPolygons(list)
+		b.StartTimer()
+		graph.convertToMonotones(Options{})
+	}
+}
+
+func BenchmarkSpiral_AddPolygons(b *testing.B) {
+	list := spiralBand(250, 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		graph := &QueryGraph{}
+		graph.AddPolygons(list)
+	}
+}
+
+// A trapezoid map built by hand, standing in for another backend
+type fakeTrapezoidMap []*Trapezoid
+
+func (m fakeTrapezoidMap) InsideTrapezoids() chan *Trapezoid {
+	ch := make(chan *Trapezoid, len(m))
+	for _, trapezoid := range m {
+		ch <- trapezoid
+	}
+	close(ch)
+	return ch
+}
+
+func TestConvertMapToMonotones_FakeBackend(t *testing.T) {
+	// The unit square, divided the way the lexicographic rotation divides it:
+	// its horizontal edges are slightly tilted, so every vertex is at a
+	// different height
+	a, b, c, d := &Point{X: 0, Y: 0}, &Point{X: 1, Y: 0}, &Point{X: 1, Y: 1}, &Point{X: 0, Y: 1}
+	ab, bc, cd, da := NewSegment(a, b), NewSegment(b, c), NewSegment(c, d), NewSegment(d, a)
+	bottom := &Trapezoid{Left: da, Right: ab, Top: b, Bottom: a}
+	middle := &Trapezoid{Left: da, Right: bc, Top: d, Bottom: b}
+	top := &Trapezoid{Left: cd, Right: bc, Top: c, Bottom: d}
+	bottom.TrapezoidsAbove[0] = middle
+	middle.TrapezoidsBelow[0] = bottom
+	middle.TrapezoidsAbove[0] = top
+	top.TrapezoidsBelow[0] = middle
+
+	square := PolygonList{{[]*Point{a, b, c, d}}}
+	monotones := ConvertMapToMonotones(fakeTrapezoidMap{bottom, middle, top})
+	validatePolygonsBySampling(t, monotones, square)
+
+	var triangles TriangleList
+	for _, monotone := range monotones {
+		triangles = append(triangles, TriangulateMonotone(&monotone)...)
+	}
+	assert.Len(t, triangles, 2)
+	assert.InDelta(t, 1, totalArea(triangles), Epsilon)
+	assert.True(t, trianglePoints(triangles).Equals(trianglePoints(square.Triangulate())))
+}
+
+func TestTriangulate_UnknownBackend(t *testing.T) {
+	err := triangulateRecovering(SimpleStar(), Options{Backend: Backend(42)})
+	assert.EqualError(t, err, "unknown backend: 42")
+}
+
+// Axis-aligned trapezoids, with horizontal tops and bottoms, like the quad from
+// the bug report, which once made a two point monotone piece
+func horizontalTrapezoids() map[string]Polygon {
+	quad := func(x, y, scale float64) Polygon {
+		return Polygon{[]*Point{
+			{X: x - 5248*scale, Y: y - 7168*scale},
+			{X: x - 256*scale, Y: y - 7168*scale},
+			{X: x - 1024*scale, Y: y - 5376*scale},
+			{X: x - 5120*scale, Y: y - 5376*scale},
+		}}
+	}
+	result := map[string]Polygon{"issue quad": quad(0, 0, 1)}
+	for _, scale := range []float64{1e-3, 0.37, 1, 2.5, 100} {
+		for _, offset := range [][2]float64{{0, 0}, {5248, 7168}, {-1000.5, 333.25}, {60000, -80000}} {
+			name := fmt.Sprintf("scale %v offset %v", scale, offset)
+			result[name] = quad(offset[0], offset[1], scale)
+		}
+	}
+	// Flipped, narrowing downward, and leaning both ways
+	result["flipped"] = Polygon{[]*Point{{X: -5120, Y: -7168}, {X: -1024, Y: -7168}, {X: -256, Y: -5376}, {X: -5248, Y: -5376}}}
+	result["leaning right"] = Polygon{[]*Point{{X: 0, Y: 0}, {X: 100, Y: 0}, {X: 400, Y: 3}, {X: 300, Y: 3}}}
+	result["leaning left"] = Polygon{[]*Point{{X: 300, Y: 0}, {X: 400, Y: 0}, {X: 100, Y: 3}, {X: 0, Y: 3}}}
+	return result
+}
+
+func TestConvertToMonotones_HorizontalTrapezoids(t *testing.T) {
+	for name, poly := range horizontalTrapezoids() {
+		poly := poly
+		t.Run(name, func(t *testing.T) {
+			require.True(t, IsCCW(&poly))
+			list := PolygonList{poly}
+			for seed := int64(0); seed < 24; seed++ {
+				// Going through the map rather than the lone monotone shortcut
+				monotones := ConvertToMonotones(list, Options{Seed: seed})
+				for _, monotone := range monotones {
+					require.GreaterOrEqual(t, len(monotone.Points), 3, "seed %d", seed)
+				}
+				for _, opts := range []Options{
+					{Seed: seed, MergeCollinearEdges: true},
+					{Seed: seed, Decomposition: Mountains},
+				} {
+					AssertValidTriangulation(t, &poly, list.TriangulateWithOptions(opts))
+				}
+			}
+			AssertValidTriangulation(t, &poly, list.Triangulate())
+		})
+	}
+
+	// Every insertion order of the issue quad's edges, in ring order from each
+	// starting point
+	sorted := Options{shuffleSource: sortedInsertion}
+	quad := horizontalTrapezoids()["issue quad"]
+	for start := range quad.Points {
+		rotated := Polygon{append(append([]*Point(nil), quad.Points[start:]...), quad.Points[:start]...)}
+		for _, monotone := range ConvertToMonotones(PolygonList{rotated}, sorted) {
+			assert.GreaterOrEqual(t, len(monotone.Points), 3, "start %d", start)
+		}
+		AssertValidTriangulation(t, &rotated, PolygonList{rotated}.TriangulateWithOptions(Options{MergeCollinearEdges: true, shuffleSource: sortedInsertion}))
+	}
+}
+
+// Callers may append to the rings they get back, such as to close them, which
+// must not change any other ring
+func TestConvertToMonotones_RingsDontAlias(t *testing.T) {
+	for name, fixture := range allFixtures() {
+		for _, opts := range []Options{{}, {MergeCollinearEdges: true}} {
+			t.Run(fmt.Sprintf("%s/merge=%v", name, opts.MergeCollinearEdges), func(t *testing.T) {
+				monotones := ConvertToMonotones(fixture(), opts)
+				snapshot := make([][]*Point, len(monotones))
+				for i, poly := range monotones {
+					require.Equal(t, len(poly.Points), cap(poly.Points), "ring %d has spare capacity", i)
+					snapshot[i] = append([]*Point(nil), poly.Points...)
+				}
+				for i := range monotones {
+					monotones[i].Points = append(monotones[i].Points, monotones[i].Points[0])
+				}
+				for i, poly := range monotones {
+					assert.Equal(t, snapshot[i], poly.Points[:len(poly.Points)-1])
+				}
+			})
+		}
+	}
+}
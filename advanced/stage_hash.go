@@ -0,0 +1,124 @@
+package advanced
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// Content hashes of the state at the end of each stage of a triangulation, for
+// finding which stage a change in the output comes from. Two runs which agree
+// on a hash agree on everything that went into it, so comparing the hashes of
+// a good run and a bad one points straight at the first stage which diverged.
+// See Options.HashStages.
+//
+// Each hash covers a canonical form of its state, which doesn't depend on
+// pointer values, map order, or where rings and triangles start, so the same
+// state always hashes the same, on any machine. All but Triangles are of the
+// working copy of the input, so they're in the swept space when Options.SweepAxis
+// or Options.AutoNormalize changes it. A lone monotone input skips the
+// trapezoid map, and leaves SegmentOrder and TrapezoidMap zero.
+type StageHashes struct {
+	// The input segments, in the order they were added to the trapezoid map.
+	// This is the only hash which depends on Options.Seed.
+	SegmentOrder uint64
+	// The inside trapezoids of the finished map
+	TrapezoidMap uint64
+	// The monotone pieces the map was split into
+	Monotones uint64
+	// The output triangles, as returned, but in canonical order
+	Triangles uint64
+}
+
+func (hashes StageHashes) String() string {
+	return fmt.Sprintf(
+		"segment order %016x, trapezoid map %016x, monotones %016x, triangles %016x",
+		hashes.SegmentOrder, hashes.TrapezoidMap, hashes.Monotones, hashes.Triangles,
+	)
+}
+
+// Hash the map's inside trapezoids, each by its top, bottom, and the ends of
+// its sides, sorted by those same points
+func hashTrapezoidMap(graph *QueryGraph) uint64 {
+	var keys [][]*Point
+	for t := range graph.InsideTrapezoids() {
+		keys = append(keys, []*Point{t.Top, t.Bottom, t.Left.Start, t.Left.End, t.Right.Start, t.Right.End})
+	}
+	sortPointSequences(keys)
+	return hashPointSequences(keys)
+}
+
+// Hash the rings of a list, each started from its lowest point, in sorted
+// order
+func hashRings(list PolygonList) uint64 {
+	keys := make([][]*Point, len(list))
+	for i, poly := range list {
+		keys[i] = canonicalRing(poly.Points)
+	}
+	sortPointSequences(keys)
+	return hashPointSequences(keys)
+}
+
+// Hash triangles the same way as rings, so that neither the order of the
+// triangles nor which vertex each starts from matters, but their windings do
+func hashTriangles(triangles TriangleList) uint64 {
+	keys := make([][]*Point, len(triangles))
+	for i, tri := range triangles {
+		keys[i] = canonicalRing([]*Point{tri.A, tri.B, tri.C})
+	}
+	sortPointSequences(keys)
+	return hashPointSequences(keys)
+}
+
+// A copy of the ring, rotated to start from its lowest point
+func canonicalRing(points []*Point) []*Point {
+	lowest := 0
+	for i, p := range points {
+		if p.Below(points[lowest]) {
+			lowest = i
+		}
+	}
+	return append(append([]*Point(nil), points[lowest:]...), points[:lowest]...)
+}
+
+// Sort point sequences lexicographically, comparing points with CompareLex,
+// and shorter sequences first where one is a prefix of the other
+func sortPointSequences(sequences [][]*Point) {
+	sort.Slice(sequences, func(i, j int) bool {
+		a, b := sequences[i], sequences[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if c := CompareLex(a[k], b[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return len(a) < len(b)
+	})
+}
+
+func hashPointSequences(sequences [][]*Point) uint64 {
+	h := fnv.New64a()
+	for _, sequence := range sequences {
+		writeHashInt(h, len(sequence))
+		for _, p := range sequence {
+			writeHashPoint(h, p)
+		}
+	}
+	return h.Sum64()
+}
+
+func writeHashPoint(h hash.Hash64, p *Point) {
+	var buf [16]byte
+	// Adding zero turns negative zero into zero, since they're the same point
+	binary.LittleEndian.PutUint64(buf[:8], math.Float64bits(p.X+0))
+	binary.LittleEndian.PutUint64(buf[8:], math.Float64bits(p.Y+0))
+	h.Write(buf[:])
+}
+
+func writeHashInt(h hash.Hash64, n int) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(n))
+	h.Write(buf[:])
+}
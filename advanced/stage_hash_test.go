@@ -0,0 +1,79 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stageHashes(list PolygonList, opts Options) StageHashes {
+	opts.HashStages = true
+	opts.Diagnostics = &Diagnostics{}
+	list.TriangulateWithOptions(opts)
+	return opts.Diagnostics.Hashes
+}
+
+func TestStageHashes(t *testing.T) {
+	list := MultiLayeredHoles()
+	hashes := stageHashes(list, Options{Seed: 7})
+	assert.NotZero(t, hashes.SegmentOrder)
+	assert.NotZero(t, hashes.TrapezoidMap)
+	assert.NotZero(t, hashes.Monotones)
+	assert.NotZero(t, hashes.Triangles)
+
+	// Repeatable, including with fresh copies of the points
+	assert.Equal(t, hashes, stageHashes(list, Options{Seed: 7}))
+	var copied PolygonList
+	for _, poly := range list {
+		var points []*Point
+		for _, p := range poly.Points {
+			copy := *p
+			points = append(points, &copy)
+		}
+		copied = append(copied, Polygon{points})
+	}
+	assert.Equal(t, hashes, stageHashes(copied, Options{Seed: 7}))
+
+	// Another seed changes the order segments go in, but the trapezoid map is
+	// unique, so nothing after that changes
+	reseeded := stageHashes(list, Options{Seed: 8})
+	assert.NotEqual(t, hashes.SegmentOrder, reseeded.SegmentOrder)
+	assert.Equal(t, hashes.TrapezoidMap, reseeded.TrapezoidMap)
+	assert.Equal(t, hashes.Monotones, reseeded.Monotones)
+	assert.Equal(t, hashes.Triangles, reseeded.Triangles)
+
+	// Output order isn't part of any stage
+	assert.Equal(t, hashes, stageHashes(list, Options{Seed: 7, SortOutput: Spatial}))
+
+	// Triangulating the pieces another way can only change the triangles
+	mountains := stageHashes(list, Options{Seed: 7, Decomposition: Mountains})
+	assert.Equal(t, hashes.TrapezoidMap, mountains.TrapezoidMap)
+	assert.Equal(t, hashes.Monotones, mountains.Monotones)
+
+	// Clockwise output changes only the triangles
+	clockwise := stageHashes(list, Options{Seed: 7, OrientTriangle: OrientCW})
+	assert.Equal(t, hashes.Monotones, clockwise.Monotones)
+	assert.NotEqual(t, hashes.Triangles, clockwise.Triangles)
+
+	// Nothing is recorded without the option
+	diagnostics := &Diagnostics{}
+	list.TriangulateWithOptions(Options{Diagnostics: diagnostics})
+	assert.Zero(t, diagnostics.Hashes)
+}
+
+func TestStageHashes_Canonical(t *testing.T) {
+	// Rotating each ring and reordering the rings leave the hashes alone
+	list := SquareWithHole()
+	var rotated PolygonList
+	for i := len(list) - 1; i >= 0; i-- {
+		points := list[i].Points
+		rotated = append(rotated, Polygon{append(append([]*Point(nil), points[1:]...), points[0])})
+	}
+	a, b := stageHashes(list, Options{}), stageHashes(rotated, Options{})
+	assert.Equal(t, a.TrapezoidMap, b.TrapezoidMap)
+	assert.Equal(t, a.Monotones, b.Monotones)
+	assert.Equal(t, a.Triangles, b.Triangles)
+
+	assert.Equal(t, hashRings(PolygonList{{[]*Point{{X: math.Copysign(0, -1), Y: 1}}}}), hashRings(PolygonList{{[]*Point{{X: 0, Y: 1}}}}))
+}
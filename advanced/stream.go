@@ -0,0 +1,137 @@
+package advanced
+
+import "container/list"
+
+// Streaming output, for triangulations too large to hold as a TriangleList.
+// TriangulateFunc hands over each monotone piece's triangles as soon as they're
+// finished, and the writers in stream_formats.go put them straight into a file.
+
+// Receives triangles one at a time, as TriangulateFunc finishes them
+type TriangleSink interface {
+	// Take the next triangle. An error stops the triangulation, and is
+	// returned from TriangulateFunc.
+	Emit(triangle *Triangle) error
+}
+
+// A function as a TriangleSink
+type TriangleSinkFunc func(triangle *Triangle) error
+
+func (f TriangleSinkFunc) Emit(triangle *Triangle) error {
+	return f(triangle)
+}
+
+// Triangulate, passing each triangle to the sink as soon as the monotone piece
+// it's part of is finished, rather than collecting them, so the memory used
+// doesn't grow with the output. The triangles are the same ones
+// TriangulateWithOptions returns, in the same order.
+//
+// Sorting and flipping need every triangle at once, so opts.SortOutput must be
+// Unsorted, opts.Delaunay must be false, and Diagnostics.Hashes.Triangles is
+// left zero.
+func (list PolygonList) TriangulateFunc(opts Options, sink TriangleSink) (err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			err = recoveredErr
+		}
+	}()
+	if opts.SortOutput != Unsorted {
+		fatalf("TriangulateFunc can't sort its output")
+	}
+	if opts.Delaunay {
+		fatalf("TriangulateFunc can't flip its output to Delaunay")
+	}
+	list.triangulateEach(opts, nil, func(triangles TriangleList) {
+		for _, triangle := range triangles {
+			if err := sink.Emit(triangle); err != nil {
+				throw(err)
+			}
+		}
+	})
+	return nil
+}
+
+// The number of triangles a triangulation of valid input has: each solid ring
+// of n points gives n-2, and each hole adds its point count plus 2. Dropping
+// zero area triangles can make the output smaller than this. Like Polygon,
+// solids must run counterclockwise, and holes clockwise.
+func ExpectedTriangleCount(list PolygonList) int64 {
+	var count int64
+	for _, poly := range list {
+		count += int64(len(poly.Points))
+		if IsCW(&poly) {
+			count += 2
+		} else {
+			count -= 2
+		}
+	}
+	return count
+}
+
+// Options for the streaming writers
+type StreamOptions struct {
+	// How many points to remember the indexes of, so that a point shared by
+	// several triangles is written once. The least recently used point is
+	// forgotten first, and written again if a later triangle uses it, so a
+	// small capacity costs some duplicate vertices, but bounds the memory.
+	// Zero means DefaultStreamIndexCapacity, and a negative capacity remembers
+	// every point.
+	IndexCapacity int
+}
+
+// The default for StreamOptions.IndexCapacity. Triangles from one monotone
+// piece share most of their points with triangles nearby in the stream, so
+// this catches nearly every repeat for pieces of any reasonable size.
+const DefaultStreamIndexCapacity = 1 << 16
+
+func streamOptions(opts []StreamOptions) StreamOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return StreamOptions{}
+}
+
+// Indexes of points already written, forgetting the least recently used past
+// its capacity
+type vertexIndex struct {
+	capacity int
+	entries  map[*Point]*list.Element
+	// Most recently used at the front
+	order *list.List
+}
+
+type vertexIndexEntry struct {
+	point *Point
+	index int64
+}
+
+func newVertexIndex(opts StreamOptions) *vertexIndex {
+	capacity := opts.IndexCapacity
+	if capacity == 0 {
+		capacity = DefaultStreamIndexCapacity
+	}
+	return &vertexIndex{
+		capacity: capacity,
+		entries:  make(map[*Point]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get the index of a point, if it's remembered
+func (v *vertexIndex) lookup(p *Point) (int64, bool) {
+	element, ok := v.entries[p]
+	if !ok {
+		return 0, false
+	}
+	v.order.MoveToFront(element)
+	return element.Value.(vertexIndexEntry).index, true
+}
+
+// Remember the index of a point which isn't remembered yet
+func (v *vertexIndex) add(p *Point, index int64) {
+	if v.capacity > 0 && len(v.entries) >= v.capacity {
+		oldest := v.order.Back()
+		v.order.Remove(oldest)
+		delete(v.entries, oldest.Value.(vertexIndexEntry).point)
+	}
+	v.entries[p] = v.order.PushFront(vertexIndexEntry{p, index})
+}
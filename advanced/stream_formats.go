@@ -0,0 +1,320 @@
+package advanced
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Writers which stream triangles from TriangulateFunc into a file, a triangle
+// at a time. Each is a TriangleSink, and must be closed after the last triangle
+// to finish the file. Closing doesn't close the underlying writer.
+
+// A TriangleSink writing a file, which Close finishes
+type TriangleWriter interface {
+	TriangleSink
+	Close() error
+}
+
+// Stream triangles to an OBJ mesh, like WriteOBJ without UVs. Vertices are
+// written as the triangles first use them, and faces as the triangles arrive,
+// so vertices and faces are interleaved, which OBJ allows. See StreamOptions
+// for how shared points are found.
+func StreamOBJ(w io.Writer, opts ...StreamOptions) TriangleWriter {
+	return &objStream{
+		writer: bufio.NewWriter(w),
+		index:  newVertexIndex(streamOptions(opts)),
+	}
+}
+
+type objStream struct {
+	writer   *bufio.Writer
+	index    *vertexIndex
+	vertices int64
+}
+
+func (s *objStream) Emit(triangle *Triangle) error {
+	var references [3]int64
+	for i, p := range [3]*Point{triangle.A, triangle.B, triangle.C} {
+		reference, ok := s.index.lookup(p)
+		if !ok {
+			s.vertices++
+			reference = s.vertices
+			s.index.add(p, reference)
+			fmt.Fprintf(s.writer, "v %s %s 0\n", formatOBJFloat(p.X), formatOBJFloat(p.Y))
+		}
+		references[i] = reference
+	}
+	// The writer's error sticks, so this reports any earlier write's too
+	_, err := fmt.Fprintf(s.writer, "f %d %d %d\n", references[0], references[1], references[2])
+	return err
+}
+
+func (s *objStream) Close() error {
+	return s.writer.Flush()
+}
+
+// Stream triangles to a binary little endian PLY mesh, with Z=0. PLY puts every
+// vertex before every face, so rather than holding the faces back, each
+// triangle gets its own three vertices, written as it arrives, and the faces,
+// which are then just consecutive triples, are written on closing. So unlike
+// the other streams, no points are shared, and StreamOptions doesn't apply.
+//
+// The header counts the vertices and faces. If count isn't negative, it's
+// written as the number of triangles, as ExpectedTriangleCount gives it. If
+// count is negative, or turns out to be wrong, w must be an io.WriteSeeker,
+// for closing to go back and correct the header, or closing fails.
+func StreamPLY(w io.Writer, count int64) TriangleWriter {
+	s := &plyStream{
+		destination: w,
+		writer:      bufio.NewWriter(w),
+		count:       count,
+	}
+	if count < 0 {
+		if _, ok := w.(io.WriteSeeker); !ok {
+			s.err = errors.New("a PLY stream without a triangle count needs an io.WriteSeeker")
+			return s
+		}
+		count = 0
+	}
+	_, s.err = s.writer.WriteString(plyHeader(count))
+	return s
+}
+
+type plyStream struct {
+	destination io.Writer
+	writer      *bufio.Writer
+	// The count in the header, or negative if there isn't one yet
+	count     int64
+	triangles int64
+	err       error
+}
+
+// PLY face indexes are 32 bit, so the vertices must fit
+const maxPLYTriangles = math.MaxInt32 / 3
+
+// The header, with the counts padded to a fixed width, so that a header with
+// the correct counts can be written over it
+func plyHeader(triangles int64) string {
+	return fmt.Sprintf(
+		"ply\n"+
+			"format binary_little_endian 1.0\n"+
+			"element vertex %-20d\n"+
+			"property double x\n"+
+			"property double y\n"+
+			"property double z\n"+
+			"element face %-20d\n"+
+			"property list uchar int vertex_indices\n"+
+			"end_header\n",
+		3*triangles, triangles,
+	)
+}
+
+func (s *plyStream) Emit(triangle *Triangle) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.triangles == maxPLYTriangles {
+		s.err = errors.Errorf("a PLY stream can hold at most %d triangles", maxPLYTriangles)
+		return s.err
+	}
+	s.triangles++
+	var buf [24]byte
+	for _, p := range [3]*Point{triangle.A, triangle.B, triangle.C} {
+		binary.LittleEndian.PutUint64(buf[:8], math.Float64bits(p.X))
+		binary.LittleEndian.PutUint64(buf[8:16], math.Float64bits(p.Y))
+		binary.LittleEndian.PutUint64(buf[16:], math.Float64bits(0))
+		if _, s.err = s.writer.Write(buf[:]); s.err != nil {
+			return s.err
+		}
+	}
+	return nil
+}
+
+func (s *plyStream) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	var face [13]byte
+	face[0] = 3
+	for i := int64(0); i < s.triangles; i++ {
+		for j := 0; j < 3; j++ {
+			binary.LittleEndian.PutUint32(face[1+4*j:], uint32(3*i+int64(j)))
+		}
+		if _, err := s.writer.Write(face[:]); err != nil {
+			return err
+		}
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if s.count == s.triangles {
+		return nil
+	}
+
+	seeker, ok := s.destination.(io.WriteSeeker)
+	if !ok {
+		return errors.Errorf("PLY header promised %d triangles, but %d were written, and the writer can't seek back to correct it", s.count, s.triangles)
+	}
+	end, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	// Assume the stream started where the header did
+	headerLength := int64(len(plyHeader(0)))
+	// Three vertices of three doubles, and a face of a count and three ints
+	dataLength := (3*24 + 13) * s.triangles
+	if _, err := seeker.Seek(end-dataLength-headerLength, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(seeker, plyHeader(s.triangles)); err != nil {
+		return err
+	}
+	_, err = seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+// The binary stream format: a magic string and version, then records, each a
+// tag byte followed by little endian fields. Vertices are numbered from 0 in
+// the order they appear, and each face refers back to three of them. The end
+// record holds the counts, so a truncated stream is detected.
+const (
+	triangleStreamMagic   = "TRISTRM"
+	triangleStreamVersion = 1
+
+	// X and Y as float64
+	triangleStreamVertex = 'v'
+	// Three vertex numbers as uint64
+	triangleStreamFace = 'f'
+	// The number of vertices and faces as uint64
+	triangleStreamEnd = 'e'
+)
+
+// Stream triangles in a compact binary format, read back by
+// ReadTriangleStream. Like StreamOBJ, each vertex is written when a triangle
+// first uses it, and faces as the triangles arrive. See StreamOptions for how
+// shared points are found.
+func StreamBinary(w io.Writer, opts ...StreamOptions) TriangleWriter {
+	s := &binaryStream{
+		writer: bufio.NewWriter(w),
+		index:  newVertexIndex(streamOptions(opts)),
+	}
+	s.writer.WriteString(triangleStreamMagic)
+	s.writer.WriteByte(triangleStreamVersion)
+	return s
+}
+
+type binaryStream struct {
+	writer    *bufio.Writer
+	index     *vertexIndex
+	vertices  int64
+	triangles int64
+}
+
+func (s *binaryStream) Emit(triangle *Triangle) error {
+	var buf [25]byte
+	var face [3]int64
+	for i, p := range [3]*Point{triangle.A, triangle.B, triangle.C} {
+		number, ok := s.index.lookup(p)
+		if !ok {
+			number = s.vertices
+			s.vertices++
+			s.index.add(p, number)
+			buf[0] = triangleStreamVertex
+			binary.LittleEndian.PutUint64(buf[1:], math.Float64bits(p.X))
+			binary.LittleEndian.PutUint64(buf[9:], math.Float64bits(p.Y))
+			s.writer.Write(buf[:17])
+		}
+		face[i] = number
+	}
+	s.triangles++
+	buf[0] = triangleStreamFace
+	for i, number := range face {
+		binary.LittleEndian.PutUint64(buf[1+8*i:], uint64(number))
+	}
+	// The writer's error sticks, so this reports any earlier write's too
+	_, err := s.writer.Write(buf[:])
+	return err
+}
+
+func (s *binaryStream) Close() error {
+	var buf [17]byte
+	buf[0] = triangleStreamEnd
+	binary.LittleEndian.PutUint64(buf[1:], uint64(s.vertices))
+	binary.LittleEndian.PutUint64(buf[9:], uint64(s.triangles))
+	s.writer.Write(buf[:])
+	return s.writer.Flush()
+}
+
+// Read triangles written by StreamBinary. Vertices written once are shared by
+// every triangle which refers to them.
+func ReadTriangleStream(r io.Reader) (TriangleList, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, len(triangleStreamMagic)+1)
+	if _, err := io.ReadFull(reader, header); err != nil || string(header[:len(triangleStreamMagic)]) != triangleStreamMagic {
+		return nil, errors.New("not a triangle stream")
+	}
+	if version := header[len(triangleStreamMagic)]; version != triangleStreamVersion {
+		return nil, errors.Errorf("unsupported triangle stream version %d", version)
+	}
+
+	var vertices []*Point
+	var triangles TriangleList
+	var buf [24]byte
+	read := func(fields int) ([]uint64, error) {
+		if _, err := io.ReadFull(reader, buf[:8*fields]); err != nil {
+			return nil, errors.Wrap(err, "truncated triangle stream")
+		}
+		values := make([]uint64, fields)
+		for i := range values {
+			values[i] = binary.LittleEndian.Uint64(buf[8*i:])
+		}
+		return values, nil
+	}
+	for {
+		tag, err := reader.ReadByte()
+		if err != nil {
+			return nil, errors.Wrap(err, "truncated triangle stream")
+		}
+		switch tag {
+		case triangleStreamVertex:
+			values, err := read(2)
+			if err != nil {
+				return nil, err
+			}
+			vertices = append(vertices, fabricatedPoint(math.Float64frombits(values[0]), math.Float64frombits(values[1])))
+
+		case triangleStreamFace:
+			values, err := read(3)
+			if err != nil {
+				return nil, err
+			}
+			for _, number := range values {
+				if number >= uint64(len(vertices)) {
+					return nil, errors.Errorf("face %d refers to vertex %d, with %d vertices so far", len(triangles), number, len(vertices))
+				}
+			}
+			triangles = append(triangles, &Triangle{vertices[values[0]], vertices[values[1]], vertices[values[2]]})
+
+		case triangleStreamEnd:
+			values, err := read(2)
+			if err != nil {
+				return nil, err
+			}
+			if values[0] != uint64(len(vertices)) || values[1] != uint64(len(triangles)) {
+				return nil, errors.Errorf(
+					"triangle stream ends claiming %d vertices and %d faces, but has %d and %d",
+					values[0], values[1], len(vertices), len(triangles),
+				)
+			}
+			return triangles, nil
+
+		default:
+			return nil, errors.Errorf("unknown triangle stream record %q", tag)
+		}
+	}
+}
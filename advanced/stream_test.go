@@ -0,0 +1,261 @@
+package advanced
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spiralList() PolygonList {
+	return PolygonList{*LoadFixture("spiral")}
+}
+
+// The corners of each triangle, by value, for comparing meshes which don't
+// share points
+func triangleCorners(triangles TriangleList) [][3]Point {
+	corners := make([][3]Point, len(triangles))
+	for i, tri := range triangles {
+		corners[i] = [3]Point{{X: tri.A.X, Y: tri.A.Y}, {X: tri.B.X, Y: tri.B.Y}, {X: tri.C.X, Y: tri.C.Y}}
+	}
+	return corners
+}
+
+func objMeshCorners(mesh objMesh) [][3]Point {
+	corners := make([][3]Point, len(mesh.faces))
+	for i, face := range mesh.faces {
+		for j, corner := range face {
+			v := mesh.vertices[corner[0]]
+			corners[i][j] = Point{X: v[0], Y: v[1]}
+		}
+	}
+	return corners
+}
+
+func TestTriangulateFunc(t *testing.T) {
+	for name, fixture := range allFixtures() {
+		t.Run(name, func(t *testing.T) {
+			var streamed TriangleList
+			err := fixture().TriangulateFunc(Options{}, TriangleSinkFunc(func(triangle *Triangle) error {
+				streamed = append(streamed, triangle)
+				return nil
+			}))
+			require.NoError(t, err)
+			assert.Equal(t, triangleCorners(fixture().Triangulate()), triangleCorners(streamed))
+		})
+	}
+}
+
+func TestTriangulateFunc_Errors(t *testing.T) {
+	stop := errors.New("stop")
+	count := 0
+	err := spiralList().TriangulateFunc(Options{}, TriangleSinkFunc(func(*Triangle) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+		return nil
+	}))
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 3, count)
+
+	err = spiralList().TriangulateFunc(Options{SortOutput: Spatial}, TriangleSinkFunc(func(*Triangle) error { return nil }))
+	assert.EqualError(t, err, "TriangulateFunc can't sort its output")
+}
+
+func TestExpectedTriangleCount(t *testing.T) {
+	for name, fixture := range allFixtures() {
+		assert.EqualValues(t, len(fixture().Triangulate()), ExpectedTriangleCount(fixture()), name)
+	}
+}
+
+func TestStreamOBJ_Spiral(t *testing.T) {
+	list := spiralList()
+	var buffer bytes.Buffer
+	stream := StreamOBJ(&buffer)
+	require.NoError(t, list.TriangulateFunc(Options{}, stream))
+	require.NoError(t, stream.Close())
+
+	triangles := list.Triangulate()
+	var exported bytes.Buffer
+	require.NoError(t, WriteOBJ(&exported, triangles, OBJOptions{}))
+
+	mesh := parseOBJMesh(t, buffer.Bytes())
+	// Every point is remembered, so the vertices are the same as exporting
+	// writes, if not in the same order
+	assert.ElementsMatch(t, parseOBJMesh(t, exported.Bytes()).vertices, mesh.vertices)
+	assert.Equal(t, triangleCorners(triangles), objMeshCorners(mesh))
+}
+
+func TestStreamOBJ_IndexCapacity(t *testing.T) {
+	list := spiralList()
+	triangles := list.Triangulate()
+	const capacity = 8
+	var buffer bytes.Buffer
+	stream := StreamOBJ(&buffer, StreamOptions{IndexCapacity: capacity})
+	index := stream.(*objStream).index
+	for _, triangle := range triangles {
+		require.NoError(t, stream.Emit(triangle))
+		require.LessOrEqual(t, len(index.entries), capacity)
+		require.Equal(t, len(index.entries), index.order.Len())
+	}
+	require.NoError(t, stream.Close())
+
+	// Forgotten points are written again, but the geometry is the same
+	mesh := parseOBJMesh(t, buffer.Bytes())
+	assert.Greater(t, len(mesh.vertices), len(list[0].Points))
+	assert.Equal(t, triangleCorners(triangles), objMeshCorners(mesh))
+}
+
+func TestVertexIndex(t *testing.T) {
+	a, b, c := &Point{}, &Point{}, &Point{}
+	index := newVertexIndex(StreamOptions{IndexCapacity: 2})
+	index.add(a, 1)
+	index.add(b, 2)
+	// Looking up a makes b the least recently used
+	_, ok := index.lookup(a)
+	assert.True(t, ok)
+	index.add(c, 3)
+	_, ok = index.lookup(b)
+	assert.False(t, ok)
+	value, ok := index.lookup(a)
+	assert.True(t, ok)
+	assert.EqualValues(t, 1, value)
+
+	unbounded := newVertexIndex(StreamOptions{IndexCapacity: -1})
+	for i := 0; i < 100; i++ {
+		unbounded.add(&Point{}, int64(i))
+	}
+	assert.Len(t, unbounded.entries, 100)
+}
+
+// Just enough of a PLY parser to check StreamPLY's output
+func parsePLY(t *testing.T, data []byte) TriangleList {
+	reader := bufio.NewReader(bytes.NewReader(data))
+	counts := map[string]int{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		fields := strings.Fields(line)
+		if fields[0] == "end_header" {
+			break
+		}
+		if fields[0] == "element" {
+			count, err := strconv.Atoi(fields[2])
+			require.NoError(t, err)
+			counts[fields[1]] = count
+		}
+	}
+	vertices := make([]*Point, counts["vertex"])
+	var buf [24]byte
+	for i := range vertices {
+		_, err := io.ReadFull(reader, buf[:])
+		require.NoError(t, err)
+		vertices[i] = &Point{
+			X: math.Float64frombits(binary.LittleEndian.Uint64(buf[:])),
+			Y: math.Float64frombits(binary.LittleEndian.Uint64(buf[8:])),
+		}
+		require.Zero(t, math.Float64frombits(binary.LittleEndian.Uint64(buf[16:])))
+	}
+	triangles := make(TriangleList, counts["face"])
+	for i := range triangles {
+		_, err := io.ReadFull(reader, buf[:13])
+		require.NoError(t, err)
+		require.EqualValues(t, 3, buf[0])
+		vertex := func(j int) *Point {
+			return vertices[binary.LittleEndian.Uint32(buf[1+4*j:])]
+		}
+		triangles[i] = &Triangle{vertex(0), vertex(1), vertex(2)}
+	}
+	_, err := reader.ReadByte()
+	assert.Equal(t, io.EOF, err, "data after the faces")
+	return triangles
+}
+
+func TestStreamPLY(t *testing.T) {
+	list := spiralList()
+	triangles := list.Triangulate()
+
+	t.Run("counted", func(t *testing.T) {
+		var buffer bytes.Buffer
+		stream := StreamPLY(&buffer, ExpectedTriangleCount(list))
+		require.NoError(t, list.TriangulateFunc(Options{}, stream))
+		require.NoError(t, stream.Close())
+		assert.Equal(t, triangleCorners(triangles), triangleCorners(parsePLY(t, buffer.Bytes())))
+	})
+
+	t.Run("miscounted without seeking", func(t *testing.T) {
+		var buffer bytes.Buffer
+		stream := StreamPLY(&buffer, 3)
+		require.NoError(t, list.TriangulateFunc(Options{}, stream))
+		assert.Error(t, stream.Close())
+	})
+
+	t.Run("uncounted without seeking", func(t *testing.T) {
+		var buffer bytes.Buffer
+		stream := StreamPLY(&buffer, -1)
+		assert.Error(t, stream.Emit(triangles[0]))
+		assert.Error(t, stream.Close())
+	})
+
+	for _, count := range []int64{-1, 3} {
+		t.Run("corrected by seeking", func(t *testing.T) {
+			file, err := os.Create(filepath.Join(t.TempDir(), "mesh.ply"))
+			require.NoError(t, err)
+			defer file.Close()
+			// The header needn't be at the start of the file
+			_, err = file.WriteString("preamble")
+			require.NoError(t, err)
+
+			stream := StreamPLY(file, count)
+			require.NoError(t, list.TriangulateFunc(Options{}, stream))
+			require.NoError(t, stream.Close())
+
+			data, err := os.ReadFile(file.Name())
+			require.NoError(t, err)
+			require.True(t, bytes.HasPrefix(data, []byte("preamble")))
+			assert.Equal(t, triangleCorners(triangles), triangleCorners(parsePLY(t, data[len("preamble"):])))
+		})
+	}
+}
+
+func TestStreamBinary(t *testing.T) {
+	list := spiralList()
+	triangles := list.Triangulate()
+	for _, capacity := range []int{0, 4} {
+		var buffer bytes.Buffer
+		stream := StreamBinary(&buffer, StreamOptions{IndexCapacity: capacity})
+		require.NoError(t, list.TriangulateFunc(Options{}, stream))
+		require.NoError(t, stream.Close())
+
+		read, err := ReadTriangleStream(bytes.NewReader(buffer.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, triangleCorners(triangles), triangleCorners(read))
+		if capacity == 0 {
+			// Shared points stay shared
+			distinct := make(PointSet)
+			for _, tri := range read {
+				distinct.Add(tri.A)
+				distinct.Add(tri.B)
+				distinct.Add(tri.C)
+			}
+			assert.Len(t, distinct, len(list[0].Points))
+		}
+
+		_, err = ReadTriangleStream(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1]))
+		assert.Error(t, err)
+	}
+
+	_, err := ReadTriangleStream(strings.NewReader("not a stream"))
+	assert.EqualError(t, err, "not a triangle stream")
+}
@@ -0,0 +1,304 @@
+package advanced
+
+import (
+	"math"
+	"sort"
+)
+
+// Subtraction of one polygon list from another. This is one step short of full
+// polygon booleans: the result is the solid area with the cutters' area taken
+// away, which is enough to stamp holes which hang off the edge of a solid, or
+// cut a solid in two.
+//
+// The boundary of the result is made of pieces of both boundaries. Every edge
+// is split wherever it meets an edge of the other list, which leaves pieces
+// that are each entirely inside or outside of the other list's area, so each
+// can be classified by its midpoint, using a trapezoid map of the other list.
+// Solid pieces outside the cutters are kept as they are, and cutter pieces
+// inside the solids are kept reversed, since they bound the new holes. Where a
+// piece of each runs along the same line, the solid's piece is kept only if the
+// cutter is on its outside. The kept pieces are then linked back into rings.
+//
+// Rings which don't meet the other list at all skip all of that, and are kept
+// or dropped whole, with their own points, so a cutter which is entirely inside
+// a solid becomes a plain hole.
+
+// Subtract the area of the cutters from the area of the list, returning rings
+// ready for triangulation: counterclockwise solids, with clockwise holes. The
+// list must be valid input for triangulation, with its rings wound as they
+// would be for Triangulate. The cutters must be valid too, so cutters which
+// overlap each other need merging first, but their windings don't matter,
+// since they're wound by how deeply they're nested, as with FillRule EvenOdd.
+//
+// Points of the input are kept where the boundary passes through them, and
+// wherever an edge of one list meets an edge of the other, there's a new point
+// with no UserIndex. A solid which the cutters split into several parts comes
+// out as several solids. Errors are returned for input which isn't valid, such
+// as crossing edges within either list.
+func (l PolygonList) Subtract(cutters PolygonList) (result PolygonList, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+	checkCoordinateRange(l)
+	checkCoordinateRange(cutters)
+	validatePolygons(l)
+	validatePolygons(cutters)
+	cutters = cutters.applyFillRule(EvenOdd)
+	if len(l) == 0 || len(cutters) == 0 {
+		return append(PolygonList(nil), l...), nil
+	}
+
+	solidGraph := &QueryGraph{}
+	solidGraph.AddPolygons(l)
+	cutterGraph := &QueryGraph{}
+	cutterGraph.AddPolygons(cutters)
+
+	rings := make([][]*Point, 0, len(l)+len(cutters))
+	for _, poly := range append(append(PolygonList(nil), l...), cutters...) {
+		rings = append(rings, poly.Points)
+	}
+	isCutter := func(ring int) bool {
+		return ring >= len(l)
+	}
+	splits, split := splitsBetween(rings, isCutter)
+
+	var untouched, holes PolygonList
+	var pieces []boundaryPiece
+	for i, ring := range rings {
+		if !split[i] {
+			mid := edgeMidpoint(ring[0], ring[1])
+			if !isCutter(i) && !cutterGraph.ContainsPoint(mid) {
+				untouched = append(untouched, l[i])
+			} else if isCutter(i) && solidGraph.ContainsPoint(mid) {
+				holes = append(holes, cutters[i-len(l)].Reverse())
+			}
+			continue
+		}
+		for j := range ring {
+			pieces = append(pieces, splitEdge(splits, ringEdge{i, j}, isCutter(i))...)
+		}
+	}
+
+	kept := classifyPieces(pieces, solidGraph, cutterGraph)
+	return append(append(untouched, linkPieces(kept)...), holes...), nil
+}
+
+// A piece of an edge, running the same way as the edge, between two points
+// where the edge meets the other list, or its own ends
+type boundaryPiece struct {
+	start, end *Point
+	cutter     bool
+}
+
+func edgeMidpoint(a, b *Point) *Point {
+	return fabricatedPoint((a.X+b.X)/2, (a.Y+b.Y)/2)
+}
+
+// The points each edge of a list of rings is split at
+type edgeSplits struct {
+	// The rings' points, canonicalized
+	ends [][]*Point
+	// The points where each edge meets the other list, in no particular order
+	points map[ringEdge][]*Point
+}
+
+// Find where the edges of the solids meet the edges of the cutters, returning
+// the split points, and whether each ring has any. Points are canonicalized by
+// their coordinates, so that a point of each list in the same place becomes
+// one point, and the pieces which meet there link up.
+func splitsBetween(rings [][]*Point, isCutter func(ring int) bool) (*edgeSplits, []bool) {
+	canonical := make(map[[2]float64]*Point)
+	canonicalize := func(p *Point) *Point {
+		// Adding zero turns negative zero into zero, since they're the same point
+		key := [2]float64{p.X + 0, p.Y + 0}
+		if existing, ok := canonical[key]; ok {
+			return existing
+		}
+		canonical[key] = p
+		return p
+	}
+	splits := &edgeSplits{
+		ends:   make([][]*Point, len(rings)),
+		points: make(map[ringEdge][]*Point),
+	}
+	for i, ring := range rings {
+		splits.ends[i] = make([]*Point, len(ring))
+		for j, p := range ring {
+			splits.ends[i][j] = canonicalize(p)
+		}
+	}
+
+	split := make([]bool, len(rings))
+	// Only edges from different lists need splitting, since each list is valid
+	// on its own
+	sameList := func(a, b ringEdge) bool {
+		return isCutter(a.ring) == isCutter(b.ring)
+	}
+	for _, crossing := range sweepCrossings(rings, false, sameList) {
+		a, b := crossing[0], crossing[1]
+		aSegment := &Segment{Start: splits.ends[a.ring][a.edge], End: splits.edgeEnd(a)}
+		bSegment := &Segment{Start: splits.ends[b.ring][b.edge], End: splits.edgeEnd(b)}
+		for _, p := range meetingPoints(aSegment, bSegment) {
+			p = canonicalize(p)
+			splits.points[a] = append(splits.points[a], p)
+			splits.points[b] = append(splits.points[b], p)
+		}
+		split[a.ring], split[b.ring] = true, true
+	}
+	return splits, split
+}
+
+func (splits *edgeSplits) edgeEnd(edge ringEdge) *Point {
+	ring := splits.ends[edge.ring]
+	return ring[CircularIndex(edge.edge+1, len(ring))]
+}
+
+// The points where two touching segments meet: their crossing point, or where
+// they overlap along a line, the ends of each which lie on the other. A meeting
+// point within Epsilon of an end of either segment is that end.
+func meetingPoints(a, b *Segment) []*Point {
+	aDirection := Vector{a.End.X - a.Start.X, a.End.Y - a.Start.Y}
+	bDirection := Vector{b.End.X - b.Start.X, b.End.Y - b.Start.Y}
+	denominator := float64(aDirection.X*bDirection.Y) - float64(aDirection.Y*bDirection.X)
+	if denominator == 0 {
+		var points []*Point
+		for _, pair := range [][2]*Segment{{a, b}, {b, a}} {
+			for _, p := range []*Point{pair[1].Start, pair[1].End} {
+				if withinBounds(pair[0], p) {
+					points = append(points, p)
+				}
+			}
+		}
+		return points
+	}
+
+	t := (float64((b.Start.X-a.Start.X)*bDirection.Y) - float64((b.Start.Y-a.Start.Y)*bDirection.X)) / denominator
+	t = math.Max(0, math.Min(1, t))
+	x, y := a.Start.X+float64(t*aDirection.X), a.Start.Y+float64(t*aDirection.Y)
+	for _, end := range []*Point{a.Start, a.End, b.Start, b.End} {
+		if Equal(end.X, x) && Equal(end.Y, y) {
+			return []*Point{end}
+		}
+	}
+	return []*Point{fabricatedPoint(x, y)}
+}
+
+// Is the point within the bounding box of the segment?
+func withinBounds(s *Segment, p *Point) bool {
+	return math.Min(s.Start.X, s.End.X) <= p.X && p.X <= math.Max(s.Start.X, s.End.X) &&
+		math.Min(s.Start.Y, s.End.Y) <= p.Y && p.Y <= math.Max(s.Start.Y, s.End.Y)
+}
+
+// Split an edge at its meeting points, in order along the edge
+func splitEdge(splits *edgeSplits, edge ringEdge, cutter bool) []boundaryPiece {
+	start, end := splits.ends[edge.ring][edge.edge], splits.edgeEnd(edge)
+	along := func(p *Point) float64 {
+		return float64((p.X-start.X)*(end.X-start.X)) + float64((p.Y-start.Y)*(end.Y-start.Y))
+	}
+	points := append([]*Point{start}, splits.points[edge]...)
+	sort.SliceStable(points[1:], func(i, j int) bool {
+		return along(points[1+i]) < along(points[1+j])
+	})
+	points = append(points, end)
+
+	// Meeting points can repeat, such as where both lists have a point
+	var pieces []boundaryPiece
+	for i := 1; i < len(points); i++ {
+		if points[i] != points[i-1] {
+			pieces = append(pieces, boundaryPiece{points[i-1], points[i], cutter})
+		}
+	}
+	return pieces
+}
+
+// Keep the pieces which bound the result, reversing the cutters' pieces so
+// that the result is on the left of every piece
+func classifyPieces(pieces []boundaryPiece, solidGraph, cutterGraph *QueryGraph) []boundaryPiece {
+	type key struct{ start, end *Point }
+	solidPieces := make(map[key]bool)
+	for _, piece := range pieces {
+		if !piece.cutter {
+			solidPieces[key{piece.start, piece.end}] = true
+		}
+	}
+	cutterPieces := make(map[key]bool)
+	for _, piece := range pieces {
+		if piece.cutter {
+			cutterPieces[key{piece.start, piece.end}] = true
+		}
+	}
+
+	var kept []boundaryPiece
+	for _, piece := range pieces {
+		forward, backward := key{piece.start, piece.end}, key{piece.end, piece.start}
+		mid := edgeMidpoint(piece.start, piece.end)
+		if !piece.cutter {
+			switch {
+			case cutterPieces[forward]:
+				// The cutter is on the same side, so this is cut away
+			case cutterPieces[backward]:
+				// The cutter is on the outside, so it leaves this edge alone
+				kept = append(kept, piece)
+			case !cutterGraph.ContainsPoint(mid):
+				kept = append(kept, piece)
+			}
+		} else if !solidPieces[forward] && !solidPieces[backward] && solidGraph.ContainsPoint(mid) {
+			kept = append(kept, boundaryPiece{piece.end, piece.start, true})
+		}
+	}
+	return kept
+}
+
+// Link pieces into rings, end to start. Where several pieces leave the same
+// point, as where two parts of the result touch at a corner, each ring takes
+// the one which turns furthest left, which keeps the parts in separate rings.
+func linkPieces(pieces []boundaryPiece) PolygonList {
+	leaving := make(map[*Point][]int)
+	for i, piece := range pieces {
+		leaving[piece.start] = append(leaving[piece.start], i)
+	}
+	used := make([]bool, len(pieces))
+
+	var result PolygonList
+	for first := range pieces {
+		if used[first] {
+			continue
+		}
+		var ring []*Point
+		current := first
+		for {
+			used[current] = true
+			piece := pieces[current]
+			ring = append(ring, piece.start)
+			if piece.end == pieces[first].start {
+				break
+			}
+			next, bestTurn := -1, math.Inf(-1)
+			for _, candidate := range leaving[piece.end] {
+				if used[candidate] {
+					continue
+				}
+				if turn := leftTurn(piece, pieces[candidate]); turn > bestTurn {
+					next, bestTurn = candidate, turn
+				}
+			}
+			if next < 0 {
+				fatalf("boundary comes to a dead end at %v while subtracting", piece.end)
+			}
+			current = next
+		}
+		if len(ring) >= 3 {
+			result = append(result, Polygon{ring})
+		}
+	}
+	return result
+}
+
+// The angle turned going from one piece to the next, positive to the left
+func leftTurn(from, to boundaryPiece) float64 {
+	fromX, fromY := from.end.X-from.start.X, from.end.Y-from.start.Y
+	toX, toY := to.end.X-to.start.X, to.end.Y-to.start.Y
+	return math.Atan2(float64(fromX*toY)-float64(fromY*toX), float64(fromX*toX)+float64(fromY*toY))
+}
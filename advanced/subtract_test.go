@@ -0,0 +1,183 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check the result of a subtraction by sampling: a point must be in the result
+// exactly when it's in the list and not in the cutters. The grid is offset by
+// irrational amounts, so that no sample lands on an edge. The result must also
+// triangulate to the area it bounds.
+func assertSubtracted(t *testing.T, list, cutters, result PolygonList) {
+	t.Helper()
+	var area float64
+	for _, poly := range result {
+		area += poly.SignedArea()
+	}
+	var triangles TriangleList
+	require.NotPanics(t, func() {
+		triangles = result.TriangulateWithOptions(Options{Validate: true})
+	})
+	assert.InDelta(t, area, totalArea(triangles), 1e-9*math.Max(1, area))
+
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, l := range []PolygonList{list, cutters} {
+		for _, poly := range l {
+			for _, p := range poly.Points {
+				minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+				maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+			}
+		}
+	}
+	step := math.Max(maxX-minX, maxY-minY) / 60
+	for y := minY - step/math.Pi; y <= maxY+step; y += step {
+		for x := minX - step/math.E; x <= maxX+step; x += step {
+			p := &Point{X: x, Y: y}
+			expected := list.ContainsPointByEvenOdd(p) && !cutters.ContainsPointByEvenOdd(p)
+			if !assert.Equal(t, expected, result.ContainsPointByEvenOdd(p), "%v", p) {
+				return
+			}
+		}
+	}
+}
+
+func TestSubtract_Notch(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10)}
+	cutters := PolygonList{squareRing(8, 3, 4)}
+	result, err := list.Subtract(cutters)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.True(t, IsCCW(&result[0]))
+	assert.InDelta(t, 92, result[0].SignedArea(), 1e-9)
+	assertSubtracted(t, list, cutters, result)
+
+	// The corners of the square are the input points, and the notch's inner
+	// corners are the cutter's
+	for _, p := range result[0].Points {
+		if p.X == 8 {
+			assert.Contains(t, cutters[0].Points, p)
+		} else if p.X == 0 {
+			assert.Contains(t, list[0].Points, p)
+		}
+	}
+}
+
+func TestSubtract_Bisect(t *testing.T) {
+	list := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 4}, {X: 0, Y: 4}}}}
+	cutters := PolygonList{{[]*Point{{X: 4, Y: -1}, {X: 6, Y: -1}, {X: 6, Y: 5}, {X: 4, Y: 5}}}}
+	result, err := list.Subtract(cutters)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	for _, poly := range result {
+		assert.True(t, IsCCW(&poly))
+		assert.InDelta(t, 16, poly.SignedArea(), 1e-9)
+	}
+	assertSubtracted(t, list, cutters, result)
+}
+
+func TestSubtract_Interior(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10)}
+	// Wound either way, an interior cutter is a plain hole
+	for _, cutter := range []Polygon{squareRing(3, 3, 2), squareRing(3, 3, 2).Reverse()} {
+		result, err := list.Subtract(PolygonList{cutter})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, list[0], result[0])
+		assert.Equal(t, windAs(cutter, false), result[1])
+
+		hole := PolygonList{list[0], windAs(cutter, false)}
+		assert.Equal(t, hole.Triangulate(), result.Triangulate())
+	}
+}
+
+func TestSubtract_Whole(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10), squareRing(20, 0, 2)}
+
+	t.Run("outside", func(t *testing.T) {
+		result, err := list.Subtract(PolygonList{squareRing(12, 12, 2)})
+		require.NoError(t, err)
+		assert.Equal(t, list, result)
+	})
+
+	t.Run("covering", func(t *testing.T) {
+		result, err := list.Subtract(PolygonList{squareRing(19, -1, 4)})
+		require.NoError(t, err)
+		assert.Equal(t, PolygonList{list[0]}, result)
+	})
+
+	t.Run("no cutters", func(t *testing.T) {
+		result, err := list.Subtract(nil)
+		require.NoError(t, err)
+		assert.Equal(t, list, result)
+	})
+}
+
+func TestSubtract_SharedEdges(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10)}
+
+	t.Run("outside", func(t *testing.T) {
+		// Touching along the right side leaves the square as it was
+		cutters := PolygonList{squareRing(10, 0, 2)}
+		result, err := list.Subtract(cutters)
+		require.NoError(t, err)
+		assertSubtracted(t, list, cutters, result)
+		assert.InDelta(t, 100, result[0].SignedArea(), 1e-9)
+	})
+
+	t.Run("inside", func(t *testing.T) {
+		// Running along the bottom and right sides, from the inside
+		cutters := PolygonList{squareRing(7, 0, 3)}
+		result, err := list.Subtract(cutters)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.InDelta(t, 91, result[0].SignedArea(), 1e-9)
+		assertSubtracted(t, list, cutters, result)
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		// Part of the cutter's bottom runs along the square's bottom
+		cutters := PolygonList{squareRing(8, 0, 4)}
+		result, err := list.Subtract(cutters)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.InDelta(t, 92, result[0].SignedArea(), 1e-9)
+		assertSubtracted(t, list, cutters, result)
+	})
+}
+
+func TestSubtract_Holes(t *testing.T) {
+	// A cutter across the edge of an existing hole, and another across the
+	// outside edge, so that they merge with the hole and make a notch
+	list := PolygonList{squareRing(0, 0, 10), squareRing(2, 2, 3).Reverse()}
+	cutters := PolygonList{
+		{[]*Point{{X: 4, Y: 4}, {X: 7, Y: 3.5}, {X: 6.5, Y: 7}}},
+		{[]*Point{{X: 9, Y: 8}, {X: 11, Y: 7}, {X: 10.5, Y: 9.5}}},
+	}
+	result, err := list.Subtract(cutters)
+	require.NoError(t, err)
+	assertSubtracted(t, list, cutters, result)
+}
+
+func TestSubtract_Touching(t *testing.T) {
+	// A diamond cutter whose corners touch the middle of each side splits the
+	// square into four corners
+	list := PolygonList{squareRing(0, 0, 10)}
+	cutters := PolygonList{{[]*Point{{X: 5, Y: 0}, {X: 10, Y: 5}, {X: 5, Y: 10}, {X: 0, Y: 5}}}}
+	result, err := list.Subtract(cutters)
+	require.NoError(t, err)
+	assert.Len(t, result, 4)
+	assertSubtracted(t, list, cutters, result)
+}
+
+func TestSubtract_Errors(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10)}
+	var crossingErr *CrossingEdgesError
+	_, err := list.Subtract(PolygonList{squareRing(1, 1, 2), squareRing(2, 2, 2)})
+	assert.ErrorAs(t, err, &crossingErr)
+	_, err = PolygonList{squareRing(0, 0, 2), squareRing(1, 1, 2)}.Subtract(list)
+	assert.ErrorAs(t, err, &crossingErr)
+}
@@ -0,0 +1,195 @@
+package advanced
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Options for TrianglesToSVG. The zero value gives the default behavior.
+type SVGOptions struct {
+	// Width of the triangle outlines, in input units. By default, this is 1/500
+	// of the larger side of the drawing, so that the outlines show at any scale.
+	// The input polygons are outlined twice as thick.
+	StrokeWidth float64
+	// Opacity of the triangle fills, from 0 to 1. By default, it's 0.5, so that
+	// overlapping triangles stand out. Since zero means the default, use a
+	// negative value for no fill at all.
+	FillOpacity float64
+	// Space around the drawing, as a fraction of its larger side. By default,
+	// it's 0.05.
+	Padding float64
+	// Draw with Y increasing upward, as the input is usually meant, rather than
+	// downward, as SVG draws it. This negates every Y coordinate in the output.
+	FlipY bool
+	// Significant digits to keep, relative to the size of the drawing, so that
+	// large coordinates don't bloat the output with digits nobody can see. By
+	// default, it's 6.
+	Precision int
+	// Give each triangle its own fill, cycling through a palette by index, so
+	// that neighbors can be told apart. By default, all triangles have the same
+	// fill.
+	FillByIndex bool
+	// If non-nil, this gives the fill of each triangle, as any SVG color. It
+	// overrides FillByIndex.
+	Fill func(index int, tri *Triangle) string
+}
+
+const (
+	svgDefaultFill      = "#9cc3e6"
+	svgStroke           = "#333"
+	svgPolygonStroke    = "#d62728"
+	svgDefaultOpacity   = 0.5
+	svgDefaultPadding   = 0.05
+	svgDefaultPrecision = 6
+	// The default stroke width, as a fraction of the larger side
+	svgDefaultStroke = 1.0 / 500
+)
+
+// Fills for FillByIndex, chosen to be told apart at a glance
+var svgPalette = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+// Draw the triangles as an SVG document, with the polygons, if any, outlined
+// on top of them. This is for bug reports and quick inspection: it needs no
+// image libraries, and the result can be opened in a browser or pasted into an
+// issue. The view box fits both the triangles and the polygons, plus padding.
+//
+// Triangles are drawn in order, so later ones cover earlier ones where they
+// overlap. The polygons are drawn as a single even-odd path, so holes show as
+// holes.
+func TrianglesToSVG(triangles TriangleList, polygons PolygonList, opts SVGOptions) string {
+	flip := 1.0
+	if opts.FlipY {
+		flip = -1
+	}
+
+	bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	extend := func(p *Point) {
+		x, y := p.X, flip*p.Y
+		bounds.MinX, bounds.MinY = math.Min(bounds.MinX, x), math.Min(bounds.MinY, y)
+		bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, x), math.Max(bounds.MaxY, y)
+	}
+	for _, tri := range triangles {
+		extend(tri.A)
+		extend(tri.B)
+		extend(tri.C)
+	}
+	for _, poly := range polygons {
+		for _, p := range poly.Points {
+			extend(p)
+		}
+	}
+	if math.IsInf(bounds.MinX, 1) {
+		bounds = Rect{0, 0, 1, 1}
+	}
+	size := math.Max(bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY)
+	if size == 0 {
+		size = 1
+	}
+
+	padding := opts.Padding
+	if padding == 0 {
+		padding = svgDefaultPadding
+	}
+	padding *= size
+	strokeWidth := opts.StrokeWidth
+	if strokeWidth == 0 {
+		strokeWidth = svgDefaultStroke * size
+	}
+	opacity := opts.FillOpacity
+	if opacity == 0 {
+		opacity = svgDefaultOpacity
+	}
+	opacity = math.Max(0, math.Min(1, opacity))
+	precision := opts.Precision
+	if precision == 0 {
+		precision = svgDefaultPrecision
+	}
+	format := svgNumberFormat(size, precision)
+	point := func(p *Point) string {
+		return format(p.X) + "," + format(flip*p.Y)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out,
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s">`+"\n",
+		format(bounds.MinX-padding), format(bounds.MinY-padding),
+		format(bounds.MaxX-bounds.MinX+2*padding), format(bounds.MaxY-bounds.MinY+2*padding),
+	)
+
+	fill := func(index int, tri *Triangle) string {
+		switch {
+		case opts.Fill != nil:
+			return opts.Fill(index, tri)
+		case opts.FillByIndex:
+			return svgPalette[index%len(svgPalette)]
+		}
+		return ""
+	}
+	groupFill := ""
+	if opts.Fill == nil && !opts.FillByIndex {
+		groupFill = fmt.Sprintf(` fill="%s"`, svgDefaultFill)
+	}
+	fmt.Fprintf(&out,
+		`<g class="triangles"%s fill-opacity="%s" stroke="%s" stroke-width="%s" stroke-linejoin="round">`+"\n",
+		groupFill, strconv.FormatFloat(opacity, 'f', -1, 64), svgStroke, format(strokeWidth),
+	)
+	for i, tri := range triangles {
+		out.WriteString(`<polygon points="`)
+		out.WriteString(point(tri.A) + " " + point(tri.B) + " " + point(tri.C))
+		out.WriteString(`"`)
+		if color := fill(i, tri); color != "" {
+			fmt.Fprintf(&out, ` fill="%s"`, color)
+		}
+		out.WriteString("/>\n")
+	}
+	out.WriteString("</g>\n")
+
+	if len(polygons) > 0 {
+		fmt.Fprintf(&out,
+			`<g class="polygons" fill="none" fill-rule="evenodd" stroke="%s" stroke-width="%s" stroke-linejoin="round">`+"\n",
+			svgPolygonStroke, format(2*strokeWidth),
+		)
+		out.WriteString(`<path d="`)
+		separator := ""
+		for _, poly := range polygons {
+			if len(poly.Points) == 0 {
+				continue
+			}
+			out.WriteString(separator + "M" + point(poly.Points[0]))
+			separator = " "
+			for _, p := range poly.Points[1:] {
+				out.WriteString("L" + point(p))
+			}
+			out.WriteString("Z")
+		}
+		out.WriteString("\"/>\n</g>\n")
+	}
+	out.WriteString("</svg>\n")
+	return out.String()
+}
+
+// A formatter for coordinates in a drawing of the given size, keeping the
+// given number of significant digits relative to the size. Trailing zeros are
+// dropped, and negative zero is written as zero, so that the output is as
+// short as it can be, and stable.
+func svgNumberFormat(size float64, precision int) func(float64) string {
+	decimals := precision - 1 - int(math.Floor(math.Log10(size)))
+	if decimals < 0 {
+		decimals = 0
+	}
+	return func(value float64) string {
+		result := strconv.FormatFloat(value, 'f', decimals, 64)
+		if strings.Contains(result, ".") {
+			result = strings.TrimRight(strings.TrimRight(result, "0"), ".")
+		}
+		if result == "-0" {
+			result = "0"
+		}
+		return result
+	}
+}
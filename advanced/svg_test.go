@@ -0,0 +1,120 @@
+package advanced
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const svgGoldenFile = "fixtures/simple_star_triangles.svg"
+
+// The SVG of the simple star's triangulation is locked down by a golden file.
+// The triangles are sorted, so that only a change to the drawing, or to which
+// triangles come out, changes the file. To accept a deliberate change, run
+// this test with TRIANGULATE_UPDATE_GOLDEN=1.
+func TestTrianglesToSVG_Golden(t *testing.T) {
+	list := SimpleStar()
+	triangles := list.TriangulateWithOptions(Options{SortOutput: Spatial})
+	actual := TrianglesToSVG(triangles, list, SVGOptions{FlipY: true, FillByIndex: true})
+
+	if os.Getenv("TRIANGULATE_UPDATE_GOLDEN") != "" {
+		require.NoError(t, os.WriteFile(svgGoldenFile, []byte(actual), 0644))
+		t.Skip("updated the golden file; rerun without TRIANGULATE_UPDATE_GOLDEN")
+	}
+	expected, err := fixtures.ReadFile(svgGoldenFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), actual)
+}
+
+func TestTrianglesToSVG(t *testing.T) {
+	list := SquareWithHole()
+	triangles := list.Triangulate()
+	svg := TrianglesToSVG(triangles, list, SVGOptions{})
+
+	// It's well formed, with a polygon per triangle, and the overlay last
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	var elements []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			elements = append(elements, start.Name.Local)
+		}
+	}
+	require.NotEmpty(t, elements)
+	assert.Equal(t, "svg", elements[0])
+	assert.Equal(t, len(triangles), strings.Count(svg, "<polygon "))
+	assert.Equal(t, "path", elements[len(elements)-1])
+	assert.Less(t, strings.Index(svg, `class="triangles"`), strings.Index(svg, `class="polygons"`))
+
+	// The polygons are one path, with a subpath per ring
+	assert.Equal(t, len(list), strings.Count(svg, "M"))
+
+	// Without polygons, there's no overlay
+	assert.NotContains(t, TrianglesToSVG(triangles, nil, SVGOptions{}), "polygons")
+}
+
+func TestTrianglesToSVG_ViewBox(t *testing.T) {
+	list := PolygonList{squareRing(-30, -20, 10)}
+	triangles := list.Triangulate()
+
+	// 10 across, padded by 5% of that on each side
+	assert.Contains(t, TrianglesToSVG(triangles, nil, SVGOptions{}), `viewBox="-30.5 -20.5 11 11"`)
+	assert.Contains(t, TrianglesToSVG(triangles, nil, SVGOptions{Padding: 0.1}), `viewBox="-31 -21 12 12"`)
+	// Flipped, Y runs from -10 to 20
+	assert.Contains(t, TrianglesToSVG(triangles, nil, SVGOptions{FlipY: true}), `viewBox="-30.5 9.5 11 11"`)
+
+	// The polygons count toward the view box too
+	big := PolygonList{squareRing(0, 0, 100)}
+	assert.Contains(t, TrianglesToSVG(triangles, big, SVGOptions{}), `viewBox="-36.5 -26.5 143 133"`)
+
+	// Nothing at all still gives a usable document
+	assert.Contains(t, TrianglesToSVG(nil, nil, SVGOptions{}), `viewBox="-0.05 -0.05 1.1 1.1"`)
+}
+
+func TestTrianglesToSVG_Fill(t *testing.T) {
+	triangles := SimpleStar().Triangulate()
+
+	plain := TrianglesToSVG(triangles, nil, SVGOptions{})
+	assert.Equal(t, 1, strings.Count(plain, "fill="), "only the group has a fill")
+	assert.Contains(t, plain, `fill-opacity="0.5"`)
+	assert.Contains(t, TrianglesToSVG(triangles, nil, SVGOptions{FillOpacity: 0.25}), `fill-opacity="0.25"`)
+	assert.Contains(t, TrianglesToSVG(triangles, nil, SVGOptions{FillOpacity: -1}), `fill-opacity="0"`)
+
+	byIndex := TrianglesToSVG(triangles, nil, SVGOptions{FillByIndex: true})
+	assert.Equal(t, len(triangles), strings.Count(byIndex, "fill=\"#"))
+	assert.Contains(t, byIndex, svgPalette[0])
+	assert.Contains(t, byIndex, svgPalette[1])
+
+	var seen []int
+	callback := TrianglesToSVG(triangles, nil, SVGOptions{
+		FillByIndex: true,
+		Fill: func(index int, tri *Triangle) string {
+			assert.Same(t, triangles[index], tri)
+			seen = append(seen, index)
+			return "green"
+		},
+	})
+	assert.Equal(t, len(triangles), strings.Count(callback, `fill="green"`))
+	assert.Len(t, seen, len(triangles))
+}
+
+func TestSVGNumberFormat(t *testing.T) {
+	format := svgNumberFormat(100, 4)
+	assert.Equal(t, "1.5", format(1.5))
+	assert.Equal(t, "0.1", format(0.1+0.2-0.2))
+	assert.Equal(t, "-3.1", format(-3.14159))
+	assert.Equal(t, "0", format(-0.0001))
+	assert.Equal(t, "12", format(12))
+
+	// Large drawings don't get decimals
+	large := svgNumberFormat(1e9, 6)
+	assert.Equal(t, "123456789", large(123456789.123))
+	assert.Equal(t, "-5000", large(-5000.4))
+}
@@ -0,0 +1,101 @@
+package advanced
+
+// Which way the trapezoid map sweeps. See Options.
+type SweepAxis int
+
+const (
+	// Compare points by Y, breaking ties by X, as Point.Below does. Every
+	// trapezoid then has horizontal top and bottom edges. This is the default.
+	YAxis SweepAxis = iota
+	// Compare points by X, breaking ties by Y, larger Y counting as lower. Every
+	// trapezoid then has vertical sides instead. Input with many vertices
+	// sharing Y values, which piles up zero height trapezoids under YAxis, is
+	// often trivial this way around.
+	XAxis
+	// Use whichever of YAxis and XAxis has fewer vertices sharing coordinates
+	// along it, counting distinct values in one pass over the input. Ties go to
+	// YAxis.
+	AutoAxis
+)
+
+// A sweep along X is a sweep along Y of the input turned a quarter turn
+// counterclockwise, so that X becomes Y, and Y becomes -X. Swapping and negating
+// coordinates is exact, so every comparison in the turned copy gives the same
+// answer the X-primary comparison would give on the input, and there's no
+// state to share between triangulations with different axes. Turning keeps
+// the winding, which reflecting would not.
+type sweepRotation struct {
+	// Maps points in the working copy back to the input points
+	originals map[*Point]*Point
+}
+
+// Resolve AutoAxis to the axis with fewer coordinate collisions
+func (axis SweepAxis) resolve(list PolygonList) SweepAxis {
+	switch axis {
+	case YAxis, XAxis:
+		return axis
+	case AutoAxis:
+	default:
+		fatalf("unknown sweep axis: %d", axis)
+	}
+	xs := make(map[float64]struct{})
+	ys := make(map[float64]struct{})
+	for _, poly := range list {
+		for _, p := range poly.Points {
+			xs[p.X] = struct{}{}
+			ys[p.Y] = struct{}{}
+		}
+	}
+	if len(xs) > len(ys) {
+		return XAxis
+	}
+	return YAxis
+}
+
+// Build a working copy of the list to sweep along the given axis. For YAxis,
+// the list is returned as it is, with a nil rotation.
+func rotateForSweep(list PolygonList, axis SweepAxis) (PolygonList, *sweepRotation) {
+	if axis.resolve(list) == YAxis {
+		return list, nil
+	}
+	r := &sweepRotation{originals: make(map[*Point]*Point)}
+	// Rings which share a point must share its working copy too
+	workingPoints := make(map[*Point]*Point)
+	result := make(PolygonList, len(list))
+	for i, poly := range list {
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			working, ok := workingPoints[p]
+			if !ok {
+				working = &Point{X: -p.Y, Y: p.X, UserIndex: p.UserIndex, HasUserIndex: p.HasUserIndex}
+				workingPoints[p] = working
+				r.originals[working] = p
+			}
+			points[j] = working
+		}
+		result[i] = Polygon{points}
+	}
+	return result, r
+}
+
+// Map a working point back to the input. Points created during the
+// triangulation get a new point, remembered so that triangles sharing it share
+// the result.
+func (r *sweepRotation) original(p *Point) *Point {
+	if original, ok := r.originals[p]; ok {
+		return original
+	}
+	original := fabricatedPoint(p.Y, -p.X)
+	r.originals[p] = original
+	return original
+}
+
+// Map triangles from the working copy back to the input. Turning keeps
+// orientation, so nothing needs judging again.
+func (r *sweepRotation) restore(triangles TriangleList) TriangleList {
+	result := make(TriangleList, len(triangles))
+	for i, tri := range triangles {
+		result[i] = &Triangle{r.original(tri.A), r.original(tri.B), r.original(tri.C)}
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package advanced
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced/corpus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A comb with the given number of teeth, whose vertices all share one of three
+// Y values, but almost all have their own X value
+func combRing(teeth int) Polygon {
+	points := []*Point{{X: 0, Y: 0}, {X: float64(2*teeth + 1), Y: 0}}
+	for i := teeth; i >= 0; i-- {
+		x := float64(2*i + 1)
+		points = append(points, &Point{X: x, Y: 2}, &Point{X: x - 0.5, Y: 2})
+		if i > 0 {
+			points = append(points, &Point{X: x - 0.5, Y: 1}, &Point{X: x - 1.5, Y: 1})
+		}
+	}
+	points = append(points, &Point{X: 0, Y: 2})
+	return Polygon{points}
+}
+
+func TestSweepAxis_Resolve(t *testing.T) {
+	comb := PolygonList{combRing(100)}
+	assert.Equal(t, XAxis, AutoAxis.resolve(comb))
+	assert.Equal(t, YAxis, YAxis.resolve(comb))
+	assert.Equal(t, XAxis, XAxis.resolve(SquareWithHole()))
+
+	// Turned a quarter turn, the comb's teeth share X values instead
+	turned := PolygonList{{make([]*Point, len(comb[0].Points))}}
+	for i, p := range comb[0].Points {
+		turned[0].Points[i] = &Point{X: -p.Y, Y: p.X}
+	}
+	assert.Equal(t, YAxis, AutoAxis.resolve(turned))
+
+	// Ties go to Y
+	assert.Equal(t, YAxis, AutoAxis.resolve(PolygonList{squareRing(0, 0, 1)}))
+}
+
+func TestSweepAxis_Fixtures(t *testing.T) {
+	for name, load := range allFixtures() {
+		t.Run(name, func(t *testing.T) {
+			list := load()
+			inputPoints := make(PointSet)
+			for _, poly := range list {
+				for _, p := range poly.Points {
+					inputPoints.Add(p)
+				}
+			}
+
+			var y, x TriangleList
+			require.NotPanics(t, func() {
+				y = list.TriangulateWithOptions(Options{SweepAxis: YAxis})
+				x = list.TriangulateWithOptions(Options{SweepAxis: XAxis})
+			})
+			validatePolygonsBySampling(t, y.ToPolygonList(), list)
+			validatePolygonsBySampling(t, x.ToPolygonList(), list)
+			validatePolygonsBySampling(t, x.ToPolygonList(), y.ToPolygonList())
+			assert.InDelta(t, totalArea(y), totalArea(x), 1e-6*totalArea(y))
+
+			// The output still references the input points, and keeps its winding
+			for _, tri := range x {
+				assert.True(t, IsCCW(tri), "%v", tri)
+				for _, p := range []*Point{tri.A, tri.B, tri.C} {
+					assert.True(t, inputPoints.Contains(p), "%v is not an input point", p)
+				}
+			}
+		})
+	}
+}
+
+// Staircases have a vertex on every Y value, two to a value, so they lean on
+// the X tie-breaking. Swept along X, they should need nothing special.
+func TestSweepAxis_Staircases(t *testing.T) {
+	for _, entry := range corpus.Entries() {
+		if !strings.Contains(entry.Name, "staircase") || (entry.Huge && testing.Short()) {
+			continue
+		}
+		entry := entry
+		t.Run(entry.Name, func(t *testing.T) {
+			list := corpusShape(entry)
+			var triangles TriangleList
+			require.NotPanics(t, func() {
+				triangles = list.TriangulateWithOptions(Options{SweepAxis: XAxis, CheckPointIntegrity: true})
+			})
+			validatePolygonsBySampling(t, triangles.ToPolygonList(), list)
+			area := list[0].SignedArea()
+			assert.InDelta(t, area, totalArea(triangles), 1e-9*area)
+		})
+	}
+}
+
+// The comb's vertices pile up on three Y values, but swept along X, no two
+// vertices are level, so the queries stay shallow
+func TestSweepAxis_SharedY(t *testing.T) {
+	list := PolygonList{combRing(1000)}
+
+	var alongY, alongX Diagnostics
+	y := list.TriangulateWithOptions(Options{Diagnostics: &alongY, shuffleSource: sortedInsertion})
+	x := list.TriangulateWithOptions(Options{SweepAxis: AutoAxis, Diagnostics: &alongX, shuffleSource: sortedInsertion})
+	validatePolygonsBySampling(t, x.ToPolygonList(), y.ToPolygonList())
+	assert.Len(t, x, len(y))
+	assert.Less(t, alongX.QueryDepth.MaxDepth, alongY.QueryDepth.MaxDepth)
+}
+
+func TestSweepAxis_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	results := make([]float64, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			axis := YAxis
+			if i%2 == 1 {
+				axis = XAxis
+			}
+			results[i] = totalArea(StarStripes().TriangulateWithOptions(Options{SweepAxis: axis}))
+		}(i)
+	}
+	wg.Wait()
+	for _, area := range results {
+		assert.InDelta(t, results[0], area, 1e-9*results[0])
+	}
+}
+
+func TestSweepAxis_Unknown(t *testing.T) {
+	err := triangulateRecovering(SquareWithHole(), Options{SweepAxis: 42})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown sweep axis")
+}
@@ -0,0 +1,79 @@
+package advanced
+
+// Symmetric input, like regular polygons, rings of them, and grids, puts many
+// vertices at the same height. Those ties are all broken by the lexicographic
+// order, which makes the trapezoid map full of zero height trapezoids, and
+// leaves the marginal decisions made while building it to the few rounding
+// errors that distinguish the ties. Those decisions depend on the order the
+// segments go in, so when building the map fails on such an input, another
+// seed can succeed where the first didn't. Only valid input is retried, though,
+// since building also fails on bad input, and another seed could get further
+// with it, only to fail somewhere less helpful, or not at all.
+
+// The default for Options.SymmetricRetries
+const DefaultSymmetricRetries = 3
+
+// Does at least half of the list's vertices share its height, within Epsilon,
+// with another vertex? See riskRepeatedHeights.
+func (list PolygonList) hasRepeatedHeights() bool {
+	ratio, _ := list.repeatedHeights()
+	return ratio >= riskRepeatedHeights
+}
+
+// Run attempt with opts.Seed. If it fails, and the list has repeated heights
+// and passes Validate, run it again with later seeds, up to
+// opts.SymmetricRetries times, until it succeeds. The seeds step past the ones buildQueryGraph rebuilds with, so a
+// retry never repeats an order already tried. Anything a failed attempt wrote
+// to the diagnostics is discarded, and a retry which succeeds is recorded as a
+// WarningSymmetricRetry. If every attempt fails, the first attempt's error is
+// thrown, since that's the one the caller would have seen without retrying.
+func retrySymmetric(list PolygonList, opts Options, attempt func(seed int64)) {
+	retries := opts.SymmetricRetries
+	if retries == 0 {
+		retries = DefaultSymmetricRetries
+	}
+	if retries < 0 || !list.hasRepeatedHeights() {
+		attempt(opts.Seed)
+		return
+	}
+
+	var firstErr error
+	for i := 0; i <= retries; i++ {
+		seed := opts.Seed + int64(i*(maxDepthRebuilds+1))
+		if err := tryAttempt(opts.Diagnostics, seed, attempt); err != nil {
+			if firstErr == nil {
+				firstErr = err
+				if list.Validate() != nil {
+					break
+				}
+			}
+			continue
+		}
+		if i > 0 && opts.Diagnostics != nil {
+			opts.Diagnostics.SymmetricRetries = i
+			opts.Diagnostics.warnf(
+				WarningSymmetricRetry,
+				"input has repeated heights, and seed %d failed (%v), but seed %d succeeded",
+				opts.Seed, firstErr, seed,
+			)
+		}
+		return
+	}
+	throw(firstErr)
+}
+
+// Run one attempt, and return its error, restoring the diagnostics to how they
+// were before it if it fails
+func tryAttempt(diagnostics *Diagnostics, seed int64, attempt func(seed int64)) (err error) {
+	var saved Diagnostics
+	if diagnostics != nil {
+		saved = *diagnostics
+	}
+	defer func() {
+		if err = HandleTriangulatePanicRecover(recover()); err != nil && diagnostics != nil {
+			*diagnostics = saved
+		}
+	}()
+	attempt(seed)
+	return nil
+}
@@ -0,0 +1,161 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A regular polygon with a regular hole, so that the trapezoid map is built
+func symmetricAnnulus(n int) PolygonList {
+	return PolygonList{circlePolygon(10, n), circlePolygon(5, n).Reverse()}
+}
+
+func regularPolygonArea(radius float64, n int) float64 {
+	return float64(n) / 2 * radius * radius * math.Sin(2*math.Pi/float64(n))
+}
+
+// A ring with no two vertices at the same height
+func asymmetricPolygon(n int) PolygonList {
+	random := rand.New(rand.NewSource(1))
+	points := make([]*Point, n)
+	for i := range points {
+		angle := 2 * math.Pi * (float64(i) + 0.1 + 0.8*random.Float64()) / float64(n)
+		radius := 8 + 2*random.Float64()
+		points[i] = &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}
+	}
+	return PolygonList{{Points: points}, squareRing(-1, -1.5, 2).Reverse()}
+}
+
+func TestHasRepeatedHeights(t *testing.T) {
+	assert.True(t, symmetricAnnulus(16).hasRepeatedHeights())
+	assert.True(t, PolygonList{squareRing(0, 0, 1)}.hasRepeatedHeights())
+	assert.False(t, asymmetricPolygon(16).hasRepeatedHeights())
+	assert.False(t, PolygonList{{Points: []*Point{{X: 0, Y: 0}, {X: 1, Y: 0.5}, {X: 0, Y: 1}}}}.hasRepeatedHeights())
+}
+
+func TestRetrySymmetric(t *testing.T) {
+	failBelow := func(limit int64, seeds *[]int64) func(int64) {
+		return func(seed int64) {
+			*seeds = append(*seeds, seed)
+			if seed < limit {
+				fatalf("unlucky seed %d", seed)
+			}
+		}
+	}
+
+	t.Run("retries symmetric input", func(t *testing.T) {
+		var seeds []int64
+		diagnostics := &Diagnostics{}
+		opts := Options{Seed: 10, Diagnostics: diagnostics}
+		retrySymmetric(symmetricAnnulus(8), opts, failBelow(18, &seeds))
+
+		assert.Equal(t, []int64{10, 14, 18}, seeds)
+		assert.Equal(t, 2, diagnostics.SymmetricRetries)
+		require.Len(t, diagnostics.Warnings, 1)
+		assert.Equal(t, WarningSymmetricRetry, diagnostics.Warnings[0].Kind)
+		assert.Contains(t, diagnostics.Warnings[0].Message, "seed 18 succeeded")
+		assert.Contains(t, diagnostics.Warnings[0].Message, "unlucky seed 10")
+	})
+
+	t.Run("discards diagnostics from failed attempts", func(t *testing.T) {
+		diagnostics := &Diagnostics{}
+		opts := Options{Diagnostics: diagnostics}
+		retrySymmetric(symmetricAnnulus(8), opts, func(seed int64) {
+			diagnostics.Trapezoids = 100 + int(seed)
+			diagnostics.warnf(WarningQueryDepth, "seed %d", seed)
+			if seed == 0 {
+				fatalf("unlucky")
+			}
+		})
+		assert.Equal(t, 104, diagnostics.Trapezoids)
+		require.Len(t, diagnostics.Warnings, 2)
+		assert.Equal(t, "seed 4", diagnostics.Warnings[0].Message)
+	})
+
+	t.Run("builds asymmetric input once", func(t *testing.T) {
+		var seeds []int64
+		err := func() (err error) {
+			defer func() { err = HandleTriangulatePanicRecover(recover()) }()
+			retrySymmetric(asymmetricPolygon(16), Options{}, failBelow(100, &seeds))
+			return nil
+		}()
+		assert.EqualError(t, err, "unlucky seed 0")
+		assert.Equal(t, []int64{0}, seeds)
+	})
+
+	t.Run("doesn't retry invalid input", func(t *testing.T) {
+		var seeds []int64
+		overlapping := PolygonList{squareRing(0, 0, 2), squareRing(1, 0, 2)}
+		require.True(t, overlapping.hasRepeatedHeights())
+		err := func() (err error) {
+			defer func() { err = HandleTriangulatePanicRecover(recover()) }()
+			retrySymmetric(overlapping, Options{}, failBelow(100, &seeds))
+			return nil
+		}()
+		assert.EqualError(t, err, "unlucky seed 0")
+		assert.Equal(t, []int64{0}, seeds)
+	})
+
+	t.Run("negative disables", func(t *testing.T) {
+		var seeds []int64
+		err := func() (err error) {
+			defer func() { err = HandleTriangulatePanicRecover(recover()) }()
+			retrySymmetric(symmetricAnnulus(8), Options{SymmetricRetries: -1}, failBelow(100, &seeds))
+			return nil
+		}()
+		assert.Error(t, err)
+		assert.Equal(t, []int64{0}, seeds)
+	})
+
+	t.Run("reports the first error", func(t *testing.T) {
+		var seeds []int64
+		diagnostics := &Diagnostics{}
+		err := func() (err error) {
+			defer func() { err = HandleTriangulatePanicRecover(recover()) }()
+			retrySymmetric(symmetricAnnulus(8), Options{Diagnostics: diagnostics}, failBelow(100, &seeds))
+			return nil
+		}()
+		assert.EqualError(t, err, "unlucky seed 0")
+		assert.Len(t, seeds, DefaultSymmetricRetries+1)
+		assert.Equal(t, Diagnostics{}, *diagnostics)
+	})
+
+	t.Run("passes other panics through", func(t *testing.T) {
+		assert.PanicsWithValue(t, "not ours", func() {
+			retrySymmetric(symmetricAnnulus(8), Options{}, func(int64) { panic("not ours") })
+		})
+	})
+}
+
+// Every regular annulus from 3 to 64 sides triangulates with the default seed.
+// None of them currently needs a retry, so any size which starts needing one
+// shows up here, rather than being quietly rescued.
+func TestTriangulate_SymmetricSweep(t *testing.T) {
+	var retried []int
+	for n := 3; n <= 64; n++ {
+		diagnostics := &Diagnostics{}
+		var triangles TriangleList
+		require.NotPanics(t, func() {
+			triangles = symmetricAnnulus(n).TriangulateWithOptions(Options{Diagnostics: diagnostics})
+		}, "n=%d", n)
+		expected := regularPolygonArea(10, n) - regularPolygonArea(5, n)
+		assert.InDelta(t, expected, totalArea(triangles), 1e-6, "n=%d", n)
+		if diagnostics.SymmetricRetries > 0 {
+			retried = append(retried, n)
+		}
+	}
+	assert.Empty(t, retried)
+}
+
+func TestTriangulate_AsymmetricSingleAttempt(t *testing.T) {
+	list := asymmetricPolygon(32)
+	require.False(t, list.hasRepeatedHeights())
+	diagnostics := &Diagnostics{}
+	require.NoError(t, triangulateRecovering(list, Options{Diagnostics: diagnostics}))
+	assert.Zero(t, diagnostics.SymmetricRetries)
+	assert.Empty(t, diagnostics.Warnings)
+}
@@ -0,0 +1,47 @@
+package testutil
+
+import (
+	"fmt"
+
+	"github.com/osuushi/triangulate/advanced"
+	"github.com/osuushi/triangulate/advanced/corpus"
+)
+
+// A shape from the corpus, with the properties it is tagged with. See the
+// corpus package.
+type NamedShape struct {
+	Name  string
+	Shape PolygonList
+	corpus.Tags
+}
+
+// Every shape in the corpus, in a fixed order. Each call makes new points,
+// since triangulation identifies points by pointer.
+func Corpus() []NamedShape {
+	entries := corpus.Entries()
+	shapes := make([]NamedShape, len(entries))
+	for i, entry := range entries {
+		shapes[i] = NamedShape{Name: entry.Name, Shape: corpusShape(entry), Tags: entry.Tags}
+	}
+	return shapes
+}
+
+// Make a new polygon list from a corpus entry
+func corpusShape(entry corpus.Entry) PolygonList {
+	if entry.Log != "" {
+		list, err := advanced.ParsePointLogs(entry.Log)
+		if err != nil {
+			panic(fmt.Sprintf("invalid corpus entry %q: %v", entry.Name, err))
+		}
+		return list
+	}
+	list := make(PolygonList, len(entry.Rings))
+	for i, ring := range entry.Rings {
+		points := make([]*Point, len(ring))
+		for j, p := range ring {
+			points[j] = &Point{X: p[0], Y: p[1]}
+		}
+		list[i] = Polygon{Points: points}
+	}
+	return list
+}
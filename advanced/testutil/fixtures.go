@@ -0,0 +1,31 @@
+package testutil
+
+import "github.com/osuushi/triangulate/advanced/corpus"
+
+// These are the ad hoc fixtures used throughout the advanced package's tests,
+// from the corpus.
+
+// A five pointed star.
+func SimpleStar() PolygonList {
+	return corpusShape(corpus.Lookup("simple star"))
+}
+
+// A square with a square hole in the middle.
+func SquareWithHole() PolygonList {
+	return corpusShape(corpus.Lookup("square with hole"))
+}
+
+// A star with a star shaped hole, leaving only a thin outline.
+func StarOutline() PolygonList {
+	return corpusShape(corpus.Lookup("star outline"))
+}
+
+// Multiple inset stars with alternating winding.
+func StarStripes() PolygonList {
+	return corpusShape(corpus.Lookup("star stripes"))
+}
+
+// Multiple holes which contain filled shapes inside.
+func MultiLayeredHoles() PolygonList {
+	return corpusShape(corpus.Lookup("multi layered holes"))
+}
@@ -0,0 +1,207 @@
+// Package testutil generates polygons with controlled properties for tests,
+// fuzzing, and benchmarks.
+//
+// Every generator returns geometry satisfying the preconditions of
+// triangulation by construction: rings are simple, solids run
+// counterclockwise, and holes run clockwise. Generators which take a
+// *rand.Rand are deterministic given the state of that source.
+//
+// The shapes which tests share are in the corpus package, and Corpus returns
+// them as polygon lists. The advanced package's own tests cannot import this
+// package, since that would be an import cycle, so they use the corpus package
+// directly.
+package testutil
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/osuushi/triangulate/advanced"
+)
+
+type Point = advanced.Point
+type Polygon = advanced.Polygon
+type PolygonList = advanced.PolygonList
+
+// Create a random star-shaped polygon with n vertices. Points are placed at
+// increasing angles around the origin at random radii between 0.5 and 1, which
+// guarantees that the polygon is simple and counterclockwise.
+func RandomSimplePolygon(n int, rng *rand.Rand) Polygon {
+	if n < 3 {
+		panic(fmt.Sprintf("polygon needs at least 3 points, got %d", n))
+	}
+	points := make([]*Point, n)
+	for i := range points {
+		// Jitter the angle within the middle of its slice of the circle. This
+		// keeps every gap between consecutive angles under pi, so the origin is
+		// always inside.
+		angle := 2 * math.Pi * (float64(i) + 0.3 + 0.4*rng.Float64()) / float64(n)
+		radius := 0.5 + 0.5*rng.Float64()
+		points[i] = &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}
+	}
+	return Polygon{Points: points}
+}
+
+// Create a random Y-monotone polygon with n vertices. The bottom and top
+// vertices sit on the Y axis, and the remaining vertices are randomly assigned
+// to a chain on either side of it, so no two chains can cross.
+func RandomMonotonePolygon(n int, rng *rand.Rand) Polygon {
+	if n < 3 {
+		panic(fmt.Sprintf("polygon needs at least 3 points, got %d", n))
+	}
+	var leftChain, rightChain []*Point
+	for i := 1; i < n-1; i++ {
+		// Jitter Y values while keeping them strictly increasing
+		y := float64(i) + 0.5*rng.Float64() - 0.25
+		x := 0.1 + rng.Float64()
+		if i == 1 || (i > 2 && rng.Intn(2) == 0) {
+			// Guarantee at least one point on the right chain, so that the polygon
+			// has nonzero area.
+			rightChain = append(rightChain, &Point{X: x, Y: y})
+		} else {
+			leftChain = append(leftChain, &Point{X: -x, Y: y})
+		}
+	}
+
+	// Counterclockwise: up the right chain, then down the left chain
+	points := make([]*Point, 0, n)
+	points = append(points, &Point{X: 0, Y: 0})
+	points = append(points, rightChain...)
+	points = append(points, &Point{X: 0, Y: float64(n - 1)})
+	for i := len(leftChain) - 1; i >= 0; i-- {
+		points = append(points, leftChain[i])
+	}
+	return Polygon{Points: points}
+}
+
+// Create a grid-aligned staircase with the given number of unit steps, with
+// its corner at the origin. These shapes have many shared X and Y values, so
+// they stress the lexicographic tie-breaking.
+func StaircasePolygon(steps int) Polygon {
+	if steps < 1 {
+		panic(fmt.Sprintf("staircase needs at least one step, got %d", steps))
+	}
+	points := make([]*Point, 0, 2*steps+2)
+	points = append(points, &Point{X: 0, Y: 0})
+	for i := 0; i < steps; i++ {
+		x := float64(steps - i)
+		points = append(points, &Point{X: x, Y: float64(i)})
+		points = append(points, &Point{X: x, Y: float64(i + 1)})
+	}
+	points = append(points, &Point{X: 0, Y: float64(steps)})
+	return Polygon{Points: points}
+}
+
+// Create a regular polygon centered on the origin. The first vertex is at the
+// given rotation angle, in radians.
+func RegularPolygon(n int, radius float64, rotation float64) Polygon {
+	if n < 3 {
+		panic(fmt.Sprintf("polygon needs at least 3 points, got %d", n))
+	}
+	points := make([]*Point, n)
+	for i := range points {
+		angle := rotation + 2*math.Pi*float64(i)/float64(n)
+		points[i] = &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}
+	}
+	return Polygon{Points: points}
+}
+
+// Create a star centered on the origin, with the given number of points, and
+// alternating outer and inner radii.
+func StarPolygon(points int, outerRadius, innerRadius float64) Polygon {
+	if points < 2 {
+		panic(fmt.Sprintf("star needs at least 2 points, got %d", points))
+	}
+	n := 2 * points
+	result := make([]*Point, n)
+	for i := range result {
+		radius := outerRadius
+		if i%2 == 1 {
+			radius = innerRadius
+		}
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		result[i] = &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}
+	}
+	return Polygon{Points: result}
+}
+
+// Create an axis-aligned rectangle. Corners are exactly the given values, so
+// this is suitable for grid-aligned tests.
+func Rectangle(minX, minY, maxX, maxY float64) Polygon {
+	return Polygon{Points: []*Point{
+		{X: minX, Y: minY},
+		{X: maxX, Y: minY},
+		{X: maxX, Y: maxY},
+		{X: minX, Y: maxY},
+	}}
+}
+
+// Create a spiral shaped band winding outward around the origin. Arms are one
+// unit apart, and the band is half a unit wide, so this is highly non-monotone.
+func SpiralPolygon(turns int, pointsPerTurn int) Polygon {
+	if turns < 1 || pointsPerTurn < 3 {
+		panic(fmt.Sprintf("invalid spiral parameters: %d turns, %d points per turn", turns, pointsPerTurn))
+	}
+	const halfWidth = 0.25
+	n := turns*pointsPerTurn + 1
+	outer := make([]*Point, n)
+	inner := make([]*Point, n)
+	for i := 0; i < n; i++ {
+		turn := float64(i) / float64(pointsPerTurn)
+		angle := 2 * math.Pi * turn
+		radius := 1 + turn
+		cos, sin := math.Cos(angle), math.Sin(angle)
+		outer[i] = &Point{X: (radius + halfWidth) * cos, Y: (radius + halfWidth) * sin}
+		inner[i] = &Point{X: (radius - halfWidth) * cos, Y: (radius - halfWidth) * sin}
+	}
+
+	// Go out along the outer edge, then back along the inner edge
+	points := make([]*Point, 0, 2*n)
+	points = append(points, outer...)
+	for i := n - 1; i >= 0; i-- {
+		points = append(points, inner[i])
+	}
+	return Polygon{Points: points}
+}
+
+// Combine an outer polygon with holes. The outer polygon is made
+// counterclockwise, and the holes are made clockwise, reversing them if
+// necessary.
+//
+// This panics if any hole vertex is not strictly inside the outer polygon, or
+// if any hole has a vertex inside another hole. This catches most mistakes, but
+// it does not check for crossing segments.
+func WithHoles(outer Polygon, holes ...Polygon) PolygonList {
+	if advanced.IsCW(&outer) {
+		outer = outer.Reverse()
+	}
+	result := PolygonList{outer}
+	for i, hole := range holes {
+		for _, p := range hole.Points {
+			if !outer.ContainsPointByEvenOdd(p) {
+				panic(fmt.Sprintf("hole %d has point %v outside the outer polygon", i, p))
+			}
+			for j, other := range holes {
+				if j != i && other.ContainsPointByEvenOdd(p) {
+					panic(fmt.Sprintf("hole %d has point %v inside hole %d", i, p, j))
+				}
+			}
+		}
+		if advanced.IsCCW(&hole) {
+			hole = hole.Reverse()
+		}
+		result = append(result, hole)
+	}
+	return result
+}
+
+// Move every point of the polygon by the given offset. This creates new points,
+// and leaves the original polygon untouched.
+func Translate(poly Polygon, dx, dy float64) Polygon {
+	points := make([]*Point, len(poly.Points))
+	for i, p := range poly.Points {
+		points[i] = &Point{X: p.X + dx, Y: p.Y + dy}
+	}
+	return Polygon{Points: points}
+}
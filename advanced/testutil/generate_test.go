@@ -0,0 +1,144 @@
+package testutil
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerators(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cases := map[string]PolygonList{
+		"RandomSimplePolygon":   {RandomSimplePolygon(100, rng)},
+		"RandomSimplePolygon 3": {RandomSimplePolygon(3, rng)},
+		"RandomMonotonePolygon": {RandomMonotonePolygon(100, rng)},
+		"StaircasePolygon":      {StaircasePolygon(10)},
+		"RegularPolygon":        {RegularPolygon(17, 3, 0.1)},
+		"StarPolygon":           {StarPolygon(7, 4, 1)},
+		"SpiralPolygon":         {SpiralPolygon(3, 20)},
+		"WithHoles": WithHoles(
+			RegularPolygon(30, 10, 0),
+			Translate(RegularPolygon(5, 2, 0), -4, 0),
+			Translate(Rectangle(-1, -1, 1, 1), 4, 0),
+		),
+		"SimpleStar":        SimpleStar(),
+		"SquareWithHole":    SquareWithHole(),
+		"StarOutline":       StarOutline(),
+		"StarStripes":       StarStripes(),
+		"MultiLayeredHoles": MultiLayeredHoles(),
+	}
+
+	for name, list := range cases {
+		list := list
+		t.Run(name, func(t *testing.T) {
+			for i, poly := range list {
+				assertSimple(t, poly, fmt.Sprintf("polygon %d", i))
+			}
+			var expectedArea float64
+			for _, poly := range list {
+				expectedArea += poly.SignedArea()
+			}
+			require.Greater(t, expectedArea, 0.0)
+
+			triangles := list.Triangulate()
+			var actualArea float64
+			for _, tri := range triangles {
+				actualArea += advanced.Area(tri)
+			}
+			assert.InDelta(t, expectedArea, actualArea, 1e-6)
+		})
+	}
+}
+
+func TestGenerators_Deterministic(t *testing.T) {
+	a := RandomSimplePolygon(20, rand.New(rand.NewSource(42)))
+	b := RandomSimplePolygon(20, rand.New(rand.NewSource(42)))
+	assert.Equal(t, a, b)
+
+	a = RandomMonotonePolygon(20, rand.New(rand.NewSource(42)))
+	b = RandomMonotonePolygon(20, rand.New(rand.NewSource(42)))
+	assert.Equal(t, a, b)
+}
+
+func TestGenerators_Windings(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, poly := range []Polygon{
+		RandomSimplePolygon(10, rng),
+		RandomMonotonePolygon(10, rng),
+		StaircasePolygon(3),
+		RegularPolygon(5, 1, 0),
+		StarPolygon(5, 2, 1),
+		SpiralPolygon(2, 10),
+	} {
+		assert.True(t, advanced.IsCCW(&poly))
+	}
+
+	list := WithHoles(Rectangle(0, 0, 10, 10).Reverse(), Rectangle(1, 1, 2, 2))
+	assert.True(t, advanced.IsCCW(&list[0]))
+	assert.True(t, advanced.IsCW(&list[1]))
+}
+
+func TestWithHoles_Invalid(t *testing.T) {
+	outer := Rectangle(0, 0, 10, 10)
+	assert.Panics(t, func() {
+		WithHoles(outer, Rectangle(8, 8, 12, 12))
+	}, "hole crossing the outer polygon")
+	assert.Panics(t, func() {
+		WithHoles(outer, Rectangle(1, 1, 5, 5), Rectangle(2, 2, 3, 3))
+	}, "hole inside another hole")
+}
+
+// Brute force check that no two non-adjacent edges of the polygon touch.
+func assertSimple(t *testing.T, poly Polygon, name string) {
+	n := len(poly.Points)
+	for i := 0; i < n; i++ {
+		a, b := poly.Points[i], poly.Points[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+			c, d := poly.Points[j], poly.Points[(j+1)%n]
+			assert.False(t, segmentsTouch(a, b, c, d), "%s: edge %d crosses edge %d", name, i, j)
+		}
+	}
+}
+
+func segmentsTouch(a, b, c, d *Point) bool {
+	orient := func(p, q, r *Point) float64 {
+		return (q.X-p.X)*(r.Y-p.Y) - (q.Y-p.Y)*(r.X-p.X)
+	}
+	d1, d2 := orient(c, d, a), orient(c, d, b)
+	d3, d4 := orient(a, b, c), orient(a, b, d)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	onSegment := func(p, q, r *Point) bool {
+		return math.Min(p.X, q.X) <= r.X && r.X <= math.Max(p.X, q.X) &&
+			math.Min(p.Y, q.Y) <= r.Y && r.Y <= math.Max(p.Y, q.Y)
+	}
+	return (d1 == 0 && onSegment(c, d, a)) ||
+		(d2 == 0 && onSegment(c, d, b)) ||
+		(d3 == 0 && onSegment(a, b, c)) ||
+		(d4 == 0 && onSegment(a, b, d))
+}
+
+func TestCorpus(t *testing.T) {
+	shapes := Corpus()
+	require.NotEmpty(t, shapes)
+	names := make(map[string]bool)
+	for _, shape := range shapes {
+		assert.False(t, names[shape.Name], "duplicate name %q", shape.Name)
+		names[shape.Name] = true
+		assert.NotEmpty(t, shape.Shape, shape.Name)
+	}
+
+	// Every call makes new points
+	again := Corpus()
+	assert.Equal(t, shapes[0].Shape, again[0].Shape)
+	assert.NotSame(t, shapes[0].Shape[0].Points[0], again[0].Shape[0].Points[0])
+}
@@ -0,0 +1,389 @@
+package advanced
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Removal of features narrower than a given width, by morphological opening:
+// shrink the shape by half the width, then grow it back by the same amount.
+// Anything too thin to survive the shrinking is gone for good, while
+// everything else comes back as it was, except that convex corners are rounded
+// off.
+//
+// Offsetting rings exactly means resolving all the self-intersections of the
+// offset rings, which is a polygon clipping library's worth of work. Instead,
+// the opening is evaluated on a grid of samples, and the result is traced back
+// out of the grid with marching squares. Traced rings never cross or touch, and
+// they come out wound correctly, so the result is always valid input for
+// triangulation. The price is that the new boundary is only accurate to within
+// a fraction of a grid cell.
+
+// Grid cells across minWidth
+const thinFeatureResolution = 8
+
+// Limit on the grid samples along each axis, to bound the memory used. Past
+// this, the grid gets coarser.
+const maxThinFeatureSamples = 2048
+
+// Remove every part of the shape which is narrower than minWidth: thin spikes,
+// thin bridges between larger parts, and thin walls between holes and the
+// outside. What remains is the union of all the disks of diameter minWidth
+// which fit inside the shape, so convex corners come back rounded, while
+// concave corners are kept.
+//
+// The result is a new list of rings, with new points throughout. Its boundary
+// is accurate to within about minWidth/8, or coarser for input which is
+// thousands of times larger than minWidth. Solid rings which vanish entirely
+// are reported as warnings if Options with Diagnostics are given. Errors are
+// returned for a minWidth which isn't positive, or is too small to resolve
+// relative to the size of the input, and for input which can't be
+// triangulated.
+func (l PolygonList) RemoveThinFeatures(minWidth float64, opts ...Options) (result PolygonList, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if !(minWidth > 0) || math.IsInf(minWidth, 1) {
+		return nil, errors.Errorf("minWidth must be positive and finite, not %v", minWidth)
+	}
+	checkCoordinateRange(l)
+
+	var segments []*Segment
+	bounds := Rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, poly := range l {
+		for i, p := range poly.Points {
+			segments = append(segments, NewSegment(p, poly.Points[CircularIndex(i+1, len(poly.Points))]))
+			bounds.MinX, bounds.MinY = math.Min(bounds.MinX, p.X), math.Min(bounds.MinY, p.Y)
+			bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, p.X), math.Max(bounds.MaxY, p.Y)
+		}
+	}
+	if len(segments) == 0 {
+		return PolygonList{}, nil
+	}
+
+	radius := minWidth / 2
+	// Leave two cells of padding, so that every traced ring closes inside the
+	// grid
+	extent := math.Max(bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY)
+	cellSize := math.Max(minWidth/thinFeatureResolution, extent/(maxThinFeatureSamples-5))
+	if cellSize > radius/2 {
+		return nil, errors.Errorf("minWidth %v is too small to resolve for input of size %v", minWidth, extent)
+	}
+	grid := newOpeningGrid(bounds, cellSize)
+
+	graph := &QueryGraph{}
+	graph.AddPolygons(l)
+	eroded := grid.erode(graph, segments, radius)
+	for _, ring := range l.vanishedRings(grid, eroded) {
+		options.Diagnostics.warnf(WarningThinFeature, "ring %d is narrower than %v everywhere, and was removed", ring, minWidth)
+	}
+	return grid.trace(grid.open(eroded, radius)), nil
+}
+
+// A grid of samples covering the input, with a margin.
+type openingGrid struct {
+	minX, minY float64
+	cellSize   float64
+	nx, ny     int
+}
+
+func newOpeningGrid(bounds Rect, cellSize float64) *openingGrid {
+	return &openingGrid{
+		minX:     bounds.MinX - 2*cellSize,
+		minY:     bounds.MinY - 2*cellSize,
+		cellSize: cellSize,
+		nx:       int(math.Ceil((bounds.MaxX-bounds.MinX)/cellSize)) + 5,
+		ny:       int(math.Ceil((bounds.MaxY-bounds.MinY)/cellSize)) + 5,
+	}
+}
+
+func (grid *openingGrid) sample(i, j int) *Point {
+	return fabricatedPoint(grid.minX+float64(i)*grid.cellSize, grid.minY+float64(j)*grid.cellSize)
+}
+
+// Find the samples which survive shrinking: those inside the shape, and at
+// least radius away from every segment. Segments are bucketed by radius-sized
+// cells, so each sample only checks the segments nearby.
+func (grid *openingGrid) erode(graph *QueryGraph, segments []*Segment, radius float64) []bool {
+	bucketOf := func(x, y float64) [2]int {
+		return [2]int{int(math.Floor((x - grid.minX) / radius)), int(math.Floor((y - grid.minY) / radius))}
+	}
+	buckets := make(map[[2]int][]*Segment)
+	for _, s := range segments {
+		lo := bucketOf(math.Min(s.Start.X, s.End.X), math.Min(s.Start.Y, s.End.Y))
+		hi := bucketOf(math.Max(s.Start.X, s.End.X), math.Max(s.Start.Y, s.End.Y))
+		for bx := lo[0]; bx <= hi[0]; bx++ {
+			for by := lo[1]; by <= hi[1]; by++ {
+				buckets[[2]int{bx, by}] = append(buckets[[2]int{bx, by}], s)
+			}
+		}
+	}
+
+	eroded := make([]bool, grid.nx*grid.ny)
+	for j := 0; j < grid.ny; j++ {
+	samples:
+		for i := 0; i < grid.nx; i++ {
+			p := grid.sample(i, j)
+			if !graph.ContainsPoint(p) {
+				continue
+			}
+			bucket := bucketOf(p.X, p.Y)
+			for bx := bucket[0] - 1; bx <= bucket[0]+1; bx++ {
+				for by := bucket[1] - 1; by <= bucket[1]+1; by++ {
+					for _, s := range buckets[[2]int{bx, by}] {
+						if distanceToSegment(p, s) < radius {
+							continue samples
+						}
+					}
+				}
+			}
+			eroded[j*grid.nx+i] = true
+		}
+	}
+	return eroded
+}
+
+// Grow the eroded samples back by radius. The result is positive at the
+// samples which are inside the opening, and falls off linearly with distance
+// outside it, so that the boundary can be interpolated between samples.
+func (grid *openingGrid) open(eroded []bool, radius float64) []float64 {
+	// Squared distances in cells, from the two pass Euclidean distance transform
+	// of Felzenszwalb and Huttenlocher
+	const far = 1e20
+	distances := make([]float64, len(eroded))
+	for k, isEroded := range eroded {
+		if !isEroded {
+			distances[k] = far
+		}
+	}
+
+	n := grid.nx
+	if grid.ny > n {
+		n = grid.ny
+	}
+	f, out := make([]float64, n), make([]float64, n)
+	v, z := make([]int, n), make([]float64, n+1)
+	for i := 0; i < grid.nx; i++ {
+		for j := 0; j < grid.ny; j++ {
+			f[j] = distances[j*grid.nx+i]
+		}
+		distanceTransform(f[:grid.ny], out, v, z)
+		for j := 0; j < grid.ny; j++ {
+			distances[j*grid.nx+i] = out[j]
+		}
+	}
+	for j := 0; j < grid.ny; j++ {
+		row := distances[j*grid.nx : (j+1)*grid.nx]
+		copy(f, row)
+		distanceTransform(f[:grid.nx], out, v, z)
+		copy(row, out[:grid.nx])
+	}
+
+	values := make([]float64, len(distances))
+	for k, d := range distances {
+		values[k] = radius - math.Sqrt(d)*grid.cellSize
+	}
+	return values
+}
+
+// One dimensional squared distance transform: out[q] is the minimum over p of
+// (q-p)² + f[p]. This is the lower envelope of the parabolas rooted at each
+// sample. v and z are scratch space, for the parabolas in the envelope and the
+// boundaries between them.
+func distanceTransform(f, out []float64, v []int, z []float64) {
+	intersection := func(q, p int) float64 {
+		return ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+	}
+
+	k := 0
+	v[0] = 0
+	z[0], z[1] = math.Inf(-1), math.Inf(1)
+	for q := 1; q < len(f); q++ {
+		s := intersection(q, v[k])
+		for s <= z[k] {
+			k--
+			s = intersection(q, v[k])
+		}
+		k++
+		v[k] = q
+		z[k], z[k+1] = s, math.Inf(1)
+	}
+
+	k = 0
+	for q := range f {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		out[q] = float64((q-v[k])*(q-v[k])) + f[v[k]]
+	}
+}
+
+// Trace the boundary of the positive samples with marching squares. Each
+// boundary crossing gets one point, shared by the two cells on either side, and
+// each cell links its crossings with the inside on the left. So the links form
+// closed rings, counterclockwise around solids, and clockwise around holes.
+func (grid *openingGrid) trace(values []float64) PolygonList {
+	inside := func(i, j int) bool {
+		return values[j*grid.nx+i] > 0
+	}
+
+	// Crossings are keyed by the sample at the start of their grid edge, and
+	// whether the edge runs up from it rather than right
+	type edgeKey struct {
+		i, j     int
+		vertical bool
+	}
+	crossings := make(map[edgeKey]*Point)
+	var order []*Point
+	crossing := func(key edgeKey) *Point {
+		if p, ok := crossings[key]; ok {
+			return p
+		}
+		i2, j2 := key.i+1, key.j
+		if key.vertical {
+			i2, j2 = key.i, key.j+1
+		}
+		a, b := values[key.j*grid.nx+key.i], values[j2*grid.nx+i2]
+		// Keep crossings away from the samples, so that crossings on different
+		// edges never coincide
+		t := clamp(a/(a-b), 0.05, 0.95)
+		start, end := grid.sample(key.i, key.j), grid.sample(i2, j2)
+		p := fabricatedPoint(start.X+float64(t*(end.X-start.X)), start.Y+float64(t*(end.Y-start.Y)))
+		crossings[key] = p
+		order = append(order, p)
+		return p
+	}
+
+	next := make(map[*Point]*Point)
+	for j := 0; j+1 < grid.ny; j++ {
+		for i := 0; i+1 < grid.nx; i++ {
+			// Corners and edges counterclockwise from the bottom left. Edge k runs
+			// from corner k to corner k+1.
+			corners := [4]bool{inside(i, j), inside(i+1, j), inside(i+1, j+1), inside(i, j+1)}
+			edges := [4]edgeKey{{i, j, false}, {i + 1, j, true}, {i, j + 1, false}, {i, j, true}}
+
+			var cellCrossings []*Point
+			var leaving []bool
+			for k := 0; k < 4; k++ {
+				if corners[k] != corners[(k+1)%4] {
+					cellCrossings = append(cellCrossings, crossing(edges[k]))
+					leaving = append(leaving, corners[k])
+				}
+			}
+			if len(cellCrossings) == 0 {
+				continue
+			}
+
+			// Crossings alternate between leaving and entering the inside. Each
+			// leaving crossing links to an adjacent entering one. That only matters
+			// for saddles, where the center decides whether the inside corners are
+			// joined through the middle of the cell.
+			center := values[j*grid.nx+i] + values[j*grid.nx+i+1] +
+				values[(j+1)*grid.nx+i+1] + values[(j+1)*grid.nx+i]
+			step := -1
+			if center > 0 {
+				step = 1
+			}
+			for m, p := range cellCrossings {
+				if leaving[m] {
+					next[p] = cellCrossings[CircularIndex(m+step, len(cellCrossings))]
+				}
+			}
+		}
+	}
+
+	result := PolygonList{}
+	visited := make(PointSet)
+	for _, start := range order {
+		if visited.Contains(start) {
+			continue
+		}
+		var points []*Point
+		for p := start; !visited.Contains(p); p = next[p] {
+			visited.Add(p)
+			points = append(points, p)
+		}
+		result = append(result, dropCollinearPoints(points))
+	}
+	return result
+}
+
+// Drop the vertices which lie on a straight line between their neighbors, such
+// as the many crossings along an axis-aligned edge.
+func dropCollinearPoints(points []*Point) Polygon {
+	classes := Polygon{points}.VertexClassification()
+	result := make([]*Point, 0, len(points))
+	for i, p := range points {
+		if classes[i] != Collinear {
+			result = append(result, p)
+		}
+	}
+	return Polygon{result}
+}
+
+// Find the solid rings with no eroded samples inside them, other than samples
+// in rings nested within them.
+func (l PolygonList) vanishedRings(grid *openingGrid, eroded []bool) []int {
+	parents := l.ContainingRings()
+	depths := make([]int, len(l))
+	for i := range l {
+		for ring := parents[i]; ring >= 0; ring = parents[ring] {
+			depths[i]++
+		}
+	}
+
+	// Check the deepest rings first, so each sample is credited to the
+	// innermost solid ring around it
+	windings := l.Windings()
+	var solids []int
+	for i, ccw := range windings {
+		if ccw {
+			solids = append(solids, i)
+		}
+	}
+	sort.SliceStable(solids, func(a, b int) bool {
+		return depths[solids[a]] > depths[solids[b]]
+	})
+
+	alive := make([]bool, len(l))
+	for k, isEroded := range eroded {
+		if !isEroded {
+			continue
+		}
+		p := grid.sample(k%grid.nx, k/grid.nx)
+		for _, ring := range solids {
+			if l[ring].ContainsPointByEvenOdd(p) {
+				alive[ring] = true
+				break
+			}
+		}
+	}
+
+	var result []int
+	for _, ring := range solids {
+		if !alive[ring] {
+			result = append(result, ring)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// The distance from a point to the nearest point on a segment
+func distanceToSegment(p *Point, s *Segment) float64 {
+	dx, dy := s.End.X-s.Start.X, s.End.Y-s.Start.Y
+	t := 0.0
+	if lengthSquared := float64(dx*dx) + float64(dy*dy); lengthSquared > 0 {
+		t = clamp((float64((p.X-s.Start.X)*dx)+float64((p.Y-s.Start.Y)*dy))/lengthSquared, 0, 1)
+	}
+	return math.Hypot(p.X-(s.Start.X+float64(t*dx)), p.Y-(s.Start.Y+float64(t*dy)))
+}
@@ -0,0 +1,110 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveThinFeatures_RoundsCorners(t *testing.T) {
+	// A square with a spike much thinner than minWidth
+	list := PolygonList{{[]*Point{
+		{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 4.75}, {X: 15, Y: 4.75}, {X: 15, Y: 5.25}, {X: 10, Y: 5.25}, {X: 10, Y: 10}, {X: 0, Y: 10},
+	}}}
+	result, err := list.RemoveThinFeatures(4)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+
+	// The opening of a square is the square with its corners rounded to the
+	// radius of the disk, and the spike is gone. Compare by sampling, away from
+	// the expected boundary.
+	const tolerance = 0.5
+	for x := -1.0; x <= 16; x += 0.25 {
+		for y := -1.0; y <= 11; y += 0.25 {
+			coreDistance := math.Hypot(x-clamp(x, 2, 8), y-clamp(y, 2, 8))
+			if math.Abs(coreDistance-2) < tolerance {
+				continue
+			}
+			assert.Equal(t, coreDistance < 2, result.ContainsPointByEvenOdd(&Point{X: x, Y: y}), "at %v, %v", x, y)
+		}
+	}
+
+	assert.NoError(t, triangulateRecovering(result, Options{}))
+	assert.InDelta(t, 100-(4-math.Pi)*4, totalArea(result.Triangulate()), 1)
+}
+
+func TestRemoveThinFeatures_Star(t *testing.T) {
+	// Eight points, each narrower at the base than minWidth
+	var points []*Point
+	for i := 0; i < 16; i++ {
+		radius := 5.0
+		if i%2 == 0 {
+			radius = 12
+		}
+		angle := float64(i) * math.Pi / 8
+		points = append(points, &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)})
+	}
+	list := PolygonList{{points}}
+
+	result, err := list.RemoveThinFeatures(4)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	for i := 0; i < 16; i++ {
+		angle := float64(i) * math.Pi / 8
+		for _, radius := range []float64{0, 2, 3.5} {
+			assert.True(t, result.ContainsPointByEvenOdd(&Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}))
+		}
+		if i%2 == 0 {
+			// Along the points, which are gone
+			for _, radius := range []float64{7, 9, 11} {
+				p := &Point{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}
+				assert.True(t, list.ContainsPointByEvenOdd(p))
+				assert.False(t, result.ContainsPointByEvenOdd(p), "at %v", p)
+			}
+		}
+	}
+	assert.NoError(t, triangulateRecovering(result, Options{}))
+}
+
+func TestRemoveThinFeatures_VanishingRings(t *testing.T) {
+	list := PolygonList{
+		{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}},
+		// A thin wall between this hole and the outside
+		{[]*Point{{X: 2, Y: 2}, {X: 2, Y: 9.5}, {X: 8, Y: 9.5}, {X: 8, Y: 2}}},
+		// A separate sliver, which goes entirely
+		{[]*Point{{X: 20, Y: 0}, {X: 20.5, Y: 0}, {X: 20.5, Y: 10}, {X: 20, Y: 10}}},
+	}
+	diagnostics := &Diagnostics{}
+	result, err := list.RemoveThinFeatures(1, Options{Diagnostics: diagnostics})
+	assert.NoError(t, err)
+
+	// The hole has opened up to the outside, leaving a single U-shaped ring
+	assert.Len(t, result, 1)
+	assert.True(t, result.ContainsPointByEvenOdd(&Point{X: 1, Y: 5}))
+	assert.False(t, result.ContainsPointByEvenOdd(&Point{X: 5, Y: 5}))
+	assert.False(t, result.ContainsPointByEvenOdd(&Point{X: 5, Y: 9.75}))
+	assert.False(t, result.ContainsPointByEvenOdd(&Point{X: 20.25, Y: 5}))
+
+	if assert.Len(t, diagnostics.Warnings, 1) {
+		assert.Equal(t, WarningThinFeature, diagnostics.Warnings[0].Kind)
+		assert.Contains(t, diagnostics.Warnings[0].Message, "ring 2")
+	}
+	assert.NoError(t, triangulateRecovering(result, Options{}))
+}
+
+func TestRemoveThinFeatures_Errors(t *testing.T) {
+	list := SquareWithHole()
+	for _, minWidth := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		_, err := list.RemoveThinFeatures(minWidth)
+		assert.Error(t, err)
+	}
+
+	// Too fine to resolve
+	_, err := list.RemoveThinFeatures(1e-6)
+	assert.Error(t, err)
+
+	result, err := PolygonList{}.RemoveThinFeatures(1)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
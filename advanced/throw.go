@@ -13,6 +13,25 @@ func fatalf(format string, args ...interface{}) {
 	panic(errors.Errorf(format, args...))
 }
 
+// Panic with an existing error, typically one of the typed errors in errors.go.
+func throw(err error) {
+	panic(err)
+}
+
+// Add context to a TriangulateError on its way up, so that the caller can tell
+// what was being worked on when it happened. This must be deferred directly.
+// Other panics pass through untouched, as do errors when the context is empty.
+func wrapPanic(context func() string) {
+	if r := recover(); r != nil {
+		if err, ok := r.(TriangulateError); ok {
+			if message := context(); message != "" {
+				panic(errors.Wrap(err, message))
+			}
+		}
+		panic(r)
+	}
+}
+
 func HandleTriangulatePanicRecover(r interface{}) error {
 	if r != nil {
 		if triangulateError, ok := r.(TriangulateError); ok {
@@ -1,6 +1,7 @@
 package advanced
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -42,3 +43,50 @@ func TestHandleTriangulatePanicRecover(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestErrorsNameSourcePolygon(t *testing.T) {
+	withBadRing := func(bad []*Point) PolygonList {
+		var list PolygonList
+		for i := 0; i < 10; i++ {
+			if i == 7 {
+				list = append(list, Polygon{bad})
+				continue
+			}
+			x := float64(i * 5)
+			list = append(list, Polygon{[]*Point{{X: x, Y: 0}, {X: x + 2, Y: 0}, {X: x + 2, Y: 2}, {X: x, Y: 2}}})
+		}
+		return list
+	}
+
+	t.Run("while adding a segment", func(t *testing.T) {
+		// Doubles back along its first edge
+		err := triangulateRecovering(withBadRing([]*Point{{X: 50, Y: 0}, {X: 52, Y: 0}, {X: 51, Y: 0}, {X: 51, Y: 2}}), Options{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "while processing segment #0 of polygon #7 (from {50.00, 0.00} to {52.00, 0.00}), number")
+		// The context is added once, for the segment being added when it failed
+		assert.Equal(t, 1, strings.Count(err.Error(), "while processing"))
+	})
+
+	t.Run("while extracting monotones", func(t *testing.T) {
+		// Only two points, so the monotone between its sides is degenerate
+		err := triangulateRecovering(withBadRing([]*Point{{X: 50, Y: 0}, {X: 51, Y: 1}}), Options{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "polygon is degenerate")
+		assert.Contains(t, err.Error(), "segment #0 of polygon #7")
+		assert.Contains(t, err.Error(), "segment #1 of polygon #7")
+	})
+
+	t.Run("typed errors still match", func(t *testing.T) {
+		err := func() (err error) {
+			defer func() {
+				err = HandleTriangulatePanicRecover(recover())
+			}()
+			defer wrapPanic(func() string { return "context" })
+			throw(&ZeroAreaTriangleError{})
+			return nil
+		}()
+		var zeroAreaErr *ZeroAreaTriangleError
+		assert.ErrorAs(t, err, &zeroAreaErr)
+		assert.Contains(t, err.Error(), "context: ")
+	})
+}
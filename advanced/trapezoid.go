@@ -28,6 +28,36 @@ type Trapezoid struct {
 	Top, Bottom                      *Point
 	TrapezoidsAbove, TrapezoidsBelow TrapezoidNeighborList
 	Sink                             *QueryNode
+
+	// Whether the trapezoid is in the TrapezoidSet being extracted into
+	// monotones, and not yet part of one. Keeping this on the trapezoid makes
+	// membership a field load, where a map lookup would dominate extraction.
+	pending bool
+}
+
+// Classification of a trapezoid's boundary vertices, by where they lie on the
+// trapezoid.
+type VertexKind int
+
+const (
+	// The vertex is an endpoint of the trapezoid's left segment
+	LeftChain VertexKind = iota
+	// The vertex is an endpoint of the trapezoid's right segment
+	RightChain
+	// The vertex lies on the top or bottom edge, away from either side
+	Floating
+)
+
+func (kind VertexKind) String() string {
+	switch kind {
+	case LeftChain:
+		return "LeftChain"
+	case RightChain:
+		return "RightChain"
+	case Floating:
+		return "Floating"
+	}
+	return "Invalid"
 }
 
 // Trapezoids can have up to two neighbors above and below them in the stable
@@ -44,6 +74,71 @@ func (t *Trapezoid) IsInside() bool {
 	return t.Left != nil && t.Right != nil && t.Left.PointsDown()
 }
 
+// Is the point, which was located in this trapezoid, within Epsilon of an
+// edge which isn't shared? The search settles a point on an edge to one side
+// of it, so the edge is a side of the trapezoid, or of one of its neighbors
+// when the point is at a vertex which ends the edge.
+func (graph *QueryGraph) isNearEdge(t *Trapezoid, p *Point) bool {
+	near := func(t *Trapezoid) bool {
+		for _, segment := range [2]*Segment{t.Left, t.Right} {
+			if segment != nil && !graph.isShared(segment) && distanceToSegment(p, segment) <= Epsilon {
+				return true
+			}
+		}
+		return false
+	}
+	if near(t) {
+		return true
+	}
+	for _, neighbors := range [2]*TrapezoidNeighborList{&t.TrapezoidsAbove, &t.TrapezoidsBelow} {
+		for _, neighbor := range neighbors {
+			if neighbor != nil && near(neighbor) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Get the two polygon vertices on the trapezoid's boundary (see the comment on
+// the Top and Bottom fields), and classify each by which side of the trapezoid
+// it lies on. When a vertex is the endpoint of both sides, as at the tip of a
+// triangle, it is reported as LeftChain.
+func (t *Trapezoid) BoundaryVertices() (top, bottom *Point, topKind, bottomKind VertexKind) {
+	kind := func(dir YDirection) VertexKind {
+		onLeft, onRight := t.vertexSides(dir)
+		if onLeft {
+			return LeftChain
+		} else if onRight {
+			return RightChain
+		}
+		return Floating
+	}
+	return t.Top, t.Bottom, kind(Up), kind(Down)
+}
+
+// Check whether the top or bottom vertex of the trapezoid is an endpoint of
+// each of its sides.
+func (t *Trapezoid) vertexSides(dir YDirection) (onLeft, onRight bool) {
+	if dir == Up {
+		onLeft = t.Left != nil && t.Left.Top() == t.Top
+		onRight = t.Right != nil && t.Right.Top() == t.Top
+	} else {
+		onLeft = t.Left != nil && t.Left.Bottom() == t.Bottom
+		onRight = t.Right != nil && t.Right.Bottom() == t.Bottom
+	}
+	return onLeft, onRight
+}
+
+// A trapezoid needs a diagonal when its top and bottom vertices are not both
+// on the same side. Splitting along that diagonal separates the trapezoid into
+// pieces which each belong to a single monotone polygon.
+func (t *Trapezoid) NeedsDiagonal() bool {
+	topOnLeft, topOnRight := t.vertexSides(Up)
+	bottomOnLeft, bottomOnRight := t.vertexSides(Down)
+	return !(topOnLeft && bottomOnLeft) && !(topOnRight && bottomOnRight)
+}
+
 func (t *Trapezoid) SegmentForSide(side XDirection) *Segment {
 	if side == Left {
 		return t.Left
@@ -98,6 +193,12 @@ func (bottomTrapezoid *Trapezoid) NonzeroOverlapWithTrapezoidAbove(topTrapezoid
 
 // Check if a segment crosses the bottom edge of the trapezoid.
 func (t *Trapezoid) BottomIntersectsSegment(segment *Segment) bool {
+	return t.bottomIntersectsSegment(segment, nil)
+}
+
+// BottomIntersectsSegment, noting how close the decisions were in margins,
+// which may be nil
+func (t *Trapezoid) bottomIntersectsSegment(segment *Segment, margins *marginTracker) bool {
 	if t.Bottom == nil { // Bottom is at infinity, nothing can intersect it
 		return false
 	}
@@ -116,9 +217,9 @@ func (t *Trapezoid) BottomIntersectsSegment(segment *Segment) bool {
 
 	// Find the x value for the segment at the bottom of the trapezoid
 	x := segment.SolveForX(t.Bottom.Y)
-	point := &Point{x, t.Bottom.Y}
+	point := &Point{X: x, Y: t.Bottom.Y}
 
-	return t.Left.IsLeftOf(point) && t.Right.IsRightOf(point)
+	return t.Left.isLeftOf(point, margins) && t.Right.isRightOf(point, margins)
 }
 
 // Split a trapezoid vertically with a segment, returning the two trapezoids. It
@@ -233,6 +334,29 @@ func (t *Trapezoid) String() string {
 	)
 }
 
+// Describe the trapezoid by its coordinates alone. Unlike String, this is
+// stable between runs, so it is suitable for error messages.
+func (t *Trapezoid) Geometry() string {
+	describeSegment := func(s *Segment) string {
+		if s == nil {
+			return "Ø"
+		}
+		return fmt.Sprintf("%v-%v", s.Start, s.End)
+	}
+	describePoint := func(p *Point) string {
+		if p == nil {
+			return "Ø"
+		}
+		return p.String()
+	}
+	return fmt.Sprintf("<L: %s, R: %s, T: %s, B: %s>",
+		describeSegment(t.Left),
+		describeSegment(t.Right),
+		describePoint(t.Top),
+		describePoint(t.Bottom),
+	)
+}
+
 func (t *Trapezoid) DbgName() string {
 	// If the trapezoid is infinite, color it orange
 	name := dbg.Name(t)
@@ -291,6 +415,17 @@ func (tl *TrapezoidNeighborList) ReplaceOrAdd(orig *Trapezoid, replacement *Trap
 	tl.Add(replacement)
 }
 
+// Count the neighbors in the list
+func (tl *TrapezoidNeighborList) Count() int {
+	var count int
+	for _, neighbor := range *tl {
+		if neighbor != nil {
+			count++
+		}
+	}
+	return count
+}
+
 func (tl *TrapezoidNeighborList) AnyNeighbor() *Trapezoid {
 	for _, neighbor := range *tl {
 		if neighbor != nil {
@@ -0,0 +1,55 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundaryVertices(t *testing.T) {
+	type expectation struct {
+		x, y                  float64
+		top, bottom           Point
+		topKind, bottomKind   VertexKind
+		expectedNeedsDiagonal bool
+	}
+
+	check := func(t *testing.T, list PolygonList, cases []expectation) {
+		g := &QueryGraph{}
+		g.AddPolygons(list)
+		for _, c := range cases {
+			trapezoid := g.FindPoint(DefaultDirectionalPoint(c.x, c.y)).Inner.(SinkNode).Trapezoid
+			require.True(t, trapezoid.IsInside())
+			top, bottom, topKind, bottomKind := trapezoid.BoundaryVertices()
+			assert.Equal(t, c.top, *top, "top for point (%v, %v)", c.x, c.y)
+			assert.Equal(t, c.bottom, *bottom, "bottom for point (%v, %v)", c.x, c.y)
+			assert.Equal(t, c.topKind, topKind, "top kind for point (%v, %v)", c.x, c.y)
+			assert.Equal(t, c.bottomKind, bottomKind, "bottom kind for point (%v, %v)", c.x, c.y)
+			assert.Equal(t, c.expectedNeedsDiagonal, trapezoid.NeedsDiagonal(), "diagonal for point (%v, %v)", c.x, c.y)
+		}
+	}
+
+	t.Run("triangle", func(t *testing.T) {
+		list := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 4, Y: 2}, {X: 1, Y: 5}}}}
+		check(t, list, []expectation{
+			// Below the middle vertex. The bottom is the tip of both sides.
+			{1, 1, Point{X: 4, Y: 2}, Point{X: 0, Y: 0}, RightChain, LeftChain, false},
+			// Above the middle vertex. The top is the tip of both sides.
+			{1.5, 3, Point{X: 1, Y: 5}, Point{X: 4, Y: 2}, LeftChain, RightChain, false},
+		})
+	})
+
+	t.Run("square with hole", func(t *testing.T) {
+		check(t, SquareWithHole(), []expectation{
+			// Below the hole. The top is the bottom of the hole, which floats.
+			{0, -4, Point{X: -2, Y: -2}, Point{X: 5, Y: -5}, Floating, RightChain, true},
+			// Left of the hole. Both vertices are on the hole's left side.
+			{-4, 0, Point{X: -2, Y: 2}, Point{X: -2, Y: -2}, RightChain, RightChain, false},
+			// Right of the hole. Both vertices are on the hole's right side.
+			{4, 0, Point{X: 2, Y: 2}, Point{X: 2, Y: -2}, LeftChain, LeftChain, false},
+			// Above the hole. The bottom is the top of the hole, which floats.
+			{0, 4, Point{X: -5, Y: 5}, Point{X: 2, Y: 2}, LeftChain, Floating, true},
+		})
+	})
+}
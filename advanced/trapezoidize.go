@@ -0,0 +1,62 @@
+package advanced
+
+// One trapezoid of the decomposition of the inside of a polygon list, as
+// Trapezoidize finds it. The top and bottom are horizontal, through the Top
+// and Bottom vertices, and the sides lie along input edges. Either side may
+// come to a point, making the trapezoid a triangle.
+type TrapezoidResult struct {
+	LeftTop, LeftBottom, RightTop, RightBottom Point
+	// The input vertices whose heights bound the trapezoid. These are the
+	// caller's points, which need not be corners of the trapezoid.
+	Top, Bottom *Point
+}
+
+// The area of the trapezoid
+func (t TrapezoidResult) Area() float64 {
+	return ((t.RightTop.X - t.LeftTop.X) + (t.RightBottom.X - t.LeftBottom.X)) / 2 * (t.Top.Y - t.Bottom.Y)
+}
+
+// Decompose the inside of the polygons into trapezoids with horizontal tops and
+// bottoms, from the same trapezoid map triangulation builds. Together, the
+// trapezoids cover the inside exactly once, in no particular order.
+//
+// Trapezoids of zero height, which the map makes along horizontal edges and
+// between vertices at the same height, cover nothing and are left out. That
+// includes every trapezoid with a horizontal side, since its top and bottom
+// are both on that side.
+func Trapezoidize(list PolygonList) (result []TrapezoidResult, err error) {
+	defer func() {
+		if recoveredErr := HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+	checkCoordinateRange(list)
+
+	graph := &QueryGraph{}
+	graph.AddPolygons(list)
+	if graph.Root == nil {
+		return nil, nil
+	}
+	for t := range graph.IterateTrapezoids() {
+		if !t.IsInside() {
+			continue
+		}
+		// Inside trapezoids are always closed off by the rings around them
+		if t.Top == nil || t.Bottom == nil {
+			fatalf("inside trapezoid is infinite: %s", t.Geometry())
+		}
+		if t.Left.IsHorizontal() || t.Right.IsHorizontal() || Equal(t.Top.Y, t.Bottom.Y) {
+			continue
+		}
+		topY, bottomY := t.Top.Y, t.Bottom.Y
+		result = append(result, TrapezoidResult{
+			LeftTop:     Point{X: t.Left.SolveForX(topY), Y: topY},
+			LeftBottom:  Point{X: t.Left.SolveForX(bottomY), Y: bottomY},
+			RightTop:    Point{X: t.Right.SolveForX(topY), Y: topY},
+			RightBottom: Point{X: t.Right.SolveForX(bottomY), Y: bottomY},
+			Top:         t.Top,
+			Bottom:      t.Bottom,
+		})
+	}
+	return result, nil
+}
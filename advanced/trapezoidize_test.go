@@ -0,0 +1,63 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trapezoidArea(trapezoids []TrapezoidResult) float64 {
+	var area float64
+	for _, t := range trapezoids {
+		area += t.Area()
+	}
+	return area
+}
+
+func TestTrapezoidize(t *testing.T) {
+	for name, list := range map[string]PolygonList{
+		"square with hole": SquareWithHole(),
+		"spiral":           {*LoadFixture("spiral")},
+	} {
+		trapezoids, err := Trapezoidize(list)
+		require.NoError(t, err, name)
+		require.NotEmpty(t, trapezoids, name)
+
+		var area float64
+		for i := range list {
+			area += list[i].SignedArea()
+		}
+		assert.InDelta(t, area, trapezoidArea(trapezoids), Epsilon, name)
+
+		for _, trapezoid := range trapezoids {
+			assert.Greater(t, trapezoid.Top.Y, trapezoid.Bottom.Y, name)
+			assert.Equal(t, trapezoid.Top.Y, trapezoid.LeftTop.Y, name)
+			assert.Equal(t, trapezoid.Bottom.Y, trapezoid.RightBottom.Y, name)
+			assert.LessOrEqual(t, trapezoid.LeftTop.X, trapezoid.RightTop.X+Epsilon, name)
+			assert.LessOrEqual(t, trapezoid.LeftBottom.X, trapezoid.RightBottom.X+Epsilon, name)
+		}
+	}
+}
+
+func TestTrapezoidize_HorizontalEdges(t *testing.T) {
+	// Every vertex of the square shares its height with another, so the map
+	// has zero-height trapezoids along the top and bottom, which are dropped
+	trapezoids, err := Trapezoidize(PolygonList{squareRing(0, 0, 10)})
+	require.NoError(t, err)
+	require.Len(t, trapezoids, 1)
+	assert.Equal(t, TrapezoidResult{
+		LeftTop: Point{X: 0, Y: 10}, LeftBottom: Point{X: 0, Y: 0},
+		RightTop: Point{X: 10, Y: 10}, RightBottom: Point{X: 10, Y: 0},
+		Top: trapezoids[0].Top, Bottom: trapezoids[0].Bottom,
+	}, trapezoids[0])
+	assert.InDelta(t, 100, trapezoids[0].Area(), 1e-12)
+
+	trapezoids, err = Trapezoidize(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, trapezoids)
+
+	_, err = Trapezoidize(PolygonList{{[]*Point{{X: 0, Y: 0}, {X: math.Inf(1), Y: 0}, {X: 0, Y: 1}}}})
+	assert.Error(t, err)
+}
@@ -1,11 +1,230 @@
 package advanced
 
+import "time"
+
 func (list PolygonList) Triangulate() TriangleList {
-	monotones := ConvertToMonotones(list)
+	return list.TriangulateWithOptions(Options{})
+}
+
+// Triangulate with the given options. See Options for details.
+func (list PolygonList) TriangulateWithOptions(opts Options) TriangleList {
+	return list.triangulate(opts, nil)
+}
+
+// Triangulate using the given scratch memory, which may be nil
+func (list PolygonList) triangulate(opts Options, b *buffers) TriangleList {
 	var result TriangleList
-	for _, monotone := range monotones {
-		triangles := TriangulateMonotone(&monotone)
+	list.triangulateEach(opts, b, func(triangles TriangleList) {
 		result = append(result, triangles...)
+	})
+	if opts.Delaunay {
+		var flips int
+		var converged bool
+		result, flips, converged = improveQuality(result, list)
+		if opts.Diagnostics != nil {
+			opts.Diagnostics.DelaunayFlips = flips
+		}
+		if !converged {
+			opts.Diagnostics.warnf(WarningDelaunayIncomplete, "stopped after %d flips, with edges left to flip", flips)
+		}
+	}
+	sortTriangles(result, opts.SortOutput)
+	if opts.HashStages && opts.Diagnostics != nil {
+		opts.Diagnostics.Hashes.Triangles = hashTriangles(result)
 	}
 	return result
 }
+
+// Triangulate using the given scratch memory, which may be nil, passing the
+// finished triangles of each monotone piece to emit as soon as they're ready.
+// Sorting the output, and hashing it, are left to the caller, since they need
+// every triangle at once.
+func (list PolygonList) triangulateEach(opts Options, b *buffers, emit func(TriangleList)) {
+	// Audit the trapezoid map on the way out of a failure, for bug reports
+	var graph *QueryGraph
+	if opts.Diagnostics != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				opts.Diagnostics.Audit = auditAfterError(graph)
+				panic(r)
+			}
+		}()
+	}
+
+	// Copy the points as given, since those are the ones the caller might touch
+	var integrity *pointIntegrity
+	if opts.CheckPointIntegrity {
+		integrity = recordPoints(list)
+	}
+	clock := opts.clock
+	if clock == nil {
+		clock = time.Now
+	}
+	stageStart := clock()
+	endStage := func(stage Stage) {
+		if opts.Diagnostics != nil {
+			opts.Diagnostics.StageTimes[stage] = clock().Sub(stageStart)
+		}
+		if opts.Progress != nil {
+			opts.Progress(stage)
+		}
+		integrity.verify(stage)
+		stageStart = clock()
+	}
+
+	// Weld first, since near-duplicate vertices are what everything after it
+	// trips over
+	if opts.WeldTolerance > 0 {
+		var err error
+		if list, err = list.Cleaned(opts.WeldTolerance); err != nil {
+			throw(err)
+		}
+	}
+
+	// Screen out near-duplicate rings before anything slow, and while the
+	// distances are still in the input space
+	checkNearDuplicateRings(list)
+
+	// Canonicalize before normalizing, so that duplicate points are found and
+	// reported in the input space
+	var canonical canonicalPoints
+	if opts.CanonicalizeOutputPoints {
+		list, canonical = canonicalizePolygons(list)
+	}
+	// After canonicalizing, since rings touching at equal coordinates only
+	// share a point once they're canonical, and before anything else relies on
+	// the rings being simple
+	if opts.Validate {
+		validatePolygons(list)
+	}
+	list = list.applyFillRule(opts.fillRule())
+
+	var normalized *normalization
+	if opts.AutoNormalize || opts.Tolerance != 0 {
+		list, normalized = normalizePolygons(list, opts.Tolerance)
+	} else {
+		checkCoordinateRange(list)
+		checkShapeSize(list, opts.Diagnostics)
+	}
+	// Turn after normalizing, since turning doesn't change the range
+	list, rotation := rotateForSweep(list, opts.SweepAxis)
+
+	// This only reports ring indexes, so the working copy is fine
+	if opts.CheckNesting && opts.Diagnostics != nil {
+		for _, err := range list.NestingErrors() {
+			opts.Diagnostics.warnf(WarningNesting, "%v", err)
+		}
+	}
+
+	// When normalized, the zero area policy is applied while restoring, so that
+	// anything it reports is in the input space
+	workingOpts := opts
+	if normalized != nil {
+		workingOpts.ZeroAreaTriangles = KeepZeroArea
+	}
+
+	if opts.Backend != RandomizedIncremental {
+		fatalf("unknown backend: %d", opts.Backend)
+	}
+	b.reset(list.vertexCount())
+	hashing := opts.HashStages && opts.Diagnostics != nil
+	if hashing {
+		opts.Diagnostics.Hashes = StageHashes{}
+	}
+	var monotones PolygonList
+	// Carries on from the map's marginal decisions, if there is a map
+	margins := marginTracker{inexact: opts.DisableExactFallback}
+	if list.isLoneMonotone(workingOpts) {
+		// The input is already a monotone piece, so there's nothing for the
+		// trapezoid map to do
+		monotones = list
+		endStage(StageGraphBuilt)
+		endStage(StageMonotonesExtracted)
+	} else {
+		var rebuilds int
+		retrySymmetric(list, opts, func(seed int64) {
+			seedOpts := opts
+			seedOpts.Seed = seed
+			b.reset(list.vertexCount())
+			graph, rebuilds = buildQueryGraph(list, seedOpts, b, hashing, func(started *QueryGraph) {
+				graph = started
+			})
+		})
+		if hashing {
+			opts.Diagnostics.Hashes.SegmentOrder = graph.orderHash.Sum64()
+			opts.Diagnostics.Hashes.TrapezoidMap = hashTrapezoidMap(graph)
+		}
+		if opts.Diagnostics != nil {
+			opts.Diagnostics.QueryDepth = graph.QueryDepth()
+			opts.Diagnostics.GraphRebuilds = rebuilds
+			opts.Diagnostics.Trapezoids = graph.insideTrapezoidCount()
+			graph.warnDeepQueries(opts.WarnDepthFactor, opts.Diagnostics)
+		}
+		if opts.Validate && opts.fillRule() == ByWinding {
+			graph.checkHolePlacement(list)
+		}
+		margins = graph.margins
+		endStage(StageGraphBuilt)
+		// Extraction splits the map's trapezoids, so an audit of what's left
+		// would only report the splitting. Failures from here on get none.
+		built := graph
+		graph = nil
+		monotones = built.convertToMonotones(workingOpts)
+		endStage(StageMonotonesExtracted)
+	}
+	if hashing {
+		opts.Diagnostics.Hashes.Monotones = hashRings(monotones)
+	}
+	// Restore each piece's triangles as it's finished, so that nothing holds
+	// more than one piece's worth unless the caller does
+	reported := make(PointSet)
+	for _, monotone := range monotones {
+		triangles := TriangleList(triangulatePiece(&monotone, workingOpts, b, &margins))
+		if rotation != nil {
+			triangles = rotation.restore(triangles)
+		}
+		if normalized != nil {
+			triangles = normalized.restore(triangles, opts)
+		}
+		if canonical != nil {
+			canonical.applyReported(triangles, reported, opts.Diagnostics)
+		}
+		orientTriangles(triangles, opts.OrientTriangle)
+		emit(triangles)
+	}
+	if opts.Diagnostics != nil {
+		opts.Diagnostics.Monotones = len(monotones)
+		opts.Diagnostics.Confidence = margins.confidence()
+		opts.Diagnostics.ExactEvaluations += margins.exactEvaluations
+	}
+	endStage(StageTriangulated)
+}
+
+// Can the list go straight to triangulateMonotone? It can if it's a single
+// counterclockwise ring which is monotone, and nothing asked for the monotone
+// pieces to be anything other than monotone: mountains are narrower, and
+// merging collinear edges needs the trapezoid map to know which edges are
+// input edges.
+func (list PolygonList) isLoneMonotone(opts Options) bool {
+	return len(list) == 1 &&
+		opts.Decomposition == Monotones &&
+		!opts.MergeCollinearEdges &&
+		IsCCW(&list[0]) &&
+		list[0].IsYMonotone()
+}
+
+func triangulateMonotones(monotones PolygonList, opts Options, b *buffers, margins *marginTracker) TriangleList {
+	var result TriangleList
+	for _, monotone := range monotones {
+		result = append(result, triangulatePiece(&monotone, opts, b, margins)...)
+	}
+	return result
+}
+
+// Triangulate one monotone piece, as a mountain if opts asks for mountains
+func triangulatePiece(monotone *Polygon, opts Options, b *buffers, margins *marginTracker) []*Triangle {
+	if opts.Decomposition == Mountains {
+		return triangulateMountain(monotone, opts, margins)
+	}
+	return triangulateMonotone(monotone, opts, b, margins)
+}
@@ -3,9 +3,25 @@ package advanced
 type Point struct {
 	X float64
 	Y float64
+	// An index set by the caller, such as the point's position in their own
+	// vertex buffer, so that output triangles can be mapped back without
+	// building a map from pointers. It only counts when HasUserIndex is set, so
+	// a point built without one, like every point the library creates, has
+	// none. The library never reads it, and output points which are input
+	// points keep it untouched.
+	UserIndex    int32
+	HasUserIndex bool
 }
 
-type Vector Point
+// Create a point which is not from the input, so has no UserIndex
+func fabricatedPoint(x, y float64) *Point {
+	return &Point{X: x, Y: y}
+}
+
+type Vector struct {
+	X float64
+	Y float64
+}
 
 // Note that all points involved with the triangulation are pointers. This means
 // they can be used as keys. We should never modify a point value from the
@@ -16,6 +32,14 @@ type Segment struct {
 	End   *Point
 }
 
+// The position of a segment in the input: the index of its polygon, and of the
+// edge within the polygon, where edge k runs from vertex k to vertex k+1. The
+// polygon is -1 when it isn't known, such as for polygons added to a query
+// graph one at a time.
+type segmentSource struct {
+	polygon, edge int
+}
+
 type Triangle struct {
 	A, B, C *Point
 }
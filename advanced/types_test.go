@@ -0,0 +1,120 @@
+package advanced
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Number every point in the list in order, returning the points by index
+func numberPoints(list PolygonList) []*Point {
+	var points []*Point
+	for _, poly := range list {
+		for _, p := range poly.Points {
+			p.UserIndex, p.HasUserIndex = int32(len(points)), true
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+func TestUserIndex_RoundTrip(t *testing.T) {
+	optionSets := map[string]Options{
+		"default":               {},
+		"normalized":            {AutoNormalize: true},
+		"x axis":                {SweepAxis: XAxis},
+		"canonical":             {CanonicalizeOutputPoints: true},
+		"mountains":             {Decomposition: Mountains},
+		"merged":                {MergeCollinearEdges: true},
+		"sorted":                {SortOutput: Spatial},
+		"integrity":             {CheckPointIntegrity: true},
+		"normalized, auto axis": {AutoNormalize: true, SweepAxis: AutoAxis},
+	}
+	for name, load := range allFixtures() {
+		for optionsName, opts := range optionSets {
+			t.Run(name+"/"+optionsName, func(t *testing.T) {
+				list := load()
+				points := numberPoints(list)
+				var triangles TriangleList
+				require.NotPanics(t, func() {
+					triangles = list.TriangulateWithOptions(opts)
+				})
+				require.NotEmpty(t, triangles)
+				for _, tri := range triangles {
+					for _, p := range []*Point{tri.A, tri.B, tri.C} {
+						require.True(t, p.HasUserIndex, "%v has no index", p)
+						require.True(t, p.UserIndex >= 0 && int(p.UserIndex) < len(points), "%v has index %d", p, p.UserIndex)
+						assert.Same(t, points[p.UserIndex], p)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Canonicalizing only looks at coordinates, so points in different rings with
+// the same coordinates and different indexes still come out as one point: the
+// first one, with its index
+func TestUserIndex_Canonical(t *testing.T) {
+	outer := Polygon{[]*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}}
+	hole := Polygon{[]*Point{{X: 0, Y: 0}, {X: 2, Y: 3}, {X: 3, Y: 2}}}
+	list := PolygonList{outer, hole}
+	numberPoints(list)
+
+	triangles := list.TriangulateWithOptions(Options{CanonicalizeOutputPoints: true})
+	for _, tri := range triangles {
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			assert.NotSame(t, hole.Points[0], p)
+		}
+	}
+	assert.Equal(t, int32(0), outer.Points[0].UserIndex)
+
+	// A duplicate within a ring is still a duplicate, whatever its index
+	dup := PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 0, UserIndex: 7, HasUserIndex: true}}}}
+	var dupErr *DuplicatePointError
+	assert.ErrorAs(t, triangulateRecovering(dup, Options{CanonicalizeOutputPoints: true}), &dupErr)
+}
+
+func TestUserIndex_Fabricated(t *testing.T) {
+	for _, p := range (Rect{MinX: 1, MinY: 1, MaxX: 2, MaxY: 2}).Hole().Points {
+		assert.False(t, p.HasUserIndex)
+	}
+
+	// Path vertices given by the caller keep their indexes, and the points
+	// along the arcs are new
+	path := stadium()
+	callerPoints := PointSet{}
+	for i, element := range path.Elements {
+		if !element.IsArc() {
+			element.To.UserIndex, element.To.HasUserIndex = int32(i), true
+			callerPoints.Add(element.To)
+		}
+	}
+	poly, _, err := path.Flatten(1e-3)
+	require.NoError(t, err)
+	for _, p := range poly.Points {
+		assert.Equal(t, callerPoints.Contains(p), p.HasUserIndex, "%v", p)
+	}
+
+	label, err := PolygonList{squareRing(0, 0, 2)}.LabelPoint(1e-3)
+	require.NoError(t, err)
+	assert.False(t, label.HasUserIndex)
+}
+
+// Points made by restoring a normalized triangulation are new, but that only
+// happens when the triangulation itself makes points, which it never should.
+// Check that they come out without an index anyway.
+func TestUserIndex_Restored(t *testing.T) {
+	_, n := normalizePolygons(PolygonList{{[]*Point{{X: 0, Y: 0, UserIndex: 3, HasUserIndex: true}, {X: 1e12, Y: 0}, {X: 0, Y: 1e12}}}}, 0)
+	require.NotNil(t, n)
+	for working, original := range n.originals {
+		assert.Equal(t, original.UserIndex, working.UserIndex)
+		assert.Equal(t, original.HasUserIndex, working.HasUserIndex)
+	}
+	assert.False(t, n.original(&Point{X: 1, Y: 1}).HasUserIndex)
+
+	_, r := rotateForSweep(PolygonList{squareRing(0, 0, 1)}, XAxis)
+	assert.False(t, r.original(&Point{X: 1, Y: math.Pi}).HasUserIndex)
+}
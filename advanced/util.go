@@ -10,30 +10,86 @@ const Epsilon = 1e-7
 // To compensate for imprecision in floats, equality is tolerance based. If we
 // don't account for this, we'll end up shaving off absurdly thin triangles on nearly
 // horizontal segments.
+//
+// These comparisons fall back to exact arithmetic when the difference is too
+// close to Epsilon to trust. See Options.DisableExactFallback.
 func Equal(a, b float64) bool {
-	return math.Abs(a-b) < Epsilon
+	return equal(a, b, nil)
 }
 
 func GreaterThan(a, b float64) bool {
-	return a-b > Epsilon
+	return greaterThan(a, b, nil)
 }
 
 func LessThan(a, b float64) bool {
-	return b-a > Epsilon
+	return greaterThan(b, a, nil)
+}
+
+// The comparisons, falling back to exact arithmetic as margins allows, and
+// counting it there. margins may be nil.
+func equal(a, b float64, margins *marginTracker) bool {
+	diff := math.Abs(a - b)
+	if margins.isMarginal(diff-Epsilon, unitRoundoff*diff) {
+		return margins.exactDecision(exactEqual, a, b)
+	}
+	return diff < Epsilon
+}
+
+func greaterThan(a, b float64, margins *marginTracker) bool {
+	diff := a - b
+	if margins.isMarginal(diff-Epsilon, unitRoundoff*math.Abs(diff)) {
+		return margins.exactDecision(exactGreater, a, b)
+	}
+	return diff > Epsilon
 }
 
 // A common convention in our geometry is that if two points have the same Y
 // value, the one with the smallex X value is "lower". This simulates a slightly
 // rotated coordinate system, allowing us to assume Y values are never equal.
+// Y values within Epsilon of each other count as the same. This is strict, so
+// a point is never below itself.
 func (p *Point) Below(otherPoint *Point) bool {
-	if Equal(p.Y, otherPoint.Y) {
+	return p.below(otherPoint, nil)
+}
+
+// Below, noting how close the decision was in margins, which may be nil
+func (p *Point) below(otherPoint *Point, margins *marginTracker) bool {
+	// A point compared with itself decides nothing
+	if p != otherPoint {
+		margins.observe(p.Y-otherPoint.Y, p, otherPoint, nil)
+	}
+	if equal(p.Y, otherPoint.Y, margins) {
 		return p.X < otherPoint.X
 	}
 	return p.Y < otherPoint.Y
 }
 
+// The mirror of Below, so this is strict too, and a point is never above
+// itself
 func (p *Point) Above(otherPoint *Point) bool {
-	return !p.Below(otherPoint)
+	return otherPoint.Below(p)
+}
+
+// Compare points by the lexicographic convention, returning -1 if a is below b,
+// 1 if a is above b, and 0 if they are the same point, or have exactly the same
+// coordinates. This agrees with Below and Above whenever either of them holds.
+// Points which neither of them orders, because their Xs are equal and their Ys
+// are within Epsilon, are ordered by their exact Ys, so that this is a total
+// order, and can be used to sort points.
+func CompareLex(a, b *Point) int {
+	switch {
+	case a == b:
+		return 0
+	case a.Below(b):
+		return -1
+	case b.Below(a):
+		return 1
+	case a.Y < b.Y:
+		return -1
+	case a.Y > b.Y:
+		return 1
+	}
+	return 0
 }
 
 // Create a directional point pointing at another point
@@ -102,10 +158,25 @@ type HasSignedArea interface {
 	SignedArea() float64
 }
 
+// Go allows the compiler to fuse a multiplication and an addition into a single
+// operation with one rounding instead of two, and whether it does depends on
+// the platform. The results differ in the last bit, which is enough to flip a
+// marginal decision, so the same input could triangulate differently on
+// different machines. An explicit conversion forces rounding, so products
+// which feed into a sum are wrapped in float64() in every predicate, that is,
+// wherever the sign of the result, or a comparison of it, decides anything.
+
 func (t *Triangle) SignedArea() float64 {
-	return ((t.A.X*t.B.Y - t.B.X*t.A.Y) +
-		(t.B.X*t.C.Y - t.C.X*t.B.Y) +
-		(t.C.X*t.A.Y - t.A.X*t.C.Y)) / 2
+	return ((float64(t.A.X*t.B.Y) - float64(t.B.X*t.A.Y)) +
+		(float64(t.B.X*t.C.Y) - float64(t.C.X*t.B.Y)) +
+		(float64(t.C.X*t.A.Y) - float64(t.A.X*t.C.Y))) / 2
+}
+
+// Twice the signed area of the triangle a, b, c, positive when it winds
+// counterclockwise. This is the one orientation predicate, so that every side
+// test rounds the same way.
+func orientation(a, b, c *Point) float64 {
+	return float64((b.X-a.X)*(c.Y-a.Y)) - float64((b.Y-a.Y)*(c.X-a.X))
 }
 
 func (poly *Polygon) SignedArea() float64 {
@@ -113,7 +184,7 @@ func (poly *Polygon) SignedArea() float64 {
 	n := len(poly.Points)
 	for i := 0; i < n; i++ {
 		nextI := (i + 1) % n
-		area += poly.Points[i].X*poly.Points[nextI].Y - poly.Points[nextI].X*poly.Points[i].Y
+		area += float64(poly.Points[i].X*poly.Points[nextI].Y) - float64(poly.Points[nextI].X*poly.Points[i].Y)
 	}
 	return area / 2
 }
@@ -156,44 +227,97 @@ func (p *Point) String() string {
 	return fmt.Sprintf("{%0.2f, %0.2f}", p.X, p.Y)
 }
 
+// Create a segment from start to end
+func NewSegment(start, end *Point) *Segment {
+	return &Segment{Start: start, End: end}
+}
+
 // A segment points down if its start point is above its endpoint
 func (s *Segment) PointsDown() bool {
 	return s.End.Below(s.Start)
 }
 
 // Is the line segment left of p. This assumes that P is vertically between the start and end of the segment
+//
+// Horizontal segments need care. Under the lexicographic rotation, a
+// horizontal segment tilts infinitesimally upward to the right, so a point
+// strictly above its Y is on its left, and a point strictly below is on its
+// right. Only points at the segment's own Y fall back to comparing X values.
+// This keeps the lexicographic fiction from flipping the answer for points
+// which are a finite distance away from the segment.
 func (s *Segment) IsLeftOf(p *Point) bool {
+	return s.isLeftOf(p, nil)
+}
+
+// IsLeftOf, noting how close the decision was in margins, which may be nil
+func (s *Segment) isLeftOf(p *Point, margins *marginTracker) bool {
 	if s == nil {
 		return true
 	}
 	// Handle horizontal case
-	if Equal(s.Start.Y, s.End.Y) {
-		return LessThan(s.Bottom().X, p.X)
+	margins.observe(s.Start.Y-s.End.Y, s.Start, s.End, nil)
+	if equal(s.Start.Y, s.End.Y, margins) {
+		margins.observe(s.Start.Y-p.Y, s.Start, s.End, p)
+		if !equal(s.Start.Y, p.Y, margins) {
+			return p.Y < s.Start.Y
+		}
+		margins.observe(p.X-s.Bottom().X, s.Start, s.End, p)
+		return greaterThan(p.X, s.Bottom().X, margins)
 	}
 
 	if s.Start == p || s.End == p {
 		return false
 	}
 
+	if s.IsVertical() {
+		margins.observe(p.X-s.Start.X, s.Start, s.End, p)
+		return greaterThan(p.X, s.Start.X, margins)
+	}
+
 	x := s.SolveForX(p.Y)
-	return LessThan(x, p.X)
+	margins.observe(p.X-x, s.Start, s.End, p)
+	if margins.isMarginal(p.X-x-Epsilon, s.solveForXErrorBound(p, x)) {
+		return margins.exactDecision(exactLeftOf, s.Start.X, s.Start.Y, s.End.X, s.End.Y, p.X, p.Y)
+	}
+	return greaterThan(p.X, x, margins)
 }
 
+// Mirror of IsLeftOf. See that method for how horizontal segments are handled.
 func (s *Segment) IsRightOf(p *Point) bool {
+	return s.isRightOf(p, nil)
+}
+
+// IsRightOf, noting how close the decision was in margins, which may be nil
+func (s *Segment) isRightOf(p *Point, margins *marginTracker) bool {
 	if s == nil {
 		return true
 	}
 	// Handle horizontal case
-	if Equal(s.Start.Y, s.End.Y) {
-		return GreaterThan(s.Top().X, p.X)
+	margins.observe(s.Start.Y-s.End.Y, s.Start, s.End, nil)
+	if equal(s.Start.Y, s.End.Y, margins) {
+		margins.observe(s.Start.Y-p.Y, s.Start, s.End, p)
+		if !equal(s.Start.Y, p.Y, margins) {
+			return p.Y > s.Start.Y
+		}
+		margins.observe(s.Top().X-p.X, s.Start, s.End, p)
+		return greaterThan(s.Top().X, p.X, margins)
 	}
 
 	if s.Start == p || s.End == p {
 		return false
 	}
 
+	if s.IsVertical() {
+		margins.observe(s.Start.X-p.X, s.Start, s.End, p)
+		return greaterThan(s.Start.X, p.X, margins)
+	}
+
 	x := s.SolveForX(p.Y)
-	return GreaterThan(x, p.X)
+	margins.observe(x-p.X, s.Start, s.End, p)
+	if margins.isMarginal(x-p.X-Epsilon, s.solveForXErrorBound(p, x)) {
+		return margins.exactDecision(exactRightOf, s.Start.X, s.Start.Y, s.End.X, s.End.Y, p.X, p.Y)
+	}
+	return greaterThan(x, p.X, margins)
 }
 
 // Determine which direction the segment points from top to bottom
@@ -268,10 +392,59 @@ func (s *Segment) SolveForX(y float64) float64 {
 	}
 
 	m := (s.End.Y - s.Start.Y) / (s.End.X - s.Start.X)
-	b := s.Start.Y - m*s.Start.X
+	b := s.Start.Y - float64(m*s.Start.X)
 	return (y - b) / m
 }
 
+// Check whether the segments cross, touch, or come within Epsilon of each
+// other, the way Validate checks a pair of edges. Segments which share an end
+// point, as neighboring edges of a ring do, only intersect if they overlap
+// along a line, or the far end of one comes back within Epsilon of the other.
+//
+// Distances are used rather than comparing Y values first, so a vertex within
+// Epsilon of a horizontal segment touches it, which is how the trapezoid map
+// treats a point at the segment's height and within its ends.
+func (s *Segment) Intersects(other *Segment) bool {
+	return segmentsIntersect(s, other) || segmentsWithin(s, other, Epsilon)
+}
+
+// Find a point where the segments intersect, in the sense of Intersects, which
+// is a new point with no UserIndex. Where they touch, or overlap along a line, it's the lowest
+// end of either segment which lies on the other, other than an end they share.
+// Otherwise, they cross, and it's where their lines meet.
+func (s *Segment) Intersection(other *Segment) (*Point, bool) {
+	if !s.Intersects(other) {
+		return nil, false
+	}
+	var touching *Point
+	for _, pair := range [2][2]*Segment{{s, other}, {other, s}} {
+		segment, end := pair[0], pair[1]
+		for _, p := range [2]*Point{end.Start, end.End} {
+			if p != segment.Start && p != segment.End && distanceToSegment(p, segment) < Epsilon &&
+				(touching == nil || p.Below(touching)) {
+				touching = p
+			}
+		}
+	}
+	if touching != nil {
+		return fabricatedPoint(touching.X, touching.Y), true
+	}
+
+	dx, dy := s.End.X-s.Start.X, s.End.Y-s.Start.Y
+	otherDX, otherDY := other.End.X-other.Start.X, other.End.Y-other.Start.Y
+	numerator := float64((other.Start.X-s.Start.X)*otherDY) - float64((other.Start.Y-s.Start.Y)*otherDX)
+	t := numerator / (float64(dx*otherDY) - float64(dy*otherDX))
+	return fabricatedPoint(s.Start.X+float64(t*dx), s.Start.Y+float64(t*dy)), true
+}
+
+// Check whether the polygon is simple: it has at least three points, uses no
+// point twice, and no two of its edges intersect (see Segment.Intersects),
+// other than neighbors meeting at their shared point. This is the check
+// Validate makes of a single ring.
+func (poly *Polygon) IsSimple() bool {
+	return PolygonList{*poly}.Validate() == nil
+}
+
 func (v Vector) Normalize() Vector {
 	return Vector{
 		X: v.X / v.Length(),
@@ -280,10 +453,11 @@ func (v Vector) Normalize() Vector {
 }
 
 func (v Vector) Length() float64 {
-	return math.Sqrt(v.X*v.X + v.Y*v.Y)
+	return math.Sqrt(float64(v.X*v.X) + float64(v.Y*v.Y))
 }
 
-// Helper mostly used in tests. Converts the triangles into generic polygons.
+// Convert the triangles into generic polygons, one for each triangle, sharing
+// the triangles' points.
 func (triangles TriangleList) ToPolygonList() PolygonList {
 	polyList := make(PolygonList, len(triangles))
 	for i, tri := range triangles {
@@ -291,3 +465,53 @@ func (triangles TriangleList) ToPolygonList() PolygonList {
 	}
 	return polyList
 }
+
+// The total area of the triangles, whichever way each one winds
+func (triangles TriangleList) Area() float64 {
+	area := 0.0
+	for _, tri := range triangles {
+		area += Area(tri)
+	}
+	return area
+}
+
+// The centroid of the triangles, with each weighted by its area. If they have
+// no area at all, this is the mean of their vertices instead, and if there are
+// no triangles, it's the zero point.
+func (triangles TriangleList) Centroid() Point {
+	var x, y, area, meanX, meanY float64
+	for _, tri := range triangles {
+		triangleArea := Area(tri)
+		centerX := (tri.A.X + tri.B.X + tri.C.X) / 3
+		centerY := (tri.A.Y + tri.B.Y + tri.C.Y) / 3
+		x += float64(triangleArea * centerX)
+		y += float64(triangleArea * centerY)
+		area += triangleArea
+		meanX += centerX
+		meanY += centerY
+	}
+	if area > 0 {
+		return Point{X: x / area, Y: y / area}
+	}
+	if len(triangles) > 0 {
+		return Point{X: meanX / float64(len(triangles)), Y: meanY / float64(len(triangles))}
+	}
+	return Point{}
+}
+
+// The smallest axis-aligned box containing every vertex of the triangles, or
+// all zeros if there are no triangles
+func (triangles TriangleList) BoundingBox() (minX, minY, maxX, maxY float64) {
+	if len(triangles) == 0 {
+		return 0, 0, 0, 0
+	}
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, tri := range triangles {
+		for _, p := range [3]*Point{tri.A, tri.B, tri.C} {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	return minX, minY, maxX, maxY
+}
@@ -3,28 +3,30 @@ package advanced
 import (
 	"fmt"
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPointStack(t *testing.T) {
 	var ps PointStack
 	assert.True(t, ps.Empty())
-	ps.Push(&Point{1, 2})
+	ps.Push(&Point{X: 1, Y: 2})
 	assert.False(t, ps.Empty())
-	assert.Equal(t, &Point{1, 2}, ps.Peek())
+	assert.Equal(t, &Point{X: 1, Y: 2}, ps.Peek())
 	assert.False(t, ps.Empty())
-	assert.Equal(t, &Point{1, 2}, ps.Pop())
+	assert.Equal(t, &Point{X: 1, Y: 2}, ps.Pop())
 	assert.True(t, ps.Empty())
-	ps.Push(&Point{1, 2})
-	ps.Push(&Point{3, 4})
+	ps.Push(&Point{X: 1, Y: 2})
+	ps.Push(&Point{X: 3, Y: 4})
 	assert.False(t, ps.Empty())
-	assert.Equal(t, &Point{3, 4}, ps.Peek())
-	assert.Equal(t, &Point{3, 4}, ps.Pop())
+	assert.Equal(t, &Point{X: 3, Y: 4}, ps.Peek())
+	assert.Equal(t, &Point{X: 3, Y: 4}, ps.Pop())
 	assert.False(t, ps.Empty())
-	assert.Equal(t, &Point{1, 2}, ps.Peek())
-	assert.Equal(t, &Point{1, 2}, ps.Pop())
+	assert.Equal(t, &Point{X: 1, Y: 2}, ps.Peek())
+	assert.Equal(t, &Point{X: 1, Y: 2}, ps.Pop())
 	assert.True(t, ps.Empty())
 }
 
@@ -44,9 +46,9 @@ func TestTriangleSignedArea(t *testing.T) {
 		cwI := cwI // import into inner scope
 		t.Run(fmt.Sprintf("With %s triangles", []string{"CCW", "CW"}[cwI]), func(t *testing.T) {
 			tri := new(Triangle)
-			tri.A = &Point{0, -1}
-			tri.B = &Point{1, 0}
-			tri.C = &Point{0, 1}
+			tri.A = &Point{X: 0, Y: -1}
+			tri.B = &Point{X: 1, Y: 0}
+			tri.C = &Point{X: 0, Y: 1}
 			// Clockwise triangles will have negative area, so sign is -1 for CW = 1
 			sign := 1 - 2*float64(cwI)
 			assertArea := func(expected float64) {
@@ -98,12 +100,12 @@ func TestPolygonSignedArea(t *testing.T) {
 			// Make a skewed polygon - an hourglass - which it is hopefully easy to see has area 64
 			poly := Polygon{
 				Points: []*Point{
-					{2, 0},
-					{6, 4},
-					{-6, 4},
-					{-2, 0},
-					{-6, -4},
-					{6, -4},
+					{X: 2, Y: 0},
+					{X: 6, Y: 4},
+					{X: -6, Y: 4},
+					{X: -2, Y: 0},
+					{X: -6, Y: -4},
+					{X: 6, Y: -4},
 				},
 			}
 			// Skew the polygon by moving the right side points down. The principle here
@@ -159,18 +161,89 @@ func TestPolygonSignedArea(t *testing.T) {
 
 // Test the lexicographically adjusted "below" method
 func TestBelow(t *testing.T) {
-	p := &Point{1, 1}
+	p := &Point{X: 1, Y: 1}
 	// Below by normal standards
-	assert.True(t, p.Below(&Point{1, 2}))
+	assert.True(t, p.Below(&Point{X: 1, Y: 2}))
 	// Above by normal standards
-	assert.False(t, p.Below(&Point{1, 0}))
+	assert.False(t, p.Below(&Point{X: 1, Y: 0}))
 
 	// Below by lexicographic correction (other point is to the right, so it is
 	// "above" p because of the tie-break)
-	assert.True(t, p.Below(&Point{2, 1}))
+	assert.True(t, p.Below(&Point{X: 2, Y: 1}))
 	// Above by lexicographic correction (other point is to the left, so it is
 	// "below" p because of the tie-break)
-	assert.False(t, p.Below(&Point{0, 1}))
+	assert.False(t, p.Below(&Point{X: 0, Y: 1}))
+}
+
+func TestAbove_Strict(t *testing.T) {
+	p := &Point{X: 1, Y: 1}
+	assert.False(t, p.Above(p))
+	assert.False(t, p.Above(&Point{X: 1, Y: 1}))
+	assert.True(t, p.Above(&Point{X: 0, Y: 1}))
+	assert.False(t, p.Above(&Point{X: 2, Y: 1}))
+	assert.Equal(t, 0, CompareLex(p, p))
+	assert.Equal(t, 0, CompareLex(p, &Point{X: 1, Y: 1}))
+
+	// Ys within Epsilon with equal Xs are neither below nor above each other,
+	// but CompareLex still orders them
+	q := &Point{X: 1, Y: 1 + Epsilon/2}
+	assert.False(t, p.Below(q))
+	assert.False(t, q.Below(p))
+	assert.Equal(t, -1, CompareLex(p, q))
+	assert.Equal(t, 1, CompareLex(q, p))
+}
+
+// CompareLex must be a total order, or sorting with it gives garbage. Points
+// on a small grid tie often, in X, in Y, and in both.
+func TestCompareLex_Properties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	points := make([]*Point, 60)
+	for i := range points {
+		if i%3 == 0 {
+			points[i] = &Point{X: r.Float64()*4 - 2, Y: r.Float64()*4 - 2}
+		} else {
+			points[i] = &Point{X: float64(r.Intn(4)), Y: float64(r.Intn(4))}
+		}
+	}
+
+	for _, a := range points {
+		for _, b := range points {
+			ab := CompareLex(a, b)
+			// Antisymmetric
+			require.Equal(t, -ab, CompareLex(b, a), "%v %v", a, b)
+			// Total, with ties only for equal coordinates
+			require.Equal(t, *a == *b, ab == 0, "%v %v", a, b)
+			// Consistent with Below and Above
+			require.Equal(t, ab < 0, a.Below(b), "%v %v", a, b)
+			require.Equal(t, ab > 0, a.Above(b), "%v %v", a, b)
+			for _, c := range points {
+				// Transitive
+				if ab <= 0 && CompareLex(b, c) <= 0 {
+					require.LessOrEqual(t, CompareLex(a, c), 0, "%v %v %v", a, b, c)
+				}
+			}
+		}
+	}
+}
+
+// Horizontal segments are tilted by the lexicographic rotation, but points a
+// finite distance above or below them must still be classified geometrically.
+func TestIsLeftOf_Horizontal(t *testing.T) {
+	segment := NewSegment(&Point{X: 0, Y: 0}, &Point{X: 10, Y: 0})
+
+	// Above the segment is left of it, regardless of X
+	assert.False(t, segment.IsLeftOf(&Point{X: 5, Y: 1}))
+	assert.False(t, segment.IsLeftOf(&Point{X: 20, Y: 1}))
+	assert.True(t, segment.IsRightOf(&Point{X: 20, Y: 1}))
+
+	// Below the segment is right of it, regardless of X
+	assert.True(t, segment.IsLeftOf(&Point{X: 5, Y: -1}))
+	assert.True(t, segment.IsLeftOf(&Point{X: -20, Y: -1}))
+	assert.False(t, segment.IsRightOf(&Point{X: -20, Y: -1}))
+
+	// At the same Y, the lexicographic tie-break applies
+	assert.True(t, segment.IsLeftOf(&Point{X: 20, Y: 0}))
+	assert.True(t, segment.IsRightOf(&Point{X: -20, Y: 0}))
 }
 
 // Helpers
@@ -183,3 +256,164 @@ func rotatePoint(point *Point, angle float64) {
 	point.X = x*cos - y*sin
 	point.Y = x*sin + y*cos
 }
+
+func TestTriangleList_Area(t *testing.T) {
+	for name, fixture := range allFixtures() {
+		list := fixture()
+		expected := 0.0
+		for _, poly := range list {
+			expected += poly.SignedArea()
+		}
+		assert.InDelta(t, expected, list.Triangulate().Area(), Epsilon*math.Max(1, math.Abs(expected)), name)
+	}
+	assert.Zero(t, TriangleList{}.Area())
+}
+
+func TestTriangleList_Centroid(t *testing.T) {
+	// A 10×10 square with a 2×2 hole off center, by subtracting moments
+	list := PolygonList{squareRing(0, 0, 10), squareRing(6, 6, 2).Reverse()}
+	expected := Point{X: (100*5 - 4*7) / 96.0, Y: (100*5 - 4*7) / 96.0}
+	centroid := list.Triangulate().Centroid()
+	assert.InDelta(t, expected.X, centroid.X, Epsilon)
+	assert.InDelta(t, expected.Y, centroid.Y, Epsilon)
+
+	// Clockwise triangles weigh the same as counterclockwise ones
+	a, b, c := &Point{X: 0, Y: 0}, &Point{X: 3, Y: 0}, &Point{X: 0, Y: 3}
+	assert.Equal(t, Point{X: 1, Y: 1}, TriangleList{{a, c, b}}.Centroid())
+
+	assert.Equal(t, Point{}, TriangleList{}.Centroid())
+	// With no area, the vertices are averaged rather than dividing by zero
+	degenerate := TriangleList{{a, b, &Point{X: 6, Y: 0}}}
+	assert.Equal(t, Point{X: 3, Y: 0}, degenerate.Centroid())
+}
+
+func TestTriangleList_BoundingBox(t *testing.T) {
+	minX, minY, maxX, maxY := TriangleList{}.BoundingBox()
+	assert.Equal(t, [4]float64{}, [4]float64{minX, minY, maxX, maxY})
+
+	minX, minY, maxX, maxY = PolygonList{circlePolygon(2, 4)}.Triangulate().BoundingBox()
+	assert.InDeltaSlice(t, []float64{-2, -2, 2, 2}, []float64{minX, minY, maxX, maxY}, Epsilon)
+}
+
+func TestTriangleList_ToPolygonList(t *testing.T) {
+	triangles := SquareWithHole().Triangulate()
+	polygons := triangles.ToPolygonList()
+	require.Len(t, polygons, len(triangles))
+	for i, tri := range triangles {
+		assert.Equal(t, []*Point{tri.A, tri.B, tri.C}, polygons[i].Points)
+	}
+}
+
+func TestSegment_Intersects(t *testing.T) {
+	shared := &Point{X: 1, Y: 1}
+	for name, test := range map[string]struct {
+		a, b         *Segment
+		intersects   bool
+		intersection Point
+	}{
+		"crossing": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 2, Y: 2}},
+			&Segment{Start: &Point{X: 0, Y: 2}, End: &Point{X: 2, Y: 0}},
+			true, Point{X: 1, Y: 1},
+		},
+		"touching at an end": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 2, Y: 2}},
+			&Segment{Start: &Point{X: 1, Y: 1}, End: &Point{X: 2, Y: 0}},
+			true, Point{X: 1, Y: 1},
+		},
+		"ends at equal coordinates": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 1, Y: 1}},
+			&Segment{Start: &Point{X: 1, Y: 1}, End: &Point{X: 2, Y: 0}},
+			true, Point{X: 1, Y: 1},
+		},
+		"sharing an end": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: shared},
+			&Segment{Start: shared, End: &Point{X: 2, Y: 0}},
+			false, Point{},
+		},
+		"folding back over a shared end": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: shared},
+			&Segment{Start: shared, End: &Point{X: 0.5, Y: 0.5}},
+			true, Point{X: 0.5, Y: 0.5},
+		},
+		"collinear overlap": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 2, Y: 0}},
+			&Segment{Start: &Point{X: 3, Y: 0}, End: &Point{X: 1, Y: 0}},
+			true, Point{X: 1, Y: 0},
+		},
+		"collinear apart": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 1, Y: 0}},
+			&Segment{Start: &Point{X: 2, Y: 0}, End: &Point{X: 3, Y: 0}},
+			false, Point{},
+		},
+		"parallel": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 2, Y: 1}},
+			&Segment{Start: &Point{X: 0, Y: 1}, End: &Point{X: 2, Y: 2}},
+			false, Point{},
+		},
+		"apart": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 1, Y: 1}},
+			&Segment{Start: &Point{X: 3, Y: 0}, End: &Point{X: 2, Y: 5}},
+			false, Point{},
+		},
+		"vertex on a horizontal edge": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 4, Y: 0}},
+			&Segment{Start: &Point{X: 2, Y: Epsilon / 2, UserIndex: 7, HasUserIndex: true}, End: &Point{X: 3, Y: 1}},
+			true, Point{X: 2, Y: Epsilon / 2},
+		},
+		"vertex past the end of a horizontal edge": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 4, Y: 0}},
+			&Segment{Start: &Point{X: 4 + 2*Epsilon, Y: 0}, End: &Point{X: 5, Y: 1}},
+			false, Point{},
+		},
+		"vertex within Epsilon of a horizontal edge's end": {
+			&Segment{Start: &Point{X: 0, Y: 0}, End: &Point{X: 4, Y: 0}},
+			&Segment{Start: &Point{X: 4 + Epsilon/2, Y: 0}, End: &Point{X: 5, Y: 1}},
+			true, Point{X: 4, Y: 0},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.intersects, test.a.Intersects(test.b))
+			assert.Equal(t, test.intersects, test.b.Intersects(test.a))
+			for _, pair := range [2][2]*Segment{{test.a, test.b}, {test.b, test.a}} {
+				p, ok := pair[0].Intersection(pair[1])
+				assert.Equal(t, test.intersects, ok)
+				if ok {
+					assert.InDelta(t, test.intersection.X, p.X, 1e-12)
+					assert.InDelta(t, test.intersection.Y, p.Y, 1e-12)
+					assert.False(t, p.HasUserIndex)
+				} else {
+					assert.Nil(t, p)
+				}
+			}
+		})
+	}
+}
+
+func TestPolygon_IsSimple(t *testing.T) {
+	for name, test := range map[string]struct {
+		points []*Point
+		simple bool
+	}{
+		"square":   {squareRing(0, 0, 1).Points, true},
+		"triangle": {[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}}, true},
+		"bowtie":   {[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 1}}, false},
+		"vertex touching an edge": {
+			[]*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 2, Y: 0}, {X: 0, Y: 4}},
+			false,
+		},
+		"spike folding back": {
+			[]*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 2, Y: 4}, {X: 2, Y: 6}, {X: 2, Y: 5}, {X: 0, Y: 4}},
+			false,
+		},
+		"two points": {[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}}, false},
+	} {
+		assert.Equal(t, test.simple, (&Polygon{test.points}).IsSimple(), name)
+	}
+
+	for name, load := range allFixtures() {
+		for i := range load() {
+			assert.True(t, load()[i].IsSimple(), "%s ring %d", name, i)
+		}
+	}
+}
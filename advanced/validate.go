@@ -0,0 +1,73 @@
+package advanced
+
+// Check the list for problems which would otherwise surface in the middle of
+// triangulation, if at all, returning an error which names the polygon and
+// vertexes or edges at fault:
+//
+//   - a TooFewPointsError for a polygon with fewer than 3 points
+//   - a DuplicatePointError for a point used twice in one polygon, or
+//     neighboring points within Epsilon of each other
+//   - a CrossingEdgesError for two edges which cross, touch, or come within
+//     Epsilon of each other, whether in one polygon or two, other than
+//     neighbors meeting at their shared point, and edges which two rings share,
+//     running opposite ways between the same coordinates
+//
+// Windings aren't checked, since any winding is valid for some fill rule. This
+// is what Options.Validate checks before triangulating.
+func (list PolygonList) Validate() (err error) {
+	defer func() {
+		err = HandleTriangulatePanicRecover(recover())
+	}()
+	validatePolygons(list)
+	return nil
+}
+
+// Throw the error Validate returns, if any
+func validatePolygons(list PolygonList) {
+	rings := make([][]*Point, len(list))
+	for i, poly := range list {
+		n := len(poly.Points)
+		if n < 3 {
+			throw(&TooFewPointsError{Polygon: i, Points: n})
+		}
+		seen := make(map[*Point]int, n)
+		for j, p := range poly.Points {
+			if first, ok := seen[p]; ok {
+				throw(&DuplicatePointError{Polygon: i, First: first, Second: j, Point: *p})
+			}
+			seen[p] = j
+			if next := poly.Points[CircularIndex(j+1, n)]; Equal(p.X, next.X) && Equal(p.Y, next.Y) {
+				first, second := j, CircularIndex(j+1, n)
+				if second < first {
+					first, second = second, first
+				}
+				throw(&DuplicatePointError{Polygon: i, First: first, Second: second, Point: *p})
+			}
+		}
+	}
+	// Rings sharing an edge are checked as they'll be triangulated, with the
+	// same pointers for its ends. See shared_edges.go.
+	working, _ := findSharedEdges(list)
+	for i, poly := range working {
+		rings[i] = poly.Points
+	}
+	// An edge two rings share, running opposite ways, overlaps itself, which is
+	// allowed.
+	sharedEdge := func(a, b ringEdge) bool {
+		aRing, bRing := rings[a.ring], rings[b.ring]
+		return a.ring != b.ring &&
+			aRing[a.edge] == bRing[CircularIndex(b.edge+1, len(bRing))] &&
+			aRing[CircularIndex(a.edge+1, len(aRing))] == bRing[b.edge]
+	}
+	if crossings := sweepCrossingsWithin(rings, true, Epsilon, sharedEdge); len(crossings) > 0 {
+		// Report in input order, which the sweep doesn't keep
+		first, second := crossings[0][0], crossings[0][1]
+		if second.ring < first.ring || (second.ring == first.ring && second.edge < first.edge) {
+			first, second = second, first
+		}
+		throw(&CrossingEdgesError{
+			FirstPolygon: first.ring, FirstEdge: first.edge,
+			SecondPolygon: second.ring, SecondEdge: second.edge,
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package advanced
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_Fixtures(t *testing.T) {
+	for name, load := range allFixtures() {
+		t.Run(name, func(t *testing.T) {
+			list := load()
+			triangles := list.TriangulateWithOptions(Options{Validate: true})
+			assert.Equal(t, triangleCoordinates(list.Triangulate()), triangleCoordinates(triangles))
+		})
+	}
+}
+
+func TestValidate_Crossing(t *testing.T) {
+	for name, test := range map[string]struct {
+		list     PolygonList
+		expected CrossingEdgesError
+	}{
+		"bowtie": {
+			PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 1}}}},
+			CrossingEdgesError{FirstPolygon: 0, FirstEdge: 0, SecondPolygon: 0, SecondEdge: 2},
+		},
+		"overlapping squares": {
+			// They cross twice, and the top of the first crossing the left of the
+			// second is found first
+			PolygonList{squareRing(0, 0, 10), squareRing(20, 20, 1), squareRing(5, 5, 10)},
+			CrossingEdgesError{FirstPolygon: 0, FirstEdge: 2, SecondPolygon: 2, SecondEdge: 3},
+		},
+		"hole touching its solid": {
+			PolygonList{squareRing(0, 0, 10), {[]*Point{{X: 10, Y: 5}, {X: 5, Y: 3}, {X: 5, Y: 7}}}},
+			CrossingEdgesError{FirstPolygon: 0, FirstEdge: 1, SecondPolygon: 1, SecondEdge: 0},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := triangulateRecovering(test.list, Options{Validate: true})
+			var crossingErr *CrossingEdgesError
+			require.ErrorAs(t, err, &crossingErr)
+			assert.Equal(t, test.expected, *crossingErr)
+		})
+	}
+}
+
+func TestValidate_TooFewPoints(t *testing.T) {
+	list := PolygonList{squareRing(0, 0, 10), {[]*Point{{X: 1, Y: 1}, {X: 2, Y: 2}}}}
+	err := triangulateRecovering(list, Options{Validate: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "polygon 1 has 2 points")
+}
+
+// Rings touching at a vertex must share the point, unless it's canonicalized
+func TestValidate_TouchingRings(t *testing.T) {
+	list := PolygonList{
+		{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}},
+		{[]*Point{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}}},
+	}
+	var crossingErr *CrossingEdgesError
+	assert.ErrorAs(t, triangulateRecovering(list, Options{Validate: true}), &crossingErr)
+	assert.NoError(t, triangulateRecovering(list, Options{Validate: true, CanonicalizeOutputPoints: true}))
+
+	list[1].Points[0] = list[0].Points[2]
+	assert.NoError(t, triangulateRecovering(list, Options{Validate: true}))
+}
+
+func TestPolygonList_Validate(t *testing.T) {
+	for name, load := range allFixtures() {
+		assert.NoError(t, load().Validate(), name)
+	}
+	assert.NoError(t, adjacentSquares().Validate())
+
+	shared := &Point{X: 5, Y: 5}
+	for name, test := range map[string]struct {
+		list     PolygonList
+		expected error
+	}{
+		"too few points": {
+			PolygonList{squareRing(0, 0, 10), {[]*Point{{X: 1, Y: 1}, {X: 2, Y: 2}}}},
+			&TooFewPointsError{Polygon: 1, Points: 2},
+		},
+		"repeated neighbors": {
+			PolygonList{squareRing(20, 0, 1), {[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: Epsilon / 2}, {X: 0, Y: 10}}}},
+			&DuplicatePointError{Polygon: 1, First: 1, Second: 2, Point: Point{X: 10, Y: 0}},
+		},
+		"repeated across the seam": {
+			PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 10}, {X: Epsilon / 2, Y: 0}}}},
+			&DuplicatePointError{Polygon: 0, First: 0, Second: 3, Point: Point{X: Epsilon / 2, Y: 0}},
+		},
+		"point used twice": {
+			// Two triangles pinched together at one point
+			PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 10, Y: 0}, shared, {X: 10, Y: 10}, {X: 0, Y: 10}, shared}}},
+			&DuplicatePointError{Polygon: 0, First: 2, Second: 5, Point: *shared},
+		},
+		"self intersecting": {
+			PolygonList{{[]*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 1}}}},
+			&CrossingEdgesError{FirstPolygon: 0, FirstEdge: 0, SecondPolygon: 0, SecondEdge: 2},
+		},
+		"crossing polygons": {
+			// The top of the first crosses the left of the second first
+			PolygonList{squareRing(0, 0, 10), squareRing(5, 5, 10)},
+			&CrossingEdgesError{FirstPolygon: 0, FirstEdge: 2, SecondPolygon: 1, SecondEdge: 3},
+		},
+		"within epsilon": {
+			// The hole's corner doesn't quite touch the solid's right side
+			PolygonList{squareRing(0, 0, 10), {[]*Point{{X: 10 - Epsilon/2, Y: 5}, {X: 5, Y: 3}, {X: 5, Y: 7}}}},
+			&CrossingEdgesError{FirstPolygon: 0, FirstEdge: 1, SecondPolygon: 1, SecondEdge: 0},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := test.list.Validate()
+			assert.Equal(t, test.expected, err)
+			// Triangulating with Options.Validate fails the same way
+			assert.Equal(t, test.expected, triangulateRecovering(test.list, Options{Validate: true}))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package advanced
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Welding of nearly coincident vertices, for input such as mesh slices, where
+// rounding leaves consecutive vertices a hair apart. Left alone, those make
+// zero height trapezoids, and eventually degenerate monotone pieces.
+//
+// Each point is merged into the earliest point in the input within the
+// tolerance of it, if there is one. Points are found through a hash of grid
+// cells as wide as the tolerance, so only the cells around each point are
+// searched.
+
+type weldCell [2]float64
+
+type weldGrid struct {
+	tolerance float64
+	cells     map[weldCell][]*Point
+	// The point each input point was merged into, which may be itself
+	merged map[*Point]*Point
+}
+
+func (grid *weldGrid) cell(p *Point) weldCell {
+	return weldCell{math.Floor(p.X / grid.tolerance), math.Floor(p.Y / grid.tolerance)}
+}
+
+// The point p is merged into
+func (grid *weldGrid) weld(p *Point) *Point {
+	if merged, ok := grid.merged[p]; ok {
+		return merged
+	}
+	home := grid.cell(p)
+	var result *Point
+	for dx := -1.0; dx <= 1 && result == nil; dx++ {
+		for dy := -1.0; dy <= 1 && result == nil; dy++ {
+			for _, other := range grid.cells[weldCell{home[0] + dx, home[1] + dy}] {
+				if math.Hypot(p.X-other.X, p.Y-other.Y) < grid.tolerance {
+					result = other
+					break
+				}
+			}
+		}
+	}
+	if result == nil {
+		result = p
+		grid.cells[home] = append(grid.cells[home], p)
+	}
+	grid.merged[p] = result
+	return result
+}
+
+// Merge vertices closer together than the tolerance, for input with nearly
+// coincident vertices. Each vertex is replaced by the earliest vertex in the
+// list within the tolerance of it, so that vertices which are merged become
+// the same *Point, in whichever rings they're in. Then, in each ring, edges
+// which are left with no length are dropped, as are vertices where the ring
+// folds back on itself, doubling back along a line to within the tolerance.
+// Rings left with fewer than three points are removed.
+//
+// The result is a new list, in the same order less the removed rings, which
+// references the input points. The input is not modified. This returns a
+// CollapsedRingError if a ring which isn't inside any other ring is removed,
+// since that loses a whole shape, but holes, and shapes inside holes, are
+// removed silently.
+func (l PolygonList) Cleaned(tolerance float64) (PolygonList, error) {
+	if !(tolerance > 0) || math.IsInf(tolerance, 1) {
+		return nil, errors.Errorf("tolerance must be positive and finite, not %v", tolerance)
+	}
+	grid := &weldGrid{
+		tolerance: tolerance,
+		cells:     make(map[weldCell][]*Point),
+		merged:    make(map[*Point]*Point),
+	}
+	result := make(PolygonList, 0, len(l))
+	for i, poly := range l {
+		points := make([]*Point, len(poly.Points))
+		for j, p := range poly.Points {
+			points[j] = grid.weld(p)
+		}
+		points = dropDegenerateEdges(points, tolerance)
+		if len(points) < 3 {
+			if l.isOutermost(i) {
+				return nil, &CollapsedRingError{Polygon: i, Tolerance: tolerance}
+			}
+			continue
+		}
+		result = append(result, Polygon{points})
+	}
+	return result, nil
+}
+
+// Drop the edges of a ring with no length, which merged points leave behind,
+// and the vertices where it folds back on itself
+func dropDegenerateEdges(points []*Point, tolerance float64) []*Point {
+	result := make([]*Point, 0, len(points))
+	for _, p := range points {
+		result = append(result, p)
+		// Dropping a vertex can leave the one before it degenerate in turn
+		for dropped := true; dropped; {
+			dropped = false
+			n := len(result)
+			if n >= 2 && result[n-1] == result[n-2] {
+				result = result[:n-1]
+				dropped = true
+			} else if n >= 3 && isFoldBack(result[n-3], result[n-2], result[n-1], tolerance) {
+				result[n-2] = result[n-1]
+				result = result[:n-1]
+				dropped = true
+			}
+		}
+	}
+
+	// The same, where the ring wraps around
+	for dropped := true; dropped && len(result) >= 3; {
+		dropped = false
+		n := len(result)
+		if result[n-1] == result[0] || isFoldBack(result[n-2], result[n-1], result[0], tolerance) {
+			result = result[:n-1]
+			dropped = true
+		} else if isFoldBack(result[n-1], result[0], result[1], tolerance) {
+			result = result[1:]
+			dropped = true
+		}
+	}
+	return result
+}
+
+// Does the ring double back at the vertex, running from a to v, and then back
+// along the same line to b?
+func isFoldBack(a, v, b *Point, tolerance float64) bool {
+	if a == b {
+		return true
+	}
+	if float64((v.X-a.X)*(b.X-v.X))+float64((v.Y-a.Y)*(b.Y-v.Y)) >= 0 {
+		return false
+	}
+	// The distance from v to the line through a and b
+	chordX, chordY := b.X-a.X, b.Y-a.Y
+	return math.Abs(float64(chordX*(v.Y-a.Y))-float64(chordY*(v.X-a.X)))/math.Hypot(chordX, chordY) < tolerance
+}
+
+// Is ring i inside no other ring in the list?
+func (l PolygonList) isOutermost(i int) bool {
+	if len(l[i].Points) == 0 {
+		return true
+	}
+	p := l[i].Points[0]
+	for j, poly := range l {
+		if j != i && poly.ContainsPointByEvenOdd(p) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package advanced
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleaned_DuplicatedCorner(t *testing.T) {
+	square := squareRing(0, 0, 10)
+	corner := square.Points[2]
+	duplicate := &Point{X: corner.X + 1e-9, Y: corner.Y - 1e-9}
+	points := append(append(append([]*Point{}, square.Points[:3]...), duplicate), square.Points[3])
+	list := PolygonList{{points}}
+
+	cleaned, err := list.Cleaned(1e-6)
+	require.NoError(t, err)
+	require.Len(t, cleaned, 1)
+	assert.Equal(t, square.Points, cleaned[0].Points)
+	// The input is left alone
+	assert.Len(t, list[0].Points, 5)
+
+	triangles := list.TriangulateWithOptions(Options{WeldTolerance: 1e-6})
+	assert.Len(t, triangles, 2)
+	assert.InDelta(t, 100, totalArea(triangles), 1e-9)
+}
+
+func TestCleaned_JitteredSpiral(t *testing.T) {
+	spiral := LoadFixture("spiral")
+	// Follow each vertex with another a hair away from it, as a mesh slice with
+	// rounding errors would
+	random := rand.New(rand.NewSource(1))
+	var points []*Point
+	for _, p := range spiral.Points {
+		angle := random.Float64() * 2 * math.Pi
+		points = append(points, p, &Point{X: p.X + 1e-9*math.Cos(angle), Y: p.Y + 1e-9*math.Sin(angle)})
+	}
+	list := PolygonList{{points}}
+
+	cleaned, err := list.Cleaned(1e-6)
+	require.NoError(t, err)
+	require.Len(t, cleaned, 1)
+	assert.Equal(t, spiral.Points, cleaned[0].Points)
+
+	expected := PolygonList{*spiral}.Triangulate()
+	triangles := list.TriangulateWithOptions(Options{WeldTolerance: 1e-6})
+	assert.Len(t, triangles, len(expected))
+	assert.InDelta(t, totalArea(expected), totalArea(triangles), 1e-9)
+	for _, triangle := range triangles {
+		for _, p := range []*Point{triangle.A, triangle.B, triangle.C} {
+			assert.Contains(t, spiral.Points, p)
+		}
+	}
+}
+
+func TestCleaned_SharedPoints(t *testing.T) {
+	// Two squares side by side, whose shared edge is a hair apart. Each point
+	// is merged into the first square's, so the edge is shared by pointer.
+	left, right := squareRing(0, 0, 1), squareRing(1+1e-9, 0, 1)
+	cleaned, err := PolygonList{left, right}.Cleaned(1e-6)
+	require.NoError(t, err)
+	require.Len(t, cleaned, 2)
+	assert.Same(t, left.Points[1], cleaned[1].Points[0])
+	assert.Same(t, left.Points[2], cleaned[1].Points[3])
+	assert.Same(t, right.Points[1], cleaned[1].Points[1])
+}
+
+func TestCleaned_FoldBack(t *testing.T) {
+	// A spike out of the top edge, which collapses to a line once its base
+	// points are merged
+	points := []*Point{
+		{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10},
+		{X: 5 + 1e-9, Y: 10}, {X: 5, Y: 15}, {X: 5, Y: 10},
+		{X: 0, Y: 10},
+	}
+	cleaned, err := PolygonList{{points}}.Cleaned(1e-6)
+	require.NoError(t, err)
+	require.Len(t, cleaned, 1)
+	assert.Equal(t, []*Point{points[0], points[1], points[2], points[3], points[6]}, cleaned[0].Points)
+
+	// The same at the seam, where the ring wraps around. The spike's base is
+	// now merged into the point which comes first.
+	rotated := append(append([]*Point{}, points[4:]...), points[:4]...)
+	cleaned, err = PolygonList{{rotated}}.Cleaned(1e-6)
+	require.NoError(t, err)
+	require.Len(t, cleaned, 1)
+	assert.ElementsMatch(t, []*Point{points[0], points[1], points[2], points[5], points[6]}, cleaned[0].Points)
+}
+
+func TestCleaned_CollapsedRings(t *testing.T) {
+	tiny := squareRing(2, 2, 1e-7)
+	// A hole which collapses is dropped
+	cleaned, err := PolygonList{squareRing(0, 0, 10), tiny.Reverse()}.Cleaned(1e-6)
+	require.NoError(t, err)
+	assert.Len(t, cleaned, 1)
+
+	// But an outer ring which collapses is an error, since a whole shape would
+	// be lost
+	_, err = PolygonList{squareRing(0, 0, 10), squareRing(20, 0, 1e-7)}.Cleaned(1e-6)
+	var collapsed *CollapsedRingError
+	require.ErrorAs(t, err, &collapsed)
+	assert.Equal(t, 1, collapsed.Polygon)
+
+	err = triangulateRecovering(PolygonList{squareRing(20, 0, 1e-7)}, Options{WeldTolerance: 1e-6})
+	assert.ErrorAs(t, err, &collapsed)
+
+	for _, tolerance := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		_, err := PolygonList{squareRing(0, 0, 10)}.Cleaned(tolerance)
+		assert.Error(t, err, "%v", tolerance)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build triangulate_capi
+// +build triangulate_capi
+
+package main
+
+/*
+#include <stdlib.h>
+#include "triangulate.h"
+
+// Go through the C ABI, the way a C caller would
+static int64_t call_triangulate_flat(double *coords, int64_t *ring_lengths, int64_t ring_count, uint32_t flags, int64_t *indices, int64_t index_capacity, char *message, int64_t message_capacity) {
+	return triangulate_flat(coords, ring_lengths, ring_count, flags, indices, index_capacity, message, message_capacity);
+}
+*/
+import "C"
+
+import "unsafe"
+
+// Call triangulate_flat from C, with the inputs and outputs in C memory. Tests
+// can't use cgo directly, so this lives here.
+func callFromC(coords []float64, ringLengths []int64, flags uint32, indexCapacity, messageCapacity int) (result int64, indices []int64, message string) {
+	cCoords := (*C.double)(C.malloc(C.size_t(8 * (len(coords) + 1))))
+	defer C.free(unsafe.Pointer(cCoords))
+	for i, coord := range coords {
+		unsafe.Slice(cCoords, len(coords))[i] = C.double(coord)
+	}
+	cLengths := (*C.int64_t)(C.malloc(C.size_t(8 * (len(ringLengths) + 1))))
+	defer C.free(unsafe.Pointer(cLengths))
+	for i, length := range ringLengths {
+		unsafe.Slice(cLengths, len(ringLengths))[i] = C.int64_t(length)
+	}
+	cIndices := (*C.int64_t)(C.malloc(C.size_t(8 * (indexCapacity + 1))))
+	defer C.free(unsafe.Pointer(cIndices))
+	cMessage := (*C.char)(C.malloc(C.size_t(messageCapacity + 1)))
+	defer C.free(unsafe.Pointer(cMessage))
+
+	result = int64(C.call_triangulate_flat(
+		cCoords, cLengths, C.int64_t(len(ringLengths)), C.uint32_t(flags),
+		cIndices, C.int64_t(indexCapacity), cMessage, C.int64_t(messageCapacity),
+	))
+	if result >= 0 {
+		for _, index := range unsafe.Slice(cIndices, 3*result) {
+			indices = append(indices, int64(index))
+		}
+	} else if messageCapacity > 0 {
+		message = C.GoString(cMessage)
+	}
+	return result, indices, message
+}
@@ -0,0 +1,123 @@
+//go:build triangulate_capi
+// +build triangulate_capi
+
+// C interface to the triangulator, for linking into programs in other
+// languages. See triangulate.h for the interface and its memory ownership
+// rules. This only builds with the triangulate_capi tag, so that normal builds
+// don't need cgo.
+package main
+
+/*
+#include "triangulate.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/osuushi/triangulate"
+	"github.com/osuushi/triangulate/advanced"
+	"github.com/pkg/errors"
+)
+
+// Required by c-archive and c-shared builds
+func main() {}
+
+// A failure, with the code to report it under
+type capiError struct {
+	code    C.int64_t
+	message string
+}
+
+//export triangulate_flat
+func triangulate_flat(
+	coords *C.double,
+	ringLengths *C.int64_t,
+	ringCount C.int64_t,
+	flags C.uint32_t,
+	indices *C.int64_t,
+	indexCapacity C.int64_t,
+	message *C.char,
+	messageCapacity C.int64_t,
+) C.int64_t {
+	count, failure := triangulateFlat(coords, ringLengths, ringCount, flags, indices, indexCapacity)
+	if failure != nil {
+		writeMessage(message, messageCapacity, failure.message)
+		return failure.code
+	}
+	return count
+}
+
+func triangulateFlat(
+	coords *C.double,
+	ringLengths *C.int64_t,
+	ringCount C.int64_t,
+	flags C.uint32_t,
+	indices *C.int64_t,
+	indexCapacity C.int64_t,
+) (C.int64_t, *capiError) {
+	if ringCount < 0 || indexCapacity < 0 || (ringCount > 0 && (coords == nil || ringLengths == nil)) {
+		return 0, &capiError{C.TRIANGULATE_ERROR_INVALID_INPUT, "null input, or negative length"}
+	}
+
+	// Copy everything out of C memory up front, so nothing is retained
+	lengths := make([]int, ringCount)
+	vertexCount := 0
+	for i, length := range unsafe.Slice(ringLengths, ringCount) {
+		if length < 3 {
+			return 0, &capiError{C.TRIANGULATE_ERROR_INVALID_INPUT, fmt.Sprintf("ring %d has %d vertices, but rings need at least 3", i, length)}
+		}
+		lengths[i] = int(length)
+		vertexCount += int(length)
+	}
+	flatCoords := make([]float64, 2*vertexCount)
+	for i, coord := range unsafe.Slice(coords, 2*vertexCount) {
+		flatCoords[i] = float64(coord)
+	}
+
+	opts := triangulate.Options{
+		CanonicalizeOutputPoints: flags&C.TRIANGULATE_CANONICALIZE_OUTPUT_POINTS != 0,
+		AutoNormalize:            flags&C.TRIANGULATE_AUTO_NORMALIZE != 0,
+	}
+	result, err := triangulate.TriangulateFlat(flatCoords, lengths, opts)
+	if err != nil {
+		return 0, &capiError{errorCode(err), err.Error()}
+	}
+
+	if len(result) > int(indexCapacity) {
+		return 0, &capiError{
+			C.TRIANGULATE_ERROR_BUFFER_TOO_SMALL,
+			fmt.Sprintf("the result has %d indices, but the buffer only has room for %d", len(result), indexCapacity),
+		}
+	}
+	if len(result) > 0 {
+		output := unsafe.Slice(indices, len(result))
+		for i, index := range result {
+			output[i] = C.int64_t(index)
+		}
+	}
+	return C.int64_t(len(result) / 3), nil
+}
+
+// Map the typed errors to their codes
+func errorCode(err error) C.int64_t {
+	var duplicateErr *advanced.DuplicatePointError
+	switch {
+	case errors.As(err, &duplicateErr):
+		return C.TRIANGULATE_ERROR_DUPLICATE_POINT
+	case errors.Is(err, advanced.ErrCoordinatesOutOfRange):
+		return C.TRIANGULATE_ERROR_COORDINATES_OUT_OF_RANGE
+	}
+	return C.TRIANGULATE_ERROR_FAILED
+}
+
+// Copy a message into a C buffer, truncated to fit along with its NUL
+func writeMessage(buffer *C.char, capacity C.int64_t, message string) {
+	if buffer == nil || capacity <= 0 {
+		return
+	}
+	output := unsafe.Slice((*byte)(unsafe.Pointer(buffer)), capacity)
+	n := copy(output[:capacity-1], message)
+	output[n] = 0
+}
@@ -0,0 +1,65 @@
+//go:build triangulate_capi
+// +build triangulate_capi
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriangulateFlat(t *testing.T) {
+	coords := []float64{
+		0, 0, 4, 0, 4, 4, 0, 4,
+		1, 1, 1, 3, 3, 3, 3, 1,
+	}
+	ringLengths := []int64{4, 4}
+	vertexCount, holeCount := 8, 1
+
+	count, indices, _ := callFromC(coords, ringLengths, 0, 3*(vertexCount+2*len(ringLengths)), 0)
+	// n + 2h - 2 triangles for n vertices and h holes
+	assert.Equal(t, int64(vertexCount+2*holeCount-2), count)
+	assert.Len(t, indices, 3*int(count))
+
+	// The triangles cover the square, less the hole, and every vertex is used
+	var area float64
+	seen := make(map[int64]bool)
+	for i := 0; i < len(indices); i += 3 {
+		a, b, c := indices[i], indices[i+1], indices[i+2]
+		seen[a], seen[b], seen[c] = true, true, true
+		area += ((coords[2*b]-coords[2*a])*(coords[2*c+1]-coords[2*a+1]) -
+			(coords[2*c]-coords[2*a])*(coords[2*b+1]-coords[2*a+1])) / 2
+	}
+	assert.InDelta(t, 16-4, area, 1e-9)
+	assert.Len(t, seen, vertexCount)
+}
+
+func TestTriangulateFlat_Errors(t *testing.T) {
+	square := []float64{0, 0, 4, 0, 4, 4, 0, 4}
+
+	count, _, message := callFromC(square, []int64{4}, 0, 5, 100)
+	assert.Equal(t, int64(-2), count)
+	assert.Contains(t, message, "buffer")
+
+	count, _, message = callFromC(square[:4], []int64{2}, 0, 100, 100)
+	assert.Equal(t, int64(-1), count)
+	assert.Contains(t, message, "ring 0")
+
+	huge := []float64{0, 0, 4e12, 0, 4e12, 4e12, 0, 4e12}
+	count, _, _ = callFromC(huge, []int64{4}, 0, 100, 100)
+	assert.Equal(t, int64(-4), count)
+	count, _, _ = callFromC(huge, []int64{4}, 2, 100, 100)
+	assert.Equal(t, int64(2), count)
+
+	duplicate := []float64{0, 0, 4, 0, 4, 4, 4, 0}
+	count, _, _ = callFromC(duplicate, []int64{4}, 1, 100, 100)
+	assert.Equal(t, int64(-3), count)
+
+	// Messages are truncated to fit, including the NUL
+	count, _, message = callFromC(square, []int64{4}, 0, 5, 10)
+	assert.Equal(t, int64(-2), count)
+	assert.Len(t, message, 9)
+	assert.True(t, strings.HasPrefix("the result has 6 indices", message))
+}
@@ -0,0 +1,66 @@
+/*
+ * C interface to github.com/osuushi/triangulate.
+ *
+ * Build with:
+ *
+ *     go build -tags triangulate_capi -buildmode=c-archive ./capi
+ *
+ * or -buildmode=c-shared, and include this header rather than the one Go
+ * generates.
+ *
+ * Memory: the caller allocates every buffer, and keeps ownership of it. The
+ * library reads the inputs and writes the outputs during the call, and never
+ * holds on to any of them afterwards. The inputs are not modified.
+ */
+#ifndef TRIANGULATE_H
+#define TRIANGULATE_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* Flags, which may be combined with | */
+#define TRIANGULATE_CANONICALIZE_OUTPUT_POINTS 1
+#define TRIANGULATE_AUTO_NORMALIZE 2
+
+/* Error codes. These are always negative, so they can't be confused with a
+ * triangle count. */
+#define TRIANGULATE_ERROR_INVALID_INPUT -1
+#define TRIANGULATE_ERROR_BUFFER_TOO_SMALL -2
+#define TRIANGULATE_ERROR_DUPLICATE_POINT -3
+#define TRIANGULATE_ERROR_COORDINATES_OUT_OF_RANGE -4
+#define TRIANGULATE_ERROR_FAILED -5
+
+/*
+ * Triangulate polygons given as flat arrays.
+ *
+ * coords holds x, y pairs for every vertex, ring after ring, and ring_lengths
+ * holds the number of vertices in each of the ring_count rings. Solid rings run
+ * counterclockwise, and holes clockwise.
+ *
+ * On success, this returns the number of triangles, and writes three vertex
+ * indexes per triangle to indices, where vertex k is at coords[2k] and
+ * coords[2k+1]. index_capacity is the length of indices. A capacity of
+ * 3 * (vertices + 2 * rings) is always enough.
+ *
+ * On failure, this returns one of the negative error codes, and writes a
+ * description of the error to message, truncated to fit message_capacity bytes
+ * including the terminating NUL. message may be NULL if message_capacity is 0.
+ */
+int64_t triangulate_flat(
+	double *coords,
+	int64_t *ring_lengths,
+	int64_t ring_count,
+	uint32_t flags,
+	int64_t *indices,
+	int64_t index_capacity,
+	char *message,
+	int64_t message_capacity);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,158 @@
+// Command triangulate triangulates the polygons on stdin, and prints the
+// triangles as point logs, one per line. Polygons are read with
+// advanced.ReadPolygons, so they can be pasted straight from a log.
+//
+// It's also a triage tool for shapes which seem to triangulate wrongly:
+//
+//	triangulate -verify -stats -quiet < shape.txt
+//
+// checks the triangles against the input by sampling, and prints what the
+// triangulator saw along the way, which is what an issue needs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/osuushi/triangulate"
+	"github.com/osuushi/triangulate/advanced"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Exit codes
+const (
+	exitOK = iota
+	// Triangulation failed, or disagreed with the input too often
+	exitFailed
+	// The flags or the input couldn't be read
+	exitUsage
+)
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("triangulate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	quiet := flags.Bool("quiet", false, "don't print the triangles")
+	seed := flags.Int64("seed", 0, "seed for the order segments are added in")
+	validate := flags.Bool("validate", false, "validate the input first")
+	evenOdd := flags.Bool("even-odd", false, "decide holes by nesting rather than by winding")
+	verify := flags.Bool("verify", false, "compare the triangles with the input by sampling a grid")
+	density := flags.Int("density", 200, "number of samples along the longer side of the grid, for -verify")
+	threshold := flags.Float64("threshold", 100, "lowest agreement percentage -verify accepts")
+	show := flags.Int("show", 10, "number of disagreeing points -verify prints")
+	stats := flags.Bool("stats", false, "print the triangulation's diagnostics")
+	dumpOnError := flags.String("dump-on-error", "", "if triangulation fails, write the input to this `file` at full precision")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %v\n", flags.Args())
+		return exitUsage
+	}
+
+	list, err := advanced.ReadPolygons(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "reading input: %v\n", err)
+		return exitUsage
+	}
+
+	var diagnostics advanced.Diagnostics
+	opts := advanced.Options{Seed: *seed, Validate: *validate, Diagnostics: &diagnostics}
+	if *evenOdd {
+		opts.FillRule = advanced.EvenOdd
+	}
+	triangles, err := triangulate.TriangulatePolygons(list, triangulate.WithOptions(opts))
+	if err != nil {
+		fmt.Fprintf(stderr, "triangulating: %v\n", err)
+		if *dumpOnError != "" {
+			if dumpErr := ioutil.WriteFile(*dumpOnError, []byte(formatPolygons(list)), 0644); dumpErr != nil {
+				fmt.Fprintf(stderr, "writing %s: %v\n", *dumpOnError, dumpErr)
+			} else {
+				fmt.Fprintf(stderr, "input written to %s\n", *dumpOnError)
+			}
+		}
+		return exitFailed
+	}
+
+	if !*quiet {
+		for _, triangle := range triangles {
+			fmt.Fprintln(stdout, advanced.FormatPointLog([]*advanced.Point{triangle.A, triangle.B, triangle.C}))
+		}
+	}
+	if *stats {
+		printStats(stdout, list, triangles, &diagnostics)
+	}
+	if *verify {
+		report := list.CompareBySampling(triangles, *density)
+		printReport(stdout, report, *show)
+		if 100*report.Agreement() < *threshold {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// The input as labeled point logs, which ReadPolygons reads back exactly
+func formatPolygons(list advanced.PolygonList) string {
+	var builder strings.Builder
+	for i, poly := range list {
+		fmt.Fprintf(&builder, "polygon %d: %s\n", i+1, advanced.FormatPointLog(poly.Points))
+	}
+	return builder.String()
+}
+
+func printStats(w io.Writer, list advanced.PolygonList, triangles advanced.TriangleList, diagnostics *advanced.Diagnostics) {
+	var vertices, solids, holes int
+	for i := range list {
+		vertices += len(list[i].Points)
+		if advanced.IsCCW(&list[i]) {
+			solids++
+		} else {
+			holes++
+		}
+	}
+	fmt.Fprintf(w, "trapezoids: %d\n", diagnostics.Trapezoids)
+	fmt.Fprintf(w, "monotones: %d\n", diagnostics.Monotones)
+	fmt.Fprintf(w, "triangles: %d\n", len(triangles))
+	// Each solid with n vertices and h holes makes n + 2h - 2 triangles. This
+	// takes the holes from their windings.
+	fmt.Fprintf(w, "expected triangles: %d (%d vertices, %d holes, %d solids)\n",
+		vertices+2*holes-2*solids, vertices, holes, solids)
+	confidence := diagnostics.Confidence
+	fmt.Fprintf(w, "marginal decisions: %d\n", confidence.MarginalDecisions)
+	if confidence.Marginal() {
+		fmt.Fprintf(w, "min margin: %g at %s\n", confidence.MinMargin, advanced.FormatPointLog(pointers(confidence.MinMarginPoints)))
+	}
+	fmt.Fprintf(w, "exact evaluations: %d\n", diagnostics.ExactEvaluations)
+	fmt.Fprintf(w, "graph rebuilds: %d\n", diagnostics.GraphRebuilds)
+	for _, warning := range diagnostics.Warnings {
+		fmt.Fprintf(w, "warning: %v\n", warning)
+	}
+}
+
+func printReport(w io.Writer, report advanced.SamplingReport, show int) {
+	fmt.Fprintf(w, "agreement: %.4f%% of %d samples\n", 100*report.Agreement(), report.Samples)
+	for i, disagreement := range report.Disagreements {
+		if i == show {
+			fmt.Fprintf(w, "... and %d more\n", len(report.Disagreements)-show)
+			break
+		}
+		p := disagreement.Point
+		fmt.Fprintf(w, "disagreement at {%v, %v}: inside=%v, covered by %d triangles\n",
+			p.X, p.Y, disagreement.Inside, disagreement.Coverage)
+	}
+}
+
+func pointers(points []advanced.Point) []*advanced.Point {
+	result := make([]*advanced.Point, len(points))
+	for i := range points {
+		result[i] = &points[i]
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/osuushi/triangulate/advanced"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const squareWithHole = `
+polygon 1: {0, 0} {10, 0} {10, 10} {0, 10}
+polygon 2: {3, 3} {3, 7} {7, 7} {7, 3}
+`
+
+func runWith(t *testing.T, input string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(input), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun_Triangles(t *testing.T) {
+	code, stdout, _ := runWith(t, squareWithHole)
+	require.Equal(t, exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 8)
+	for _, line := range lines {
+		points, err := advanced.ParsePointLog(line)
+		require.NoError(t, err)
+		assert.Len(t, points, 3)
+	}
+
+	code, stdout, _ = runWith(t, squareWithHole, "-quiet")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+}
+
+func TestRun_Verify(t *testing.T) {
+	code, stdout, _ := runWith(t, squareWithHole, "-quiet", "-verify", "-density", "40")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "agreement: 100.0000%")
+	assert.NotContains(t, stdout, "disagreement")
+
+	// A hole outside any solid is left empty, but the even-odd rule says it's
+	// inside
+	strayHole := "{0, 0} {10, 0} {10, 10} {0, 10}\n\n{20, 3} {20, 7} {24, 7} {24, 3}\n"
+	code, stdout, _ = runWith(t, strayHole, "-quiet", "-verify", "-density", "40", "-show", "2")
+	assert.Equal(t, exitFailed, code)
+	assert.Contains(t, stdout, "inside=true, covered by 0 triangles")
+	assert.Equal(t, 2, strings.Count(stdout, "disagreement at"))
+	assert.Contains(t, stdout, "more\n")
+
+	code, _, _ = runWith(t, strayHole, "-quiet", "-verify", "-density", "40", "-threshold", "50")
+	assert.Equal(t, exitOK, code)
+}
+
+func TestRun_Stats(t *testing.T) {
+	code, stdout, _ := runWith(t, squareWithHole, "-quiet", "-stats")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "monotones: 2\n")
+	assert.Contains(t, stdout, "triangles: 8\n")
+	assert.Contains(t, stdout, "expected triangles: 8 (8 vertices, 1 holes, 1 solids)\n")
+	assert.Contains(t, stdout, "marginal decisions: ")
+}
+
+func TestRun_DumpOnError(t *testing.T) {
+	dump := filepath.Join(t.TempDir(), "dump.txt")
+	bowtie := "{0, 0.1} {10, 10.3} {10, 0.2} {0, 10.4}\n"
+	code, _, stderr := runWith(t, bowtie, "-validate", "-dump-on-error", dump)
+	assert.Equal(t, exitFailed, code)
+	assert.Contains(t, stderr, "triangulating: ")
+	assert.Contains(t, stderr, "input written to "+dump)
+
+	data, err := ioutil.ReadFile(dump)
+	require.NoError(t, err)
+	list, err := advanced.ReadPolygons(bytes.NewReader(data))
+	require.NoError(t, err)
+	expected, err := advanced.ReadPolygons(strings.NewReader(bowtie))
+	require.NoError(t, err)
+	assert.Equal(t, expected, list)
+
+	// Without the flag, nothing is written
+	code, _, stderr = runWith(t, bowtie, "-validate")
+	assert.Equal(t, exitFailed, code)
+	assert.NotContains(t, stderr, "written")
+}
+
+func TestRun_Usage(t *testing.T) {
+	code, _, stderr := runWith(t, squareWithHole, "-no-such-flag")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "no-such-flag")
+
+	code, _, stderr = runWith(t, "{0, 0} {1}", "")
+	assert.Equal(t, exitUsage, code)
+	assert.NotEmpty(t, stderr)
+
+	code, _, stderr = runWith(t, "1 2 3\n")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "reading input")
+}
@@ -0,0 +1,92 @@
+package triangulate
+
+import (
+	"math"
+
+	"github.com/osuushi/triangulate/advanced"
+	"github.com/pkg/errors"
+)
+
+// Triangulate polygons given as flat arrays, for callers which don't deal in
+// pointers, such as code on the other side of a foreign function interface.
+//
+// The coordinates of every vertex are packed into coords as x, y pairs, ring
+// after ring, and ringLengths gives the number of vertices in each ring. The
+// result has three indexes per triangle, each indexing a vertex in the order
+// given, so that vertex k is at coords[2k] and coords[2k+1]. Options may
+// optionally be given.
+func TriangulateFlat(coords []float64, ringLengths []int, opts ...Options) (indexes []int, err error) {
+	defer func() {
+		if recoveredErr := advanced.HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			indexes, err = nil, recoveredErr
+		}
+	}()
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	vertexCount := 0
+	for i, length := range ringLengths {
+		if length < 3 {
+			return nil, errors.Errorf("ring %d has %d vertices, but rings need at least 3", i, length)
+		}
+		vertexCount += length
+	}
+	if len(coords) != 2*vertexCount {
+		return nil, errors.Errorf("the rings have %d vertices, which needs %d coordinates, not %d", vertexCount, 2*vertexCount, len(coords))
+	}
+
+	if vertexCount > math.MaxInt32 {
+		return nil, errors.Errorf("%d vertices is more than can be indexed", vertexCount)
+	}
+
+	// Each point carries its own index, so there's no map to build
+	pointSlices := make([][]*Point, len(ringLengths))
+	k := 0
+	for i, length := range ringLengths {
+		pointSlices[i] = make([]*Point, length)
+		for j := range pointSlices[i] {
+			p := &Point{X: coords[2*k], Y: coords[2*k+1], UserIndex: int32(k), HasUserIndex: true}
+			pointSlices[i][j] = p
+			k++
+		}
+	}
+
+	triangles := PolygonsFromPointSlices(pointSlices).TriangulateWithOptions(options)
+	indexes = make([]int, 0, 3*len(triangles))
+	for _, tri := range triangles {
+		for _, p := range []*Point{tri.A, tri.B, tri.C} {
+			if !p.HasUserIndex {
+				return nil, errors.Errorf("triangle references point %v, which is not in the input", p)
+			}
+			indexes = append(indexes, int(p.UserIndex))
+		}
+	}
+	return indexes, nil
+}
+
+// Triangulate rings of point values, for callers which don't want to allocate
+// pointers themselves. The result has one triple of indexes per triangle, each
+// indexing a vertex in the order given, ring after ring, as in TriangulateFlat.
+// The rings are wound as for Triangulate.
+func TriangulateValues(rings ...[]Point) (triangles [][3]int, err error) {
+	var coords []float64
+	ringLengths := make([]int, len(rings))
+	for i, ring := range rings {
+		ringLengths[i] = len(ring)
+		for _, p := range ring {
+			coords = append(coords, p.X, p.Y)
+		}
+	}
+
+	indexes, err := TriangulateFlat(coords, ringLengths)
+	if err != nil {
+		return nil, err
+	}
+	triangles = make([][3]int, len(indexes)/3)
+	for i := range triangles {
+		copy(triangles[i][:], indexes[3*i:])
+	}
+	return triangles, nil
+}
@@ -0,0 +1,55 @@
+package triangulate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriangulateFlat(t *testing.T) {
+	coords := []float64{
+		0, 0, 4, 0, 4, 4, 0, 4,
+		1, 1, 1, 3, 3, 3, 3, 1,
+	}
+	indexes, err := TriangulateFlat(coords, []int{4, 4})
+	assert.NoError(t, err)
+	// n + 2h - 2 triangles for n vertices and h holes
+	assert.Len(t, indexes, 3*(8+2-2))
+
+	var area float64
+	seen := make(map[int]bool)
+	for i := 0; i < len(indexes); i += 3 {
+		a, b, c := indexes[i], indexes[i+1], indexes[i+2]
+		seen[a], seen[b], seen[c] = true, true, true
+		area += ((coords[2*b]-coords[2*a])*(coords[2*c+1]-coords[2*a+1]) -
+			(coords[2*c]-coords[2*a])*(coords[2*b+1]-coords[2*a+1])) / 2
+	}
+	assert.InDelta(t, 12, area, 1e-9)
+	assert.Len(t, seen, 8)
+
+	_, err = TriangulateFlat(coords, []int{4, 3})
+	assert.Error(t, err)
+	_, err = TriangulateFlat(coords[:4], []int{2})
+	assert.Error(t, err)
+}
+
+func TestTriangulateValues(t *testing.T) {
+	outer := []Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	hole := []Point{{X: 1, Y: 1}, {X: 1, Y: 3}, {X: 3, Y: 3}, {X: 3, Y: 1}}
+	triangles, err := TriangulateValues(outer, hole)
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 8)
+
+	vertices := append(append([]Point{}, outer...), hole...)
+	var area float64
+	for _, tri := range triangles {
+		a, b, c := vertices[tri[0]], vertices[tri[1]], vertices[tri[2]]
+		area += ((b.X-a.X)*(c.Y-a.Y) - (c.X-a.X)*(b.Y-a.Y)) / 2
+	}
+	assert.InDelta(t, 12, area, 1e-9)
+
+	_, err = TriangulateValues(outer, hole[:2])
+	assert.Error(t, err)
+	_, err = TriangulateValues([]Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}})
+	assert.Error(t, err)
+}
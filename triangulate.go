@@ -5,11 +5,47 @@
 // set of triangles containing only the original points.
 package triangulate
 
-import "github.com/osuushi/triangulate/advanced"
+import (
+	"time"
+
+	"github.com/osuushi/triangulate/advanced"
+)
 
 type Point = advanced.Point
+type Segment = advanced.Segment
 type Triangle = advanced.Triangle
 type Polygon = advanced.Polygon
+type PolygonList = advanced.PolygonList
+type TriangleList = advanced.TriangleList
+type Options = advanced.Options
+type Diagnostics = advanced.Diagnostics
+type Rect = advanced.Rect
+type Session = advanced.Session
+type Triangulator = advanced.Triangulator
+type TriangulatorPool = advanced.TriangulatorPool
+type Option = advanced.Option
+type FillRule = advanced.FillRule
+type PointI = advanced.PointI
+type TriangleI = advanced.TriangleI
+type TriangleIList = advanced.TriangleIList
+
+const (
+	ByWinding = advanced.ByWinding
+	EvenOdd   = advanced.EvenOdd
+)
+
+// These are aliases, not new types, so values move freely between this package
+// and the advanced package. Make sure it stays that way.
+var (
+	_ = advanced.Point(Point{})
+	_ = advanced.Segment(Segment{})
+	_ = advanced.Triangle(Triangle{})
+	_ = advanced.Polygon(Polygon{})
+	_ = advanced.PolygonList(PolygonList{})
+	_ = advanced.TriangleList(TriangleList{})
+	_ = (*advanced.Session)((*Session)(nil))
+	_ = (*advanced.TriangulatorPool)((*TriangulatorPool)(nil))
+)
 
 // Take a set of point lists and convert them into triangles.
 //
@@ -19,16 +55,182 @@ type Polygon = advanced.Polygon
 //
 // The order of the polygons is irrelevant. See the readme for more details.
 func Triangulate(polygonPoints ...[]*Point) (result []*Triangle, err error) {
+	return TriangulateWithOptions(Options{}, polygonPoints...)
+}
+
+// Same as Triangulate, but with options. See Options for details.
+func TriangulateWithOptions(opts Options, polygonPoints ...[]*Point) (result []*Triangle, err error) {
+	defer handlePanic(&result, &err)
+	return []*Triangle(PolygonsFromPointSlices(polygonPoints).TriangulateWithOptions(opts)), nil
+}
+
+// Check rings for problems which would make triangulating them fail or give
+// wrong triangles, without triangulating them. The error names the polygon and
+// the vertexes or edges at fault. See advanced.PolygonList.Validate.
+func Validate(polygonPoints ...[]*Point) error {
+	return PolygonsFromPointSlices(polygonPoints).Validate()
+}
+
+// Same as Triangulate, but for integer coordinates, which are handled exactly,
+// with no tolerance. See advanced.TriangulateInt.
+func TriangulateInt(polygonPoints ...[]*PointI) (TriangleIList, error) {
+	return advanced.TriangulateInt(polygonPoints, Options{})
+}
+
+// Triangulate a polygon list, with any number of functional options, as in
+// TriangulatePolygons(list, WithSeed(42), WithValidation()). This is the entry
+// point for new settings; Triangulate's variadic rings leave no room for them.
+// Rings given as point slices can be wrapped with PolygonsFromPointSlices.
+func TriangulatePolygons(polygons PolygonList, options ...Option) (result TriangleList, err error) {
+	defer func() {
+		if recoveredErr := advanced.HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, err = nil, recoveredErr
+		}
+	}()
+	return polygons.TriangulateWithOptions(advanced.NewOptions(options...)), nil
+}
+
+// Seed the pseudorandom order of the segments. See advanced.Options.Seed.
+func WithSeed(seed int64) Option {
+	return advanced.WithSeed(seed)
+}
+
+// Check the input before triangulating. See advanced.Options.Validate.
+func WithValidation() Option {
+	return advanced.WithValidation()
+}
+
+// Choose which rings are solids and which are holes. See
+// advanced.Options.FillRule.
+func WithFillRule(rule FillRule) Option {
+	return advanced.WithFillRule(rule)
+}
+
+// Merge vertices closer together than the tolerance before triangulating.
+// See advanced.Options.WeldTolerance.
+func WithWeldTolerance(tolerance float64) Option {
+	return advanced.WithWeldTolerance(tolerance)
+}
+
+// Treat coordinates closer together than the tolerance as equal, in place of
+// advanced.Epsilon. See advanced.Options.Tolerance.
+func WithTolerance(tolerance float64) Option {
+	return advanced.WithTolerance(tolerance)
+}
+
+// Use the given Options, for settings with no Option of their own. Options
+// after this one still apply on top.
+func WithOptions(opts Options) Option {
+	return advanced.WithOptions(opts)
+}
+
+// Same as Triangulate, but also flag which triangle edges are on the boundary
+// of the input, including the boundaries of holes. The flags are a parallel
+// array to the triangles, where flags[i][0] is edge A-B of triangle i,
+// flags[i][1] is edge B-C, and flags[i][2] is edge C-A. See
+// advanced.PolygonList.BoundaryFlags.
+func TriangulateWithBoundaryFlags(polygonPoints ...[]*Point) (result TriangleList, flags [][3]bool, err error) {
+	defer func() {
+		if recoveredErr := advanced.HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, flags, err = nil, nil, recoveredErr
+		}
+	}()
+	polygons := PolygonsFromPointSlices(polygonPoints)
+	result = polygons.Triangulate()
+	return result, polygons.BoundaryFlags(result), nil
+}
+
+// Triangulate a counterclockwise polygon with axis-aligned rectangular holes.
+// The rectangles must be strictly inside the polygon, and must not touch each
+// other. Otherwise, this returns an advanced.RectOutsideError or
+// advanced.RectOverlapError naming the offending rectangles.
+//
+// The rings for the holes are created internally with the correct winding.
+// Options may optionally be given, in which case the four new points for each
+// rectangle are recorded in the SynthesizedPoints of the diagnostics, in the
+// same order as the holes. Each rectangle's points start at its minimum corner
+// and run clockwise.
+func TriangulateWithRectHoles(outer []*Point, holes []Rect, opts ...Options) (result []*Triangle, err error) {
+	defer handlePanic(&result, &err)
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	polygons := advanced.RectHoles(advanced.Polygon{Points: outer}, holes)
+	if options.Diagnostics != nil {
+		for _, hole := range polygons[1:] {
+			options.Diagnostics.SynthesizedPoints = append(options.Diagnostics.SynthesizedPoints, hole.Points...)
+		}
+	}
+	return []*Triangle(polygons.TriangulateWithOptions(options)), nil
+}
+
+// Triangulate within a rough time budget, for interactive previews. If the
+// full triangulation is estimated to fit in the budget, it is returned with
+// complete set to true. Otherwise, the result is a triangulation of a
+// simplified version of the input, using a subset of its points. See
+// advanced.PolygonList.TriangulateBudgeted for details.
+func TriangulateBudgeted(budget time.Duration, polygonPoints ...[]*Point) (result TriangleList, complete bool, err error) {
 	defer func() {
-		recoveredErr := advanced.HandleTriangulatePanicRecover(recover())
-		if recoveredErr != nil {
-			result = nil
-			err = recoveredErr
+		if recoveredErr := advanced.HandleTriangulatePanicRecover(recover()); recoveredErr != nil {
+			result, complete, err = nil, false, recoveredErr
 		}
 	}()
-	polygons := make(advanced.PolygonList, len(polygonPoints))
-	for i, points := range polygonPoints {
-		polygons[i] = advanced.Polygon{Points: points}
+	result, complete = PolygonsFromPointSlices(polygonPoints).TriangulateBudgeted(budget)
+	return result, complete, nil
+}
+
+// Wrap each slice of points as a polygon. The slices are shared, not copied.
+// The inverse is PolygonList.PointSlices.
+func PolygonsFromPointSlices(pointSlices [][]*Point) PolygonList {
+	return advanced.PolygonsFromPointSlices(pointSlices)
+}
+
+// Triangulate many independent groups of rings in parallel, such as many small
+// polygons which don't overlap. Each group is a list of rings, like the
+// arguments to Triangulate, and is triangulated on its own, so a bad group
+// only fails itself. The results are in the same order as the groups. See
+// advanced.TriangulatorPool.TriangulateBatch for details.
+func TriangulateBatch(polygons [][][]*Point) ([]TriangleList, []error) {
+	groups := make([]PolygonList, len(polygons))
+	for i, group := range polygons {
+		groups[i] = PolygonsFromPointSlices(group)
+	}
+	return batchPool.TriangulateBatch(groups, Options{})
+}
+
+// Triangulate many disjoint polygons, such as the sectors of a map, on up to
+// the given number of goroutines, or GOMAXPROCS if it's zero or less. The rings
+// are split into groups by containment, so that each outline is triangulated
+// with its holes, and anything in them. See
+// advanced.TriangulatorPool.TriangulateParallel for details.
+func TriangulateParallel(workers int, polygonPoints ...[]*Point) (TriangleList, error) {
+	return batchPool.TriangulateParallel(PolygonsFromPointSlices(polygonPoints), workers, Options{})
+}
+
+// The Triangulators for TriangulateBatch and TriangulateParallel. Batches are expected to be of small
+// polygons, so one which has grown large is dropped.
+var batchPool = advanced.NewTriangulatorPool(0, 1<<16)
+
+// Start a session for triangulating segments which arrive in chunks. See
+// advanced.Session for details.
+func NewSession() *Session {
+	return advanced.NewSession()
+}
+
+// Create a pool of Triangulators, for triangulating on many goroutines at once.
+// See advanced.NewTriangulatorPool for details.
+func NewTriangulatorPool(initialVertices, maxRetainedVertices int) *TriangulatorPool {
+	return advanced.NewTriangulatorPool(initialVertices, maxRetainedVertices)
+}
+
+// Convert a panic from the advanced package into an error. This must be
+// deferred directly, with pointers to the caller's named results.
+func handlePanic(result *[]*Triangle, err *error) {
+	recoveredErr := advanced.HandleTriangulatePanicRecover(recover())
+	if recoveredErr != nil {
+		*result = nil
+		*err = recoveredErr
 	}
-	return []*Triangle(polygons.Triangulate()), nil
 }
@@ -2,7 +2,10 @@ package triangulate
 
 import (
 	"testing"
+	"time"
 
+	"github.com/osuushi/triangulate/advanced"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,3 +22,204 @@ func TestTriangulate(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, triangles, 2)
 }
+
+func TestTriangulateWithOptions(t *testing.T) {
+	// Two triangles touching at a vertex, with a different pointer for each
+	first := []*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}
+	second := []*Point{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}}
+
+	triangles, err := TriangulateWithOptions(Options{CanonicalizeOutputPoints: true}, first, second)
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 2)
+
+	// Duplicate points within a ring are an error
+	_, err = TriangulateWithOptions(Options{CanonicalizeOutputPoints: true}, []*Point{
+		{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0},
+	})
+	var duplicateErr *advanced.DuplicatePointError
+	assert.ErrorAs(t, err, &duplicateErr)
+
+	// A hole wound the wrong way is corrected
+	outer := []*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	hole := []*Point{{X: 1, Y: 1}, {X: 3, Y: 1}, {X: 3, Y: 3}, {X: 1, Y: 3}}
+	triangles, err = TriangulateWithOptions(Options{FixWinding: true}, outer, hole)
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 8)
+	assert.Equal(t, 1.0, hole[1].Y, "input is unchanged")
+}
+
+func TestTriangulatePolygons(t *testing.T) {
+	outer := []*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	hole := []*Point{{X: 1, Y: 1}, {X: 3, Y: 1}, {X: 3, Y: 3}, {X: 1, Y: 3}}
+	polygons := PolygonsFromPointSlices([][]*Point{outer, hole})
+
+	// With no options, it's the same as Triangulate. The hole winds the wrong
+	// way, so it's a solid overlapping the outer square, which fails.
+	_, expectedErr := Triangulate(outer, hole)
+	_, err := TriangulatePolygons(polygons)
+	assert.Error(t, err)
+	assert.Equal(t, expectedErr.Error(), err.Error())
+
+	// Under the even-odd rule, the winding doesn't matter
+	triangles, err := TriangulatePolygons(polygons, WithFillRule(EvenOdd), WithSeed(42))
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 8)
+
+	// Options with no functional option of their own still work
+	diagnostics := &Diagnostics{}
+	_, err = TriangulatePolygons(polygons, WithOptions(Options{Diagnostics: diagnostics}), WithFillRule(EvenOdd))
+	assert.NoError(t, err)
+	assert.NotZero(t, diagnostics.QueryDepth.Queries)
+
+	// Correctly wound, the square with a hole needs no options
+	polygons[1] = Polygon{Points: []*Point{hole[0], hole[3], hole[2], hole[1]}}
+	triangles, err = TriangulatePolygons(polygons)
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 8)
+
+	degenerate := PolygonsFromPointSlices([][]*Point{{{X: 0, Y: 0}, {X: 1, Y: 1}}})
+	_, err = TriangulatePolygons(degenerate)
+	assert.Error(t, err)
+
+	bowtie := PolygonsFromPointSlices([][]*Point{{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 1}}})
+	_, err = TriangulatePolygons(bowtie, WithValidation())
+	var crossingErr *advanced.CrossingEdgesError
+	assert.ErrorAs(t, err, &crossingErr)
+}
+
+func TestValidate(t *testing.T) {
+	square := []*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	assert.NoError(t, Validate(square))
+
+	bowtie := []*Point{{X: 10, Y: 0}, {X: 11, Y: 1}, {X: 11, Y: 0}, {X: 10, Y: 1}}
+	var crossingErr *advanced.CrossingEdgesError
+	if assert.ErrorAs(t, Validate(square, bowtie), &crossingErr) {
+		assert.Equal(t, 1, crossingErr.FirstPolygon)
+		assert.Equal(t, 1, crossingErr.SecondPolygon)
+	}
+
+	var tooFewErr *advanced.TooFewPointsError
+	assert.ErrorAs(t, Validate(square[:2]), &tooFewErr)
+}
+
+func TestTriangulateBatch(t *testing.T) {
+	square := []*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}}
+	outer := []*Point{{X: 2, Y: 0}, {X: 6, Y: 0}, {X: 6, Y: 4}, {X: 2, Y: 4}}
+	hole := []*Point{{X: 3, Y: 1}, {X: 3, Y: 3}, {X: 5, Y: 3}, {X: 5, Y: 1}}
+	degenerate := []*Point{{X: 0, Y: 0}, {X: 1, Y: 1}}
+
+	results, errs := TriangulateBatch([][][]*Point{{square}, {degenerate}, {outer, hole}})
+	assert.NoError(t, errs[0])
+	assert.Len(t, results[0], 2)
+	assert.Error(t, errs[1])
+	assert.NoError(t, errs[2])
+	assert.Len(t, results[2], 8)
+}
+
+// Smoke test. The internals are already tested.
+func TestTriangulateParallel(t *testing.T) {
+	square := []*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}}
+	outer := []*Point{{X: 2, Y: 0}, {X: 6, Y: 0}, {X: 6, Y: 4}, {X: 2, Y: 4}}
+	hole := []*Point{{X: 3, Y: 1}, {X: 3, Y: 3}, {X: 5, Y: 3}, {X: 5, Y: 1}}
+
+	triangles, err := TriangulateParallel(2, square, outer, hole)
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 10)
+}
+
+func TestTriangulate_CoordinateRange(t *testing.T) {
+	square := []*Point{{X: 1e12, Y: 1e12}, {X: 3e12, Y: 1e12}, {X: 3e12, Y: 3e12}, {X: 1e12, Y: 3e12}}
+
+	_, err := Triangulate(square)
+	assert.True(t, errors.Is(err, advanced.ErrCoordinatesOutOfRange))
+
+	triangles, err := TriangulateWithOptions(Options{AutoNormalize: true}, square)
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 2)
+}
+
+func TestTriangulate_Bowtie(t *testing.T) {
+	bowtie := []*Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 1}}
+	_, err := Triangulate(bowtie)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "intersects existing segment")
+}
+
+func TestTriangulateInt(t *testing.T) {
+	outer := []*PointI{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	hole := []*PointI{{X: 1, Y: 1}, {X: 1, Y: 3}, {X: 3, Y: 3}, {X: 3, Y: 1}}
+	triangles, err := TriangulateInt(outer, hole)
+	assert.NoError(t, err)
+	assert.Len(t, triangles, 8)
+}
+
+func TestTriangulateWithBoundaryFlags(t *testing.T) {
+	outer := []*Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	hole := []*Point{{X: 1, Y: 1}, {X: 1, Y: 3}, {X: 3, Y: 3}, {X: 3, Y: 1}}
+
+	triangles, flags, err := TriangulateWithBoundaryFlags(outer, hole)
+	assert.NoError(t, err)
+	assert.Len(t, flags, len(triangles))
+	count := 0
+	for _, triangleFlags := range flags {
+		for _, flag := range triangleFlags {
+			if flag {
+				count++
+			}
+		}
+	}
+	assert.Equal(t, 8, count)
+
+	_, _, err = TriangulateWithBoundaryFlags([]*Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}})
+	assert.Error(t, err)
+}
+
+func TestTriangulateWithRectHoles(t *testing.T) {
+	outer := []*Point{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 20, Y: 10}, {X: 0, Y: 10}}
+	holes := []Rect{{MinX: 2, MinY: 2, MaxX: 4, MaxY: 4}, {MinX: 10, MinY: 3, MaxX: 15, MaxY: 8}}
+
+	diagnostics := &Diagnostics{}
+	triangles, err := TriangulateWithRectHoles(outer, holes, Options{Diagnostics: diagnostics})
+	assert.NoError(t, err)
+	// n + 2h - 2 triangles for n vertices and h holes
+	assert.Len(t, triangles, 12+4-2)
+	assert.Len(t, diagnostics.SynthesizedPoints, 8)
+	assert.Equal(t, Point{X: 2, Y: 2}, *diagnostics.SynthesizedPoints[0])
+	assert.Equal(t, Point{X: 10, Y: 3}, *diagnostics.SynthesizedPoints[4])
+
+	_, err = TriangulateWithRectHoles(outer, []Rect{{MinX: 18, MinY: 2, MaxX: 22, MaxY: 4}})
+	var outsideErr *advanced.RectOutsideError
+	assert.ErrorAs(t, err, &outsideErr)
+}
+
+func TestPointSliceConversions(t *testing.T) {
+	pointSlices := [][]*Point{
+		{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}},
+		{{X: 2, Y: 2}, {X: 3, Y: 2}, {X: 2, Y: 3}},
+	}
+	polygons := PolygonsFromPointSlices(pointSlices)
+	assert.Len(t, polygons, 2)
+	for i, poly := range polygons {
+		assert.Same(t, &pointSlices[i][0], &poly.Points[0])
+	}
+
+	roundTripped := polygons.PointSlices()
+	assert.Equal(t, pointSlices, roundTripped)
+	for i := range pointSlices {
+		assert.Same(t, &pointSlices[i][0], &roundTripped[i][0])
+	}
+
+	// Aliased types work directly with the advanced package
+	var list advanced.PolygonList = polygons
+	var triangles TriangleList = list.Triangulate()
+	assert.Len(t, triangles, 2)
+	assert.Empty(t, PolygonsFromPointSlices(nil))
+}
+
+func TestTriangulateBudgeted(t *testing.T) {
+	points := []*Point{{X: 1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+	triangles, complete, err := TriangulateBudgeted(time.Minute, points)
+	assert.NoError(t, err)
+	assert.True(t, complete)
+	assert.Len(t, triangles, 2)
+}